@@ -5,6 +5,7 @@ package bundle
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -51,6 +52,25 @@ type GraphNode struct {
 	NestedBundles []GraphNode
 }
 
+// LockRewrite How the ImagesLock of a bundle is written when pulling it from the repository it was relocated to
+type LockRewrite string
+
+const (
+	// LockRewriteRelocated replaces the images in the ImagesLock with their location in the bundle repository
+	LockRewriteRelocated LockRewrite = "relocated"
+	// LockRewriteOriginal keeps the ImagesLock as it was pushed, referencing the original images
+	LockRewriteOriginal LockRewrite = "original"
+	// LockRewriteBoth replaces the images in the ImagesLock with their location in the bundle repository
+	// and keeps the ImagesLock, as it was pushed, in OriginalImagesLockFile
+	LockRewriteBoth LockRewrite = "both"
+)
+
+// ImageRelocation Image of the ImagesLock of a pulled bundle and where it was found after the bundle was relocated
+type ImageRelocation struct {
+	Original  string `json:"original"`
+	Relocated string `json:"relocated"`
+}
+
 // Bundle struct that represents a bundle
 type Bundle struct {
 	plainImg         *plainimg.PlainImage
@@ -58,14 +78,26 @@ type Bundle struct {
 	imagesLockReader ImagesLockReader
 	bundleFetcher    Fetcher
 
+	// index is the OCI Image Index of a multi-arch bundle, nil for any other bundle.
+	// plainImg is then the bundle of the platform resolved from it
+	index *platformIndex
+
 	// cachedNestedBundleGraph stores a graph with all the nested
 	// bundles associated with the current bundle
 	cachedNestedBundleGraph []GraphNode
 
+	// pulledRelocations stores where the images of the ImagesLock of this bundle,
+	// and of its nested bundles, were found when pulling it
+	pulledRelocations []ImageRelocation
+
 	// cachedImageRefs stores set of ImageRefs that were
 	// discovered as part of reading the bundle.
 	// Includes refs only directly referenced by the bundle.
 	cachedImageRefs *imageRefCache
+
+	// deprecation stores the deprecation of the bundle once deprecationFetched is set
+	deprecation        *Deprecation
+	deprecationFetched bool
 }
 
 // NewBundleFromPlainImage Creates a new Bundle with a PlainImage and uses Registry Fetcher
@@ -103,6 +135,9 @@ func (o *Bundle) Tag() string { return o.plainImg.Tag() }
 // NestedBundles Provides information about the Graph of nested bundles associated with the current bundle
 func (o *Bundle) NestedBundles() []GraphNode { return o.cachedNestedBundleGraph }
 
+// PulledRelocations Provides where the images of the ImagesLocks were found when the bundle was pulled from the repository it was relocated to
+func (o *Bundle) PulledRelocations() []ImageRelocation { return o.pulledRelocations }
+
 func (o *Bundle) findCachedImageRef(digestRef string) (ImageRef, bool) {
 	ref, found := o.cachedImageRefs.ImageRef(digestRef)
 	if found {
@@ -122,11 +157,18 @@ func (o *Bundle) findCachedImageRef(digestRef string) (ImageRef, bool) {
 
 // NoteCopy writes an image-location representing the bundle / images that have been copied
 func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesMetadataWriter, ui util.LoggerWithLevels) error {
+	return o.NoteCopyExcluding(processedImages, nil, reg, ui)
+}
+
+// NoteCopyExcluding writes an image-location representing the bundle / images that have been copied, the images
+// for which isExcluded returns true were left out of the copy on purpose and are recorded as excluded
+func (o *Bundle) NoteCopyExcluding(processedImages *imageset.ProcessedImages, isExcluded func(imageRef string) bool, reg ImagesMetadataWriter, ui util.LoggerWithLevels) error {
 	locationsCfg := ImageLocationsConfig{
 		APIVersion: LocationAPIVersion,
 		Kind:       ImageLocationsKind,
 	}
 	foundImages := map[string]bool{}
+	var destinationRepos []string
 	var bundleProcessedImage imageset.ProcessedImage
 	for _, image := range processedImages.All() {
 		imgDigest, err := regname.NewDigest(image.UnprocessedImageRef.DigestRef)
@@ -141,6 +183,7 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 					Image:    ref.Image,
 					IsBundle: *ref.IsBundle,
 				})
+				destinationRepos = append(destinationRepos, processedImageRepo(image))
 				foundImages[imgDigest.DigestStr()] = true
 			}
 		}
@@ -150,6 +193,21 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 		}
 	}
 
+	if isExcluded != nil {
+		for _, ref := range o.cachedImageRefs.All() {
+			if foundImages[ref.Digest()] || !isExcluded(ref.Image) {
+				continue
+			}
+			locationsCfg.Images = append(locationsCfg.Images, ImageLocation{
+				Image:    ref.Image,
+				IsBundle: *ref.IsBundle,
+				Excluded: true,
+			})
+			destinationRepos = append(destinationRepos, "")
+			foundImages[ref.Digest()] = true
+		}
+	}
+
 	if len(locationsCfg.Images) != o.cachedImageRefs.Size() {
 		panic(fmt.Sprintf("Expected: on bundle %s %d images to be written to Location OCI. Actual: %d were written", o.DigestRef(), o.cachedImageRefs.Size(), len(locationsCfg.Images)))
 	}
@@ -159,15 +217,52 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 		panic(fmt.Sprintf("Internal inconsistency: '%s' have to be a digest", bundleProcessedImage.DigestRef))
 	}
 
+	// Images copied to a repository other than the one of the bundle, like when copying to a registry, need to be located there
+	for idx, repo := range destinationRepos {
+		if repo != "" && repo != destinationRef.Context().Name() {
+			locationsCfg.Images[idx].Repository = repo
+		}
+	}
+
 	ui.Debugf("creating Locations OCI Image\n")
 
 	// Using NewNoopLevelLogger because we do not want to have output from this push
-	return NewLocations(ui).Save(reg, destinationRef, locationsCfg, util.NewNoopLevelLogger())
+	err = NewLocations(ui).Save(reg, destinationRef, locationsCfg, util.NewNoopLevelLogger())
+	if err != nil {
+		return err
+	}
+
+	// The deprecation is only known when it was fetched from the source of the copy
+	if o.deprecationFetched && o.deprecation != nil {
+		ui.Debugf("creating deprecation image\n")
+		return SaveDeprecation(reg, destinationRef, o.deprecation)
+	}
+	return nil
+}
+
+func processedImageRepo(image imageset.ProcessedImage) string {
+	destinationRef, err := regname.NewDigest(image.DigestRef)
+	if err != nil {
+		panic(fmt.Sprintf("Internal inconsistency: '%s' have to be a digest", image.DigestRef))
+	}
+	return destinationRef.Context().Name()
 }
 
 // Pull Downloads bundle image to disk and checks if it can update the ImagesLock file
 func (o *Bundle) Pull(outputPath string, logger Logger, pullNestedBundles bool) (bool, error) {
-	isRootBundleRelocated, err := o.pull(outputPath, logger, pullNestedBundles, "", map[string]bool{}, 0)
+	return o.PullWithLockRewrite(outputPath, logger, pullNestedBundles, LockRewriteRelocated)
+}
+
+// PullWithLockRewrite Downloads bundle image to disk and, when the bundle repository hosts every image of the ImagesLock,
+// writes the ImagesLock file as requested by rewrite
+func (o *Bundle) PullWithLockRewrite(outputPath string, logger Logger, pullNestedBundles bool, rewrite LockRewrite) (bool, error) {
+	switch rewrite {
+	case LockRewriteRelocated, LockRewriteOriginal, LockRewriteBoth:
+	default:
+		return false, fmt.Errorf("Unknown ImagesLock rewrite '%s' (supported: %s, %s, %s)", rewrite, LockRewriteRelocated, LockRewriteOriginal, LockRewriteBoth)
+	}
+
+	isRootBundleRelocated, err := o.pull(outputPath, logger, pullNestedBundles, "", map[string]bool{}, 0, rewrite)
 	if err != nil {
 		return false, err
 	}
@@ -175,13 +270,16 @@ func (o *Bundle) Pull(outputPath string, logger Logger, pullNestedBundles bool)
 	logger.Logf("\nLocating image lock file images...\n")
 	if isRootBundleRelocated {
 		logger.Logf("The bundle repo (%s) is hosting every image specified in the bundle's Images Lock file (.imgpkg/images.yml)\n", o.Repo())
+		if rewrite == LockRewriteOriginal {
+			logger.Logf("Keeping the original images in the lock file\n")
+		}
 	} else {
 		logger.Logf("One or more images not found in bundle repo; skipping lock file update\n")
 	}
 	return isRootBundleRelocated, nil
 }
 
-func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bool, bundlePath string, imagesProcessed map[string]bool, numSubBundles int) (bool, error) {
+func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bool, bundlePath string, imagesProcessed map[string]bool, numSubBundles int, rewrite LockRewrite) (bool, error) {
 	img, err := o.checkedImage()
 	if err != nil {
 		return false, err
@@ -222,6 +320,13 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 		return false, err
 	}
 
+	if isRelocatedToBundle {
+		relocatedImagesLock := bundleImageRefs.ImagesLock()
+		for idx, img := range imagesLock.Images {
+			o.pulledRelocations = append(o.pulledRelocations, ImageRelocation{Original: img.Image, Relocated: relocatedImagesLock.Images[idx].Image})
+		}
+	}
+
 	if pullNestedBundles {
 		for _, bundleImgRef := range bundleImageRefs.ImageRefs() {
 			if isBundle, alreadyProcessedImage := imagesProcessed[bundleImgRef.Image]; alreadyProcessedImage {
@@ -259,10 +364,11 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 			if err != nil {
 				return false, err
 			}
-			_, err = subBundle.pull(baseOutputPath, util.NewIndentedLevelLogger(logger), pullNestedBundles, o.subBundlePath(bundleDigest), imagesProcessed, numSubBundles)
+			_, err = subBundle.pull(baseOutputPath, util.NewIndentedLevelLogger(logger), pullNestedBundles, o.subBundlePath(bundleDigest), imagesProcessed, numSubBundles, rewrite)
 			if err != nil {
 				return false, err
 			}
+			o.pulledRelocations = append(o.pulledRelocations, subBundle.pulledRelocations...)
 
 			o.cachedNestedBundleGraph = append(o.cachedNestedBundleGraph, GraphNode{
 				Path:          filepath.Join(baseOutputPath, o.subBundlePath(bundleDigest)),
@@ -273,15 +379,37 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 	}
 
 	if isRelocatedToBundle {
-		err := bundleImageRefs.ImagesLock().WriteToPath(filepath.Join(baseOutputPath, bundlePath, ImgpkgDir, ImagesLockFile))
+		err := writeImagesLock(filepath.Join(baseOutputPath, bundlePath, ImgpkgDir), bundleImageRefs.ImagesLock(), rewrite)
 		if err != nil {
-			return false, fmt.Errorf("Rewriting image lock file: %s", err)
+			return false, err
 		}
 	}
 
 	return isRelocatedToBundle, nil
 }
 
+// writeImagesLock Writes the ImagesLock, with the images in the bundle repository, to the .imgpkg folder as requested by rewrite
+func writeImagesLock(imgpkgDir string, relocatedImagesLock lockconfig.ImagesLock, rewrite LockRewrite) error {
+	lockPath := filepath.Join(imgpkgDir, ImagesLockFile)
+
+	switch rewrite {
+	case LockRewriteOriginal:
+		return nil
+
+	case LockRewriteBoth:
+		err := os.Rename(lockPath, filepath.Join(imgpkgDir, OriginalImagesLockFile))
+		if err != nil {
+			return fmt.Errorf("Keeping original image lock file: %s", err)
+		}
+	}
+
+	err := relocatedImagesLock.WriteToPath(lockPath)
+	if err != nil {
+		return fmt.Errorf("Rewriting image lock file: %s", err)
+	}
+	return nil
+}
+
 func (*Bundle) subBundlePath(bundleDigest regname.Digest) string {
 	return filepath.Join(ImgpkgDir, BundlesDir, strings.ReplaceAll(bundleDigest.DigestStr(), "sha256:", "sha256-"))
 }
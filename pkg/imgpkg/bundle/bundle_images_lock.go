@@ -172,10 +172,11 @@ func (o *SingleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error)
 		return conf, err
 	}
 
-	if len(layers) != 1 {
-		return conf, fmt.Errorf("Expected bundle to only have a single layer, got %d", len(layers))
+	if len(layers) == 0 {
+		return conf, fmt.Errorf("Expected bundle to have at least one layer")
 	}
 
+	// bundles pushed with several layers (--layer-per-dir or --layer) keep the bundle metadata in the first one
 	layer := layers[0]
 
 	mediaType, err := layer.MediaType()
@@ -183,8 +184,8 @@ func (o *SingleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error)
 		return conf, err
 	}
 
-	if mediaType != types.DockerLayer {
-		return conf, fmt.Errorf("Expected layer to have docker layer media type, was %s", mediaType)
+	if mediaType != types.DockerLayer && mediaType != types.OCILayer {
+		return conf, fmt.Errorf("Expected layer to have docker or OCI gzip layer media type, was %s", mediaType)
 	}
 
 	// here we know layer is .tgz so decompress and read tar headers
@@ -8,8 +8,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
 	regname "github.com/google/go-containerregistry/pkg/name"
@@ -21,12 +24,21 @@ const (
 	ImgpkgDir      = ".imgpkg"
 	BundlesDir     = "bundles"
 	ImagesLockFile = "images.yml"
+	// OriginalImagesLockFile ImagesLock, as it was pushed, kept next to the rewritten ImagesLock when pulling with LockRewriteBoth
+	OriginalImagesLockFile = "images-original.yml"
 )
 
 type Contents struct {
 	paths               []string
 	excludedPaths       []string
 	preservePermissions bool
+	compression         ctlimg.LayerCompression
+	created             time.Time
+	annotations         map[string]string
+	fileFilter          ctlimg.FileFilter
+	fileAttributes      ctlimg.FileAttributes
+	layerSplit          ctlimg.LayerSplit
+	resolvedImagesLock  *lockconfig.ImagesLock
 }
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . ImagesMetadataWriter
@@ -42,6 +54,75 @@ func NewContents(paths []string, excludedPaths []string, preservePermissions boo
 	return Contents{paths: paths, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
 }
 
+// WithCompression Compresses the layer of the bundle image with compression instead of gzip
+func (b Contents) WithCompression(compression ctlimg.LayerCompression) Contents {
+	b.compression = compression
+	return b
+}
+
+// WithCreated Records created as the creation time of the bundle image and as the modification time of its files
+func (b Contents) WithCreated(created time.Time) Contents {
+	b.created = created
+	return b
+}
+
+// WithAnnotations Adds annotations to the manifest of the bundle
+func (b Contents) WithAnnotations(annotations map[string]string) Contents {
+	b.annotations = annotations
+	return b
+}
+
+// WithFileFilter Adds the contents returned by fileFilter, instead of the contents on disk, for each file of the bundle image
+func (b Contents) WithFileFilter(fileFilter ctlimg.FileFilter) Contents {
+	b.fileFilter = fileFilter
+	return b
+}
+
+// WithFileAttributes Records the owner and the permissions of fileAttributes for the files and directories of the bundle image
+func (b Contents) WithFileAttributes(fileAttributes ctlimg.FileAttributes) Contents {
+	b.fileAttributes = fileAttributes
+	return b
+}
+
+// WithLayerSplit Splits the files of the bundle image into the layers of layerSplit instead of a single layer
+func (b Contents) WithLayerSplit(layerSplit ctlimg.LayerSplit) Contents {
+	b.layerSplit = layerSplit
+	return b
+}
+
+// ResolveImagesLockTags Returns Contents where the images of .imgpkg/images.yml referenced by tag are referenced by
+// their digest in imagesMetadata. The resolved ImagesLock is validated and pushed instead of the one on disk
+func (b Contents) ResolveImagesLockTags(imagesMetadata ImagesMetadata, logger Logger) (Contents, error) {
+	imgpkgDirs, err := b.findImgpkgDirs()
+	if err != nil {
+		return Contents{}, err
+	}
+	err = b.validateImgpkgDirs(imgpkgDirs)
+	if err != nil {
+		return Contents{}, err
+	}
+
+	imagesLock, err := lockconfig.NewImagesLockWithTagsFromPath(filepath.Join(imgpkgDirs[0], ImagesLockFile))
+	if err != nil {
+		return Contents{}, err
+	}
+
+	resolvedImagesLock, err := imagesLock.ResolveTags(func(ref regname.Tag) (regv1.Hash, error) {
+		digest, err := imagesMetadata.Digest(ref)
+		if err != nil {
+			return regv1.Hash{}, err
+		}
+		logger.Logf("Resolved image '%s' to digest '%s'\n", ref.String(), digest)
+		return digest, nil
+	})
+	if err != nil {
+		return Contents{}, err
+	}
+
+	b.resolvedImagesLock = &resolvedImagesLock
+	return b, nil
+}
+
 // Push the contents of the bundle to the registry as an OCI Image
 func (b Contents) Push(uploadRef regname.Tag, labels map[string]string, registry ImagesMetadataWriter, logger Logger) (string, error) {
 	err := b.validate()
@@ -49,12 +130,74 @@ func (b Contents) Push(uploadRef regname.Tag, labels map[string]string, registry
 		return "", err
 	}
 
+	fileFilter, err := b.imagesLockFileFilter()
+	if err != nil {
+		return "", err
+	}
+
 	if labels == nil {
 		labels = map[string]string{}
 	}
 	labels[BundleConfigLabel] = "true"
 
-	return plainimage.NewContents(b.paths, b.excludedPaths, b.preservePermissions).Push(uploadRef, labels, registry, logger)
+	metadataLabels, err := b.metadataLabels()
+	if err != nil {
+		return "", err
+	}
+	for key, value := range metadataLabels {
+		labels[key] = value
+	}
+
+	return plainimage.NewContents(b.paths, b.excludedPaths, b.preservePermissions).WithCompression(b.compression).WithCreated(b.created).WithAnnotations(b.annotations).WithFileFilter(fileFilter).WithFileAttributes(b.fileAttributes).WithLayerSplit(b.layerSplit).Push(uploadRef, labels, registry, logger)
+}
+
+// imagesLockFileFilter Returns the file filter of the bundle, adding the resolved ImagesLock instead of
+// .imgpkg/images.yml when the tags of the ImagesLock were resolved
+func (b Contents) imagesLockFileFilter() (ctlimg.FileFilter, error) {
+	if b.resolvedImagesLock == nil {
+		return b.fileFilter, nil
+	}
+
+	imagesLockBytes, err := b.resolvedImagesLock.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(relPath string, contents []byte) []byte {
+		if relPath == ImgpkgDir+"/"+ImagesLockFile {
+			contents = imagesLockBytes
+		}
+		if b.fileFilter != nil {
+			return b.fileFilter(relPath, contents)
+		}
+		return contents
+	}, nil
+}
+
+// imagesLock Reads the ImagesLock in imgpkgDir, or returns the resolved ImagesLock when the tags of the ImagesLock were resolved
+func (b Contents) imagesLock(imgpkgDir string) (lockconfig.ImagesLock, error) {
+	if b.resolvedImagesLock == nil {
+		return lockconfig.NewImagesLockFromPath(filepath.Join(imgpkgDir, ImagesLockFile))
+	}
+
+	// Parse the resolved ImagesLock to use the fully qualified name of the images, as when it is read from disk
+	imagesLockBytes, err := b.resolvedImagesLock.AsBytes()
+	if err != nil {
+		return lockconfig.ImagesLock{}, err
+	}
+	return lockconfig.NewImagesLockFromBytes(imagesLockBytes)
+}
+
+// metadataLabels Labels derived from the bundle metadata file, empty when the bundle does not have one
+func (b Contents) metadataLabels() (map[string]string, error) {
+	metadata, found, err := b.metadata()
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return map[string]string{}, nil
+	}
+	return metadata.Labels()
 }
 
 // PresentsAsBundle checks if the provided folders have the needed structure to be a bundle
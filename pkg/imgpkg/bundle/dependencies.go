@@ -0,0 +1,355 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"golang.org/x/mod/semver"
+)
+
+// DependencyViolation Requirement of a bundle that is not satisfied by its nested bundles
+type DependencyViolation struct {
+	// Bundle that declares the requirement
+	Bundle      string
+	Requirement BundleRequirement
+	Reason      string
+}
+
+// DependencyError Returned when one or more requirements declared by the bundles are not satisfied
+type DependencyError struct {
+	Violations []DependencyViolation
+}
+
+// Error Lists all the requirements that are not satisfied
+func (d DependencyError) Error() string {
+	var lines []string
+	for _, violation := range d.Violations {
+		requirement := violation.Requirement.Name
+		if violation.Requirement.Version != "" {
+			requirement += " " + violation.Requirement.Version
+		}
+		lines = append(lines, fmt.Sprintf("- %s: requires '%s': %s", violation.Bundle, requirement, violation.Reason))
+	}
+	return fmt.Sprintf("Validating bundle dependencies:\n%s\n(hint: nest a bundle with the required name and version, or update the requires section of .imgpkg/%s)",
+		strings.Join(lines, "\n"), MetadataFile)
+}
+
+// ValidateDependencies Verifies that the requirements declared by each bundle are satisfied by the bundles
+// nested in it, directly or through other nested bundles
+func ValidateDependencies(bundles []*Bundle) error {
+	graph := newDependencyGraph()
+	err := graph.addBundles(bundles)
+	if err != nil {
+		return err
+	}
+	return graph.validate()
+}
+
+// ValidateDependencies Verifies that the requirements declared in .imgpkg/bundle.yml are satisfied by the bundles
+// referenced in .imgpkg/images.yml, directly or through other nested bundles
+func (b Contents) ValidateDependencies(imagesMetadata ImagesMetadata, logger Logger) error {
+	metadata, found, err := b.metadata()
+	if err != nil {
+		return err
+	}
+	if !found || len(metadata.Requires) == 0 {
+		return nil
+	}
+
+	imgpkgDirs, err := b.findImgpkgDirs()
+	if err != nil {
+		return err
+	}
+	imagesLock, err := b.imagesLock(imgpkgDirs[0])
+	if err != nil {
+		return err
+	}
+
+	graph := newDependencyGraph()
+	root := &dependencyNode{ref: filepath.Dir(imgpkgDirs[0]), metadata: metadata}
+
+	lockReader := NewImagesLockReader()
+	for _, image := range imagesLock.Images {
+		nestedBundle := NewBundleFromRef(image.Image, imagesMetadata, lockReader, NewRegistryFetcher(imagesMetadata, lockReader))
+		isBundle, err := nestedBundle.IsBundle()
+		if err != nil {
+			return fmt.Errorf("Checking if '%s' is a bundle: %s", image.Image, err)
+		}
+		if !isBundle {
+			continue
+		}
+
+		allBundles, _, err := nestedBundle.AllImagesLockRefs(1, logger)
+		if err != nil {
+			return fmt.Errorf("Retrieving nested bundles of '%s': %s", image.Image, err)
+		}
+		err = graph.addBundles(allBundles)
+		if err != nil {
+			return err
+		}
+		root.nested = append(root.nested, nestedBundle.Digest())
+	}
+	graph.add(localBundleKey(root.ref), root)
+
+	return graph.validate()
+}
+
+type dependencyNode struct {
+	ref      string
+	metadata Metadata
+	// image of the bundle whose metadata is read from its contents, only when a bundle requires it, because the
+	// bundles without requirements do not record it in their labels
+	image regv1.Image
+	// nested digests of the bundles directly referenced in the ImagesLock
+	nested []string
+}
+
+// readMetadata Reads the metadata of the bundle from its contents, when it is not recorded in its labels
+func (n *dependencyNode) readMetadata() error {
+	if n.image == nil {
+		return nil
+	}
+	metadata, err := metadataFromImage(n.image)
+	if err != nil {
+		return fmt.Errorf("Reading metadata of bundle '%s': %s", n.ref, err)
+	}
+	n.metadata = metadata
+	n.image = nil
+	return nil
+}
+
+type dependencyGraph struct {
+	nodes map[string]*dependencyNode
+	order []string
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{nodes: map[string]*dependencyNode{}}
+}
+
+func localBundleKey(ref string) string { return "local:" + ref }
+
+func (g *dependencyGraph) add(key string, node *dependencyNode) {
+	if _, found := g.nodes[key]; found {
+		return
+	}
+	g.nodes[key] = node
+	g.order = append(g.order, key)
+}
+
+func (g *dependencyGraph) addBundles(bundles []*Bundle) error {
+	for _, bundle := range bundles {
+		if _, found := g.nodes[bundle.Digest()]; found {
+			continue
+		}
+
+		img, err := bundle.checkedImage()
+		if err != nil {
+			return err
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("Reading config of bundle '%s': %s", bundle.DigestRef(), err)
+		}
+		metadata, err := NewMetadataFromLabels(cfg.Config.Labels)
+		if err != nil {
+			return fmt.Errorf("Reading metadata of bundle '%s': %s", bundle.DigestRef(), err)
+		}
+
+		node := &dependencyNode{ref: bundle.DigestRef(), metadata: metadata}
+		if cfg.Config.Labels[BundleNameLabel] == "" {
+			node.image = img
+		}
+		for _, imageRef := range bundle.ImagesRefsWithErrors() {
+			if imageRef.IsBundle == nil || !*imageRef.IsBundle {
+				continue
+			}
+			digest, err := regname.NewDigest(imageRef.PrimaryLocation())
+			if err != nil {
+				return fmt.Errorf("Internal inconsistency: nested bundle '%s' should be a digest reference", imageRef.PrimaryLocation())
+			}
+			node.nested = append(node.nested, digest.DigestStr())
+		}
+		g.add(bundle.Digest(), node)
+	}
+	return nil
+}
+
+func (g *dependencyGraph) validate() error {
+	var violations []DependencyViolation
+	for _, key := range g.order {
+		node := g.nodes[key]
+		if len(node.metadata.Requires) == 0 {
+			continue
+		}
+
+		nestedBundles := g.allNested(node)
+		for _, nested := range nestedBundles {
+			err := nested.readMetadata()
+			if err != nil {
+				return err
+			}
+		}
+		for _, requirement := range node.metadata.Requires {
+			constraint, err := NewVersionConstraint(requirement.Version)
+			if err != nil {
+				return fmt.Errorf("Parsing requirement '%s' of bundle '%s': %s", requirement.Name, node.ref, err)
+			}
+
+			found := false
+			for _, nested := range nestedBundles {
+				if nested.metadata.Metadata.Name != requirement.Name {
+					continue
+				}
+				found = true
+
+				reason := ""
+				switch version := nested.metadata.Metadata.Version; {
+				case constraint.IsEmpty():
+				case version == "":
+					reason = fmt.Sprintf("Nested bundle '%s' does not declare a version", nested.ref)
+				default:
+					matches, err := constraint.Check(version)
+					if err != nil {
+						reason = fmt.Sprintf("Nested bundle '%s' has version '%s': %s", nested.ref, version, err)
+					} else if !matches {
+						reason = fmt.Sprintf("Nested bundle '%s' has version '%s'", nested.ref, version)
+					}
+				}
+				if reason != "" {
+					violations = append(violations, DependencyViolation{Bundle: node.ref, Requirement: requirement, Reason: reason})
+				}
+			}
+
+			if !found {
+				violations = append(violations, DependencyViolation{Bundle: node.ref, Requirement: requirement, Reason: "Not found in the nested bundles"})
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return DependencyError{Violations: violations}
+	}
+	return nil
+}
+
+// allNested Returns the bundles nested in node, directly or through other nested bundles
+func (g *dependencyGraph) allNested(node *dependencyNode) []*dependencyNode {
+	var result []*dependencyNode
+	visited := map[string]bool{}
+	toVisit := append([]string{}, node.nested...)
+	for len(toVisit) > 0 {
+		key := toVisit[0]
+		toVisit = toVisit[1:]
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		nested, found := g.nodes[key]
+		if !found {
+			continue
+		}
+		result = append(result, nested)
+		toVisit = append(toVisit, nested.nested...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].ref < result[j].ref })
+	return result
+}
+
+// VersionConstraint Conditions, separated by commas, that a semantic version must satisfy. For example ">=1.2.0, <2.0.0"
+type VersionConstraint struct {
+	conditions []versionCondition
+}
+
+type versionCondition struct {
+	operator string
+	version  string
+}
+
+// versionOperators Supported operators, the ones that are a prefix of others must be after them
+var versionOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// NewVersionConstraint Parses a version constraint, an empty constraint is satisfied by any version
+func NewVersionConstraint(constraint string) (VersionConstraint, error) {
+	result := VersionConstraint{}
+	if strings.TrimSpace(constraint) == "" {
+		return result, nil
+	}
+
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		operator := "="
+		for _, op := range versionOperators {
+			if strings.HasPrefix(part, op) {
+				operator = op
+				part = strings.TrimSpace(strings.TrimPrefix(part, op))
+				break
+			}
+		}
+
+		version, err := parseVersion(part)
+		if err != nil {
+			return VersionConstraint{}, fmt.Errorf("Invalid constraint '%s': %s", constraint, err)
+		}
+		result.conditions = append(result.conditions, versionCondition{operator: operator, version: version})
+	}
+	return result, nil
+}
+
+// IsEmpty Returns true when any version satisfies the constraint
+func (c VersionConstraint) IsEmpty() bool { return len(c.conditions) == 0 }
+
+// Check Returns true when version satisfies all the conditions of the constraint
+func (c VersionConstraint) Check(version string) (bool, error) {
+	parsedVersion, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	for _, condition := range c.conditions {
+		comparison := semver.Compare(parsedVersion, condition.version)
+		var satisfied bool
+		switch condition.operator {
+		case ">=":
+			satisfied = comparison >= 0
+		case "<=":
+			satisfied = comparison <= 0
+		case "!=":
+			satisfied = comparison != 0
+		case ">":
+			satisfied = comparison > 0
+		case "<":
+			satisfied = comparison < 0
+		case "=":
+			satisfied = comparison == 0
+		default:
+			panic(fmt.Sprintf("Internal inconsistency: unknown version operator '%s'", condition.operator))
+		}
+		if !satisfied {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseVersion Returns the version in the format expected by semver, prefixed with v. Versions without the minor or
+// patch, such as 1.2, are accepted
+func parseVersion(version string) (string, error) {
+	parsed := strings.TrimSpace(version)
+	if !strings.HasPrefix(parsed, "v") {
+		parsed = "v" + parsed
+	}
+	if !semver.IsValid(parsed) {
+		return "", fmt.Errorf("Expected '%s' to be a semantic version (e.g. 1.2.0)", version)
+	}
+	return parsed, nil
+}
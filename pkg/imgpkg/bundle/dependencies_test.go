@@ -0,0 +1,229 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		matches    bool
+	}{
+		{constraint: "", version: "0.0.1", matches: true},
+		{constraint: ">=1.2", version: "1.2.0", matches: true},
+		{constraint: ">=1.2.0", version: "1.1.9", matches: false},
+		{constraint: ">=1.2.0, <2.0.0", version: "1.9.3", matches: true},
+		{constraint: ">=1.2.0, <2.0.0", version: "2.0.0", matches: false},
+		{constraint: "1.2.0", version: "v1.2.0", matches: true},
+		{constraint: "!=1.2.0", version: "1.2.0", matches: false},
+		{constraint: ">1.2.0", version: "1.2.1-rc.1", matches: true},
+		{constraint: "<=1.2.0", version: "1.2.0", matches: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("when the constraint is '%s' and the version is '%s', it returns %t", test.constraint, test.version, test.matches), func(t *testing.T) {
+			constraint, err := bundle.NewVersionConstraint(test.constraint)
+			require.NoError(t, err)
+			matches, err := constraint.Check(test.version)
+			require.NoError(t, err)
+			assert.Equal(t, test.matches, matches)
+		})
+	}
+
+	t.Run("when the constraint is not a semantic version, it returns an error", func(t *testing.T) {
+		_, err := bundle.NewVersionConstraint(">=latest")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected 'latest' to be a semantic version")
+	})
+}
+
+func TestValidateDependencies(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+
+	loggingV11 := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("logging:1.1.0"), bundleYAML("logging-bundle", "1.1.0"), nil)
+	loggingV13 := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("logging:1.3.0"), bundleYAML("logging-bundle", "1.3.0"), nil)
+	// The requirement is satisfied through a bundle nested in observability
+	observability := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("observability:1.0.0"), bundleYAML("observability-bundle", "1.0.0"), []string{loggingV13})
+
+	platformYAML := bundleYAML("platform-bundle", "2.0.0") + `requires:
+- name: logging-bundle
+  version: ">=1.2.0, <2.0.0"
+`
+
+	t.Run("when the nested bundle satisfies the requirement, push succeeds", func(t *testing.T) {
+		contents := bundle.NewContents([]string{createBundleDir(t, assets, platformYAML, []string{observability})}, nil, false)
+		require.NoError(t, contents.ValidateDependencies(reg, util.NewNoopLevelLogger()))
+	})
+
+	t.Run("when the nested bundle version does not satisfy the requirement, push fails", func(t *testing.T) {
+		contents := bundle.NewContents([]string{createBundleDir(t, assets, platformYAML, []string{loggingV11})}, nil, false)
+		err := contents.ValidateDependencies(reg, util.NewNoopLevelLogger())
+		require.Error(t, err)
+
+		depErr, ok := err.(bundle.DependencyError)
+		require.True(t, ok, "expected a bundle.DependencyError but got: %s", err)
+		require.Len(t, depErr.Violations, 1)
+		assert.Equal(t, "logging-bundle", depErr.Violations[0].Requirement.Name)
+		assert.Contains(t, depErr.Violations[0].Reason, "has version '1.1.0'")
+		assert.Contains(t, err.Error(), "requires 'logging-bundle >=1.2.0, <2.0.0'")
+	})
+
+	t.Run("when the required bundle is not nested, push fails", func(t *testing.T) {
+		contents := bundle.NewContents([]string{createBundleDir(t, assets, platformYAML, nil)}, nil, false)
+		err := contents.ValidateDependencies(reg, util.NewNoopLevelLogger())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Not found in the nested bundles")
+	})
+
+	t.Run("when the bundle metadata has an invalid constraint, push fails", func(t *testing.T) {
+		invalidYAML := bundleYAML("platform-bundle", "2.0.0") + `requires:
+- name: logging-bundle
+  version: ">=one"
+`
+		contents := bundle.NewContents([]string{createBundleDir(t, assets, invalidYAML, nil)}, nil, false)
+		err := contents.ValidateDependencies(reg, util.NewNoopLevelLogger())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "requires[0].version")
+	})
+
+	t.Run("when a pushed bundle has a nested bundle that does not satisfy the requirement, it returns an error", func(t *testing.T) {
+		platform := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("platform:2.0.0"), platformYAML, []string{loggingV11})
+
+		lockReader := bundle.NewImagesLockReader()
+		subject := bundle.NewBundleFromRef(platform, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		allBundles, _, err := subject.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+
+		err = bundle.ValidateDependencies(allBundles)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("- %s: requires 'logging-bundle >=1.2.0, <2.0.0': Nested bundle '%s' has version '1.1.0'", platform, loggingV11))
+	})
+
+	t.Run("when a pushed bundle satisfies the requirements, it succeeds", func(t *testing.T) {
+		platform := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("platform:2.0.1"), platformYAML, []string{observability})
+
+		lockReader := bundle.NewImagesLockReader()
+		subject := bundle.NewBundleFromRef(platform, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		allBundles, _, err := subject.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+
+		require.NoError(t, bundle.ValidateDependencies(allBundles))
+	})
+}
+
+func TestNewMetadataFromBytes(t *testing.T) {
+	t.Run("when the versions are not quoted, it reads them as they are written", func(t *testing.T) {
+		metadata, err := bundle.NewMetadataFromBytes([]byte(bundleYAML("platform-bundle", "1.10") + `requires:
+- name: logging-bundle
+  version: 1.20
+`))
+		require.NoError(t, err)
+		assert.Equal(t, "1.10", metadata.Metadata.Version)
+		assert.Equal(t, "1.20", metadata.Requires[0].Version)
+	})
+
+	t.Run("when the bundle does not have requirements, it does not validate its version", func(t *testing.T) {
+		metadata, err := bundle.NewMetadataFromBytes([]byte(bundleYAML("logging-bundle", "dev")))
+		require.NoError(t, err)
+		assert.Equal(t, "dev", metadata.Metadata.Version)
+	})
+
+	t.Run("when the bundle does not have requirements, it accepts the free-form metadata pushed before them", func(t *testing.T) {
+		_, err := bundle.NewMetadataFromBytes([]byte("metadata: logging-bundle\nversion: [1, 2]\n"))
+		require.NoError(t, err)
+	})
+}
+
+func TestContentsPushMetadataLabels(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+
+	t.Run("when the bundle does not have requirements, it only has the bundle label, so its digest does not change", func(t *testing.T) {
+		digestRef := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("logging:dev"), bundleYAML("logging-bundle", "dev"), nil)
+		assert.Equal(t, map[string]string{bundle.BundleConfigLabel: "true"}, configLabels(t, reg, digestRef))
+	})
+
+	t.Run("when the bundle has requirements, it records its metadata in the labels", func(t *testing.T) {
+		logging := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("logging:1.3.0"), bundleYAML("logging-bundle", "1.3.0"), nil)
+		digestRef := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("platform:2.0.0"), bundleYAML("platform-bundle", "2.0.0")+`requires:
+- name: logging-bundle
+`, []string{logging})
+		assert.Equal(t, map[string]string{
+			bundle.BundleConfigLabel:   "true",
+			bundle.BundleNameLabel:     "platform-bundle",
+			bundle.BundleVersionLabel:  "2.0.0",
+			bundle.BundleRequiresLabel: `[{"name":"logging-bundle"}]`,
+		}, configLabels(t, reg, digestRef))
+	})
+}
+
+func configLabels(t *testing.T, reg registry.Registry, digestRef string) map[string]string {
+	ref, err := name.ParseReference(digestRef)
+	require.NoError(t, err)
+	img, err := reg.Image(ref)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	return cfg.Config.Labels
+}
+
+func bundleYAML(name, version string) string {
+	return fmt.Sprintf(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Bundle
+metadata:
+  name: %s
+  version: %s
+`, name, version)
+}
+
+func createBundleDir(t *testing.T, assets *helpers.Assets, bundleYAML string, images []string) string {
+	imagesLockYAML := `---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images: []
+`
+	if len(images) > 0 {
+		imagesLockYAML = strings.TrimSuffix(imagesLockYAML, " []\n") + "\n"
+		for _, image := range images {
+			imagesLockYAML += fmt.Sprintf("- image: %s\n", image)
+		}
+	}
+
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	return bundleBuilder.CreateBundleDir(bundleYAML, imagesLockYAML)
+}
+
+func pushBundle(t *testing.T, assets *helpers.Assets, reg registry.Registry, ref string, bundleYAML string, images []string) string {
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+
+	contents := bundle.NewContents([]string{createBundleDir(t, assets, bundleYAML, images)}, nil, false)
+	digestRef, err := contents.Push(tag, nil, reg, util.NewNoopLevelLogger())
+	require.NoError(t, err)
+	return digestRef
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	deprecationTagFmt string = "%s-%s.deprecated.imgpkg"
+
+	// BundleDeprecatedLabel Label of the deprecation image with the deprecation of the bundle, encoded as JSON
+	BundleDeprecatedLabel = "dev.carvel.imgpkg.bundle.deprecated"
+)
+
+// Deprecation Marks a bundle version as deprecated, so that copies and describes warn about it
+type Deprecation struct {
+	// Message Why the bundle should not be used anymore
+	Message string `json:"message,omitempty"`
+	// Successor Version or location of the bundle that replaces this one
+	Successor string `json:"successor,omitempty"`
+}
+
+// DeprecatedBundle Bundle that is marked as deprecated
+type DeprecatedBundle struct {
+	// Bundle location of the bundle, including the digest
+	Bundle     string
+	Identity   MetadataIdentity
+	Deprecated Deprecation
+}
+
+// String Describes the deprecation and where to find the successor
+func (d DeprecatedBundle) String() string {
+	name := d.Bundle
+	if d.Identity.Name != "" {
+		name = fmt.Sprintf("%s (%s", d.Bundle, d.Identity.Name)
+		if d.Identity.Version != "" {
+			name += " " + d.Identity.Version
+		}
+		name += ")"
+	}
+
+	msg := fmt.Sprintf("Bundle %s is deprecated", name)
+	if d.Deprecated.Message != "" {
+		msg += ": " + d.Deprecated.Message
+	}
+	if d.Deprecated.Successor != "" {
+		msg += fmt.Sprintf(" (successor: %s)", d.Deprecated.Successor)
+	}
+	return msg
+}
+
+// DeprecationError Returned when deprecated bundles are not allowed and one or more were found
+type DeprecationError struct {
+	Bundles []DeprecatedBundle
+}
+
+// Error Lists the deprecated bundles
+func (d DeprecationError) Error() string {
+	var lines []string
+	for _, deprecated := range d.Bundles {
+		lines = append(lines, "- "+deprecated.String())
+	}
+	return fmt.Sprintf("Found deprecated bundles:\n%s\n(hint: use the successor of each bundle, or remove --fail-on-deprecated to continue anyway)",
+		strings.Join(lines, "\n"))
+}
+
+// DeprecatedBundles Returns the bundles that are marked as deprecated, in the order they were provided.
+// Bundles that are present more than once are only returned once
+func DeprecatedBundles(bundles []*Bundle) ([]DeprecatedBundle, error) {
+	var deprecated []DeprecatedBundle
+	processed := map[string]bool{}
+	for _, bundle := range bundles {
+		if processed[bundle.Digest()] {
+			continue
+		}
+		processed[bundle.Digest()] = true
+
+		deprecation, err := bundle.Deprecation()
+		if err != nil {
+			return nil, err
+		}
+		if deprecation == nil {
+			continue
+		}
+
+		img, err := bundle.checkedImage()
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("Reading config of bundle '%s': %s", bundle.DigestRef(), err)
+		}
+		metadata, err := NewMetadataFromLabels(cfg.Config.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("Reading metadata of bundle '%s': %s", bundle.DigestRef(), err)
+		}
+
+		deprecated = append(deprecated, DeprecatedBundle{
+			Bundle:     bundle.DigestRef(),
+			Identity:   metadata.Metadata,
+			Deprecated: *deprecation,
+		})
+	}
+	return deprecated, nil
+}
+
+// Deprecation Retrieves the deprecation of the bundle, nil when the bundle is not deprecated.
+// The deprecation is kept in the bundle so that it can be copied with the bundle
+func (o *Bundle) Deprecation() (*Deprecation, error) {
+	if o.deprecationFetched {
+		return o.deprecation, nil
+	}
+
+	bundleRef, err := name.NewDigest(o.DigestRef())
+	if err != nil {
+		return nil, err
+	}
+	deprecation, err := FetchDeprecation(o.imgRetriever, bundleRef)
+	if err != nil {
+		return nil, err
+	}
+	o.deprecation = deprecation
+	o.deprecationFetched = true
+	return deprecation, nil
+}
+
+// FetchDeprecation Retrieves the deprecation of the bundle from the deprecation image tagged next to it,
+// nil when the bundle is not deprecated
+func FetchDeprecation(reg ImagesMetadata, bundleRef name.Digest) (*Deprecation, error) {
+	depRef, err := deprecationRefFromBundleRef(bundleRef)
+	if err != nil {
+		return nil, fmt.Errorf("Calculating deprecation image tag: %s", err)
+	}
+
+	img, err := reg.Image(depRef)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) {
+			if _, ok := imageNotFoundStatusCode[terr.StatusCode]; ok {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("Fetching deprecation image '%s': %s", depRef.Name(), err)
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("Reading config of deprecation image '%s': %s", depRef.Name(), err)
+	}
+	// A deprecation image without the label is left behind when the deprecation is withdrawn
+	label := cfg.Config.Labels[BundleDeprecatedLabel]
+	if label == "" {
+		return nil, nil
+	}
+
+	deprecation := &Deprecation{}
+	err = json.Unmarshal([]byte(label), deprecation)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshaling label '%s' of deprecation image '%s': %s", BundleDeprecatedLabel, depRef.Name(), err)
+	}
+	return deprecation, nil
+}
+
+// SaveDeprecation Marks the bundle as deprecated by tagging a deprecation image next to it. The bundle itself is not modified,
+// so bundles that were already published can be deprecated. When deprecation is nil the deprecation is withdrawn
+func SaveDeprecation(reg ImagesMetadataWriter, bundleRef name.Digest, deprecation *Deprecation) error {
+	depRef, err := deprecationRefFromBundleRef(bundleRef)
+	if err != nil {
+		return fmt.Errorf("Calculating deprecation image tag: %s", err)
+	}
+
+	labels := map[string]string{}
+	if deprecation != nil {
+		encoded, err := json.Marshal(deprecation)
+		if err != nil {
+			return fmt.Errorf("Marshaling bundle deprecation: %s", err)
+		}
+		labels[BundleDeprecatedLabel] = string(encoded)
+	}
+
+	img, err := mutate.ConfigFile(empty.Image, &regv1.ConfigFile{
+		Architecture: "amd64",
+		OS:           "linux",
+		Config:       regv1.Config{Labels: labels},
+	})
+	if err != nil {
+		return fmt.Errorf("Creating deprecation image: %s", err)
+	}
+
+	err = reg.WriteImage(depRef, img, nil)
+	if err != nil {
+		return fmt.Errorf("Pushing deprecation image to '%s': %s", depRef.Name(), err)
+	}
+	return nil
+}
+
+func deprecationRefFromBundleRef(bundleRef name.Digest) (name.Tag, error) {
+	hash, err := regv1.NewHash(bundleRef.DigestStr())
+	if err != nil {
+		return name.Tag{}, err
+	}
+	return bundleRef.Context().Tag(fmt.Sprintf(deprecationTagFmt, hash.Algorithm, hash.Hex)), nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecatedBundles(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+
+	logging := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("logging:1.1.0"), bundleYAML("logging-bundle", "1.1.0"), nil)
+
+	t.Run("when a nested bundle is deprecated after it was pushed, it is returned once", func(t *testing.T) {
+		loggingRef, err := name.NewDigest(logging)
+		require.NoError(t, err)
+		require.NoError(t, bundle.SaveDeprecation(reg, loggingRef, &bundle.Deprecation{Message: "Leaks credentials in the logs", Successor: "logging:1.1.1"}))
+		defer func() {
+			require.NoError(t, bundle.SaveDeprecation(reg, loggingRef, nil))
+		}()
+
+		platform := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("platform:2.0.0"), bundleYAML("platform-bundle", "2.0.0"), []string{logging})
+
+		lockReader := bundle.NewImagesLockReader()
+		subject := bundle.NewBundleFromRef(platform, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		allBundles, _, err := subject.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+
+		deprecated, err := bundle.DeprecatedBundles(append(allBundles, allBundles...))
+		require.NoError(t, err)
+		require.Len(t, deprecated, 1)
+		assert.Equal(t, logging, deprecated[0].Bundle)
+		assert.Equal(t, bundle.Deprecation{Message: "Leaks credentials in the logs", Successor: "logging:1.1.1"}, deprecated[0].Deprecated)
+		assert.Equal(t, "Bundle "+logging+" is deprecated: Leaks credentials in the logs (successor: logging:1.1.1)", deprecated[0].String())
+
+		err = bundle.DeprecationError{Bundles: deprecated}
+		assert.Contains(t, err.Error(), "- Bundle "+logging+" is deprecated")
+	})
+
+	t.Run("when the deprecation was withdrawn, it returns nothing", func(t *testing.T) {
+		loggingRef, err := name.NewDigest(logging)
+		require.NoError(t, err)
+		require.NoError(t, bundle.SaveDeprecation(reg, loggingRef, &bundle.Deprecation{Message: "Leaks credentials in the logs"}))
+		require.NoError(t, bundle.SaveDeprecation(reg, loggingRef, nil))
+
+		platform := pushBundle(t, assets, reg, fakeRegistry.ReferenceOnTestServer("platform:2.0.1"), bundleYAML("platform-bundle", "2.0.1"), []string{logging})
+
+		lockReader := bundle.NewImagesLockReader()
+		subject := bundle.NewBundleFromRef(platform, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		allBundles, _, err := subject.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+
+		deprecated, err := bundle.DeprecatedBundles(allBundles)
+		require.NoError(t, err)
+		assert.Empty(t, deprecated)
+	})
+}
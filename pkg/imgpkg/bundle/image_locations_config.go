@@ -26,6 +26,10 @@ type ImageLocationsConfig struct {
 type ImageLocation struct {
 	Image    string `json:"image"`    // This generated yaml, but due to lib we need to use `json`
 	IsBundle bool   `json:"isBundle"` // This generated yaml, but due to lib we need to use `json`
+	// Repository where the image was copied to, when it is not the repository of the bundle
+	Repository string `json:"repository,omitempty"`
+	// Excluded the image was not copied on purpose, it is only available in its original location
+	Excluded bool `json:"excluded,omitempty"`
 }
 
 func NewLocationConfigFromPath(path string) (ImageLocationsConfig, error) {
@@ -4,6 +4,7 @@
 package bundle
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -127,7 +128,28 @@ func (i *ImageRefs) LocalizeToRepo(relativeToRepo string) {
 	i.refsLock.Lock()
 	defer i.refsLock.Unlock()
 
+	imageRepos := map[string]string{}
+	excludedImages := map[string]bool{}
+	if i.imageLocationsConfig != nil {
+		for _, imgLoc := range i.imageLocationsConfig.Images {
+			if imgLoc.Repository != "" {
+				imageRepos[imgLoc.Image] = imgLoc.Repository
+			}
+			if imgLoc.Excluded {
+				excludedImages[imgLoc.Image] = true
+			}
+		}
+	}
+
 	for j, imgRef := range i.refs {
+		// Excluded images were not copied, so they are only found in their original location
+		if excludedImages[imgRef.Image] {
+			continue
+		}
+		if repo, found := imageRepos[imgRef.Image]; found {
+			i.refs[j].AddLocation(replaceImageRepo(imgRef.Image, repo))
+			continue
+		}
 		i.refs[j].AddLocation(replaceImageRepo(imgRef.Image, relativeToRepo))
 	}
 }
@@ -145,7 +167,8 @@ func (i *ImageRefs) UpdateRelativeToRepo(imgRetriever ImagesMetadata, relativeTo
 		}
 		_, err = imgRetriever.Digest(image)
 		if err != nil {
-			if terr, ok := err.(*transport.Error); ok {
+			var terr *transport.Error
+			if errors.As(err, &terr) {
 				if i.imageIsNotFound(terr) {
 					return false, nil
 				}
@@ -215,9 +238,18 @@ func (i ImageRefs) ImagesLock() lockconfig.ImagesLock {
 			panic(fmt.Errorf("Internal inconsistency: '%s' could not be found", originalImg.Image))
 		}
 
+		annotations := originalImg.Annotations
+		if i.isExcluded(originalImg.Image) {
+			annotations = map[string]string{}
+			for key, value := range originalImg.Annotations {
+				annotations[key] = value
+			}
+			annotations[lockconfig.ImageRefExcludedAnnotationKey] = "true"
+		}
+
 		imgLock.Images = append(imgLock.Images, lockconfig.ImageRef{
 			Image:       ref.PrimaryLocation(),
-			Annotations: originalImg.Annotations,
+			Annotations: annotations,
 		})
 	}
 
@@ -232,6 +264,19 @@ func (i ImageRefs) ImageRefs() []ImageRef {
 	return refsCopy
 }
 
+// isExcluded Returns true when the image was excluded from the copy of the bundle
+func (i ImageRefs) isExcluded(image string) bool {
+	if i.imageLocationsConfig == nil {
+		return false
+	}
+	for _, imgLoc := range i.imageLocationsConfig.Images {
+		if imgLoc.Image == image {
+			return imgLoc.Excluded
+		}
+	}
+	return false
+}
+
 func (i *ImageRefs) imageIsNotFound(terr *transport.Error) bool {
 	_, ok := imageNotFoundStatusCode[terr.StatusCode]
 	return ok
@@ -5,6 +5,7 @@ package bundle
 
 import (
 	"archive/tar"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -68,7 +69,8 @@ func (r *LocationsConfigs) Fetch(registry ImagesMetadata, bundleRef name.Digest)
 
 	img, err := registry.Image(locRef)
 	if err != nil {
-		if terr, ok := err.(*transport.Error); ok {
+		var terr *transport.Error
+		if errors.As(err, &terr) {
 			if _, ok := imageNotFoundStatusCode[terr.StatusCode]; ok {
 				r.ui.Debugf("Did not find Locations OCI Image for bundle: %s\n", bundleRef)
 				return ImageLocationsConfig{}, &LocationsNotFound{image: locRef.Name()}
@@ -98,7 +100,8 @@ func (r LocationsConfigs) LocationsImageDigest(registry ImagesMetadata, bundleRe
 
 	digest, err := registry.Digest(locRef)
 	if err != nil {
-		if terr, ok := err.(*transport.Error); ok {
+		var terr *transport.Error
+		if errors.As(err, &terr) {
 			if _, ok := imageNotFoundStatusCode[terr.StatusCode]; ok {
 				r.ui.Debugf("Did not find Locations OCI Image for bundle: %s\n", bundleRef)
 				return name.Digest{}, &LocationsNotFound{image: locRef.Name()}
@@ -194,8 +197,8 @@ func (o *locationsSingleLayerReader) Read(img regv1.Image) (ImageLocationsConfig
 		return conf, err
 	}
 
-	if mediaType != types.DockerLayer {
-		return conf, fmt.Errorf("Expected layer to have docker layer media type, was %s", mediaType)
+	if mediaType != types.DockerLayer && mediaType != types.OCILayer {
+		return conf, fmt.Errorf("Expected layer to have docker or OCI gzip layer media type, was %s", mediaType)
 	}
 
 	// here we know layer is .tgz so decompress and read tar headers
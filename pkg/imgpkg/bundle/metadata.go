@@ -0,0 +1,252 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MetadataFile Optional file in the .imgpkg folder that identifies the bundle and declares its dependencies
+	MetadataFile       = "bundle.yml"
+	MetadataKind       = "Bundle"
+	MetadataAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+
+	// BundleNameLabel Label of the bundle image with the name present in the bundle metadata
+	BundleNameLabel = "dev.carvel.imgpkg.bundle.name"
+	// BundleVersionLabel Label of the bundle image with the version present in the bundle metadata
+	BundleVersionLabel = "dev.carvel.imgpkg.bundle.version"
+	// BundleRequiresLabel Label of the bundle image with the requirements present in the bundle metadata, encoded as JSON
+	BundleRequiresLabel = "dev.carvel.imgpkg.bundle.requires"
+)
+
+// Metadata Contents of the .imgpkg/bundle.yml file
+//
+//	apiVersion: imgpkg.carvel.dev/v1alpha1
+//	kind: Bundle
+//	metadata:
+//	  name: platform-bundle
+//	  version: 2.1.0
+//	requires:
+//	- name: logging-bundle
+//	  version: ">=1.2.0, <2.0.0"
+type Metadata struct {
+	APIVersion string              `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
+	Kind       string              `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
+	Metadata   MetadataIdentity    `json:"metadata"`
+	Authors    []MetadataAuthor    `json:"authors,omitempty"`
+	Websites   []MetadataWebsite   `json:"websites,omitempty"`
+	Requires   []BundleRequirement `json:"requires,omitempty"`
+}
+
+// MetadataAuthor Author of a bundle
+type MetadataAuthor struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// MetadataWebsite URL where more information about a bundle can be found
+type MetadataWebsite struct {
+	URL string `json:"url,omitempty"`
+}
+
+// MetadataIdentity Name and version of a bundle
+type MetadataIdentity struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// BundleRequirement Nested bundle that must be present with a version matching the constraint
+type BundleRequirement struct {
+	Name string `json:"name"`
+	// Version constraint, for example ">=1.2.0, <2.0.0". When empty any version is accepted
+	Version string `json:"version,omitempty"`
+}
+
+// NewMetadataFromPath Reads and validates the bundle metadata present in path
+func NewMetadataFromPath(path string) (Metadata, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	metadata, err := NewMetadataFromBytes(bs)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("Validating bundle metadata '%s': %s", path, err)
+	}
+	return metadata, nil
+}
+
+// NewMetadataFromBytes Parses and validates the bundle metadata
+func NewMetadataFromBytes(data []byte) (Metadata, error) {
+	var metadata Metadata
+
+	// bundle.yml was free-form before requirements were introduced, it is only validated when it declares
+	// requirements, to keep accepting the bundles that were already pushed
+	err := yaml.Unmarshal(data, &metadata)
+	if err != nil {
+		var requirements struct {
+			Requires interface{} `yaml:"requires"`
+		}
+		if yamlv3.Unmarshal(data, &requirements) == nil && requirements.Requires == nil {
+			return Metadata{}, nil
+		}
+		return metadata, fmt.Errorf("Unmarshaling bundle metadata: %s", err)
+	}
+
+	// the versions are read as they are written, an unquoted 1.10 would otherwise be read as the number 1.1
+	var versions struct {
+		Metadata struct {
+			Version string `yaml:"version"`
+		} `yaml:"metadata"`
+		Requires []struct {
+			Version string `yaml:"version"`
+		} `yaml:"requires"`
+	}
+	if yamlv3.Unmarshal(data, &versions) == nil {
+		metadata.Metadata.Version = versions.Metadata.Version
+		for idx := range metadata.Requires {
+			if idx < len(versions.Requires) {
+				metadata.Requires[idx].Version = versions.Requires[idx].Version
+			}
+		}
+	}
+
+	return metadata, metadata.Validate()
+}
+
+// NewMetadataFromLabels Retrieves the bundle metadata recorded in the labels of the bundle image
+func NewMetadataFromLabels(labels map[string]string) (Metadata, error) {
+	metadata := Metadata{
+		APIVersion: MetadataAPIVersion,
+		Kind:       MetadataKind,
+		Metadata: MetadataIdentity{
+			Name:    labels[BundleNameLabel],
+			Version: labels[BundleVersionLabel],
+		},
+	}
+
+	if requires := labels[BundleRequiresLabel]; requires != "" {
+		err := json.Unmarshal([]byte(requires), &metadata.Requires)
+		if err != nil {
+			return metadata, fmt.Errorf("Unmarshaling label '%s': %s", BundleRequiresLabel, err)
+		}
+	}
+
+	return metadata, metadata.Validate()
+}
+
+// Validate Checks that the requirements can be parsed. The version of the bundle is only checked when a bundle
+// requires it, so that bundles with versions such as dev can still be pushed
+func (m Metadata) Validate() error {
+	var errs []string
+	for idx, requirement := range m.Requires {
+		if requirement.Name == "" {
+			errs = append(errs, fmt.Sprintf("requires[%d].name: Expected to be non-empty", idx))
+		}
+		if _, err := NewVersionConstraint(requirement.Version); err != nil {
+			errs = append(errs, fmt.Sprintf("requires[%d].version: %s", idx, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Labels Returns the labels used to record the metadata in the bundle image, so it can be read without
+// downloading the contents of the bundle. The name and version are only recorded when the bundle has requirements,
+// so that the other bundles keep the same digest
+func (m Metadata) Labels() (map[string]string, error) {
+	labels := map[string]string{}
+	recordIdentity := len(m.Requires) > 0
+	if recordIdentity && m.Metadata.Name != "" {
+		labels[BundleNameLabel] = m.Metadata.Name
+	}
+	if recordIdentity && m.Metadata.Version != "" {
+		labels[BundleVersionLabel] = m.Metadata.Version
+	}
+	if len(m.Requires) > 0 {
+		requires, err := json.Marshal(m.Requires)
+		if err != nil {
+			return nil, fmt.Errorf("Marshaling bundle requirements: %s", err)
+		}
+		labels[BundleRequiresLabel] = string(requires)
+	}
+	return labels, nil
+}
+
+// metadata Reads the .imgpkg/bundle.yml file, returns false when the bundle does not provide it
+func (b Contents) metadata() (Metadata, bool, error) {
+	imgpkgDirs, err := b.findImgpkgDirs()
+	if err != nil {
+		return Metadata{}, false, err
+	}
+	err = b.validateImgpkgDirs(imgpkgDirs)
+	if err != nil {
+		return Metadata{}, false, err
+	}
+
+	path := filepath.Join(imgpkgDirs[0], MetadataFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Metadata{}, false, nil
+	}
+
+	metadata, err := NewMetadataFromPath(path)
+	if err != nil {
+		return Metadata{}, false, err
+	}
+	return metadata, true, nil
+}
+
+// metadataFromImage Reads the .imgpkg/bundle.yml file from the first layer of the bundle image, where the bundle
+// metadata is kept, returns an empty Metadata when the bundle does not provide it
+func metadataFromImage(img regv1.Image) (Metadata, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return Metadata{}, err
+	}
+	if len(layers) == 0 {
+		return Metadata{}, fmt.Errorf("Expected bundle to have at least one layer")
+	}
+
+	contents, err := layers[0].Uncompressed()
+	if err != nil {
+		return Metadata{}, fmt.Errorf("Could not read bundle image layer contents: %s", err)
+	}
+	defer contents.Close()
+
+	tarReader := tar.NewReader(contents)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return Metadata{}, nil
+		}
+		if err != nil {
+			return Metadata{}, fmt.Errorf("Reading tar: %s", err)
+		}
+		if path.Clean(header.Name) != ImgpkgDir+"/"+MetadataFile {
+			continue
+		}
+
+		bs, err := io.ReadAll(tarReader)
+		if err != nil {
+			return Metadata{}, fmt.Errorf("Reading %s from layer: %s", MetadataFile, err)
+		}
+		return NewMetadataFromBytes(bs)
+	}
+}
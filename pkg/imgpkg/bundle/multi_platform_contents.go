@@ -0,0 +1,106 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"time"
+
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// MultiPlatformContents Contents of a multi-arch bundle, an OCI Image Index with a bundle per platform
+type MultiPlatformContents struct {
+	platforms           []plainimage.PlatformContents
+	excludedPaths       []string
+	preservePermissions bool
+	compression         ctlimg.LayerCompression
+	created             time.Time
+	annotations         map[string]string
+	fileFilter          ctlimg.FileFilter
+	fileAttributes      ctlimg.FileAttributes
+	layerSplit          ctlimg.LayerSplit
+}
+
+// NewMultiPlatformContents creates MultiPlatformContents struct
+func NewMultiPlatformContents(platforms []plainimage.PlatformContents, excludedPaths []string, preservePermissions bool) MultiPlatformContents {
+	return MultiPlatformContents{platforms: platforms, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
+}
+
+// WithCompression Compresses the layers of the bundle images with compression instead of gzip
+func (m MultiPlatformContents) WithCompression(compression ctlimg.LayerCompression) MultiPlatformContents {
+	m.compression = compression
+	return m
+}
+
+// WithCreated Records created as the creation time of the bundle images and as the modification time of its files
+func (m MultiPlatformContents) WithCreated(created time.Time) MultiPlatformContents {
+	m.created = created
+	return m
+}
+
+// WithAnnotations Adds annotations to the image index and to the manifest of each bundle
+func (m MultiPlatformContents) WithAnnotations(annotations map[string]string) MultiPlatformContents {
+	m.annotations = annotations
+	return m
+}
+
+// WithFileFilter Adds the contents returned by fileFilter, instead of the contents on disk, for each file of the bundle images
+func (m MultiPlatformContents) WithFileFilter(fileFilter ctlimg.FileFilter) MultiPlatformContents {
+	m.fileFilter = fileFilter
+	return m
+}
+
+// WithFileAttributes Records the owner and the permissions of fileAttributes for the files and directories of the bundle images
+func (m MultiPlatformContents) WithFileAttributes(fileAttributes ctlimg.FileAttributes) MultiPlatformContents {
+	m.fileAttributes = fileAttributes
+	return m
+}
+
+// WithLayerSplit Splits the files of the bundle images into the layers of layerSplit instead of a single layer
+func (m MultiPlatformContents) WithLayerSplit(layerSplit ctlimg.LayerSplit) MultiPlatformContents {
+	m.layerSplit = layerSplit
+	return m
+}
+
+// PlatformBundles Contents of the bundle of each platform
+func (m MultiPlatformContents) PlatformBundles() []Contents {
+	var bundles []Contents
+	for _, platform := range m.platforms {
+		bundles = append(bundles, NewContents(platform.Paths, m.excludedPaths, m.preservePermissions))
+	}
+	return bundles
+}
+
+// Push the bundle of each platform to the registry, as the OCI Images of an OCI Image Index
+// annotated with BundleConfigLabel
+func (m MultiPlatformContents) Push(uploadRef regname.Tag, labels map[string]string, registry plainimage.IndexWriter, logger Logger) (string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[BundleConfigLabel] = "true"
+
+	var platforms []plainimage.PlatformContents
+	for i, contents := range m.PlatformBundles() {
+		platform := m.platforms[i]
+		err := contents.validate()
+		if err != nil {
+			return "", fmt.Errorf("Validating bundle of platform '%s': %s", platform.Platform.String(), err)
+		}
+
+		metadataLabels, err := contents.metadataLabels()
+		if err != nil {
+			return "", fmt.Errorf("Reading bundle metadata of platform '%s': %s", platform.Platform.String(), err)
+		}
+		for key, value := range platform.Labels {
+			metadataLabels[key] = value
+		}
+		platform.Labels = metadataLabels
+		platforms = append(platforms, platform)
+	}
+
+	return plainimage.NewMultiPlatformContents(platforms, m.excludedPaths, m.preservePermissions).WithCompression(m.compression).WithCreated(m.created).WithAnnotations(m.annotations).WithIndexAnnotations(map[string]string{BundleConfigLabel: "true"}).WithFileFilter(m.fileFilter).WithFileAttributes(m.fileAttributes).WithLayerSplit(m.layerSplit).Push(uploadRef, labels, registry, logger)
+}
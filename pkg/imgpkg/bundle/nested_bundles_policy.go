@@ -0,0 +1,154 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// NestedBundlesPolicy Rules that the images referenced by a bundle, and by all of its nested bundles, must follow
+type NestedBundlesPolicy struct {
+	// AllowedRegistries Registries where the images can be stored, wildcards are supported (example: *.example.com).
+	// When empty any registry is allowed
+	AllowedRegistries []string
+}
+
+// NestedBundlesPolicyViolation Image that does not comply with the NestedBundlesPolicy
+type NestedBundlesPolicyViolation struct {
+	// BundlePath Chain of bundles, starting in the bundle being validated, that reference the image
+	BundlePath []string
+	Image      string
+	Reason     string
+}
+
+// NestedBundlesPolicyError Returned when one or more images do not comply with the NestedBundlesPolicy
+type NestedBundlesPolicyError struct {
+	Violations []NestedBundlesPolicyViolation
+}
+
+// Error Lists all the violations found
+func (n NestedBundlesPolicyError) Error() string {
+	var lines []string
+	for _, violation := range n.Violations {
+		lines = append(lines, fmt.Sprintf("- %s: image '%s': %s", strings.Join(violation.BundlePath, " -> "), violation.Image, violation.Reason))
+	}
+	return fmt.Sprintf("Validating nested bundles:\n%s", strings.Join(lines, "\n"))
+}
+
+// ValidateNestedBundles Verifies that the ImagesLock of the bundle, and recursively the ImagesLock of every nested bundle,
+// only reference images by digest and that these images are stored in the allowed registries
+func (b Contents) ValidateNestedBundles(policy NestedBundlesPolicy, imagesMetadata ImagesMetadata) error {
+	imgpkgDirs, err := b.findImgpkgDirs()
+	if err != nil {
+		return err
+	}
+	err = b.validateImgpkgDirs(imgpkgDirs)
+	if err != nil {
+		return err
+	}
+
+	imagesLockPath := filepath.Join(imgpkgDirs[0], ImagesLockFile)
+	imagesLock, err := b.imagesLock(imgpkgDirs[0])
+	if err != nil {
+		return err
+	}
+
+	validator := nestedBundlesValidator{
+		policy:           policy,
+		imagesMetadata:   imagesMetadata,
+		imagesLockReader: NewImagesLockReader(),
+		visited:          map[string]bool{},
+	}
+	err = validator.validate([]string{imagesLockPath}, imagesLock)
+	if err != nil {
+		return err
+	}
+
+	if len(validator.violations) > 0 {
+		return NestedBundlesPolicyError{Violations: validator.violations}
+	}
+	return nil
+}
+
+type nestedBundlesValidator struct {
+	policy           NestedBundlesPolicy
+	imagesMetadata   ImagesMetadata
+	imagesLockReader ImagesLockReader
+
+	visited    map[string]bool
+	violations []NestedBundlesPolicyViolation
+}
+
+func (v *nestedBundlesValidator) validate(bundlePath []string, imagesLock lockconfig.ImagesLock) error {
+	for _, image := range imagesLock.Images {
+		ref, err := regname.NewDigest(image.Image)
+		if err != nil {
+			v.addViolation(bundlePath, image.Image, "Expected ref to be in digest form")
+			continue
+		}
+
+		if !v.policy.allowsRegistry(ref.Context().RegistryStr()) {
+			v.addViolation(bundlePath, image.Image, fmt.Sprintf("Registry '%s' is not one of the allowed registries (%s)",
+				ref.Context().RegistryStr(), strings.Join(v.policy.AllowedRegistries, ", ")))
+			continue
+		}
+
+		if v.visited[ref.Name()] {
+			continue
+		}
+		v.visited[ref.Name()] = true
+
+		img, err := v.imagesMetadata.Image(ref)
+		if err != nil {
+			return fmt.Errorf("Fetching image '%s' referenced in %s: %s", image.Image, strings.Join(bundlePath, " -> "), err)
+		}
+
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("Fetching image '%s' referenced in %s: %s", image.Image, strings.Join(bundlePath, " -> "), err)
+		}
+		if _, isBundle := cfg.Config.Labels[BundleConfigLabel]; !isBundle {
+			continue
+		}
+
+		nestedImagesLock, err := v.imagesLockReader.Read(img)
+		if err != nil {
+			v.addViolation(bundlePath, image.Image, fmt.Sprintf("Invalid ImagesLock in nested bundle: %s", err))
+			continue
+		}
+
+		err = v.validate(append(append([]string{}, bundlePath...), image.Image), nestedImagesLock)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *nestedBundlesValidator) addViolation(bundlePath []string, image string, reason string) {
+	v.violations = append(v.violations, NestedBundlesPolicyViolation{
+		BundlePath: append([]string{}, bundlePath...),
+		Image:      image,
+		Reason:     reason,
+	})
+}
+
+func (p NestedBundlesPolicy) allowsRegistry(registry string) bool {
+	if len(p.AllowedRegistries) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.AllowedRegistries {
+		if matched, err := path.Match(allowed, registry); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentsValidateNestedBundles(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	imagesLockYAML := `---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+`
+
+	t.Run("when all nested bundles reference images by digest, it succeeds", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, logger)
+		defer fakeRegistry.CleanUp()
+		image := fakeRegistry.WithRandomImage("library/image")
+		nestedBundle := fakeRegistry.WithRandomBundle("library/nested-bundle").WithImageRefs([]lockconfig.ImageRef{{Image: image.RefDigest}})
+		reg := fakeRegistry.Build()
+
+		assets := &helpers.Assets{T: t}
+		defer assets.CleanCreatedFolders()
+		bundleDirBuilder := helpers.NewBundleDir(t, assets)
+		bundleDir := bundleDirBuilder.CreateBundleDir(helpers.BundleYAML, fmt.Sprintf(imagesLockYAML, nestedBundle.RefDigest))
+
+		subject := bundle.NewContents([]string{bundleDir}, nil, false)
+		require.NoError(t, subject.ValidateNestedBundles(bundle.NestedBundlesPolicy{}, reg))
+		require.NoError(t, subject.ValidateNestedBundles(bundle.NestedBundlesPolicy{AllowedRegistries: []string{fakeRegistry.Host()}}, reg))
+	})
+
+	t.Run("when a nested bundle references an image by tag, it fails with the path to the nested bundle", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, logger)
+		defer fakeRegistry.CleanUp()
+
+		assets := &helpers.Assets{T: t}
+		defer assets.CleanCreatedFolders()
+		bundleDirBuilder := helpers.NewBundleDir(t, assets)
+		innerBundleDir := bundleDirBuilder.CreateBundleDir(helpers.BundleYAML, fmt.Sprintf(imagesLockYAML, "some.registry.io/image:1.0.0"))
+		innerBundle := fakeRegistry.WithBundleFromPath("library/inner-bundle", innerBundleDir)
+		outerBundle := fakeRegistry.WithRandomBundle("library/outer-bundle").WithImageRefs([]lockconfig.ImageRef{{Image: innerBundle.RefDigest}})
+		reg := fakeRegistry.Build()
+
+		bundleDir := bundleDirBuilder.CreateBundleDir(helpers.BundleYAML, fmt.Sprintf(imagesLockYAML, outerBundle.RefDigest))
+
+		err := bundle.NewContents([]string{bundleDir}, nil, false).ValidateNestedBundles(bundle.NestedBundlesPolicy{}, reg)
+		require.Error(t, err)
+
+		var policyErr bundle.NestedBundlesPolicyError
+		require.True(t, errors.As(err, &policyErr))
+		require.Len(t, policyErr.Violations, 1)
+		assert.Equal(t, []string{filepath.Join(bundleDir, bundle.ImgpkgDir, bundle.ImagesLockFile), outerBundle.RefDigest}, policyErr.Violations[0].BundlePath)
+		assert.Equal(t, innerBundle.RefDigest, policyErr.Violations[0].Image)
+		assert.Contains(t, policyErr.Violations[0].Reason, "Expected ref to be in digest form, got 'some.registry.io/image:1.0.0'")
+	})
+
+	t.Run("when a nested bundle references an image outside of the allowed registries, it fails with the path to the nested bundle", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, logger)
+		defer fakeRegistry.CleanUp()
+		nestedBundle := fakeRegistry.WithRandomBundle("library/nested-bundle").WithImageRefs([]lockconfig.ImageRef{
+			{Image: "other.registry.io/image@sha256:703218c0465075f4425e58fac086e09e1de5c340b12976ab9eb8ad26615c3715"},
+		})
+		reg := fakeRegistry.Build()
+
+		assets := &helpers.Assets{T: t}
+		defer assets.CleanCreatedFolders()
+		bundleDirBuilder := helpers.NewBundleDir(t, assets)
+		bundleDir := bundleDirBuilder.CreateBundleDir(helpers.BundleYAML, fmt.Sprintf(imagesLockYAML, nestedBundle.RefDigest))
+
+		err := bundle.NewContents([]string{bundleDir}, nil, false).ValidateNestedBundles(bundle.NestedBundlesPolicy{AllowedRegistries: []string{fakeRegistry.Host()}}, reg)
+		require.Error(t, err)
+
+		var policyErr bundle.NestedBundlesPolicyError
+		require.True(t, errors.As(err, &policyErr))
+		require.Len(t, policyErr.Violations, 1)
+		assert.Equal(t, []string{filepath.Join(bundleDir, bundle.ImgpkgDir, bundle.ImagesLockFile), nestedBundle.RefDigest}, policyErr.Violations[0].BundlePath)
+		assert.Contains(t, err.Error(), fmt.Sprintf("- %s -> %s: image 'other.registry.io/image@sha256:703218c0465075f4425e58fac086e09e1de5c340b12976ab9eb8ad26615c3715': Registry 'other.registry.io' is not one of the allowed registries (%s)",
+			filepath.Join(bundleDir, bundle.ImgpkgDir, bundle.ImagesLockFile), nestedBundle.RefDigest, fakeRegistry.Host()))
+	})
+}
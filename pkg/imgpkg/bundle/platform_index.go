@@ -0,0 +1,130 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"runtime"
+
+	plainimg "carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// platformIndex OCI Image Index of a multi-arch bundle, pushed with --platform-dir
+type platformIndex struct {
+	digestRef string
+	tag       string
+	manifests []regv1.Descriptor
+	// bundles of all the platforms, read once
+	bundles []*Bundle
+}
+
+// HostPlatform Platform, with the format os/arch, of the bundle resolved from the OCI Image Index of a multi-arch bundle
+func HostPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// IndexDigestRef Location of the OCI Image Index of a multi-arch bundle, including registry, repository and digest.
+// Empty when the bundle is not part of one
+func (o *Bundle) IndexDigestRef() string {
+	if o.index == nil {
+		return ""
+	}
+	return o.index.digestRef
+}
+
+// IndexTag Tag of the OCI Image Index of a multi-arch bundle. Empty when the bundle is not part of one
+func (o *Bundle) IndexTag() string {
+	if o.index == nil {
+		return ""
+	}
+	return o.index.tag
+}
+
+// PlatformBundles Bundles of all the platforms of the OCI Image Index of a multi-arch bundle,
+// or only this bundle when it is not part of one
+func (o *Bundle) PlatformBundles() ([]*Bundle, error) {
+	if o.index == nil {
+		return []*Bundle{o}, nil
+	}
+	if o.index.bundles != nil {
+		return o.index.bundles, nil
+	}
+
+	indexRef, err := regname.NewDigest(o.index.digestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundles []*Bundle
+	for _, manifest := range o.index.manifests {
+		childRef := indexRef.Context().Digest(manifest.Digest.String()).Name()
+		if childRef == o.DigestRef() {
+			bundles = append(bundles, o)
+			continue
+		}
+
+		bundle := NewBundleFromRef(childRef, o.imgRetriever, o.imagesLockReader, o.bundleFetcher)
+		isBundle, err := bundle.IsBundle()
+		if err != nil {
+			return nil, fmt.Errorf("Checking if %s is a bundle: %s", childRef, err)
+		}
+		if !isBundle {
+			return nil, fmt.Errorf("Expected the manifest %s of the multi-arch bundle %s to be a bundle", childRef, o.index.digestRef)
+		}
+		bundles = append(bundles, bundle)
+	}
+	o.index.bundles = bundles
+	return bundles, nil
+}
+
+// resolvePlatformBundle Replaces the OCI Image Index of a multi-arch bundle with the bundle of the HostPlatform.
+// Returns false when the image is not the OCI Image Index of a multi-arch bundle
+func (o *Bundle) resolvePlatformBundle() (bool, error) {
+	if o.index != nil {
+		return false, nil
+	}
+
+	indexRef, err := regname.NewDigest(o.plainImg.DigestRef())
+	if err != nil {
+		return false, err
+	}
+	desc, err := o.imgRetriever.Get(indexRef)
+	if err != nil {
+		return false, err
+	}
+	if !desc.MediaType.IsIndex() {
+		return false, nil
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return false, err
+	}
+	idxManifest, err := idx.IndexManifest()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := idxManifest.Annotations[BundleConfigLabel]; !ok {
+		return false, nil
+	}
+
+	childRef, err := plainimg.ResolveIndexChild(indexRef.Name(), HostPlatform(), o.imgRetriever)
+	if err != nil {
+		return false, fmt.Errorf("Resolving the bundle of multi-arch bundle %s: %s", indexRef.Name(), err)
+	}
+	childDigest, err := regname.NewDigest(childRef)
+	if err != nil {
+		return false, err
+	}
+	childImg, err := o.imgRetriever.Image(childDigest)
+	if err != nil {
+		return false, err
+	}
+
+	o.index = &platformIndex{digestRef: indexRef.Name(), tag: o.plainImg.Tag(), manifests: idxManifest.Manifests}
+	o.plainImg = plainimg.NewFetchedPlainImageWithTag(childRef, o.plainImg.Tag(), childImg)
+	return o.IsBundle()
+}
@@ -4,6 +4,7 @@
 package bundle
 
 import (
+	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/artifact"
 	plainimg "carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 )
 
@@ -22,11 +23,13 @@ func IsNotBundleError(err error) bool {
 	return ok
 }
 
+// IsBundle Returns true when the image is a bundle. When it is the OCI Image Index of a multi-arch bundle
+// the bundle of the platform imgpkg runs on is resolved from it
 func (o *Bundle) IsBundle() (bool, error) {
 	img, err := o.plainImg.Fetch()
 	if err != nil {
 		if plainimg.IsNotAnImageError(err) {
-			return false, nil
+			return o.resolvePlatformBundle()
 		}
 		return false, err
 	}
@@ -35,6 +38,12 @@ func (o *Bundle) IsBundle() (bool, error) {
 		return false, nil
 	}
 
+	// Artifacts, like CNAB bundles, do not have an image config with labels
+	hasImageConfig, err := artifact.HasImageConfig(img)
+	if err != nil || !hasImageConfig {
+		return false, err
+	}
+
 	cfg, err := img.ConfigFile()
 	if err != nil {
 		return false, err
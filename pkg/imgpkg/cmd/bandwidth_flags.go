@@ -0,0 +1,42 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// BandwidthFlags Flags used to limit the bandwidth used when transferring images
+type BandwidthFlags struct {
+	MaxUploadRate   int64
+	MaxDownloadRate int64
+}
+
+// Set Registers the flags in the command
+func (b *BandwidthFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&b.MaxUploadRate, "max-upload-rate", 0,
+		"Maximum number of bytes per second uploaded to the registries, 0 means unlimited (example: 10485760 for 10 MiB/s)")
+	cmd.Flags().Int64Var(&b.MaxDownloadRate, "max-download-rate", 0,
+		"Maximum number of bytes per second downloaded from the registries, 0 means unlimited (example: 10485760 for 10 MiB/s)")
+}
+
+// Validate Checks that the rates are not negative
+func (b *BandwidthFlags) Validate() error {
+	if b.MaxUploadRate < 0 {
+		return fmt.Errorf("Expected --max-upload-rate to be 0 or greater, got %d", b.MaxUploadRate)
+	}
+	if b.MaxDownloadRate < 0 {
+		return fmt.Errorf("Expected --max-download-rate to be 0 or greater, got %d", b.MaxDownloadRate)
+	}
+	return nil
+}
+
+// ApplyTo Limits the bandwidth used by the registry
+func (b *BandwidthFlags) ApplyTo(opts *registry.Opts) {
+	opts.MaxUploadRate = b.MaxUploadRate
+	opts.MaxDownloadRate = b.MaxDownloadRate
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/spf13/cobra"
+)
+
+// BudgetFlags Limits of the size and of the number of images of the bundle pushed, so that it stays within
+// the limits of the transfers to air-gapped environments
+type BudgetFlags struct {
+	MaxBundleSize string
+	MaxImageCount int
+}
+
+// Set Registers the flags in the command
+func (b *BudgetFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&b.MaxBundleSize, "max-bundle-size", "", "Fail before pushing when the bundle, its nested bundles and the images "+
+		"they reference exceed this compressed size, blobs shared between images are counted once (example: 4GB, 700MiB)")
+	cmd.Flags().IntVar(&b.MaxImageCount, "max-image-count", 0, "Fail before pushing when the bundle and its nested bundles reference more than this number of images")
+}
+
+// IsSet Returns true when a budget is provided
+func (b BudgetFlags) IsSet() bool {
+	return b.MaxBundleSize != "" || b.MaxImageCount != 0
+}
+
+// Validate Checks that the budgets can be parsed
+func (b BudgetFlags) Validate() error {
+	if b.MaxImageCount < 0 {
+		return fmt.Errorf("Expected --max-image-count to be a positive number, got '%d'", b.MaxImageCount)
+	}
+	if b.MaxBundleSize == "" {
+		return nil
+	}
+	_, err := parseSize("--max-bundle-size", b.MaxBundleSize)
+	return err
+}
+
+// Check Fails when the images of the report, of copying the bundle, exceed the budgets
+func (b BudgetFlags) Check(report v1.CopyReport) error {
+	if b.MaxBundleSize != "" {
+		maxSize, err := parseSize("--max-bundle-size", b.MaxBundleSize)
+		if err != nil {
+			return err
+		}
+		if report.TotalSize > maxSize {
+			return fmt.Errorf("Expected the bundle and the images it references to be at most %s (--max-bundle-size), but they are %s",
+				b.MaxBundleSize, util.HumanizeBytes(report.TotalSize))
+		}
+	}
+
+	if b.MaxImageCount > 0 {
+		count := 0
+		for _, img := range report.Images {
+			if img.Kind != v1.BundleCopyReportKind {
+				count++
+			}
+		}
+		if count > b.MaxImageCount {
+			return fmt.Errorf("Expected the bundle to reference at most %d images (--max-image-count), but it references %d", b.MaxImageCount, count)
+		}
+	}
+	return nil
+}
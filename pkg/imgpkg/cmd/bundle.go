@@ -0,0 +1,27 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewBundleCmd Creates the bundle command, parent of the commands that work with bundles
+func NewBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Bundle",
+	}
+	return cmd
+}
+
+// NewBundleGraphCmd Creates the bundle graph command, parent of the commands that work with the graph of the bundles
+// and images referenced by a bundle
+func NewBundleGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Bundle graph",
+	}
+	return cmd
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+// BundleDeprecateOptions Options for the bundle deprecate command
+type BundleDeprecateOptions struct {
+	ui ui.UI
+
+	BundleFlags   BundleFlags
+	RegistryFlags RegistryFlags
+
+	Message   string
+	Successor string
+	Undo      bool
+}
+
+// NewBundleDeprecateOptions Builder for BundleDeprecateOptions
+func NewBundleDeprecateOptions(ui ui.UI) *BundleDeprecateOptions {
+	return &BundleDeprecateOptions{ui: ui}
+}
+
+// NewBundleDeprecateCmd Creates the bundle deprecate command
+func NewBundleDeprecateCmd(o *BundleDeprecateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deprecate",
+		Short: "Mark a bundle that was already pushed as deprecated, so that copies and describes warn about it",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Mark bundle registry.corp/logging-bundle:1.1.0 as deprecated
+    imgpkg bundle deprecate -b registry.corp/logging-bundle:1.1.0 --message "Leaks credentials in the logs" --successor registry.corp/logging-bundle:1.1.1
+
+    # Withdraw the deprecation of bundle registry.corp/logging-bundle:1.1.0
+    imgpkg bundle deprecate -b registry.corp/logging-bundle:1.1.0 --undo`,
+	}
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.Message, "message", "", "Why the bundle should not be used anymore")
+	cmd.Flags().StringVar(&o.Successor, "successor", "", "Version or location of the bundle that replaces this one")
+	cmd.Flags().BoolVar(&o.Undo, "undo", false, "Withdraw the deprecation of the bundle")
+	return cmd
+}
+
+// Run Executes the bundle deprecate command
+func (o *BundleDeprecateOptions) Run() error {
+	if o.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Expected bundle flag when deprecating a bundle (hint: use -b, --bundle)")
+	}
+	if o.Undo && (o.Message != "" || o.Successor != "") {
+		return fmt.Errorf("Expected --message and --successor not to be provided with --undo")
+	}
+
+	reg, err := registry.NewSimpleRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	ref, err := regname.ParseReference(o.BundleFlags.Bundle, regname.WeakValidation)
+	if err != nil {
+		return err
+	}
+	digest, err := reg.Digest(ref)
+	if err != nil {
+		return err
+	}
+	bundleRef := ref.Context().Digest(digest.String())
+
+	lockReader := bundle.NewImagesLockReader()
+	isBundle, err := bundle.NewBundleFromRef(bundleRef.Name(), reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader)).IsBundle()
+	if err != nil {
+		return err
+	}
+	if !isBundle {
+		return fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
+	}
+
+	var deprecation *bundle.Deprecation
+	if !o.Undo {
+		deprecation = &bundle.Deprecation{Message: o.Message, Successor: o.Successor}
+	}
+	err = bundle.SaveDeprecation(reg, bundleRef, deprecation)
+	if err != nil {
+		return err
+	}
+
+	if o.Undo {
+		o.ui.PrintLinef("Withdrew the deprecation of %s", bundleRef.Name())
+	} else {
+		o.ui.PrintLinef("Marked %s as deprecated", bundleRef.Name())
+	}
+	return nil
+}
@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
 	"github.com/spf13/cobra"
 )
 
@@ -16,5 +17,25 @@ func (b *BundleFlags) Set(cmd *cobra.Command) {
 }
 
 func (b *BundleFlags) SetCopy(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&b.Bundle, "bundle", "b", "", "Bundle reference for copying (happens thickly, i.e. bundle image + all referenced images)")
+	cmd.Flags().StringVarP(&b.Bundle, "bundle", "b", "", "Bundle reference for copying (happens thickly, i.e. bundle image + all referenced images). "+
+		"Use oci:<path>[@<digest>] to read the bundle from a local OCI image layout")
+}
+
+// ResolveOCILayout When the bundle is stored in a local OCI image layout (oci:<path>[@<digest>]) it returns the reference
+// of the bundle, named after the image it was read from, and a registry that reads the images of the layout before reaching reg,
+// otherwise returns the bundle and reg as is
+func (b *BundleFlags) ResolveOCILayout(reg registry.Registry) (string, registry.Registry, error) {
+	if !registry.IsOCILayoutRef(b.Bundle) {
+		return b.Bundle, reg, nil
+	}
+
+	bundleRef, layoutPath, err := registry.OCILayoutReference(b.Bundle)
+	if err != nil {
+		return "", nil, err
+	}
+	layoutReg, err := registry.NewOCILayoutRegistry(layoutPath, reg)
+	if err != nil {
+		return "", nil, err
+	}
+	return bundleRef, layoutReg, nil
 }
@@ -0,0 +1,135 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/sbom"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+// BundleGraphExportOptions Options for the bundle graph export command
+type BundleGraphExportOptions struct {
+	ui ui.UI
+
+	BundleFlags   BundleFlags
+	RegistryFlags RegistryFlags
+
+	Format      string
+	Output      string
+	Concurrency int
+}
+
+// NewBundleGraphExportOptions Builder for BundleGraphExportOptions
+func NewBundleGraphExportOptions(ui ui.UI) *BundleGraphExportOptions {
+	return &BundleGraphExportOptions{ui: ui}
+}
+
+// NewBundleGraphExportCmd Creates the bundle graph export command
+func NewBundleGraphExportCmd(o *BundleGraphExportOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the bundles and images referenced by a bundle, and its nested bundles, as an SBOM",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Export the graph of bundle registry.corp/app1-bundle as SPDX relationships
+    imgpkg bundle graph export -b registry.corp/app1-bundle
+
+    # Export the graph of bundle registry.corp/app1-bundle as CycloneDX components to a file
+    imgpkg bundle graph export -b registry.corp/app1-bundle --format cyclonedx --output app1-bundle.cdx.json`,
+	}
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.Format, "format", string(sbom.FormatSPDX), "Format of the SBOM (format: spdx or cyclonedx)")
+	cmd.Flags().StringVar(&o.Output, "output", "", "Write the SBOM to this path instead of printing it")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the bundle graph export command
+func (o *BundleGraphExportOptions) Run() error {
+	if o.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Expected bundle flag when exporting the graph of a bundle (hint: use -b, --bundle)")
+	}
+	format, err := sbom.ParseFormat(o.Format)
+	if err != nil {
+		return fmt.Errorf("Parsing --format: %s", err)
+	}
+
+	description, err := v1.Describe(o.BundleFlags.Bundle, v1.DescribeOpts{
+		Logger:      util.NewUILevelLogger(util.LogWarn, util.NewLogger(o.ui)),
+		Concurrency: o.Concurrency,
+		Layers:      true,
+	}, o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	root, err := bundleGraphNode(description)
+	if err != nil {
+		return err
+	}
+	doc, err := sbom.GenerateGraph(format, sbom.Graph{Root: root, Created: time.Now(), ToolVersion: Version})
+	if err != nil {
+		return fmt.Errorf("Generating the SBOM of '%s': %s", description.Image, err)
+	}
+
+	if o.Output == "" {
+		o.ui.PrintBlock(append(doc, '\n'))
+		return nil
+	}
+	err = atomicfile.WriteFile(o.Output, append(doc, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Writing the SBOM: %s", err)
+	}
+	return nil
+}
+
+// bundleGraphNode Returns the node of the bundle described, with a node per bundle and image it references
+func bundleGraphNode(description v1.Description) (sbom.GraphNode, error) {
+	ref, err := regname.NewDigest(description.Image)
+	if err != nil {
+		return sbom.GraphNode{}, fmt.Errorf("Parsing '%s': %s", description.Image, err)
+	}
+	node := sbom.GraphNode{Ref: ref, IsBundle: true, Annotations: description.Annotations, Size: layersTotalSize(description.Layers)}
+
+	for _, key := range sortedKeys(description.Content.Bundles) {
+		bundleNode, err := bundleGraphNode(description.Content.Bundles[key])
+		if err != nil {
+			return sbom.GraphNode{}, err
+		}
+		node.Nodes = append(node.Nodes, bundleNode)
+	}
+
+	for _, key := range sortedKeys(description.Content.Images) {
+		image := description.Content.Images[key]
+		// Images that could not be described are keyed by their reference in the images lock
+		imageRef := image.Image
+		if imageRef == "" {
+			imageRef = key
+		}
+		ref, err := regname.NewDigest(imageRef)
+		if err != nil {
+			return sbom.GraphNode{}, fmt.Errorf("Parsing '%s': %s", imageRef, err)
+		}
+		node.Nodes = append(node.Nodes, sbom.GraphNode{Ref: ref, Annotations: image.Annotations, Size: layersTotalSize(image.Layers)})
+	}
+	return node, nil
+}
+
+// layersTotalSize Sum of the compressed size of all the layers, 0 when the layers information was not retrieved
+func layersTotalSize(layers []v1.Layers) int64 {
+	var size int64
+	for _, layer := range layers {
+		size += layer.Size
+	}
+	return size
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleGraphExport(t *testing.T) {
+	t.Run("fails when the format is unknown", func(t *testing.T) {
+		export := BundleGraphExportOptions{BundleFlags: BundleFlags{Bundle: "foo"}, Format: "swid"}
+		err := export.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Unknown SBOM format 'swid'")
+	})
+
+	t.Run("writes the bundles and images of the graph as CycloneDX components", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("app/image")
+		nestedBundle := fakeRegistry.WithRandomBundleAndImages("app/nested-bundle", []lockconfig.ImageRef{{Image: img.RefDigest}})
+		outerBundle := fakeRegistry.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{
+			{Image: nestedBundle.RefDigest},
+			{Image: img.RefDigest, Annotations: map[string]string{"kbld.carvel.dev/id": "app/image:1.0"}},
+		})
+		fakeRegistry.Build()
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		output := filepath.Join(t.TempDir(), "bundle.cdx.json")
+		export := BundleGraphExportOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: outerBundle.RefDigest}, Format: "cyclonedx", Output: output, Concurrency: 1}
+		require.NoError(t, export.Run())
+
+		contents, err := os.ReadFile(output)
+		require.NoError(t, err)
+		var cycloneDX struct {
+			Metadata struct {
+				Component struct {
+					Version string `json:"version"`
+				} `json:"component"`
+			} `json:"metadata"`
+			Components []struct {
+				Version    string `json:"version"`
+				Properties []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"properties"`
+			} `json:"components"`
+			Dependencies []struct {
+				DependsOn []string `json:"dependsOn"`
+			} `json:"dependencies"`
+		}
+		require.NoError(t, json.Unmarshal(contents, &cycloneDX))
+		assert.Equal(t, outerBundle.Digest, cycloneDX.Metadata.Component.Version)
+		require.Len(t, cycloneDX.Components, 2)
+		assert.Equal(t, img.Digest, cycloneDX.Components[0].Version)
+		assert.Equal(t, "image", cycloneDX.Components[0].Properties[0].Value)
+		assert.Contains(t, cycloneDX.Components[0].Properties, struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		}{Name: "kbld.carvel.dev/id", Value: "app/image:1.0"})
+		assert.Equal(t, nestedBundle.Digest, cycloneDX.Components[1].Version)
+		assert.Equal(t, "bundle", cycloneDX.Components[1].Properties[0].Value)
+		require.Len(t, cycloneDX.Dependencies, 2)
+		assert.Len(t, cycloneDX.Dependencies[0].DependsOn, 2)
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// BundlesFileFlags Flags used to copy multiple bundles and images in a single invocation
+type BundlesFileFlags struct {
+	Path string
+}
+
+// BundlesFile Bundles and images to copy, read from the file provided via --bundles-file
+type BundlesFile struct {
+	Bundles []string `json:"bundles,omitempty"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// Set Registers the flags in the command
+func (b *BundlesFileFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&b.Path, "bundles-file", "",
+		"File with the bundles and images to copy in a single invocation (format: YAML with 'bundles' and 'images' lists of references)")
+}
+
+// Read Parses the file and checks that it contains at least one reference
+func (b BundlesFileFlags) Read() (BundlesFile, error) {
+	bs, err := os.ReadFile(b.Path)
+	if err != nil {
+		return BundlesFile{}, fmt.Errorf("Reading bundles file: %s", err)
+	}
+
+	var bundlesFile BundlesFile
+	err = yaml.UnmarshalStrict(bs, &bundlesFile)
+	if err != nil {
+		return BundlesFile{}, fmt.Errorf("Unmarshaling bundles file '%s': %s", b.Path, err)
+	}
+
+	if len(bundlesFile.Bundles) == 0 && len(bundlesFile.Images) == 0 {
+		return BundlesFile{}, fmt.Errorf("Expected bundles file '%s' to contain at least one bundle or image", b.Path)
+	}
+
+	return bundlesFile, nil
+}
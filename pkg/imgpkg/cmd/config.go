@@ -0,0 +1,184 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+)
+
+// ConfigOutputTypes Possible output options of the config command
+var ConfigOutputTypes = []string{"text", "json"}
+
+// ConfigOptions Command Line options that can be provided to the config command
+type ConfigOptions struct {
+	ui ui.UI
+
+	ImageFlags      ImageFlags
+	IndexChildFlags IndexChildFlags
+	RegistryFlags   RegistryFlags
+
+	OutputType string
+}
+
+// NewConfigOptions constructor for building a ConfigOptions, holding values derived via flags
+func NewConfigOptions(ui ui.UI) *ConfigOptions {
+	return &ConfigOptions{ui: ui}
+}
+
+// NewConfigCmd constructor for the config command
+func NewConfigCmd(o *ConfigOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the config of an image: environment, entrypoint, labels and history",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Print the config of an image
+    imgpkg config -i carvel.dev/app1-image:v1.0.0
+
+    # Print the config of the linux/arm64 image of an image index as JSON
+    imgpkg config -i carvel.dev/app1-image:v1.0.0 --index-child-platform linux/arm64 -o json`,
+	}
+	o.ImageFlags.Set(cmd)
+	o.IndexChildFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", "text", fmt.Sprintf("Type of output possible values: [%s]", strings.Join(ConfigOutputTypes, ", ")))
+	return cmd
+}
+
+// Run functions called when the config command is provided in the command line
+func (o *ConfigOptions) Run() error {
+	if o.ImageFlags.Image == "" {
+		return fmt.Errorf("Expected --image (-i)")
+	}
+	if o.OutputType != "text" && o.OutputType != "json" {
+		return fmt.Errorf("--output-type can only have the following values [%s]", strings.Join(ConfigOutputTypes, ", "))
+	}
+
+	reg, err := registry.NewSimpleRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	imageConfig, err := v1.GetImageConfig(o.ImageFlags.Image, v1.ImageConfigOpts{IndexChildPlatform: o.IndexChildFlags.Platform}, reg)
+	if err != nil {
+		return err
+	}
+
+	if o.OutputType == "json" {
+		output, err := json.MarshalIndent(imageConfig, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Marshaling image config: %s", err)
+		}
+		o.ui.PrintBlock(append(output, '\n'))
+		return nil
+	}
+
+	o.printText(imageConfig)
+	return nil
+}
+
+func (o *ConfigOptions) printText(imageConfig v1.ImageConfig) {
+	config := imageConfig.Config
+	platform := config.Platform()
+
+	summary := uitable.Table{
+		Title:     "Image",
+		Transpose: true,
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Image"),
+			uitable.NewHeader("Config digest"),
+			uitable.NewHeader("Platform"),
+			uitable.NewHeader("Created"),
+			uitable.NewHeader("Author"),
+			uitable.NewHeader("User"),
+			uitable.NewHeader("Working dir"),
+			uitable.NewHeader("Entrypoint"),
+			uitable.NewHeader("Cmd"),
+			uitable.NewHeader("Env"),
+			uitable.NewHeader("Exposed ports"),
+			uitable.NewHeader("Volumes"),
+			uitable.NewHeader("Stop signal"),
+		},
+	}
+	platformValue := ""
+	if platform != nil {
+		platformValue = platform.String()
+	}
+	created := ""
+	if !config.Created.IsZero() {
+		created = config.Created.UTC().Format(time.RFC3339)
+	}
+	summary.Rows = append(summary.Rows, []uitable.Value{
+		uitable.NewValueString(imageConfig.Image),
+		uitable.NewValueString(imageConfig.ConfigDigest),
+		uitable.NewValueString(platformValue),
+		uitable.NewValueString(created),
+		uitable.NewValueString(config.Author),
+		uitable.NewValueString(config.Config.User),
+		uitable.NewValueString(config.Config.WorkingDir),
+		uitable.NewValueStrings(config.Config.Entrypoint),
+		uitable.NewValueStrings(config.Config.Cmd),
+		uitable.NewValueStrings(config.Config.Env),
+		uitable.NewValueStrings(sortedKeys(config.Config.ExposedPorts)),
+		uitable.NewValueStrings(sortedKeys(config.Config.Volumes)),
+		uitable.NewValueString(config.Config.StopSignal),
+	})
+	o.ui.PrintTable(summary)
+
+	labels := uitable.Table{
+		Title:   "Labels",
+		Content: "labels",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Name"),
+			uitable.NewHeader("Value"),
+		},
+
+		SortBy: []uitable.ColumnSort{{Column: 0, Asc: true}},
+	}
+	for name, value := range config.Config.Labels {
+		labels.Rows = append(labels.Rows, []uitable.Value{
+			uitable.NewValueString(name),
+			uitable.NewValueString(value),
+		})
+	}
+	o.ui.PrintTable(labels)
+
+	history := uitable.Table{
+		Title:   "History",
+		Content: "history entries",
+
+		FillFirstColumn: true,
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Created"),
+			uitable.NewHeader("Created by"),
+			uitable.NewHeader("Empty layer"),
+			uitable.NewHeader("Comment"),
+		},
+	}
+	for _, entry := range config.History {
+		entryCreated := ""
+		if !entry.Created.IsZero() {
+			entryCreated = entry.Created.UTC().Format(time.RFC3339)
+		}
+		history.Rows = append(history.Rows, []uitable.Value{
+			uitable.NewValueString(entryCreated),
+			uitable.NewValueString(entry.CreatedBy),
+			uitable.NewValueBool(entry.EmptyLayer),
+			uitable.NewValueString(entry.Comment),
+		})
+	}
+	o.ui.PrintTable(history)
+}
@@ -5,35 +5,87 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
 	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/tui"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
-	"carvel.dev/imgpkg/pkg/imgpkg/signature"
 	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
 	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 type CopyOptions struct {
 	ui ui.UI
 
-	ImageFlags      ImageFlags
-	BundleFlags     BundleFlags
-	LockInputFlags  LockInputFlags
-	LockOutputFlags LockOutputFlags
-	TarFlags        TarFlags
-	RegistryFlags   RegistryFlags
-	SignatureFlags  SignatureFlags
+	ImageFlags            ImageFlags
+	IndexChildFlags       IndexChildFlags
+	PlatformFlags         PlatformFlags
+	TagSelectionFlags     TagSelectionFlags
+	BundleFlags           BundleFlags
+	BundlesFileFlags      BundlesFileFlags
+	FileFlags             FileFlags
+	LockInputFlags        LockInputFlags
+	LockOutputFlags       LockOutputFlags
+	RelocationOutputFlags RelocationOutputFlags
+	TarFlags              TarFlags
+	OCILayoutFlags        OCILayoutFlags
+	RegistryFlags         RegistryFlags
+	SignatureFlags        SignatureFlags
+	MediaTypeFlags        MediaTypePolicyFlags
+	BandwidthFlags        BandwidthFlags
+	RetryBudgetFlags      RetryBudgetFlags
+	ProgressFlags         ProgressFlags
 
 	RepoDst string
+	// RepoDsts repositories copied to along with RepoDst, the source is read once
+	RepoDsts    []string
+	RegistryDst string
 
 	Concurrency             int
+	BalanceConcurrency      bool
 	IncludeNonDistributable bool
 	UseRepoBasedTags        bool
+	Incremental             bool
+	SignaturesOnly          bool
+	DryRun                  bool
+	Estimate                bool
+	StateFile               string
+	ExcludeImages           []string
+	TUI                     bool
+	Output                  string
+	Recompress              string
+	FailOnDeprecated        bool
+	VerifyAfterCopy         bool
+	VerifyBlobSample        int
+	DestAnnotations         map[string]string
+	PrefetchTokens          bool
+	// ImageTimeout when greater than 0, images that are not written to the destination within this time fail
+	ImageTimeout time.Duration
+	// RootBundle digest, name or repository of the bundle written to --lock-output when the tar or OCI image layout
+	// contains several root bundles
+	RootBundle string
+	// MetricsOutput file where the metrics of the copy are written in the Prometheus text exposition format once it finishes
+	MetricsOutput string
+
+	metrics *copyMetricsCollector
 }
 
 // NewCopyOptions constructor for building a CopyOptions, holding values derived via flags
@@ -50,9 +102,27 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
     # Copy bundle dkalinin/app1-bundle to local tarball at /Volumes/app1-bundle.tar
     imgpkg copy -b dkalinin/app1-bundle --to-tar /Volumes/app1-bundle.tar
 
+    # Copy bundle dkalinin/app1-bundle to an OCI image layout directory at ./app1-bundle-layout
+    imgpkg copy -b dkalinin/app1-bundle --to-oci-layout ./app1-bundle-layout
+
     # Copy bundle dkalinin/app1-bundle to another registry (or repository)
     imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle
 
+    # Copy bundle stored in a local OCI image layout to another registry (or repository)
+    imgpkg copy -b oci:./app1-bundle-layout --to-repo internal-registry/app1-bundle
+
+    # Copy the images and bundles in an OCI image layout directory, written by imgpkg or other tools, to another registry (or repository)
+    imgpkg copy --oci-layout ./app1-bundle-layout --to-repo internal-registry/app1-bundle
+
+    # Inside a disconnected enclave, convert the tarball of bundle app1-bundle to an OCI image layout, failing on any network access
+    imgpkg copy --tar /Volumes/app1-bundle.tar --to-oci-layout ./app1-bundle-layout --offline
+
+    # Copy all the bundles and images listed in refs.yml to another registry (or repository)
+    imgpkg copy --bundles-file refs.yml --to-repo internal-registry/product-suite
+
+    # Copy all the versions of a product listed in refs.yml to a tarball, storing only once the layers, or the beginning of the layers, the versions share
+    imgpkg copy --bundles-file refs.yml --to-tar /Volumes/product-suite.tar --to-tar-chunking
+
     # Copy image dkalinin/app1-image to another registry (or repository)
     # ##########################################################################
     # NOTE: if not using ~/.docker.config for authn, use env vars as described  #
@@ -60,6 +130,116 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
     # ##########################################################################
     imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image
 
+    # Copy only the linux/arm64 image of the image index dkalinin/app1-image
+    imgpkg copy -i dkalinin/app1-image --index-child-platform linux/arm64 --to-repo internal-registry/app1-image
+
+    # Copy only the linux/amd64 and linux/arm64 images of the image indexes listed in refs.yml
+    imgpkg copy --bundles-file refs.yml --platform linux/amd64 --platform linux/arm64 --to-repo internal-registry/product-suite
+
+    # Copy the tags of repository dkalinin/app1-image starting with v1. that are not yet in internal-registry/app1-image
+    imgpkg copy -i dkalinin/app1-image --only-new-tags --tag-pattern 'v1.*' --to-repo internal-registry/app1-image
+
+    # Nightly sync of bundle dkalinin/app1-bundle, skipping the images already copied by previous runs
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --incremental
+
+    # Copy bundle dkalinin/app1-bundle recording its progress in copy.state,
+    # running the same command again after an interruption continues from the images already copied
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --state-file copy.state
+
+    # Copy bundle dkalinin/app1-bundle without its documentation and debug images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --exclude-images '*/app1-docs' --exclude-images 'regex:-debug$'
+
+    # Copy image nginx:1.25 together with the other tags that point to the same digest (example: 1.25.3, stable)
+    imgpkg copy -i nginx:1.25 --to-repo internal-registry/nginx --additional-tags
+
+    # Copy the images of an ImagesLock keeping the tags they were resolved from, and record those tags in the lock output
+    imgpkg copy --lock images.lock.yml --to-repo internal-registry/app1-images --preserve-lock-tags --lock-output relocated.lock.yml --lock-output-tags
+
+    # Copy the images built by ko, listed in the file written by --image-refs, writing the ImagesLock of the copied images
+    imgpkg copy --image-digest-file image-refs.txt --to-repo internal-registry/app1-images --lock-output relocated.lock.yml
+
+    # Copy bundle dkalinin/app1-bundle writing its metrics to metrics.prom, to be stored with the other artifacts of the run and scraped later
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --metrics-output metrics.prom
+
+    # Copy bundle dkalinin/app1-bundle recording in the lock output the imgpkg version, the time, the source, the destination and the options of the copy
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --lock-output bundle.lock.yml --lock-output-annotations
+
+    # Copy bundle dkalinin/app1-bundle to the registries of two regions, reading it only once
+    imgpkg copy -b dkalinin/app1-bundle --to-repo us-registry/app1-bundle --to-repo eu-registry/app1-bundle
+
+    # Copy bundle dkalinin/app1-bundle to the registries of two regions, writing the lock output of each region to
+    # locks/us-registry_app1-bundle.yml and locks/eu-registry_app1-bundle.yml
+    imgpkg copy -b dkalinin/app1-bundle --to-repo us-registry/app1-bundle --to-repo eu-registry/app1-bundle --lock-output-template 'locks/{{.Dest}}.yml'
+
+    # Copy bundle dkalinin/app1-bundle without using more than 10 MiB/s of upload bandwidth
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --max-upload-rate 10485760
+
+    # Copy bundle dkalinin/app1-bundle with its cosign signatures, recording in them the ticket that approved the import
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --cosign-signatures \
+                --signature-annotation ticket=SEC-1234 --signature-annotation approver=jane
+
+    # Copy bundle dkalinin/app1-bundle with the Notation signatures of its images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --notation-signatures
+
+    # Copy bundle dkalinin/app1-bundle with its cosign signatures and every other artifact that refers to its images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --signatures cosign,referrers
+
+    # Copy bundle dkalinin/app1-bundle with the SBOMs, attestations and signatures that refer to its images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --include-referrers
+
+    # Copy bundle dkalinin/app1-bundle recording on the relocated bundle when and by whom it was relocated
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle \
+                --dest-annotation relocated-at=2024-05-01 --dest-annotation relocated-by=jane
+
+    # Copy bundle dkalinin/app1-bundle only if it and all its images are signed with the key of cosign.pub
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --cosign-signatures \
+                --require-cosign-signature --cosign-public-key cosign.pub
+
+    # Copy a package repository bundle and generate a ytt overlay that points the PackageRepository to the copy
+    imgpkg copy -b dkalinin/app1-repo-bundle --to-repo internal-registry/app1-repo-bundle \
+                --relocation-output relocation.yml --relocation-output-format package-repository
+
+    # Copy a tarball with several bundles to a repository, writing the lock of the bundle named app1
+    imgpkg copy --tar /Volumes/app-bundles.tar --to-repo internal-registry/app-bundles --lock-output app1.lock.yml --root-bundle app1
+
+    # Copy bundle dkalinin/app1-bundle retrying the downloads that do not receive any byte for 1 minute, and failing the images not copied within 30 minutes
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --registry-stall-timeout 1m --image-timeout 30m
+
+    # Copy bundle dkalinin/app1-bundle stopping once 50 requests to a registry host failed, instead of retrying against a misconfigured destination
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --retry-budget 50
+
+    # In CI, copy bundle dkalinin/app1-bundle stopping on the first request that fails
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --fail-fast
+
+    # Copy the cosign signatures and attestations added to bundle dkalinin/app1-bundle and its images after it was copied
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --signatures-only --cosign-signatures
+
+    # Copy bundle dkalinin/app1-bundle, with hundreds of images, checking first that every repository can be accessed
+    imgpkg copy -b dkalinin/app1-bundle --to-registry internal-registry --prefetch-tokens
+
+    # Copy a bundle and its images to another registry keeping their repository paths,
+    # e.g. index.docker.io/library/nginx is copied to internal-registry/library/nginx
+    imgpkg copy -b dkalinin/app1-bundle --to-registry internal-registry
+
+    # Push the bundle in the config/ directory, containing config/.imgpkg/images.yml, and copy its images next to it
+    imgpkg copy -f config/ --file-bundle-tag 1.0.0 --to-repo internal-registry/app1-bundle --lock-output bundle.lock.yml
+
+    # Copy a bundle, then fetch its manifests and 20 of its blobs again from the destination before writing the lock file
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --lock-output bundle.lock.yml \
+                --verify-after-copy --verify-blob-sample 20
+
+    # Copy a bundle with hundreds of images following the progress of each one of them in a full screen view
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --tui
+
+    # Copy a bundle and print, for CI pipelines, where each one of its images was copied
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --output json
+
+    # Print the images that copying a bundle would transfer and their sizes, without copying them
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --dry-run
+
+    # Print the size of a bundle and its images, and how much of it is missing from internal-registry/app1-bundle
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --estimate
+
     # Copy using image --repo-based-tags flag
     imgpkg copy -i registry.foo.bar/some/application/app \
                 --to-repo other-reg.faz.baz/my-app --repo-based-tags
@@ -71,40 +251,386 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
 	}
 
 	o.ImageFlags.SetCopy(cmd)
+	o.IndexChildFlags.Set(cmd)
+	o.PlatformFlags.Set(cmd)
+	o.TagSelectionFlags.Set(cmd)
 	o.BundleFlags.SetCopy(cmd)
-	o.LockInputFlags.Set(cmd)
+	o.BundlesFileFlags.Set(cmd)
+	o.FileFlags.SetCopy(cmd)
+	o.LockInputFlags.SetOnCopy(cmd)
 	o.LockOutputFlags.SetOnCopy(cmd)
+	o.RelocationOutputFlags.Set(cmd)
 	o.TarFlags.Set(cmd)
+	o.OCILayoutFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
 	o.SignatureFlags.Set(cmd)
-	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Location to upload assets")
+	o.MediaTypeFlags.Set(cmd)
+	o.BandwidthFlags.Set(cmd)
+	o.RetryBudgetFlags.Set(cmd)
+	o.ProgressFlags.Set(cmd)
+	cmd.Flags().StringSliceVar(&o.RepoDsts, "to-repo", nil,
+		"Location to upload assets (can be specified multiple times, the source is read once and copied to every repository)")
+	cmd.Flags().StringVar(&o.RegistryDst, "to-registry", "", "Registry to upload assets to, keeping the repository path they have in their source registry")
 	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	cmd.Flags().BoolVar(&o.BalanceConcurrency, "balance-concurrency", false,
+		"When copying between registry hosts, start with half of --concurrency images at a time and adjust it, up to --concurrency, to the throughput of the hosts")
 	cmd.Flags().BoolVar(&o.IncludeNonDistributable, "include-non-distributable-layers", false,
 		"Include non-distributable layers when copying an image/bundle")
 	cmd.Flags().BoolVar(&o.UseRepoBasedTags, "repo-based-tags", false,
 		"Allow imgpkg to use repository-based tags for convenience")
+	cmd.Flags().BoolVar(&o.Incremental, "incremental", false,
+		"Check the destination repository before copying and skip the images that are already present in it")
+	cmd.Flags().BoolVar(&o.SignaturesOnly, "signatures-only", false,
+		"Copy only the signatures, attestations and SBOMs of the images already copied to the repository that are not in it yet, "+
+			"so that the ones added to the source after the copy are synced without copying the images again")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false,
+		"Print the images, nested bundles and signatures that would be copied and their sizes, without writing anything to the destination")
+	cmd.Flags().BoolVar(&o.Estimate, "estimate", false,
+		"Print the size of each image that would be copied and the total size, without transferring anything. A destination is not required, "+
+			"when a single repository is provided with --to-repo the blobs already present in it are not counted")
+	cmd.Flags().StringVar(&o.StateFile, "state-file", "",
+		"File where the images copied to the repository are recorded. When the file exists, the copy resumes from it and only writes the missing images. Removed once the copy succeeds")
+	cmd.Flags().StringSliceVar(&o.ExcludeImages, "exclude-images", nil,
+		"Do not copy the images of the bundle whose repository matches this pattern, they are marked as excluded in the ImagesLock when the bundle is pulled "+
+			"(format: glob or regex:<expression>, example: */app-docs) (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.Output, "output", "",
+		"Print a document describing every image copied, with its source, destination, tags and bytes transferred (format: json, yaml)")
+	cmd.Flags().StringVar(&o.Recompress, "recompress", "",
+		"Convert the gzip layers of the images to this compression while copying, changing their digests (format: zstd)")
+	cmd.Flags().BoolVar(&o.FailOnDeprecated, "fail-on-deprecated", false,
+		"Fail before copying anything when the bundle or one of its nested bundles is marked as deprecated (see imgpkg bundle deprecate), instead of only warning")
+	cmd.Flags().StringToStringVar(&o.DestAnnotations, "dest-annotation", map[string]string{},
+		"Add annotations to the manifest of the root bundle in the destination, changing its digest but not the digests of its images (format: key=value) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.VerifyAfterCopy, "verify-after-copy", false,
+		"Once the images are copied, fetch their manifests again from the destination and verify their digests before writing the lock and relocation outputs")
+	cmd.Flags().IntVar(&o.VerifyBlobSample, "verify-blob-sample", 0,
+		"Number of blobs of the copied images, chosen at random, downloaded again from the destination to verify their digests (requires --verify-after-copy)")
+	cmd.Flags().StringVar(&o.RootBundle, "root-bundle", "",
+		"Bundle written to --lock-output when the tar or OCI image layout contains several bundles, selected by its digest, the name in its metadata or its repository "+
+			"(format: sha256:<digest>, name or repository, example: dkalinin/app1-bundle)")
+	cmd.Flags().BoolVar(&o.PrefetchTokens, "prefetch-tokens", false,
+		"Before copying anything, authenticate concurrently with every source and destination repository and fetch their tokens, failing right away when one of them cannot be accessed")
+	cmd.Flags().DurationVar(&o.ImageTimeout, "image-timeout", 0,
+		"Fail the images that are not written to the destination within this time, instead of waiting for a hung transfer, 0 for no limit (ms|s|m|h) "+
+			"(see --registry-stall-timeout to retry the transfers that stall)")
+	cmd.Flags().StringVar(&o.MetricsOutput, "metrics-output", "",
+		"Write the metrics of the copy, like the images copied, the time taken by each image and the traffic with each registry, to this file "+
+			"in the Prometheus text exposition format once the copy finishes, even when it fails (example: metrics.prom)")
+	cmd.Flags().BoolVar(&o.TUI, "tui", false,
+		"Show a full screen view with the progress of each image, the failures, the throughput and the estimated time left")
 	return cmd
 }
 
 func (c *CopyOptions) Run() error {
+	if c.MetricsOutput == "" {
+		return c.run()
+	}
+
+	c.metrics = newCopyMetricsCollector(time.Now)
+	err := c.run()
+	metricsErr := c.metrics.Metrics(err).WriteFile(c.MetricsOutput)
+	if err != nil {
+		return err
+	}
+	return metricsErr
+}
+
+func (c *CopyOptions) run() error {
+	if c.RepoDst != "" && !slices.Contains(c.RepoDsts, c.RepoDst) {
+		c.RepoDsts = append([]string{c.RepoDst}, c.RepoDsts...)
+	}
 	if !c.hasOneSrc() {
-		return fmt.Errorf("Expected either --lock, --bundle (-b), --image (-i), or --tar as a source")
+		return fmt.Errorf("Expected either --lock, --image-digest-file, --bundle (-b), --image (-i), --bundles-file, --file (-f), --tar, or --oci-layout as a source")
+	}
+	if !c.hasOneDst() && !(c.Estimate && c.hasNoDst()) {
+		return fmt.Errorf("Expected either --to-tar, --to-oci-layout, --to-repo or --to-registry")
+	}
+	if c.Estimate {
+		if c.DryRun {
+			return fmt.Errorf("Flag --estimate cannot be used with --dry-run")
+		}
+		if c.Output != "" || c.TUI {
+			return fmt.Errorf("Flag --estimate cannot be used with --output or --tui")
+		}
+		if len(c.FileFlags.Files) > 0 {
+			return fmt.Errorf("Flag --estimate cannot be used with --file (-f), the bundle would be pushed to the destination")
+		}
+	}
+	if c.IndexChildFlags.Platform != "" && c.ImageFlags.Image == "" {
+		return fmt.Errorf("Flag --index-child-platform can only be used when copying an image (-i)")
+	}
+	if len(c.PlatformFlags.Platforms) > 0 {
+		if c.IndexChildFlags.Platform != "" {
+			return fmt.Errorf("Flag --platform cannot be used with --index-child-platform")
+		}
+		if c.TarFlags.IsSrc() {
+			return fmt.Errorf("Flag --platform cannot be used when copying from a tar (--tar)")
+		}
+		if c.BundleFlags.Bundle != "" {
+			return fmt.Errorf("Flag --platform cannot be used when copying bundles, the digests of the image indexes referenced by the bundle would change")
+		}
+	}
+	if len(c.TagSelectionFlags.TagPatterns) > 0 && !c.TagSelectionFlags.OnlyNewTags {
+		return fmt.Errorf("Flag --tag-pattern can only be used with --only-new-tags")
+	}
+	if c.TagSelectionFlags.OnlyNewTags {
+		if c.ImageFlags.Image == "" || !c.isRepoDst() {
+			return fmt.Errorf("Flag --only-new-tags can only be used when copying an image repository (-i) to a repository (--to-repo)")
+		}
+		if c.IndexChildFlags.Platform != "" {
+			return fmt.Errorf("Flag --only-new-tags cannot be used with --index-child-platform")
+		}
+	}
+	if c.TagSelectionFlags.AdditionalTags || c.TagSelectionFlags.AllTags {
+		if c.ImageFlags.Image == "" {
+			return fmt.Errorf("Flags --additional-tags and --all-tags can only be used when copying an image (-i)")
+		}
+		if c.TagSelectionFlags.OnlyNewTags {
+			return fmt.Errorf("Flags --additional-tags and --all-tags cannot be used with --only-new-tags")
+		}
+		if c.IndexChildFlags.Platform != "" {
+			return fmt.Errorf("Flags --additional-tags and --all-tags cannot be used with --index-child-platform")
+		}
+	}
+	if len(c.FileFlags.Files) > 0 {
+		if !c.isRepoDst() || len(c.RepoDsts) > 1 {
+			return fmt.Errorf("Flag --file (-f) can only be used when copying to a single repository (--to-repo)")
+		}
+		if c.DryRun {
+			return fmt.Errorf("Flag --file (-f) cannot be used with --dry-run, the bundle would be pushed to the destination")
+		}
+	}
+	if c.SignaturesOnly {
+		if !c.isRepoDst() || len(c.RepoDsts) > 1 {
+			return fmt.Errorf("Flag --signatures-only can only be used when copying to a single repository (--to-repo)")
+		}
+		if c.BundleFlags.Bundle == "" && c.ImageFlags.Image == "" && c.LockInputFlags.LockFilePath == "" {
+			return fmt.Errorf("Flag --signatures-only can only be used when copying a bundle (-b), an image (-i) or a lock (--lock) from a registry")
+		}
+		if !c.SignatureFlags.IsSet() {
+			return fmt.Errorf("Expected --signatures-only to be used with --cosign-signatures, --notation-signatures, --include-referrers or --signatures")
+		}
+		if c.LockOutputFlags.IsSet() || c.RelocationOutputFlags.Path != "" || c.TagSelectionFlags.OnlyNewTags || len(c.DestAnnotations) > 0 || c.DryRun || c.Estimate {
+			return fmt.Errorf("Flag --signatures-only cannot be used with --lock-output, --relocation-output, --only-new-tags, --dest-annotation, " +
+				"--dry-run or --estimate, the images and bundles are not copied")
+		}
+	}
+	if c.Incremental && !c.isRepoDst() && !c.isRegistryDst() {
+		return fmt.Errorf("Flag --incremental can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+	}
+	if err := c.BandwidthFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.RetryBudgetFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.ProgressFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.TarFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.SignatureFlags.Validate(); err != nil {
+		return err
+	}
+	if len(c.DestAnnotations) > 0 {
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --dest-annotation can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if c.ImageFlags.Image != "" {
+			return fmt.Errorf("Flag --dest-annotation can only be used when copying bundles")
+		}
+	}
+	if c.SignatureFlags.RequireCosignSignature && (c.TarFlags.IsSrc() || c.OCILayoutFlags.IsSrc()) {
+		return fmt.Errorf("Flag --require-cosign-signature cannot be used when copying from a tar (--tar) or an OCI image layout (--oci-layout) " +
+			"(hint: verify the signatures when copying the images from their registry)")
+	}
+	if len(c.SignatureFlags.Annotations) > 0 && !c.isRepoDst() && !c.isRegistryDst() {
+		return fmt.Errorf("Flag --signature-annotation can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+	}
+	if c.LockInputFlags.PreserveTags && !c.LockInputFlags.IsImagesSrc() {
+		return fmt.Errorf("Flag --preserve-lock-tags can only be used when copying an ImagesLock (--lock) or image digest files (--image-digest-file)")
+	}
+	if err := c.LockOutputFlags.Validate(); err != nil {
+		return err
+	}
+	if c.LockOutputFlags.Tags && !c.LockOutputFlags.IsSet() {
+		return fmt.Errorf("Flag --lock-output-tags can only be used with --lock-output or --lock-output-template")
+	}
+	if c.LockOutputFlags.Annotations && !c.LockOutputFlags.IsSet() {
+		return fmt.Errorf("Flag --lock-output-annotations can only be used with --lock-output or --lock-output-template")
+	}
+	if c.LockOutputFlags.PathTemplate != "" {
+		if c.LockOutputFlags.LockFilePath != "" {
+			return fmt.Errorf("Flag --lock-output-template cannot be used with --lock-output")
+		}
+		if !c.isRepoDst() {
+			return fmt.Errorf("Flag --lock-output-template can only be used when copying to a repository (--to-repo)")
+		}
+		if _, err := c.LockOutputFlags.Paths(c.RepoDsts); err != nil {
+			return err
+		}
+	}
+	if c.RootBundle != "" {
+		if !c.LockOutputFlags.IsSet() {
+			return fmt.Errorf("Flag --root-bundle can only be used with --lock-output or --lock-output-template")
+		}
+		if !c.TarFlags.IsSrc() && !c.OCILayoutFlags.IsSrc() {
+			return fmt.Errorf("Flag --root-bundle can only be used when copying from a tar (--tar) or an OCI image layout (--oci-layout)")
+		}
+	}
+	if len(c.ExcludeImages) > 0 {
+		if c.BundleFlags.Bundle == "" {
+			return fmt.Errorf("Flag --exclude-images can only be used when copying a bundle (-b)")
+		}
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --exclude-images can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+	}
+	if c.FailOnDeprecated && c.BundleFlags.Bundle == "" && c.BundlesFileFlags.Path == "" && c.LockInputFlags.LockFilePath == "" {
+		return fmt.Errorf("Flag --fail-on-deprecated can only be used when copying bundles from a registry (-b, --bundles-file or --lock)")
+	}
+	if c.StateFile != "" && !c.isRepoDst() && !c.isRegistryDst() {
+		return fmt.Errorf("Flag --state-file can only be used when copying to a repository (--to-repo) or a registry (--to-registry) (hint: use --resume to resume copies to a tar)")
+	}
+	if len(c.RepoDsts) > 1 {
+		if c.TagSelectionFlags.OnlyNewTags {
+			return fmt.Errorf("Flag --only-new-tags can only be used when copying to a single repository (--to-repo)")
+		}
+		if c.LockOutputFlags.LockFilePath != "" {
+			return fmt.Errorf("Flag --lock-output can only be used when copying to a single repository (--to-repo) " +
+				"(hint: use --lock-output-template to write a lockfile per repository)")
+		}
+		if c.RelocationOutputFlags.Path != "" {
+			return fmt.Errorf("Flag --relocation-output can only be used when copying to a single repository (--to-repo)")
+		}
+	}
+	if c.Output != "" {
+		if !slices.Contains(CopyOutputTypes, c.Output) {
+			return fmt.Errorf("Flag --output can only have the following values [%s]", strings.Join(CopyOutputTypes, ", "))
+		}
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --output can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if len(c.RepoDsts) > 1 {
+			return fmt.Errorf("Flag --output can only be used when copying to a single repository (--to-repo)")
+		}
+		if c.DryRun {
+			return fmt.Errorf("Flag --output cannot be used with --dry-run")
+		}
 	}
-	if !c.hasOneDst() {
-		return fmt.Errorf("Expected either --to-tar or --to-repo")
+	if c.Recompress != "" {
+		if c.Recompress != string(ctlimgset.RecompressionZstd) {
+			return fmt.Errorf("Expected --recompress to be '%s', got '%s'", ctlimgset.RecompressionZstd, c.Recompress)
+		}
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --recompress can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if c.BundleFlags.Bundle != "" || c.BundlesFileFlags.Path != "" {
+			return fmt.Errorf("Flag --recompress cannot be used when copying bundles, the digests of the images referenced by the bundle would change")
+		}
+		if c.SignatureFlags.IsSet() {
+			return fmt.Errorf("Flag --recompress cannot be used when copying signatures, they reference the digests of the original images")
+		}
+		if c.Incremental {
+			return fmt.Errorf("Flag --recompress cannot be used with --incremental, the recompressed images do not have the digests of the source")
+		}
+	}
+	if c.VerifyAfterCopy {
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --verify-after-copy can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if c.DryRun {
+			return fmt.Errorf("Flag --verify-after-copy cannot be used with --dry-run")
+		}
+	}
+	if c.PrefetchTokens && !c.isRepoDst() && !c.isRegistryDst() {
+		return fmt.Errorf("Flag --prefetch-tokens can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+	}
+	if c.ImageTimeout != 0 {
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --image-timeout can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if c.ImageTimeout < 0 {
+			return fmt.Errorf("Expected --image-timeout to not be negative, got %s", c.ImageTimeout)
+		}
+	}
+	if c.VerifyBlobSample != 0 {
+		if !c.VerifyAfterCopy {
+			return fmt.Errorf("Flag --verify-blob-sample can only be used with --verify-after-copy")
+		}
+		if c.VerifyBlobSample < 0 {
+			return fmt.Errorf("Expected --verify-blob-sample to not be negative, got %d", c.VerifyBlobSample)
+		}
+	}
+	if c.TUI {
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --tui can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		if c.DryRun {
+			return fmt.Errorf("Flag --tui cannot be used with --dry-run")
+		}
+		if !c.ProgressFlags.IsAuto() {
+			return fmt.Errorf("Flag --tui cannot be used with --progress=%s", c.ProgressFlags.Mode)
+		}
+		if !isatty.IsTerminal(os.Stdout.Fd()) {
+			return fmt.Errorf("Flag --tui can only be used when the output is a terminal")
+		}
+	}
+	if c.RelocationOutputFlags.Path != "" {
+		if !c.isRepoDst() && !c.isRegistryDst() {
+			return fmt.Errorf("Flag --relocation-output can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+		}
+		err := c.RelocationOutputFlags.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	var bundlesFile BundlesFile
+	if c.BundlesFileFlags.Path != "" {
+		var err error
+		bundlesFile, err = c.BundlesFileFlags.Read()
+		if err != nil {
+			return err
+		}
+	}
+
+	platforms, err := c.platforms(bundlesFile)
+	if err != nil {
+		return err
 	}
 
 	registryOpts := c.RegistryFlags.AsRegistryOpts()
+	if registryOpts.Offline && (c.isRepoDst() || c.isRegistryDst()) {
+		return fmt.Errorf("Flag --offline cannot be used when copying to a registry (--to-repo or --to-registry)")
+	}
 	registryOpts.IncludeNonDistributableLayers = c.IncludeNonDistributable
+	c.BandwidthFlags.ApplyTo(&registryOpts)
+	c.RetryBudgetFlags.ApplyTo(&registryOpts)
+	trafficMeter := registry.NewTrafficMeter()
+	registryOpts.TrafficMeter = trafficMeter
+	if c.metrics != nil {
+		c.metrics.trafficMeter = trafficMeter
+	}
 
-	reg, err := registry.NewSimpleRegistry(registryOpts)
+	simpleReg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return err
+	}
+	bundleRef, reg, err := c.BundleFlags.ResolveOCILayout(simpleReg)
 	if err != nil {
 		return err
 	}
 
+	var dashboard *tui.CopyDashboard
 	prefixedLogger := util.NewPrefixedLogger("copy | ", util.NewLogger(c.ui))
+	if c.TUI {
+		dashboard = tui.NewCopyDashboard(os.Stdout, terminalSize)
+		prefixedLogger = util.NewPrefixedLogger("copy | ", dashboard)
+	}
 	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
-	imagesUploaderLogger := util.NewProgressBar(levelLogger, "done uploading images", "Error uploading images")
+	imagesUploaderLogger := c.ProgressFlags.ProgressLogger(c.ui, levelLogger, "copy | ", "done uploading images", "Error uploading images")
 
 	var tagGen util.TagGenerator
 	tagGen = util.DefaultTagGenerator{}
@@ -112,14 +638,47 @@ func (c *CopyOptions) Run() error {
 		tagGen = util.RepoBasedTagGenerator{}
 	}
 
-	imageSet := ctlimgset.NewImageSet(c.Concurrency, prefixedLogger, tagGen)
-	tarImageSet := ctlimgset.NewTarImageSet(imageSet, c.Concurrency, prefixedLogger)
+	mediaTypePolicy, err := c.MediaTypeFlags.MediaTypePolicy()
+	if err != nil {
+		return err
+	}
+
+	journalDir := copyJournalDir()
+	if journalDir == "" && (c.isRepoDst() || c.isRegistryDst()) {
+		prefixedLogger.Logf("Warning: unable to find a folder for the journal of the copy, it will not be resumable\n")
+	}
 
-	var signatureRetriever v1.SignatureFetcher
-	if c.SignatureFlags.CopyCosignSignatures {
-		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), c.Concurrency)
-	} else {
-		signatureRetriever = signature.NewNoop()
+	imageSet := ctlimgset.NewImageSet(c.Concurrency, prefixedLogger, tagGen).WithMediaTypePolicy(mediaTypePolicy).WithPlatforms(platforms).
+		WithJournal(journalDir, c.TarFlags.Resume).WithCopyOptions(c.copyOptions(mediaTypePolicy)).WithIncremental(c.Incremental).
+		WithStateFile(c.StateFile).WithRecompression(ctlimgset.Recompression(c.Recompress)).WithImageTimeout(c.ImageTimeout)
+	if c.BalanceConcurrency {
+		imageSet = imageSet.WithConcurrencyBalancing(trafficMeter)
+	}
+	if dashboard != nil {
+		imageSet = imageSet.WithObserver(dashboard)
+	}
+	if c.metrics != nil {
+		imageSet = imageSet.WithObserver(c.metrics)
+	}
+	var resultCollector *copyResultCollector
+	if c.Output != "" {
+		resultCollector = newCopyResultCollector()
+		imageSet = imageSet.WithObserver(resultCollector)
+	}
+	splitSize, err := c.TarFlags.SplitSizeBytes()
+	if err != nil {
+		return err
+	}
+	tarImageSet := ctlimgset.NewTarImageSet(imageSet, c.Concurrency, prefixedLogger).
+		WithSplitSize(splitSize).WithChecksums(c.TarFlags.Checksums).WithChunking(c.TarFlags.Chunking)
+
+	signatureRetriever, err := c.SignatureFlags.Fetcher(reg, c.Concurrency)
+	if err != nil {
+		return err
+	}
+	signatureVerifier, err := c.SignatureFlags.Verifier(reg)
+	if err != nil {
+		return err
 	}
 
 	opts := v1.CopyOpts{
@@ -130,6 +689,32 @@ func (c *CopyOptions) Run() error {
 		SignatureRetriever:      signatureRetriever,
 		IncludeNonDistributable: c.IncludeNonDistributable,
 		Resume:                  c.TarFlags.Resume,
+		SignatureAnnotations:    c.SignatureFlags.Annotations,
+		FailOnDeprecated:        c.FailOnDeprecated,
+		SignatureVerifier:       signatureVerifier,
+		RootBundleAnnotations:   c.DestAnnotations,
+	}
+	if c.PrefetchTokens {
+		opts.TokenPrefetcher = simpleReg
+	}
+
+	if c.Estimate {
+		origin := v1.CopyOrigin{
+			ImageRef:             c.ImageFlags.Image,
+			BundleRef:            bundleRef,
+			TarPath:              c.TarFlags.TarSrc,
+			OCILayoutPath:        c.OCILayoutFlags.LayoutSrc,
+			LockfilePath:         c.LockInputFlags.LockFilePath,
+			ImageDigestFilePaths: c.LockInputFlags.ImageDigestFiles,
+			PreserveLockTags:     c.LockInputFlags.PreserveTags,
+			IndexChildPlatform:   c.IndexChildFlags.Platform,
+			AdditionalTags:       c.TagSelectionFlags.AdditionalTags,
+			AllTags:              c.TagSelectionFlags.AllTags,
+			ExcludeImages:        c.ExcludeImages,
+			BundleRefs:           bundlesFile.Bundles,
+			ImageRefs:            bundlesFile.Images,
+		}
+		return c.printCopyEstimate(origin, opts, reg)
 	}
 
 	switch {
@@ -137,15 +722,29 @@ func (c *CopyOptions) Run() error {
 		if c.TarFlags.IsSrc() {
 			return fmt.Errorf("Cannot use tar source (--tar) with tar destination (--to-tar)")
 		}
+		if c.OCILayoutFlags.IsSrc() {
+			return fmt.Errorf("Cannot use OCI image layout source (--oci-layout) with tar destination (--to-tar)")
+		}
 		if c.LockOutputFlags.LockFilePath != "" {
 			return fmt.Errorf("Cannot output lock file with tar destination")
 		}
 
 		origin := v1.CopyOrigin{
-			ImageRef:     c.ImageFlags.Image,
-			BundleRef:    c.BundleFlags.Bundle,
-			LockfilePath: c.LockInputFlags.LockFilePath,
+			ImageRef:             c.ImageFlags.Image,
+			BundleRef:            bundleRef,
+			LockfilePath:         c.LockInputFlags.LockFilePath,
+			ImageDigestFilePaths: c.LockInputFlags.ImageDigestFiles,
+			PreserveLockTags:     c.LockInputFlags.PreserveTags,
+			IndexChildPlatform:   c.IndexChildFlags.Platform,
+			AdditionalTags:       c.TagSelectionFlags.AdditionalTags,
+			AllTags:              c.TagSelectionFlags.AllTags,
+			BundleRefs:           bundlesFile.Bundles,
+			ImageRefs:            bundlesFile.Images,
+		}
+		if c.DryRun {
+			return c.printCopyReport(origin, opts, reg)
 		}
+
 		ids, err := v1.CopyToTar(origin, c.TarFlags.TarDst, opts, registry.NewRegistryWithProgress(reg, imagesUploaderLogger))
 		if err != nil {
 			return err
@@ -155,45 +754,435 @@ func (c *CopyOptions) Run() error {
 
 		return nil
 
-	case c.isRepoDst():
-		if c.TarFlags.Resume {
-			return fmt.Errorf("Flag --resume can only be used when copying to tar")
+	case c.OCILayoutFlags.IsDst():
+		if c.TarFlags.IsSrc() {
+			return fmt.Errorf("Cannot use tar source (--tar) with OCI image layout destination (--to-oci-layout)")
+		}
+		if c.OCILayoutFlags.IsSrc() {
+			return fmt.Errorf("Cannot use OCI image layout source (--oci-layout) with OCI image layout destination (--to-oci-layout)")
+		}
+		if c.LockOutputFlags.LockFilePath != "" {
+			return fmt.Errorf("Cannot output lock file with OCI image layout destination")
 		}
 
 		origin := v1.CopyOrigin{
-			ImageRef:     c.ImageFlags.Image,
-			BundleRef:    c.BundleFlags.Bundle,
-			TarPath:      c.TarFlags.TarSrc,
-			LockfilePath: c.LockInputFlags.LockFilePath,
+			ImageRef:             c.ImageFlags.Image,
+			BundleRef:            bundleRef,
+			LockfilePath:         c.LockInputFlags.LockFilePath,
+			ImageDigestFilePaths: c.LockInputFlags.ImageDigestFiles,
+			PreserveLockTags:     c.LockInputFlags.PreserveTags,
+			IndexChildPlatform:   c.IndexChildFlags.Platform,
+			AdditionalTags:       c.TagSelectionFlags.AdditionalTags,
+			AllTags:              c.TagSelectionFlags.AllTags,
+			BundleRefs:           bundlesFile.Bundles,
+			ImageRefs:            bundlesFile.Images,
+		}
+		if c.DryRun {
+			return c.printCopyReport(origin, opts, reg)
 		}
 
-		processedImages, err := v1.CopyToRepository(origin, c.RepoDst, opts, reg)
+		ids, err := v1.CopyToOCILayout(origin, c.OCILayoutFlags.LayoutDst, opts, registry.NewRegistryWithProgress(reg, imagesUploaderLogger))
+		if err != nil {
+			return err
+		}
+		informUserToUseTheNonDistributableFlagWithDescriptors(
+			levelLogger, c.IncludeNonDistributable, getNonDistributableLayersFromImageDescriptors(ids))
+
+		return nil
+
+	case c.isRepoDst(), c.isRegistryDst():
+		if c.isRegistryDst() && c.OCILayoutFlags.IsSrc() {
+			return fmt.Errorf("Cannot use OCI image layout source (--oci-layout) with registry destination (--to-registry)")
+		}
+
+		origin := v1.CopyOrigin{
+			ImageRef:             c.ImageFlags.Image,
+			BundleRef:            bundleRef,
+			TarPath:              c.TarFlags.TarSrc,
+			OCILayoutPath:        c.OCILayoutFlags.LayoutSrc,
+			LockfilePath:         c.LockInputFlags.LockFilePath,
+			ImageDigestFilePaths: c.LockInputFlags.ImageDigestFiles,
+			PreserveLockTags:     c.LockInputFlags.PreserveTags,
+			IndexChildPlatform:   c.IndexChildFlags.Platform,
+			AdditionalTags:       c.TagSelectionFlags.AdditionalTags,
+			AllTags:              c.TagSelectionFlags.AllTags,
+			OnlyNewTags:          c.TagSelectionFlags.OnlyNewTags,
+			SignaturesOnly:       c.SignaturesOnly,
+			ExcludeImages:        c.ExcludeImages,
+			TagPatterns:          c.TagSelectionFlags.TagPatterns,
+			BundleRefs:           bundlesFile.Bundles,
+			ImageRefs:            bundlesFile.Images,
+		}
+
+		if c.DryRun {
+			return c.printCopyReport(origin, opts, reg)
+		}
+
+		if len(c.FileFlags.Files) > 0 {
+			origin.BundleRef, reg, err = c.pushFilesBundle(reg, levelLogger)
+			if err != nil {
+				return err
+			}
+		}
+
+		if dashboard != nil {
+			dashboard.Start()
+		}
+		if len(c.RepoDsts) > 1 {
+			return c.copyToRepositories(origin, opts, reg, levelLogger, dashboard, trafficMeter)
+		}
+
+		var processedImages *ctlimgset.ProcessedImages
+		if c.isRegistryDst() {
+			processedImages, err = v1.CopyToRegistry(origin, c.RegistryDst, opts, reg)
+		} else {
+			processedImages, err = v1.CopyToRepository(origin, c.RepoDsts[0], opts, reg)
+		}
+		if dashboard != nil {
+			dashboard.Stop()
+		}
+		if err != nil {
+			return err
+		}
+
+		err = c.removeStateFile()
 		if err != nil {
 			return err
 		}
 
 		informUserToUseTheNonDistributableFlagWithDescriptors(
 			levelLogger, c.IncludeNonDistributable, processedImagesNonDistLayer(processedImages))
+		logHostsTraffic(levelLogger, trafficMeter.Hosts())
 
-		return c.writeLockOutput(processedImages, reg)
+		err = c.verifyCopy(processedImages, reg, levelLogger)
+		if err != nil {
+			return err
+		}
+
+		err = c.writeRelocationOutput(processedImages)
+		if err != nil {
+			return err
+		}
+
+		destination := c.RegistryDst
+		if c.isRepoDst() {
+			destination = c.RepoDsts[0]
+		}
+		lockPaths, err := c.LockOutputFlags.Paths([]string{destination})
+		if err != nil {
+			return err
+		}
+		err = c.writeLockOutput(processedImages, reg, lockPaths[0], destination)
+		if err != nil {
+			return err
+		}
+
+		if resultCollector != nil {
+			return c.printCopyResult(resultCollector, processedImages, tagGen, trafficMeter.Hosts())
+		}
+		return nil
 
 	default:
 		panic("Unreachable")
 	}
 }
 
-func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages, registry registry.Registry) error {
-	if c.LockOutputFlags.LockFilePath == "" {
+// pushFilesBundle Pushes, by its digest, the bundle in the directories provided with --file (-f) to the destination repository,
+// so that it is copied like a bundle pushed beforehand. Its tag is only written by the copy, once the images of the bundle are copied,
+// so the registry returned resolves the tag to the bundle pushed. The tag reference is returned so that the tag is kept in the lock output
+func (c *CopyOptions) pushFilesBundle(reg registry.Registry, logger *util.LevelLogger) (string, registry.Registry, error) {
+	uploadRef, err := regname.NewTag(fmt.Sprintf("%s:%s", c.RepoDsts[0], c.FileFlags.BundleTag), regname.WeakValidation)
+	if err != nil {
+		return "", nil, fmt.Errorf("Parsing '%s': %s", c.RepoDsts[0], err)
+	}
+
+	contents := bundle.NewContents(c.FileFlags.Files, c.FileFlags.ExcludedFilePaths, c.FileFlags.PreservePermissions)
+	err = contents.ValidateDependencies(reg, logger)
+	if err != nil {
+		return "", nil, err
+	}
+	// The tag held back is discarded, the copy writes it
+	digestRef, err := contents.Push(uploadRef, nil, &heldTagsRegistry{Registry: reg}, logger)
+	if err != nil {
+		return "", nil, err
+	}
+	bundleRef, err := regname.NewDigest(digestRef)
+	if err != nil {
+		return "", nil, fmt.Errorf("Parsing '%s': %s", digestRef, err)
+	}
+	return uploadRef.Name(), &pendingTagRegistry{Registry: reg, tag: uploadRef, digest: bundleRef}, nil
+}
+
+// pendingTagRegistry Resolves tag, that is not written yet, to digest
+type pendingTagRegistry struct {
+	registry.Registry
+	tag    regname.Tag
+	digest regname.Digest
+}
+
+// Get Retrieves the descriptor of the image, of digest when ref is tag
+func (r *pendingTagRegistry) Get(ref regname.Reference) (*regremote.Descriptor, error) {
+	return r.Registry.Get(r.resolve(ref))
+}
+
+// Digest Retrieves the digest of the image, of digest when ref is tag
+func (r *pendingTagRegistry) Digest(ref regname.Reference) (regv1.Hash, error) {
+	return r.Registry.Digest(r.resolve(ref))
+}
+
+// Image Retrieves the image, of digest when ref is tag
+func (r *pendingTagRegistry) Image(ref regname.Reference) (regv1.Image, error) {
+	return r.Registry.Image(r.resolve(ref))
+}
+
+// Index Retrieves the index, of digest when ref is tag
+func (r *pendingTagRegistry) Index(ref regname.Reference) (regv1.ImageIndex, error) {
+	return r.Registry.Index(r.resolve(ref))
+}
+
+func (r *pendingTagRegistry) resolve(ref regname.Reference) regname.Reference {
+	if ref.Name() == r.tag.Name() {
+		return r.digest
+	}
+	return ref
+}
+
+// copyToRepositories Copies origin to every repository provided with --to-repo, reading it only once
+func (c *CopyOptions) copyToRepositories(origin v1.CopyOrigin, opts v1.CopyOpts, reg registry.Registry, logger *util.LevelLogger,
+	dashboard *tui.CopyDashboard, trafficMeter *registry.TrafficMeter) error {
+	allProcessedImages, err := v1.CopyToRepositories(origin, c.RepoDsts, opts, reg)
+	if dashboard != nil {
+		dashboard.Stop()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.removeStateFile()
+	if err != nil {
+		return err
+	}
+
+	informUserToUseTheNonDistributableFlagWithDescriptors(
+		logger, c.IncludeNonDistributable, processedImagesNonDistLayer(allProcessedImages[0]))
+	logHostsTraffic(logger, trafficMeter.Hosts())
+
+	for _, processedImages := range allProcessedImages {
+		err = c.verifyCopy(processedImages, reg, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	lockPaths, err := c.LockOutputFlags.Paths(c.RepoDsts)
+	if err != nil {
+		return err
+	}
+	for i, processedImages := range allProcessedImages {
+		err = c.writeLockOutput(processedImages, reg, lockPaths[i], c.RepoDsts[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyCopy Verifies, when --verify-after-copy is provided, that the destination holds the images that were copied
+func (c *CopyOptions) verifyCopy(processedImages *ctlimgset.ProcessedImages, reg registry.Registry, logger *util.LevelLogger) error {
+	if !c.VerifyAfterCopy {
 		return nil
 	}
 
-	processedImageRootBundle := c.findProcessedImageRootBundle(processedImages)
+	result, err := v1.VerifyCopy(processedImages, v1.VerifyCopyOpts{
+		Logger:                  logger,
+		Concurrency:             c.Concurrency,
+		BlobSample:              c.VerifyBlobSample,
+		IncludeNonDistributable: c.IncludeNonDistributable,
+	}, reg)
+	if err != nil {
+		return err
+	}
+	logger.Logf("verified %d manifest(s) and %d blob(s) in the destination\n", result.Manifests, result.Blobs)
+	return nil
+}
+
+// printCopyResult Prints the document describing the images copied in the format selected with --output
+func (c *CopyOptions) printCopyResult(collector *copyResultCollector, processedImages *ctlimgset.ProcessedImages,
+	tagGen util.TagGenerator, hostsTraffic []registry.HostTraffic) error {
+	result, err := collector.Result(processedImages, tagGen, hostsTraffic)
+	if err != nil {
+		return err
+	}
+
+	resultBytes, err := result.Marshal(c.Output)
+	if err != nil {
+		return err
+	}
+	c.ui.PrintBlock(resultBytes)
+	return nil
+}
+
+// printCopyReport Prints the images that would be copied, and their sizes, instead of copying them
+func (c *CopyOptions) printCopyReport(origin v1.CopyOrigin, opts v1.CopyOpts, reg registry.Registry) error {
+	report, err := v1.CopyDryRun(origin, opts, reg)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintTable(copyReportTable("Images that would be copied", report))
+	c.ui.PrintLinef("Dry run: %d images would be copied, transferring %s (blobs shared between images are counted once)",
+		len(report.Images), util.HumanizeBytes(report.TotalSize))
+
+	return nil
+}
+
+// printCopyEstimate Prints the size of the images that would be copied and the total size. When copying to a single
+// repository, the manifests and blobs already present in it are checked and not counted in what would be transferred
+func (c *CopyOptions) printCopyEstimate(origin v1.CopyOrigin, opts v1.CopyOpts, reg registry.Registry) error {
+	report, err := v1.CopyDryRun(origin, opts, reg)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintTable(copyReportTable("Estimated size of the images", report))
+	c.ui.PrintLinef("Estimate: %d images, %s in total (blobs shared between images are counted once)",
+		len(report.Images), util.HumanizeBytes(report.TotalSize))
+
+	if len(c.RepoDsts) != 1 {
+		return nil
+	}
+	estimate, err := v1.EstimateTransfer(origin, c.RepoDsts[0], opts, reg)
+	if err != nil {
+		return fmt.Errorf("Estimating transfer to '%s': %s", c.RepoDsts[0], err)
+	}
+	c.ui.PrintLinef("%s already present in %s, copying would transfer %s",
+		util.HumanizeBytes(estimate.BytesPresent), estimate.Repository, util.HumanizeBytes(estimate.Bytes))
+	return nil
+}
+
+// copyReportTable Table with the size of each image of the report
+func copyReportTable(title string, report v1.CopyReport) uitable.Table {
+	table := uitable.Table{
+		Title:   title,
+		Content: "images",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Reference"),
+			uitable.NewHeader("Tag"),
+			uitable.NewHeader("Kind"),
+			uitable.NewHeader("Layers"),
+			uitable.NewHeader("Size"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 0, Asc: true},
+		},
+	}
+
+	for _, img := range report.Images {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(img.Ref),
+			uitable.NewValueString(img.Tag),
+			uitable.NewValueString(img.Kind),
+			uitable.NewValueInt(img.Layers),
+			uitable.NewValueString(util.HumanizeBytes(img.Size)),
+		})
+	}
+	return table
+}
+
+// removeStateFile Deletes the state file once every image was copied, so a following copy starts over
+func (c *CopyOptions) removeStateFile() error {
+	if c.StateFile == "" {
+		return nil
+	}
+	err := os.Remove(c.StateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Removing state file '%s': %s", c.StateFile, err)
+	}
+	return nil
+}
+
+// copyJournalDir folder where copies to a repository record their progress, so they can be resumed
+func copyJournalDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "imgpkg", "copy-journals")
+}
+
+// platforms Parses the platforms to copy, and checks that no bundle is being copied since the bundles
+// reference the image indexes by digest
+func (c *CopyOptions) platforms(bundlesFile BundlesFile) ([]regv1.Platform, error) {
+	if len(c.PlatformFlags.Platforms) == 0 {
+		return nil, nil
+	}
+
+	if len(bundlesFile.Bundles) > 0 {
+		return nil, fmt.Errorf("Flag --platform cannot be used when copying bundles, the digests of the image indexes referenced by the bundles would change (hint: list only images in the bundles file)")
+	}
+	if c.LockInputFlags.LockFilePath != "" {
+		bundleLock, _, err := lockconfig.NewLockFromPath(c.LockInputFlags.LockFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if bundleLock != nil {
+			return nil, fmt.Errorf("Flag --platform cannot be used when copying bundles, the digests of the image indexes referenced by the bundle would change")
+		}
+	}
+
+	return c.PlatformFlags.AsPlatforms()
+}
+
+// copyOptions options that change what is copied, an interrupted copy can only be resumed when they do not change
+func (c *CopyOptions) copyOptions(mediaTypePolicy ctlimgset.MediaTypePolicy) journal.Options {
+	allowedMediaTypes := append([]string{}, mediaTypePolicy.AllowedMediaTypes...)
+	sort.Strings(allowedMediaTypes)
+	platforms := append([]string{}, c.PlatformFlags.Platforms...)
+	sort.Strings(platforms)
+
+	return journal.Options{
+		ImgpkgVersion: Version,
+		Settings: map[string]string{
+			"include-non-distributable-layers": strconv.FormatBool(c.IncludeNonDistributable),
+			"allowed-media-types":              strings.Join(allowedMediaTypes, ","),
+			"warn-on-disallowed-media-types":   strconv.FormatBool(mediaTypePolicy.WarnOnly),
+			"repo-based-tags":                  strconv.FormatBool(c.UseRepoBasedTags),
+			"platform":                         strings.Join(platforms, ","),
+			"recompress":                       c.Recompress,
+		},
+	}
+}
+
+// writeLockOutput Writes the lockfile of the images copied to destination to lockPath, the path of --lock-output
+// or the one --lock-output-template renders for destination
+func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages, registry registry.Registry, lockPath, destination string) error {
+	if lockPath == "" {
+		return nil
+	}
+
+	if c.LockOutputFlags.PathTemplate != "" {
+		err := os.MkdirAll(filepath.Dir(lockPath), 0700)
+		if err != nil {
+			return fmt.Errorf("Creating directory of lockfile '%s': %s", lockPath, err)
+		}
+	}
+
+	// When copying multiple bundles and images a single ImagesLock with all of them is generated
+	if c.BundlesFileFlags.Path != "" {
+		return c.writeImagesLockOutput(processedImages, lockPath, destination)
+	}
+
+	processedImageRootBundle, err := c.findProcessedImageRootBundle(processedImages, registry)
+	if err != nil {
+		return err
+	}
 
 	if processedImageRootBundle != nil {
-		// this is an optimization to avoid getting an image descriptor for an ImageIndex, since we know
-		// it definetely will not be a bundle
+		// the OCI Image Index of a multi-arch bundle is copied as the root bundle
 		if processedImageRootBundle.ImageIndex != nil {
-			panic(fmt.Errorf("Internal inconsistency: '%s' should be a bundle but it is not", processedImageRootBundle.DigestRef))
+			return c.writeBundleLockOutput(processedImageRootBundle.DigestRef, processedImageRootBundle.UnprocessedImageRef.Tag, lockPath, destination)
 		}
 
 		plainImg := plainimage.NewFetchedPlainImageWithTag(processedImageRootBundle.DigestRef, processedImageRootBundle.UnprocessedImageRef.Tag, processedImageRootBundle.Image)
@@ -206,36 +1195,120 @@ func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages
 			panic(fmt.Errorf("Internal inconsistency: '%s' should be a bundle but it is not", processedImageRootBundle.DigestRef))
 		}
 
-		return c.writeBundleLockOutput(foundBundle)
+		return c.writeBundleLockOutput(foundBundle.DigestRef(), foundBundle.Tag(), lockPath, destination)
 	}
 
 	// if the tarball was created with an older version (prior to assign a label to the root bundle) and it contains a bundle
 	// then return an error to the user informing them to recreate the tarball, since we don't know which is the root bundle.
-	err := c.informUserIfTarballNeedsToBeRecreated(processedImages, registry)
+	err = c.informUserIfTarballNeedsToBeRecreated(processedImages, registry)
 	if err != nil {
 		return err
 	}
 
-	return c.writeImagesLockOutput(processedImages)
+	return c.writeImagesLockOutput(processedImages, lockPath, destination)
 }
 
-func (c *CopyOptions) findProcessedImageRootBundle(processedImages *ctlimgset.ProcessedImages) *ctlimgset.ProcessedImage {
-	var bundleProcessedImage *ctlimgset.ProcessedImage
-
+// findProcessedImageRootBundle Returns the root bundle copied, nil when there is none. When several bundles are
+// candidates the one selected with --root-bundle is returned
+func (c *CopyOptions) findProcessedImageRootBundle(processedImages *ctlimgset.ProcessedImages, registry registry.Registry) (*ctlimgset.ProcessedImage, error) {
+	var rootBundles []ctlimgset.ProcessedImage
 	for _, processedImage := range processedImages.All() {
 		if v1.IsRootBundle(processedImage) {
-			if bundleProcessedImage != nil {
-				panic("Internal inconsistency: expected only 1 root bundle")
+			rootBundles = append(rootBundles, processedImage)
+		}
+	}
+
+	if c.RootBundle == "" {
+		switch len(rootBundles) {
+		case 0:
+			return nil, nil
+		case 1:
+			return &rootBundles[0], nil
+		default:
+			return nil, fmt.Errorf("Unable to determine correct root bundle to use for lock-output, found %d root bundles: %s (hint: select one of them with --root-bundle)",
+				len(rootBundles), strings.Join(describeRootBundleCandidates(rootBundles), ", "))
+		}
+	}
+
+	candidates := rootBundles
+	if len(candidates) == 0 {
+		// Tarballs created by older versions do not mark the root bundles, every bundle is a candidate
+		for _, item := range processedImages.All() {
+			if item.ImageIndex != nil {
+				continue
+			}
+			plainImg := plainimage.NewFetchedPlainImageWithTag(item.DigestRef, item.UnprocessedImageRef.Tag, item.Image)
+			ok, err := bundle.NewBundleFromPlainImage(plainImg, registry).IsBundle()
+			if err != nil {
+				return nil, fmt.Errorf("Check if '%s' is bundle: %s", item.DigestRef, err)
 			}
-			bundleProcessedImage = &ctlimgset.ProcessedImage{
-				UnprocessedImageRef: processedImage.UnprocessedImageRef,
-				DigestRef:           processedImage.DigestRef,
-				Image:               processedImage.Image,
-				ImageIndex:          processedImage.ImageIndex,
+			if ok {
+				candidates = append(candidates, item)
 			}
 		}
 	}
-	return bundleProcessedImage
+
+	var selected []ctlimgset.ProcessedImage
+	for _, candidate := range candidates {
+		if rootBundleMatches(c.RootBundle, candidate) {
+			selected = append(selected, candidate)
+		}
+	}
+	switch len(selected) {
+	case 0:
+		return nil, fmt.Errorf("Expected --root-bundle '%s' to match one of the bundles copied: %s",
+			c.RootBundle, strings.Join(describeRootBundleCandidates(candidates), ", "))
+	case 1:
+		return &selected[0], nil
+	default:
+		return nil, fmt.Errorf("Expected --root-bundle '%s' to match a single bundle, but it matched: %s (hint: select the bundle by its digest)",
+			c.RootBundle, strings.Join(describeRootBundleCandidates(selected), ", "))
+	}
+}
+
+// rootBundleMatches Checks if the bundle has the digest, the name in its metadata or the source repository of selector
+func rootBundleMatches(selector string, bundleImg ctlimgset.ProcessedImage) bool {
+	for _, ref := range []string{bundleImg.UnprocessedImageRef.DigestRef, bundleImg.DigestRef} {
+		if strings.HasSuffix(ref, "@"+selector) {
+			return true
+		}
+	}
+	if name, found := rootBundleName(bundleImg); found && name == selector {
+		return true
+	}
+
+	sourceRef, err := regname.ParseReference(bundleImg.UnprocessedImageRef.DigestRef)
+	if err != nil {
+		return false
+	}
+	return sourceRef.Context().Name() == selector || sourceRef.Context().RepositoryStr() == selector
+}
+
+// rootBundleName Returns the name in the metadata of the bundle, if it has one
+func rootBundleName(bundleImg ctlimgset.ProcessedImage) (string, bool) {
+	if bundleImg.Image == nil {
+		return "", false
+	}
+	cfg, err := bundleImg.Image.ConfigFile()
+	if err != nil || cfg == nil {
+		return "", false
+	}
+	name, found := cfg.Config.Labels[bundle.BundleNameLabel]
+	return name, found && name != ""
+}
+
+// describeRootBundleCandidates Describes the bundles, with their source reference and the name in their metadata
+func describeRootBundleCandidates(bundles []ctlimgset.ProcessedImage) []string {
+	var descriptions []string
+	for _, bundleImg := range bundles {
+		description := bundleImg.UnprocessedImageRef.DigestRef
+		if name, found := rootBundleName(bundleImg); found {
+			description = fmt.Sprintf("%s (%s)", description, name)
+		}
+		descriptions = append(descriptions, description)
+	}
+	sort.Strings(descriptions)
+	return descriptions
 }
 
 func (c *CopyOptions) informUserIfTarballNeedsToBeRecreated(processedImages *ctlimgset.ProcessedImages, registry registry.Registry) error {
@@ -254,24 +1327,37 @@ func (c *CopyOptions) informUserIfTarballNeedsToBeRecreated(processedImages *ctl
 			return fmt.Errorf("Check if '%s' is bundle: %s", item.DigestRef, err)
 		}
 		if ok {
-			return fmt.Errorf("Unable to determine correct root bundle to use for lock-output. hint: if copying from a tarball, try re-generating the tarball")
+			return fmt.Errorf("Unable to determine correct root bundle to use for lock-output. hint: if copying from a tarball, try re-generating the tarball or select the bundle with --root-bundle")
 		}
 	}
 	return nil
 }
 
-func (c *CopyOptions) isRepoDst() bool { return c.RepoDst != "" }
+func (c *CopyOptions) isRepoDst() bool { return len(c.RepoDsts) > 0 }
+
+func (c *CopyOptions) isRegistryDst() bool { return c.RegistryDst != "" }
 
 func (c *CopyOptions) hasOneDst() bool {
-	repoSet := c.isRepoDst()
-	tarSet := c.TarFlags.IsDst()
-	return (repoSet || tarSet) && !(repoSet && tarSet)
+	var seen bool
+	for _, set := range []bool{c.isRepoDst(), c.isRegistryDst(), c.TarFlags.IsDst(), c.OCILayoutFlags.IsDst()} {
+		if set {
+			if seen {
+				return false
+			}
+			seen = true
+		}
+	}
+	return seen
+}
+
+func (c *CopyOptions) hasNoDst() bool {
+	return !c.isRepoDst() && !c.isRegistryDst() && !c.TarFlags.IsDst() && !c.OCILayoutFlags.IsDst()
 }
 
 func (c *CopyOptions) hasOneSrc() bool {
 	var seen bool
-	for _, ref := range []string{c.LockInputFlags.LockFilePath, c.TarFlags.TarSrc,
-		c.BundleFlags.Bundle, c.ImageFlags.Image} {
+	for _, ref := range []string{c.LockInputFlags.LockFilePath, strings.Join(c.LockInputFlags.ImageDigestFiles, ","), c.TarFlags.TarSrc,
+		c.OCILayoutFlags.LayoutSrc, c.BundleFlags.Bundle, c.ImageFlags.Image, c.BundlesFileFlags.Path, strings.Join(c.FileFlags.Files, ",")} {
 		if ref != "" {
 			if seen {
 				return false
@@ -282,7 +1368,7 @@ func (c *CopyOptions) hasOneSrc() bool {
 	return seen
 }
 
-func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.ProcessedImages) error {
+func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.ProcessedImages, lockPath, destination string) error {
 	imagesLock := lockconfig.ImagesLock{
 		LockVersion: lockconfig.LockVersion{
 			APIVersion: lockconfig.ImagesLockAPIVersion,
@@ -290,41 +1376,122 @@ func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.Processed
 		},
 	}
 
-	if c.LockInputFlags.LockFilePath != "" {
+	if c.LockInputFlags.IsImagesSrc() {
 		var err error
-		imagesLock, err = lockconfig.NewImagesLockFromPath(c.LockInputFlags.LockFilePath)
+		if len(c.LockInputFlags.ImageDigestFiles) > 0 {
+			imagesLock, err = lockconfig.NewImagesLockFromDigestFiles(c.LockInputFlags.ImageDigestFiles)
+		} else {
+			imagesLock, err = lockconfig.NewImagesLockFromPath(c.LockInputFlags.LockFilePath)
+		}
 		if err != nil {
 			return err
 		}
 		for i, image := range imagesLock.Images {
-			img, found := processedImages.FindByURL(ctlimgset.UnprocessedImageRef{DigestRef: image.Image})
+			unprocessedImageRef := ctlimgset.UnprocessedImageRef{DigestRef: image.Image}
+			if c.LockInputFlags.PreserveTags {
+				unprocessedImageRef.Tag = image.OriginalTag()
+			}
+			img, found := processedImages.FindByURL(unprocessedImageRef)
 			if !found {
 				return fmt.Errorf("Expected image '%s' to have been copied but was not", image.Image)
 			}
 			imagesLock.Images[i].Image = img.DigestRef
+			c.annotateLockImageWithTag(&imagesLock.Images[i], img)
 		}
 	} else {
 		for _, img := range processedImages.All() {
-			imagesLock.Images = append(imagesLock.Images, lockconfig.ImageRef{
+			imageRef := lockconfig.ImageRef{
 				Image: img.DigestRef,
-			})
+			}
+			c.annotateLockImageWithTag(&imageRef, img)
+			imagesLock.Images = append(imagesLock.Images, imageRef)
+		}
+	}
+
+	annotations, err := c.lockAnnotations(destination)
+	if err != nil {
+		return err
+	}
+	for key, value := range annotations {
+		if imagesLock.Annotations == nil {
+			imagesLock.Annotations = map[string]string{}
+		}
+		imagesLock.Annotations[key] = value
+	}
+
+	format, err := c.LockOutputFlags.Format(lockPath)
+	if err != nil {
+		return err
+	}
+	return imagesLock.WriteToPathInFormat(lockPath, format)
+}
+
+// lockAnnotations Returns the annotations describing the copy to destination, when requested, for the lock file written to it
+func (c *CopyOptions) lockAnnotations(destination string) (map[string]string, error) {
+	if !c.LockOutputFlags.Annotations {
+		return nil, nil
+	}
+
+	mediaTypePolicy, err := c.MediaTypeFlags.MediaTypePolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, source := range []string{c.BundleFlags.Bundle, c.ImageFlags.Image, c.LockInputFlags.LockFilePath,
+		c.TarFlags.TarSrc, c.OCILayoutFlags.LayoutSrc, c.BundlesFileFlags.Path} {
+		if source != "" {
+			sources = append(sources, source)
 		}
 	}
+	sources = append(sources, c.LockInputFlags.ImageDigestFiles...)
+	sources = append(sources, c.FileFlags.Files...)
+
+	return c.LockOutputFlags.SummaryAnnotations(sources, destination, c.copyOptions(mediaTypePolicy).Settings), nil
+}
+
+// annotateLockImageWithTag When requested, records in the image of the ImagesLock the tagged reference the image has in the destination
+func (c *CopyOptions) annotateLockImageWithTag(imageRef *lockconfig.ImageRef, img ctlimgset.ProcessedImage) {
+	if !c.LockOutputFlags.Tags || img.Tag == "" {
+		return
+	}
 
-	return imagesLock.WriteToPath(c.LockOutputFlags.LockFilePath)
+	digest, err := regname.NewDigest(img.DigestRef)
+	if err != nil {
+		panic(fmt.Sprintf("Internal consistency: %s should be a digest", img.DigestRef))
+	}
+	if imageRef.Annotations == nil {
+		imageRef.Annotations = map[string]string{}
+	}
+	imageRef.Annotations[lockconfig.ImageRefTagAnnotationKey] = digest.Context().Tag(img.Tag).Name()
 }
 
-func (c *CopyOptions) writeBundleLockOutput(bundle *bundle.Bundle) error {
+func (c *CopyOptions) writeBundleLockOutput(bundleRef, bundleTag, lockPath, destination string) error {
+	annotations, err := c.lockAnnotations(destination)
+	if err != nil {
+		return err
+	}
+
 	bundleLock := lockconfig.BundleLock{
 		LockVersion: lockconfig.LockVersion{
 			APIVersion: lockconfig.BundleLockAPIVersion,
 			Kind:       lockconfig.BundleLockKind,
 		},
+		Annotations: annotations,
 		Bundle: lockconfig.BundleRef{
-			Image: bundle.DigestRef(),
-			Tag:   bundle.Tag(),
+			Image: bundleRef,
+			Tag:   bundleTag,
 		},
 	}
 
-	return bundleLock.WriteToPath(c.LockOutputFlags.LockFilePath)
+	format, err := c.LockOutputFlags.Format(lockPath)
+	if err != nil {
+		return err
+	}
+	return bundleLock.WriteToPathInFormat(lockPath, format)
+}
+
+// terminalSize Returns the size of the terminal where the copy dashboard is drawn
+func terminalSize() (int, int, error) {
+	return term.GetSize(int(os.Stdout.Fd()))
 }
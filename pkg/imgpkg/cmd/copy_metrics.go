@@ -0,0 +1,107 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/metrics"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+)
+
+// copyImageDurationBuckets upper bounds, in seconds, of the buckets of the histogram of the time taken to copy each image
+var copyImageDurationBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+// copyMetricsCollector Keeps the time taken, the result and the bytes written of each image while it is copied,
+// and the traffic of the registry hosts, to write them to --metrics-output when the copy finishes
+type copyMetricsCollector struct {
+	lock         sync.Mutex
+	now          func() time.Time
+	started      time.Time
+	trafficMeter *registry.TrafficMeter
+
+	imagesStarted   map[string]time.Time
+	imageDurations  []float64
+	imagesSucceeded int
+	imagesFailed    int
+	bytes           map[string]int64
+}
+
+var _ ctlimgset.CopyObserver = &copyMetricsCollector{}
+
+func newCopyMetricsCollector(now func() time.Time) *copyMetricsCollector {
+	return &copyMetricsCollector{now: now, started: now(), imagesStarted: map[string]time.Time{}, bytes: map[string]int64{}}
+}
+
+func (c *copyMetricsCollector) ImagesFound(int) {}
+
+func (c *copyMetricsCollector) ImageStarted(ref string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.imagesStarted[ref] = c.now()
+}
+
+func (c *copyMetricsCollector) ImageProgress(ref string, complete, _ int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if complete > c.bytes[ref] {
+		c.bytes[ref] = complete
+	}
+}
+
+func (c *copyMetricsCollector) ImageFinished(ref string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if started, found := c.imagesStarted[ref]; found {
+		c.imageDurations = append(c.imageDurations, c.now().Sub(started).Seconds())
+		delete(c.imagesStarted, ref)
+	}
+	if err != nil {
+		c.imagesFailed++
+	} else {
+		c.imagesSucceeded++
+	}
+}
+
+// Metrics Builds the metrics of the copy that finished with copyErr
+func (c *copyMetricsCollector) Metrics(copyErr error) *metrics.Set {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	finished := c.now()
+	success := 1.0
+	if copyErr != nil {
+		success = 0
+	}
+	var bytesWritten int64
+	for _, written := range c.bytes {
+		bytesWritten += written
+	}
+
+	set := metrics.NewSet()
+	set.Gauge("imgpkg_build_info", "Version of imgpkg that ran the copy", metrics.Labels{"version": Version}, 1)
+	set.Gauge("imgpkg_copy_success", "1 when the copy succeeded, 0 when it failed", nil, success)
+	set.Gauge("imgpkg_copy_duration_seconds", "Time taken by the copy", nil, finished.Sub(c.started).Seconds())
+	set.Gauge("imgpkg_copy_last_run_timestamp_seconds", "Time the copy finished, in seconds since the Unix epoch", nil, float64(finished.Unix()))
+	set.Counter("imgpkg_copy_images_total", "Images written to the destination by result", metrics.Labels{"result": "succeeded"}, float64(c.imagesSucceeded))
+	set.Counter("imgpkg_copy_images_total", "Images written to the destination by result", metrics.Labels{"result": "failed"}, float64(c.imagesFailed))
+	set.Counter("imgpkg_copy_bytes_written_total", "Bytes of the layers written to the destination", nil, float64(bytesWritten))
+	set.Histogram("imgpkg_copy_image_duration_seconds", "Time taken to write each image to the destination", nil, copyImageDurationBuckets, c.imageDurations)
+
+	if c.trafficMeter != nil {
+		for _, traffic := range c.trafficMeter.Hosts() {
+			host := metrics.Labels{"host": traffic.Host}
+			set.Counter("imgpkg_registry_requests_total", "Requests sent to the registry host", host, float64(traffic.Requests))
+			set.Counter("imgpkg_registry_throttled_requests_total", "Requests the registry host answered with 429 Too Many Requests or 503 Service Unavailable",
+				host, float64(traffic.ThrottledRequests))
+			set.Counter("imgpkg_registry_bytes_sent_total", "Bytes sent to the registry host", host, float64(traffic.BytesSent))
+			set.Counter("imgpkg_registry_bytes_received_total", "Bytes received from the registry host", host, float64(traffic.BytesReceived))
+			set.Counter("imgpkg_registry_busy_seconds_total", "Time during which at least one request to the registry host was in flight", host, traffic.Busy.Seconds())
+		}
+	}
+	return set
+}
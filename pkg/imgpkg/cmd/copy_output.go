@@ -0,0 +1,159 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedigest"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+)
+
+// CopyOutputTypes Formats of the document describing the result of a copy
+var CopyOutputTypes = []string{"json", "yaml"}
+
+// CopyResult Document that describes every image processed by a copy, printed with --output
+type CopyResult struct {
+	Images []CopyResultImage `json:"images"`
+	// Hosts traffic exchanged with each registry host during the copy
+	Hosts []CopyResultHost `json:"hosts"`
+}
+
+// CopyResultImage Image processed by a copy
+type CopyResultImage struct {
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Tags        []string `json:"tags"`
+	// BytesTransferred bytes of the manifest and blobs written for the image, 0 when the image was already
+	// present in the destination. Blobs that the registry already had, or mounted from another repository, are included
+	BytesTransferred int64 `json:"bytesTransferred"`
+}
+
+// CopyResultHost Traffic exchanged with a registry host during a copy
+type CopyResultHost struct {
+	Host              string `json:"host"`
+	BytesSent         int64  `json:"bytesSent"`
+	BytesReceived     int64  `json:"bytesReceived"`
+	Requests          int64  `json:"requests"`
+	ThrottledRequests int64  `json:"throttledRequests"`
+	// BytesPerSecond bytes sent and received per second while requests to the host were in flight
+	BytesPerSecond int64 `json:"bytesPerSecond"`
+}
+
+func newCopyResultHosts(hostsTraffic []registry.HostTraffic) []CopyResultHost {
+	result := []CopyResultHost{}
+	for _, traffic := range hostsTraffic {
+		result = append(result, CopyResultHost{
+			Host:              traffic.Host,
+			BytesSent:         traffic.BytesSent,
+			BytesReceived:     traffic.BytesReceived,
+			Requests:          traffic.Requests,
+			ThrottledRequests: traffic.ThrottledRequests,
+			BytesPerSecond:    traffic.BytesPerSecond(),
+		})
+	}
+	return result
+}
+
+// logHostsTraffic Logs the throughput of each registry host the copy exchanged data with
+func logHostsTraffic(logger *util.LevelLogger, hostsTraffic []registry.HostTraffic) {
+	for _, traffic := range hostsTraffic {
+		if traffic.BytesSent+traffic.BytesReceived == 0 {
+			continue
+		}
+		logger.Logf("%s: sent %s, received %s at %s/s\n", traffic.Host, util.HumanizeBytes(traffic.BytesSent),
+			util.HumanizeBytes(traffic.BytesReceived), util.HumanizeBytes(traffic.BytesPerSecond()))
+	}
+}
+
+// copyResultCollector Keeps the bytes written for each image while it is copied
+type copyResultCollector struct {
+	lock  sync.Mutex
+	bytes map[string]int64
+}
+
+var _ ctlimgset.CopyObserver = &copyResultCollector{}
+
+func newCopyResultCollector() *copyResultCollector {
+	return &copyResultCollector{bytes: map[string]int64{}}
+}
+
+func (c *copyResultCollector) ImagesFound(int) {}
+
+func (c *copyResultCollector) ImageStarted(string) {}
+
+func (c *copyResultCollector) ImageProgress(ref string, complete, _ int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if complete > c.bytes[ref] {
+		c.bytes[ref] = complete
+	}
+}
+
+func (c *copyResultCollector) ImageFinished(string, error) {}
+
+// Result Builds the document for the images processed and the traffic of the registry hosts, tagGen must be the generator used by the copy
+func (c *copyResultCollector) Result(processedImages *ctlimgset.ProcessedImages, tagGen util.TagGenerator,
+	hostsTraffic []registry.HostTraffic) (CopyResult, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	result := CopyResult{Images: []CopyResultImage{}, Hosts: newCopyResultHosts(hostsTraffic)}
+	for _, img := range processedImages.All() {
+		dstRef, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return CopyResult{}, err
+		}
+
+		digestWrap := imagedigest.DigestWrap{}
+		err = digestWrap.DigestWrap(img.UnprocessedImageRef.DigestRef, img.OrigRef)
+		if err != nil {
+			return CopyResult{}, err
+		}
+		uploadTagRef, err := tagGen.GenerateTag(digestWrap, dstRef.Context())
+		if err != nil {
+			return CopyResult{}, err
+		}
+
+		tags := []string{uploadTagRef.Name()}
+		if img.Tag != "" {
+			tags = append(tags, dstRef.Context().Tag(img.Tag).Name())
+		}
+
+		result.Images = append(result.Images, CopyResultImage{
+			Source:           img.UnprocessedImageRef.DigestRef,
+			Destination:      img.DigestRef,
+			Tags:             tags,
+			BytesTransferred: c.bytes[img.UnprocessedImageRef.DigestRef],
+		})
+	}
+
+	sort.Slice(result.Images, func(i, j int) bool {
+		if result.Images[i].Source != result.Images[j].Source {
+			return result.Images[i].Source < result.Images[j].Source
+		}
+		return result.Images[i].Destination < result.Images[j].Destination
+	})
+	return result, nil
+}
+
+// Marshal Returns the document in the requested format
+func (r CopyResult) Marshal(outputType string) ([]byte, error) {
+	switch outputType {
+	case "json":
+		resultBytes, err := json.MarshalIndent(r, "", "  ")
+		return append(resultBytes, '\n'), err
+	case "yaml":
+		return yaml.Marshal(r)
+	default:
+		return nil, fmt.Errorf("Unknown output type '%s'", outputType)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyOutput(t *testing.T) {
+	t.Run("it prints the source, destination, tags and bytes transferred of the images copied, and the traffic of each host", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/app")
+		fakeRegistry.Build()
+
+		stdout := &bytes.Buffer{}
+		// Same as redirecting the output of imgpkg to a file, the logs are not printed so the output can be parsed
+		confUI := ui.NewWrappingConfUI(ui.NewNonTTYUI(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger())), ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-app")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDst = destRepo
+		copyOptions.Concurrency = 1
+		copyOptions.Output = "json"
+		require.NoError(t, copyOptions.Run())
+
+		var result CopyResult
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &result), "output: %s", stdout.String())
+		require.Len(t, result.Images, 1)
+		assert.Equal(t, img.RefDigest, result.Images[0].Source)
+		assert.Equal(t, destRepo+"@"+img.Digest, result.Images[0].Destination)
+		assert.Equal(t, []string{destRepo + ":" + "sha256-" + img.Digest[len("sha256:"):] + ".imgpkg"}, result.Images[0].Tags)
+		assert.Greater(t, result.Images[0].BytesTransferred, int64(0))
+
+		destRef, err := regname.ParseReference(destRepo)
+		require.NoError(t, err)
+		require.Len(t, result.Hosts, 1)
+		assert.Equal(t, destRef.Context().RegistryStr(), result.Hosts[0].Host)
+		assert.Greater(t, result.Hosts[0].BytesSent, int64(0))
+		assert.Greater(t, result.Hosts[0].BytesReceived, int64(0))
+		assert.Greater(t, result.Hosts[0].BytesPerSecond, int64(0))
+	})
+
+	t.Run("fails when the output type is unknown", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, Output: "xml"}).Run()
+		require.ErrorContains(t, err, "Flag --output can only have the following values [json, yaml]")
+	})
+
+	t.Run("fails when copying to a tar", func(t *testing.T) {
+		err := (&CopyOptions{TarFlags: TarFlags{TarDst: "foo"}, ImageFlags: ImageFlags{Image: "bar"}, Output: "json"}).Run()
+		require.ErrorContains(t, err, "Flag --output can only be used when copying to a repository (--to-repo) or a registry (--to-registry)")
+	})
+}
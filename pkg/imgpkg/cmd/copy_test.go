@@ -4,8 +4,25 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMultiDest(t *testing.T) {
@@ -14,7 +31,7 @@ func TestMultiDest(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-oci-layout, --to-repo or --to-registry") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -25,7 +42,7 @@ func TestNoDest(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-oci-layout, --to-repo or --to-registry") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -36,7 +53,7 @@ func TestMultiSrc(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), or --tar as a source") {
+	if !strings.Contains(err.Error(), "Expected either --lock, --image-digest-file, --bundle (-b), --image (-i), --bundles-file, --file (-f), --tar, or --oci-layout as a source") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -47,7 +64,7 @@ func TestNoSrc(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), or --tar as a source") {
+	if !strings.Contains(err.Error(), "Expected either --lock, --image-digest-file, --bundle (-b), --image (-i), --bundles-file, --file (-f), --tar, or --oci-layout as a source") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -62,3 +79,745 @@ func TestTarSrcWithTarDst(t *testing.T) {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
+
+func TestOCILayoutSrcWithTarDst(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarDst: "bar"}, OCILayoutFlags: OCILayoutFlags{LayoutSrc: "foo"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Cannot use OCI image layout source (--oci-layout) with tar destination (--to-tar)") {
+		t.Fatalf("Expected error message related to destinations, got: %s", err)
+	}
+}
+
+func TestPlatformWithBundle(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, BundleFlags: BundleFlags{Bundle: "bar"}, PlatformFlags: PlatformFlags{Platforms: []string{"linux/amd64"}}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --platform cannot be used when copying bundles") {
+		t.Fatalf("Expected error message related to platforms, got: %s", err)
+	}
+}
+
+func TestRepoDstWithRegistryDst(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, RegistryDst: "bar", ImageFlags: ImageFlags{Image: "baz"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-oci-layout, --to-repo or --to-registry") {
+		t.Fatalf("Expected error message related to destinations, got: %s", err)
+	}
+}
+
+func TestStateFileWithTarDst(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarDst: "foo"}, ImageFlags: ImageFlags{Image: "bar"}, StateFile: "copy.state"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --state-file can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the state file, got: %s", err)
+	}
+}
+
+func TestSignatureAnnotationWithTarDst(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarDst: "foo"}, ImageFlags: ImageFlags{Image: "bar"}, SignatureFlags: SignatureFlags{Annotations: map[string]string{"ticket": "SEC-1234"}}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --signature-annotation can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the signature annotations, got: %s", err)
+	}
+}
+
+func TestLockOutputWithMultipleRepoDst(t *testing.T) {
+	err := (&CopyOptions{RepoDst: "foo", RepoDsts: []string{"baz"}, ImageFlags: ImageFlags{Image: "bar"}, LockOutputFlags: LockOutputFlags{LockFilePath: "lock.yml"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --lock-output can only be used when copying to a single repository (--to-repo)") {
+		t.Fatalf("Expected error message related to the lock output, got: %s", err)
+	}
+}
+
+func TestPreserveLockTagsWithoutLock(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, LockInputFlags: LockInputFlags{PreserveTags: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --preserve-lock-tags can only be used when copying an ImagesLock (--lock)") {
+		t.Fatalf("Expected error message related to the lock tags, got: %s", err)
+	}
+}
+
+func TestAdditionalTagsWithBundle(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, BundleFlags: BundleFlags{Bundle: "bar"}, TagSelectionFlags: TagSelectionFlags{AdditionalTags: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flags --additional-tags and --all-tags can only be used when copying an image (-i)") {
+		t.Fatalf("Expected error message related to the additional tags, got: %s", err)
+	}
+}
+
+func TestExcludeImagesWithImage(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, ExcludeImages: []string{"*/docs"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --exclude-images can only be used when copying a bundle (-b)") {
+		t.Fatalf("Expected error message related to the excluded images, got: %s", err)
+	}
+}
+
+func TestTUIWithTarDst(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarDst: "foo"}, ImageFlags: ImageFlags{Image: "bar"}, TUI: true}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --tui can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the tui, got: %s", err)
+	}
+}
+
+func TestRecompressWithBundle(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, BundleFlags: BundleFlags{Bundle: "bar"}, Recompress: "zstd"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --recompress cannot be used when copying bundles") {
+		t.Fatalf("Expected error message related to the recompression, got: %s", err)
+	}
+}
+
+func TestUnknownProgressMode(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, ProgressFlags: ProgressFlags{Mode: "fancy"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --progress to be one of: auto, plain, none, got 'fancy'") {
+		t.Fatalf("Expected error message related to the progress mode, got: %s", err)
+	}
+}
+
+func TestNegativeMaxUploadRate(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, BandwidthFlags: BandwidthFlags{MaxUploadRate: -1}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --max-upload-rate to be 0 or greater, got -1") {
+		t.Fatalf("Expected error message related to the upload rate, got: %s", err)
+	}
+}
+
+func TestFailOnDeprecatedWithImage(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, FailOnDeprecated: true}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --fail-on-deprecated can only be used when copying bundles from a registry") {
+		t.Fatalf("Expected error message related to the deprecated bundles, got: %s", err)
+	}
+}
+
+func TestTarSplitSizeWithRepoDst(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, TarFlags: TarFlags{SplitSize: "4GB"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --to-tar-split-size can only be used when copying to a tar (--to-tar)") {
+		t.Fatalf("Expected error message related to the tar split size, got: %s", err)
+	}
+}
+
+func TestUnknownSignatureBackend(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, SignatureFlags: SignatureFlags{Backends: []string{"gpg"}}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Unknown signature backend 'gpg'") {
+		t.Fatalf("Expected error message related to the signature backend, got: %s", err)
+	}
+}
+
+func TestTarChecksumsWithRepoDst(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, TarFlags: TarFlags{Checksums: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --to-tar-checksums can only be used when copying to a tar (--to-tar)") {
+		t.Fatalf("Expected error message related to the tar checksums, got: %s", err)
+	}
+}
+
+func TestTarChunkingWithRepoDst(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, TarFlags: TarFlags{Chunking: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --to-tar-chunking can only be used when copying to a tar (--to-tar)") {
+		t.Fatalf("Expected error message related to the tar chunking, got: %s", err)
+	}
+}
+
+func TestVerifyBlobSampleWithoutVerifyAfterCopy(t *testing.T) {
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, VerifyBlobSample: 5}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --verify-blob-sample can only be used with --verify-after-copy") {
+		t.Fatalf("Expected error message related to the blob sample, got: %s", err)
+	}
+}
+
+func TestFileWithRegistryDst(t *testing.T) {
+	err := (&CopyOptions{RegistryDst: "foo", FileFlags: FileFlags{Files: []string{"bundle-dir"}}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --file (-f) can only be used when copying to a single repository (--to-repo)") {
+		t.Fatalf("Expected error message related to the bundle files, got: %s", err)
+	}
+}
+
+func TestOfflineWithRepoDst(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarSrc: "file.tar"}, RepoDsts: []string{"foo"}, RegistryFlags: RegistryFlags{Offline: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --offline cannot be used when copying to a registry (--to-repo or --to-registry)") {
+		t.Fatalf("Expected error message related to offline mode, got: %s", err)
+	}
+}
+
+func TestRequireCosignSignatureWithoutPublicKey(t *testing.T) {
+	err := (&CopyOptions{BundleFlags: BundleFlags{Bundle: "foo"}, RepoDsts: []string{"bar"}, SignatureFlags: SignatureFlags{RequireCosignSignature: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --cosign-public-key to be provided with --require-cosign-signature") {
+		t.Fatalf("Expected error message related to the cosign public key, got: %s", err)
+	}
+}
+
+func TestDestAnnotationWithTarDst(t *testing.T) {
+	err := (&CopyOptions{BundleFlags: BundleFlags{Bundle: "foo"}, TarFlags: TarFlags{TarDst: "file.tar"}, DestAnnotations: map[string]string{"key": "value"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --dest-annotation can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the destination annotations, got: %s", err)
+	}
+}
+
+func TestPrefetchTokensWithTarDst(t *testing.T) {
+	err := (&CopyOptions{BundleFlags: BundleFlags{Bundle: "foo"}, TarFlags: TarFlags{TarDst: "file.tar"}, PrefetchTokens: true}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --prefetch-tokens can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the token prefetch, got: %s", err)
+	}
+}
+
+func TestImageTimeoutWithTarDst(t *testing.T) {
+	err := (&CopyOptions{BundleFlags: BundleFlags{Bundle: "foo"}, TarFlags: TarFlags{TarDst: "file.tar"}, ImageTimeout: time.Minute}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --image-timeout can only be used when copying to a repository (--to-repo) or a registry (--to-registry)") {
+		t.Fatalf("Expected error message related to the image timeout, got: %s", err)
+	}
+}
+
+func TestRootBundleWithoutLockOutput(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarSrc: "file.tar"}, RepoDsts: []string{"foo"}, RootBundle: "sha256:abc"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --root-bundle can only be used with --lock-output") {
+		t.Fatalf("Expected error message related to the root bundle, got: %s", err)
+	}
+}
+
+func TestFindProcessedImageRootBundle(t *testing.T) {
+	rootBundle := func(repo, name string) ctlimgset.ProcessedImage {
+		img, err := random.Image(100, 1)
+		require.NoError(t, err)
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		cfg = cfg.DeepCopy()
+		cfg.Config.Labels = map[string]string{bundle.BundleConfigLabel: "true", bundle.BundleNameLabel: name}
+		img, err = mutate.ConfigFile(img, cfg)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		return ctlimgset.ProcessedImage{
+			UnprocessedImageRef: ctlimgset.UnprocessedImageRef{
+				DigestRef: fmt.Sprintf("%s@%s", repo, digest),
+				Labels:    map[string]string{"dev.carvel.imgpkg.copy.root-bundle": ""},
+			},
+			DigestRef: fmt.Sprintf("index.docker.io/dest/bundles@%s", digest),
+			Image:     img,
+		}
+	}
+	app1 := rootBundle("index.docker.io/carvel/app1-bundle", "app1")
+	app2 := rootBundle("index.docker.io/carvel/app2-bundle", "app2")
+	shared := rootBundle("index.docker.io/other/app2-bundle", "shared")
+	processedImages := ctlimgset.NewProcessedImages()
+	processedImages.Add(app1)
+	processedImages.Add(app2)
+	processedImages.Add(shared)
+
+	t.Run("without --root-bundle it suggests the flag", func(t *testing.T) {
+		_, err := (&CopyOptions{}).findProcessedImageRootBundle(processedImages, nil)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "found 3 root bundles: ")
+		assert.ErrorContains(t, err, app1.UnprocessedImageRef.DigestRef+" (app1)")
+		assert.ErrorContains(t, err, "hint: select one of them with --root-bundle")
+	})
+
+	t.Run("it selects the bundle by digest, name or repository", func(t *testing.T) {
+		for _, selector := range []string{
+			strings.Split(app1.DigestRef, "@")[1],
+			"app1",
+			"index.docker.io/carvel/app1-bundle",
+			"carvel/app1-bundle",
+		} {
+			selected, err := (&CopyOptions{RootBundle: selector}).findProcessedImageRootBundle(processedImages, nil)
+			require.NoError(t, err, selector)
+			assert.Equal(t, app1.DigestRef, selected.DigestRef, selector)
+		}
+	})
+
+	t.Run("when the selection does not match a single bundle it errors", func(t *testing.T) {
+		_, err := (&CopyOptions{RootBundle: "app3"}).findProcessedImageRootBundle(processedImages, nil)
+		assert.ErrorContains(t, err, "Expected --root-bundle 'app3' to match one of the bundles copied: ")
+
+		_, err = (&CopyOptions{RootBundle: "app2-bundle"}).findProcessedImageRootBundle(processedImages, nil)
+		assert.ErrorContains(t, err, "Expected --root-bundle 'app2-bundle' to match one of the bundles copied")
+
+		app2Dup := rootBundle("index.docker.io/carvel/app2-dup", "app2")
+		processedImages.Add(app2Dup)
+		_, err = (&CopyOptions{RootBundle: "app2"}).findProcessedImageRootBundle(processedImages, nil)
+		assert.ErrorContains(t, err, "Expected --root-bundle 'app2' to match a single bundle, but it matched: ")
+		assert.ErrorContains(t, err, "hint: select the bundle by its digest")
+	})
+}
+
+func TestCopyFiles(t *testing.T) {
+	t.Run("it pushes the bundle directory to the destination and copies its images next to it", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/app")
+		reg := fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+`, img.RefDigest)))
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/app-bundle")
+		lockPath := filepath.Join(t.TempDir(), "bundle.lock.yml")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.FileFlags = FileFlags{Files: []string{bundleDir}, BundleTag: "1.0.0"}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		copyOptions.LockOutputFlags.LockFilePath = lockPath
+		require.NoError(t, copyOptions.Run())
+
+		bundleLock, err := lockconfig.NewBundleLockFromPath(lockPath)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(bundleLock.Bundle.Image, destRepo+"@sha256:"))
+		assert.Equal(t, "1.0.0", bundleLock.Bundle.Tag)
+
+		imgRef, err := regname.NewDigest(destRepo + "@" + img.Digest)
+		require.NoError(t, err)
+		_, err = reg.Digest(imgRef)
+		assert.NoError(t, err, "image of the bundle was not copied to the destination")
+	})
+
+	t.Run("when the images of the bundle cannot be copied, the bundle is not tagged", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		reg := fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s@sha256:%s
+`, fakeRegistry.ReferenceOnTestServer("library/missing"), strings.Repeat("a", 64))))
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/app-bundle")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.FileFlags = FileFlags{Files: []string{bundleDir}, BundleTag: "1.0.0"}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		require.Error(t, copyOptions.Run())
+
+		tagRef, err := regname.NewTag(destRepo + ":1.0.0")
+		require.NoError(t, err)
+		_, err = reg.Digest(tagRef)
+		assert.Error(t, err, "the bundle was tagged before its images were copied")
+	})
+}
+
+func TestCopyMultiArchBundle(t *testing.T) {
+	t.Run("it copies the bundle of every platform, and their images, with the image index", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/app")
+		reg := fakeRegistry.Build()
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		bundleRef := pushMultiArchBundle(t, confUI, fakeRegistry.ReferenceOnTestServer("library/app-bundle"), fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+`, img.RefDigest))
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+		lockPath := filepath.Join(t.TempDir(), "bundle.lock.yml")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.BundleFlags = BundleFlags{Bundle: bundleRef}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		copyOptions.LockOutputFlags.LockFilePath = lockPath
+		require.NoError(t, copyOptions.Run())
+
+		srcIndexRef, err := regname.NewTag(bundleRef)
+		require.NoError(t, err)
+		srcIndexDigest, err := reg.Digest(srcIndexRef)
+		require.NoError(t, err)
+
+		bundleLock, err := lockconfig.NewBundleLockFromPath(lockPath)
+		require.NoError(t, err)
+		assert.Equal(t, destRepo+"@"+srcIndexDigest.String(), bundleLock.Bundle.Image)
+		assert.Equal(t, "latest", bundleLock.Bundle.Tag)
+
+		imgRef, err := regname.NewDigest(destRepo + "@" + img.Digest)
+		require.NoError(t, err)
+		_, err = reg.Digest(imgRef)
+		assert.NoError(t, err, "image of the bundles was not copied to the destination")
+
+		pulledDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleLock.Bundle.Image}, OutputPath: pulledDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(pulledDir, "platform.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "platform: "+bundle.HostPlatform(), string(contents))
+		relocatedLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(pulledDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		require.Len(t, relocatedLock.Images, 1)
+		assert.Equal(t, destRepo+"@"+img.Digest, relocatedLock.Images[0].Image)
+	})
+}
+
+func TestCopyLockOutputAnnotations(t *testing.T) {
+	t.Run("when --lock-output is not provided it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, LockOutputFlags: LockOutputFlags{Annotations: true}}).Run()
+		require.ErrorContains(t, err, "Flag --lock-output-annotations can only be used with --lock-output")
+	})
+
+	t.Run("it annotates the lock with how the images were copied", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/app")
+		fakeRegistry.Build()
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-app")
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		copyOptions.IncludeNonDistributable = true
+		copyOptions.LockOutputFlags = LockOutputFlags{LockFilePath: lockPath, Annotations: true}
+		require.NoError(t, copyOptions.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 1)
+		assert.Equal(t, "imgpkg "+Version, imagesLock.Annotations[lockconfig.LockGeneratedByAnnotationKey])
+		_, err = time.Parse(time.RFC3339, imagesLock.Annotations[lockconfig.LockGeneratedAtAnnotationKey])
+		assert.NoError(t, err)
+		assert.Equal(t, img.RefDigest, imagesLock.Annotations[lockconfig.LockSourceAnnotationKey])
+		assert.Equal(t, destRepo, imagesLock.Annotations[lockconfig.LockDestinationAnnotationKey])
+		assert.Contains(t, imagesLock.Annotations[lockconfig.LockOptionsAnnotationKey], "include-non-distributable-layers=true")
+	})
+}
+
+func TestCopyLockOutputFormat(t *testing.T) {
+	t.Run("when --lock-output is not provided it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, LockOutputFlags: LockOutputFlags{OutputFormat: "json"}}).Run()
+		require.ErrorContains(t, err, "Flag --lock-output-format can only be used with --lock-output or --lock-output-template")
+	})
+
+	t.Run("when the format is unknown it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, LockOutputFlags: LockOutputFlags{LockFilePath: "lock.yml", OutputFormat: "toml"}}).Run()
+		require.ErrorContains(t, err, "Parsing --lock-output-format: Unknown lock format 'toml' (known: yaml, json)")
+	})
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/app")
+	fakeRegistry.Build()
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-app")
+
+	copyWithLockOutput := func(t *testing.T, lockOutputFlags LockOutputFlags) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		copyOptions.LockOutputFlags = lockOutputFlags
+		require.NoError(t, copyOptions.Run())
+	}
+
+	t.Run("when the lock output ends in .json it writes the lock as JSON", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock.json")
+		copyWithLockOutput(t, LockOutputFlags{LockFilePath: lockPath})
+
+		contents, err := os.ReadFile(lockPath)
+		require.NoError(t, err)
+		var imagesLock lockconfig.ImagesLock
+		require.NoError(t, json.Unmarshal(contents, &imagesLock))
+		assert.Equal(t, lockconfig.ImagesLockKind, imagesLock.Kind)
+		require.Len(t, imagesLock.Images, 1)
+		assert.Equal(t, destRepo+"@"+img.Digest, imagesLock.Images[0].Image)
+
+		_, err = lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err, "the JSON lock can be read back")
+	})
+
+	t.Run("when --lock-output-format is provided it takes precedence over the extension", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock")
+		copyWithLockOutput(t, LockOutputFlags{LockFilePath: lockPath, OutputFormat: "json"})
+
+		contents, err := os.ReadFile(lockPath)
+		require.NoError(t, err)
+		assert.True(t, json.Valid(contents), "expected the lock to be JSON, got:\n%s", contents)
+	})
+}
+
+func TestCopyImageDigestFile(t *testing.T) {
+	t.Run("when another source is provided it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"}, LockInputFlags: LockInputFlags{ImageDigestFiles: []string{"image.digest"}}}).Run()
+		require.ErrorContains(t, err, "Expected either --lock, --image-digest-file, --bundle (-b), --image (-i), --bundles-file, --file (-f), --tar, or --oci-layout as a source")
+	})
+
+	t.Run("it copies the images of the files, keeping their tags, and writes their ImagesLock", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		app := fakeRegistry.WithRandomImage("library/app")
+		worker := fakeRegistry.WithRandomImage("library/worker")
+		reg := fakeRegistry.Build()
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		digestFile := filepath.Join(t.TempDir(), "image-refs.txt")
+		appTagged := fakeRegistry.ReferenceOnTestServer("library/app:1.0") + "@" + app.Digest
+		require.NoError(t, os.WriteFile(digestFile, []byte(appTagged+"\n"+worker.RefDigest+"\n"), 0600))
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied")
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.LockInputFlags = LockInputFlags{ImageDigestFiles: []string{digestFile}, PreserveTags: true}
+		copyOptions.RepoDsts = []string{destRepo}
+		copyOptions.Concurrency = 1
+		copyOptions.LockOutputFlags = LockOutputFlags{LockFilePath: lockPath}
+		require.NoError(t, copyOptions.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 2)
+		assert.Equal(t, destRepo+"@"+app.Digest, imagesLock.Images[0].Image)
+		assert.Equal(t, destRepo+"@"+worker.Digest, imagesLock.Images[1].Image)
+
+		tagRef, err := regname.NewTag(destRepo + ":1.0")
+		require.NoError(t, err)
+		digest, err := reg.Digest(tagRef)
+		require.NoError(t, err)
+		assert.Equal(t, app.Digest, digest.String(), "the tag of the reference in the file should be preserved")
+	})
+}
+
+func TestCopyLockOutputTemplate(t *testing.T) {
+	t.Run("when --lock-output is also provided it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"foo"}, ImageFlags: ImageFlags{Image: "bar"},
+			LockOutputFlags: LockOutputFlags{LockFilePath: "lock.yml", PathTemplate: "locks/{{.Dest}}.yml"}}).Run()
+		require.ErrorContains(t, err, "Flag --lock-output-template cannot be used with --lock-output")
+	})
+
+	t.Run("when the destination is not a repository it errors", func(t *testing.T) {
+		err := (&CopyOptions{TarFlags: TarFlags{TarDst: "foo.tar"}, ImageFlags: ImageFlags{Image: "bar"},
+			LockOutputFlags: LockOutputFlags{PathTemplate: "locks/{{.Dest}}.yml"}}).Run()
+		require.ErrorContains(t, err, "Flag --lock-output-template can only be used when copying to a repository (--to-repo)")
+	})
+
+	t.Run("when the template renders the same path for two destinations it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"us-registry/app", "eu-registry/app"}, ImageFlags: ImageFlags{Image: "bar"},
+			LockOutputFlags: LockOutputFlags{PathTemplate: "locks/lock.yml"}}).Run()
+		require.ErrorContains(t, err, "Expected --lock-output-template to render a different path for each destination, "+
+			"'us-registry/app' and 'eu-registry/app' both render 'locks/lock.yml' (hint: use {{.Dest}} in the template)")
+	})
+
+	t.Run("when the template uses an unknown field it errors", func(t *testing.T) {
+		err := (&CopyOptions{RepoDsts: []string{"us-registry/app"}, ImageFlags: ImageFlags{Image: "bar"},
+			LockOutputFlags: LockOutputFlags{PathTemplate: "locks/{{.Destination}}.yml"}}).Run()
+		require.ErrorContains(t, err, "Rendering --lock-output-template for 'us-registry/app'")
+	})
+
+	t.Run("it writes a lock file for each destination with the images copied to it", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/app")
+		fakeRegistry.Build()
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		locksDir := t.TempDir()
+		destRepos := []string{fakeRegistry.ReferenceOnTestServer("us/app"), fakeRegistry.ReferenceOnTestServer("eu/app")}
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDsts = destRepos
+		copyOptions.Concurrency = 1
+		copyOptions.LockOutputFlags = LockOutputFlags{PathTemplate: filepath.Join(locksDir, "locks", "{{.Index}}-{{.Dest}}.yml"), Annotations: true}
+		require.NoError(t, copyOptions.Run())
+
+		for i, destRepo := range destRepos {
+			lockPath := filepath.Join(locksDir, "locks", fmt.Sprintf("%d-%s.yml", i, strings.NewReplacer("/", "_", ":", "_").Replace(destRepo)))
+			imagesLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+			require.NoError(t, err)
+			require.Len(t, imagesLock.Images, 1)
+			assert.Equal(t, destRepo+"@"+img.Digest, imagesLock.Images[0].Image)
+			assert.Equal(t, destRepo, imagesLock.Annotations[lockconfig.LockDestinationAnnotationKey])
+		}
+	})
+}
+
+func TestCopyMetricsOutput(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/app")
+	fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	t.Run("it writes the metrics of the copy", func(t *testing.T) {
+		metricsPath := filepath.Join(t.TempDir(), "metrics.prom")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDsts = []string{fakeRegistry.ReferenceOnTestServer("library/copied-app")}
+		copyOptions.Concurrency = 1
+		copyOptions.MetricsOutput = metricsPath
+		require.NoError(t, copyOptions.Run())
+
+		contents, err := os.ReadFile(metricsPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), "imgpkg_copy_success 1\n")
+		assert.Contains(t, string(contents), `imgpkg_copy_images_total{result="succeeded"} 1`+"\n")
+		assert.Contains(t, string(contents), `imgpkg_copy_image_duration_seconds_count 1`+"\n")
+		assert.Contains(t, string(contents), fmt.Sprintf(`imgpkg_registry_requests_total{host="%s"} `, fakeRegistry.Host()))
+	})
+
+	t.Run("it writes the metrics when the copy fails", func(t *testing.T) {
+		metricsPath := filepath.Join(t.TempDir(), "metrics.prom")
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: fakeRegistry.ReferenceOnTestServer("library/missing:1.0.0")}
+		copyOptions.RepoDsts = []string{fakeRegistry.ReferenceOnTestServer("library/copied-app")}
+		copyOptions.Concurrency = 1
+		copyOptions.MetricsOutput = metricsPath
+		require.Error(t, copyOptions.Run())
+
+		contents, err := os.ReadFile(metricsPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), "imgpkg_copy_success 0\n")
+	})
+}
+
+func TestCopyEstimate(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/app")
+	fakeRegistry.CopyImage(*img, "library/copied-app")
+	fakeRegistry.Build()
+
+	runEstimate := func(repoDsts []string) string {
+		stdout := &bytes.Buffer{}
+		confUI := ui.NewWrappingConfUI(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()), ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		copyOptions := NewCopyOptions(confUI)
+		copyOptions.ImageFlags = ImageFlags{Image: img.RefDigest}
+		copyOptions.RepoDsts = repoDsts
+		copyOptions.Concurrency = 1
+		copyOptions.Estimate = true
+		require.NoError(t, copyOptions.Run())
+		return stdout.String()
+	}
+
+	t.Run("it prints the size of the images without a destination", func(t *testing.T) {
+		output := runEstimate(nil)
+		assert.Contains(t, output, img.RefDigest)
+		assert.Contains(t, output, "Estimate: 1 images")
+		assert.NotContains(t, output, "copying would transfer")
+	})
+
+	t.Run("when copying to a repository that has the image, it reports that nothing would be transferred", func(t *testing.T) {
+		output := runEstimate([]string{fakeRegistry.ReferenceOnTestServer("library/copied-app")})
+		assert.Contains(t, output, "Estimate: 1 images")
+		assert.Contains(t, output, "copying would transfer 0 B")
+	})
+
+	t.Run("fails when used with --dry-run", func(t *testing.T) {
+		err := (&CopyOptions{ImageFlags: ImageFlags{Image: "bar"}, Estimate: true, DryRun: true}).Run()
+		require.ErrorContains(t, err, "Flag --estimate cannot be used with --dry-run")
+	})
+}
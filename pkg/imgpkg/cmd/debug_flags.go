@@ -13,11 +13,15 @@ import (
 // DebugFlags indicates debugging
 type DebugFlags struct {
 	Debug bool
+	// RecordRun records the command so that support-bundle can include it
+	RecordRun bool
 }
 
-// Set adds the debug flag to the command
+// Set adds the debug flags to the command
 func (f *DebugFlags) Set(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVar(&f.Debug, "debug", false, "Enables debugging")
+	cmd.PersistentFlags().BoolVar(&f.RecordRun, "record-run", false,
+		"Record the arguments, the outcome and the registry logs of the command in the user cache folder, so that support-bundle can include them")
 }
 
 // ConfigureDebug set debug output to os.Stdout
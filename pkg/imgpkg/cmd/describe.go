@@ -8,7 +8,10 @@ import (
 	"sort"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
 	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
 	goui "github.com/cppforlife/go-cli-ui/ui"
 	regname "github.com/google/go-containerregistry/pkg/name"
@@ -18,7 +21,7 @@ import (
 
 var (
 	// DescribeOutputType Possible output options
-	DescribeOutputType = []string{"text", "yaml"}
+	DescribeOutputType = []string{"text", "yaml", "markdown"}
 )
 
 // DescribeOptions Command Line options that can be provided to the describe command
@@ -32,6 +35,9 @@ type DescribeOptions struct {
 	OutputType             string
 	Layers                 bool
 	IncludeCosignArtifacts bool
+
+	RepoDst          string
+	EstimateTransfer bool
 }
 
 // NewDescribeOptions constructor for building a DescribeOptions, holding values derived via flags
@@ -47,15 +53,26 @@ func NewDescribeCmd(o *DescribeOptions) *cobra.Command {
 		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
 		Example: `
     # Describe a bundle
-    imgpkg describe -b carvel.dev/app1-bundle`,
+    imgpkg describe -b carvel.dev/app1-bundle
+
+    # Describe a bundle stored in a local OCI image layout
+    imgpkg describe -b oci:./app1-bundle-layout
+
+    # Describe a bundle as markdown, suitable for release notes
+    imgpkg describe -b carvel.dev/app1-bundle -o markdown
+
+    # Describe a bundle and how much a copy to another repository would transfer
+    imgpkg describe -b carvel.dev/app1-bundle --to-repo internal-registry/app1-bundle --estimate-transfer`,
 	}
 
 	o.BundleFlags.SetCopy(cmd)
 	o.RegistryFlags.Set(cmd)
 	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
-	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", "text", "Type of output possible values: [text, yaml]")
+	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", "text", "Type of output possible values: [text, yaml, markdown]")
 	cmd.Flags().BoolVarP(&o.Layers, "layers", "", true, "Retrieve image layers info (Default: false)")
 	cmd.Flags().BoolVar(&o.IncludeCosignArtifacts, "cosign-artifacts", true, "Retrieve cosign artifact information (Default: true)")
+	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Repository the bundle would be copied to (example: internal-registry/app1-bundle)")
+	cmd.Flags().BoolVar(&o.EstimateTransfer, "estimate-transfer", false, "Check which blobs are already present in the repository provided with --to-repo and report what a copy would transfer")
 	return cmd
 }
 
@@ -67,31 +84,87 @@ func (d *DescribeOptions) Run() error {
 	}
 	logLevel := util.LogWarn
 
+	simpleReg, err := registry.NewSimpleRegistry(d.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+	bundleRef, reg, err := d.BundleFlags.ResolveOCILayout(simpleReg)
+	if err != nil {
+		return err
+	}
+
+	var signatureRetriever v1.SignatureFetcher
+	if d.IncludeCosignArtifacts {
+		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), d.Concurrency)
+	} else {
+		signatureRetriever = signature.NewNoop()
+	}
+
 	levelLogger := util.NewUILevelLogger(logLevel, util.NewLogger(d.ui))
-	description, err := v1.Describe(
-		d.BundleFlags.Bundle,
+	description, err := v1.DescribeWithRegistryAndSignatureFetcher(
+		bundleRef,
 		v1.DescribeOpts{
 			Logger:                 levelLogger,
 			Concurrency:            d.Concurrency,
 			IncludeCosignArtifacts: d.IncludeCosignArtifacts,
 			Layers:                 d.Layers,
 		},
-		d.RegistryFlags.AsRegistryOpts())
+		reg, signatureRetriever)
 	if err != nil {
 		return err
 	}
 
+	var estimate *v1.TransferEstimate
+	if d.EstimateTransfer {
+		estimate, err = d.estimateTransfer(description.Image, reg, levelLogger)
+		if err != nil {
+			return err
+		}
+	}
+
 	ttyEnabledLogger := util.NewUILevelLogger(logLevel, util.NewLoggerNoTTY(d.ui))
 	if d.OutputType == "text" {
 		p := bundleTextPrinter{logger: ttyEnabledLogger}
 		p.Print(description)
+		if estimate != nil {
+			p.PrintTransferEstimate(*estimate)
+		}
 	} else if d.OutputType == "yaml" {
 		p := bundleYAMLPrinter{logger: ttyEnabledLogger}
-		return p.Print(description)
+		return p.Print(description, estimate)
+	} else if d.OutputType == "markdown" {
+		p := bundleMarkdownPrinter{logger: ttyEnabledLogger}
+		p.Print(description)
+		if estimate != nil {
+			p.PrintTransferEstimate(*estimate)
+		}
 	}
 	return nil
 }
 
+// estimateTransfer Checks what copying the bundle to the repository provided with --to-repo would transfer
+func (d *DescribeOptions) estimateTransfer(bundleRef string, reg registry.Registry, logger *util.LevelLogger) (*v1.TransferEstimate, error) {
+	var signatureRetriever v1.SignatureFetcher
+	if d.IncludeCosignArtifacts {
+		signatureRetriever = signature.NewSignaturesWithFinders(signature.NewCosignFinders(reg), d.Concurrency)
+	} else {
+		signatureRetriever = signature.NewNoop()
+	}
+
+	opts := v1.CopyOpts{
+		Logger:             logger,
+		ImageSet:           ctlimgset.NewImageSet(d.Concurrency, logger, util.DefaultTagGenerator{}),
+		Concurrency:        d.Concurrency,
+		SignatureRetriever: signatureRetriever,
+	}
+
+	estimate, err := v1.EstimateTransfer(v1.CopyOrigin{BundleRef: bundleRef}, d.RepoDst, opts, reg)
+	if err != nil {
+		return nil, fmt.Errorf("Estimating transfer to '%s': %s", d.RepoDst, err)
+	}
+	return &estimate, nil
+}
+
 func (d *DescribeOptions) validateFlags() error {
 	outputType := ""
 	for _, s := range DescribeOutputType {
@@ -101,7 +174,13 @@ func (d *DescribeOptions) validateFlags() error {
 		}
 	}
 	if outputType == "" {
-		return fmt.Errorf("--output-type can only have the following values [text, yaml]")
+		return fmt.Errorf("--output-type can only have the following values [text, yaml, markdown]")
+	}
+	if d.EstimateTransfer && d.RepoDst == "" {
+		return fmt.Errorf("Expected --to-repo to be provided with --estimate-transfer")
+	}
+	if d.RepoDst != "" && !d.EstimateTransfer {
+		return fmt.Errorf("Expected --estimate-transfer to be provided with --to-repo")
 	}
 	return nil
 }
@@ -116,11 +195,27 @@ func (p bundleTextPrinter) Print(description v1.Description) {
 		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
 	}
 	p.logger.Logf("Bundle SHA: %s\n", bundleRef.Identifier())
+	if description.Deprecated != nil {
+		p.logger.Logf("Deprecated: %s\n", deprecationText(*description.Deprecated))
+	}
+	if len(description.ManifestAnnotations) > 0 {
+		p.logger.Logf("Manifest annotations:\n")
+		for _, key := range sortedKeys(description.ManifestAnnotations) {
+			p.logger.Logf("  %s: %s\n", key, description.ManifestAnnotations[key])
+		}
+	}
 
 	p.logger.Logf("\n")
 	p.printerRec(description, p.logger, p.logger)
 }
 
+func (p bundleTextPrinter) PrintTransferEstimate(estimate v1.TransferEstimate) {
+	p.logger.Logf("\nTransfer to %s:\n", estimate.Repository)
+	p.logger.Logf("  Manifests: %d to push, %d already present\n", estimate.Manifests, estimate.ManifestsPresent)
+	p.logger.Logf("  Blobs: %d to upload, %d already present\n", estimate.Blobs, estimate.BlobsPresent)
+	p.logger.Logf("  Size: %s to transfer, %s already present\n", util.HumanizeBytes(estimate.Bytes), util.HumanizeBytes(estimate.BytesPresent))
+}
+
 func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger Logger, logger Logger) {
 	indentLogger := util.NewIndentedLogger(logger)
 	if len(description.Content.Bundles) == 0 && len(description.Content.Images) == 0 {
@@ -141,6 +236,9 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 		indentLogger.Logf("- Image: %s\n", b.Image)
 		indentLogger.Logf("  Type: Bundle\n")
 		indentLogger.Logf("  Origin: %s\n", b.Origin)
+		if b.Deprecated != nil {
+			indentLogger.Logf("  Deprecated: %s\n", deprecationText(*b.Deprecated))
+		}
 		if len(b.Layers) > 0 {
 			indentLogger.Logf("  Layers:\n")
 			for _, d := range b.Layers {
@@ -166,6 +264,9 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 		}
 		indentLogger.Logf("- Image: %s\n", image.Image)
 		indentLogger.Logf("  Type: %s\n", image.ImageType)
+		if image.ArtifactType != "" {
+			indentLogger.Logf("  Artifact Type: %s\n", image.ArtifactType)
+		}
 		if image.ImageType == bundle.ContentImage {
 			indentLogger.Logf("  Origin: %s\n", image.Origin)
 		}
@@ -196,11 +297,23 @@ func (p bundleTextPrinter) printAnnotations(annotations map[string]string, inden
 	}
 }
 
+// deprecationText Message of the deprecation followed by the successor of the bundle
+func deprecationText(deprecation v1.Deprecation) string {
+	text := deprecation.Message
+	if text == "" {
+		text = "yes"
+	}
+	if deprecation.Successor != "" {
+		text += fmt.Sprintf(" (successor: %s)", deprecation.Successor)
+	}
+	return text
+}
+
 type bundleYAMLPrinter struct {
 	logger Logger
 }
 
-func (p bundleYAMLPrinter) Print(description v1.Description) error {
+func (p bundleYAMLPrinter) Print(description v1.Description, estimate *v1.TransferEstimate) error {
 	bundleRef, err := regname.ParseReference(description.Image)
 	if err != nil {
 		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
@@ -214,5 +327,13 @@ func (p bundleYAMLPrinter) Print(description v1.Description) error {
 	p.logger.Logf("sha: %s\n", bundleRef.Identifier())
 	p.logger.Logf(string(yamlDesc))
 
+	if estimate != nil {
+		yamlEstimate, err := yaml.Marshal(map[string]v1.TransferEstimate{"transferEstimate": *estimate})
+		if err != nil {
+			return err
+		}
+		p.logger.Logf(string(yamlEstimate))
+	}
+
 	return nil
 }
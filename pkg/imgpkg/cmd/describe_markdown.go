@@ -0,0 +1,153 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// versionAnnotations Annotations checked, in order, to find the version of a bundle or image
+var versionAnnotations = []string{
+	"org.opencontainers.image.version",
+	"version",
+}
+
+type bundleMarkdownPrinter struct {
+	logger Logger
+}
+
+// Print writes the bundle description as a markdown bill of materials.
+// Every bundle gets its own section, listing the images it references, so nested bundles
+// are rendered after the bundle that includes them.
+func (p bundleMarkdownPrinter) Print(description v1.Description) {
+	bundleRef, err := regname.ParseReference(description.Image)
+	if err != nil {
+		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
+	}
+
+	p.logger.Logf("# Bundle `%s`\n", bundleRef.Identifier())
+	if len(description.ManifestAnnotations) > 0 {
+		p.logger.Logf("\n")
+		for _, key := range sortedKeys(description.ManifestAnnotations) {
+			p.logger.Logf("- %s: %s\n", markdownEscape(key), markdownEscape(description.ManifestAnnotations[key]))
+		}
+	}
+
+	visited := map[string]bool{}
+	p.printBundle(description, visited)
+}
+
+// PrintTransferEstimate writes what a copy to the repository would transfer as a table
+func (p bundleMarkdownPrinter) PrintTransferEstimate(estimate v1.TransferEstimate) {
+	p.logger.Logf("\n## Transfer to %s\n\n", markdownCode(estimate.Repository))
+	p.logger.Logf("| | To transfer | Already present |\n")
+	p.logger.Logf("| --- | --- | --- |\n")
+	p.logger.Logf("| Manifests | %d | %d |\n", estimate.Manifests, estimate.ManifestsPresent)
+	p.logger.Logf("| Blobs | %d | %d |\n", estimate.Blobs, estimate.BlobsPresent)
+	p.logger.Logf("| Size | %s | %s |\n", util.HumanizeBytes(estimate.Bytes), util.HumanizeBytes(estimate.BytesPresent))
+}
+
+func (p bundleMarkdownPrinter) printBundle(description v1.Description, visited map[string]bool) {
+	if visited[description.Image] {
+		return
+	}
+	visited[description.Image] = true
+
+	p.logger.Logf("\n## %s\n\n", markdownCode(description.Image))
+	p.logger.Logf("- Origin: %s\n", markdownCode(description.Origin))
+	if version := versionFromAnnotations(description.Annotations); version != "" {
+		p.logger.Logf("- Version: %s\n", markdownEscape(version))
+	}
+	if description.Deprecated != nil {
+		p.logger.Logf("- Deprecated: %s\n", markdownEscape(deprecationText(*description.Deprecated)))
+	}
+	if len(description.Layers) > 0 {
+		p.logger.Logf("- Size: %s\n", layersSize(description.Layers))
+	}
+
+	bundleDigests := sortedKeys(description.Content.Bundles)
+	imageDigests := sortedKeys(description.Content.Images)
+	if len(bundleDigests) == 0 && len(imageDigests) == 0 {
+		return
+	}
+
+	p.logger.Logf("\n| Type | Image | Origin | Version | Size |\n")
+	p.logger.Logf("| --- | --- | --- | --- | --- |\n")
+	for _, digest := range bundleDigests {
+		b := description.Content.Bundles[digest]
+		p.logger.Logf("| Bundle | %s | %s | %s | %s |\n",
+			markdownCode(b.Image), markdownCode(b.Origin), versionCell(b.Annotations), layersSize(b.Layers))
+	}
+	for _, digest := range imageDigests {
+		image := description.Content.Images[digest]
+		if image.Error != "" {
+			p.logger.Logf("| %s | %s | Error: %s | - | - |\n",
+				image.ImageType, markdownCode(digest), markdownEscape(image.Error))
+			continue
+		}
+		p.logger.Logf("| %s | %s | %s | %s | %s |\n",
+			image.ImageType, markdownCode(image.Image), markdownCode(image.Origin), versionCell(image.Annotations), layersSize(image.Layers))
+	}
+
+	for _, digest := range bundleDigests {
+		p.printBundle(description.Content.Bundles[digest], visited)
+	}
+}
+
+func versionFromAnnotations(annotations map[string]string) string {
+	for _, key := range versionAnnotations {
+		if version, found := annotations[key]; found {
+			return version
+		}
+	}
+	return ""
+}
+
+func versionCell(annotations map[string]string) string {
+	version := versionFromAnnotations(annotations)
+	if version == "" {
+		return "-"
+	}
+	return markdownEscape(version)
+}
+
+// layersSize Sum of the compressed size of all the layers, or "-" when the layers information was not retrieved
+func layersSize(layers []v1.Layers) string {
+	if len(layers) == 0 {
+		return "-"
+	}
+
+	var total int64
+	for _, layer := range layers {
+		total += layer.Size
+	}
+	return util.HumanizeBytes(total)
+}
+
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(value, "`", "'") + "`"
+}
+
+func markdownEscape(value string) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bufferLogger struct {
+	buf bytes.Buffer
+}
+
+func (b *bufferLogger) Logf(str string, args ...interface{}) {
+	b.buf.WriteString(fmt.Sprintf(str, args...))
+}
+
+func TestBundleMarkdownPrinter(t *testing.T) {
+	nestedBundle := v1.Description{
+		Image:       "registry.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		Origin:      "other.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		Annotations: map[string]string{"org.opencontainers.image.version": "1.2.3"},
+		Layers:      []v1.Layers{{Digest: "sha256:aaaa", Size: 2048}},
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				"sha256:3333333333333333333333333333333333333333333333333333333333333333": {
+					Image:     "registry.io/nested@sha256:3333333333333333333333333333333333333333333333333333333333333333",
+					Origin:    "other.io/app@sha256:3333333333333333333333333333333333333333333333333333333333333333",
+					ImageType: bundle.ContentImage,
+					Layers:    []v1.Layers{{Digest: "sha256:bbbb", Size: 1024 * 1024}, {Digest: "sha256:cccc", Size: 512 * 1024}},
+				},
+			},
+		},
+	}
+	description := v1.Description{
+		Image:  "registry.io/bundle@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		Origin: "registry.io/bundle@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				"sha256:2222222222222222222222222222222222222222222222222222222222222222": nestedBundle,
+			},
+			Images: map[string]v1.ImageInfo{
+				"sha256:4444444444444444444444444444444444444444444444444444444444444444": {
+					Image:       "registry.io/bundle@sha256:4444444444444444444444444444444444444444444444444444444444444444",
+					Origin:      "other.io/db@sha256:4444444444444444444444444444444444444444444444444444444444444444",
+					Annotations: map[string]string{"version": "v5|beta"},
+					ImageType:   bundle.ContentImage,
+				},
+				"other.io/broken@sha256:5555555555555555555555555555555555555555555555555555555555555555": {
+					ImageType: bundle.SignatureImage,
+					Error:     "Unable to fetch signature",
+				},
+			},
+		},
+	}
+
+	logger := &bufferLogger{}
+	bundleMarkdownPrinter{logger: logger}.Print(description)
+
+	assert.Equal(t, "# Bundle `sha256:1111111111111111111111111111111111111111111111111111111111111111`\n"+
+		"\n"+
+		"## `registry.io/bundle@sha256:1111111111111111111111111111111111111111111111111111111111111111`\n"+
+		"\n"+
+		"- Origin: `registry.io/bundle@sha256:1111111111111111111111111111111111111111111111111111111111111111`\n"+
+		"\n"+
+		"| Type | Image | Origin | Version | Size |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| Bundle | `registry.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222` | `other.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222` | 1.2.3 | 2.0 KiB |\n"+
+		"| Signature | `other.io/broken@sha256:5555555555555555555555555555555555555555555555555555555555555555` | Error: Unable to fetch signature | - | - |\n"+
+		"| Image | `registry.io/bundle@sha256:4444444444444444444444444444444444444444444444444444444444444444` | `other.io/db@sha256:4444444444444444444444444444444444444444444444444444444444444444` | v5\\|beta | - |\n"+
+		"\n"+
+		"## `registry.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222`\n"+
+		"\n"+
+		"- Origin: `other.io/nested@sha256:2222222222222222222222222222222222222222222222222222222222222222`\n"+
+		"- Version: 1.2.3\n"+
+		"- Size: 2.0 KiB\n"+
+		"\n"+
+		"| Type | Image | Origin | Version | Size |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| Image | `registry.io/nested@sha256:3333333333333333333333333333333333333333333333333333333333333333` | `other.io/app@sha256:3333333333333333333333333333333333333333333333333333333333333333` | - | 1.5 MiB |\n",
+		logger.buf.String())
+
+	yamlLogger := &bufferLogger{}
+	require.NoError(t, bundleYAMLPrinter{logger: yamlLogger}.Print(nestedBundle, nil))
+	assert.NotContains(t, yamlLogger.buf.String(), "size", "the layers size should only be part of the markdown output")
+}
+
+func TestBundleMarkdownPrinterTransferEstimate(t *testing.T) {
+	logger := &bufferLogger{}
+	bundleMarkdownPrinter{logger: logger}.PrintTransferEstimate(v1.TransferEstimate{
+		Repository:       "registry.io/copied",
+		Manifests:        1,
+		ManifestsPresent: 2,
+		Blobs:            3,
+		BlobsPresent:     4,
+		Bytes:            2048,
+		BytesPresent:     1024 * 1024,
+	})
+
+	assert.Equal(t, "\n## Transfer to `registry.io/copied`\n"+
+		"\n"+
+		"| | To transfer | Already present |\n"+
+		"| --- | --- | --- |\n"+
+		"| Manifests | 1 | 2 |\n"+
+		"| Blobs | 3 | 4 |\n"+
+		"| Size | 2.0 KiB | 1.0 MiB |\n",
+		logger.buf.String())
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+)
+
+// DiffOptions Options for the diff command
+type DiffOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+	Layers        bool
+	Concurrency   int
+}
+
+// NewDiffOptions Builder for DiffOptions
+func NewDiffOptions(ui ui.UI) *DiffOptions {
+	return &DiffOptions{ui: ui}
+}
+
+// NewDiffCmd Creates the diff command
+func NewDiffCmd(o *DiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff OLD-BUNDLE NEW-BUNDLE",
+		Short: "Compare two versions of a bundle",
+		Args:  cobra.ExactArgs(2),
+		RunE:  func(_ *cobra.Command, args []string) error { return o.Run(args[0], args[1]) },
+		Example: `
+    # Report the layers that version 1.1.0 of the bundle shares with version 1.0.0, the new ones,
+    # and how much copying 1.1.0 to a repository that already has 1.0.0 would transfer
+    imgpkg diff --layers registry.io/org/app-bundle:1.0.0 registry.io/org/app-bundle:1.1.0`,
+	}
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().BoolVar(&o.Layers, "layers", false, "Compare the layers of the bundles, their images and nested bundles")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the diff command
+func (d *DiffOptions) Run(oldBundleRef, newBundleRef string) error {
+	if !d.Layers {
+		return fmt.Errorf("Expected --layers (hint: only the layers of the bundles can be compared)")
+	}
+
+	reg, err := registry.NewSimpleRegistry(d.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	prefixedLogger := util.NewPrefixedLogger("diff | ", util.NewLogger(d.ui))
+	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
+	opts := v1.CopyOpts{
+		Logger:             levelLogger,
+		ImageSet:           ctlimgset.NewImageSet(d.Concurrency, prefixedLogger, util.DefaultTagGenerator{}),
+		Concurrency:        d.Concurrency,
+		SignatureRetriever: signature.NewNoop(),
+	}
+
+	diff, err := v1.DiffLayers(oldBundleRef, newBundleRef, opts, reg)
+	if err != nil {
+		return err
+	}
+
+	table := uitable.Table{
+		Title:   "Layers",
+		Content: "layers",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Status"),
+			uitable.NewHeader("Digest"),
+			uitable.NewHeader("Size"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 0, Asc: true},
+			{Column: 1, Asc: true},
+		},
+	}
+
+	for _, layer := range diff.Layers {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(layer.Status),
+			uitable.NewValueString(layer.Digest),
+			uitable.NewValueString(util.HumanizeBytes(layer.Size)),
+		})
+	}
+
+	d.ui.PrintTable(table)
+	d.ui.PrintLinef("Shared: %d layers (%s)", diff.SharedLayers, util.HumanizeBytes(diff.SharedBytes))
+	d.ui.PrintLinef("New: %d layers (%s)", diff.NewLayers, util.HumanizeBytes(diff.NewBytes))
+	d.ui.PrintLinef("Removed: %d layers (%s)", diff.RemovedLayers, util.HumanizeBytes(diff.RemovedBytes))
+	d.ui.PrintLinef("Copying %s to a repository with %s would transfer %s", newBundleRef, oldBundleRef, util.HumanizeBytes(diff.TransferSize))
+
+	return nil
+}
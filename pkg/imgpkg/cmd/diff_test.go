@@ -0,0 +1,20 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffWithoutLayers(t *testing.T) {
+	err := (&DiffOptions{}).Run("registry.io/org/app-bundle:1.0.0", "registry.io/org/app-bundle:1.1.0")
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --layers") {
+		t.Fatalf("Expected error message related to --layers, got: %s", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/featureflags"
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+)
+
+// FeatureFlagsOptions Command Line options that can be provided to the feature-flags command
+type FeatureFlagsOptions struct {
+	ui ui.UI
+}
+
+// NewFeatureFlagsOptions constructor for building a FeatureFlagsOptions
+func NewFeatureFlagsOptions(ui ui.UI) *FeatureFlagsOptions {
+	return &FeatureFlagsOptions{ui: ui}
+}
+
+// NewFeatureFlagsCmd constructor for the feature-flags command
+func NewFeatureFlagsCmd(o *FeatureFlagsOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "feature-flags",
+		Short: "List the feature flags that gate behavior changes, whether they are enabled and where that comes from",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: fmt.Sprintf(`
+    # List the feature flags
+    imgpkg feature-flags
+
+    # Push bundles with the OCI media types in this shell
+    export %[1]s=%[3]s=true
+
+    # Push bundles with the OCI media types on this machine, unless %[1]s disables it
+    mkdir -p ~/.config/imgpkg
+    printf 'apiVersion: %[4]s\nkind: %[5]s\nflags:\n  %[3]s: true\n' > ~/.config/imgpkg/feature-flags.yml
+
+    # Read the feature flags of the environment from another config file
+    export %[2]s=./ci/feature-flags.yml`,
+			featureflags.Env, featureflags.ConfigEnv, featureflags.OCIMediaTypes, featureflags.ConfigAPIVersion, featureflags.ConfigKind),
+	}
+	return cmd
+}
+
+// Run functions called when the feature-flags command is provided in the command line
+func (o *FeatureFlagsOptions) Run() error {
+	set := featureflags.Active()
+
+	table := uitable.Table{
+		Title:   "Feature flags",
+		Content: "feature flags",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Name"),
+			uitable.NewHeader("Stage"),
+			uitable.NewHeader("Enabled"),
+			uitable.NewHeader("Source"),
+			uitable.NewHeader("Description"),
+		},
+
+		Notes: []string{fmt.Sprintf("Config: %s", featureFlagsConfigNote(set))},
+	}
+	for _, state := range set.States() {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(state.Name),
+			uitable.NewValueString(string(state.Stage)),
+			uitable.NewValueBool(state.Enabled),
+			uitable.NewValueString(string(state.Source)),
+			uitable.NewValueString(state.Description),
+		})
+	}
+	o.ui.PrintTable(table)
+	return nil
+}
+
+// configureFeatureFlags Loads the feature flags of the environment and the config file for the command being run
+func configureFeatureFlags(ui ui.UI) error {
+	set, err := featureflags.Load(os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	featureflags.SetActive(set)
+	for _, warning := range set.Warnings() {
+		ui.ErrorLinef("Warning: %s", warning)
+	}
+	return nil
+}
+
+// featureFlagsText Lists the state of each feature flag, printed by version with --verbose
+func featureFlagsText(set featureflags.Set) string {
+	text := fmt.Sprintf("Feature flags (config: %s):\n", featureFlagsConfigNote(set))
+	for _, state := range set.States() {
+		text += fmt.Sprintf("  %s=%s (%s, %s)\n", state.Name, strconv.FormatBool(state.Enabled), state.Stage, state.Source)
+	}
+	return text
+}
+
+// featureFlagsConfigNote Path of the config file of the feature flags, or where it would be read from
+func featureFlagsConfigNote(set featureflags.Set) string {
+	if set.ConfigPath != "" {
+		return set.ConfigPath
+	}
+	path := featureflags.DefaultConfigPath()
+	if path == "" {
+		return "none"
+	}
+	return fmt.Sprintf("none (%s not found)", path)
+}
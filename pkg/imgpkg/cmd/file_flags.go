@@ -12,15 +12,28 @@ type FileFlags struct {
 
 	ExcludedFilePaths   []string
 	PreservePermissions bool
+	// BundleTag tag of the bundle built from Files by copy
+	BundleTag string
 }
 
 func (f *FileFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().StringSliceVarP(&f.Files, "file", "f", nil, "Set file (format: /tmp/foo) (can be specified multiple times)")
+	cmd.Flags().StringSliceVarP(&f.Files, "file", "f", nil, "Set file, - reads a tar stream with the files from stdin (format: /tmp/foo or -) (can be specified multiple times)")
 
 	cmd.Flags().StringSliceVar(&f.ExcludedFilePaths, "file-exclude-defaults", []string{".git"}, "Excluded file paths by default (can be specified multiple times)")
 	cmd.Flags().MarkDeprecated("file-exclude-defaults", "use '--file-exclusion' instead")
 
-	cmd.Flags().StringSliceVar(&f.ExcludedFilePaths, "file-exclusion", []string{".git"}, "Exclude file whose path, relative to the bundle root, matches (format: bar.yaml, nested-dir/baz.txt) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&f.ExcludedFilePaths, "file-exclusion", []string{".git"}, "Exclude file whose path, relative to the bundle root, matches (format: bar.yaml, nested-dir/baz.txt) (can be specified multiple times), "+
+		"the paths matching the .imgpkgignore file, with the gitignore syntax, in the root of a directory are also excluded")
 
 	cmd.Flags().BoolVar(&f.PreservePermissions, "preserve-permissions", false, "Preserve the group and all permissions of all the files and folders")
 }
+
+// SetCopy Sets the flags of copy, whose files are pushed as the bundle that is copied
+func (f *FileFlags) SetCopy(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&f.Files, "file", "f", nil, "Bundle directory, containing .imgpkg/images.yml, pushed to the destination repository "+
+		"before its images are copied (format: /tmp/foo) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&f.ExcludedFilePaths, "file-exclusion", []string{".git"}, "Exclude file whose path, relative to the bundle root, matches (format: bar.yaml, nested-dir/baz.txt) (can be specified multiple times), "+
+		"the paths matching the .imgpkgignore file, with the gitignore syntax, in the root of a directory are also excluded")
+	cmd.Flags().BoolVar(&f.PreservePermissions, "preserve-permissions", false, "Preserve the group and all permissions of all the files and folders")
+	cmd.Flags().StringVar(&f.BundleTag, "file-bundle-tag", "latest", "Tag of the bundle pushed from the files provided with --file (-f)")
+}
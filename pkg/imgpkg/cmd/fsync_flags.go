@@ -0,0 +1,25 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"github.com/spf13/cobra"
+)
+
+// FsyncFlags indicates if the files written are flushed to disk
+type FsyncFlags struct {
+	Fsync bool
+}
+
+// Set adds the fsync flag to the command
+func (f *FsyncFlags) Set(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&f.Fsync, "fsync", true,
+		"Flush lock files, tars and reports to disk before moving them into place (disabling it is faster but a power loss might leave empty files)")
+}
+
+// ConfigureFsync configures how the files are written
+func (f *FsyncFlags) ConfigureFsync() {
+	atomicfile.SetFsync(f.Fsync)
+}
@@ -4,12 +4,16 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/runstate"
 	"github.com/cppforlife/cobrautil"
 	"github.com/cppforlife/go-cli-ui/ui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 type ImgpkgOptions struct {
@@ -17,6 +21,10 @@ type ImgpkgOptions struct {
 
 	UIFlags    UIFlags
 	DebugFlags DebugFlags
+	FsyncFlags FsyncFlags
+
+	// VerboseVersion lists the feature flags after the version printed with --version
+	VerboseVersion bool
 }
 
 func NewImgpkgOptions(ui *ui.ConfUI) *ImgpkgOptions {
@@ -46,18 +54,39 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 
 	o.UIFlags.Set(cmd)
 	o.DebugFlags.Set(cmd)
+	o.FsyncFlags.Set(cmd)
+	cmd.Flags().BoolVar(&o.VerboseVersion, "verbose", false, "With --version, also list the feature flags, whether they are enabled and where that comes from")
+	cobra.AddTemplateFunc("verboseVersion", verboseVersion)
+	cmd.SetVersionTemplate(versionTemplate)
 
 	cmd.AddCommand(NewPushCmd(NewPushOptions(o.ui)))
 	cmd.AddCommand(NewPullCmd(NewPullOptions(o.ui)))
 	cmd.AddCommand(NewVersionCmd(NewVersionOptions(o.ui)))
 	cmd.AddCommand(NewCopyCmd(NewCopyOptions(o.ui)))
 	cmd.AddCommand(NewDescribeCmd(NewDescribeOptions(o.ui)))
+	cmd.AddCommand(NewWhoamiCmd(NewWhoamiOptions(o.ui)))
+	cmd.AddCommand(NewSupportBundleCmd(NewSupportBundleOptions(o.ui)))
+	cmd.AddCommand(NewWarmCmd(NewWarmOptions(o.ui)))
+	cmd.AddCommand(NewProxyCmd(NewProxyOptions(o.ui)))
+	cmd.AddCommand(NewConfigCmd(NewConfigOptions(o.ui)))
+	cmd.AddCommand(NewFeatureFlagsCmd(NewFeatureFlagsOptions(o.ui)))
 
 	tagCmd := NewTagCmd()
 	tagCmd.AddCommand(NewTagListCmd(NewTagListOptions(o.ui)))
 	tagCmd.AddCommand(NewTagResolveCmd(NewTagResolveOptions(o.ui)))
 	cmd.AddCommand(tagCmd)
 
+	lockCmd := NewLockCmd()
+	lockCmd.AddCommand(NewLockDiffCmd(NewLockDiffOptions(o.ui)))
+	cmd.AddCommand(lockCmd)
+
+	bundleGraphCmd := NewBundleGraphCmd()
+	bundleGraphCmd.AddCommand(NewBundleGraphExportCmd(NewBundleGraphExportOptions(o.ui)))
+	bundleCmd := NewBundleCmd()
+	bundleCmd.AddCommand(bundleGraphCmd)
+	bundleCmd.AddCommand(NewBundleDeprecateCmd(NewBundleDeprecateOptions(o.ui)))
+	cmd.AddCommand(bundleCmd)
+
 	// Last one runs first
 	cobrautil.VisitCommands(cmd, cobrautil.ReconfigureCmdWithSubcmd)
 	cobrautil.VisitCommands(cmd, cobrautil.DisallowExtraArgs)
@@ -66,10 +95,26 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 	// This configurations forces all nodes to do not accept extra args, but the completion requires 1 extra arg
 	cmd.AddCommand(NewCompletionCmd())
 
+	// Tar verify receives the path of the tar as an argument, so it is also added after the DisallowExtraArgs
+	tarCmd := NewTarCmd()
+	tarCmd.AddCommand(NewTarVerifyCmd(NewTarVerifyOptions(o.ui)))
+	cobrautil.ReconfigureCmdWithSubcmd(tarCmd)
+	cobrautil.DisallowExtraArgs(tarCmd)
+	cmd.AddCommand(tarCmd)
+
+	// Diff receives the references of the bundles to compare as arguments
+	diffCmd := NewDiffCmd(NewDiffOptions(o.ui))
+	cobrautil.ReconfigureCmdWithSubcmd(diffCmd)
+	cmd.AddCommand(diffCmd)
+
+	// Recording is the innermost wrapper, so that the registry logs are copied once debugging is configured
+	cobrautil.VisitCommands(cmd, recordRunState(&o.DebugFlags))
+
 	cobrautil.VisitCommands(cmd, cobrautil.WrapRunEForCmd(func(*cobra.Command, []string) error {
 		o.UIFlags.ConfigureUI(o.ui)
 		o.DebugFlags.ConfigureDebug()
-		return nil
+		o.FsyncFlags.ConfigureFsync()
+		return configureFeatureFlags(o.ui)
 	}))
 
 	cobrautil.VisitCommands(cmd, cobrautil.WrapRunEForCmd(cobrautil.ResolveFlagsForCmd))
@@ -77,6 +122,38 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 	return cmd
 }
 
+// recordRunState Records, when --record-run is provided, the arguments and outcome of the command, so that support-bundle
+// can include them. Commands that do not talk to registries are not recorded, to keep the last run that might need to be diagnosed
+func recordRunState(debugFlags *DebugFlags) func(cmd *cobra.Command) {
+	return func(cmd *cobra.Command) {
+		path := strings.Fields(cmd.CommandPath())
+		if cmd.RunE == nil || len(path) < 2 {
+			return
+		}
+		switch path[1] {
+		case "support-bundle", "version", "completion", "help", "feature-flags":
+			return
+		}
+
+		origRunE := cmd.RunE
+		cmd.RunE = func(cmd2 *cobra.Command, args []string) error {
+			if !debugFlags.RecordRun {
+				return origRunE(cmd2, args)
+			}
+
+			recordedArgs := append([]string{}, args...)
+			cmd2.Flags().Visit(func(flag *pflag.Flag) {
+				recordedArgs = append(recordedArgs, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+			})
+
+			recorder := runstate.Start(runstate.DefaultDir(), cmd2.CommandPath(), recordedArgs, Version)
+			err := origRunE(cmd2, args)
+			recorder.Finish(err)
+			return err
+		}
+	}
+}
+
 type uiBlockWriter struct {
 	ui ui.UI
 }
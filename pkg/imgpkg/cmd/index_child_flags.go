@@ -0,0 +1,19 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// IndexChildFlags Flags used to select a single child manifest of an image index
+type IndexChildFlags struct {
+	Platform string
+}
+
+// Set Registers the flags in the command
+func (i *IndexChildFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&i.Platform, "index-child-platform", "",
+		"When the image is an image index, only use the child manifest for this platform (format: os/arch[/variant], example: linux/arm64)")
+}
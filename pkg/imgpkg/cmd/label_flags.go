@@ -4,15 +4,51 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
 // LabelFlags is a struct that holds the labels for an OCI artifact
 type LabelFlags struct {
 	Labels map[string]string
+	// Label labels provided one at a time, whose values can contain commas (format: key=value)
+	Label []string
 }
 
 // Set sets the labels for an OCI artifact
 func (l *LabelFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringToStringVarP(&l.Labels, "labels", "l", map[string]string{}, "Set labels on image")
+	cmd.Flags().StringArrayVar(&l.Label, "label", nil, "Set label on image (format: key=value, example: org.opencontainers.image.licenses=Apache-2.0) (can be specified multiple times)")
+}
+
+// AsLabels Returns the labels provided with --labels and --label
+func (l *LabelFlags) AsLabels() (map[string]string, error) {
+	label, err := parseKeyValues("--label", l.Label)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for key, value := range l.Labels {
+		labels[key] = value
+	}
+	for key, value := range label {
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// parseKeyValues parses the values of flag, with the format key=value, the last value of a key wins
+func parseKeyValues(flag string, values []string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, keyValue := range values {
+		key, value, found := strings.Cut(keyValue, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("Expected %s '%s' to have the format key=value", flag, keyValue)
+		}
+		result[key] = value
+	}
+	return result, nil
 }
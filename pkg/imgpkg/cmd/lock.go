@@ -0,0 +1,17 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewLockCmd Creates the lock command, parent of the commands that work with lock files
+func NewLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Lock",
+	}
+	return cmd
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+)
+
+// LockDiffOptions Options for the lock diff command
+type LockDiffOptions struct {
+	ui ui.UI
+
+	LockInputFlags LockInputFlags
+	RegistryFlags  RegistryFlags
+
+	Repository    string
+	MissingOutput string
+	Concurrency   int
+}
+
+// NewLockDiffOptions Builder for LockDiffOptions
+func NewLockDiffOptions(ui ui.UI) *LockDiffOptions {
+	return &LockDiffOptions{ui: ui}
+}
+
+// NewLockDiffCmd Creates the lock diff command
+func NewLockDiffCmd(o *LockDiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report the images of a lock file that are present in and missing from a repository",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Check which images of the ImagesLock were already copied to the mirror
+    imgpkg lock diff --lock images.yml --repo registry.corp/mirror/app
+
+    # Write an ImagesLock with only the images missing from the mirror, and copy them
+    imgpkg lock diff --lock images.yml --repo registry.corp/mirror/app --missing-output missing.yml
+    imgpkg copy --lock missing.yml --to-repo registry.corp/mirror/app`,
+	}
+	cmd.Flags().StringVar(&o.LockInputFlags.LockFilePath, "lock", "", "BundleLock or ImagesLock with the images to look for")
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.Repository, "repo", "", "Repository where the images are expected, as copied with copy --to-repo (example: registry.corp/mirror/app)")
+	cmd.Flags().StringVar(&o.MissingOutput, "missing-output", "", "Write an ImagesLock with the missing images that are not bundles to this path")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the lock diff command
+func (o *LockDiffOptions) Run() error {
+	if o.LockInputFlags.LockFilePath == "" {
+		return fmt.Errorf("Expected --lock")
+	}
+	if o.Repository == "" {
+		return fmt.Errorf("Expected --repo")
+	}
+
+	reg, err := registry.NewSimpleRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	levelLogger := util.NewUILevelLogger(util.LogWarn, util.NewPrefixedLogger("lock diff | ", util.NewLogger(o.ui)))
+	diff, err := v1.DiffLock(o.LockInputFlags.LockFilePath, o.Repository, v1.LockDiffOpts{Logger: levelLogger, Concurrency: o.Concurrency}, reg)
+	if err != nil {
+		return err
+	}
+
+	table := uitable.Table{
+		Title:   "Images",
+		Content: "images",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Status"),
+			uitable.NewHeader("Image"),
+			uitable.NewHeader("Type"),
+			uitable.NewHeader("Destination"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 0, Asc: true},
+			{Column: 1, Asc: true},
+		},
+	}
+
+	missingBundles := 0
+	for _, img := range diff.Images {
+		status, imageType := "present", "image"
+		if !img.Present {
+			status = "missing"
+		}
+		if img.IsBundle {
+			imageType = "bundle"
+			if !img.Present {
+				missingBundles++
+			}
+		}
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(status),
+			uitable.NewValueString(img.Image),
+			uitable.NewValueString(imageType),
+			uitable.NewValueString(img.Destination),
+		})
+	}
+
+	o.ui.PrintTable(table)
+	missing := len(diff.Missing())
+	o.ui.PrintLinef("Present: %d, Missing: %d", len(diff.Images)-missing, missing)
+
+	if o.MissingOutput != "" {
+		err = diff.MissingImagesLock().WriteToPath(o.MissingOutput)
+		if err != nil {
+			return err
+		}
+		if missingBundles > 0 {
+			levelLogger.Warnf("%d missing bundle(s) are not included in %s, an ImagesLock cannot contain bundles (hint: copy them with -b)\n", missingBundles, o.MissingOutput)
+		}
+	}
+	return nil
+}
@@ -9,9 +9,27 @@ import (
 
 type LockInputFlags struct {
 	LockFilePath string
+	PreserveTags bool
+	// ImageDigestFiles files with an image reference with digest per line, like the ones written by ko or the Bazel rules
+	ImageDigestFiles []string
 }
 
 func (l *LockInputFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
 		"Lock file with asset references to copy to destination")
 }
+
+// SetOnCopy Sets the lock flags for Copy command
+func (l *LockInputFlags) SetOnCopy(cmd *cobra.Command) {
+	l.Set(cmd)
+	cmd.Flags().BoolVar(&l.PreserveTags, "preserve-lock-tags", false,
+		"Tag each image of the ImagesLock in the destination with the tag it was resolved from (kbld.carvel.dev/id annotation)")
+	cmd.Flags().StringArrayVar(&l.ImageDigestFiles, "image-digest-file", nil, "File with an image reference with digest per line, "+
+		"like the ones written by ko or the Bazel rules, copied the same as an ImagesLock with those images "+
+		"(example: registry.corp/app:1.0@sha256:...) (can be specified multiple times)")
+}
+
+// IsImagesSrc Returns true when the images to copy are read from an ImagesLock, or a BundleLock, or from image digest files
+func (l LockInputFlags) IsImagesSrc() bool {
+	return l.LockFilePath != "" || len(l.ImageDigestFiles) > 0
+}
@@ -4,21 +4,159 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/spf13/cobra"
 )
 
 type LockOutputFlags struct {
 	LockFilePath string
+	// PathTemplate template of the path of the lock file written for each destination (example: locks/{{.Dest}}.yml)
+	PathTemplate string
+	Tags         bool
+	// Annotations when true, the lock file is annotated with how it was generated
+	Annotations bool
+	// OutputFormat format of the lock file, when empty it is the one of the extension of the path (json or yaml)
+	OutputFormat string
 }
 
 // SetOnCopy Sets the lock-output flag for Copy command
 func (l *LockOutputFlags) SetOnCopy(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock-output", "",
 		"Location to output the generated lockfile. Option only available when using --bundle or --lock flags")
+	cmd.Flags().StringVar(&l.PathTemplate, "lock-output-template", "",
+		"Template of the location of the lockfile generated for each repository copied to, with the fields .Dest, the repository "+
+			"with '/' and ':' replaced by '_', .Repository and .Index, the position of the repository in --to-repo (example: locks/{{.Dest}}.yml)")
+	cmd.Flags().BoolVar(&l.Tags, "lock-output-tags", false,
+		"Annotate each tagged image of the generated ImagesLock with its tagged reference in the destination (imgpkg.carvel.dev/tag annotation)")
+	l.setAnnotations(cmd)
+	l.setFormat(cmd)
 }
 
 // SetOnPush Sets the lock-output flag for Push command
 func (l *LockOutputFlags) SetOnPush(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock-output", "",
 		"Location to output the generated lockfile. Option only available when using --bundle flag")
+	l.setAnnotations(cmd)
+	l.setFormat(cmd)
+}
+
+func (l *LockOutputFlags) setAnnotations(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&l.Annotations, "lock-output-annotations", false,
+		"Annotate the generated lockfile with the imgpkg version, the time, the source, the destination and the options used to generate it "+
+			"(imgpkg.carvel.dev/generated-by, generated-at, source, destination and options annotations), lockfiles with annotations cannot be read by older imgpkg versions")
+}
+
+func (l *LockOutputFlags) setFormat(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.OutputFormat, "lock-output-format", "",
+		fmt.Sprintf("Format of the generated lockfile (one of: %s), when not provided it is json for paths ending in .json and yaml otherwise",
+			strings.Join(lockconfig.Formats(), ", ")))
+}
+
+// lockOutputTemplateData Fields available to --lock-output-template
+type lockOutputTemplateData struct {
+	// Dest destination repository usable as a file name
+	Dest       string
+	Repository string
+	Index      int
+}
+
+var lockOutputDestReplacer = strings.NewReplacer("/", "_", ":", "_")
+
+// IsSet Returns true when a lock file is written, either to --lock-output or to the paths of --lock-output-template
+func (l LockOutputFlags) IsSet() bool {
+	return l.LockFilePath != "" || l.PathTemplate != ""
+}
+
+// Validate Checks that the format is known and only provided when a lock file is written
+func (l LockOutputFlags) Validate() error {
+	if l.OutputFormat == "" {
+		return nil
+	}
+	if !l.IsSet() {
+		return fmt.Errorf("Flag --lock-output-format can only be used with --lock-output or --lock-output-template")
+	}
+	_, err := l.Format("")
+	return err
+}
+
+// Format Returns the format of the lock file written to path, the one provided with --lock-output-format or else the one of its extension
+func (l LockOutputFlags) Format(path string) (lockconfig.Format, error) {
+	format, err := lockconfig.ParseFormat(l.OutputFormat, path)
+	if err != nil {
+		return "", fmt.Errorf("Parsing --lock-output-format: %s", err)
+	}
+	return format, nil
+}
+
+// Paths Returns the path of the lock file written for each destination, rendering --lock-output-template with it,
+// or the --lock-output path when no template is provided. Each destination is expected to get its own lock file,
+// so that the copies to several destinations do not write the same file
+func (l LockOutputFlags) Paths(destinations []string) ([]string, error) {
+	var paths []string
+	if l.PathTemplate == "" {
+		for range destinations {
+			paths = append(paths, l.LockFilePath)
+		}
+		return paths, nil
+	}
+
+	tmpl, err := template.New("lock-output-template").Parse(l.PathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing --lock-output-template: %s", err)
+	}
+
+	destinationsByPath := map[string]string{}
+	for i, destination := range destinations {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, lockOutputTemplateData{Dest: lockOutputDestReplacer.Replace(destination), Repository: destination, Index: i})
+		if err != nil {
+			return nil, fmt.Errorf("Rendering --lock-output-template for '%s': %s", destination, err)
+		}
+		path := buf.String()
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("Expected --lock-output-template to render a path for '%s'", destination)
+		}
+		if other, found := destinationsByPath[filepath.Clean(path)]; found {
+			return nil, fmt.Errorf("Expected --lock-output-template to render a different path for each destination, "+
+				"'%s' and '%s' both render '%s' (hint: use {{.Dest}} in the template)", other, destination, path)
+		}
+		destinationsByPath[filepath.Clean(path)] = destination
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// SummaryAnnotations Returns the annotations describing how the lock file was generated,
+// nil when they were not requested. The options with an empty value are left out
+func (l LockOutputFlags) SummaryAnnotations(sources []string, destination string, options map[string]string) map[string]string {
+	if !l.Annotations {
+		return nil
+	}
+
+	annotations := map[string]string{
+		lockconfig.LockGeneratedByAnnotationKey: "imgpkg " + Version,
+		lockconfig.LockGeneratedAtAnnotationKey: time.Now().UTC().Format(time.RFC3339),
+		lockconfig.LockSourceAnnotationKey:      strings.Join(sources, ", "),
+		lockconfig.LockDestinationAnnotationKey: destination,
+	}
+
+	var setOptions []string
+	for key, value := range options {
+		if value != "" {
+			setOptions = append(setOptions, key+"="+value)
+		}
+	}
+	if len(setOptions) > 0 {
+		sort.Strings(setOptions)
+		annotations[lockconfig.LockOptionsAnnotationKey] = strings.Join(setOptions, " ")
+	}
+	return annotations
 }
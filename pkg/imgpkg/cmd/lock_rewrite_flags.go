@@ -0,0 +1,74 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// LockRewriteFlags Flags used to control how the ImagesLock of a relocated bundle is written when pulling it
+type LockRewriteFlags struct {
+	Rewrite     string
+	MappingPath string
+}
+
+// lockRewriteMapping File listing where the original images of the ImagesLocks were found
+type lockRewriteMapping struct {
+	Relocations []bundle.ImageRelocation `json:"relocations"`
+}
+
+// Set Registers the flags in the command
+func (l *LockRewriteFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.Rewrite, "lock-rewrite", string(bundle.LockRewriteRelocated),
+		fmt.Sprintf("How .imgpkg/images.yml is written when the bundle was relocated (%s: reference the images in the bundle repository, %s: keep the original images, %s: reference the images in the bundle repository and keep the original file in .imgpkg/%s)",
+			bundle.LockRewriteRelocated, bundle.LockRewriteOriginal, bundle.LockRewriteBoth, bundle.OriginalImagesLockFile))
+	cmd.Flags().StringVar(&l.MappingPath, "lock-mapping-output", "",
+		"Location to output a file mapping the original images of the bundle, and of its nested bundles, to where they were found")
+}
+
+// LockRewrite Returns the requested rewrite, bundle.LockRewriteRelocated when none was provided
+func (l LockRewriteFlags) LockRewrite() bundle.LockRewrite {
+	if l.Rewrite == "" {
+		return bundle.LockRewriteRelocated
+	}
+	return bundle.LockRewrite(l.Rewrite)
+}
+
+// Validate Checks that the rewrite is supported
+func (l LockRewriteFlags) Validate() error {
+	switch l.LockRewrite() {
+	case bundle.LockRewriteRelocated, bundle.LockRewriteOriginal, bundle.LockRewriteBoth:
+		return nil
+	default:
+		return fmt.Errorf("Unknown --lock-rewrite '%s' (supported: %s, %s, %s)",
+			l.Rewrite, bundle.LockRewriteRelocated, bundle.LockRewriteOriginal, bundle.LockRewriteBoth)
+	}
+}
+
+// WriteMapping Writes the mapping file, when requested
+func (l LockRewriteFlags) WriteMapping(relocations []bundle.ImageRelocation) error {
+	if l.MappingPath == "" {
+		return nil
+	}
+
+	mapping := lockRewriteMapping{Relocations: relocations}
+	if mapping.Relocations == nil {
+		mapping.Relocations = []bundle.ImageRelocation{}
+	}
+	bs, err := yaml.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("Marshaling lock mapping: %s", err)
+	}
+
+	err = atomicfile.WriteFile(l.MappingPath, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing lock mapping: %s", err)
+	}
+	return nil
+}
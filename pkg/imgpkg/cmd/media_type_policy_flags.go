@@ -0,0 +1,65 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"github.com/spf13/cobra"
+)
+
+// MediaTypePolicyFlags Flags used to restrict the media types of the images being copied
+type MediaTypePolicyFlags struct {
+	AllowedMediaTypes     []string
+	AllowedMediaTypesFile string
+	WarnOnly              bool
+}
+
+// Set Registers the flags in the command
+func (m *MediaTypePolicyFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&m.AllowedMediaTypes, "allowed-media-types", nil,
+		"Only copy images whose configuration and layers use these media types, glob patterns are supported (example: 'application/vnd.oci.image.layer.v1.*')")
+	cmd.Flags().StringVar(&m.AllowedMediaTypesFile, "allowed-media-types-file", "",
+		"File with the media types allowed when copying, one per line (lines starting with # are ignored)")
+	cmd.Flags().BoolVar(&m.WarnOnly, "warn-on-disallowed-media-types", false,
+		"Only warn instead of failing when media types are not allowed")
+}
+
+// MediaTypePolicy Builds the media type policy from the flags
+func (m *MediaTypePolicyFlags) MediaTypePolicy() (ctlimgset.MediaTypePolicy, error) {
+	allowed := append([]string{}, m.AllowedMediaTypes...)
+
+	if m.AllowedMediaTypesFile != "" {
+		file, err := os.Open(m.AllowedMediaTypesFile)
+		if err != nil {
+			return ctlimgset.MediaTypePolicy{}, fmt.Errorf("Reading allowed media types file: %s", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			allowed = append(allowed, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return ctlimgset.MediaTypePolicy{}, fmt.Errorf("Reading allowed media types file: %s", err)
+		}
+		if len(allowed) == 0 {
+			return ctlimgset.MediaTypePolicy{}, fmt.Errorf("Expected allowed media types file '%s' to contain at least one media type", m.AllowedMediaTypesFile)
+		}
+	}
+
+	if m.WarnOnly && len(allowed) == 0 {
+		return ctlimgset.MediaTypePolicy{}, fmt.Errorf("Flag --warn-on-disallowed-media-types requires --allowed-media-types or --allowed-media-types-file")
+	}
+
+	return ctlimgset.MediaTypePolicy{AllowedMediaTypes: allowed, WarnOnly: m.WarnOnly}, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"github.com/spf13/cobra"
+)
+
+// NestedBundlesFlags Flags used to validate the nested bundles before pushing a bundle
+type NestedBundlesFlags struct {
+	Validate          bool
+	AllowedRegistries []string
+}
+
+// Set Registers the flags in the command
+func (n *NestedBundlesFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&n.Validate, "validate-nested-bundles", false,
+		"Verify that images of the bundle and of all its nested bundles are referenced by digest")
+	cmd.Flags().StringSliceVar(&n.AllowedRegistries, "allowed-registry", nil,
+		"Registry where images of the bundle and of all its nested bundles must be stored, wildcards are supported (format: *.example.com) (can be specified multiple times, implies --validate-nested-bundles)")
+}
+
+// Enabled Returns true when the nested bundles need to be validated
+func (n NestedBundlesFlags) Enabled() bool {
+	return n.Validate || len(n.AllowedRegistries) > 0
+}
+
+// AsPolicy Returns the policy that the nested bundles must follow
+func (n NestedBundlesFlags) AsPolicy() bundle.NestedBundlesPolicy {
+	return bundle.NestedBundlesPolicy{AllowedRegistries: n.AllowedRegistries}
+}
@@ -0,0 +1,28 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// OCILayoutFlags Flags used to copy from and to an OCI image layout directory
+type OCILayoutFlags struct {
+	LayoutSrc string
+	LayoutDst string
+}
+
+// Set Registers the flags in the command
+func (o *OCILayoutFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.LayoutSrc, "oci-layout", "",
+		"Location of an OCI image layout directory, created by imgpkg or other tools, to copy the assets from")
+	cmd.Flags().StringVar(&o.LayoutDst, "to-oci-layout", "",
+		"Location of an OCI image layout directory to write the assets to, created when it does not exist")
+}
+
+// IsSrc Returns true when copying from an OCI image layout
+func (o OCILayoutFlags) IsSrc() bool { return o.LayoutSrc != "" }
+
+// IsDst Returns true when copying to an OCI image layout
+func (o OCILayoutFlags) IsDst() bool { return o.LayoutDst != "" }
@@ -0,0 +1,35 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/spf13/cobra"
+)
+
+// PlatformFlags Flags used to select the child manifests of image indexes that are copied
+type PlatformFlags struct {
+	Platforms []string
+}
+
+// Set Registers the flags in the command
+func (p *PlatformFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&p.Platforms, "platform", nil,
+		"Only copy the child manifests of image indexes for this platform, the indexes are rewritten to reference only them. Can be provided multiple times (format: os/arch[/variant], example: linux/amd64)")
+}
+
+// AsPlatforms Parses the platforms provided
+func (p PlatformFlags) AsPlatforms() ([]regv1.Platform, error) {
+	var platforms []regv1.Platform
+	for _, platform := range p.Platforms {
+		parsedPlatform, err := regv1.ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing platform '%s': %s", platform, err)
+		}
+		platforms = append(platforms, *parsedPlatform)
+	}
+	return platforms, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+)
+
+// progressLinesInterval how often the progress is logged with --progress=plain
+const progressLinesInterval = 10 * time.Second
+
+var progressModes = []string{string(util.ProgressModeAuto), string(util.ProgressModePlain), string(util.ProgressModeNone)}
+
+// ProgressFlags Flags used to choose how the progress of a transfer is displayed
+type ProgressFlags struct {
+	Mode string
+}
+
+// Set Registers the flags in the command
+func (p *ProgressFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.Mode, "progress", string(util.ProgressModeAuto),
+		fmt.Sprintf("How to display the progress: auto shows a progress bar when the output is a terminal, plain logs a line every %s "+
+			"and none only the final summary (one of: %s)", progressLinesInterval, strings.Join(progressModes, ", ")))
+}
+
+// Validate Checks that the mode is known
+func (p *ProgressFlags) Validate() error {
+	if !p.IsAuto() && !slices.Contains(progressModes, p.Mode) {
+		return fmt.Errorf("Expected --progress to be one of: %s, got '%s'", strings.Join(progressModes, ", "), p.Mode)
+	}
+	return nil
+}
+
+// IsAuto Returns true when the progress is displayed as a progress bar
+func (p *ProgressFlags) IsAuto() bool {
+	return p.Mode == "" || p.Mode == string(util.ProgressModeAuto)
+}
+
+// ProgressLogger Builds the ProgressLogger for the mode. The progress bar is displayed with logger, while the plain lines
+// and the final summary are written to ui with prefix even when the output is not a terminal, so that they show in CI logs
+func (p *ProgressFlags) ProgressLogger(ui goui.UI, logger util.LoggerWithLevels, prefix, finalMessage, errorMessagePrefix string) util.ProgressLogger {
+	switch util.ProgressMode(p.Mode) {
+	case util.ProgressModePlain, util.ProgressModeNone:
+		interval := progressLinesInterval
+		if p.Mode == string(util.ProgressModeNone) {
+			interval = 0
+		}
+		linesLogger := util.NewUILevelLogger(util.LogWarn, util.NewPrefixedLogger(prefix, util.NewLoggerNoTTY(ui)))
+		return util.NewProgressLines(linesLogger, interval, finalMessage, errorMessagePrefix)
+	default:
+		return util.NewProgressBar(logger, finalMessage, errorMessagePrefix)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+)
+
+// ProxyOptions Options for the proxy command
+type ProxyOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+
+	Mappings    []string
+	RepoDst     string
+	Listen      string
+	TLSCert     string
+	TLSKey      string
+	Concurrency int
+}
+
+// NewProxyOptions Builder for ProxyOptions
+func NewProxyOptions(ui ui.UI) *ProxyOptions {
+	return &ProxyOptions{ui: ui}
+}
+
+// NewProxyCmd Creates the proxy command
+func NewProxyCmd(o *ProxyOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Serve images relocating them to a repository when first requested (experimental)",
+		Long: "Serve a read-only registry that relocates the images of upstream repositories to a repository the first time " +
+			"clients request them, and serves them from that repository afterwards. Requesting a bundle relocates it " +
+			"together with all its images and nested bundles, so that edge registries are seeded gradually. " +
+			"This command is experimental, its flags and behavior may change",
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Serve registry.corp/org/app as localhost:5000/app, relocating the requested images to edge.corp/mirror
+    imgpkg proxy --map registry.corp/org/app=app --to-repo edge.corp/mirror
+
+    # Pull through the proxy, the first pull relocates the image
+    docker pull localhost:5000/app:1.0.0
+
+    # Serve every repository of registry.corp/org under org, with TLS
+    imgpkg proxy --map registry.corp/org=org --to-repo edge.corp/mirror --listen :5443 --tls-cert tls.crt --tls-key tls.key`,
+	}
+	o.RegistryFlags.Set(cmd)
+
+	cmd.Flags().StringSliceVar(&o.Mappings, "map", nil, "Upstream repository, or prefix of repositories, and the name it is served with (format: upstream=name, can be specified multiple times)")
+	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Location of the repository where the requested images are relocated")
+	cmd.Flags().StringVar(&o.Listen, "listen", "127.0.0.1:5000", "Address the proxy listens on")
+	cmd.Flags().StringVar(&o.TLSCert, "tls-cert", "", "Path to the certificate served by the proxy (requires --tls-key)")
+	cmd.Flags().StringVar(&o.TLSKey, "tls-key", "", "Path to the private key of the certificate served by the proxy")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the proxy command
+func (o *ProxyOptions) Run() error {
+	if o.RepoDst == "" {
+		return fmt.Errorf("Expected --to-repo to be specified")
+	}
+	if (o.TLSCert == "") != (o.TLSKey == "") {
+		return fmt.Errorf("Expected both --tls-cert and --tls-key to be specified")
+	}
+	mappings, err := o.mappings()
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.NewSimpleRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	prefixedLogger := util.NewPrefixedLogger("proxy | ", util.NewLogger(o.ui))
+	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
+	imageSet := ctlimgset.NewImageSet(o.Concurrency, prefixedLogger, util.DefaultTagGenerator{})
+
+	proxy, err := v1.NewProxy(v1.ProxyOpts{
+		Mappings:   mappings,
+		Repository: o.RepoDst,
+		CopyOpts: v1.CopyOpts{
+			Logger:             levelLogger,
+			ImageSet:           imageSet,
+			TarImageSet:        ctlimgset.NewTarImageSet(imageSet, o.Concurrency, prefixedLogger),
+			Concurrency:        o.Concurrency,
+			SignatureRetriever: signature.NewNoop(),
+		},
+	}, reg)
+	if err != nil {
+		return err
+	}
+
+	// There is no read or write timeout, the first request of a bundle waits for all its images to be relocated
+	server := &http.Server{
+		Addr:              o.Listen,
+		Handler:           proxy,
+		ReadHeaderTimeout: 30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	o.ui.PrintLinef("Proxy listening on %s, relocating to %s", o.Listen, o.RepoDst)
+	if o.TLSCert != "" {
+		return server.ListenAndServeTLS(o.TLSCert, o.TLSKey)
+	}
+	return server.ListenAndServe()
+}
+
+func (o *ProxyOptions) mappings() ([]v1.ProxyMapping, error) {
+	if len(o.Mappings) == 0 {
+		return nil, fmt.Errorf("Expected at least one --map to be specified")
+	}
+	var mappings []v1.ProxyMapping
+	for _, value := range o.Mappings {
+		pieces := strings.SplitN(value, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" || pieces[1] == "" {
+			return nil, fmt.Errorf("Expected mapping '%s' to have the format upstream=name (example: registry.corp/org/app=app)", value)
+		}
+		mappings = append(mappings, v1.ProxyMapping{Upstream: pieces[0], Local: pieces[1]})
+	}
+	return mappings, nil
+}
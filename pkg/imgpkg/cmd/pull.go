@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
@@ -18,12 +19,15 @@ type PullOptions struct {
 	ui ui.UI
 
 	ImageFlags           ImageFlags
+	IndexChildFlags      IndexChildFlags
 	ImageIsBundleCheck   bool
 	RegistryFlags        RegistryFlags
 	BundleFlags          BundleFlags
 	LockInputFlags       LockInputFlags
 	BundleRecursiveFlags BundleRecursiveFlags
+	LockRewriteFlags     LockRewriteFlags
 	OutputPath           string
+	Paths                []string
 }
 
 func NewPullOptions(ui ui.UI) *PullOptions {
@@ -40,16 +44,30 @@ func NewPullCmd(o *PullOptions) *cobra.Command {
   imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle
 
   # Pull image repo/app1-image and extract into /tmp/app1-image
-  imgpkg pull -i repo/app1-image -o /tmp/app1-image`,
+  imgpkg pull -i repo/app1-image -o /tmp/app1-image
+
+  # Pull the linux/arm64 image of the image index repo/app1-image and extract into /tmp/app1-image
+  imgpkg pull -i repo/app1-image --index-child-platform linux/arm64 -o /tmp/app1-image
+
+  # Pull only the config/charts directory of image repo/app1-image into /tmp/app1-image, downloading
+  # only the contents of its files when the layers are compressed with zstd:chunked
+  imgpkg pull -i repo/app1-image --path config/charts -o /tmp/app1-image
+
+  # Pull relocated bundle repo/app1-bundle keeping the upstream images in .imgpkg/images.yml
+  # and writing where they were relocated to in /tmp/mapping.yml
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --lock-rewrite original --lock-mapping-output /tmp/mapping.yml`,
 	}
 	o.ImageFlags.Set(cmd)
+	o.IndexChildFlags.Set(cmd)
 	cmd.Flags().BoolVar(&o.ImageIsBundleCheck, "image-is-bundle-check", true, "Error when image is a bundle (disable pulling bundles via -i)")
 	o.RegistryFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
 	o.BundleRecursiveFlags.Set(cmd)
 	o.LockInputFlags.Set(cmd)
+	o.LockRewriteFlags.Set(cmd)
 	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Output directory path")
 	cmd.MarkFlagRequired("output")
+	cmd.Flags().StringArrayVar(&o.Paths, "path", nil, "Pull only this file or directory of the image, relative to its root (can be specified multiple times)")
 
 	return cmd
 }
@@ -80,14 +98,21 @@ func (po *PullOptions) Run() error {
 	}
 
 	pullOpts := v1.PullOpts{
-		Logger:   levelLogger,
-		AsImage:  !po.ImageIsBundleCheck,
-		IsBundle: len(po.ImageFlags.Image) == 0,
+		Logger:             levelLogger,
+		AsImage:            !po.ImageIsBundleCheck,
+		IsBundle:           len(po.ImageFlags.Image) == 0,
+		IndexChildPlatform: po.IndexChildFlags.Platform,
+		LockRewrite:        po.LockRewriteFlags.LockRewrite(),
+		Paths:              po.Paths,
 	}
+	var status v1.PullStatus
 	if po.BundleRecursiveFlags.Recursive {
-		_, err = v1.PullRecursive(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
+		status, err = v1.PullRecursive(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
 	} else {
-		_, err = v1.Pull(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
+		status, err = v1.Pull(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
+	}
+	if err == nil {
+		return po.LockRewriteFlags.WriteMapping(status.Relocations)
 	}
 
 	if errors.Is(err, &v1.ErrIsBundle{}) {
@@ -131,8 +156,20 @@ func (po *PullOptions) validate() error {
 		return fmt.Errorf("Cannot use --recursive (-r) flag when pulling a bundle")
 	}
 
+	if po.IndexChildFlags.Platform != "" && len(po.ImageFlags.Image) == 0 {
+		return fmt.Errorf("Flag --index-child-platform can only be used when pulling an image (-i)")
+	}
+
 	if !po.ImageIsBundleCheck && len(po.BundleFlags.Bundle) != 0 {
 		return fmt.Errorf("Cannot set --image-is-bundle-check while using -b flag")
 	}
+
+	err := po.LockRewriteFlags.Validate()
+	if err != nil {
+		return err
+	}
+	if len(po.ImageFlags.Image) > 0 && (po.LockRewriteFlags.LockRewrite() != bundle.LockRewriteRelocated || po.LockRewriteFlags.MappingPath != "") {
+		return fmt.Errorf("Flags --lock-rewrite and --lock-mapping-output can only be used when pulling a bundle (-b or --lock)")
+	}
 	return nil
 }
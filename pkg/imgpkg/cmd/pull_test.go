@@ -33,6 +33,20 @@ func TestPullErrors(t *testing.T) {
 		require.ErrorContains(t, err, "Cannot use --recursive (-r) flag when pulling a bundle")
 	})
 
+	t.Run("fails when the lock rewrite is unknown", func(t *testing.T) {
+		pull := PullOptions{OutputPath: "/tmp/some/place", ImageIsBundleCheck: true, BundleFlags: BundleFlags{"my-bundle"}, LockRewriteFlags: LockRewriteFlags{Rewrite: "upstream"}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Unknown --lock-rewrite 'upstream' (supported: relocated, original, both)")
+	})
+
+	t.Run("fails when the lock mapping output is provided but not the bundle flag", func(t *testing.T) {
+		pull := PullOptions{OutputPath: "/tmp/some/place", ImageFlags: ImageFlags{"image@123456"}, LockRewriteFlags: LockRewriteFlags{MappingPath: "mapping.yml"}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flags --lock-rewrite and --lock-mapping-output can only be used when pulling a bundle (-b or --lock)")
+	})
+
 	t.Run("fails when arguments are provided without a flag", func(t *testing.T) {
 		confUI := ui.NewConfUI(ui.NewNoopLogger())
 		defer confUI.Flush()
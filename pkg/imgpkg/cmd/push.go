@@ -5,19 +5,33 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/featureflags"
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/secretscan"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
 	"github.com/cppforlife/go-cli-ui/ui"
 	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/spf13/cobra"
 )
 
 type PushOptions struct {
 	ui ui.UI
+	// stdin read when --file (-f) is '-', os.Stdin when not set
+	stdin io.Reader
 
 	ImageFlags      ImageFlags
 	BundleFlags     BundleFlags
@@ -25,6 +39,70 @@ type PushOptions struct {
 	FileFlags       FileFlags
 	RegistryFlags   RegistryFlags
 	LabelFlags      LabelFlags
+	BudgetFlags     BudgetFlags
+
+	NestedBundlesFlags NestedBundlesFlags
+
+	// PlatformDirs directories of each platform of a multi-arch image or bundle (format: os/arch[/variant]=path)
+	PlatformDirs []string
+	// Compression of the layers pushed (format: gzip or zstd[:level])
+	Compression string
+	// Estargz writes the layers pushed as seekable eStargz, that can be lazily pulled
+	Estargz bool
+	// OCIMediaTypes pushes the manifests, configs and layers with the OCI media types instead of the Docker ones
+	OCIMediaTypes bool
+	// Created time recorded in the images pushed (format: RFC3339 or source-epoch)
+	Created string
+	// Chown owner recorded for the files pushed (format: uid:gid)
+	Chown string
+	// ChmodMask permissions cleared from the files pushed (format: octal, example: 022)
+	ChmodMask string
+	// Annotations added to the manifest pushed (format: key=value)
+	Annotations []string
+	// SecretScan policy applied to the likely secrets found in the files pushed (off, warn, fail or redact)
+	SecretScan string
+	// ToOCILayout OCI image layout directory written instead of pushing to the registry
+	ToOCILayout string
+	// ToTar tar file written instead of pushing to the registry
+	ToTar string
+	// DryRun builds the image, or bundle, locally and prints its files and digests instead of pushing it
+	DryRun bool
+	// AttachSBOM format of the SBOM generated and attached to the image, or bundle, pushed (spdx or cyclonedx)
+	AttachSBOM string
+	// SBOMAttachment how the SBOM is attached, as an OCI referrer (referrer) or with the cosign .sbom tag (tag)
+	SBOMAttachment string
+	// LayerPerDir adds each top-level directory pushed to its own layer
+	LayerPerDir bool
+	// Layers paths added to their own layer (format: name=path)
+	Layers []string
+	// MaxLayerSize maximum size of the layers pushed, the files are split into more layers to stay under it (example: 10GB)
+	MaxLayerSize string
+	// ChunkedLayers splits the files into layers at content-defined boundaries
+	ChunkedLayers bool
+	// SignKey path to the cosign private key, or KMS URI, the digest pushed is signed with
+	SignKey string
+	// ResolveLockTags resolves the images of .imgpkg/images.yml referenced by tag to their digest before pushing the bundle
+	ResolveLockTags bool
+}
+
+// stdinFile value of --file (-f) that reads the files to push from a tar stream on stdin
+const stdinFile = "-"
+
+// minMaxLayerSize smallest --max-layer-size accepted, so that the files are not split into too many layers
+const minMaxLayerSize = 1024 * 1024
+
+// pushConcurrency images read concurrently when writing to an OCI image layout or a tar, the default of copy
+const pushConcurrency = 5
+
+// imageOpts Options of the images built from the files pushed
+type imageOpts struct {
+	compression ctlimg.LayerCompression
+	created     time.Time
+	labels      map[string]string
+	annotations map[string]string
+	fileFilter  ctlimg.FileFilter
+	fileAttrs   ctlimg.FileAttributes
+	layerSplit  ctlimg.LayerSplit
 }
 
 func NewPushOptions(ui ui.UI) *PushOptions {
@@ -41,7 +119,80 @@ func NewPushCmd(o *PushOptions) *cobra.Command {
   imgpkg push -b repo/app1-config -f config/
 
   # Push image repo/app1-config with contents from multiple locations
-  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml`,
+  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml
+
+  # Push bundle repo/app1-config only when all images, including the ones in nested bundles, are referenced by digest and stored in registry.example.com
+  imgpkg push -b repo/app1-config -f config/ --allowed-registry registry.example.com
+
+  # Push bundle repo/platform, the nested bundles must satisfy the requires section of config/.imgpkg/bundle.yml
+  imgpkg push -b repo/platform -f config/
+
+  # Push bundle repo/app1-config with the images of config/.imgpkg/images.yml referenced by tag, like nginx:1.21, resolved to their digest
+  imgpkg push -b repo/app1-config -f config/ --resolve-lock-tags
+
+  # Push bundle repo/app1-config with the files of a tar stream piped by the build system
+  bazel run //config:bundle_tar | imgpkg push -b repo/app1-config -f -
+
+  # Push bundle repo/app1-config leaving out the build artifacts and editor files listed in config/.imgpkgignore, with the gitignore syntax
+  printf 'build/\n*.swp\n!keep.swp\n' > config/.imgpkgignore
+  imgpkg push -b repo/app1-config -f config/
+
+  # Push bundle repo/app1-config with a layer per top-level directory of config/, so the unchanged directories are not uploaded again by the next version
+  imgpkg push -b repo/app1-config -f config/ --layer-per-dir
+
+  # Push bundle repo/app1-config with the charts in config/charts in their own layer
+  imgpkg push -b repo/app1-config -f config/ --layer charts=charts
+
+  # Push bundle repo/app1-config to a registry that rejects blobs bigger than 10GB, splitting the files into layers under that size
+  imgpkg push -b repo/app1-config -f config/ --max-layer-size 10GB
+
+  # Push bundle repo/app1-config with big files that change a little between versions, so that copies of the next versions only upload the layers with the changes
+  imgpkg push -b repo/app1-config -f config/ --chunked-layers
+
+  # Push bundle repo/app1-config with the OCI media types, to a registry that rejects the Docker ones
+  imgpkg push -b repo/app1-config -f config/ --oci-media-types
+
+  # Push bundle repo/app1-config compressing its contents with zstd at level 19
+  imgpkg push -b repo/app1-config -f config/ --compression zstd:19
+
+  # Push bundle repo/app1-config attaching an SPDX SBOM of its files and images, discovered through the OCI Referrers API
+  imgpkg push -b repo/app1-config -f config/ --attach-sbom spdx
+
+  # Push bundle repo/app1-config and sign its digest with the cosign key cosign.key, decrypted with the password in COSIGN_PASSWORD
+  imgpkg push -b repo/app1-config -f config/ --sign-key cosign.key
+
+  # Push bundle repo/app1-config with large contents as eStargz, lazily pulled by the stargz-snapshotter of containerd
+  imgpkg push -b repo/app1-config -f config/ --estargz
+
+  # Push bundle repo/app1-config recording the time of the last commit, so pushing the same files always results in the same digest
+  SOURCE_DATE_EPOCH=$(git log -1 --format=%ct) imgpkg push -b repo/app1-config -f config/ --created source-epoch
+
+  # Push bundle repo/app1-config with the same digest from every build agent, owned by 0:0 and without group or other write permissions
+  imgpkg push -b repo/app1-config -f config/ --preserve-permissions --chown 0:0 --chmod-mask 022
+
+  # Push bundle repo/app1-config with the version and the licenses as annotations of its manifest
+  imgpkg push -b repo/app1-config -f config/ --annotation org.opencontainers.image.version=1.0.0 --annotation org.opencontainers.image.licenses="Apache-2.0 OR MIT"
+
+  # Push bundle repo/app1-config failing when private keys or access tokens are found in config/
+  imgpkg push -b repo/app1-config -f config/ --secret-scan fail
+
+  # Write bundle registry.example.com/app1-config to the OCI image layout ./out, without access to registry.example.com,
+  # and copy it to the registry later
+  imgpkg push -b registry.example.com/app1-config:1.0.0 -f config/ --to-oci-layout ./out
+  imgpkg copy -b oci:./out --to-repo registry.example.com/app1-config
+
+  # Write bundle registry.example.com/app1-config to the tar app1-config.tar, copied later like the ones created with imgpkg copy --to-tar
+  imgpkg push -b registry.example.com/app1-config:1.0.0 -f config/ --to-tar app1-config.tar
+  imgpkg copy --tar app1-config.tar --to-repo registry.example.com/app1-config
+
+  # Push bundle repo/app1-config only when it and the images it references fit in 4GB and are at most 20 images
+  imgpkg push -b repo/app1-config -f config/ --max-bundle-size 4GB --max-image-count 20
+
+  # Preview the files, layer, config and manifest digests of bundle repo/app1-config without contacting its registry
+  imgpkg push -b repo/app1-config -f config/ --dry-run
+
+  # Push multi-arch bundle repo/app1-config, an image index with a bundle per platform
+  imgpkg push -b repo/app1-config --platform-dir linux/amd64=./amd64 --platform-dir linux/arm64=./arm64`,
 	}
 	o.ImageFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
@@ -49,6 +200,48 @@ func NewPushCmd(o *PushOptions) *cobra.Command {
 	o.FileFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
 	o.LabelFlags.Set(cmd)
+	o.NestedBundlesFlags.Set(cmd)
+	o.BudgetFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.Compression, "compression", "gzip", "Compression of the layers pushed, zstd layers use the OCI media types (format: gzip, zstd[:level], example: zstd:19)")
+	cmd.Flags().BoolVar(&o.Estargz, "estargz", false, "Write the layers pushed as seekable eStargz, so that the consumers using "+
+		"the stargz-snapshotter of containerd lazily pull only the files they read (requires --compression gzip)")
+	cmd.Flags().BoolVar(&o.OCIMediaTypes, "oci-media-types", false, "Push the manifests, configs and layers with the OCI media types instead of the Docker ones, "+
+		"for registries and artifact policies that reject the Docker media types (same as the feature flag "+featureflags.OCIMediaTypes+")")
+	cmd.Flags().BoolVar(&o.LayerPerDir, "layer-per-dir", false, "Add each top-level directory pushed to its own layer, and the top-level files "+
+		"and the bundle metadata to the first layer, so that copies of the next versions only upload the directories that changed")
+	cmd.Flags().StringArrayVar(&o.Layers, "layer", nil, "Add the files of a path, relative to the root of the image, to their own layer, "+
+		"the other files are in the first layer unless --layer-per-dir is used (format: name=path, example: charts=config/charts) (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.MaxLayerSize, "max-layer-size", "", "Split the files into more layers so that each layer is smaller than this size, "+
+		"the files bigger than it are split into parts across layers and reassembled on pull, "+
+		"versions of imgpkg that do not support this flag fail to pull the image (example: 10GB, 500MiB)")
+	cmd.Flags().BoolVar(&o.ChunkedLayers, "chunked-layers", false, "Split the files into parts, and the parts into layers, at boundaries chosen by their content, "+
+		"so that a small change to a big file only changes the layers around it and copies of the next versions do not upload the other layers again")
+	cmd.Flags().StringVar(&o.Created, "created", "", "Creation time recorded in the image config and as the modification time of the files, "+
+		"instead of the Unix epoch (format: RFC3339 or source-epoch to read it from SOURCE_DATE_EPOCH, example: 2024-01-31T10:00:00Z)")
+	cmd.Flags().StringVar(&o.Chown, "chown", "", "Owner recorded for the files and folders pushed, instead of 0:0 (format: uid:gid, example: 1000:1000)")
+	cmd.Flags().StringVar(&o.ChmodMask, "chmod-mask", "", "Permissions cleared from the files and folders pushed, so that they do not depend on "+
+		"the umask of the user that created them (format: octal, example: 022)")
+	cmd.Flags().StringArrayVar(&o.Annotations, "annotation", nil, "Set annotation on the manifest pushed, and on the manifest of each platform "+
+		"(format: key=value, example: org.opencontainers.image.version=1.0.0) (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.SecretScan, "secret-scan", string(secretscan.PolicyOff), "Scan the files pushed for likely secrets, like private keys and access tokens, "+
+		"and report them (warn), stop before pushing anything (fail) or push them redacted (redact) (format: off, warn, fail or redact)")
+	cmd.Flags().StringVar(&o.ToOCILayout, "to-oci-layout", "", "Write the image, or the bundle and the images it references, to this OCI image layout "+
+		"directory instead of pushing to the registry, the reference provided is recorded in the layout")
+	cmd.Flags().StringVar(&o.ToTar, "to-tar", "", "Write the image, or the bundle and the images it references, to this tar file "+
+		"instead of pushing to the registry, the reference provided is recorded in the tar")
+	cmd.Flags().StringArrayVar(&o.PlatformDirs, "platform-dir", nil, "Push an image index with an image, or bundle, per platform built from the "+
+		"directory of the platform instead of --file (-f) (format: os/arch[/variant]=path, example: linux/amd64=./amd64) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "Build the image, or bundle, without pushing it and print its files, "+
+		"the digests of its layers and config, and the digest of the manifest that would be pushed")
+	cmd.Flags().StringVar(&o.AttachSBOM, "attach-sbom", "", "Generate an SBOM listing the files pushed and, for bundles, the images they reference, "+
+		"and attach it to the image, or bundle, pushed (format: spdx or cyclonedx)")
+	cmd.Flags().StringVar(&o.SBOMAttachment, "sbom-attachment", sbomAttachmentReferrer, "Attach the SBOM as an OCI referrer, listed by the "+
+		"OCI Referrers API, or with the .sbom tag of cosign (format: referrer or tag)")
+	cmd.Flags().BoolVar(&o.ResolveLockTags, "resolve-lock-tags", false, "Resolve the images of .imgpkg/images.yml referenced by tag to their digest, "+
+		"and push the bundle with the resolved ImagesLock, recording the tagged reference in the imgpkg.carvel.dev/resolved-tag annotation")
+	cmd.Flags().StringVar(&o.SignKey, "sign-key", "", "Sign the digest pushed with this cosign private key, decrypted with the password "+
+		"in the environment variable COSIGN_PASSWORD, or with this KMS URI using the cosign binary (example: cosign.key, awskms:///arn:aws:kms:...). "+
+		"The digest is tagged once it is signed. The signature is not uploaded to the transparency log, verify it with cosign verify --insecure-ignore-tlog")
 
 	return cmd
 }
@@ -64,11 +257,44 @@ func (po *PushOptions) Run() error {
 		return err
 	}
 
+	cleanupStdinFiles, err := po.readStdinFiles()
+	if err != nil {
+		return err
+	}
+	defer cleanupStdinFiles()
+
+	opts, err := po.imageOpts()
+	if err != nil {
+		return err
+	}
+
+	opts.fileFilter, err = po.scanSecrets()
+	if err != nil {
+		return err
+	}
+
 	var imageURL string
 
 	isBundle := po.BundleFlags.Bundle != ""
 	isImage := po.ImageFlags.Image != ""
 
+	var pushReg registry.Registry = reg
+	var localReg *registry.OCILayoutRegistry
+	if (po.isLocalDst() || po.DryRun) && (isBundle != isImage) {
+		localReg, err = po.localRegistry(reg)
+		if err != nil {
+			return err
+		}
+		defer localReg.Cleanup()
+		pushReg = localReg
+	}
+	// The digest is pushed and signed before it is tagged
+	var signedTagsReg *heldTagsRegistry
+	if po.SignKey != "" {
+		signedTagsReg = &heldTagsRegistry{Registry: pushReg}
+		pushReg = signedTagsReg
+	}
+
 	switch {
 	case isBundle && isImage:
 		return fmt.Errorf("Expected only one of image or bundle")
@@ -77,13 +303,27 @@ func (po *PushOptions) Run() error {
 		return fmt.Errorf("Expected either image or bundle")
 
 	case isBundle:
-		imageURL, err = po.pushBundle(reg)
+		if po.BudgetFlags.IsSet() && localReg == nil {
+			err = po.checkBudgetsBeforePush(reg, opts)
+			if err != nil {
+				return err
+			}
+		}
+
+		imageURL, err = po.pushBundle(pushReg, opts)
 		if err != nil {
 			return err
 		}
 
+		if po.BudgetFlags.IsSet() && localReg != nil {
+			err = po.checkBudgets(localReg, imageURL)
+			if err != nil {
+				return err
+			}
+		}
+
 	case isImage:
-		imageURL, err = po.pushImage(reg)
+		imageURL, err = po.pushImage(pushReg, opts)
 		if err != nil {
 			return err
 		}
@@ -92,19 +332,101 @@ func (po *PushOptions) Run() error {
 		panic("Unreachable code")
 	}
 
+	if po.DryRun {
+		return po.printDryRun(localReg, imageURL)
+	}
+	if localReg != nil {
+		return po.writeLocalDst(localReg)
+	}
+
+	if po.AttachSBOM != "" {
+		err = po.attachSBOM(reg, imageURL, opts.created)
+		if err != nil {
+			return err
+		}
+	}
+
+	if po.SignKey != "" {
+		err = po.sign(reg, imageURL)
+		if err != nil {
+			return err
+		}
+		err = signedTagsReg.WriteHeldTags()
+		if err != nil {
+			return err
+		}
+	}
+
 	po.ui.BeginLinef("Pushed '%s'", imageURL)
 
 	return nil
 }
 
-func (po *PushOptions) pushBundle(registry registry.Registry) (string, error) {
+// isLocalDst Returns true when writing to an OCI image layout or a tar instead of the registry
+func (po *PushOptions) isLocalDst() bool {
+	return po.ToOCILayout != "" || po.ToTar != ""
+}
+
+// localRegistry Returns the registry that keeps the repository pushed in a temporary folder, so that it can be written to
+// the OCI image layout or the tar, or previewed with --dry-run, while the images referenced by the bundle are read from their registries.
+// The caller removes the folder with Cleanup
+func (po *PushOptions) localRegistry(reg registry.Registry) (*registry.OCILayoutRegistry, error) {
+	ref := po.pushedRef()
+	uploadRef, err := regname.NewTag(ref, regname.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing '%s': %s", ref, err)
+	}
+	return registry.NewTemporaryOCILayoutRegistry(uploadRef.Context(), reg)
+}
+
+// writeLocalDst Writes the image, or the bundle and the images it references, pushed to reg to the OCI image layout or the tar
+func (po *PushOptions) writeLocalDst(reg registry.Registry) error {
+	prefixedLogger := util.NewPrefixedLogger("push | ", util.NewLogger(po.ui))
+	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
+
+	imageSet := ctlimgset.NewImageSet(pushConcurrency, prefixedLogger, util.DefaultTagGenerator{})
+	opts := v1.CopyOpts{
+		Logger:      levelLogger,
+		ImageSet:    imageSet,
+		TarImageSet: ctlimgset.NewTarImageSet(imageSet, pushConcurrency, prefixedLogger),
+		Concurrency: pushConcurrency,
+		// the signatures of the images referenced by the bundle are not written, the same as when copying without --cosign-signatures
+		SignatureRetriever: signature.NewNoop(),
+	}
+	origin := v1.CopyOrigin{ImageRef: po.ImageFlags.Image, BundleRef: po.BundleFlags.Bundle}
+
+	if po.ToOCILayout != "" {
+		_, err := v1.CopyToOCILayout(origin, po.ToOCILayout, opts, reg)
+		if err != nil {
+			return err
+		}
+		po.ui.BeginLinef("Pushed '%s' to OCI image layout '%s'", po.pushedRef(), po.ToOCILayout)
+		return nil
+	}
+
+	_, err := v1.CopyToTar(origin, po.ToTar, opts, reg)
+	if err != nil {
+		return err
+	}
+	po.ui.BeginLinef("Pushed '%s' to tar '%s'", po.pushedRef(), po.ToTar)
+	return nil
+}
+
+// pushedRef Returns the reference of the image or bundle pushed
+func (po *PushOptions) pushedRef() string {
+	if po.BundleFlags.Bundle != "" {
+		return po.BundleFlags.Bundle
+	}
+	return po.ImageFlags.Image
+}
+
+func (po *PushOptions) pushBundle(registry registry.Registry, opts imageOpts) (string, error) {
 	uploadRef, err := regname.NewTag(po.BundleFlags.Bundle, regname.WeakValidation)
 	if err != nil {
 		return "", fmt.Errorf("Parsing '%s': %s", po.BundleFlags.Bundle, err)
 	}
 
-	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
-	imageURL, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).Push(uploadRef, po.LabelFlags.Labels, registry, logger)
+	imageURL, err := po.buildBundle(uploadRef, registry, opts, util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui)))
 	if err != nil {
 		return "", err
 	}
@@ -115,13 +437,26 @@ func (po *PushOptions) pushBundle(registry registry.Registry) (string, error) {
 				APIVersion: lockconfig.BundleLockAPIVersion,
 				Kind:       lockconfig.BundleLockKind,
 			},
+			Annotations: po.LockOutputFlags.SummaryAnnotations(append(append([]string{}, po.FileFlags.Files...), po.PlatformDirs...), uploadRef.Name(), map[string]string{
+				"compression":          po.Compression,
+				"estargz":              strconv.FormatBool(po.Estargz),
+				"oci-media-types":      strconv.FormatBool(po.OCIMediaTypes),
+				"created":              po.Created,
+				"preserve-permissions": strconv.FormatBool(po.FileFlags.PreservePermissions),
+				"chown":                po.Chown,
+				"chmod-mask":           po.ChmodMask,
+			}),
 			Bundle: lockconfig.BundleRef{
 				Image: imageURL,
 				Tag:   uploadRef.TagStr(),
 			},
 		}
 
-		err := bundleLock.WriteToPath(po.LockOutputFlags.LockFilePath)
+		format, err := po.LockOutputFlags.Format(po.LockOutputFlags.LockFilePath)
+		if err != nil {
+			return "", err
+		}
+		err = bundleLock.WriteToPathInFormat(po.LockOutputFlags.LockFilePath, format)
 		if err != nil {
 			return "", err
 		}
@@ -130,7 +465,89 @@ func (po *PushOptions) pushBundle(registry registry.Registry) (string, error) {
 	return imageURL, nil
 }
 
-func (po *PushOptions) pushImage(registry registry.Registry) (string, error) {
+// buildBundle Validates the contents of the bundle and pushes it to registry, returning the reference of the bundle pushed
+func (po *PushOptions) buildBundle(uploadRef regname.Tag, registry registry.Registry, opts imageOpts, logger *util.LevelLogger) (string, error) {
+	if len(po.PlatformDirs) > 0 {
+		platforms, err := po.platformContents()
+		if err != nil {
+			return "", err
+		}
+		contents := bundle.NewMultiPlatformContents(platforms, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).WithCompression(opts.compression).WithCreated(opts.created).WithAnnotations(opts.annotations).WithFileFilter(opts.fileFilter).WithFileAttributes(opts.fileAttrs).WithLayerSplit(opts.layerSplit)
+		for _, platformBundle := range contents.PlatformBundles() {
+			err = po.validateBundleContents(platformBundle, registry, logger)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return contents.Push(uploadRef, opts.labels, registry, logger)
+	}
+
+	contents := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).WithCompression(opts.compression).WithCreated(opts.created).WithAnnotations(opts.annotations).WithFileFilter(opts.fileFilter).WithFileAttributes(opts.fileAttrs).WithLayerSplit(opts.layerSplit)
+	if po.ResolveLockTags {
+		var err error
+		contents, err = contents.ResolveImagesLockTags(registry, logger)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := po.validateBundleContents(contents, registry, logger)
+	if err != nil {
+		return "", err
+	}
+
+	return contents.Push(uploadRef, opts.labels, registry, logger)
+}
+
+// checkBudgetsBeforePush Builds the bundle locally, without pushing it, to check it against the budgets
+func (po *PushOptions) checkBudgetsBeforePush(reg registry.Registry, opts imageOpts) error {
+	uploadRef, err := regname.NewTag(po.BundleFlags.Bundle, regname.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", po.BundleFlags.Bundle, err)
+	}
+	localReg, err := po.localRegistry(reg)
+	if err != nil {
+		return err
+	}
+	defer localReg.Cleanup()
+
+	imageURL, err := po.buildBundle(uploadRef, localReg, opts, util.NewNoopLevelLogger())
+	if err != nil {
+		return err
+	}
+	return po.checkBudgets(localReg, imageURL)
+}
+
+// checkBudgets Fails when the bundle in reg, its nested bundles and the images they reference exceed the budgets
+func (po *PushOptions) checkBudgets(reg registry.Registry, imageURL string) error {
+	imageSet := ctlimgset.NewImageSet(pushConcurrency, util.NewNoopLogger(), util.DefaultTagGenerator{})
+	report, err := v1.CopyDryRun(v1.CopyOrigin{BundleRef: imageURL}, v1.CopyOpts{
+		Logger:             util.NewNoopLevelLogger(),
+		ImageSet:           imageSet,
+		TarImageSet:        ctlimgset.NewTarImageSet(imageSet, pushConcurrency, util.NewNoopLogger()),
+		Concurrency:        pushConcurrency,
+		SignatureRetriever: signature.NewNoop(),
+	}, reg)
+	if err != nil {
+		return fmt.Errorf("Checking the budgets of the bundle: %s", err)
+	}
+	return po.BudgetFlags.Check(report)
+}
+
+// validateBundleContents checks the nested bundles, when requested, and the dependencies of the bundle
+func (po *PushOptions) validateBundleContents(contents bundle.Contents, registry registry.Registry, logger bundle.Logger) error {
+	if po.NestedBundlesFlags.Enabled() {
+		err := contents.ValidateNestedBundles(po.NestedBundlesFlags.AsPolicy(), registry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return contents.ValidateDependencies(registry, logger)
+}
+
+func (po *PushOptions) pushImage(registry registry.Registry, opts imageOpts) (string, error) {
 	if po.LockOutputFlags.LockFilePath != "" {
 		return "", fmt.Errorf("Lock output is not compatible with image, use bundle for lock output")
 	}
@@ -140,26 +557,357 @@ func (po *PushOptions) pushImage(registry registry.Registry) (string, error) {
 		return "", fmt.Errorf("Parsing '%s': %s", po.ImageFlags.Image, err)
 	}
 
-	isBundle, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).PresentsAsBundle()
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
+
+	if len(po.PlatformDirs) > 0 {
+		platforms, err := po.platformContents()
+		if err != nil {
+			return "", err
+		}
+		for _, platform := range platforms {
+			err = po.validateNotBundle(platform.Paths)
+			if err != nil {
+				return "", err
+			}
+		}
+		return plainimage.NewMultiPlatformContents(platforms, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).WithCompression(opts.compression).WithCreated(opts.created).WithAnnotations(opts.annotations).WithFileFilter(opts.fileFilter).WithFileAttributes(opts.fileAttrs).WithLayerSplit(opts.layerSplit).Push(uploadRef, opts.labels, registry, logger)
+	}
+
+	err = po.validateNotBundle(po.FileFlags.Files)
 	if err != nil {
 		return "", err
 	}
-	if isBundle {
-		return "", fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
+
+	return plainimage.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).WithCompression(opts.compression).WithCreated(opts.created).WithAnnotations(opts.annotations).WithFileFilter(opts.fileFilter).WithFileAttributes(opts.fileAttrs).WithLayerSplit(opts.layerSplit).Push(uploadRef, opts.labels, registry, logger)
+}
+
+// readStdinFiles When --file (-f) is '-', extracts the tar stream read from stdin to a temporary directory that is
+// pushed in its place. The returned function removes the directory
+func (po *PushOptions) readStdinFiles() (func(), error) {
+	stdinIdx := -1
+	for i, file := range po.FileFlags.Files {
+		if file != stdinFile {
+			continue
+		}
+		if stdinIdx != -1 {
+			return nil, fmt.Errorf("Flag --file (-f) can only be '%s' once, stdin can only be read once", stdinFile)
+		}
+		stdinIdx = i
+	}
+	if stdinIdx == -1 {
+		return func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "imgpkg-push-stdin")
+	if err != nil {
+		return nil, fmt.Errorf("Creating directory for the files read from stdin: %s", err)
+	}
+	files := po.FileFlags.Files
+	cleanup := func() {
+		po.FileFlags.Files = files
+		_ = os.RemoveAll(dir)
+	}
+
+	stdin := po.stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	err = ctlimg.ExtractTarStream(stdin, dir)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("Reading the files to push from stdin: %s", err)
+	}
+
+	po.FileFlags.Files = append([]string{}, files...)
+	po.FileFlags.Files[stdinIdx] = dir
+	return cleanup, nil
+}
+
+// imageOpts parses the flags configuring the images pushed
+func (po *PushOptions) imageOpts() (imageOpts, error) {
+	compression, err := po.layerCompression()
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	created, err := po.created()
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	labels, err := po.LabelFlags.AsLabels()
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	annotations, err := parseKeyValues("--annotation", po.Annotations)
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	fileAttrs, err := po.fileAttributes()
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	layerSplit, err := po.layerSplit()
+	if err != nil {
+		return imageOpts{}, err
+	}
+
+	return imageOpts{compression: compression, created: created, labels: labels, annotations: annotations, fileAttrs: fileAttrs, layerSplit: layerSplit}, nil
+}
+
+// scanSecrets scans the files pushed for likely secrets according to --secret-scan, returning the filter
+// that redacts them when they should be pushed redacted
+func (po *PushOptions) scanSecrets() (ctlimg.FileFilter, error) {
+	if po.SecretScan == "" {
+		return nil, nil
+	}
+	policy, err := secretscan.ParsePolicy(po.SecretScan)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing --secret-scan: %s", err)
+	}
+	if policy == secretscan.PolicyOff {
+		return nil, nil
+	}
+
+	paths := po.FileFlags.Files
+	if len(po.PlatformDirs) > 0 {
+		platforms, err := po.platformContents()
+		if err != nil {
+			return nil, err
+		}
+		for _, platform := range platforms {
+			paths = append(paths, platform.Paths...)
+		}
+	}
+
+	findings, err := secretscan.ScanPaths(paths, po.FileFlags.ExcludedFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(findings) == 0 {
+		return nil, nil
 	}
 
 	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
-	return plainimage.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).Push(uploadRef, po.LabelFlags.Labels, registry, logger)
+	switch policy {
+	case secretscan.PolicyWarn:
+		logger.Warnf("Found %d likely secret(s) in the files pushed:\n%s\n", len(findings), secretscan.Describe(findings))
+		return nil, nil
+	case secretscan.PolicyRedact:
+		logger.Warnf("Redacting %d likely secret(s) in the files pushed:\n%s\n", len(findings), secretscan.Describe(findings))
+		return secretscan.Redact, nil
+	default:
+		return nil, fmt.Errorf("Found %d likely secret(s) in the files pushed, nothing was pushed "+
+			"(hint: remove them, or push them redacted with --secret-scan redact):\n%s", len(findings), secretscan.Describe(findings))
+	}
+}
+
+// layerCompression parses --compression, gzip is used when it is not provided. The media types are the OCI ones
+// with --oci-media-types or when the feature flag oci-media-types is enabled
+func (po *PushOptions) layerCompression() (ctlimg.LayerCompression, error) {
+	ociMediaTypes := po.OCIMediaTypes || featureflags.Enabled(featureflags.OCIMediaTypes)
+	if po.Compression == "" {
+		return ctlimg.LayerCompression{Estargz: po.Estargz, OCIMediaTypes: ociMediaTypes}, nil
+	}
+	compression, err := ctlimg.ParseLayerCompression(po.Compression)
+	if err != nil {
+		return ctlimg.LayerCompression{}, fmt.Errorf("Parsing --compression: %s", err)
+	}
+	if po.Estargz && compression.IsZstd() {
+		return ctlimg.LayerCompression{}, fmt.Errorf("Flag --estargz can only be used with --compression gzip")
+	}
+	compression.Estargz = po.Estargz
+	compression.OCIMediaTypes = ociMediaTypes
+	return compression, nil
+}
+
+// created parses --created, the Unix epoch is recorded when it is not provided
+func (po *PushOptions) created() (time.Time, error) {
+	if po.Created == "" {
+		return time.Time{}, nil
+	}
+	created, err := ctlimg.ParseCreated(po.Created)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Parsing --created: %s", err)
+	}
+	return created, nil
+}
+
+// fileAttributes parses --chown and --chmod-mask, the files are owned by 0:0 and keep their permissions when they are not provided
+func (po *PushOptions) fileAttributes() (ctlimg.FileAttributes, error) {
+	var fileAttrs ctlimg.FileAttributes
+	var err error
+	if po.Chown != "" {
+		fileAttrs.UID, fileAttrs.GID, err = ctlimg.ParseChown(po.Chown)
+		if err != nil {
+			return ctlimg.FileAttributes{}, fmt.Errorf("Parsing --chown: %s", err)
+		}
+	}
+	if po.ChmodMask != "" {
+		fileAttrs.ChmodMask, err = ctlimg.ParseChmodMask(po.ChmodMask)
+		if err != nil {
+			return ctlimg.FileAttributes{}, fmt.Errorf("Parsing --chmod-mask: %s", err)
+		}
+	}
+	return fileAttrs, nil
+}
+
+// layerSplit parses --layer-per-dir, --layer, --max-layer-size and --chunked-layers, all the files are in a single layer when they are not provided
+func (po *PushOptions) layerSplit() (ctlimg.LayerSplit, error) {
+	layerSplit := ctlimg.LayerSplit{PerDir: po.LayerPerDir}
+	for _, value := range po.Layers {
+		layer, err := ctlimg.ParseNamedLayerPath(value)
+		if err != nil {
+			return ctlimg.LayerSplit{}, fmt.Errorf("Parsing --layer: %s", err)
+		}
+		layerSplit.Layers = append(layerSplit.Layers, layer)
+	}
+
+	if po.MaxLayerSize != "" {
+		maxSize, err := parseSize("--max-layer-size", po.MaxLayerSize)
+		if err != nil {
+			return ctlimg.LayerSplit{}, err
+		}
+		if maxSize < minMaxLayerSize {
+			return ctlimg.LayerSplit{}, fmt.Errorf("Expected --max-layer-size to be at least 1MiB, got '%s'", po.MaxLayerSize)
+		}
+		if po.Estargz {
+			return ctlimg.LayerSplit{}, fmt.Errorf("Flag --max-layer-size cannot be used with --estargz")
+		}
+		layerSplit.MaxSize = maxSize
+	}
+
+	if po.ChunkedLayers {
+		if po.MaxLayerSize != "" {
+			return ctlimg.LayerSplit{}, fmt.Errorf("Flag --chunked-layers cannot be used with --max-layer-size")
+		}
+		if po.Estargz {
+			return ctlimg.LayerSplit{}, fmt.Errorf("Flag --chunked-layers cannot be used with --estargz")
+		}
+		layerSplit.Chunking = true
+	}
+	return layerSplit, nil
+}
+
+// validateNotBundle checks that the paths pushed as an image do not contain '.imgpkg' directories
+func (po *PushOptions) validateNotBundle(paths []string) error {
+	isBundle, err := bundle.NewContents(paths, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).PresentsAsBundle()
+	if err != nil {
+		return err
+	}
+	if isBundle {
+		return fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
+	}
+	return nil
+}
+
+// platformContents parses --platform-dir, the directories provided for the same platform are pushed together
+func (po *PushOptions) platformContents() ([]plainimage.PlatformContents, error) {
+	var platforms []plainimage.PlatformContents
+	platformIdx := map[string]int{}
+	for _, platformDir := range po.PlatformDirs {
+		platformStr, path, found := strings.Cut(platformDir, "=")
+		if !found || platformStr == "" || path == "" {
+			return nil, fmt.Errorf("Expected --platform-dir '%s' to have the format os/arch[/variant]=path", platformDir)
+		}
+		platform, err := regv1.ParsePlatform(platformStr)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing platform '%s': %s", platformStr, err)
+		}
+		if platform.OS == "" || platform.Architecture == "" {
+			return nil, fmt.Errorf("Expected platform '%s' to have the format os/arch[/variant]", platformStr)
+		}
+
+		if i, seen := platformIdx[platform.String()]; seen {
+			platforms[i].Paths = append(platforms[i].Paths, path)
+			continue
+		}
+		platformIdx[platform.String()] = len(platforms)
+		platforms = append(platforms, plainimage.PlatformContents{Platform: *platform, Paths: []string{path}})
+	}
+	return platforms, nil
 }
 
 // validateFlags checks if the provided flags are valid
 func (po *PushOptions) validateFlags() error {
+	labels, err := po.LabelFlags.AsLabels()
+	if err != nil {
+		return err
+	}
 
 	// Verify the user did NOT specify a reserved OCI label
-	_, present := po.LabelFlags.Labels[bundle.BundleConfigLabel]
+	for _, reservedLabel := range []string{bundle.BundleConfigLabel, bundle.BundleNameLabel, bundle.BundleVersionLabel, bundle.BundleRequiresLabel} {
+		_, present := labels[reservedLabel]
+
+		if present {
+			return fmt.Errorf("label '%s' is reserved and cannot be overriden. Please use a different key", reservedLabel)
+		}
+	}
+
+	if po.NestedBundlesFlags.Enabled() && po.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Validating nested bundles is only possible when pushing a bundle")
+	}
+
+	if po.ResolveLockTags && po.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Flag --resolve-lock-tags can only be used when pushing a bundle")
+	}
+
+	if po.ResolveLockTags && len(po.PlatformDirs) > 0 {
+		return fmt.Errorf("Flag --resolve-lock-tags cannot be used with --platform-dir")
+	}
+
+	if po.BudgetFlags.IsSet() && po.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Flags --max-bundle-size and --max-image-count can only be used when pushing a bundle")
+	}
+
+	err = po.BudgetFlags.Validate()
+	if err != nil {
+		return err
+	}
+
+	if len(po.PlatformDirs) > 0 && len(po.FileFlags.Files) > 0 {
+		return fmt.Errorf("Flag --platform-dir cannot be used with --file (-f)")
+	}
+
+	if po.ToOCILayout != "" && po.ToTar != "" {
+		return fmt.Errorf("Flag --to-oci-layout cannot be used with --to-tar")
+	}
+
+	if po.LockOutputFlags.Annotations && po.LockOutputFlags.LockFilePath == "" {
+		return fmt.Errorf("Flag --lock-output-annotations can only be used with --lock-output")
+	}
+
+	if po.LockOutputFlags.OutputFormat != "" && po.LockOutputFlags.LockFilePath == "" {
+		return fmt.Errorf("Flag --lock-output-format can only be used with --lock-output")
+	}
+
+	err = po.LockOutputFlags.Validate()
+	if err != nil {
+		return err
+	}
+
+	if po.isLocalDst() && po.LockOutputFlags.LockFilePath != "" {
+		return fmt.Errorf("Flag --lock-output cannot be used with --to-oci-layout or --to-tar")
+	}
+
+	if po.DryRun && po.isLocalDst() {
+		return fmt.Errorf("Flag --dry-run cannot be used with --to-oci-layout or --to-tar")
+	}
+
+	if po.DryRun && po.LockOutputFlags.LockFilePath != "" {
+		return fmt.Errorf("Flag --lock-output cannot be used with --dry-run, nothing is pushed")
+	}
+
+	err = po.validateSBOMFlags()
+	if err != nil {
+		return err
+	}
 
-	if present {
-		return fmt.Errorf("label '%s' is reserved and cannot be overriden. Please use a different key", bundle.BundleConfigLabel)
+	if po.SignKey != "" && (po.isLocalDst() || po.DryRun) {
+		return fmt.Errorf("Flag --sign-key cannot be used with --to-oci-layout, --to-tar or --dry-run")
 	}
 
 	return nil
@@ -0,0 +1,165 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// printDryRun Prints the files, the layers and the digests of the image, or bundle, built in reg instead of pushing it.
+// For multi-platform images the index digest is printed after the preview of each platform
+func (po *PushOptions) printDryRun(reg registry.Registry, imageURL string) error {
+	ref, err := regname.NewDigest(imageURL)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", imageURL, err)
+	}
+	desc, err := reg.Get(ref)
+	if err != nil {
+		return err
+	}
+
+	if !desc.MediaType.IsIndex() {
+		err = po.printImageDryRun(reg, ref, "")
+		if err != nil {
+			return err
+		}
+		po.ui.PrintLinef("Dry run: '%s' would be pushed with manifest digest %s", po.pushedRef(), ref.DigestStr())
+		return nil
+	}
+
+	index, err := reg.Index(ref)
+	if err != nil {
+		return err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return err
+	}
+	for _, manifest := range indexManifest.Manifests {
+		platform := ""
+		if manifest.Platform != nil {
+			platform = manifest.Platform.String()
+		}
+		err = po.printImageDryRun(reg, ref.Context().Digest(manifest.Digest.String()), platform)
+		if err != nil {
+			return err
+		}
+	}
+	po.ui.PrintLinef("Dry run: '%s' would be pushed with index digest %s", po.pushedRef(), ref.DigestStr())
+	return nil
+}
+
+// printImageDryRun Prints the files of each layer of the image, its layer and config digests and its manifest digest
+func (po *PushOptions) printImageDryRun(reg registry.Registry, ref regname.Digest, platform string) error {
+	img, err := reg.Image(ref)
+	if err != nil {
+		return err
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+
+	title := "Files"
+	if platform != "" {
+		title = fmt.Sprintf("Files of platform %s", platform)
+	}
+	filesTable := uitable.Table{
+		Title:   title,
+		Content: "files",
+		Header: []uitable.Header{
+			uitable.NewHeader("Path"),
+			uitable.NewHeader("Size"),
+			uitable.NewHeader("Layer"),
+		},
+	}
+	layersTable := uitable.Table{
+		Content: "layers",
+		Header: []uitable.Header{
+			uitable.NewHeader("Layer"),
+			uitable.NewHeader("Media Type"),
+			uitable.NewHeader("Size"),
+		},
+	}
+
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return err
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return err
+		}
+		layersTable.Rows = append(layersTable.Rows, []uitable.Value{
+			uitable.NewValueString(digest.String()),
+			uitable.NewValueString(string(mediaType)),
+			uitable.NewValueString(util.HumanizeBytes(size)),
+		})
+
+		files, err := layerFiles(layer)
+		if err != nil {
+			return fmt.Errorf("Reading the files of layer %s: %s", digest, err)
+		}
+		for _, file := range files {
+			filesTable.Rows = append(filesTable.Rows, []uitable.Value{
+				uitable.NewValueString(file.Name),
+				uitable.NewValueString(util.HumanizeBytes(file.Size)),
+				uitable.NewValueString(digest.String()),
+			})
+		}
+	}
+
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		return err
+	}
+
+	po.ui.PrintTable(filesTable)
+	po.ui.PrintTable(layersTable)
+	po.ui.PrintLinef("Config digest: %s", configDigest)
+	if platform != "" {
+		po.ui.PrintLinef("Manifest digest of platform %s: %s", platform, ref.DigestStr())
+	}
+	return nil
+}
+
+// layerFiles Returns the headers of the regular files, and the parts of the files split across layers, of the layer
+// in the order they are stored
+func layerFiles(layer regv1.Layer) ([]*tar.Header, error) {
+	contents, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer contents.Close()
+
+	var files []*tar.Header
+	tarReader := tar.NewReader(contents)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == ctlimg.LayerPartTypeflag {
+			files = append(files, header)
+		}
+	}
+}
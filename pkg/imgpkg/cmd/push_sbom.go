@@ -0,0 +1,119 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/sbom"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature/cosign"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// How the SBOM is attached to the image, or bundle, pushed
+const (
+	// sbomAttachmentReferrer pushes the SBOM by digest with the image as its subject, listed by the OCI Referrers API
+	sbomAttachmentReferrer = "referrer"
+	// sbomAttachmentTag pushes the SBOM with the .sbom tag cosign uses
+	sbomAttachmentTag = "tag"
+)
+
+// validateSBOMFlags Checks the format and the attachment of the SBOM
+func (po *PushOptions) validateSBOMFlags() error {
+	if po.AttachSBOM == "" {
+		return nil
+	}
+	_, err := sbom.ParseFormat(po.AttachSBOM)
+	if err != nil {
+		return fmt.Errorf("Parsing --attach-sbom: %s", err)
+	}
+	switch po.SBOMAttachment {
+	case "", sbomAttachmentReferrer, sbomAttachmentTag:
+	default:
+		return fmt.Errorf("Unknown SBOM attachment '%s' (supported: %s, %s)", po.SBOMAttachment, sbomAttachmentReferrer, sbomAttachmentTag)
+	}
+	if po.isLocalDst() || po.DryRun {
+		return fmt.Errorf("Flag --attach-sbom cannot be used with --to-oci-layout, --to-tar or --dry-run")
+	}
+	return nil
+}
+
+// attachSBOM Generates the SBOM of the image, or bundle, pushed and attaches it. For multi-platform images an SBOM
+// is attached to the manifest of each platform
+func (po *PushOptions) attachSBOM(reg registry.Registry, imageURL string, created time.Time) error {
+	format, err := sbom.ParseFormat(po.AttachSBOM)
+	if err != nil {
+		return err
+	}
+	ref, err := regname.NewDigest(imageURL)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", imageURL, err)
+	}
+	desc, err := reg.Get(ref)
+	if err != nil {
+		return err
+	}
+
+	if !desc.MediaType.IsIndex() {
+		return po.attachSBOMTo(reg, ref, desc.Descriptor, format, created)
+	}
+
+	index, err := reg.Index(ref)
+	if err != nil {
+		return err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return err
+	}
+	for _, manifest := range indexManifest.Manifests {
+		err = po.attachSBOMTo(reg, ref.Context().Digest(manifest.Digest.String()), manifest, format, created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachSBOMTo Pushes the SBOM of the image ref, described by subject, by digest or with the cosign .sbom tag
+func (po *PushOptions) attachSBOMTo(reg registry.Registry, ref regname.Digest, subject regv1.Descriptor, format sbom.Format, created time.Time) error {
+	img, err := reg.Image(ref)
+	if err != nil {
+		return err
+	}
+	sbomSubject, err := sbom.NewSubject(ref, img)
+	if err != nil {
+		return fmt.Errorf("Generating the SBOM of '%s': %s", ref.Name(), err)
+	}
+	sbomSubject.Created = created
+	sbomSubject.ToolVersion = Version
+
+	doc, err := sbom.Generate(format, sbomSubject)
+	if err != nil {
+		return fmt.Errorf("Generating the SBOM of '%s': %s", ref.Name(), err)
+	}
+	artifact, err := sbom.NewArtifact(format, doc, subject)
+	if err != nil {
+		return err
+	}
+	digest, err := artifact.Digest()
+	if err != nil {
+		return err
+	}
+
+	var target regname.Reference = ref.Context().Digest(digest.String())
+	if po.SBOMAttachment == sbomAttachmentTag {
+		target = ref.Context().Tag(cosign.MungeWithSuffix(subject, cosign.SBOMTagSuffix))
+	}
+	err = reg.WriteImage(target, artifact, nil)
+	if err != nil {
+		return fmt.Errorf("Attaching the SBOM of '%s': %s", ref.Name(), err)
+	}
+
+	po.ui.PrintLinef("Attached %s SBOM '%s' to '%s'", format, ref.Context().Digest(digest.String()).Name(), ref.Name())
+	return nil
+}
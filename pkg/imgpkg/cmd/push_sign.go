@@ -0,0 +1,103 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// cosignPasswordEnv environment variable with the password of the cosign private key, the same cosign reads
+const cosignPasswordEnv = "COSIGN_PASSWORD"
+
+// sign Signs the digest pushed, of the image, bundle or index, with the cosign key of --sign-key
+func (po *PushOptions) sign(reg registry.Registry, imageURL string) error {
+	ref, err := regname.NewDigest(imageURL)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", imageURL, err)
+	}
+	key, err := signature.LoadCosignSigningKey(po.SignKey, []byte(os.Getenv(cosignPasswordEnv)))
+	if err != nil {
+		return err
+	}
+
+	sigTag, err := signature.NewCosignSigner(reg, key).Sign(ref)
+	if err != nil {
+		return err
+	}
+
+	po.ui.PrintLinef("Signed '%s' with cosign signature '%s'", ref.Name(), sigTag.Name())
+	return nil
+}
+
+// heldTagsRegistry Writes the images and indexes pushed by their digest and holds back their tags, until WriteHeldTags
+// is called once the digest pushed is signed, so that the tags never reference a digest that is not signed yet
+type heldTagsRegistry struct {
+	registry.Registry
+	heldTags []heldTag
+}
+
+// heldTag Tag written by WriteHeldTags
+type heldTag struct {
+	tag      regname.Tag
+	taggable regremote.Taggable
+}
+
+// WriteImage Writes the image by its digest, holding back the tag when ref is one
+func (r *heldTagsRegistry) WriteImage(ref regname.Reference, img regv1.Image, updatesCh chan regv1.Update) error {
+	tag, ok := ref.(regname.Tag)
+	if !ok {
+		return r.Registry.WriteImage(ref, img, updatesCh)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	err = r.Registry.WriteImage(tag.Context().Digest(digest.String()), img, updatesCh)
+	if err != nil {
+		return err
+	}
+	return r.WriteTag(tag, img)
+}
+
+// WriteIndex Writes the index by its digest, holding back the tag when ref is one
+func (r *heldTagsRegistry) WriteIndex(ref regname.Reference, idx regv1.ImageIndex) error {
+	tag, ok := ref.(regname.Tag)
+	if !ok {
+		return r.Registry.WriteIndex(ref, idx)
+	}
+	digest, err := idx.Digest()
+	if err != nil {
+		return err
+	}
+	err = r.Registry.WriteIndex(tag.Context().Digest(digest.String()), idx)
+	if err != nil {
+		return err
+	}
+	return r.WriteTag(tag, idx)
+}
+
+// WriteTag Holds back the tag until WriteHeldTags is called
+func (r *heldTagsRegistry) WriteTag(tag regname.Tag, taggable regremote.Taggable) error {
+	r.heldTags = append(r.heldTags, heldTag{tag: tag, taggable: taggable})
+	return nil
+}
+
+// WriteHeldTags Writes the tags held back, in the order they were requested
+func (r *heldTagsRegistry) WriteHeldTags() error {
+	for _, held := range r.heldTags {
+		err := r.Registry.WriteTag(held.tag, held.taggable)
+		if err != nil {
+			return fmt.Errorf("Writing Tag '%s': %s", held.tag.Name(), err)
+		}
+	}
+	r.heldTags = nil
+	return nil
+}
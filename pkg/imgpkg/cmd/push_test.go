@@ -4,16 +4,33 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/featureflags"
+	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
 	"carvel.dev/imgpkg/test/helpers"
+	"github.com/cppforlife/go-cli-ui/ui"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -238,6 +255,421 @@ func TestImageAndBundleLockError(t *testing.T) {
 	}
 }
 
+func TestPushPlatformDirs(t *testing.T) {
+	t.Run("fails when --platform-dir is provided with --file", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, PlatformDirs: []string{"linux/amd64=./amd64"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --platform-dir cannot be used with --file (-f)")
+	})
+
+	t.Run("fails when --platform-dir does not have a platform", func(t *testing.T) {
+		push := PushOptions{BundleFlags: BundleFlags{Bundle: "foo"}, PlatformDirs: []string{"./amd64"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --platform-dir './amd64' to have the format os/arch[/variant]=path")
+	})
+
+	t.Run("pushes a bundle per platform in an image index", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		amd64Dir := t.TempDir()
+		require.NoError(t, createBundleDir(amd64Dir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(amd64Dir, "arch.yml"), []byte("arch: amd64"), 0600))
+		arm64Dir := t.TempDir()
+		require.NoError(t, createBundleDir(arm64Dir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(arm64Dir, "arch.yml"), []byte("arch: arm64"), 0600))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: bundleRef},
+			LabelFlags:   LabelFlags{Labels: map[string]string{"foo": "bar"}},
+			PlatformDirs: []string{"linux/amd64=" + amd64Dir, "linux/arm64/v8=" + arm64Dir},
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		idx, err := remote.Index(ref)
+		require.NoError(t, err)
+		idxManifest, err := idx.IndexManifest()
+		require.NoError(t, err)
+		assert.Equal(t, types.OCIImageIndex, idxManifest.MediaType)
+		assert.Equal(t, map[string]string{bundle.BundleConfigLabel: "true"}, idxManifest.Annotations)
+		require.Len(t, idxManifest.Manifests, 2)
+		assert.Equal(t, "linux/amd64", idxManifest.Manifests[0].Platform.String())
+		assert.Equal(t, "linux/arm64/v8", idxManifest.Manifests[1].Platform.String())
+
+		for _, manifest := range idxManifest.Manifests {
+			img, err := idx.Image(manifest.Digest)
+			require.NoError(t, err)
+			config, err := img.ConfigFile()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"dev.carvel.imgpkg.bundle": "true", "foo": "bar"}, config.Config.Labels)
+			assert.Equal(t, manifest.Platform.String(), config.Platform().String())
+			assert.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+		}
+	})
+
+	t.Run("pulls the bundle of the platform imgpkg runs on", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleRef := pushMultiArchBundle(t, confUI, fakeRegistry.ReferenceOnTestServer("some/bundle"), "")
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "platform.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "platform: "+bundle.HostPlatform(), string(contents))
+	})
+}
+
+// pushMultiArchBundle Pushes to bundleRef a bundle for the platform imgpkg runs on and for another platform,
+// both with imagesLock as their ImagesLock
+func pushMultiArchBundle(t *testing.T, confUI ui.UI, bundleRef string, imagesLock string) string {
+	var platformDirs []string
+	for _, platform := range []string{bundle.HostPlatform(), "plan9/riscv64"} {
+		dir := t.TempDir()
+		require.NoError(t, createBundleDir(dir, imagesLock))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "platform.yml"), []byte("platform: "+platform), 0600))
+		platformDirs = append(platformDirs, platform+"="+dir)
+	}
+
+	push := PushOptions{
+		ui:           confUI,
+		BundleFlags:  BundleFlags{Bundle: bundleRef},
+		PlatformDirs: platformDirs,
+	}
+	require.NoError(t, push.Run())
+	return bundleRef
+}
+
+func TestPushCompression(t *testing.T) {
+	t.Run("fails when the compression is unknown", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Compression: "lz4"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --compression: Unknown compression 'lz4' (supported: gzip, zstd[:level])")
+	})
+
+	t.Run("pushes a bundle with zstd layers that can be pulled", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			Compression: "zstd:19",
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Layers, 1)
+		assert.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+		assert.Equal(t, types.OCILayerZStd, manifest.Layers[0].MediaType)
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo: bar", string(contents))
+	})
+
+	t.Run("fails when eStargz is used with zstd", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Compression: "zstd", Estargz: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --estargz can only be used with --compression gzip")
+	})
+
+	t.Run("pushes a bundle with eStargz layers that can be pulled", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			Compression: "gzip",
+			Estargz:     true,
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Layers, 1)
+		assert.Equal(t, types.DockerLayer, manifest.Layers[0].MediaType)
+		assert.NotEmpty(t, manifest.Layers[0].Annotations["containerd.io/snapshot/stargz/toc.digest"])
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo: bar", string(contents))
+		assert.NoFileExists(t, filepath.Join(outputDir, "stargz.index.json"), "the table of contents of eStargz should not be extracted")
+		assert.NoFileExists(t, filepath.Join(outputDir, ".no.prefetch.landmark"))
+	})
+}
+
+func TestPushFileAttributes(t *testing.T) {
+	t.Run("fails when the owner is not numeric", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Chown: "root:root"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --chown: Expected owner 'root:root' to be numeric ids with the format uid:gid (example: 0:0)")
+	})
+
+	t.Run("fails when the permission mask is not octal", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, ChmodMask: "go-w"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --chmod-mask: Expected permission mask 'go-w' to be octal between 000 and 777 (example: 022)")
+	})
+
+	t.Run("pushes the same bundle regardless of the owner and the umask of the files", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		var digests []string
+		for i, perm := range []os.FileMode{0644, 0664} {
+			bundleDir := t.TempDir()
+			require.NoError(t, createBundleDir(bundleDir, ""))
+			require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+			require.NoError(t, os.Chmod(filepath.Join(bundleDir, "config.yml"), perm))
+
+			bundleRef := fakeRegistry.ReferenceOnTestServer(fmt.Sprintf("some/bundle%d", i))
+			push := PushOptions{
+				ui:          confUI,
+				BundleFlags: BundleFlags{Bundle: bundleRef},
+				FileFlags:   FileFlags{Files: []string{bundleDir}, PreservePermissions: true},
+				Chown:       "0:0",
+				ChmodMask:   "022",
+			}
+			require.NoError(t, push.Run())
+
+			ref, err := name.NewTag(bundleRef)
+			require.NoError(t, err)
+			img, err := remote.Image(ref)
+			require.NoError(t, err)
+			digest, err := img.Digest()
+			require.NoError(t, err)
+			digests = append(digests, digest.String())
+		}
+		assert.Equal(t, digests[0], digests[1])
+	})
+}
+
+func TestPushCreated(t *testing.T) {
+	t.Run("fails when the creation time is not RFC3339", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Created: "yesterday"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --created: Expected creation time 'yesterday' to be RFC3339")
+	})
+
+	t.Run("pushing the same bundle from different directories results in the same digest", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		var digests []string
+		for _, repo := range []string{"some/bundle", "other/bundle"} {
+			bundleDir := t.TempDir()
+			require.NoError(t, createBundleDir(bundleDir, ""))
+			require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+			bundleRef := fakeRegistry.ReferenceOnTestServer(repo)
+			push := PushOptions{
+				ui:          confUI,
+				BundleFlags: BundleFlags{Bundle: bundleRef},
+				FileFlags:   FileFlags{Files: []string{bundleDir}},
+				Created:     "2024-01-31T10:00:00Z",
+			}
+			require.NoError(t, push.Run())
+
+			ref, err := name.NewTag(bundleRef)
+			require.NoError(t, err)
+			img, err := remote.Image(ref)
+			require.NoError(t, err)
+			cfg, err := img.ConfigFile()
+			require.NoError(t, err)
+			assert.Equal(t, "2024-01-31T10:00:00Z", cfg.Created.Format(time.RFC3339))
+			digest, err := img.Digest()
+			require.NoError(t, err)
+			digests = append(digests, digest.String())
+		}
+		assert.Equal(t, digests[0], digests[1])
+	})
+}
+
+func TestPushAnnotations(t *testing.T) {
+	t.Run("fails when the annotation does not have a value", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Annotations: []string{"version"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --annotation 'version' to have the format key=value")
+	})
+
+	t.Run("fails when --label sets a reserved label", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, LabelFlags: LabelFlags{Label: []string{bundle.BundleNameLabel + "=app"}}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, fmt.Sprintf("label '%s' is reserved and cannot be overriden", bundle.BundleNameLabel))
+	})
+
+	t.Run("pushes a bundle with the annotations in its manifest and the labels in its config", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			LabelFlags: LabelFlags{
+				Labels: map[string]string{"team": "carvel"},
+				Label:  []string{"org.opencontainers.image.licenses=Apache-2.0,MIT"},
+			},
+			Annotations: []string{"org.opencontainers.image.version=1.0.0", "org.opencontainers.image.revision=abc=123"},
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"org.opencontainers.image.version":  "1.0.0",
+			"org.opencontainers.image.revision": "abc=123",
+		}, manifest.Annotations)
+
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		assert.Equal(t, "carvel", cfg.Config.Labels["team"])
+		assert.Equal(t, "Apache-2.0,MIT", cfg.Config.Labels["org.opencontainers.image.licenses"])
+		assert.Equal(t, "true", cfg.Config.Labels[bundle.BundleConfigLabel])
+	})
+}
+
+func TestPushSecretScan(t *testing.T) {
+	// split so that the source code does not look like it contains an access key
+	awsKey := "AKIA" + "IOSFODNN7EXAMPLE"
+
+	setup := func(t *testing.T) (*helpers.FakeTestRegistryBuilder, string, ui.UI) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		t.Cleanup(confUI.Flush)
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		t.Cleanup(fakeRegistry.CleanUp)
+		fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("key: "+awsKey+"\nname: app\n"), 0600))
+		return fakeRegistry, bundleDir, confUI
+	}
+
+	t.Run("fails when the policy is unknown", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, SecretScan: "ignore"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --secret-scan: Unknown secret scan policy 'ignore'")
+	})
+
+	t.Run("with fail policy it reports the secrets and does not push the bundle", func(t *testing.T) {
+		fakeRegistry, bundleDir, confUI := setup(t)
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}, SecretScan: "fail"}
+		err := push.Run()
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Found 1 likely secret(s) in the files pushed, nothing was pushed")
+		assert.ErrorContains(t, err, filepath.Join(bundleDir, "config.yml")+":1: AWS access key")
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		_, err = remote.Image(ref)
+		require.Error(t, err)
+	})
+
+	t.Run("with redact policy it pushes the bundle with the secrets redacted", func(t *testing.T) {
+		fakeRegistry, bundleDir, confUI := setup(t)
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}, SecretScan: "redact"}
+		require.NoError(t, push.Run())
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "key: <redacted-by-imgpkg>\nname: app\n", string(contents))
+
+		original, err := os.ReadFile(filepath.Join(bundleDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(original), awsKey, "the files on disk should not be changed")
+	})
+}
+
 func TestLabels(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -348,3 +780,615 @@ func createEmptyBundleDir(loc string) error {
 	bundleDir := filepath.Join(loc, ".imgpkg")
 	return os.Mkdir(bundleDir, 0700)
 }
+
+func TestPushToLocalDst(t *testing.T) {
+	t.Run("fails when both --to-oci-layout and --to-tar are provided", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, ToOCILayout: "out", ToTar: "out.tar"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --to-oci-layout cannot be used with --to-tar")
+	})
+
+	t.Run("fails when --lock-output is provided", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, ToTar: "out.tar", LockOutputFlags: LockOutputFlags{LockFilePath: "lock.yml"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --lock-output cannot be used with --to-oci-layout or --to-tar")
+	})
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	appImage := fakeRegistry.WithRandomImage("app/image")
+	fakeRegistry.Build()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+`, appImage.RefDigest)))
+
+	// the bundle is never pushed to its registry, that does not exist
+	bundleRef := "registry.invalid/app/bundle:1.0.0"
+
+	t.Run("writes the bundle and the images it references to an OCI image layout", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		layoutDir := filepath.Join(t.TempDir(), "layout")
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}, ToOCILayout: layoutDir}
+		require.NoError(t, push.Run())
+
+		index, err := layout.ImageIndexFromPath(layoutDir)
+		require.NoError(t, err)
+		manifest, err := index.IndexManifest()
+		require.NoError(t, err)
+		var digests []string
+		for _, desc := range manifest.Manifests {
+			digests = append(digests, desc.Digest.String())
+			if desc.Digest.String() != appImage.Digest {
+				assert.Equal(t, "1.0.0", desc.Annotations["org.opencontainers.image.ref.name"])
+			}
+		}
+		assert.Len(t, digests, 2)
+		assert.Contains(t, digests, appImage.Digest, "the image referenced by the bundle should be in the layout")
+
+		// the bundle is read back from the layout with the reference it was pushed as
+		layoutBundleRef, _, err := registry.OCILayoutReference("oci:" + layoutDir)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(layoutBundleRef, "registry.invalid/app/bundle@sha256:"), layoutBundleRef)
+	})
+
+	t.Run("writes the bundle and the images it references to a tar", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}, ToTar: tarPath}
+		require.NoError(t, push.Run())
+
+		images, err := imagetar.NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+		refs := map[string]string{}
+		for _, img := range images {
+			digest, err := img.Digest()
+			require.NoError(t, err)
+			refs[digest.String()] = img.Ref()
+		}
+		assert.Len(t, refs, 2)
+		assert.Contains(t, refs, appImage.Digest, "the image referenced by the bundle should be in the tar")
+		for digest, ref := range refs {
+			if digest != appImage.Digest {
+				assert.Equal(t, "registry.invalid/app/bundle@"+digest, ref)
+			}
+		}
+	})
+}
+
+func TestPushFromStdin(t *testing.T) {
+	t.Run("fails when --file (-f) is '-' more than once", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"-", "-"}}, BundleFlags: BundleFlags{Bundle: "foo"}, stdin: &bytes.Buffer{}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --file (-f) can only be '-' once, stdin can only be read once")
+	})
+
+	t.Run("fails when stdin is not a tar stream with relative paths", func(t *testing.T) {
+		stdin := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(stdin)
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "/etc/config.yml", Typeflag: tar.TypeReg, Mode: 0600}))
+		require.NoError(t, tarWriter.Close())
+
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"-"}}, BundleFlags: BundleFlags{Bundle: "foo"}, stdin: stdin}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Reading the files to push from stdin: Expected tar entry '/etc/config.yml' to be relative to the root of the tar")
+	})
+
+	t.Run("pushes a bundle with the files of the tar stream that can be pulled", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		stdin := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(stdin)
+		for name, contents := range map[string]string{".imgpkg/images.yml": emptyImagesYaml, "config/config.yml": "foo: bar"} {
+			require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(contents))}))
+			_, err := tarWriter.Write([]byte(contents))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tarWriter.Close())
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			stdin:       stdin,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{"-"}},
+		}
+		require.NoError(t, push.Run())
+		assert.Equal(t, []string{"-"}, push.FileFlags.Files, "the files provided should be restored after the push")
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "config", "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo: bar", string(contents))
+	})
+}
+
+func TestPushDryRun(t *testing.T) {
+	t.Run("fails when --to-tar is provided", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, ToTar: "out.tar", DryRun: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --dry-run cannot be used with --to-oci-layout or --to-tar")
+	})
+
+	t.Run("fails when --lock-output is provided", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, LockOutputFlags: LockOutputFlags{LockFilePath: "lock.yml"}, DryRun: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --lock-output cannot be used with --dry-run")
+	})
+
+	t.Run("prints the files and the digests of the bundle that would be pushed, without contacting its registry", func(t *testing.T) {
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("replicas: 3\n"), 0600))
+
+		stdout := &bytes.Buffer{}
+		confUI := ui.NewWrappingConfUI(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()), ui.NewNoopLogger())
+		// the bundle is never pushed to its registry, that does not exist
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: "registry.invalid/app/bundle:1.0.0"}, FileFlags: FileFlags{Files: []string{bundleDir}}, DryRun: true}
+		require.NoError(t, push.Run())
+		confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+		bundleRef := fakeRegistry.ReferenceOnTestServer("app/bundle:1.0.0")
+		push = PushOptions{ui: ui.NewConfUI(ui.NewNoopLogger()), BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		configDigest, err := img.ConfigName()
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		layerDigest, err := layers[0].Digest()
+		require.NoError(t, err)
+
+		output := stdout.String()
+		assert.Contains(t, output, "config.yml")
+		assert.Contains(t, output, ".imgpkg/images.yml")
+		assert.Contains(t, output, layerDigest.String())
+		assert.Contains(t, output, "Config digest: "+configDigest.String())
+		assert.Contains(t, output, fmt.Sprintf("Dry run: 'registry.invalid/app/bundle:1.0.0' would be pushed with manifest digest %s", digest),
+			"the digest previewed should be the one of the bundle pushed")
+	})
+}
+
+func TestPushBudgets(t *testing.T) {
+	t.Run("fails when pushing an image", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, ImageFlags: ImageFlags{Image: "foo"}, BudgetFlags: BudgetFlags{MaxImageCount: 1}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flags --max-bundle-size and --max-image-count can only be used when pushing a bundle")
+	})
+
+	t.Run("fails when the size cannot be parsed", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, BudgetFlags: BudgetFlags{MaxBundleSize: "big"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --max-bundle-size to be a positive size (example: 4GB, 700MiB or 1048576), got 'big'")
+	})
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img1 := fakeRegistry.WithRandomImage("app/image-1")
+	img2 := fakeRegistry.WithRandomImage("app/image-2")
+	fakeRegistry.Build()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+- image: %s
+`, img1.RefDigest, img2.RefDigest)))
+
+	pushWithBudgets := func(repo string, budgets BudgetFlags) error {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer(repo)}, FileFlags: FileFlags{Files: []string{bundleDir}}, BudgetFlags: budgets}
+		return push.Run()
+	}
+	assertNotPushed := func(repo string) {
+		ref, err := name.NewTag(fakeRegistry.ReferenceOnTestServer(repo))
+		require.NoError(t, err)
+		_, err = remote.Head(ref)
+		require.Error(t, err, "the bundle should not be pushed")
+	}
+
+	t.Run("fails before pushing when the bundle references more images than --max-image-count", func(t *testing.T) {
+		err := pushWithBudgets("app/count-bundle", BudgetFlags{MaxImageCount: 1})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected the bundle to reference at most 1 images (--max-image-count), but it references 2")
+		assertNotPushed("app/count-bundle")
+	})
+
+	t.Run("fails before pushing when the bundle and its images are bigger than --max-bundle-size", func(t *testing.T) {
+		err := pushWithBudgets("app/size-bundle", BudgetFlags{MaxBundleSize: "1KB"})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected the bundle and the images it references to be at most 1KB (--max-bundle-size)")
+		assertNotPushed("app/size-bundle")
+	})
+
+	t.Run("pushes the bundle when it is within the budgets", func(t *testing.T) {
+		require.NoError(t, pushWithBudgets("app/bundle", BudgetFlags{MaxBundleSize: "1GB", MaxImageCount: 2}))
+
+		ref, err := name.NewTag(fakeRegistry.ReferenceOnTestServer("app/bundle"))
+		require.NoError(t, err)
+		_, err = remote.Head(ref)
+		require.NoError(t, err)
+	})
+}
+
+func TestPushResolveLockTags(t *testing.T) {
+	t.Run("fails when pushing an image", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, ImageFlags: ImageFlags{Image: "foo"}, ResolveLockTags: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --resolve-lock-tags can only be used when pushing a bundle")
+	})
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	taggedImg := fakeRegistry.WithRandomTaggedImage("app/image-1:1.21", "1.21")
+	digestImg := fakeRegistry.WithRandomImage("app/image-2")
+	fakeRegistry.Build()
+
+	t.Run("pushes the bundle with the tags of the ImagesLock resolved to digests", func(t *testing.T) {
+		taggedRef := fakeRegistry.ReferenceOnTestServer("app/image-1:1.21")
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+- image: %s
+  annotations:
+    some-annotation: some-value
+`, taggedRef, digestImg.RefDigest)))
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		bundleRef := fakeRegistry.ReferenceOnTestServer("app/bundle")
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}, ResolveLockTags: true}
+		require.NoError(t, push.Run())
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 2)
+		assert.Equal(t, taggedImg.RefDigest, imagesLock.Images[0].Image)
+		assert.Equal(t, map[string]string{lockconfig.ImageRefOriginAnnotationKey: taggedRef, lockconfig.ImageRefResolvedTagAnnotationKey: taggedRef}, imagesLock.Images[0].Annotations)
+		assert.Equal(t, digestImg.RefDigest, imagesLock.Images[1].Image)
+		assert.Equal(t, map[string]string{"some-annotation": "some-value"}, imagesLock.Images[1].Annotations)
+
+		contents, err := os.ReadFile(filepath.Join(bundleDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), taggedRef, "the ImagesLock on disk should not be changed")
+	})
+
+	t.Run("fails when a tag of the ImagesLock does not exist", func(t *testing.T) {
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+`, fakeRegistry.ReferenceOnTestServer("app/image-1:does-not-exist"))))
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("app/missing-bundle")}, FileFlags: FileFlags{Files: []string{bundleDir}}, ResolveLockTags: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Resolving tag of image")
+	})
+}
+
+func TestPushSBOM(t *testing.T) {
+	t.Run("fails when the SBOM format is unknown", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, AttachSBOM: "swid"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --attach-sbom: Unknown SBOM format 'swid' (supported: spdx, cyclonedx)")
+	})
+
+	t.Run("fails when nothing is pushed to the registry", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, AttachSBOM: "spdx", DryRun: true}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --attach-sbom cannot be used with --to-oci-layout, --to-tar or --dry-run")
+	})
+
+	pushBundle := func(t *testing.T, fakeRegistry *helpers.FakeTestRegistryBuilder, format, attachment string) name.Digest {
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:             confUI,
+			BundleFlags:    BundleFlags{Bundle: bundleRef},
+			FileFlags:      FileFlags{Files: []string{bundleDir}},
+			AttachSBOM:     format,
+			SBOMAttachment: attachment,
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		desc, err := remote.Head(ref)
+		require.NoError(t, err)
+		return ref.Context().Digest(desc.Digest.String())
+	}
+
+	t.Run("attaches the SBOM to the bundle as an OCI referrer", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDigest := pushBundle(t, fakeRegistry, "cyclonedx", "")
+
+		index, err := remote.Referrers(bundleDigest)
+		require.NoError(t, err)
+		referrers, err := index.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, referrers.Manifests, 1)
+		assert.Equal(t, "application/vnd.cyclonedx+json", referrers.Manifests[0].ArtifactType)
+
+		sbomImg, err := remote.Image(bundleDigest.Context().Digest(referrers.Manifests[0].Digest.String()))
+		require.NoError(t, err)
+		layers, err := sbomImg.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		contents, err := layers[0].Compressed()
+		require.NoError(t, err)
+		defer contents.Close()
+		var doc bytes.Buffer
+		_, err = doc.ReadFrom(contents)
+		require.NoError(t, err)
+		assert.Contains(t, doc.String(), `"name": "config.yml"`)
+	})
+
+	t.Run("attaches the SBOM to the bundle with the .sbom tag of cosign", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDigest := pushBundle(t, fakeRegistry, "spdx", "tag")
+
+		sbomTag := bundleDigest.Context().Tag(strings.ReplaceAll(bundleDigest.DigestStr(), ":", "-") + ".sbom")
+		sbomImg, err := remote.Image(sbomTag)
+		require.NoError(t, err)
+		manifest, err := sbomImg.Manifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Layers, 1)
+		assert.Equal(t, types.MediaType("application/spdx+json"), manifest.Layers[0].MediaType)
+		require.NotNil(t, manifest.Subject)
+		assert.Equal(t, bundleDigest.DigestStr(), manifest.Subject.Digest.String())
+	})
+}
+
+func TestPushSignKey(t *testing.T) {
+	t.Run("fails when nothing is pushed to the registry", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, SignKey: "cosign.key", ToTar: "bundle.tar"}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Flag --sign-key cannot be used with --to-oci-layout, --to-tar or --dry-run")
+	})
+
+	t.Run("signs the digest of the bundle pushed", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		reg := fakeRegistry.Build()
+
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		keyDir := t.TempDir()
+		privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(keyDir, "cosign.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+		publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(keyDir, "cosign.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			SignKey:     filepath.Join(keyDir, "cosign.key"),
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		desc, err := remote.Head(ref)
+		require.NoError(t, err)
+		publicKey, err := signature.LoadCosignPublicKey(filepath.Join(keyDir, "cosign.pub"))
+		require.NoError(t, err)
+		require.NoError(t, signature.NewCosignVerifier(reg, publicKey).Verify(ref.Context().Digest(desc.Digest.String())))
+	})
+
+	t.Run("does not tag the bundle when it cannot be signed", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		keyPath := filepath.Join(t.TempDir(), "cosign.key")
+		require.NoError(t, os.WriteFile(keyPath, []byte("not a key"), 0600))
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			SignKey:     keyPath,
+		}
+		require.ErrorContains(t, push.Run(), "to be PEM encoded")
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		_, err = remote.Head(ref)
+		require.Error(t, err, "the bundle was tagged before it was signed")
+	})
+}
+
+func TestPushOCIMediaTypesFeatureFlag(t *testing.T) {
+	set, err := featureflags.Load(func(name string) (string, bool) {
+		env := map[string]string{featureflags.ConfigEnv: "", featureflags.Env: featureflags.OCIMediaTypes + "=true"}
+		value, found := env[name]
+		return value, found
+	})
+	require.NoError(t, err)
+	featureflags.SetActive(set)
+	defer featureflags.SetActive(featureflags.NewDefaultSet())
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, createBundleDir(bundleDir, ""))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+	bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+	push := PushOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, FileFlags: FileFlags{Files: []string{bundleDir}}}
+	require.NoError(t, push.Run())
+
+	ref, err := name.NewTag(bundleRef)
+	require.NoError(t, err)
+	img, err := remote.Image(ref)
+	require.NoError(t, err)
+	manifest, err := img.Manifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+	assert.Equal(t, types.OCIConfigJSON, manifest.Config.MediaType)
+	assert.Equal(t, types.OCILayer, manifest.Layers[0].MediaType)
+
+	outputDir := filepath.Join(t.TempDir(), "pulled")
+	pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+	require.NoError(t, pull.Run())
+	contents, err := os.ReadFile(filepath.Join(outputDir, "config.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo: bar", string(contents))
+}
+
+func TestPushOCIMediaTypes(t *testing.T) {
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	imageDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(imageDir, "config.yml"), []byte("foo: bar"), 0600))
+
+	imageRef := fakeRegistry.ReferenceOnTestServer("some/image")
+	push := PushOptions{ui: confUI, ImageFlags: ImageFlags{Image: imageRef}, FileFlags: FileFlags{Files: []string{imageDir}}, OCIMediaTypes: true}
+	require.NoError(t, push.Run())
+
+	ref, err := name.NewTag(imageRef)
+	require.NoError(t, err)
+	img, err := remote.Image(ref)
+	require.NoError(t, err)
+	manifest, err := img.Manifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+	assert.Equal(t, types.OCIConfigJSON, manifest.Config.MediaType)
+	assert.Equal(t, types.OCILayer, manifest.Layers[0].MediaType)
+}
+
+func TestPushLayerPerDir(t *testing.T) {
+	t.Run("fails when a layer does not have a name", func(t *testing.T) {
+		push := PushOptions{FileFlags: FileFlags{Files: []string{"config/"}}, BundleFlags: BundleFlags{Bundle: "foo"}, Layers: []string{"charts"}}
+		err := push.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing --layer: Expected layer 'charts' to have the format name=path (example: charts=config/charts)")
+	})
+
+	t.Run("pushes a bundle with a layer per top-level directory that can be pulled", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		fakeRegistry.Build()
+
+		bundleDir := t.TempDir()
+		require.NoError(t, createBundleDir(bundleDir, ""))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+		require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, "charts", "app"), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "charts", "app", "Chart.yaml"), []byte("name: app"), 0600))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		push := PushOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			LayerPerDir: true,
+		}
+		require.NoError(t, push.Run())
+
+		ref, err := name.NewTag(bundleRef)
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Layers, 2)
+		assert.Equal(t, "charts", manifest.Layers[1].Annotations["imgpkg.carvel.dev/layer"])
+
+		outputDir := filepath.Join(t.TempDir(), "pulled")
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputDir, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		contents, err := os.ReadFile(filepath.Join(outputDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo: bar", string(contents))
+		contents, err = os.ReadFile(filepath.Join(outputDir, "charts", "app", "Chart.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "name: app", string(contents))
+	})
+}
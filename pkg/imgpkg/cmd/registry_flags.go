@@ -17,16 +17,27 @@ type RegistryFlags struct {
 	CACertPaths []string
 	VerifyCerts bool
 	Insecure    bool
+	// InsecureRegistries registries, with the format host[:port], that are allowed to use http or unverified certificates
+	InsecureRegistries []string
 
 	Username string
 	Password string
 	Token    string
 	Anon     bool
 
-	RetryCount int
+	RetryCount      int
+	RetryBackoff    time.Duration
+	RetryMaxElapsed time.Duration
 
 	ResponseHeaderTimeout time.Duration
+	StallTimeout          time.Duration
 	ActiveKeychains       string
+
+	FIPS    bool
+	Offline bool
+
+	ProxyHelper     string
+	ProxyAuthHelper string
 }
 
 // Set Registers the flags available to the provided command
@@ -34,6 +45,8 @@ func (r *RegistryFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringSliceVar(&r.CACertPaths, "registry-ca-cert-path", nil, "Add CA certificates for registry API (format: /tmp/foo) (can be specified multiple times)")
 	cmd.Flags().BoolVar(&r.VerifyCerts, "registry-verify-certs", true, "Set whether to verify server's certificate chain and host name")
 	cmd.Flags().BoolVar(&r.Insecure, "registry-insecure", false, "Allow the use of http when interacting with registries")
+	cmd.Flags().MarkDeprecated("registry-insecure", "it allows http for every registry, use '--insecure-registry' for the registries that need it instead")
+	cmd.Flags().StringSliceVar(&r.InsecureRegistries, "insecure-registry", nil, "Allow the use of http, and of certificates that cannot be verified, only when interacting with this registry (format: registry.io, localhost:5000) (can be specified multiple times)")
 
 	cmd.Flags().StringVar(&r.Username, "registry-username", "", "Set username for auth ($IMGPKG_USERNAME)")
 	cmd.Flags().StringVar(&r.Password, "registry-password", "", "Set password for auth ($IMGPKG_PASSWORD)")
@@ -41,7 +54,16 @@ func (r *RegistryFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&r.Anon, "registry-anon", false, "Set anonymous auth ($IMGPKG_ANON)")
 
 	cmd.Flags().DurationVar(&r.ResponseHeaderTimeout, "registry-response-header-timeout", 30*time.Second, "Maximum time to allow a request to wait for a server's response headers from the registry (ms|s|m|h)")
+	cmd.Flags().DurationVar(&r.StallTimeout, "registry-stall-timeout", 0, "Retry a transfer from the registry on a new connection when it does not receive any byte for this time, 0 to wait until the connection fails (ms|s|m|h)")
 	cmd.Flags().IntVar(&r.RetryCount, "registry-retry-count", 5, "Set the number of times imgpkg retries to send requests to the registry in case of an error")
+	cmd.Flags().DurationVar(&r.RetryBackoff, "registry-retry-backoff", registry.DefaultRetryBackoff, "Time to wait before retrying a request to the registry, doubled after each retry up to 10 times this value (ms|s|m|h)")
+	cmd.Flags().DurationVar(&r.RetryMaxElapsed, "registry-retry-max-elapsed", 0, "Maximum time to wait between the retries of a request to the registry, no more retries are done once it is reached, 0 for no limit (ms|s|m|h)")
+
+	cmd.Flags().StringVar(&r.ProxyHelper, "registry-proxy-helper", "", "Command that receives the URL of a registry and prints the proxy to use in proxy auto-config format, e.g. 'PROXY proxy.corp:8080' or 'DIRECT' ($IMGPKG_PROXY_HELPER)")
+	cmd.Flags().StringVar(&r.ProxyAuthHelper, "registry-proxy-auth-helper", "", "Command that receives the URL of the proxy and the registry host:port and prints the Proxy-Authorization header to send, e.g. 'Negotiate <token>' ($IMGPKG_PROXY_AUTH_HELPER)")
+
+	cmd.Flags().BoolVar(&r.FIPS, "fips", false, "Only use FIPS-approved algorithms for TLS and digest verification, and fail on registries or artifacts that do not comply ($IMGPKG_FIPS)")
+	cmd.Flags().BoolVar(&r.Offline, "offline", false, "Fail on any request that would reach the network, only tar files and OCI image layouts can be used ($IMGPKG_OFFLINE)")
 }
 
 // AsRegistryOpts convert command flags and environment variables into registry.Opts
@@ -51,16 +73,31 @@ func (r *RegistryFlags) AsRegistryOpts() registry.Opts {
 		VerifyCerts: r.VerifyCerts,
 		Insecure:    r.Insecure,
 
+		InsecureRegistries: r.InsecureRegistries,
+
 		Username: r.Username,
 		Password: r.Password,
 		Token:    r.Token,
 		Anon:     r.Anon,
 
 		RetryCount:            r.RetryCount,
+		RetryBackoff:          r.RetryBackoff,
+		RetryMaxElapsed:       r.RetryMaxElapsed,
 		ResponseHeaderTimeout: r.ResponseHeaderTimeout,
+		StallTimeout:          r.StallTimeout,
+
+		FIPS:    r.FIPS,
+		Offline: r.Offline,
 
 		EnvironFunc: os.Environ,
 	}
 
+	if r.ProxyHelper != "" {
+		opts.ProxySelector = registry.NewCommandProxySelector(r.ProxyHelper)
+	}
+	if r.ProxyAuthHelper != "" {
+		opts.ProxyAuthenticator = registry.NewCommandProxyAuthenticator(r.ProxyAuthHelper)
+	}
+
 	return v1.OptsFromEnv(opts, os.LookupEnv)
 }
@@ -0,0 +1,96 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// relocationValues Data values describing where each copied image can be found after the copy
+type relocationValues struct {
+	Bundle string           `json:"bundle,omitempty"`
+	Images []relocatedImage `json:"images"`
+}
+
+type relocatedImage struct {
+	Original  string `json:"original"`
+	Relocated string `json:"relocated"`
+}
+
+const packageRepositoryOverlayTemplate = `#@ load("@ytt:overlay", "overlay")
+
+#@overlay/match by=overlay.subset({"apiVersion": "packaging.carvel.dev/v1alpha1", "kind": "PackageRepository"}), expects="1+"
+---
+spec:
+  fetch:
+    #@overlay/match missing_ok=True
+    imgpkgBundle:
+      image: %s
+`
+
+// relocationOutput Generates the relocation output, in the requested format, for the images that were copied
+func relocationOutput(format string, processedImages *ctlimgset.ProcessedImages) ([]byte, error) {
+	values := relocationValues{Images: []relocatedImage{}}
+	var rootBundles []string
+	for _, img := range processedImages.All() {
+		original := img.UnprocessedImageRef.OrigRef
+		if original == "" {
+			original = img.UnprocessedImageRef.DigestRef
+		}
+		values.Images = append(values.Images, relocatedImage{Original: original, Relocated: img.DigestRef})
+
+		if v1.IsRootBundle(img) {
+			rootBundles = append(rootBundles, img.DigestRef)
+		}
+	}
+	// When multiple bundles are copied together their locations are only listed in the images
+	if len(rootBundles) == 1 {
+		values.Bundle = rootBundles[0]
+	}
+	sort.Slice(values.Images, func(i, j int) bool {
+		return values.Images[i].Original < values.Images[j].Original
+	})
+
+	switch format {
+	case RelocationValuesFormat:
+		bs, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("Marshaling relocation values: %s", err)
+		}
+		return append([]byte("#@data/values\n---\n"), bs...), nil
+
+	case RelocationPackageRepositoryFormat:
+		if values.Bundle == "" {
+			return nil, fmt.Errorf("Expected a single bundle to be copied to generate the PackageRepository overlay, found %d (hint: copy the package repository bundle with -b)", len(rootBundles))
+		}
+		return []byte(fmt.Sprintf(packageRepositoryOverlayTemplate, values.Bundle)), nil
+
+	default:
+		return nil, fmt.Errorf("Unknown relocation output format '%s'", format)
+	}
+}
+
+func (c *CopyOptions) writeRelocationOutput(processedImages *ctlimgset.ProcessedImages) error {
+	if c.RelocationOutputFlags.Path == "" {
+		return nil
+	}
+
+	bs, err := relocationOutput(c.RelocationOutputFlags.Format, processedImages)
+	if err != nil {
+		return err
+	}
+
+	err = atomicfile.WriteFile(c.RelocationOutputFlags.Path, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing relocation output: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// RelocationValuesFormat ytt data values file with the relocated location of the bundle and of each image
+	RelocationValuesFormat = "values"
+	// RelocationPackageRepositoryFormat ytt overlay that points kapp-controller PackageRepositories to the relocated bundle
+	RelocationPackageRepositoryFormat = "package-repository"
+)
+
+// RelocationOutputFlags Flags used to output where the copied bundle and images were relocated to
+type RelocationOutputFlags struct {
+	Path   string
+	Format string
+}
+
+// Set Registers the flags in the command
+func (r *RelocationOutputFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&r.Path, "relocation-output", "",
+		"Location to output a file describing where the bundle and images were relocated to. Option only available when using --to-repo")
+	cmd.Flags().StringVar(&r.Format, "relocation-output-format", RelocationValuesFormat,
+		fmt.Sprintf("Format of the relocation output (%s: ytt data values, %s: ytt overlay for kapp-controller PackageRepository)",
+			RelocationValuesFormat, RelocationPackageRepositoryFormat))
+}
+
+// Validate Checks that the format is supported
+func (r RelocationOutputFlags) Validate() error {
+	switch r.Format {
+	case RelocationValuesFormat, RelocationPackageRepositoryFormat:
+		return nil
+	default:
+		return fmt.Errorf("Unknown relocation output format '%s' (supported: %s, %s)",
+			r.Format, RelocationValuesFormat, RelocationPackageRepositoryFormat)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocationOutput(t *testing.T) {
+	img, err := random.Image(100, 1)
+	require.NoError(t, err)
+
+	processedImages := ctlimgset.NewProcessedImages()
+	processedImages.Add(ctlimgset.ProcessedImage{
+		UnprocessedImageRef: ctlimgset.UnprocessedImageRef{
+			DigestRef: "source.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			Labels:    map[string]string{"dev.carvel.imgpkg.copy.root-bundle": ""},
+		},
+		DigestRef: "dest.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		Image:     img,
+	})
+	processedImages.Add(ctlimgset.ProcessedImage{
+		UnprocessedImageRef: ctlimgset.UnprocessedImageRef{
+			DigestRef: "source.io/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			OrigRef:   "other.io/app@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		},
+		DigestRef: "dest.io/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		Image:     img,
+	})
+
+	t.Run("when the format is values, it writes the relocated bundle and images as ytt data values", func(t *testing.T) {
+		bs, err := relocationOutput(RelocationValuesFormat, processedImages)
+		require.NoError(t, err)
+		assert.Equal(t, `#@data/values
+---
+bundle: dest.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111
+images:
+- original: other.io/app@sha256:2222222222222222222222222222222222222222222222222222222222222222
+  relocated: dest.io/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222
+- original: source.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111
+  relocated: dest.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111
+`, string(bs))
+	})
+
+	t.Run("when the format is package-repository, it writes an overlay pointing to the relocated bundle", func(t *testing.T) {
+		bs, err := relocationOutput(RelocationPackageRepositoryFormat, processedImages)
+		require.NoError(t, err)
+		assert.Contains(t, string(bs), `kind": "PackageRepository"`)
+		assert.Contains(t, string(bs), "image: dest.io/repo@sha256:1111111111111111111111111111111111111111111111111111111111111111\n")
+	})
+
+	t.Run("when the format is package-repository and no bundle was copied, it errors", func(t *testing.T) {
+		onlyImages := ctlimgset.NewProcessedImages()
+		onlyImages.Add(ctlimgset.ProcessedImage{
+			UnprocessedImageRef: ctlimgset.UnprocessedImageRef{DigestRef: "source.io/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222"},
+			DigestRef:           "dest.io/repo@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			Image:               img,
+		})
+		_, err := relocationOutput(RelocationPackageRepositoryFormat, onlyImages)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected a single bundle to be copied")
+	})
+}
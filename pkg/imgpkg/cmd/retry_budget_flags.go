@@ -0,0 +1,50 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// RetryBudgetFlags Flags used to stop retrying the requests to a registry host that keeps failing
+type RetryBudgetFlags struct {
+	MaxRetries      int
+	MaxFailureRatio float64
+	FailFast        bool
+}
+
+// Set Registers the flags in the command
+func (r *RetryBudgetFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&r.MaxRetries, "retry-budget", 0,
+		"Stop when this number of requests to a registry host failed and were retried in total, 0 means unlimited")
+	cmd.Flags().Float64Var(&r.MaxFailureRatio, "retry-budget-failure-ratio", 0,
+		"Stop when more than this ratio of the requests to a registry host failed, checked once 20 requests were sent, 0 means unlimited (example: 0.5)")
+	cmd.Flags().BoolVar(&r.FailFast, "fail-fast", false,
+		"Stop on the first request to a registry host that fails, without retrying it (useful in CI)")
+}
+
+// Validate Checks that the budget is not negative and the ratio is between 0 and 1
+func (r *RetryBudgetFlags) Validate() error {
+	if r.MaxRetries < 0 {
+		return fmt.Errorf("Expected --retry-budget to be 0 or greater, got %d", r.MaxRetries)
+	}
+	if r.MaxFailureRatio < 0 || r.MaxFailureRatio > 1 {
+		return fmt.Errorf("Expected --retry-budget-failure-ratio to be between 0 and 1, got %g", r.MaxFailureRatio)
+	}
+	return nil
+}
+
+// ApplyTo Limits the retries of the requests sent by the registry, with --fail-fast the failed requests are not retried
+func (r *RetryBudgetFlags) ApplyTo(opts *registry.Opts) {
+	if r.MaxRetries == 0 && r.MaxFailureRatio == 0 && !r.FailFast {
+		return
+	}
+	opts.RetryBudget = registry.NewRetryBudget(r.MaxRetries, r.MaxFailureRatio, r.FailFast)
+	if r.FailFast {
+		opts.RetryCount = 1
+	}
+}
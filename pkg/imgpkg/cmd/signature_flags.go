@@ -3,12 +3,87 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/spf13/cobra"
+)
 
 type SignatureFlags struct {
-	CopyCosignSignatures bool
+	CopyCosignSignatures   bool
+	CopyNotationSignatures bool
+	IncludeReferrers       bool
+	// Backends signing schemes whose artifacts are copied with the images, see signature.NewFetcherForBackends
+	Backends []string
+	// Annotations added to the relocated signatures, attestations and SBOMs
+	Annotations map[string]string
+	// RequireCosignSignature when set, the images whose cosign signature cannot be verified with CosignPublicKey abort the copy
+	RequireCosignSignature bool
+	CosignPublicKey        string
 }
 
 func (s *SignatureFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().BoolVar(&s.CopyCosignSignatures, "cosign-signatures", false, "Find and copy cosign signatures for images")
+	cmd.Flags().BoolVar(&s.CopyCosignSignatures, "cosign-signatures", false, "Find and copy cosign signatures, attestations and SBOMs for images (same as --signatures cosign)")
+	cmd.Flags().BoolVar(&s.CopyNotationSignatures, "notation-signatures", false, "Find, using the OCI Referrers API, and copy Notation signatures for images (same as --signatures notation)")
+	cmd.Flags().BoolVar(&s.IncludeReferrers, "include-referrers", false, "Find, using the OCI Referrers API, and copy every artifact that refers to the images, "+
+		"and the artifacts that refer to them, keeping their subject (same as --signatures referrers)")
+	cmd.Flags().StringSliceVar(&s.Backends, "signatures", nil,
+		fmt.Sprintf("Find and copy the signatures, attestations and SBOMs of the images created with these schemes (one of: %s, tag-suffix:<suffix>) "+
+			"(can be specified multiple times)", strings.Join(signature.BackendNames(), ", ")))
+	cmd.Flags().StringToStringVar(&s.Annotations, "signature-annotation", map[string]string{},
+		"Set annotations on the signatures, attestations and SBOMs copied to the repository or registry, changing their digests (format: key=value) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&s.RequireCosignSignature, "require-cosign-signature", false,
+		"Verify the cosign signature of every image and bundle before copying anything, and fail if any of them is not signed with the key provided with --cosign-public-key")
+	cmd.Flags().StringVar(&s.CosignPublicKey, "cosign-public-key", "", "Path to the PEM encoded public key, as written by cosign generate-key-pair, used with --require-cosign-signature")
+}
+
+// Validate Checks that the signature backends are known
+func (s *SignatureFlags) Validate() error {
+	if s.RequireCosignSignature && s.CosignPublicKey == "" {
+		return fmt.Errorf("Expected --cosign-public-key to be provided with --require-cosign-signature")
+	}
+	if s.CosignPublicKey != "" && !s.RequireCosignSignature {
+		return fmt.Errorf("Flag --cosign-public-key can only be used with --require-cosign-signature")
+	}
+	return signature.CheckBackends(s.Backends)
+}
+
+// IsSet Returns true when signatures are copied with the images
+func (s *SignatureFlags) IsSet() bool {
+	return len(s.BackendNames()) > 0
+}
+
+// BackendNames Names of the signature backends selected, including the ones selected with the cosign and notation flags
+func (s *SignatureFlags) BackendNames() []string {
+	var names []string
+	if s.CopyCosignSignatures {
+		names = append(names, signature.CosignBackend)
+	}
+	if s.CopyNotationSignatures {
+		names = append(names, signature.NotationBackend)
+	}
+	if s.IncludeReferrers {
+		names = append(names, signature.ReferrersBackend)
+	}
+	return append(names, s.Backends...)
+}
+
+// Fetcher Constructs the Signature Fetcher that retrieves the artifacts of all the selected backends
+func (s *SignatureFlags) Fetcher(reg signature.Registry, concurrency int) (signature.Fetcher, error) {
+	return signature.NewFetcherForBackends(s.BackendNames(), reg, concurrency)
+}
+
+// Verifier Constructs the verifier of the cosign signatures of the images, nil when signatures are not required
+func (s *SignatureFlags) Verifier(reg signature.ImageReader) (v1.SignatureVerifier, error) {
+	if !s.RequireCosignSignature {
+		return nil, nil
+	}
+	publicKey, err := signature.LoadCosignPublicKey(s.CosignPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return signature.NewCosignVerifier(reg, publicKey), nil
 }
@@ -0,0 +1,101 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/runstate"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+// SupportBundleOptions Options for the support-bundle command
+type SupportBundleOptions struct {
+	ui ui.UI
+
+	RegistryFlags   RegistryFlags
+	Output          string
+	ProbeRegistries []string
+	RunStateDir     string
+}
+
+// NewSupportBundleOptions Builder for SupportBundleOptions
+func NewSupportBundleOptions(ui ui.UI) *SupportBundleOptions {
+	return &SupportBundleOptions{ui: ui, RunStateDir: runstate.DefaultDir()}
+}
+
+// NewSupportBundleCmd Creates the support-bundle command
+func NewSupportBundleCmd(o *SupportBundleOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Gather the report and logs of the last run, the environment and the reachability of registries to attach to bug reports",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Gather the information about a copy that failed, checking that both registries can be reached
+  imgpkg copy -b registry.io/org/app-bundle --to-repo internal-registry.io/org/app-bundle --record-run
+  imgpkg support-bundle --probe-registry registry.io --probe-registry internal-registry.io -o support-bundle.tar.gz
+
+  # Include the requests sent to the registries by running the failing command with --debug
+  imgpkg copy -b registry.io/org/app-bundle --to-repo internal-registry.io/org/app-bundle --record-run --debug
+  imgpkg support-bundle`,
+	}
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "imgpkg-support-bundle.tar.gz", "Path of the archive to write")
+	cmd.Flags().StringSliceVar(&o.ProbeRegistries, "probe-registry", nil,
+		"Registry to check, with an anonymous request, that can be reached from this machine (format: registry.io) (can be specified multiple times)")
+	return cmd
+}
+
+// Run Executes the support-bundle command
+func (o *SupportBundleOptions) Run() error {
+	registryOpts := o.RegistryFlags.AsRegistryOpts()
+
+	var registries []regname.Registry
+	for _, probeRegistry := range o.ProbeRegistries {
+		var refOpts []regname.Option
+		if registryOpts.IsInsecure(probeRegistry) {
+			refOpts = append(refOpts, regname.Insecure)
+		}
+		reg, err := regname.NewRegistry(probeRegistry, refOpts...)
+		if err != nil {
+			return fmt.Errorf("Parsing registry '%s': %s", probeRegistry, err)
+		}
+		registries = append(registries, reg)
+	}
+
+	var probes []registry.ProbeReport
+	if len(registries) > 0 {
+		var err error
+		probes, err = registry.ProbeRegistries(registryOpts, registries)
+		if err != nil {
+			return err
+		}
+	}
+
+	supportBundle := runstate.SupportBundle{
+		RunStateDir:    o.RunStateDir,
+		Environment:    runstate.NewEnvironment(Version, os.Environ()),
+		RegistryProbes: probes,
+	}
+	files, err := supportBundle.Write(o.Output)
+	if err != nil {
+		return err
+	}
+
+	if _, err := runstate.ReadReport(o.RunStateDir); err != nil {
+		o.ui.PrintLinef("No report of a previous run was found, only the environment is included (hint: run the failing command again with --record-run)")
+	}
+	for _, probe := range probes {
+		if probe.Error != "" {
+			o.ui.PrintLinef("Registry %s cannot be reached: %s", probe.Registry, probe.Error)
+		}
+	}
+	o.ui.PrintLinef("Support bundle written to %s (%s)", o.Output, strings.Join(files, ", "))
+	return nil
+}
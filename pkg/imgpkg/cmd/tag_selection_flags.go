@@ -0,0 +1,28 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// TagSelectionFlags Flags used to mirror the tags of a repository
+type TagSelectionFlags struct {
+	OnlyNewTags    bool
+	TagPatterns    []string
+	AdditionalTags bool
+	AllTags        bool
+}
+
+// Set Registers the flags in the command
+func (t *TagSelectionFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&t.OnlyNewTags, "only-new-tags", false,
+		"Copy the tags of the image repository (-i) that are missing, or point to a different digest, in the destination repository")
+	cmd.Flags().StringSliceVar(&t.TagPatterns, "tag-pattern", nil,
+		"Only copy tags that match this pattern, used with --only-new-tags (format: glob, example: v1.*) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&t.AdditionalTags, "additional-tags", false,
+		"Also create in the destination the other tags of the image repository (-i) that point to the same digest")
+	cmd.Flags().BoolVar(&t.AllTags, "all-tags", false,
+		"Also copy every other tag of the image repository (-i) and the images they point to")
+}
@@ -0,0 +1,16 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewTarCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tar",
+		Short: "Tar",
+	}
+	return cmd
+}
@@ -4,20 +4,92 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
+// sizeUnits Suffixes accepted by the flags with sizes, like --to-tar-split-size, longest first so that GiB is not read as B
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
 type TarFlags struct {
-	TarSrc string
-	TarDst string
-	Resume bool
+	TarSrc    string
+	TarDst    string
+	Resume    bool
+	SplitSize string
+	Checksums bool
+	Chunking  bool
 }
 
 func (t *TarFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&t.TarDst, "to-tar", "", "Location to write a tar file containing assets")
 	cmd.Flags().StringVar(&t.TarSrc, "tar", "", "Path to tar file which contains assets to be copied to a registry")
-	cmd.Flags().BoolVar(&t.Resume, "resume", false, "Resume the copy to tar. When set to true will try to read the tar and only download the missing blobs")
+	cmd.Flags().BoolVar(&t.Resume, "resume", false, "Resume an interrupted copy. When set to true will reuse the layers and images recorded in the copy journal, or present in the tar, and only copy the missing ones. Fails when the copy options differ from the ones of the interrupted copy")
+	cmd.Flags().StringVar(&t.SplitSize, "to-tar-split-size", "",
+		"Split the tar written with --to-tar into parts of at most this size, named <tar>.000, <tar>.001, ... "+
+			"The parts are read back together when --tar is the tar or its first part (example: 4GB, 700MiB)")
+	cmd.Flags().BoolVar(&t.Checksums, "to-tar-checksums", false,
+		"Write next to the tar written with --to-tar the SHA256 of the tar, or of each of its parts, in <tar>.sha256 and of each blob in it in <tar>.blobs.sha256, "+
+			"so that the tar can be verified with 'sha256sum -c <tar>.sha256' after transporting it")
+	cmd.Flags().BoolVar(&t.Chunking, "to-tar-chunking", false,
+		"Store the layers in the tar written with --to-tar as content-defined chunks, each chunk once, so that identical layers, and layers whose compressed content "+
+			"starts the same, share the chunks. The chunks are cut from the compressed layers, so layers only share the chunks before their first difference "+
+			"(layers are written one at a time)")
 }
 
 func (t TarFlags) IsSrc() bool { return t.TarSrc != "" }
 func (t TarFlags) IsDst() bool { return t.TarDst != "" }
+
+// Validate Checks that the split size, checksums and chunking can only be used when writing a tar and that the split size can be parsed
+func (t TarFlags) Validate() error {
+	if t.Checksums && !t.IsDst() {
+		return fmt.Errorf("Flag --to-tar-checksums can only be used when copying to a tar (--to-tar)")
+	}
+	if t.Chunking && !t.IsDst() {
+		return fmt.Errorf("Flag --to-tar-chunking can only be used when copying to a tar (--to-tar)")
+	}
+	if t.SplitSize == "" {
+		return nil
+	}
+	if !t.IsDst() {
+		return fmt.Errorf("Flag --to-tar-split-size can only be used when copying to a tar (--to-tar)")
+	}
+	_, err := t.SplitSizeBytes()
+	return err
+}
+
+// SplitSizeBytes Returns the size of the parts of the tar in bytes, 0 when the tar is not split
+func (t TarFlags) SplitSizeBytes() (int64, error) {
+	if t.SplitSize == "" {
+		return 0, nil
+	}
+	return parseSize("--to-tar-split-size", t.SplitSize)
+}
+
+// parseSize Parses the size provided to flag in bytes, with or without a unit (example: 4GB, 700MiB or 1048576)
+func parseSize(flag, sizeStr string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(sizeStr))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size*float64(multiplier) < 1 {
+		return 0, fmt.Errorf("Expected %s to be a positive size (example: 4GB, 700MiB or 1048576), got '%s'", flag, sizeStr)
+	}
+	return int64(size * float64(multiplier)), nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarFlagsSplitSizeBytes(t *testing.T) {
+	tests := []struct {
+		splitSize string
+		expected  int64
+	}{
+		{splitSize: "", expected: 0},
+		{splitSize: "1048576", expected: 1048576},
+		{splitSize: "4GB", expected: 4000000000},
+		{splitSize: "700MiB", expected: 700 * 1024 * 1024},
+		{splitSize: "1.5kb", expected: 1500},
+		{splitSize: "512 B", expected: 512},
+	}
+	for _, test := range tests {
+		t.Run(test.splitSize, func(t *testing.T) {
+			size, err := TarFlags{TarDst: "bundle.tar", SplitSize: test.splitSize}.SplitSizeBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, size)
+		})
+	}
+
+	for _, invalid := range []string{"GB", "-1GB", "four", "0"} {
+		t.Run("when the size is "+invalid+", it errors", func(t *testing.T) {
+			err := TarFlags{TarDst: "bundle.tar", SplitSize: invalid}.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "Expected --to-tar-split-size to be a positive size")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"github.com/cppforlife/go-cli-ui/ui"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/spf13/cobra"
+)
+
+type TarVerifyOptions struct {
+	ui ui.UI
+
+	ProgressFlags ProgressFlags
+
+	Deep bool
+}
+
+func NewTarVerifyOptions(ui ui.UI) *TarVerifyOptions {
+	return &TarVerifyOptions{ui: ui}
+}
+
+func NewTarVerifyCmd(o *TarVerifyOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify PATH",
+		Short: "Verify that a tar created by imgpkg copy --to-tar is complete and not corrupted",
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(_ *cobra.Command, args []string) error { return o.Run(args[0]) },
+		Example: `
+    # Check that the internal manifest of the tar is valid and that all layers are present
+    imgpkg tar verify bundle.tar
+
+    # Also verify the digest of every layer, reading the full tar
+    imgpkg tar verify --deep bundle.tar`,
+	}
+	o.ProgressFlags.Set(cmd)
+	cmd.Flags().BoolVar(&o.Deep, "deep", false, "Verify the digest of every layer in the tar")
+	return cmd
+}
+
+func (t *TarVerifyOptions) Run(path string) error {
+	if err := t.ProgressFlags.Validate(); err != nil {
+		return err
+	}
+
+	prefixedLogger := util.NewPrefixedLogger("tar verify | ", util.NewLogger(t.ui))
+	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
+	progressBar := t.ProgressFlags.ProgressLogger(t.ui, levelLogger, "tar verify | ", "done verifying tar", "Error verifying tar")
+
+	updatesCh := make(chan regv1.Update, 1)
+	progressBar.Start(context.Background(), updatesCh)
+	result, err := imagetar.NewTarVerifier(path).Verify(t.Deep, updatesCh)
+	progressBar.End()
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Marshaling verification result: %s", err)
+	}
+	t.ui.PrintBlock(append(output, '\n'))
+
+	if !result.Valid {
+		return fmt.Errorf("Tar '%s' is not valid: found %d problem(s)", path, len(result.Problems))
+	}
+	return nil
+}
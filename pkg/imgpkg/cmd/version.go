@@ -5,19 +5,28 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/featureflags"
 	"github.com/cppforlife/go-cli-ui/ui"
 	"github.com/spf13/cobra"
 )
 
 var Version = "develop"
 
+// versionTemplate Template of imgpkg --version, with --verbose the feature flags are listed after the version
+const versionTemplate = `{{printf "imgpkg version %s" .Version}}
+{{verboseVersion .}}`
+
 type VersionOptions struct {
 	ui ui.UI
+
+	// Verbose lists the state of the feature flags after the version
+	Verbose bool
 }
 
 func NewVersionOptions(ui ui.UI) *VersionOptions {
-	return &VersionOptions{ui}
+	return &VersionOptions{ui: ui}
 }
 
 func NewVersionCmd(o *VersionOptions) *cobra.Command {
@@ -26,11 +35,29 @@ func NewVersionCmd(o *VersionOptions) *cobra.Command {
 		Short: "Print client version",
 		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
 	}
+	cmd.Flags().BoolVar(&o.Verbose, "verbose", false, "Also list the feature flags, whether they are enabled and where that comes from")
 	return cmd
 }
 
 func (o *VersionOptions) Run() error {
 	o.ui.PrintBlock([]byte(fmt.Sprintf("imgpkg version %s\n", Version)))
+	if o.Verbose {
+		o.ui.PrintBlock([]byte(featureFlagsText(featureflags.Active())))
+	}
 
 	return nil
 }
+
+// verboseVersion Returns the feature flags listed by imgpkg --version --verbose, the command does not run with
+// --version so the feature flags are loaded here
+func verboseVersion(cmd *cobra.Command) string {
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil || !verbose {
+		return ""
+	}
+	set, err := featureflags.Load(os.LookupEnv)
+	if err != nil {
+		return fmt.Sprintf("Feature flags: %s\n", err)
+	}
+	return featureFlagsText(set)
+}
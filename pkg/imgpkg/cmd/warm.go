@@ -0,0 +1,108 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+)
+
+// WarmNodesTypes Ways of reaching the nodes of the cluster to pre-pull the images
+var WarmNodesTypes = []string{"daemonset"}
+
+// WarmOptions Options for the warm command
+type WarmOptions struct {
+	ui ui.UI
+
+	BundleFlags    BundleFlags
+	LockInputFlags LockInputFlags
+	RegistryFlags  RegistryFlags
+
+	Nodes            string
+	Name             string
+	Namespace        string
+	HelperImage      string
+	ImagePullSecrets []string
+	Concurrency      int
+}
+
+// NewWarmOptions Builder for WarmOptions
+func NewWarmOptions(ui ui.UI) *WarmOptions {
+	return &WarmOptions{ui: ui}
+}
+
+// NewWarmCmd Creates the warm command
+func NewWarmCmd(o *WarmOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "warm",
+		Short: "Pre-pull the images of a bundle onto the nodes of a cluster",
+		Long: "Print the manifest of a DaemonSet that pre-pulls the images of a bundle, or of an ImagesLock, onto every node " +
+			"of a cluster, so that the first deployment after a relocation does not wait for the images to be pulled. " +
+			"The pods of the DaemonSet are ready once all the images are pulled on their node, the DaemonSet can then be deleted",
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Pre-pull the images of the relocated bundle described by the lock file written by copy --lock-output
+    imgpkg warm --lock bundle.lock.yml > warm.yml
+    kubectl apply -f warm.yml
+    kubectl rollout status daemonset/imgpkg-warm
+    kubectl delete -f warm.yml
+
+    # Pre-pull the images of a bundle, using a busybox image relocated to the registry of the cluster
+    imgpkg warm -b registry.corp/org/app-bundle:1.0.0 --helper-image registry.corp/library/busybox:1.36`,
+	}
+	o.BundleFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.LockInputFlags.LockFilePath, "lock", "", "BundleLock or ImagesLock with the images to pre-pull")
+	o.RegistryFlags.Set(cmd)
+
+	cmd.Flags().StringVar(&o.Nodes, "nodes", WarmNodesTypes[0], fmt.Sprintf("How to reach the nodes of the cluster (one of: %s)", strings.Join(WarmNodesTypes, ", ")))
+	cmd.Flags().StringVar(&o.Name, "name", "imgpkg-warm", "Name of the DaemonSet")
+	cmd.Flags().StringVar(&o.Namespace, "namespace", "default", "Namespace of the DaemonSet")
+	cmd.Flags().StringVar(&o.HelperImage, "helper-image", "busybox", "Image with a statically linked busybox, used to pull the images without running their entrypoint")
+	cmd.Flags().StringSliceVar(&o.ImagePullSecrets, "image-pull-secret", nil, "Secret used by the nodes to pull the images (can be specified multiple times)")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the warm command
+func (o *WarmOptions) Run() error {
+	if o.BundleFlags.Bundle == "" && o.LockInputFlags.LockFilePath == "" {
+		return fmt.Errorf("Expected either --lock or --bundle (-b)")
+	}
+	if o.BundleFlags.Bundle != "" && o.LockInputFlags.LockFilePath != "" {
+		return fmt.Errorf("Expected only one of --lock or --bundle (-b)")
+	}
+	if o.Nodes != "daemonset" {
+		return fmt.Errorf("Flag --nodes can only have the following values [%s]", strings.Join(WarmNodesTypes, ", "))
+	}
+
+	reg, err := registry.NewSimpleRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	levelLogger := util.NewUILevelLogger(util.LogWarn, util.NewPrefixedLogger("warm | ", util.NewLogger(o.ui)))
+	origin := v1.WarmOrigin{BundleRef: o.BundleFlags.Bundle, LockfilePath: o.LockInputFlags.LockFilePath}
+	images, err := v1.WarmImages(origin, v1.WarmOpts{Logger: levelLogger, Concurrency: o.Concurrency}, reg)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := v1.WarmDaemonSet(images, v1.WarmDaemonSetOpts{
+		Name:             o.Name,
+		Namespace:        o.Namespace,
+		HelperImage:      o.HelperImage,
+		ImagePullSecrets: o.ImagePullSecrets,
+	})
+	if err != nil {
+		return err
+	}
+	o.ui.PrintBlock(manifest)
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+// WhoamiOptions Options for the whoami command
+type WhoamiOptions struct {
+	ui ui.UI
+
+	Images         []string
+	LockInputFlags LockInputFlags
+	RepoDst        string
+	RegistryFlags  RegistryFlags
+}
+
+// NewWhoamiOptions Builder for WhoamiOptions
+func NewWhoamiOptions(ui ui.UI) *WhoamiOptions {
+	return &WhoamiOptions{ui: ui}
+}
+
+// NewWhoamiCmd Creates the whoami command
+func NewWhoamiCmd(o *WhoamiOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Report which credentials are used for each registry and which scopes they are granted",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Check credentials used to access an image
+  imgpkg whoami -i registry.io/org/app
+
+  # Check credentials for every image in a lock file and for the copy destination
+  imgpkg whoami --lock images.lock.yml --to-repo other-registry.io/org/app`,
+	}
+	cmd.Flags().StringSliceVarP(&o.Images, "image", "i", nil, "Image reference to check credentials for (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.LockInputFlags.LockFilePath, "lock", "", "Lock file with image references to check credentials for")
+	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Destination repository to check credentials for")
+	o.RegistryFlags.Set(cmd)
+	return cmd
+}
+
+// Run Executes the whoami command
+func (o *WhoamiOptions) Run() error {
+	refs, err := o.references()
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return fmt.Errorf("Expected at least one of --image (-i), --lock or --to-repo")
+	}
+
+	registryOpts := o.RegistryFlags.AsRegistryOpts()
+
+	var repos []regname.Repository
+	seen := map[string]struct{}{}
+	for _, ref := range refs {
+		repo, err := o.parseRepository(ref, registryOpts)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[repo.Name()]; ok {
+			continue
+		}
+		seen[repo.Name()] = struct{}{}
+		repos = append(repos, repo)
+	}
+
+	reports, err := registry.NewCredentialsReports(registryOpts, repos)
+	if err != nil {
+		return err
+	}
+
+	table := uitable.Table{
+		Title:   "Credentials",
+		Content: "repositories",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Registry"),
+			uitable.NewHeader("Repository"),
+			uitable.NewHeader("Credentials source"),
+			uitable.NewHeader("Pull"),
+			uitable.NewHeader("Push"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 0, Asc: true},
+			{Column: 1, Asc: true},
+		},
+	}
+
+	for _, report := range reports {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(report.Registry),
+			uitable.NewValueString(report.Repository),
+			uitable.NewValueString(string(report.Source)),
+			uitable.NewValueString(o.scopeAccessString(report.Pull)),
+			uitable.NewValueString(o.scopeAccessString(report.Push)),
+		})
+	}
+
+	o.ui.PrintTable(table)
+
+	return nil
+}
+
+func (o *WhoamiOptions) references() ([]string, error) {
+	refs := append([]string{}, o.Images...)
+
+	if o.LockInputFlags.LockFilePath != "" {
+		bundleLock, imagesLock, err := lockconfig.NewLockFromPath(o.LockInputFlags.LockFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if bundleLock != nil {
+			refs = append(refs, bundleLock.Bundle.Image)
+		}
+		if imagesLock != nil {
+			for _, img := range imagesLock.Images {
+				refs = append(refs, img.Image)
+			}
+		}
+	}
+
+	if o.RepoDst != "" {
+		refs = append(refs, o.RepoDst)
+	}
+
+	return refs, nil
+}
+
+func (o *WhoamiOptions) parseRepository(ref string, registryOpts registry.Opts) (regname.Repository, error) {
+	repo, err := o.parseRepositoryWithOpts(ref)
+	if err != nil {
+		return regname.Repository{}, err
+	}
+	if registryOpts.IsInsecure(repo.RegistryStr()) {
+		return o.parseRepositoryWithOpts(ref, regname.Insecure)
+	}
+	return repo, nil
+}
+
+func (o *WhoamiOptions) parseRepositoryWithOpts(ref string, refOpts ...regname.Option) (regname.Repository, error) {
+	parsedRef, err := regname.ParseReference(ref, refOpts...)
+	if err == nil {
+		return parsedRef.Context(), nil
+	}
+
+	repo, repoErr := regname.NewRepository(ref, refOpts...)
+	if repoErr != nil {
+		return regname.Repository{}, fmt.Errorf("Parsing reference '%s': %s", ref, err)
+	}
+	return repo, nil
+}
+
+func (o *WhoamiOptions) scopeAccessString(access registry.ScopeAccess) string {
+	if access.Granted {
+		return "granted"
+	}
+	return fmt.Sprintf("denied (%s)", access.Reason)
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package featureflags Gates the larger behavior changes of imgpkg, so that they can be opted into before they become
+// the default, and opted out of for a while after, per environment
+package featureflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Env environment variable with the feature flags enabled or disabled, it takes precedence over the config file
+	// (format: name=true|false separated by commas, example: oci-media-types=true)
+	Env = "IMGPKG_FEATURE_FLAGS"
+	// ConfigEnv environment variable with the path of the config file, instead of DefaultConfigPath
+	ConfigEnv = "IMGPKG_FEATURE_FLAGS_CONFIG"
+
+	ConfigKind       = "FeatureFlags"
+	ConfigAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+)
+
+// Names of the feature flags
+const (
+	// OCIMediaTypes pushes images and bundles with the OCI media types instead of the Docker ones
+	OCIMediaTypes = "oci-media-types"
+)
+
+// Stage Maturity of the behavior a feature flag gates
+type Stage string
+
+const (
+	// StageAlpha the behavior is disabled by default and might change
+	StageAlpha Stage = "alpha"
+	// StageBeta the behavior is disabled by default and is expected to become the default
+	StageBeta Stage = "beta"
+	// StageDeprecated the behavior is enabled by default, and disabling it will stop being possible in a future release
+	StageDeprecated Stage = "deprecated"
+)
+
+// Source Where the state of a feature flag comes from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceConfig  Source = "config"
+	SourceEnv     Source = "env"
+)
+
+// Flag Behavior change that can be enabled or disabled
+type Flag struct {
+	Name        string
+	Description string
+	Stage       Stage
+	Default     bool
+}
+
+// Flags Feature flags known by this version of imgpkg
+var Flags = []Flag{
+	{
+		Name:        OCIMediaTypes,
+		Description: "Push images and bundles with the OCI media types instead of the Docker ones",
+		Stage:       StageAlpha,
+		Default:     false,
+	},
+}
+
+// State Whether a feature flag is enabled and where that comes from
+type State struct {
+	Flag
+	Enabled bool
+	Source  Source
+}
+
+// Set State of all the known feature flags
+type Set struct {
+	states []State
+	// ConfigPath config file read, empty when there was none
+	ConfigPath string
+}
+
+// Config Contents of the config file of the feature flags
+type Config struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Flags      map[string]bool `json:"flags,omitempty"`
+}
+
+var (
+	active      = NewDefaultSet()
+	activeMutex sync.RWMutex
+)
+
+// NewDefaultSet Returns the feature flags with their default state
+func NewDefaultSet() Set {
+	var states []State
+	for _, flag := range Flags {
+		states = append(states, State{Flag: flag, Enabled: flag.Default, Source: SourceDefault})
+	}
+	return Set{states: states}
+}
+
+// DefaultConfigPath Config file read when ConfigEnv is not set, empty when there is no config folder for the user
+func DefaultConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "imgpkg", "feature-flags.yml")
+}
+
+// Load Returns the feature flags of the config file, of ConfigEnv or DefaultConfigPath, overridden by the ones of Env.
+// The default config file is optional, the one of ConfigEnv is not
+func Load(readEnv func(string) (string, bool)) (Set, error) {
+	set := NewDefaultSet()
+
+	configPath, found := readEnv(ConfigEnv)
+	if !found || configPath == "" {
+		configPath = DefaultConfigPath()
+		if configPath != "" {
+			if _, err := os.Stat(configPath); err != nil {
+				configPath = ""
+			}
+		}
+	}
+	if configPath != "" {
+		config, err := readConfig(configPath)
+		if err != nil {
+			return Set{}, err
+		}
+		for name, enabled := range config.Flags {
+			err = set.set(name, enabled, SourceConfig)
+			if err != nil {
+				return Set{}, fmt.Errorf("Reading feature flags config '%s': %s", configPath, err)
+			}
+		}
+		set.ConfigPath = configPath
+	}
+
+	value, _ := readEnv(Env)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, enabledStr, _ := strings.Cut(pair, "=")
+		enabled, err := strconv.ParseBool(enabledStr)
+		if err != nil {
+			return Set{}, fmt.Errorf("Parsing %s: expected '%s' to have the format name=true|false", Env, pair)
+		}
+		err = set.set(strings.TrimSpace(name), enabled, SourceEnv)
+		if err != nil {
+			return Set{}, fmt.Errorf("Parsing %s: %s", Env, err)
+		}
+	}
+
+	return set, nil
+}
+
+func readConfig(path string) (Config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("Reading feature flags config: %s", err)
+	}
+	var config Config
+	err = yaml.UnmarshalStrict(bs, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("Unmarshaling feature flags config '%s': %s", path, err)
+	}
+	if config.APIVersion != ConfigAPIVersion || config.Kind != ConfigKind {
+		return Config{}, fmt.Errorf("Expected feature flags config '%s' to have apiVersion '%s' and kind '%s'", path, ConfigAPIVersion, ConfigKind)
+	}
+	return config, nil
+}
+
+func (s Set) set(name string, enabled bool, source Source) error {
+	for i := range s.states {
+		if s.states[i].Name == name {
+			s.states[i].Enabled = enabled
+			s.states[i].Source = source
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown feature flag '%s' (known: %s)", name, strings.Join(names(), ", "))
+}
+
+// Enabled Returns true when the feature flag name is enabled
+func (s Set) Enabled(name string) bool {
+	for _, state := range s.states {
+		if state.Name == name {
+			return state.Enabled
+		}
+	}
+	return false
+}
+
+// States Returns the state of the feature flags sorted by name
+func (s Set) States() []State {
+	states := append([]State{}, s.states...)
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Warnings Returns a warning for each deprecated behavior that was disabled, since disabling it will stop being possible
+func (s Set) Warnings() []string {
+	var warnings []string
+	for _, state := range s.States() {
+		if state.Stage == StageDeprecated && !state.Enabled {
+			warnings = append(warnings, fmt.Sprintf("Feature flag '%s' is deprecated, disabling it will not be possible in a future release", state.Name))
+		}
+	}
+	return warnings
+}
+
+// SetActive Sets the feature flags that apply to the command being run
+func SetActive(set Set) {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	active = set
+}
+
+// Active Returns the feature flags that apply to the command being run
+func Active() Set {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+	return active
+}
+
+// Enabled Returns true when the feature flag name is enabled for the command being run
+func Enabled(name string) bool {
+	return Active().Enabled(name)
+}
+
+func names() []string {
+	var result []string
+	for _, flag := range Flags {
+		result = append(result, flag.Name)
+	}
+	sort.Strings(result)
+	return result
+}
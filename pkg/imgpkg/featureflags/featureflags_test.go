@@ -0,0 +1,75 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package featureflags_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/featureflags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	envWith := func(env map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			value, found := env[name]
+			return value, found
+		}
+	}
+	writeConfig := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "feature-flags.yml")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+
+	t.Run("when nothing is set, the flags have their default state", func(t *testing.T) {
+		set, err := featureflags.Load(envWith(map[string]string{featureflags.ConfigEnv: ""}))
+		require.NoError(t, err)
+		assert.False(t, set.Enabled(featureflags.OCIMediaTypes))
+		assert.Equal(t, featureflags.SourceDefault, set.States()[0].Source)
+	})
+
+	t.Run("when the config file enables a flag, it is enabled", func(t *testing.T) {
+		configPath := writeConfig(t, "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: FeatureFlags\nflags:\n  oci-media-types: true\n")
+		set, err := featureflags.Load(envWith(map[string]string{featureflags.ConfigEnv: configPath}))
+		require.NoError(t, err)
+		assert.True(t, set.Enabled(featureflags.OCIMediaTypes))
+		assert.Equal(t, featureflags.SourceConfig, set.States()[0].Source)
+		assert.Equal(t, configPath, set.ConfigPath)
+	})
+
+	t.Run("when the environment variable disables a flag of the config file, it is disabled", func(t *testing.T) {
+		configPath := writeConfig(t, "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: FeatureFlags\nflags:\n  oci-media-types: true\n")
+		set, err := featureflags.Load(envWith(map[string]string{featureflags.ConfigEnv: configPath, featureflags.Env: " oci-media-types=false "}))
+		require.NoError(t, err)
+		assert.False(t, set.Enabled(featureflags.OCIMediaTypes))
+		assert.Equal(t, featureflags.SourceEnv, set.States()[0].Source)
+	})
+
+	t.Run("when a flag is unknown, it fails", func(t *testing.T) {
+		_, err := featureflags.Load(envWith(map[string]string{featureflags.Env: "new-tag-scheme=true"}))
+		require.EqualError(t, err, "Parsing IMGPKG_FEATURE_FLAGS: Unknown feature flag 'new-tag-scheme' (known: oci-media-types)")
+	})
+
+	t.Run("when a flag is not set to a boolean, it fails", func(t *testing.T) {
+		_, err := featureflags.Load(envWith(map[string]string{featureflags.Env: "oci-media-types"}))
+		require.EqualError(t, err, "Parsing IMGPKG_FEATURE_FLAGS: expected 'oci-media-types' to have the format name=true|false")
+	})
+
+	t.Run("when the config file provided does not exist, it fails", func(t *testing.T) {
+		_, err := featureflags.Load(envWith(map[string]string{featureflags.ConfigEnv: filepath.Join(t.TempDir(), "missing.yml")}))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Reading feature flags config")
+	})
+
+	t.Run("when the config file has another kind, it fails", func(t *testing.T) {
+		configPath := writeConfig(t, "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: ImagesLock\n")
+		_, err := featureflags.Load(envWith(map[string]string{featureflags.ConfigEnv: configPath}))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "to have apiVersion 'imgpkg.carvel.dev/v1alpha1' and kind 'FeatureFlags'")
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// CreatedSourceEpoch value of ParseCreated that reads the time from SourceDateEpochEnv
+	CreatedSourceEpoch = "source-epoch"
+	// SourceDateEpochEnv environment variable with the seconds since the Unix epoch, see https://reproducible-builds.org/specs/source-date-epoch/
+	SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+)
+
+// ParseCreated Parses the creation time of the images built from files, with the format RFC3339 (example: 2024-01-31T10:00:00Z)
+// or source-epoch, which reads it from the SOURCE_DATE_EPOCH environment variable
+func ParseCreated(value string) (time.Time, error) {
+	if value != CreatedSourceEpoch {
+		created, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Expected creation time '%s' to be RFC3339 (example: 2024-01-31T10:00:00Z) or %s", value, CreatedSourceEpoch)
+		}
+		return created.UTC(), nil
+	}
+
+	epoch, found := os.LookupEnv(SourceDateEpochEnv)
+	if !found || epoch == "" {
+		return time.Time{}, fmt.Errorf("Expected environment variable %s to be set when using %s", SourceDateEpochEnv, CreatedSourceEpoch)
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, fmt.Errorf("Expected environment variable %s '%s' to be the number of seconds since the Unix epoch", SourceDateEpochEnv, epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
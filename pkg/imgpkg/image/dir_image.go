@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/containerd/stargz-snapshotter/estargz"
+	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
@@ -25,12 +28,41 @@ type DirImage struct {
 	img         regv1.Image
 	shouldChown bool
 	logger      Logger
+
+	paths        []string
+	matchedPaths map[string]bool
+	blobs        BlobRangeReader
+	blobsRepo    regname.Repository
+
+	// layerPartOffsets offset in each file split across layers of its next part in the layer being extracted
+	layerPartOffsets map[string]int64
+	// splitFiles files split across layers that were extracted, true once their first part was written
+	splitFiles map[string]bool
 }
 
 // NewDirImage given an OCI Image representation creates a struct that will allow that image to be
 // extracted into the provided directory
 func NewDirImage(dirPath string, img regv1.Image, logger Logger) *DirImage {
-	return &DirImage{dirPath, img, os.Getuid() == 0, logger}
+	return &DirImage{dirPath: dirPath, img: img, shouldChown: os.Getuid() == 0, logger: logger}
+}
+
+// ParseImagePath Parses a path of a file or directory of an image, relative to its root (example: config/charts)
+func ParseImagePath(value string) (string, error) {
+	cleanPath := path.Clean(strings.TrimPrefix(strings.ReplaceAll(value, "\\", "/"), "./"))
+	if value == "" || path.IsAbs(cleanPath) || cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+		return "", fmt.Errorf("Expected path '%s' to be relative to the root of the image, and inside it", value)
+	}
+	return cleanPath, nil
+}
+
+// WithPaths Extracts only the files and directories of paths, parsed with ParseImagePath. When blobs is provided, the
+// layers compressed with zstd:chunked are read from the repository blobsRepo using their table of contents, so that
+// only the contents of the selected files are downloaded
+func (i *DirImage) WithPaths(paths []string, blobs BlobRangeReader, blobsRepo regname.Repository) *DirImage {
+	i.paths = paths
+	i.blobs = blobs
+	i.blobsRepo = blobsRepo
+	return i
 }
 
 // AsDirectory extracts the OCI image to the provided location in disk
@@ -50,7 +82,14 @@ func (i *DirImage) AsDirectory() error {
 		return err
 	}
 
+	manifest, err := i.img.Manifest()
+	if err != nil {
+		return err
+	}
+
 	fileMap := map[string]bool{}
+	i.matchedPaths = map[string]bool{}
+	i.splitFiles = map[string]bool{}
 
 	// we iterate through the layers in reverse order because it makes handling
 	// whiteout layers more efficient, since we can just keep track of the removed
@@ -64,6 +103,25 @@ func (i *DirImage) AsDirectory() error {
 
 		i.logger.Logf("Extracting layer '%s' (%d/%d)\n", digest, len(layers)-idx, len(layers))
 
+		var layerAnnotations map[string]string
+		if idx < len(manifest.Layers) {
+			layerAnnotations = manifest.Layers[idx].Annotations
+		}
+		i.layerPartOffsets, err = layerPartOffsets(layerAnnotations)
+		if err != nil {
+			return err
+		}
+
+		if len(i.paths) > 0 && i.blobs != nil && idx < len(manifest.Layers) {
+			extracted, err := i.writeZstdChunkedLayer(fileMap, manifest.Layers[idx])
+			if err != nil {
+				return err
+			}
+			if extracted {
+				continue
+			}
+		}
+
 		layerStream, err := imgLayer.Uncompressed()
 		if err != nil {
 			return err
@@ -77,6 +135,18 @@ func (i *DirImage) AsDirectory() error {
 		}
 	}
 
+	for file, firstPartWritten := range i.splitFiles {
+		if !firstPartWritten {
+			return fmt.Errorf("Expected the layers to have the first part of file '%s'", file)
+		}
+	}
+
+	for _, imagePath := range i.paths {
+		if !i.matchedPaths[imagePath] {
+			return fmt.Errorf("Expected path '%s' to be in the image", imagePath)
+		}
+	}
+
 	return nil
 }
 
@@ -94,48 +164,148 @@ func (i *DirImage) writeLayer(fileMap map[string]bool, stream io.Reader) error {
 			return err
 		}
 
-		path := i.hydrateFilepath(hdr.Name)
-		base := filepath.Base(path)
+		if isEstargzEntry(hdr.Name) || !i.selected(hdr.Name) {
+			continue
+		}
 
-		const (
-			whiteoutPrefix = ".wh."
-		)
+		err = i.writeEntry(fileMap, hdr, tarReader)
+		if err != nil {
+			return err
+		}
+	}
 
-		if strings.HasPrefix(base, whiteoutPrefix) {
-			dir := filepath.Dir(path)
+	return nil
+}
 
-			err := os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
-			if err != nil {
-				return nil
-			}
-			fileMap[base] = true
+// writeZstdChunkedLayer Extracts the selected files of the layer when it is compressed with zstd:chunked, reading
+// only their contents. It returns false, so that the whole layer is extracted, when the table of contents is not available
+func (i *DirImage) writeZstdChunkedLayer(fileMap map[string]bool, desc regv1.Descriptor) (bool, error) {
+	position, found := desc.Annotations[ZstdChunkedManifestPositionAnnotation]
+	if !found {
+		return false, nil
+	}
+
+	layerRef := i.blobsRepo.Digest(desc.Digest.String())
+	toc, err := readZstdChunkedTOC(i.blobs, layerRef, position, desc.Annotations[ZstdChunkedManifestChecksumAnnotation])
+	if err != nil {
+		i.logger.Logf("Unable to read the zstd:chunked table of contents, downloading the whole layer: %s\n", err)
+		return false, nil
+	}
+
+	for idx := 0; idx < len(toc.Entries); idx++ {
+		entry := toc.Entries[idx]
+		if entry.Type == zstdChunkedTypeChunk {
 			continue
 		}
+		chunks := []zstdChunkedEntry{entry}
+		for idx+1 < len(toc.Entries) && toc.Entries[idx+1].Type == zstdChunkedTypeChunk {
+			idx++
+			chunks = append(chunks, toc.Entries[idx])
+		}
 
-		// check for a whited out parent directory
-		if inWhiteoutDir(fileMap, path) {
+		if isEstargzEntry(entry.Name) || !i.selected(entry.Name) {
 			continue
 		}
 
-		if fi, err := os.Lstat(path); err == nil {
-			if fi.IsDir() && hdr.Name == "." {
-				continue
-			}
-			if !(fi.IsDir() && hdr.Typeflag == tar.TypeDir) {
-				if err := os.RemoveAll(path); err != nil {
-					return err
-				}
+		hdr, err := entry.tarHeader()
+		if err != nil {
+			return false, err
+		}
+
+		var contents io.Reader = strings.NewReader("")
+		if hdr.Typeflag == tar.TypeReg && hdr.Size > 0 {
+			fileReader, err := newZstdChunkedFileReader(i.blobs, layerRef, chunks)
+			if err != nil {
+				return false, err
 			}
+			defer fileReader.Close()
+			contents = fileReader
 		}
 
-		fileMap[hdr.Name] = true
-		err = i.extractTarEntry(hdr, tarReader)
+		err = i.writeEntry(fileMap, hdr, contents)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
 
-	return nil
+	return true, nil
+}
+
+// selected Returns true when the file or directory name of the layer is in the paths to extract, and records the
+// path it matched
+func (i *DirImage) selected(name string) bool {
+	if len(i.paths) == 0 {
+		return true
+	}
+
+	entryPath := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	dir, base := path.Split(entryPath)
+	// whiteout files remove the file they are named after
+	entryPath = dir + strings.TrimPrefix(base, ".wh.")
+
+	for _, imagePath := range i.paths {
+		if entryPath == imagePath || strings.HasPrefix(entryPath, imagePath+"/") {
+			i.matchedPaths[imagePath] = true
+			return true
+		}
+	}
+	return false
+}
+
+// writeEntry Extracts the file or directory of the tar header, with the contents of input, unless a layer above it removed it
+func (i *DirImage) writeEntry(fileMap map[string]bool, hdr *tar.Header, input io.Reader) error {
+	path := i.hydrateFilepath(hdr.Name)
+	base := filepath.Base(path)
+
+	const (
+		whiteoutPrefix = ".wh."
+	)
+
+	if strings.HasPrefix(base, whiteoutPrefix) {
+		dir := filepath.Dir(path)
+
+		err := os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		if err != nil {
+			return nil
+		}
+		fileMap[base] = true
+		return nil
+	}
+
+	// check for a whited out parent directory
+	if inWhiteoutDir(fileMap, path) {
+		return nil
+	}
+
+	_, isPart, err := LayerFilePart(hdr)
+	if err != nil {
+		return err
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.IsDir() && hdr.Name == "." {
+			return nil
+		}
+		// the other parts of a file split across layers were already written to it
+		alreadyWrittenPart := isPart && fileMap[hdr.Name] && fi.Mode().IsRegular()
+		if !(fi.IsDir() && hdr.Typeflag == tar.TypeDir) && !alreadyWrittenPart {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	fileMap[hdr.Name] = true
+	return i.extractTarEntry(hdr, input)
+}
+
+// isEstargzEntry Returns true for the table of contents and the landmarks that eStargz layers add next to the files
+func isEstargzEntry(name string) bool {
+	switch name {
+	case estargz.TOCTarName, estargz.PrefetchLandmark, estargz.NoPrefetchLandmark:
+		return true
+	}
+	return false
 }
 
 func inWhiteoutDir(fileMap map[string]bool, file string) bool {
@@ -181,17 +351,46 @@ func (i *DirImage) extractTarEntry(header *tar.Header, input io.Reader) error {
 	case tar.TypeDir:
 		return nil
 
-	case tar.TypeReg, tar.TypeRegA:
-		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, permMode)
+	case tar.TypeReg, tar.TypeRegA, LayerPartTypeflag:
+		position, isPart, err := LayerFilePart(header)
 		if err != nil {
 			return err
 		}
+		flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+		offset, found := i.layerPartOffsets[header.Name]
+		if isPart {
+			if !found {
+				return fmt.Errorf("Expected annotation %s of the layer to have the offset of file '%s'", LayerPartOffsetsAnnotation, header.Name)
+			}
+			// the layers are extracted in reverse order, the file is truncated by the part extracted first
+			if _, started := i.splitFiles[header.Name]; started {
+				flags = os.O_RDWR | os.O_CREATE
+			}
+			i.splitFiles[header.Name] = i.splitFiles[header.Name] || position == LayerPartFirst
+		}
+
+		file, err := os.OpenFile(path, flags, permMode)
+		if err != nil {
+			return err
+		}
+
+		if isPart {
+			// each part of a file split across layers continues the previous part in the layer
+			_, err = file.Seek(offset, io.SeekStart)
+			if err != nil {
+				_ = file.Close()
+				return err
+			}
+		}
 
-		_, err = io.Copy(file, input)
+		written, err := io.Copy(file, input)
 		if err != nil {
 			_ = file.Close()
 			return err
 		}
+		if isPart {
+			i.layerPartOffsets[header.Name] = offset + written
+		}
 
 		err = file.Close()
 		if err != nil {
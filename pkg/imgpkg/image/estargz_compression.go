@@ -0,0 +1,92 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/containerd/stargz-snapshotter/estargz"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// estargzCompression gzip compression of eStargz layers that writes the footer itself.
+// The footer of estargz relies on compress/gzip writing exactly 51 bytes with NoCompression,
+// which recent versions of Go no longer do
+type estargzCompression struct {
+	*estargz.GzipCompressor
+	*estargz.GzipDecompressor
+}
+
+func newEstargzCompression() estargzCompression {
+	return estargzCompression{
+		GzipCompressor:   estargz.NewGzipCompressorWithLevel(gzip.BestCompression),
+		GzipDecompressor: &estargz.GzipDecompressor{},
+	}
+}
+
+// WriteTOCAndFooter Writes the table of contents, as the estargz gzip compressor does, followed by the footer
+func (c estargzCompression) WriteTOCAndFooter(w io.Writer, off int64, toc *estargz.JTOC, diffHash hash.Hash) (digest.Digest, error) {
+	tocJSON, err := json.MarshalIndent(toc, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	gw := io.Writer(gz)
+	if diffHash != nil {
+		gw = io.MultiWriter(gz, diffHash)
+	}
+	tw := tar.NewWriter(gw)
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     estargz.TOCTarName,
+		Size:     int64(len(tocJSON)),
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = tw.Write(tocJSON)
+	if err != nil {
+		return "", err
+	}
+	err = tw.Close()
+	if err != nil {
+		return "", err
+	}
+	err = gz.Close()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = w.Write(estargzFooter(off))
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(tocJSON), nil
+}
+
+// estargzFooter Returns the 51 bytes of the empty gzip stream that records, in its extra field,
+// the offset of the table of contents (https://github.com/containerd/stargz-snapshotter/blob/main/docs/estargz.md#footer)
+func estargzFooter(tocOffset int64) []byte {
+	subfield := fmt.Sprintf("%016xSTARGZ", tocOffset)
+	footer := make([]byte, 0, estargz.FooterSize)
+	// gzip header with the FEXTRA flag, no modification time and an unknown OS
+	footer = append(footer, 0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff)
+	footer = binary.LittleEndian.AppendUint16(footer, uint16(4+len(subfield)))
+	footer = append(footer, 'S', 'G')
+	footer = binary.LittleEndian.AppendUint16(footer, uint16(len(subfield)))
+	footer = append(footer, subfield...)
+	// final empty stored block, followed by the CRC-32 and the size of the empty contents
+	footer = append(footer, 1, 0, 0, 0xff, 0xff)
+	footer = append(footer, 0, 0, 0, 0, 0, 0, 0, 0)
+	return footer
+}
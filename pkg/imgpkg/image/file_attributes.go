@@ -0,0 +1,52 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileAttributes Owner and permissions recorded for the files and directories of the images built from files,
+// so that the layers do not depend on the user that created the files. The zero value records the owner 0:0
+type FileAttributes struct {
+	UID int
+	GID int
+	// ChmodMask permissions cleared from the files and directories
+	ChmodMask os.FileMode
+}
+
+// ParseChown Parses the owner recorded for the files, with the format uid:gid (example: 1000:1000)
+func ParseChown(value string) (uid, gid int, err error) {
+	uidStr, gidStr, found := strings.Cut(value, ":")
+	if found {
+		uid, err = strconv.Atoi(uidStr)
+		if err == nil {
+			gid, err = strconv.Atoi(gidStr)
+		}
+	}
+	if !found || err != nil || uid < 0 || gid < 0 {
+		return 0, 0, fmt.Errorf("Expected owner '%s' to be numeric ids with the format uid:gid (example: 0:0)", value)
+	}
+	return uid, gid, nil
+}
+
+// ParseChmodMask Parses the permissions cleared from the files, in octal (example: 022)
+func ParseChmodMask(value string) (os.FileMode, error) {
+	mask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mask > 0777 {
+		return 0, fmt.Errorf("Expected permission mask '%s' to be octal between 000 and 777 (example: 022)", value)
+	}
+	return os.FileMode(mask), nil
+}
+
+// apply Records the owner and clears the permissions masked in the header of a file or directory
+func (a FileAttributes) apply(header *tar.Header) {
+	header.Uid = a.UID
+	header.Gid = a.GID
+	header.Mode &^= int64(a.ChmodMask)
+}
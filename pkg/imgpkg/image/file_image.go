@@ -6,10 +6,13 @@ package image
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
+	"github.com/containerd/stargz-snapshotter/estargz"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
@@ -19,57 +22,200 @@ import (
 
 type FileImage struct {
 	v1.Image
+	paths []string
+	// compressedPaths when the layers were compressed ahead of time, files with the compressed layers
+	compressedPaths []string
+}
+
+// layerFile Tarball with the files of a layer, named when the files of the image are split into several layers
+type layerFile struct {
+	name string
 	path string
+	// partOffsets offset in each file split across layers of its first part in the tarball
+	partOffsets map[string]int64
 }
 
 func NewFileImage(path string, labels map[string]string) (*FileImage, error) {
-	sha256, err := sha256Path(path)
-	if err != nil {
-		return nil, err
-	}
+	return NewFileImageWithCompression(path, labels, LayerCompression{})
+}
 
-	layer, err := partial.UncompressedToLayer(&UncompressedFileLayer{
-		diffID:    v1.Hash{Algorithm: "sha256", Hex: sha256},
-		mediaType: types.DockerLayer,
-		path:      path,
-	})
-	if err != nil {
-		return nil, err
-	}
+// NewFileImageWithCompression Creates an image whose single layer is the tarball in path, compressed with layerCompression.
+// zstd layers are compressed once, next to path, and the image uses the OCI media types
+func NewFileImageWithCompression(path string, labels map[string]string, layerCompression LayerCompression) (*FileImage, error) {
+	return newFileImage([]layerFile{{path: path}}, labels, layerCompression, time.Time{})
+}
+
+// newFileImage Creates an image with a layer per tarball in layerFiles, recording created as its creation time
+func newFileImage(layerFiles []layerFile, labels map[string]string, layerCompression LayerCompression, created time.Time) (*FileImage, error) {
+	fileImg := &FileImage{}
+	var adds []mutate.Addendum
+	for _, layerFile := range layerFiles {
+		fileImg.paths = append(fileImg.paths, layerFile.path)
 
-	add := mutate.Addendum{
-		Layer: layer,
-		History: v1.History{
-			Author:    "imgpkg",
-			CreatedBy: "imgpkg",
-			Created:   v1.Time{}, // static
-		},
+		layer, layerAnnotations, compressedPath, err := newFileLayer(layerFile.path, layerCompression)
+		if compressedPath != "" {
+			fileImg.compressedPaths = append(fileImg.compressedPaths, compressedPath)
+		}
+		if err != nil {
+			fileImg.removeCompressed()
+			return nil, err
+		}
+		if layerFile.name != "" {
+			if layerAnnotations == nil {
+				layerAnnotations = map[string]string{}
+			}
+			layerAnnotations[LayerNameAnnotation] = layerFile.name
+		}
+		if len(layerFile.partOffsets) > 0 {
+			partOffsets, err := json.Marshal(layerFile.partOffsets)
+			if err != nil {
+				fileImg.removeCompressed()
+				return nil, err
+			}
+			if layerAnnotations == nil {
+				layerAnnotations = map[string]string{}
+			}
+			layerAnnotations[LayerPartOffsetsAnnotation] = string(partOffsets)
+		}
+
+		adds = append(adds, mutate.Addendum{
+			Layer:       layer,
+			Annotations: layerAnnotations,
+			History: v1.History{
+				Author:    "imgpkg",
+				CreatedBy: "imgpkg",
+				Created:   v1.Time{Time: created}, // static unless provided
+			},
+		})
 	}
 
-	img, err := mutate.Append(empty.Image, add)
+	img, err := mutate.Append(empty.Image, adds...)
 	if err != nil {
+		fileImg.removeCompressed()
 		return nil, err
 	}
 
-	if len(labels) > 0 {
+	if len(labels) > 0 || !created.IsZero() {
 		cfg, err := img.ConfigFile()
 		if err != nil {
+			fileImg.removeCompressed()
 			return nil, fmt.Errorf("Fetching image config: %s", err)
 		}
 
-		cfg.Config.Labels = labels
+		if len(labels) > 0 {
+			cfg.Config.Labels = labels
+		}
+		cfg.Created = v1.Time{Time: created}
 
 		img, err = mutate.ConfigFile(img, cfg)
 		if err != nil {
+			fileImg.removeCompressed()
 			return nil, err
 		}
 	}
 
-	return &FileImage{img, path}, nil
+	if layerCompression.UsesOCIMediaTypes() {
+		img = mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), types.OCIConfigJSON)
+	}
+
+	fileImg.Image = img
+	return fileImg, nil
+}
+
+// newFileLayer Returns the layer with the tarball in path, compressed with layerCompression, and the file with the
+// compressed tarball when it was compressed ahead of time
+func newFileLayer(path string, layerCompression LayerCompression) (v1.Layer, map[string]string, string, error) {
+	switch {
+	case layerCompression.IsZstd():
+		compressedPath := path + ".zst"
+		sha256, err := sha256Path(path)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		layer, err := zstdFileLayer(path, compressedPath, v1.Hash{Algorithm: "sha256", Hex: sha256}, layerCompression)
+		return layer, nil, compressedPath, err
+
+	case layerCompression.IsEstargz():
+		compressedPath := path + ".estargz"
+		layer, layerAnnotations, err := estargzFileLayer(path, compressedPath, layerCompression)
+		return layer, layerAnnotations, compressedPath, err
+
+	default:
+		sha256, err := sha256Path(path)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		layer, err := partial.UncompressedToLayer(&UncompressedFileLayer{
+			diffID:    v1.Hash{Algorithm: "sha256", Hex: sha256},
+			mediaType: layerCompression.gzipLayerMediaType(),
+			path:      path,
+		})
+		return layer, nil, "", err
+	}
 }
 
 func (i *FileImage) Remove() error {
-	return os.Remove(i.path)
+	i.removeCompressed()
+	var lastErr error
+	for _, path := range i.paths {
+		err := os.Remove(path)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// removeCompressed Removes the files with the layers compressed ahead of time
+func (i *FileImage) removeCompressed() {
+	for _, compressedPath := range i.compressedPaths {
+		_ = os.Remove(compressedPath)
+	}
+}
+
+// zstdFileLayer Compresses the tarball in path with zstd into compressedPath, and returns the layer with its contents
+func zstdFileLayer(path, compressedPath string, diffID v1.Hash, layerCompression LayerCompression) (v1.Layer, error) {
+	digest, err := layerCompression.compressZstd(path, compressedPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(compressedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return partial.CompressedToLayer(&CompressedFileLayer{
+		digest:    v1.Hash{Algorithm: "sha256", Hex: digest},
+		diffID:    diffID,
+		size:      info.Size(),
+		mediaType: types.OCILayerZStd,
+		path:      compressedPath,
+	})
+}
+
+// estargzFileLayer Converts the tarball in path to eStargz into compressedPath, and returns the layer with its contents
+// and the annotations that let the stargz-snapshotter find its table of contents
+func estargzFileLayer(path, compressedPath string, layerCompression LayerCompression) (v1.Layer, map[string]string, error) {
+	digest, diffID, tocDigest, err := layerCompression.compressEstargz(path, compressedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(compressedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	layer, err := partial.CompressedToLayer(&CompressedFileLayer{
+		digest:    v1.Hash{Algorithm: "sha256", Hex: digest},
+		diffID:    v1.Hash{Algorithm: "sha256", Hex: diffID},
+		size:      info.Size(),
+		mediaType: layerCompression.gzipLayerMediaType(),
+		path:      compressedPath,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return layer, map[string]string{estargz.TOCJSONDigestAnnotation: tocDigest}, nil
 }
 
 func sha256Path(path string) (string, error) {
@@ -32,3 +32,39 @@ func (ul *UncompressedFileLayer) Uncompressed() (io.ReadCloser, error) {
 func (ul *UncompressedFileLayer) MediaType() (regtypes.MediaType, error) {
 	return ul.mediaType, nil
 }
+
+// CompressedFileLayer Layer whose compressed contents are stored in a file
+type CompressedFileLayer struct {
+	digest    regv1.Hash
+	diffID    regv1.Hash
+	size      int64
+	mediaType regtypes.MediaType
+	path      string
+}
+
+var _ regpartial.CompressedLayer = (*CompressedFileLayer)(nil)
+
+// Digest of the compressed contents
+func (cl *CompressedFileLayer) Digest() (regv1.Hash, error) {
+	return cl.digest, nil
+}
+
+// DiffID digest of the uncompressed contents
+func (cl *CompressedFileLayer) DiffID() (regv1.Hash, error) {
+	return cl.diffID, nil
+}
+
+// Compressed Returns the compressed contents
+func (cl *CompressedFileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(cl.path)
+}
+
+// Size of the compressed contents
+func (cl *CompressedFileLayer) Size() (int64, error) {
+	return cl.size, nil
+}
+
+// MediaType of the layer
+func (cl *CompressedFileLayer) MediaType() (regtypes.MediaType, error) {
+	return cl.mediaType, nil
+}
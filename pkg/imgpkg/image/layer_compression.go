@@ -0,0 +1,167 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/google/go-containerregistry/pkg/compression"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// zstdMinLevel and zstdMaxLevel levels accepted by zstd
+	zstdMinLevel = 1
+	zstdMaxLevel = 22
+	// zstdDefaultLevel level used by zstd when none is provided
+	zstdDefaultLevel = 3
+)
+
+// LayerCompression Compression of the layer of the images built from files
+type LayerCompression struct {
+	// Algorithm compression.GZip (default) or compression.ZStd
+	Algorithm compression.Compression
+	// Level of the zstd compression, 0 uses the default level of zstd
+	Level int
+	// Estargz when compressing with gzip, write the layer as seekable eStargz, so that it can be lazily pulled
+	// by the consumers that use the stargz-snapshotter of containerd
+	Estargz bool
+	// OCIMediaTypes write the layers, and the images with them, with the OCI media types instead of the Docker ones
+	OCIMediaTypes bool
+}
+
+// ParseLayerCompression Parses a layer compression with the format gzip or zstd[:level] (example: zstd:19)
+func ParseLayerCompression(value string) (LayerCompression, error) {
+	algorithm, level, hasLevel := strings.Cut(value, ":")
+	switch compression.Compression(algorithm) {
+	case compression.GZip:
+		if hasLevel {
+			return LayerCompression{}, fmt.Errorf("Expected compression '%s' to not have a level, only zstd accepts one", value)
+		}
+		return LayerCompression{Algorithm: compression.GZip}, nil
+
+	case compression.ZStd:
+		layerCompression := LayerCompression{Algorithm: compression.ZStd}
+		if !hasLevel {
+			return layerCompression, nil
+		}
+		parsedLevel, err := strconv.Atoi(level)
+		if err != nil || parsedLevel < zstdMinLevel || parsedLevel > zstdMaxLevel {
+			return LayerCompression{}, fmt.Errorf("Expected zstd compression level '%s' to be a number between %d and %d", level, zstdMinLevel, zstdMaxLevel)
+		}
+		layerCompression.Level = parsedLevel
+		return layerCompression, nil
+
+	default:
+		return LayerCompression{}, fmt.Errorf("Unknown compression '%s' (supported: gzip, zstd[:level])", value)
+	}
+}
+
+// IsZstd Returns true when the layers are compressed with zstd
+func (c LayerCompression) IsZstd() bool {
+	return c.Algorithm == compression.ZStd
+}
+
+// UsesOCIMediaTypes Returns true when the layers, and the images with them, have the OCI media types.
+// zstd layers are only defined by the OCI image spec
+func (c LayerCompression) UsesOCIMediaTypes() bool {
+	return c.OCIMediaTypes || c.IsZstd()
+}
+
+// gzipLayerMediaType Media type of the layers compressed with gzip
+func (c LayerCompression) gzipLayerMediaType() types.MediaType {
+	if c.OCIMediaTypes {
+		return types.OCILayer
+	}
+	return types.DockerLayer
+}
+
+// IsEstargz Returns true when the layers are written as eStargz
+func (c LayerCompression) IsEstargz() bool {
+	return c.Estargz && !c.IsZstd()
+}
+
+// zstdLevel Level of the zstd compression, the default one of zstd when none was provided
+func (c LayerCompression) zstdLevel() int {
+	if c.Level == 0 {
+		return zstdDefaultLevel
+	}
+	return c.Level
+}
+
+// compressZstd Compresses, once, the file in path with zstd into compressedPath, returning the digest of the compressed file
+func (c LayerCompression) compressZstd(path, compressedPath string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	compressedFile, err := os.Create(compressedPath)
+	if err != nil {
+		return "", err
+	}
+	defer compressedFile.Close()
+
+	hasher := sha256.New()
+	encoder, err := zstd.NewWriter(io.MultiWriter(compressedFile, hasher), zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.zstdLevel())))
+	if err != nil {
+		return "", fmt.Errorf("Creating zstd encoder: %s", err)
+	}
+	_, err = io.Copy(encoder, file)
+	if err != nil {
+		encoder.Close()
+		return "", fmt.Errorf("Compressing layer with zstd: %s", err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", fmt.Errorf("Compressing layer with zstd: %s", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// compressEstargz Converts, once, the tarball in path to eStargz into compressedPath, returning the digest of the
+// compressed file, the digest of its uncompressed contents, that include the table of contents, and the digest of
+// the table of contents
+func (c LayerCompression) compressEstargz(path, compressedPath string) (string, string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	blob, err := estargz.Build(io.NewSectionReader(file, 0, info.Size()), estargz.WithCompression(newEstargzCompression()))
+	if err != nil {
+		return "", "", "", fmt.Errorf("Converting layer to eStargz: %s", err)
+	}
+	defer blob.Close()
+
+	compressedFile, err := os.Create(compressedPath)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer compressedFile.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(compressedFile, hasher), blob)
+	if err != nil {
+		return "", "", "", fmt.Errorf("Converting layer to eStargz: %s", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), blob.DiffID().Encoded(), blob.TOCDigest().String(), nil
+}
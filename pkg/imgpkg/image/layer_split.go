@@ -0,0 +1,138 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// LayerNameAnnotation annotation of the layers of an image split into several layers, with the name of the layer
+	LayerNameAnnotation = "imgpkg.carvel.dev/layer"
+	// LayerPartTypeflag tar entry type of the parts of a file split across layers. The versions of imgpkg that do not
+	// reassemble the parts fail to extract the entries, instead of writing the last part as the whole file
+	LayerPartTypeflag byte = 'P'
+	// LayerPartOffsetsAnnotation annotation of the layers with parts of files split across layers, with the offset in
+	// each file of its first part in the layer (format: JSON object, example: {"big.bin":1048576}). The offsets are kept
+	// out of the layers, so that the layers after content inserted in a file do not change
+	LayerPartOffsetsAnnotation = "imgpkg.carvel.dev/layer-part-offsets"
+	// LayerPartPAXRecord PAX record of the parts of a file split across layers, with the position of the part in the
+	// file: LayerPartFirst, LayerPartNext or LayerPartLast. In a layer, each part continues the file where the previous one ended
+	LayerPartPAXRecord = "IMGPKG.part"
+	// LayerPartFirst position of the first part of a file split across layers
+	LayerPartFirst = "first"
+	// LayerPartNext position of the parts between the first and the last one
+	LayerPartNext = "next"
+	// LayerPartLast position of the last part of a file split across layers
+	LayerPartLast = "last"
+	// imgpkgDir folder with the metadata of a bundle, always kept in the first layer
+	imgpkgDir = ".imgpkg"
+)
+
+// LayerSplit How the files of an image are split into several layers, so that unchanged directories result in the
+// same layer across versions of the image. When it is empty all the files are in a single layer
+type LayerSplit struct {
+	// PerDir adds each top-level directory to its own layer, named after the directory
+	PerDir bool
+	// Layers paths added to the layer of their name, they take precedence over PerDir
+	Layers []NamedLayerPath
+	// MaxSize maximum size, in bytes, of the layers. The files that do not fit are added to a new layer, with the
+	// same name, and the files bigger than MaxSize are split into parts across several layers. 0 when there is no maximum
+	MaxSize int64
+	// Chunking splits the files bigger than a chunk into parts at content-defined boundaries, and starts a new layer,
+	// with the same name, after the parts, or files, whose content is a boundary. A small change to a big file then
+	// only changes the layers with the parts that changed, the other layers are the same as in the previous version
+	Chunking bool
+}
+
+// NamedLayerPath Path, relative to the root of the image, whose files are added to the layer Name
+type NamedLayerPath struct {
+	Name string
+	Path string
+}
+
+// ParseNamedLayerPath Parses a layer with the format name=path (example: charts=config/charts)
+func ParseNamedLayerPath(value string) (NamedLayerPath, error) {
+	name, layerPath, found := strings.Cut(value, "=")
+	if !found || name == "" || layerPath == "" {
+		return NamedLayerPath{}, fmt.Errorf("Expected layer '%s' to have the format name=path (example: charts=config/charts)", value)
+	}
+	cleanPath := path.Clean(strings.ReplaceAll(layerPath, "\\", "/"))
+	if path.IsAbs(cleanPath) || cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+		return NamedLayerPath{}, fmt.Errorf("Expected the path of layer '%s' to be relative to the root of the image, and inside it", value)
+	}
+	if cleanPath == imgpkgDir || strings.HasPrefix(cleanPath, imgpkgDir+"/") {
+		return NamedLayerPath{}, fmt.Errorf("Expected the path of layer '%s' to not be in %s, the bundle metadata is kept in the first layer", value, imgpkgDir)
+	}
+	return NamedLayerPath{Name: name, Path: cleanPath}, nil
+}
+
+// IsEmpty Returns true when all the files are in a single layer
+func (s LayerSplit) IsEmpty() bool {
+	return !s.PerDir && len(s.Layers) == 0 && s.MaxSize == 0 && !s.Chunking
+}
+
+// layerName Returns the name of the layer of the file or directory relPath (separated by /), the first layer is
+// the unnamed one, with the top-level files, the bundle metadata and the parent directories of the paths of Layers
+func (s LayerSplit) layerName(relPath string, isDir bool) string {
+	if relPath == "." {
+		return ""
+	}
+	name := ""
+	matchedLen := 0
+	for _, layer := range s.Layers {
+		if (relPath == layer.Path || strings.HasPrefix(relPath, layer.Path+"/")) && len(layer.Path) > matchedLen {
+			name = layer.Name
+			matchedLen = len(layer.Path)
+		}
+	}
+	if matchedLen > 0 || !s.PerDir {
+		return name
+	}
+
+	topLevel, _, nested := strings.Cut(relPath, "/")
+	if topLevel == imgpkgDir || (!nested && !isDir) {
+		return ""
+	}
+	return topLevel
+}
+
+// isBundleMetadata Returns true for the bundle metadata folder, and its files, of the path relPath (separated by /)
+func isBundleMetadata(relPath string) bool {
+	return relPath == imgpkgDir || strings.HasPrefix(relPath, imgpkgDir+"/")
+}
+
+// LayerFilePart Returns the position of the part in the file, LayerPartFirst, LayerPartNext or LayerPartLast, when
+// the tar entry is a part of a file split across several layers because of LayerSplit.MaxSize or LayerSplit.Chunking
+func LayerFilePart(header *tar.Header) (string, bool, error) {
+	if header.Typeflag != LayerPartTypeflag {
+		return "", false, nil
+	}
+	position := header.PAXRecords[LayerPartPAXRecord]
+	switch position {
+	case LayerPartFirst, LayerPartNext, LayerPartLast:
+		return position, true, nil
+	default:
+		return "", false, fmt.Errorf("Expected PAX record %s of file '%s' to be one of %s, %s or %s, got '%s'",
+			LayerPartPAXRecord, header.Name, LayerPartFirst, LayerPartNext, LayerPartLast, position)
+	}
+}
+
+// layerPartOffsets Returns the offsets of LayerPartOffsetsAnnotation in the annotations of a layer
+func layerPartOffsets(annotations map[string]string) (map[string]int64, error) {
+	offsets := map[string]int64{}
+	value, found := annotations[LayerPartOffsetsAnnotation]
+	if !found {
+		return offsets, nil
+	}
+	err := json.Unmarshal([]byte(value), &offsets)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing annotation %s of layer: %s", LayerPartOffsetsAnnotation, err)
+	}
+	return offsets, nil
+}
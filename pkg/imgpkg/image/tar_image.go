@@ -5,6 +5,8 @@ package image
 
 import (
 	"archive/tar"
+	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
@@ -12,92 +14,180 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/cdc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/ignorefile"
 )
 
+// FileFilter Returns the contents of the file added to the image, given its path relative to the image and its contents on disk
+type FileFilter func(relPath string, contents []byte) []byte
+
 type TarImage struct {
 	files           []string
 	excludePaths    []string
 	logger          Logger
 	keepPermissions bool
+	compression     LayerCompression
+	created         time.Time
+	fileFilter      FileFilter
+	fileAttributes  FileAttributes
+	layerSplit      LayerSplit
 }
 
 // NewTarImage creates a struct that will allow users to create a representation of a set of paths as an OCI Image
 func NewTarImage(files []string, excludePaths []string, logger Logger, keepPermissions bool) *TarImage {
-	return &TarImage{files, excludePaths, logger, keepPermissions}
+	return &TarImage{files: files, excludePaths: excludePaths, logger: logger, keepPermissions: keepPermissions}
+}
+
+// WithCompression Compresses the layer of the image with compression instead of gzip
+func (i *TarImage) WithCompression(compression LayerCompression) *TarImage {
+	i.compression = compression
+	return i
+}
+
+// WithCreated Records created as the creation time of the image and as the modification time of its files
+func (i *TarImage) WithCreated(created time.Time) *TarImage {
+	i.created = created
+	return i
+}
+
+// WithFileFilter Adds the contents returned by fileFilter, instead of the contents on disk, for each file
+func (i *TarImage) WithFileFilter(fileFilter FileFilter) *TarImage {
+	i.fileFilter = fileFilter
+	return i
+}
+
+// WithFileAttributes Records the owner and the permissions of fileAttributes for the files and directories
+func (i *TarImage) WithFileAttributes(fileAttributes FileAttributes) *TarImage {
+	i.fileAttributes = fileAttributes
+	return i
+}
+
+// WithLayerSplit Splits the files of the image into the layers of layerSplit instead of a single layer
+func (i *TarImage) WithLayerSplit(layerSplit LayerSplit) *TarImage {
+	i.layerSplit = layerSplit
+	return i
 }
 
 // AsFileImage Creates an OCI Image representation of the provided folders
 func (i *TarImage) AsFileImage(labels map[string]string) (*FileImage, error) {
-	tmpFile, err := os.CreateTemp("", "imgpkg-tar-image")
-	if err != nil {
-		return nil, err
-	}
+	tarballs := &layerTarballs{maxSize: i.layerSplit.MaxSize, chunking: i.layerSplit.Chunking}
 
-	err = i.createTarball(tmpFile, i.files)
+	err := i.createTarballs(tarballs, i.files)
 	if err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpFile.Name())
+		tarballs.remove()
 		return nil, err
 	}
 
-	// Close file explicitly to make sure all data is flushed
-	err = tmpFile.Close()
+	// Close files explicitly to make sure all data is flushed
+	layerFiles, err := tarballs.close()
 	if err != nil {
-		_ = os.Remove(tmpFile.Name())
+		tarballs.remove()
 		return nil, err
 	}
 
-	fileImg, err := NewFileImage(tmpFile.Name(), labels)
+	fileImg, err := newFileImage(layerFiles, labels, i.compression, i.created)
 	if err != nil {
-		_ = os.Remove(tmpFile.Name())
+		tarballs.remove()
 		return nil, err
 	}
 
 	return fileImg, nil
 }
 
-func (i *TarImage) createTarball(file *os.File, filePaths []string) error {
-	tarWriter := tar.NewWriter(file)
-	defer tarWriter.Close()
+func (i *TarImage) createTarballs(tarballs *layerTarballs, filePaths []string) error {
+	// the unnamed layer, with the top-level files and the bundle metadata, is always the first one
+	_, err := tarballs.tarball("")
+	if err != nil {
+		return err
+	}
+
+	// when the layers have a maximum size, the bundle metadata is added before the other files, so that there is
+	// room for it in the first layer
+	metadataFirst := tarballs.maxSize > 0
+	if metadataFirst {
+		for _, path := range filePaths {
+			err = i.addPathToTarballs(tarballs, path, isBundleMetadata)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	for _, path := range filePaths {
-		info, err := os.Stat(path)
+		err = i.addPathToTarballs(tarballs, path, func(relPath string) bool {
+			return !metadataFirst || !isBundleMetadata(relPath)
+		})
 		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+// addPathToTarballs Adds the file, or the files and directories of the directory, path whose relative path (separated
+// by /) is included. The root directory is walked even when it is not included
+func (i *TarImage) addPathToTarballs(tarballs *layerTarballs, path string, include func(relPath string) bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
 
+	if !info.IsDir() {
+		if !include(filepath.Base(path)) {
+			return nil
+		}
+		return i.addFileToTar(path, filepath.Base(path), info, tarballs, i.layerSplit.layerName(filepath.Base(path), false))
+	}
+
+	ignored, err := ignorefile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	// Walk is deterministic according to https://golang.org/pkg/path/filepath/#Walk
+	err = filepath.Walk(path, func(walkedPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(path, walkedPath)
+		if err != nil {
+			return err
+		}
+		if relPath != "." && ignored.Match(relPath, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if info.IsDir() {
-			// Walk is deterministic according to https://golang.org/pkg/path/filepath/#Walk
-			err := filepath.Walk(path, func(walkedPath string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				relPath, err := filepath.Rel(path, walkedPath)
-				if err != nil {
-					return err
-				}
-				if info.IsDir() {
-					if i.isExcluded(relPath) {
-						return filepath.SkipDir
-					}
-					return i.addDirToTar(path, relPath, tarWriter)
-				}
-				if (info.Mode() & os.ModeType) != 0 {
-					return fmt.Errorf("Expected file '%s' to be a regular file", walkedPath)
+			if i.isExcluded(relPath) {
+				return filepath.SkipDir
+			}
+			if !include(filepath.ToSlash(relPath)) {
+				if relPath == "." {
+					return nil
 				}
-				return i.addFileToTar(walkedPath, relPath, info, tarWriter)
-			})
-			if err != nil {
-				return fmt.Errorf("Adding file '%s' to tar: %s", path, err)
+				return filepath.SkipDir
 			}
-		} else {
-			err := i.addFileToTar(path, filepath.Base(path), info, tarWriter)
+			tarWriter, err := tarballs.writer(i.layerSplit.layerName(filepath.ToSlash(relPath), true), filepath.ToSlash(relPath))
 			if err != nil {
 				return err
 			}
+			return i.addDirToTar(path, relPath, tarWriter)
+		}
+		if !include(filepath.ToSlash(relPath)) {
+			return nil
 		}
+		if (info.Mode() & os.ModeType) != 0 {
+			return fmt.Errorf("Expected file '%s' to be a regular file", walkedPath)
+		}
+		return i.addFileToTar(walkedPath, relPath, info, tarballs, i.layerSplit.layerName(filepath.ToSlash(relPath), false))
+	})
+	if err != nil {
+		return fmt.Errorf("Adding file '%s' to tar: %s", path, err)
 	}
-
 	return nil
 }
 
@@ -125,14 +215,16 @@ func (i *TarImage) addDirToTar(fullPath string, relPath string, tarWriter *tar.W
 	header := &tar.Header{
 		Name:     relPath,
 		Mode:     folderPermission, // static
-		ModTime:  time.Time{},      // static
+		ModTime:  i.created,        // static unless provided
 		Typeflag: tar.TypeDir,
 	}
+	i.fileAttributes.apply(header)
 
 	return tarWriter.WriteHeader(header)
 }
 
-func (i *TarImage) addFileToTar(fullPath, relPath string, info os.FileInfo, tarWriter *tar.Writer) error {
+// addFileToTar Adds the file to the tarball of the layer layerName
+func (i *TarImage) addFileToTar(fullPath, relPath string, info os.FileInfo, tarballs *layerTarballs, layerName string) error {
 	if i.isExcluded(relPath) {
 		return nil
 	}
@@ -159,17 +251,304 @@ func (i *TarImage) addFileToTar(fullPath, relPath string, info os.FileInfo, tarW
 		Name:     relPath,
 		Size:     info.Size(),
 		Mode:     filePermission, // static
-		ModTime:  time.Time{},    // static
+		ModTime:  i.created,      // static unless provided
 		Typeflag: tar.TypeReg,
 	}
+	i.fileAttributes.apply(header)
+
+	var contents io.Reader = file
+	if i.fileFilter != nil {
+		filteredContents, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		filteredContents = i.fileFilter(relPath, filteredContents)
+		header.Size = int64(len(filteredContents))
+		contents = bytes.NewReader(filteredContents)
+	}
+
+	return tarballs.writeFile(layerName, header, contents)
+}
+
+const (
+	// tarBlockSize size of the blocks of a tarball, the contents of the files are padded to it
+	tarBlockSize = 512
+	// tarHeaderMaxSize space reserved for the header of an entry, including the PAX headers of the parts of a file
+	tarHeaderMaxSize = 4 * tarBlockSize
+	// tarFooterSize size of the two empty blocks at the end of a tarball
+	tarFooterSize = 2 * tarBlockSize
+	// chunkedLayerMinSize size of a tarball before it can be cut after a boundary, when the layers are chunked
+	chunkedLayerMinSize = 16 << 20
+	// chunkedLayerBoundaryMask one in 16 parts, or files, is a boundary
+	chunkedLayerBoundaryMask = 0x0f
+)
+
+// layerChunkSizes sizes of the parts of the files, when the layers are chunked
+var layerChunkSizes = cdc.Sizes{Min: 64 << 10, Avg: 256 << 10, Max: 1 << 20}
+
+// layerTarballs Tarballs of the layers of the image, in the order their first file was added
+type layerTarballs struct {
+	tarballs []*layerTarball
+	// current tarball of each layer name, the files are added to it
+	current map[string]*layerTarball
+	// maxSize maximum size of each tarball, 0 when there is no maximum
+	maxSize int64
+	// chunking when true, the files are split into parts, and the tarballs are cut, at content-defined boundaries
+	chunking bool
+}
+
+type layerTarball struct {
+	name    string
+	file    *os.File
+	writer  *tar.Writer
+	written *countingWriter
+	// partOffsets offset in each file split across layers of its first part in the tarball
+	partOffsets map[string]int64
+}
+
+// writer Returns the writer of the tarball the directory relPath of the layer name is added to
+func (t *layerTarballs) writer(name string, relPath string) (*tar.Writer, error) {
+	if isBundleMetadata(relPath) {
+		return t.tarballs[0].writer, nil
+	}
+	tarball, err := t.tarballWithRoom(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return tarball.writer, nil
+}
+
+// writeFile Adds the file, with the contents of header.Size bytes, to the tarball of the layer name. When there is a
+// maximum size, the file is added to a new tarball of the layer when it does not fit in the current one, and it is
+// split into parts across several tarballs when it does not fit in an empty one. The bundle metadata is always added to
+// the first tarball
+func (t *layerTarballs) writeFile(name string, header *tar.Header, contents io.Reader) error {
+	if t.chunking {
+		return t.writeChunkedFile(name, header, contents)
+	}
+	if isBundleMetadata(header.Name) {
+		tarball := t.tarballs[0]
+		if t.maxSize > 0 && header.Size > t.room(tarball.written.size) {
+			return fmt.Errorf("Expected the bundle metadata to fit in the first layer, of at most %d bytes, to add file '%s'", t.maxSize, header.Name)
+		}
+		err := tarball.writer.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tarball.writer, contents)
+		return err
+	}
+	if t.maxSize == 0 || header.Size <= t.room(0) {
+		tarball, err := t.tarballWithRoom(name, header.Size)
+		if err != nil {
+			return err
+		}
+		err = tarball.writer.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tarball.writer, contents)
+		return err
+	}
+
+	for offset := int64(0); offset < header.Size; {
+		tarball, err := t.tarballWithRoom(name, tarBlockSize)
+		if err != nil {
+			return err
+		}
+		partSize := header.Size - offset
+		if room := t.room(tarball.written.size); partSize > room {
+			partSize = room
+		}
+		if partSize <= 0 {
+			return fmt.Errorf("Expected the maximum size of the layers to be bigger than %d bytes, to add file '%s'", t.maxSize, header.Name)
+		}
+
+		position := LayerPartNext
+		switch {
+		case offset == 0:
+			position = LayerPartFirst
+		case offset+partSize == header.Size:
+			position = LayerPartLast
+		}
+		tarball.addPart(header.Name, offset)
+		err = tarball.writer.WriteHeader(filePartHeader(header, position, partSize))
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(tarball.writer, contents, partSize)
+		if err != nil {
+			return err
+		}
+		offset += partSize
+	}
+	return nil
+}
 
-	err = tarWriter.WriteHeader(header)
+// filePartHeader Returns the header of the part, of partSize bytes at position, of the file split across layers
+func filePartHeader(header *tar.Header, position string, partSize int64) *tar.Header {
+	part := *header
+	part.Typeflag = LayerPartTypeflag
+	part.Size = partSize
+	part.Format = tar.FormatPAX
+	part.PAXRecords = map[string]string{LayerPartPAXRecord: position}
+	return &part
+}
+
+// writeChunkedFile Adds the file, with the contents of header.Size bytes, to the tarball of the layer name. The file
+// is split into parts at content-defined boundaries when it is bigger than a chunk
+func (t *layerTarballs) writeChunkedFile(name string, header *tar.Header, contents io.Reader) error {
+	chunker := cdc.NewChunker(io.LimitReader(contents, header.Size), layerChunkSizes)
+	chunk, err := chunker.Next()
+	if err == io.EOF || (err == nil && int64(len(chunk)) == header.Size) {
+		return t.writeChunk(name, header, chunk, 0)
+	}
+
+	var offset int64
+	position := LayerPartFirst
+	// the chunks are only valid until the next one is read, this one is kept in current
+	current := make([]byte, 0, layerChunkSizes.Max)
+	for {
+		if err != nil {
+			return err
+		}
+		// The next chunk is read before writing this one, to know if this one is the last part
+		chunk = append(current[:0], chunk...)
+		next, nextErr := chunker.Next()
+		if nextErr == io.EOF {
+			position = LayerPartLast
+		}
+		err = t.writeChunk(name, filePartHeader(header, position, int64(len(chunk))), chunk, offset)
+		if err != nil {
+			return err
+		}
+		offset += int64(len(chunk))
+		if nextErr == io.EOF {
+			break
+		}
+		chunk, err, position = next, nextErr, LayerPartNext
+	}
+	if offset != header.Size {
+		return fmt.Errorf("Expected file '%s' to have %d bytes, but read %d", header.Name, header.Size, offset)
+	}
+	return nil
+}
+
+// writeChunk Adds the entry, with the contents of chunk at offset in the file, to the tarball of the layer name. The
+// next entries are added to a new tarball of the layer when the tarball is big enough and the chunk is a boundary, so
+// that the tarballs are cut after the same contents across versions of the files. The bundle metadata is always added
+// to the first tarball
+func (t *layerTarballs) writeChunk(name string, header *tar.Header, chunk []byte, offset int64) error {
+	tarball := t.tarballs[0]
+	if !isBundleMetadata(header.Name) {
+		var err error
+		tarball, err = t.tarballWithRoom(name, header.Size)
+		if err != nil {
+			return err
+		}
+	}
+
+	if header.Typeflag == LayerPartTypeflag {
+		tarball.addPart(header.Name, offset)
+	}
+	err := tarball.writer.WriteHeader(header)
 	if err != nil {
 		return err
 	}
+	_, err = tarball.writer.Write(chunk)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(chunk)
+	if t.current[name] == tarball && tarball.written.size >= chunkedLayerMinSize && sum[0]&chunkedLayerBoundaryMask == 0 {
+		delete(t.current, name)
+	}
+	return nil
+}
+
+// room Returns the size of the contents of the biggest file that can be added to a tarball with written bytes
+func (t *layerTarballs) room(written int64) int64 {
+	// leave room for the compression of contents that do not compress, which adds a few bytes per block
+	limit := t.maxSize - t.maxSize/1024 - tarFooterSize
+	// the contents of the last file written are padded to a block
+	written = (written + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+	room := limit - written - tarHeaderMaxSize
+	return room / tarBlockSize * tarBlockSize
+}
+
+// tarballWithRoom Returns the current tarball of the layer name, creating a new one when it does not exist yet or
+// when a file of size bytes does not fit in it
+func (t *layerTarballs) tarballWithRoom(name string, size int64) (*layerTarball, error) {
+	tarball, found := t.current[name]
+	if found && (t.maxSize == 0 || tarball.written.size == 0 || size <= t.room(tarball.written.size)) {
+		return tarball, nil
+	}
+	return t.tarball(name)
+}
+
+// tarball Creates a new tarball for the layer name, the following files of the layer are added to it
+func (t *layerTarballs) tarball(name string) (*layerTarball, error) {
+	if t.current == nil {
+		t.current = map[string]*layerTarball{}
+	}
+	file, err := os.CreateTemp("", "imgpkg-tar-image")
+	if err != nil {
+		return nil, err
+	}
+	written := &countingWriter{writer: file}
+	tarball := &layerTarball{name: name, file: file, writer: tar.NewWriter(written), written: written}
+	t.current[name] = tarball
+	t.tarballs = append(t.tarballs, tarball)
+	return tarball, nil
+}
+
+// close Flushes the tarballs, and returns them in order
+func (t *layerTarballs) close() ([]layerFile, error) {
+	var layerFiles []layerFile
+	for _, tarball := range t.tarballs {
+		err := tarball.writer.Close()
+		if err != nil {
+			return nil, err
+		}
+		err = tarball.file.Close()
+		if err != nil {
+			return nil, err
+		}
+		layerFiles = append(layerFiles, layerFile{name: tarball.name, path: tarball.file.Name(), partOffsets: tarball.partOffsets})
+	}
+	return layerFiles, nil
+}
+
+// addPart Records offset as the offset of the first part of file in the tarball, the next parts of the file in the
+// tarball continue it
+func (t *layerTarball) addPart(file string, offset int64) {
+	if t.partOffsets == nil {
+		t.partOffsets = map[string]int64{}
+	}
+	if _, found := t.partOffsets[file]; !found {
+		t.partOffsets[file] = offset
+	}
+}
+
+// remove Removes the tarballs
+func (t *layerTarballs) remove() {
+	for _, tarball := range t.tarballs {
+		_ = tarball.file.Close()
+		_ = os.Remove(tarball.file.Name())
+	}
+}
+
+// countingWriter Counts the bytes written to writer
+type countingWriter struct {
+	writer io.Writer
+	size   int64
+}
 
-	_, err = io.Copy(tarWriter, file)
-	return err
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.size += int64(n)
+	return n, err
 }
 
 func (i *TarImage) isExcluded(relPath string) bool {
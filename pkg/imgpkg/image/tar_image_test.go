@@ -4,10 +4,21 @@
 package image_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"testing"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/image"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +49,506 @@ func TestTarImage(t *testing.T) {
 			require.Equal(t, "sha256:8e0fe8a5564ffd6097bf9390bf01560645847f4e8f3ed5fa33e11a241ba0b5b3", d.String())
 		}
 	})
+
+	t.Run("When compressing with zstd the layer has the zstd media type and the same contents", func(t *testing.T) {
+		gzipImg, err := image.NewTarImage([]string{"test_assets/tar_folder"}, nil, logger, false).AsFileImage(nil)
+		require.NoError(t, err)
+		defer gzipImg.Remove()
+
+		compression, err := image.ParseLayerCompression("zstd:19")
+		require.NoError(t, err)
+		img, err := image.NewTarImage([]string{"test_assets/tar_folder"}, nil, logger, false).WithCompression(compression).AsFileImage(map[string]string{"foo": "bar"})
+		require.NoError(t, err)
+
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+		require.Equal(t, types.OCIConfigJSON, manifest.Config.MediaType)
+		require.Len(t, manifest.Layers, 1)
+		require.Equal(t, types.OCILayerZStd, manifest.Layers[0].MediaType)
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		gzipLayers, err := gzipImg.Layers()
+		require.NoError(t, err)
+		diffID, err := layers[0].DiffID()
+		require.NoError(t, err)
+		gzipDiffID, err := gzipLayers[0].DiffID()
+		require.NoError(t, err)
+		require.Equal(t, gzipDiffID, diffID)
+
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		require.Equal(t, "bar", cfg.Config.Labels["foo"])
+
+		require.NoError(t, img.Remove())
+	})
+
+	t.Run("When writing eStargz the layer is seekable, annotated with its table of contents and always results in the same SHA", func(t *testing.T) {
+		var digests []string
+		for i := 0; i < 2; i++ {
+			img, err := image.NewTarImage([]string{"test_assets/tar_folder"}, nil, logger, false).WithCompression(image.LayerCompression{Estargz: true}).AsFileImage(nil)
+			require.NoError(t, err)
+			defer img.Remove()
+
+			d, err := img.Digest()
+			require.NoError(t, err)
+			digests = append(digests, d.String())
+
+			manifest, err := img.Manifest()
+			require.NoError(t, err)
+			require.Len(t, manifest.Layers, 1)
+			require.Equal(t, types.DockerLayer, manifest.Layers[0].MediaType)
+			tocDigest := manifest.Layers[0].Annotations[estargz.TOCJSONDigestAnnotation]
+			require.NotEmpty(t, tocDigest)
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			compressed, err := layers[0].Compressed()
+			require.NoError(t, err)
+			contents, err := io.ReadAll(compressed)
+			require.NoError(t, err)
+			require.NoError(t, compressed.Close())
+
+			reader, err := estargz.Open(io.NewSectionReader(bytes.NewReader(contents), 0, int64(len(contents))))
+			require.NoError(t, err)
+			require.Equal(t, tocDigest, reader.TOCDigest().String())
+			_, found := reader.Lookup("text.txt")
+			require.True(t, found, "the files should be found through the table of contents")
+
+			diffID, err := layers[0].DiffID()
+			require.NoError(t, err)
+			cfg, err := img.ConfigFile()
+			require.NoError(t, err)
+			require.Equal(t, []regv1.Hash{diffID}, cfg.RootFS.DiffIDs)
+		}
+		require.Equal(t, digests[0], digests[1])
+	})
+
+	t.Run("When the creation time is provided it is recorded in the config and the files, always resulting in the same SHA", func(t *testing.T) {
+		created := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+		var digests []string
+		for i := 0; i < 2; i++ {
+			img, err := image.NewTarImage([]string{"test_assets/tar_folder"}, nil, logger, false).WithCreated(created).AsFileImage(nil)
+			require.NoError(t, err)
+			defer img.Remove()
+
+			d, err := img.Digest()
+			require.NoError(t, err)
+			digests = append(digests, d.String())
+
+			cfg, err := img.ConfigFile()
+			require.NoError(t, err)
+			require.True(t, created.Equal(cfg.Created.Time))
+			require.Len(t, cfg.History, 1)
+			require.True(t, created.Equal(cfg.History[0].Created.Time))
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			contents, err := layers[0].Uncompressed()
+			require.NoError(t, err)
+			tarReader := tar.NewReader(contents)
+			for {
+				header, err := tarReader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				require.True(t, created.Equal(header.ModTime), header.Name)
+			}
+			require.NoError(t, contents.Close())
+		}
+		require.Equal(t, digests[0], digests[1])
+	})
+
+	t.Run("When the directory has an .imgpkgignore the paths it matches are left out", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "config", "build"), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".imgpkgignore"), []byte("build/\n*.swp\n"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "values.yml"), []byte("name: app"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "values.yml.swp"), []byte("swap"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "build", "app.bin"), []byte("binary"), 0600))
+
+		img, err := image.NewTarImage([]string{dir}, nil, logger, false).AsFileImage(nil)
+		require.NoError(t, err)
+		defer img.Remove()
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		contents, err := layers[0].Uncompressed()
+		require.NoError(t, err)
+		defer contents.Close()
+
+		var names []string
+		tarReader := tar.NewReader(contents)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, header.Name)
+		}
+		require.Equal(t, []string{".", ".imgpkgignore", "config", "config/values.yml"}, names)
+	})
+
+	t.Run("When the file attributes are provided the owner is recorded and the permissions masked are cleared", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo"), 0600))
+		// permissions are set explicitly since the ones used to create the file are reduced by the umask
+		require.NoError(t, os.Chmod(filepath.Join(dir, "run.sh"), 0777))
+		require.NoError(t, os.Chmod(dir, 0777))
+
+		fileAttributes := image.FileAttributes{UID: 1000, GID: 2000, ChmodMask: 0022}
+		img, err := image.NewTarImage([]string{dir}, nil, logger, true).WithFileAttributes(fileAttributes).AsFileImage(nil)
+		require.NoError(t, err)
+		defer img.Remove()
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		contents, err := layers[0].Uncompressed()
+		require.NoError(t, err)
+		defer contents.Close()
+
+		modes := map[string]os.FileMode{}
+		tarReader := tar.NewReader(contents)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1000, header.Uid, header.Name)
+			require.Equal(t, 2000, header.Gid, header.Name)
+			modes[header.Name] = header.FileInfo().Mode().Perm()
+		}
+		require.Equal(t, map[string]os.FileMode{".": 0755, "run.sh": 0755}, modes)
+	})
+
+	t.Run("When splitting per directory each top-level directory is in its own layer", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, path := range []string{".imgpkg/images.yml", "config.yml", "charts/app/Chart.yaml", "crds/crd.yml"} {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0700))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(path), 0600))
+		}
+
+		img, err := image.NewTarImage([]string{dir}, nil, logger, false).WithLayerSplit(image.LayerSplit{PerDir: true}).AsFileImage(nil)
+		require.NoError(t, err)
+		defer img.Remove()
+
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Layers, 3)
+		require.Empty(t, manifest.Layers[0].Annotations[image.LayerNameAnnotation])
+		require.Equal(t, "charts", manifest.Layers[1].Annotations[image.LayerNameAnnotation])
+		require.Equal(t, "crds", manifest.Layers[2].Annotations[image.LayerNameAnnotation])
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Equal(t, []string{".", ".imgpkg", ".imgpkg/images.yml", "config.yml"}, layerEntries(t, layers[0]))
+		require.Equal(t, []string{"charts", "charts/app", "charts/app/Chart.yaml"}, layerEntries(t, layers[1]))
+		require.Equal(t, []string{"crds", "crds/crd.yml"}, layerEntries(t, layers[2]))
+	})
+
+	t.Run("When a path has its own layer only its files are in it, and the layer is the same when other files change", func(t *testing.T) {
+		var chartsDigests []regv1.Hash
+		for _, contents := range []string{"v1", "v2"} {
+			dir := t.TempDir()
+			for _, path := range []string{"config.yml", "vendor/charts/Chart.yaml", "vendor/README.md"} {
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0700))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(path), 0600))
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yml"), []byte(contents), 0600))
+
+			layer, err := image.ParseNamedLayerPath("charts=vendor/charts")
+			require.NoError(t, err)
+			img, err := image.NewTarImage([]string{dir}, nil, logger, false).WithLayerSplit(image.LayerSplit{Layers: []image.NamedLayerPath{layer}}).AsFileImage(nil)
+			require.NoError(t, err)
+			defer img.Remove()
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			require.Len(t, layers, 2)
+			require.Equal(t, []string{".", "config.yml", "vendor", "vendor/README.md"}, layerEntries(t, layers[0]))
+			require.Equal(t, []string{"vendor/charts", "vendor/charts/Chart.yaml"}, layerEntries(t, layers[1]))
+			chartsDigest, err := layers[1].Digest()
+			require.NoError(t, err)
+			chartsDigests = append(chartsDigests, chartsDigest)
+		}
+		require.Equal(t, chartsDigests[0], chartsDigests[1])
+	})
+
+	t.Run("When the layers have a maximum size the files are split into layers under it, and the files bigger than it are reassembled when extracted", func(t *testing.T) {
+		const maxSize = 64 * 1024
+		random := mathrand.New(mathrand.NewSource(1))
+		files := map[string][]byte{"config.yml": []byte("foo: bar")}
+		for path, size := range map[string]int{"a.bin": 40 * 1024, "b.bin": 40 * 1024, "big.bin": 150 * 1024} {
+			contents := make([]byte, size)
+			_, err := random.Read(contents)
+			require.NoError(t, err)
+			files[path] = contents
+		}
+		dir := t.TempDir()
+		for path, contents := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, path), contents, 0600))
+		}
+
+		img, err := image.NewTarImage([]string{dir}, nil, logger, false).WithLayerSplit(image.LayerSplit{MaxSize: maxSize}).AsFileImage(nil)
+		require.NoError(t, err)
+		defer img.Remove()
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 5)
+		require.Equal(t, []string{".", "a.bin"}, layerEntries(t, layers[0]))
+		require.Equal(t, []string{"b.bin", "big.bin"}, layerEntries(t, layers[1]))
+		require.Equal(t, []string{"big.bin", "config.yml"}, layerEntries(t, layers[4]))
+		// the parts have their own type, so that the versions of imgpkg that do not reassemble them fail to extract them
+		require.Equal(t, map[string]byte{"big.bin": image.LayerPartTypeflag, "config.yml": tar.TypeReg}, layerTypeflags(t, layers[4]))
+		// the offset of the parts is in the annotations of the layers, so that the layers do not depend on it
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		require.Equal(t, `{"big.bin":0}`, manifest.Layers[1].Annotations[image.LayerPartOffsetsAnnotation])
+		require.Empty(t, manifest.Layers[0].Annotations[image.LayerPartOffsetsAnnotation])
+		for _, layer := range layers {
+			size, err := layer.Size()
+			require.NoError(t, err)
+			require.LessOrEqual(t, size, int64(maxSize))
+		}
+
+		outputDir := t.TempDir()
+		require.NoError(t, image.NewDirImage(outputDir, img, logger).AsDirectory())
+		for path, contents := range files {
+			extracted, err := os.ReadFile(filepath.Join(outputDir, path))
+			require.NoError(t, err)
+			require.Equal(t, contents, extracted, "file %s", path)
+		}
+	})
+
+	t.Run("When the layers have a maximum size the bundle metadata is in the first layer, even when it is in the last path", func(t *testing.T) {
+		const maxSize = 1024 * 1024
+		assetsDir := t.TempDir()
+		big := make([]byte, 3*1024*1024)
+		_, err := mathrand.New(mathrand.NewSource(1)).Read(big)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(assetsDir, "big.bin"), big, 0600))
+		configDir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(configDir, ".imgpkg"), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, ".imgpkg", "images.yml"), []byte("images: []"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yml"), []byte("foo: bar"), 0600))
+
+		img, err := image.NewTarImage([]string{assetsDir, configDir}, nil, logger, false).WithLayerSplit(image.LayerSplit{MaxSize: maxSize}).AsFileImage(nil)
+		require.NoError(t, err)
+		defer img.Remove()
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Greater(t, len(layers), 3)
+		require.Equal(t, []string{".imgpkg", ".imgpkg/images.yml"}, layerEntries(t, layers[0])[:2])
+		for _, layer := range layers[1:] {
+			require.NotContains(t, layerEntries(t, layer), ".imgpkg/images.yml")
+		}
+
+		outputDir := t.TempDir()
+		require.NoError(t, image.NewDirImage(outputDir, img, logger).AsDirectory())
+		extracted, err := os.ReadFile(filepath.Join(outputDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		require.Equal(t, "images: []", string(extracted))
+		extracted, err = os.ReadFile(filepath.Join(outputDir, "big.bin"))
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(big, extracted))
+	})
+
+	t.Run("When the layers are chunked a change, or an insertion, in the middle of a big file only changes the layers around it", func(t *testing.T) {
+		random := mathrand.New(mathrand.NewSource(1))
+		original := make([]byte, 64*1024*1024)
+		_, err := random.Read(original)
+		require.NoError(t, err)
+		changed := append([]byte{}, original...)
+		copy(changed[40*1024*1024:], "changed")
+		inserted := append(append(append([]byte{}, original[:40*1024*1024]...), "inserted"...), original[40*1024*1024:]...)
+
+		var versions [][]regv1.Hash
+		for _, big := range [][]byte{original, changed, inserted} {
+			dir := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(dir, ".imgpkg"), 0700))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".imgpkg", "images.yml"), []byte("images: []"), 0600))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"), big, 0600))
+
+			img, err := image.NewTarImage([]string{dir}, nil, logger, false).WithLayerSplit(image.LayerSplit{Chunking: true}).AsFileImage(nil)
+			require.NoError(t, err)
+			defer img.Remove()
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			require.Greater(t, len(layers), 2)
+			require.Subset(t, layerEntries(t, layers[0]), []string{".imgpkg", ".imgpkg/images.yml"})
+
+			var digests []regv1.Hash
+			for _, layer := range layers {
+				digest, err := layer.Digest()
+				require.NoError(t, err)
+				digests = append(digests, digest)
+			}
+			versions = append(versions, digests)
+
+			outputDir := t.TempDir()
+			require.NoError(t, image.NewDirImage(outputDir, img, logger).AsDirectory())
+			extracted, err := os.ReadFile(filepath.Join(outputDir, "big.bin"))
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(big, extracted))
+		}
+
+		for _, version := range versions[1:] {
+			changedLayers := 0
+			for _, digest := range version {
+				if !slices.Contains(versions[0], digest) {
+					changedLayers++
+				}
+			}
+			require.Equal(t, 1, changedLayers)
+		}
+	})
+}
+
+func TestParseCreated(t *testing.T) {
+	created, err := image.ParseCreated("2024-01-31T11:00:00+01:00")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), created)
+
+	t.Setenv(image.SourceDateEpochEnv, "1706695200")
+	created, err = image.ParseCreated("source-epoch")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), created)
+
+	t.Setenv(image.SourceDateEpochEnv, "yesterday")
+	_, err = image.ParseCreated("source-epoch")
+	require.ErrorContains(t, err, "Expected environment variable SOURCE_DATE_EPOCH 'yesterday' to be the number of seconds since the Unix epoch")
+
+	t.Setenv(image.SourceDateEpochEnv, "")
+	_, err = image.ParseCreated("source-epoch")
+	require.ErrorContains(t, err, "Expected environment variable SOURCE_DATE_EPOCH to be set when using source-epoch")
+
+	_, err = image.ParseCreated("31/01/2024")
+	require.ErrorContains(t, err, "Expected creation time '31/01/2024' to be RFC3339 (example: 2024-01-31T10:00:00Z) or source-epoch")
+}
+
+func TestParseLayerCompression(t *testing.T) {
+	compression, err := image.ParseLayerCompression("zstd")
+	require.NoError(t, err)
+	require.True(t, compression.IsZstd())
+	require.Equal(t, 0, compression.Level)
+
+	compression, err = image.ParseLayerCompression("gzip")
+	require.NoError(t, err)
+	require.False(t, compression.IsZstd())
+
+	_, err = image.ParseLayerCompression("zstd:23")
+	require.ErrorContains(t, err, "Expected zstd compression level '23' to be a number between 1 and 22")
+
+	_, err = image.ParseLayerCompression("gzip:9")
+	require.ErrorContains(t, err, "Expected compression 'gzip:9' to not have a level, only zstd accepts one")
+
+	_, err = image.ParseLayerCompression("lz4")
+	require.ErrorContains(t, err, "Unknown compression 'lz4' (supported: gzip, zstd[:level])")
+}
+
+func TestParseChown(t *testing.T) {
+	uid, gid, err := image.ParseChown("1000:2000")
+	require.NoError(t, err)
+	require.Equal(t, 1000, uid)
+	require.Equal(t, 2000, gid)
+
+	for _, value := range []string{"1000", "root:root", "-1:0", "0:"} {
+		_, _, err = image.ParseChown(value)
+		require.ErrorContains(t, err, "Expected owner '"+value+"' to be numeric ids with the format uid:gid (example: 0:0)")
+	}
+}
+
+func TestParseChmodMask(t *testing.T) {
+	mask, err := image.ParseChmodMask("022")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0022), mask)
+
+	for _, value := range []string{"1777", "rw", "9"} {
+		_, err = image.ParseChmodMask(value)
+		require.ErrorContains(t, err, "Expected permission mask '"+value+"' to be octal between 000 and 777 (example: 022)")
+	}
+}
+
+func TestParseNamedLayerPath(t *testing.T) {
+	layer, err := image.ParseNamedLayerPath("charts=./vendor/charts/")
+	require.NoError(t, err)
+	require.Equal(t, image.NamedLayerPath{Name: "charts", Path: "vendor/charts"}, layer)
+
+	for _, value := range []string{"charts", "=vendor", "charts="} {
+		_, err = image.ParseNamedLayerPath(value)
+		require.ErrorContains(t, err, "Expected layer '"+value+"' to have the format name=path (example: charts=config/charts)")
+	}
+
+	_, err = image.ParseNamedLayerPath("charts=../charts")
+	require.ErrorContains(t, err, "Expected the path of layer 'charts=../charts' to be relative to the root of the image, and inside it")
+
+	_, err = image.ParseNamedLayerPath("metadata=.imgpkg")
+	require.ErrorContains(t, err, "Expected the path of layer 'metadata=.imgpkg' to not be in .imgpkg, the bundle metadata is kept in the first layer")
+}
+
+func TestLayerFilePart(t *testing.T) {
+	header := &tar.Header{Name: "big.bin", Typeflag: image.LayerPartTypeflag, Size: 10, PAXRecords: map[string]string{
+		image.LayerPartPAXRecord: image.LayerPartNext,
+	}}
+	position, isPart, err := image.LayerFilePart(header)
+	require.NoError(t, err)
+	require.True(t, isPart)
+	require.Equal(t, image.LayerPartNext, position)
+
+	// Only the entries with the type of the parts are parts, the regular files are extracted as they are
+	regular := *header
+	regular.Typeflag = tar.TypeReg
+	_, isPart, err = image.LayerFilePart(&regular)
+	require.NoError(t, err)
+	require.False(t, isPart)
+
+	missingRecord := *header
+	missingRecord.PAXRecords = nil
+	_, _, err = image.LayerFilePart(&missingRecord)
+	require.ErrorContains(t, err, "Expected PAX record IMGPKG.part of file 'big.bin' to be one of first, next or last, got ''")
+}
+
+// layerEntries Returns the names of the entries of the tarball of layer
+func layerEntries(t *testing.T, layer regv1.Layer) []string {
+	contents, err := layer.Uncompressed()
+	require.NoError(t, err)
+	defer contents.Close()
+
+	var names []string
+	tarReader := tar.NewReader(contents)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return names
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+}
+
+// layerTypeflags Returns the type of each entry of the tarball of layer
+func layerTypeflags(t *testing.T, layer regv1.Layer) map[string]byte {
+	contents, err := layer.Uncompressed()
+	require.NoError(t, err)
+	defer contents.Close()
+
+	typeflags := map[string]byte{}
+	tarReader := tar.NewReader(contents)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return typeflags
+		}
+		require.NoError(t, err)
+		typeflags[header.Name] = header.Typeflag
+	}
 }
 
 type testLogger struct{}
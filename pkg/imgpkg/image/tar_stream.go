@@ -0,0 +1,77 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTarStream Extracts the directories and regular files of the tar stream into dirPath, so that they can be pushed
+// like the files of a directory. The permissions of the entries are kept, the entries that are not directories or
+// regular files, or whose path is outside of dirPath, are rejected since they could not be pushed from a directory either
+func ExtractTarStream(stream io.Reader, dirPath string) error {
+	tarReader := tar.NewReader(stream)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("Reading tar stream: %s", err)
+		}
+
+		relPath := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("Expected tar entry '%s' to be relative to the root of the tar", header.Name)
+		}
+		path := filepath.Join(dirPath, relPath)
+		mode := header.FileInfo().Mode().Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0700)
+			if err != nil {
+				return err
+			}
+			err = os.Chmod(path, mode|0700)
+
+		case tar.TypeReg, tar.TypeRegA:
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err != nil {
+				return err
+			}
+			err = writeTarEntry(path, mode, tarReader)
+
+		default:
+			return fmt.Errorf("Expected tar entry '%s' to be a regular file or a directory", header.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("Extracting tar entry '%s': %s", header.Name, err)
+		}
+	}
+}
+
+func writeTarEntry(path string, mode os.FileMode, contents io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, contents)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	// the mode used to create the file is reduced by the umask
+	return os.Chmod(path, mode|0600)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTarStream(t *testing.T) {
+	buildTar := func(headers ...*tar.Header) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(buf)
+		for _, header := range headers {
+			require.NoError(t, tarWriter.WriteHeader(header))
+			if header.Typeflag == tar.TypeReg {
+				_, err := tarWriter.Write([]byte(header.Name))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tarWriter.Close())
+		return buf
+	}
+
+	t.Run("it extracts the directories and files of the stream", func(t *testing.T) {
+		dir := t.TempDir()
+		stream := buildTar(
+			&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "./.imgpkg/", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "./.imgpkg/images.yml", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len("./.imgpkg/images.yml"))},
+			// the directory of a file does not need its own entry
+			&tar.Header{Name: "config/run.sh", Typeflag: tar.TypeReg, Mode: 0750, Size: int64(len("config/run.sh"))},
+		)
+		require.NoError(t, image.ExtractTarStream(stream, dir))
+
+		contents, err := os.ReadFile(filepath.Join(dir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "./.imgpkg/images.yml", string(contents))
+
+		info, err := os.Stat(filepath.Join(dir, "config", "run.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+	})
+
+	t.Run("it rejects entries outside of the directory", func(t *testing.T) {
+		stream := buildTar(&tar.Header{Name: "../escaped.yml", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len("../escaped.yml"))})
+		err := image.ExtractTarStream(stream, t.TempDir())
+		require.ErrorContains(t, err, "Expected tar entry '../escaped.yml' to be relative to the root of the tar")
+	})
+
+	t.Run("it rejects entries that are not files or directories", func(t *testing.T) {
+		stream := buildTar(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"})
+		err := image.ExtractTarStream(stream, t.TempDir())
+		require.ErrorContains(t, err, "Expected tar entry 'link' to be a regular file or a directory")
+	})
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	// ZstdChunkedManifestPositionAnnotation annotation of zstd:chunked layers with the position of their table of
+	// contents in the blob, with the format offset:length:lengthUncompressed:type
+	ZstdChunkedManifestPositionAnnotation = "io.github.containers.zstd-chunked.manifest-position"
+	// ZstdChunkedManifestChecksumAnnotation annotation of zstd:chunked layers with the digest of their compressed table of contents
+	ZstdChunkedManifestChecksumAnnotation = "io.github.containers.zstd-chunked.manifest-checksum"
+
+	// zstdChunkedManifestTypeCRFS the only type of table of contents of zstd:chunked layers
+	zstdChunkedManifestTypeCRFS = "1"
+	// zstdChunkedTypeChunk entry with the following part of the contents of the previous file
+	zstdChunkedTypeChunk = "chunk"
+	// zstdChunkedChunkTypeZeros chunk of zeros that is not stored in the blob
+	zstdChunkedChunkTypeZeros = "zeros"
+)
+
+// BlobRangeReader Reads part of a blob of the registry
+type BlobRangeReader interface {
+	BlobRange(ref regname.Digest, offset, length int64) (io.ReadCloser, error)
+}
+
+// zstdChunkedTOC Table of contents of a zstd:chunked layer, with the position of the contents of each file in the blob
+type zstdChunkedTOC struct {
+	Version int                `json:"version"`
+	Entries []zstdChunkedEntry `json:"entries"`
+}
+
+type zstdChunkedEntry struct {
+	Type       string     `json:"type"`
+	Name       string     `json:"name,omitempty"`
+	Linkname   string     `json:"linkName,omitempty"`
+	Mode       int64      `json:"mode,omitempty"`
+	Size       int64      `json:"size,omitempty"`
+	UID        int        `json:"uid,omitempty"`
+	GID        int        `json:"gid,omitempty"`
+	ModTime    *time.Time `json:"modtime,omitempty"`
+	AccessTime *time.Time `json:"accesstime,omitempty"`
+	Digest     string     `json:"digest,omitempty"`
+	Offset     int64      `json:"offset,omitempty"`
+	EndOffset  int64      `json:"endOffset,omitempty"`
+	ChunkSize  int64      `json:"chunkSize,omitempty"`
+	ChunkType  string     `json:"chunkType,omitempty"`
+}
+
+// readZstdChunkedTOC Fetches and decompresses the table of contents at position, verifying it has the checksum when it is provided
+func readZstdChunkedTOC(blobs BlobRangeReader, layerRef regname.Digest, position string, checksum string) (zstdChunkedTOC, error) {
+	parts := strings.Split(position, ":")
+	if len(parts) != 4 {
+		return zstdChunkedTOC{}, fmt.Errorf("Expected annotation %s to have the format offset:length:lengthUncompressed:type, was '%s'", ZstdChunkedManifestPositionAnnotation, position)
+	}
+	var values [3]int64
+	for idx := range values {
+		value, err := strconv.ParseInt(parts[idx], 10, 64)
+		if err != nil || value < 0 {
+			return zstdChunkedTOC{}, fmt.Errorf("Expected annotation %s to have the format offset:length:lengthUncompressed:type, was '%s'", ZstdChunkedManifestPositionAnnotation, position)
+		}
+		values[idx] = value
+	}
+	if parts[3] != zstdChunkedManifestTypeCRFS {
+		return zstdChunkedTOC{}, fmt.Errorf("Unsupported zstd:chunked table of contents type '%s'", parts[3])
+	}
+	offset, length, lengthUncompressed := values[0], values[1], values[2]
+
+	body, err := blobs.BlobRange(layerRef, offset, length)
+	if err != nil {
+		return zstdChunkedTOC{}, err
+	}
+	defer body.Close()
+
+	compressed, err := io.ReadAll(io.LimitReader(body, length))
+	if err != nil {
+		return zstdChunkedTOC{}, fmt.Errorf("Reading table of contents: %s", err)
+	}
+	if checksum != "" {
+		expected, err := digest.Parse(checksum)
+		if err != nil {
+			return zstdChunkedTOC{}, fmt.Errorf("Parsing annotation %s: %s", ZstdChunkedManifestChecksumAnnotation, err)
+		}
+		if actual := expected.Algorithm().FromBytes(compressed); actual != expected {
+			return zstdChunkedTOC{}, fmt.Errorf("Expected table of contents to have digest %s, but was %s", expected, actual)
+		}
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return zstdChunkedTOC{}, err
+	}
+	defer decoder.Close()
+
+	uncompressed, err := decoder.DecodeAll(compressed, make([]byte, 0, lengthUncompressed))
+	if err != nil {
+		return zstdChunkedTOC{}, fmt.Errorf("Decompressing table of contents: %s", err)
+	}
+
+	var toc zstdChunkedTOC
+	if err := json.Unmarshal(uncompressed, &toc); err != nil {
+		return zstdChunkedTOC{}, fmt.Errorf("Unmarshaling table of contents: %s", err)
+	}
+	if toc.Version != 1 {
+		return zstdChunkedTOC{}, fmt.Errorf("Unsupported zstd:chunked table of contents version %d", toc.Version)
+	}
+	return toc, nil
+}
+
+// tarHeader Header of the file of the entry, as it is in the tar stream of the layer
+func (e zstdChunkedEntry) tarHeader() (*tar.Header, error) {
+	header := &tar.Header{
+		Name:     e.Name,
+		Linkname: e.Linkname,
+		Mode:     e.Mode,
+		Size:     e.Size,
+		Uid:      e.UID,
+		Gid:      e.GID,
+	}
+	if e.ModTime != nil {
+		header.ModTime = *e.ModTime
+	}
+	if e.AccessTime != nil {
+		header.AccessTime = *e.AccessTime
+	}
+
+	switch e.Type {
+	case "reg":
+		header.Typeflag = tar.TypeReg
+	case "dir":
+		header.Typeflag = tar.TypeDir
+	case "symlink":
+		header.Typeflag = tar.TypeSymlink
+	case "hardlink":
+		header.Typeflag = tar.TypeLink
+	case "char":
+		header.Typeflag = tar.TypeChar
+	case "block":
+		header.Typeflag = tar.TypeBlock
+	case "fifo":
+		header.Typeflag = tar.TypeFifo
+	default:
+		return nil, fmt.Errorf("Unsupported zstd:chunked entry type '%s' for file '%s'", e.Type, e.Name)
+	}
+	return header, nil
+}
+
+// zstdChunkedFileReader Reads the contents of a file of a zstd:chunked layer, fetching each chunk from the registry
+// only when the previous one was read, and verifying the contents have the digest of the file
+type zstdChunkedFileReader struct {
+	blobs    BlobRangeReader
+	layerRef regname.Digest
+	name     string
+	chunks   []zstdChunkedEntry
+
+	expected digest.Digest
+	hasher   hash.Hash
+
+	current io.Reader
+	closer  func() error
+}
+
+func newZstdChunkedFileReader(blobs BlobRangeReader, layerRef regname.Digest, chunks []zstdChunkedEntry) (*zstdChunkedFileReader, error) {
+	reader := &zstdChunkedFileReader{blobs: blobs, layerRef: layerRef, name: chunks[0].Name, chunks: chunks}
+	if chunks[0].Digest != "" {
+		expected, err := digest.Parse(chunks[0].Digest)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing digest of file '%s': %s", reader.name, err)
+		}
+		reader.expected = expected
+		reader.hasher = expected.Algorithm().Hash()
+	}
+	return reader, nil
+}
+
+// Read Reads the contents of the current chunk, and moves to the next one when it is done
+func (r *zstdChunkedFileReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, r.verify()
+			}
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if r.hasher != nil {
+			r.hasher.Write(p[:n])
+		}
+		if err == io.EOF {
+			if closeErr := r.Close(); closeErr != nil {
+				return n, closeErr
+			}
+			err = nil
+			if n == 0 {
+				continue
+			}
+		}
+		return n, err
+	}
+}
+
+// Close Releases the chunk being read
+func (r *zstdChunkedFileReader) Close() error {
+	r.current = nil
+	if r.closer == nil {
+		return nil
+	}
+	closer := r.closer
+	r.closer = nil
+	return closer()
+}
+
+func (r *zstdChunkedFileReader) next() error {
+	chunk := r.chunks[0]
+	r.chunks = r.chunks[1:]
+
+	if chunk.ChunkType == zstdChunkedChunkTypeZeros {
+		r.current = io.LimitReader(zeroReader{}, chunk.ChunkSize)
+		return nil
+	}
+	if chunk.EndOffset <= chunk.Offset {
+		return fmt.Errorf("Expected file '%s' to have its contents in the layer", r.name)
+	}
+
+	body, err := r.blobs.BlobRange(r.layerRef, chunk.Offset, chunk.EndOffset-chunk.Offset)
+	if err != nil {
+		return fmt.Errorf("Reading file '%s': %s", r.name, err)
+	}
+	decoder, err := zstd.NewReader(body)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("Decompressing file '%s': %s", r.name, err)
+	}
+	r.current = decoder
+	r.closer = func() error {
+		decoder.Close()
+		return body.Close()
+	}
+	return nil
+}
+
+func (r *zstdChunkedFileReader) verify() error {
+	if r.hasher == nil {
+		return io.EOF
+	}
+	if actual := digest.NewDigest(r.expected.Algorithm(), r.hasher); actual != r.expected {
+		return fmt.Errorf("Expected file '%s' to have digest %s, but was %s", r.name, r.expected, actual)
+	}
+	return io.EOF
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for idx := range p {
+		p[idx] = 0
+	}
+	return len(p), nil
+}
@@ -33,7 +33,7 @@ func (l DescribedCompressedLayer) Digest() (regv1.Hash, error) { return regv1.Ne
 // DiffID returns the DiffID of the layer
 func (l DescribedCompressedLayer) DiffID() (regv1.Hash, error) { return regv1.NewHash(l.desc.DiffID) }
 
-// Compressed returns a reader for the Layer anv validates the Digest of the layer matches
+// Compressed returns a reader for the Layer and validates the Digest and Size of the layer match
 func (l DescribedCompressedLayer) Compressed() (io.ReadCloser, error) {
 	rc, err := l.contents.Open()
 	if err != nil {
@@ -45,7 +45,12 @@ func (l DescribedCompressedLayer) Compressed() (io.ReadCloser, error) {
 		return nil, fmt.Errorf("Computing digest: %v", err)
 	}
 
-	rc, err = verify.ReadCloser(rc, verify.SizeUnknown, h)
+	size := int64(verify.SizeUnknown)
+	if l.desc.Size > 0 {
+		size = l.desc.Size
+	}
+
+	rc, err = verify.ReadCloser(rc, size, h)
 	if err != nil {
 		return nil, fmt.Errorf("Creating verified reader: %v", err)
 	}
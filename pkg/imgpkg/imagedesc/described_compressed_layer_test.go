@@ -0,0 +1,57 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagedesc_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bytesContents []byte
+
+func (b bytesContents) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func TestDescribedCompressedLayer_Compressed(t *testing.T) {
+	content := []byte("some layer content")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+
+	t.Run("when the content matches the digest and size, it is read", func(t *testing.T) {
+		layer := imagedesc.NewDescribedCompressedLayer(imagedesc.ImageLayerDescriptor{Digest: digest, Size: int64(len(content))}, bytesContents(content))
+
+		reader, err := layer.Compressed()
+		require.NoError(t, err)
+		read, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, content, read)
+	})
+
+	t.Run("when the content is smaller than the size of the layer, it fails", func(t *testing.T) {
+		layer := imagedesc.NewDescribedCompressedLayer(imagedesc.ImageLayerDescriptor{Digest: digest, Size: int64(len(content)) + 10}, bytesContents(content))
+
+		reader, err := layer.Compressed()
+		require.NoError(t, err)
+		_, err = io.ReadAll(reader)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("error verifying size; got %d, want %d", len(content), len(content)+10))
+	})
+
+	t.Run("when the content is bigger than the size of the layer, it fails", func(t *testing.T) {
+		layer := imagedesc.NewDescribedCompressedLayer(imagedesc.ImageLayerDescriptor{Digest: digest, Size: int64(len(content)) - 5}, bytesContents(content))
+
+		reader, err := layer.Compressed()
+		require.NoError(t, err)
+		_, err = io.ReadAll(reader)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error verifying")
+	})
+}
@@ -53,7 +53,7 @@ func (i DescribedImage) ConfigName() (regv1.Hash, error) {
 // ConfigFile returns this image's config file.
 func (i DescribedImage) ConfigFile() (*regv1.ConfigFile, error) {
 	var config *regv1.ConfigFile
-	err := json.Unmarshal([]byte(i.desc.Config.Raw), &config)
+	err := json.Unmarshal(i.desc.Config.Bytes(), &config)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +62,7 @@ func (i DescribedImage) ConfigFile() (*regv1.ConfigFile, error) {
 
 // RawConfigFile returns the serialized bytes of ConfigFile()
 func (i DescribedImage) RawConfigFile() ([]byte, error) {
-	return []byte(i.desc.Config.Raw), nil
+	return i.desc.Config.Bytes(), nil
 }
 
 // Digest returns the sha256 of this image's manifest.
@@ -193,10 +193,7 @@ func (ids *ImageRefDescriptors) buildImage(ref Metadata) (ImageDescriptor, error
 	td = ImageDescriptor{
 		Refs: []string{ref.Ref.String()},
 
-		Config: ConfigDescriptor{
-			Digest: cfgDigest.String(),
-			Raw:    string(cfgBlob),
-		},
+		Config: NewConfigDescriptor(cfgDigest.String(), cfgBlob),
 
 		Manifest: ManifestDescriptor{
 			MediaType: string(manifestMediaType),
@@ -0,0 +1,137 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagedesc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regv1types "github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// FilterPlatforms Removes from the image indexes the child manifests that do not match any of the platforms.
+// When children are removed the manifest of the index is rewritten to reference only the remaining ones, so its digest changes.
+// Images that are not part of an index are kept
+func (ids *ImageRefDescriptors) FilterPlatforms(platforms []regv1.Platform) error {
+	for idx, desc := range ids.descs {
+		if desc.ImageIndex == nil {
+			continue
+		}
+
+		filteredIndex, _, err := filterIndexPlatforms(*desc.ImageIndex, platforms)
+		if err != nil {
+			return err
+		}
+		ids.descs[idx].ImageIndex = &filteredIndex
+	}
+	return nil
+}
+
+// filterIndexPlatforms Returns the index with only the children matching the platforms, and the descriptor
+// that references the new index manifest
+func filterIndexPlatforms(index ImageIndexDescriptor, platforms []regv1.Platform) (ImageIndexDescriptor, regv1.Descriptor, error) {
+	manifest, err := regv1.ParseIndexManifest(strings.NewReader(index.Raw))
+	if err != nil {
+		return ImageIndexDescriptor{}, regv1.Descriptor{}, fmt.Errorf("Parsing image index %s: %s", index.Refs[0], err)
+	}
+
+	nestedIndexes := map[string]ImageIndexDescriptor{}
+	for _, nestedIndex := range index.Indexes {
+		nestedIndexes[nestedIndex.Digest] = nestedIndex
+	}
+
+	keptDigests := map[string]bool{}
+	var keptManifests []regv1.Descriptor
+	var filteredIndexes []ImageIndexDescriptor
+	var available []string
+	changed := false
+	for _, child := range manifest.Manifests {
+		if nestedIndex, found := nestedIndexes[child.Digest.String()]; found {
+			filteredNested, nestedDesc, err := filterIndexPlatforms(nestedIndex, platforms)
+			if err != nil {
+				// None of the children of the nested index match the platforms
+				changed = true
+				continue
+			}
+			if nestedDesc.Digest != child.Digest {
+				changed = true
+			}
+			nestedDesc.Annotations = child.Annotations
+			nestedDesc.Platform = child.Platform
+			keptManifests = append(keptManifests, nestedDesc)
+			filteredIndexes = append(filteredIndexes, filteredNested)
+			continue
+		}
+
+		if child.Platform != nil {
+			available = append(available, child.Platform.String())
+			if platformMatches(*child.Platform, platforms) {
+				keptManifests = append(keptManifests, child)
+				keptDigests[child.Digest.String()] = true
+				continue
+			}
+		}
+		changed = true
+	}
+
+	if len(keptManifests) == 0 {
+		var wanted []string
+		for _, platform := range platforms {
+			wanted = append(wanted, platform.String())
+		}
+		return ImageIndexDescriptor{}, regv1.Descriptor{}, fmt.Errorf("Unable to find platforms %s in image index %s (available platforms: %s)",
+			strings.Join(wanted, ", "), index.Refs[0], strings.Join(available, ", "))
+	}
+
+	if !changed {
+		digest, err := regv1.NewHash(index.Digest)
+		if err != nil {
+			return ImageIndexDescriptor{}, regv1.Descriptor{}, err
+		}
+		return index, regv1.Descriptor{MediaType: regv1types.MediaType(index.MediaType), Digest: digest, Size: int64(len(index.Raw))}, nil
+	}
+
+	var filteredImages []ImageDescriptor
+	for _, img := range index.Images {
+		if keptDigests[img.Manifest.Digest] {
+			filteredImages = append(filteredImages, img)
+		}
+	}
+
+	manifest.Manifests = keptManifests
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return ImageIndexDescriptor{}, regv1.Descriptor{}, fmt.Errorf("Marshaling image index %s: %s", index.Refs[0], err)
+	}
+	digest, size, err := regv1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return ImageIndexDescriptor{}, regv1.Descriptor{}, err
+	}
+
+	ref, err := regname.NewDigest(index.Refs[0])
+	if err != nil {
+		return ImageIndexDescriptor{}, regv1.Descriptor{}, fmt.Errorf("Parsing reference %s: %s", index.Refs[0], err)
+	}
+
+	index.Refs = []string{ref.Context().Digest(digest.String()).Name()}
+	index.Digest = digest.String()
+	index.Raw = string(raw)
+	index.Images = filteredImages
+	index.Indexes = filteredIndexes
+
+	return index, regv1.Descriptor{MediaType: regv1types.MediaType(index.MediaType), Digest: digest, Size: size}, nil
+}
+
+func platformMatches(platform regv1.Platform, wanted []regv1.Platform) bool {
+	for _, wantedPlatform := range wanted {
+		if platform.Satisfies(wantedPlatform) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ package imagedesc
 
 import (
 	"io"
+	"unicode/utf8"
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	regv1types "github.com/google/go-containerregistry/pkg/v1/types"
@@ -78,6 +79,25 @@ type ImageLayerDescriptor struct {
 type ConfigDescriptor struct {
 	Digest string
 	Raw    string
+	// RawBytes config, instead of Raw, when it is not valid UTF-8 and would not be kept byte-for-byte
+	// as a JSON string, like the configs of some ORAS artifacts
+	RawBytes []byte `json:",omitempty"`
+}
+
+// NewConfigDescriptor Describes the config with digest, keeping its contents byte-for-byte
+func NewConfigDescriptor(digest string, raw []byte) ConfigDescriptor {
+	if !utf8.Valid(raw) {
+		return ConfigDescriptor{Digest: digest, RawBytes: raw}
+	}
+	return ConfigDescriptor{Digest: digest, Raw: string(raw)}
+}
+
+// Bytes Returns the contents of the config
+func (td ConfigDescriptor) Bytes() []byte {
+	if td.RawBytes != nil {
+		return td.RawBytes
+	}
+	return []byte(td.Raw)
 }
 
 type ManifestDescriptor struct {
@@ -0,0 +1,122 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"strings"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+)
+
+const (
+	// balancerInterval time between two adjustments of the number of images copied at the same time
+	balancerInterval = 2 * time.Second
+	// balancerTolerance relative change of the throughput that is considered noise
+	balancerTolerance = 0.05
+)
+
+// concurrencyBalancer Adjusts the number of images copied at the same time when the images are streamed
+// from a source host to a different destination host. It starts with half of the concurrency, workers are added,
+// up to the concurrency, for as long as the throughput of both hosts together keeps improving, removed when adding
+// the last one made it worse, and halved when a host answers that it receives too many requests
+type concurrencyBalancer struct {
+	meter    *registry.TrafficMeter
+	hosts    []string
+	throttle *util.AdaptiveThrottle
+	min      int
+	start    int
+	max      int
+	logger   Logger
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+
+	lastBytes     int64
+	lastThrottled int64
+	lastRate      float64
+	lastIncreased bool
+}
+
+// newConcurrencyBalancer Creates a balancer that adjusts throttle, starting at half of concurrency, up to concurrency
+func newConcurrencyBalancer(meter *registry.TrafficMeter, hosts []string, throttle *util.AdaptiveThrottle, concurrency int, logger Logger) *concurrencyBalancer {
+	return &concurrencyBalancer{
+		meter:    meter,
+		hosts:    hosts,
+		throttle: throttle,
+		min:      1,
+		start:    max((concurrency+1)/2, 1),
+		max:      max(concurrency, 1),
+		logger:   logger,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start Adjusts the throttle periodically until Stop is called
+func (b *concurrencyBalancer) Start() {
+	b.throttle.SetMax(b.start)
+	b.lastBytes, b.lastThrottled = b.totals()
+	go func() {
+		defer close(b.doneCh)
+		ticker := time.NewTicker(balancerInterval)
+		defer ticker.Stop()
+
+		lastTick := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				b.adjust(now.Sub(lastTick))
+				lastTick = now
+			case <-b.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop Stops adjusting the throttle
+func (b *concurrencyBalancer) Stop() {
+	close(b.stopCh)
+	<-b.doneCh
+}
+
+func (b *concurrencyBalancer) adjust(elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	bytes, throttled := b.totals()
+	rate := float64(bytes-b.lastBytes) / elapsed.Seconds()
+
+	current := b.throttle.Max()
+	next := current
+	switch {
+	case throttled > b.lastThrottled:
+		next = current / 2
+		b.logger.Logf("requests are being throttled while copying between %s, copying up to %d images at a time\n", strings.Join(b.hosts, ", "), max(next, b.min))
+	case b.lastIncreased && rate < b.lastRate*(1-balancerTolerance):
+		next = current - 1
+	case b.throttle.InUse() >= current && rate > b.lastRate*(1+balancerTolerance):
+		next = current + 1
+	}
+	next = min(max(next, b.min), b.max)
+
+	if next != current {
+		b.throttle.SetMax(next)
+	}
+	b.lastIncreased = next > current
+	b.lastBytes, b.lastThrottled, b.lastRate = bytes, throttled, rate
+}
+
+// totals Returns the bytes transferred and the number of throttled requests so far. Every host is included,
+// registries often redirect blob downloads to a storage service on another host
+func (b *concurrencyBalancer) totals() (int64, int64) {
+	var bytes, throttled int64
+	for _, traffic := range b.meter.Hosts() {
+		bytes += traffic.BytesSent + traffic.BytesReceived
+		throttled += traffic.ThrottledRequests
+	}
+	return bytes, throttled
+}
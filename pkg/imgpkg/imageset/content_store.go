@@ -0,0 +1,90 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// ContentSource Location the images being copied are read from, like a registry, a tarball or an OCI image layout
+type ContentSource interface {
+	// Read Returns the images and image indexes available in the source
+	Read(registry registry.ImagesReaderWriter) (Content, error)
+}
+
+// ContentDestination Location the images being copied are written to, like a repository, a tarball or an OCI image layout
+type ContentDestination interface {
+	// Write Stores the content in the destination
+	Write(content Content, registry registry.ImagesReaderWriter) (WrittenContent, error)
+}
+
+// RegistryContentDestination ContentDestination in a registry, that knows the repositories the images are written to
+type RegistryContentDestination interface {
+	ContentDestination
+	// Repositories Returns the repositories the images of refs are written to
+	Repositories(refs *UnprocessedImageRefs) ([]regname.Repository, error)
+}
+
+// Content Images and image indexes read from a ContentSource.
+// Sources that can be read through the registry only list the references of the images, so that the destination
+// decides what needs to be fetched, while the other sources provide the images themselves
+type Content struct {
+	refs         *UnprocessedImageRefs
+	imgOrIndexes []imagedesc.ImageOrIndex
+}
+
+// NewContentFromRefs Creates Content for images that can be fetched from the registry
+func NewContentFromRefs(refs *UnprocessedImageRefs) Content {
+	return Content{refs: refs}
+}
+
+// NewContentFromImages Creates Content for images that were already read from the source
+func NewContentFromImages(imgOrIndexes []imagedesc.ImageOrIndex) Content {
+	return Content{imgOrIndexes: imgOrIndexes}
+}
+
+// Refs Returns the references of the images, false when the content was not created from references
+func (c Content) Refs() (*UnprocessedImageRefs, bool) {
+	return c.refs, c.refs != nil
+}
+
+// ImagesOrIndexes Returns the images and image indexes, false when the content was created from references
+func (c Content) ImagesOrIndexes() ([]imagedesc.ImageOrIndex, bool) {
+	return c.imgOrIndexes, c.refs == nil
+}
+
+// WrittenContent Result of writing Content to a ContentDestination.
+// Destinations in a registry report where each image was written to,
+// while destinations on disk report the descriptors of the images they contain
+type WrittenContent struct {
+	ProcessedImages *ProcessedImages
+	Descriptors     *imagedesc.ImageRefDescriptors
+}
+
+// Copy Reads the images from the source and writes them to the destination
+func Copy(source ContentSource, destination ContentDestination, registry registry.ImagesReaderWriter) (WrittenContent, error) {
+	content, err := source.Read(registry)
+	if err != nil {
+		return WrittenContent{}, err
+	}
+
+	return destination.Write(content, registry)
+}
+
+// RegistrySource Images that are read from a registry
+type RegistrySource struct {
+	refs *UnprocessedImageRefs
+}
+
+// NewRegistrySource Creates a ContentSource for the images in the registry
+func NewRegistrySource(refs *UnprocessedImageRefs) RegistrySource {
+	return RegistrySource{refs}
+}
+
+// Read Returns the references of the images
+func (s RegistrySource) Read(_ registry.ImagesReaderWriter) (Content, error) {
+	return NewContentFromRefs(s.refs), nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// CopyObserver Receives the progress of each one of the images imported into a repository
+type CopyObserver interface {
+	ImagesFound(total int)
+	ImageStarted(ref string)
+	ImageProgress(ref string, complete, total int64)
+	ImageFinished(ref string, err error)
+}
+
+// writeObserved writes a single image or index reporting its upload progress to the observers
+func (i *ImageSet) writeObserved(ref string, tag regname.Tag, taggable regremote.Taggable, registry registry.ImagesReaderWriter) error {
+	if len(i.observers) == 0 {
+		return i.writeImage(tag, taggable, registry, nil)
+	}
+
+	updatesCh := make(chan regv1.Update)
+	doneCh := make(chan struct{})
+	readerDoneCh := make(chan struct{})
+	go func() {
+		defer close(readerDoneCh)
+		for {
+			select {
+			case update, ok := <-updatesCh:
+				if !ok {
+					return
+				}
+				if update.Error != nil {
+					continue
+				}
+				for _, observer := range i.observers {
+					observer.ImageProgress(ref, update.Complete, update.Total)
+				}
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	err := i.writeImage(tag, taggable, registry, updatesCh)
+	// The registry does not close the channel when it fails before starting the upload
+	close(doneCh)
+	<-readerDoneCh
+	return err
+}
+
+// finishObserved reports to the observers that the image is no longer being written
+func (i *ImageSet) finishObserved(ref string, err error) {
+	for _, observer := range i.observers {
+		observer.ImageFinished(ref, err)
+	}
+}
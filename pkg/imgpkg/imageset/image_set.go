@@ -4,11 +4,18 @@
 package imageset
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedigest"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
 	regname "github.com/google/go-containerregistry/pkg/name"
@@ -21,28 +28,144 @@ type Logger interface {
 }
 
 type ImageSet struct {
-	concurrency int
-	logger      Logger
-	tagGen      util.TagGenerator
+	concurrency     int
+	logger          Logger
+	tagGen          util.TagGenerator
+	mediaTypePolicy MediaTypePolicy
+	platforms       []regv1.Platform
+	journalDir      string
+	resume          bool
+	stateFile       string
+	copyOptions     journal.Options
+	incremental     bool
+	observers       []CopyObserver
+	onProcessed     serializedHandler
+	recompression   Recompression
+	trafficMeter    *registry.TrafficMeter
+	sourceHosts     []string
+	imageTimeout    time.Duration
 }
 
 // NewImageSet constructor for creating an ImageSet
 func NewImageSet(concurrency int, logger Logger, tagGen util.TagGenerator) ImageSet {
-	return ImageSet{concurrency, logger, tagGen}
+	return ImageSet{concurrency: concurrency, logger: logger, tagGen: tagGen}
+}
+
+// WithMediaTypePolicy Returns a copy of the ImageSet that only exports images that comply with the policy
+func (i ImageSet) WithMediaTypePolicy(policy MediaTypePolicy) ImageSet {
+	i.mediaTypePolicy = policy
+	return i
+}
+
+// WithPlatforms Returns a copy of the ImageSet that only exports, from the image indexes, the child manifests of these platforms.
+// The indexes are rewritten to reference only the exported children
+func (i ImageSet) WithPlatforms(platforms []regv1.Platform) ImageSet {
+	i.platforms = platforms
+	return i
+}
+
+// WithJournal Returns a copy of the ImageSet that records, in a journal inside dir, every image imported into a repository.
+// When resume is true, images already recorded in the journal and still present in the repository are not written again
+func (i ImageSet) WithJournal(dir string, resume bool) ImageSet {
+	i.journalDir = dir
+	i.resume = resume
+	return i
+}
+
+// WithStateFile Returns a copy of the ImageSet that records every image imported into a repository in the journal at path,
+// instead of the journal folder, and resumes from it when it already exists. The state file is kept after each import,
+// so that imports into several repositories share it, and should be removed by the caller once the whole copy succeeded
+func (i ImageSet) WithStateFile(path string) ImageSet {
+	i.stateFile = path
+	return i
+}
+
+// WithCopyOptions Returns a copy of the ImageSet that records the imgpkg version and options of the copy in journals and tars,
+// so that a copy is only resumed when its options are compatible with the ones of the interrupted copy
+func (i ImageSet) WithCopyOptions(options journal.Options) ImageSet {
+	i.copyOptions = options
+	return i
+}
+
+// WithIncremental Returns a copy of the ImageSet that, when incremental is true, checks the destination repository
+// before copying and skips the images that are already present in it
+func (i ImageSet) WithIncremental(incremental bool) ImageSet {
+	i.incremental = incremental
+	return i
+}
+
+// WithObserver Returns a copy of the ImageSet that writes each image on its own and also reports its progress to the observer,
+// instead of writing all the images together
+func (i ImageSet) WithObserver(observer CopyObserver) ImageSet {
+	i.observers = append(append([]CopyObserver{}, i.observers...), observer)
+	return i
+}
+
+// WithProcessedImageHandler Returns a copy of the ImageSet that calls handler with each image imported into a repository
+// as soon as it is verified in the repository, or found already present in it, while the other images are still being copied.
+// The calls are serialized, handler does not need to be safe for concurrent use
+func (i ImageSet) WithProcessedImageHandler(handler ProcessedImageHandler) ImageSet {
+	i.onProcessed = serializedHandler{handler: handler, lock: &sync.Mutex{}}
+	return i
+}
+
+// WithRecompression Returns a copy of the ImageSet that converts the gzip layers of the images imported into a repository
+// to the recompression, changing the digests of the images. The original digests are recorded in annotations
+func (i ImageSet) WithRecompression(recompression Recompression) ImageSet {
+	i.recompression = recompression
+	return i
+}
+
+// WithConcurrencyBalancing Returns a copy of the ImageSet that, when relocating images from other registry hosts than the destination,
+// adjusts the number of images copied at the same time, never above the concurrency, to the throughput measured by meter.
+// meter has to measure the traffic of the registry used to relocate the images
+func (i ImageSet) WithConcurrencyBalancing(meter *registry.TrafficMeter) ImageSet {
+	i.trafficMeter = meter
+	return i
 }
 
 func (i ImageSet) Relocate(foundImages *UnprocessedImageRefs,
 	importRepo regname.Repository, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
+	var presentImages []ProcessedImage
+	if i.incremental {
+		var err error
+		foundImages, presentImages, err = i.skipPresentImages(foundImages, importRepo, registry)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range presentImages {
+			i.onProcessed.handle(img)
+		}
+		// Images that are missing were already checked, there is no need to check them again while importing
+		i.incremental = false
+	}
+
 	ids, err := i.Export(foundImages, registry)
 	if err != nil {
 		return nil, err
 	}
 
+	// Images are streamed from their registry to importRepo, the hosts on both sides are involved in every copy
+	i.sourceHosts, err = registryHosts(foundImages)
+	if err != nil {
+		return nil, err
+	}
+
 	imgOrIndexes := imagedesc.NewDescribedReader(ids, ids).Read()
 
 	images, err := i.Import(imgOrIndexes, importRepo, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	if presentImages != nil {
+		for _, img := range presentImages {
+			images.Add(img)
+		}
+		i.logger.Logf("skipped %d images already present in %s, copied %d images\n", len(presentImages), importRepo.Name(), len(imgOrIndexes))
+	}
 
-	return images, err
+	return images, nil
 }
 
 func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
@@ -68,6 +191,18 @@ func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
 		return nil, fmt.Errorf("Collecting packaging metadata: %s", err)
 	}
 
+	if len(i.platforms) > 0 {
+		err = ids.FilterPlatforms(i.platforms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = i.mediaTypePolicy.Check(imagedesc.NewDescribedReader(ids, ids).Read(), i.logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return ids, nil
 }
 
@@ -77,20 +212,106 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 	importedImages := NewProcessedImages()
 
 	i.logger.Logf("importing %d images...\n", len(imgOrIndexes))
+	for _, observer := range i.observers {
+		observer.ImagesFound(len(imgOrIndexes))
+	}
+
+	copyJournal, err := i.openJournal(imgOrIndexes, importRepo)
+	if err != nil {
+		return nil, err
+	}
+	if copyJournal != nil {
+		defer copyJournal.Close()
+	}
 
-	importThrottle := util.NewThrottle(i.concurrency)
+	// Items are replaced by their recompressed version before being written, so that they are verified with the new digests
+	items := append([]imagedesc.ImageOrIndex{}, imgOrIndexes...)
+	var layersRecompressor *recompressor
+	if i.recompression == RecompressionZstd {
+		layersRecompressor, err = newRecompressor(i.logger)
+		if err != nil {
+			return nil, err
+		}
+		defer layersRecompressor.Cleanup()
+	}
+
+	importThrottle := util.NewAdaptiveThrottle(i.concurrency)
+	balancer := i.concurrencyBalancer(importRepo, importThrottle)
+	if balancer != nil {
+		balancer.Start()
+		defer balancer.Stop()
+	}
 
 	imageOrIndexesToWrite := map[regname.Reference]regremote.Taggable{}
 	var imageOrIndexesToWriteLock = &sync.Mutex{}
+	var skippedImages int
+	// Images written on their own are verified as soon as they are written, the others after all of them are written
+	verified := make([]bool, len(imgOrIndexes))
+	processed := func(idx int, processedImage ProcessedImage) {
+		verified[idx] = true
+		importedImages.Add(processedImage)
+		i.onProcessed.handle(processedImage)
+	}
 	errCh := make(chan error, len(imgOrIndexes))
-	for _, item := range imgOrIndexes {
-		item := item // copy
+	for idx, item := range imgOrIndexes {
+		idx, item := idx, item // copy
 
 		go func() {
 			importThrottle.Take()
 			defer importThrottle.Done()
+			for _, observer := range i.observers {
+				observer.ImageStarted(item.Ref())
+			}
+			if layersRecompressor != nil {
+				recompressedItem, err := layersRecompressor.Recompress(item)
+				if err != nil {
+					i.finishObserved(item.Ref(), err)
+					errCh <- err
+					return
+				}
+				item = recompressedItem
+				items[idx] = item
+			}
+			if i.incremental {
+				if processedImage, err := i.verifyImageOrIndex(item, importRepo, registry); err == nil {
+					i.logger.Logf("skipping %s, already present in %s\n", item.Ref(), importRepo.Name())
+					imageOrIndexesToWriteLock.Lock()
+					skippedImages++
+					imageOrIndexesToWriteLock.Unlock()
+					processed(idx, processedImage)
+					i.finishObserved(item.Ref(), nil)
+					errCh <- nil
+					return
+				}
+			}
+
 			tag, taggable, err := i.getImageOrImageIndexForMultiWrite(item, importRepo, registry)
 			if err != nil {
+				i.finishObserved(item.Ref(), err)
+				errCh <- err
+				return
+			}
+			if copyJournal != nil {
+				processedImage, err := i.writeAndRecord(item, tag, taggable, importRepo, registry, copyJournal)
+				if err == nil {
+					processed(idx, processedImage)
+				}
+				i.finishObserved(item.Ref(), err)
+				errCh <- err
+				return
+			}
+			// Images are written one by one when the throttle is balanced, so that it limits the images being written,
+			// and when they have a timeout
+			if len(i.observers) > 0 || balancer != nil || i.imageTimeout > 0 {
+				err = i.writeObserved(item.Ref(), tag, taggable, registry)
+				if err == nil {
+					var processedImage ProcessedImage
+					processedImage, err = i.verifyImageOrIndex(item, importRepo, registry)
+					if err == nil {
+						processed(idx, processedImage)
+					}
+				}
+				i.finishObserved(item.Ref(), err)
 				errCh <- err
 				return
 			}
@@ -102,27 +323,39 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 		}()
 	}
 
-	err := checkForAnyAsyncErrors(imgOrIndexes, errCh)
+	if copyJournal != nil || len(i.observers) > 0 || balancer != nil || i.imageTimeout > 0 {
+		// Wait for every write to finish, so that all the images copied are recorded in the journal
+		// and the observers know the outcome of each one of them
+		err = waitForAllAsyncErrors(imgOrIndexes, errCh)
+	} else {
+		err = checkForAnyAsyncErrors(imgOrIndexes, errCh)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = registry.MultiWrite(imageOrIndexesToWrite, i.concurrency, nil)
-	if err != nil {
-		return nil, err
+	if len(imageOrIndexesToWrite) > 0 {
+		err = registry.MultiWrite(imageOrIndexesToWrite, i.concurrency, nil)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	errChVerifyImages := make(chan error, len(imgOrIndexes))
-	for _, item := range imgOrIndexes {
-		item := item // copy
+	for idx, item := range items {
+		idx, item := idx, item // copy
 
 		go func() {
+			if verified[idx] {
+				errChVerifyImages <- nil
+				return
+			}
 			importThrottle.Take()
 			defer importThrottle.Done()
 
 			processedImage, err := i.verifyImageOrIndex(item, importRepo, registry)
 			if err == nil {
-				importedImages.Add(processedImage)
+				processed(idx, processedImage)
 			}
 			errChVerifyImages <- err
 		}()
@@ -133,9 +366,247 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 		return nil, err
 	}
 
+	if copyJournal != nil && i.stateFile == "" {
+		err = copyJournal.Remove()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if i.incremental {
+		i.logger.Logf("skipped %d images already present in %s, copied %d images\n", skippedImages, importRepo.Name(), len(imgOrIndexes)-skippedImages)
+	}
+
 	return importedImages, nil
 }
 
+// concurrencyBalancer Returns the balancer of throttle when the images are relocated from other registry hosts than
+// the one of importRepo and the traffic is measured, nil otherwise
+func (i *ImageSet) concurrencyBalancer(importRepo regname.Repository, throttle *util.AdaptiveThrottle) *concurrencyBalancer {
+	if i.trafficMeter == nil {
+		return nil
+	}
+
+	var hosts []string
+	for _, host := range i.sourceHosts {
+		if host != importRepo.RegistryStr() {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return newConcurrencyBalancer(i.trafficMeter, append(hosts, importRepo.RegistryStr()), throttle, i.concurrency, i.logger)
+}
+
+// registryHosts Returns the registry hosts of the images, sorted
+func registryHosts(images *UnprocessedImageRefs) ([]string, error) {
+	found := map[string]bool{}
+	var hosts []string
+	for _, img := range images.All() {
+		ref, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return nil, err
+		}
+		if !found[ref.Context().RegistryStr()] {
+			found[ref.Context().RegistryStr()] = true
+			hosts = append(hosts, ref.Context().RegistryStr())
+		}
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// importImages Checks the media types of the images that were read from disk before importing them
+func (i *ImageSet) importImages(imgOrIndexes []imagedesc.ImageOrIndex,
+	importRepo regname.Repository, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
+	err := i.mediaTypePolicy.Check(imgOrIndexes, i.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.Import(imgOrIndexes, importRepo, registry)
+}
+
+// skipPresentImages splits foundImages in the images that are missing from importRepo and the ones that are already present.
+// An image is present when the tag that imgpkg uses to upload it points to the same digest
+func (i ImageSet) skipPresentImages(foundImages *UnprocessedImageRefs, importRepo regname.Repository,
+	registry registry.ImagesReaderWriter) (*UnprocessedImageRefs, []ProcessedImage, error) {
+	missingImages := NewUnprocessedImageRefs()
+	presentImages := []ProcessedImage{}
+	lock := &sync.Mutex{}
+
+	throttle := util.NewThrottle(i.concurrency)
+	allImages := foundImages.All()
+	errCh := make(chan error, len(allImages))
+	for _, img := range allImages {
+		img := img // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			processedImage, present, err := i.presentImage(img, importRepo, registry)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if present {
+				i.logger.Logf("skipping %s, already present in %s\n", img.DigestRef, importRepo.Name())
+				presentImages = append(presentImages, processedImage)
+			} else {
+				missingImages.Add(img)
+			}
+			errCh <- nil
+		}()
+	}
+
+	for range allImages {
+		if err := <-errCh; err != nil {
+			return nil, nil, err
+		}
+	}
+	return missingImages, presentImages, nil
+}
+
+// presentImage returns the ProcessedImage, backed by the destination registry, when img is already present in importRepo
+func (i ImageSet) presentImage(img UnprocessedImageRef, importRepo regname.Repository, registry registry.ImagesReaderWriter) (ProcessedImage, bool, error) {
+	srcRef, err := regname.NewDigest(img.DigestRef)
+	if err != nil {
+		return ProcessedImage{}, false, err
+	}
+
+	digestWrap := imagedigest.DigestWrap{}
+	err = digestWrap.DigestWrap(img.DigestRef, img.OrigRef)
+	if err != nil {
+		return ProcessedImage{}, false, err
+	}
+	uploadTagRef, err := i.tagGen.GenerateTag(digestWrap, importRepo)
+	if err != nil {
+		return ProcessedImage{}, false, err
+	}
+
+	// Any failure to find the image in the destination is handled by copying it again
+	dstDigest, err := registry.Digest(uploadTagRef)
+	if err != nil || dstDigest.String() != srcRef.DigestStr() {
+		return ProcessedImage{}, false, nil
+	}
+
+	importDigestRef := importRepo.Digest(dstDigest.String())
+	desc, err := registry.Get(importDigestRef)
+	if err != nil {
+		return ProcessedImage{}, false, nil
+	}
+
+	processedImage := ProcessedImage{UnprocessedImageRef: img, DigestRef: importDigestRef.Name()}
+	if desc.MediaType.IsIndex() {
+		processedImage.ImageIndex, err = desc.ImageIndex()
+	} else {
+		processedImage.Image, err = desc.Image()
+	}
+	if err != nil {
+		return ProcessedImage{}, false, nil
+	}
+	return processedImage, true, nil
+}
+
+// openJournal returns the journal of copies of imgOrIndexes into importRepo, discarding the images recorded by an
+// interrupted copy when the copy is not resumed. Without a journal folder, see WithJournal, no journal is kept.
+// When the journal folder cannot be written, or when the journal is used by another copy of the same images that is
+// not being resumed, the copy continues without a journal, it just cannot be resumed
+func (i *ImageSet) openJournal(imgOrIndexes []imagedesc.ImageOrIndex, importRepo regname.Repository) (*journal.Journal, error) {
+	if i.stateFile != "" {
+		copyJournal, err := journal.Open(i.stateFile)
+		if err != nil {
+			return nil, err
+		}
+		err = copyJournal.CheckOptions(i.copyOptions)
+		if err != nil {
+			copyJournal.Close()
+			return nil, fmt.Errorf("Resuming from state file '%s': %s", i.stateFile, err)
+		}
+		return copyJournal, nil
+	}
+	if i.journalDir == "" {
+		return nil, nil
+	}
+
+	copyJournal, err := journal.Open(filepath.Join(i.journalDir, journalName(imgOrIndexes, importRepo)))
+	if err != nil {
+		// Copies of the same images write the same content, so they can run at the same time without sharing the journal
+		if _, locked := err.(journal.LockedError); locked && i.resume {
+			return nil, err
+		}
+		i.logger.Logf("Warning: %s, the copy will not be resumable\n", err)
+		return nil, nil
+	}
+
+	if !i.resume {
+		err = copyJournal.Reset()
+		if err != nil {
+			copyJournal.Close()
+			return nil, err
+		}
+	}
+
+	err = copyJournal.CheckOptions(i.copyOptions)
+	if err != nil {
+		copyJournal.Close()
+		return nil, err
+	}
+	return copyJournal, nil
+}
+
+// journalName name of the journal of copies of imgOrIndexes into importRepo, copies of other images into the same
+// repository do not share it
+func journalName(imgOrIndexes []imagedesc.ImageOrIndex, importRepo regname.Repository) string {
+	var refs []string
+	for _, item := range imgOrIndexes {
+		refs = append(refs, item.Ref())
+	}
+	sort.Strings(refs)
+
+	copySha := sha256.Sum256([]byte(strings.Join(append(refs, importRepo.Name()), "\n")))
+	return hex.EncodeToString(copySha[:]) + ".journal"
+}
+
+// writeAndRecord writes a single image or index and records it in the journal once the registry confirms it is present.
+// Images that a previous copy recorded are only verified. Returns the image as processed
+func (i *ImageSet) writeAndRecord(item imagedesc.ImageOrIndex, tag regname.Tag, taggable regremote.Taggable,
+	importRepo regname.Repository, registry registry.ImagesReaderWriter, copyJournal *journal.Journal) (ProcessedImage, error) {
+	itemDigest, err := item.Digest()
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	if entry, found := copyJournal.Find(journal.ImageKind, itemDigest.String()); found && entry.Ref == tag.Name() {
+		processedImage, err := i.verifyImageOrIndex(item, importRepo, registry)
+		if err == nil {
+			i.logger.Logf("skipping %s, already copied\n", tag.Name())
+			return processedImage, nil
+		}
+	}
+
+	err = i.writeObserved(item.Ref(), tag, taggable, registry)
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	processedImage, err := i.verifyImageOrIndex(item, importRepo, registry)
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	err = copyJournal.Append(journal.Entry{Kind: journal.ImageKind, Digest: itemDigest.String(), Ref: tag.Name()})
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+	return processedImage, nil
+}
+
 func checkForAnyAsyncErrors(imgOrIndexes []imagedesc.ImageOrIndex, errCh chan error) error {
 	for i := 0; i < len(imgOrIndexes); i++ {
 		err := <-errCh
@@ -146,6 +617,17 @@ func checkForAnyAsyncErrors(imgOrIndexes []imagedesc.ImageOrIndex, errCh chan er
 	return nil
 }
 
+func waitForAllAsyncErrors(imgOrIndexes []imagedesc.ImageOrIndex, errCh chan error) error {
+	var firstErr error
+	for i := 0; i < len(imgOrIndexes); i++ {
+		err := <-errCh
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 func (i ImageSet) getImageOrImageIndexForMultiWrite(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry registry.ImagesReaderWriter) (regname.Tag, regremote.Taggable, error) {
 	digestWrap := imagedigest.DigestWrap{}
 	err := digestWrap.DigestWrap(item.Ref(), item.OrigRef)
@@ -166,7 +648,10 @@ func (i ImageSet) getImageOrImageIndexForMultiWrite(item imagedesc.ImageOrIndex,
 			return regname.Tag{}, nil, err
 		}
 	case item.Index != nil:
-		artifactToWrite = *item.Index
+		artifactToWrite, err = i.mountableIndex(*item.Index, uploadTagRef, registry)
+		if err != nil {
+			return regname.Tag{}, nil, err
+		}
 	default:
 		panic("Unknown item")
 	}
@@ -175,25 +660,64 @@ func (i ImageSet) getImageOrImageIndexForMultiWrite(item imagedesc.ImageOrIndex,
 }
 
 func (i ImageSet) mountableImage(imageWithRef imagedesc.ImageWithRef, uploadTagRef regname.Tag, registry registry.ImagesReaderWriter) (regremote.Taggable, error) {
-	itemRef, err := regname.NewDigest(imageWithRef.Ref())
+	digest, err := imageWithRef.Digest()
+	if err != nil {
+		return regv1.Image(imageWithRef), nil
+	}
+	descriptor, err := mountableDescriptor(imageWithRef.Ref(), digest, uploadTagRef, registry)
+	if err != nil || descriptor == nil {
+		return regv1.Image(imageWithRef), err
+	}
+	artifactToWrite, err := descriptor.Image()
 	if err != nil {
-		return nil, fmt.Errorf("Unable to parse reference: %s: %s", imageWithRef.Ref(), err)
+		// If a performance improvement cannot be done, fallback to the 'non-performant' way
+		return regv1.Image(imageWithRef), nil
 	}
+	return artifactToWrite, nil
+}
 
-	if imageBlobsCanBeMounted(itemRef, uploadTagRef, registry) {
-		descriptor, err := registry.Get(itemRef)
-		if err != nil {
-			// If a performance improvement cannot be done, fallback to the 'non-performant' way
-			return regv1.Image(imageWithRef), nil
-		}
-		artifactToWrite, err := descriptor.Image()
-		if err != nil {
-			// If a performance improvement cannot be done, fallback to the 'non-performant' way
-			return regv1.Image(imageWithRef), nil
-		}
-		return artifactToWrite, nil
+// mountableIndex Returns the index read from the source when its blobs can be mounted in the destination, so that
+// the layers of the images of each platform are mounted from the source repository instead of being uploaded again
+func (i ImageSet) mountableIndex(indexWithRef imagedesc.ImageIndexWithRef, uploadTagRef regname.Tag, registry registry.ImagesReaderWriter) (regremote.Taggable, error) {
+	digest, err := indexWithRef.Digest()
+	if err != nil {
+		return regv1.ImageIndex(indexWithRef), nil
+	}
+	descriptor, err := mountableDescriptor(indexWithRef.Ref(), digest, uploadTagRef, registry)
+	if err != nil || descriptor == nil {
+		return regv1.ImageIndex(indexWithRef), err
+	}
+	artifactToWrite, err := descriptor.ImageIndex()
+	if err != nil {
+		// If a performance improvement cannot be done, fallback to the 'non-performant' way
+		return regv1.ImageIndex(indexWithRef), nil
+	}
+	return artifactToWrite, nil
+}
+
+// mountableDescriptor Returns the descriptor of ref, read from the source, when the blobs it references can be mounted
+// in the repository of uploadTagRef, nil when they cannot. The layers read through it are mounted by the registry
+// instead of being uploaded again
+func mountableDescriptor(ref string, digest regv1.Hash, uploadTagRef regname.Tag, registry registry.ImagesReaderWriter) (*regremote.Descriptor, error) {
+	itemRef, err := regname.NewDigest(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse reference: %s: %s", ref, err)
+	}
+
+	// Recompressed images, and indexes without some platforms, are not the ones in the source, so they cannot be read from it
+	if digest.String() != itemRef.DigestStr() {
+		return nil, nil
+	}
+
+	if !imageBlobsCanBeMounted(itemRef, uploadTagRef, registry) {
+		return nil, nil
+	}
+	descriptor, err := registry.Get(itemRef)
+	if err != nil {
+		// If a performance improvement cannot be done, fallback to the 'non-performant' way
+		return nil, nil
 	}
-	return regv1.Image(imageWithRef), nil
+	return descriptor, nil
 }
 
 func (i *ImageSet) verifyImageOrIndex(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry registry.ImagesReaderWriter) (ProcessedImage, error) {
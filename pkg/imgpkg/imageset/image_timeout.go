@@ -0,0 +1,42 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// WithImageTimeout Returns a copy of the ImageSet that fails the images that are not written within timeout, so that
+// a single image whose transfer hangs does not hold the copy. The images are written one by one when it is provided
+func (i ImageSet) WithImageTimeout(timeout time.Duration) ImageSet {
+	i.imageTimeout = timeout
+	return i
+}
+
+// writeImage writes a single image or index, failing when it is not written within the image timeout.
+// The write is stopped when the timeout expires, so that it does not keep running after it failed.
+// Each image written on its own holds one of the concurrency slots of the import throttle, so its blobs are
+// uploaded one at a time, keeping the uploads in flight within the concurrency
+func (i *ImageSet) writeImage(tag regname.Tag, taggable regremote.Taggable, reg registry.ImagesReaderWriter, updatesCh chan regv1.Update) error {
+	const jobs = 1
+	toWrite := map[regname.Reference]regremote.Taggable{tag: taggable}
+	if i.imageTimeout <= 0 {
+		return reg.MultiWrite(toWrite, jobs, updatesCh)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.imageTimeout)
+	defer cancel()
+	err := registry.MultiWriteWithContext(ctx, reg, toWrite, jobs, updatesCh)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("Writing image '%s': not written within %s", tag.Name(), i.imageTimeout)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// MediaTypePolicy Restricts the media types of the configuration and layers of the images that can be copied
+type MediaTypePolicy struct {
+	// AllowedMediaTypes media types that are allowed, glob patterns are supported (example: application/vnd.oci.image.layer.v1.*)
+	AllowedMediaTypes []string
+	// WarnOnly when true disallowed media types are reported as warnings instead of failing the copy
+	WarnOnly bool
+}
+
+// Check Validates that the configuration and layers of all images only use allowed media types
+func (p MediaTypePolicy) Check(imgOrIndexes []imagedesc.ImageOrIndex, logger Logger) error {
+	if len(p.AllowedMediaTypes) == 0 {
+		return nil
+	}
+
+	var violations []string
+	for _, item := range imgOrIndexes {
+		var err error
+		var found []string
+		switch {
+		case item.Image != nil:
+			found, err = p.checkImage(*item.Image, (*item.Image).Ref())
+		case item.Index != nil:
+			found, err = p.checkIndex(*item.Index, (*item.Index).Ref())
+		}
+		if err != nil {
+			return err
+		}
+		violations = append(violations, found...)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+
+	msg := fmt.Sprintf("Found media types that are not allowed (allowed: %s):\n- %s",
+		strings.Join(p.AllowedMediaTypes, ", "), strings.Join(violations, "\n- "))
+	if p.WarnOnly {
+		logger.Logf("Warning: %s\n", msg)
+		return nil
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (p MediaTypePolicy) checkIndex(index regv1.ImageIndex, ref string) ([]string, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("Reading index manifest of '%s': %s", ref, err)
+	}
+
+	var violations []string
+	for _, desc := range indexManifest.Manifests {
+		childRef := fmt.Sprintf("%s@%s", strings.Split(ref, "@")[0], desc.Digest)
+		var found []string
+		if desc.MediaType.IsIndex() {
+			childIndex, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("Reading index '%s': %s", childRef, err)
+			}
+			found, err = p.checkIndex(childIndex, childRef)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			childImage, err := index.Image(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("Reading image '%s': %s", childRef, err)
+			}
+			found, err = p.checkImage(childImage, childRef)
+			if err != nil {
+				return nil, err
+			}
+		}
+		violations = append(violations, found...)
+	}
+	return violations, nil
+}
+
+func (p MediaTypePolicy) checkImage(img regv1.Image, ref string) ([]string, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("Reading manifest of '%s': %s", ref, err)
+	}
+
+	var violations []string
+	if !p.isAllowed(string(manifest.Config.MediaType)) {
+		violations = append(violations, fmt.Sprintf("%s config %s: %s", ref, manifest.Config.Digest, manifest.Config.MediaType))
+	}
+	for _, layer := range manifest.Layers {
+		if !p.isAllowed(string(layer.MediaType)) {
+			violations = append(violations, fmt.Sprintf("%s layer %s: %s", ref, layer.Digest, layer.MediaType))
+		}
+	}
+	return violations, nil
+}
+
+func (p MediaTypePolicy) isAllowed(mediaType string) bool {
+	for _, allowed := range p.AllowedMediaTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if matched, err := path.Match(allowed, mediaType); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// OCILayoutImageSet Exports a set of images to an OCI image layout directory, see OCILayoutSource to read them back
+type OCILayoutImageSet struct {
+	imageSet    ImageSet
+	concurrency int
+	logger      Logger
+}
+
+// NewOCILayoutImageSet provides export operations on an OCI image layout for a set of images
+func NewOCILayoutImageSet(imageSet ImageSet, concurrency int, logger Logger) OCILayoutImageSet {
+	return OCILayoutImageSet{imageSet, concurrency, logger}
+}
+
+// Export Writes the provided Images to the OCI image layout in layoutPath, creating it when it does not exist.
+// Blobs already present in the layout are not written again and the images already listed in the layout index are kept.
+// Each image is named in the layout index after the repository it was read from, so it can be read back with oci:<path>@<digest>
+func (i OCILayoutImageSet) Export(foundImages *UnprocessedImageRefs, layoutPath string, registry registry.ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) (*imagedesc.ImageRefDescriptors, error) {
+	ids, err := i.imageSet.Export(foundImages, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := openOCILayout(layoutPath)
+	if err != nil {
+		return nil, err
+	}
+
+	i.logger.Logf("writing OCI image layout to %s...\n", layoutPath)
+
+	imgOrIndexes := imagedesc.NewDescribedReader(ids, ids).Read()
+	descriptors := make([]regv1.Descriptor, len(imgOrIndexes))
+
+	throttle := util.NewThrottle(i.concurrency)
+	errCh := make(chan error, len(imgOrIndexes))
+	for idx, item := range imgOrIndexes {
+		idx, item := idx, item // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			desc, err := writeToOCILayout(path, item, imageLayerWriterCheck)
+			if err != nil {
+				errCh <- fmt.Errorf("Writing '%s' to OCI image layout: %s", item.Ref(), err)
+				return
+			}
+			descriptors[idx] = desc
+			errCh <- nil
+		}()
+	}
+
+	for range imgOrIndexes {
+		if err := <-errCh; err != nil {
+			return nil, err
+		}
+	}
+
+	// The index is updated once every blob is written, so it never lists an image that is not complete
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].Digest.String() < descriptors[j].Digest.String()
+	})
+	var digests []regv1.Hash
+	for _, desc := range descriptors {
+		digests = append(digests, desc.Digest)
+	}
+	err = path.RemoveDescriptors(match.Digests(digests...))
+	if err != nil {
+		return nil, fmt.Errorf("Writing OCI image layout index: %s", err)
+	}
+	for _, desc := range descriptors {
+		err = path.AppendDescriptor(desc)
+		if err != nil {
+			return nil, fmt.Errorf("Writing OCI image layout index: %s", err)
+		}
+	}
+
+	return ids, nil
+}
+
+// openOCILayout Opens the OCI image layout in layoutPath, creating an empty one when it does not exist
+func openOCILayout(layoutPath string) (layout.Path, error) {
+	_, err := os.Stat(layoutPath)
+	if err == nil {
+		path, err := layout.FromPath(layoutPath)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("Reading OCI image layout index: %s", err)
+		}
+	}
+
+	path, err := layout.Write(layoutPath, empty.Index)
+	if err != nil {
+		return "", fmt.Errorf("Creating OCI image layout '%s': %s", layoutPath, err)
+	}
+	return path, nil
+}
+
+// writeToOCILayout Writes the blobs of the image or index to the layout and returns the descriptor to add to the layout index,
+// annotated with the reference of the image, its tag and its labels
+func writeToOCILayout(path layout.Path, item imagedesc.ImageOrIndex, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) (regv1.Descriptor, error) {
+	var desc *regv1.Descriptor
+	var err error
+	if item.Image != nil {
+		img := distributableImage{*item.Image, imageLayerWriterCheck}
+		err = path.WriteImage(img)
+		if err == nil {
+			desc, err = partial.Descriptor(*item.Image)
+		}
+	} else {
+		index := distributableIndex{*item.Index, imageLayerWriterCheck}
+		err = path.WriteIndex(index)
+		if err == nil {
+			desc, err = partial.Descriptor(*item.Index)
+		}
+	}
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+
+	ref, err := regname.NewDigest(item.Ref())
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+	annotations := map[string]string{registry.OCILayoutImageNameAnnotation: ref.Name()}
+	if item.Tag() != "" {
+		annotations[registry.OCILayoutRefNameAnnotation] = item.Tag()
+		annotations[registry.OCILayoutImageNameAnnotation] = ref.Context().Tag(item.Tag()).Name()
+	}
+	for key, value := range item.Labels {
+		annotations[key] = value
+	}
+
+	return regv1.Descriptor{MediaType: desc.MediaType, Size: desc.Size, Digest: desc.Digest, Annotations: annotations}, nil
+}
+
+// distributableImage Image whose layers are only written to the layout when imageLayerWriterCheck includes them,
+// the manifest is kept as is
+type distributableImage struct {
+	regv1.Image
+	imageLayerWriterCheck imagetar.ImageLayerWriterFilter
+}
+
+// Layers Returns the layers that are written to the layout
+func (i distributableImage) Layers() ([]regv1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var included []regv1.Layer
+	for _, layer := range layers {
+		include, err := i.imageLayerWriterCheck.ShouldLayerBeIncluded(layer)
+		if err != nil {
+			return nil, err
+		}
+		if include {
+			included = append(included, layer)
+		}
+	}
+	return included, nil
+}
+
+// distributableIndex Index whose images only have the layers included by imageLayerWriterCheck written to the layout,
+// the manifests are kept as is
+type distributableIndex struct {
+	index                 regv1.ImageIndex
+	imageLayerWriterCheck imagetar.ImageLayerWriterFilter
+}
+
+var _ regv1.ImageIndex = distributableIndex{}
+
+// MediaType Returns the media type of the index
+func (i distributableIndex) MediaType() (types.MediaType, error) { return i.index.MediaType() }
+
+// Digest Returns the digest of the index
+func (i distributableIndex) Digest() (regv1.Hash, error) { return i.index.Digest() }
+
+// Size Returns the size of the index manifest
+func (i distributableIndex) Size() (int64, error) { return i.index.Size() }
+
+// IndexManifest Returns the parsed index manifest
+func (i distributableIndex) IndexManifest() (*regv1.IndexManifest, error) {
+	return i.index.IndexManifest()
+}
+
+// RawManifest Returns the index manifest
+func (i distributableIndex) RawManifest() ([]byte, error) { return i.index.RawManifest() }
+
+// Image Returns the image of the index, see distributableImage
+func (i distributableIndex) Image(digest regv1.Hash) (regv1.Image, error) {
+	img, err := i.index.Image(digest)
+	if err != nil {
+		return nil, err
+	}
+	return distributableImage{img, i.imageLayerWriterCheck}, nil
+}
+
+// ImageIndex Returns the nested index of the index, see distributableIndex
+func (i distributableIndex) ImageIndex(digest regv1.Hash) (regv1.ImageIndex, error) {
+	index, err := i.index.ImageIndex(digest)
+	if err != nil {
+		return nil, err
+	}
+	return distributableIndex{index, i.imageLayerWriterCheck}, nil
+}
+
+// Layer Returns the blob of a manifest of the index that is neither an image nor an index
+func (i distributableIndex) Layer(digest regv1.Hash) (regv1.Layer, error) {
+	withLayer, ok := i.index.(interface {
+		Layer(regv1.Hash) (regv1.Layer, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Unable to read '%s' from the image index", digest)
+	}
+	return withLayer.Layer(digest)
+}
+
+// OCILayoutSource Images listed in the index of an OCI image layout
+type OCILayoutSource struct {
+	layoutPath layout.Path
+	logger     Logger
+}
+
+// NewOCILayoutSource Creates a ContentSource for the images in the OCI image layout in layoutPath.
+// The registry used to read them must be able to read from the layout, see registry.NewOCILayoutRegistry
+func NewOCILayoutSource(layoutPath layout.Path, logger Logger) OCILayoutSource {
+	return OCILayoutSource{layoutPath, logger}
+}
+
+// Read Returns the references of the images in the layout index, their tags and labels are taken from the annotations
+func (s OCILayoutSource) Read(_ registry.ImagesReaderWriter) (Content, error) {
+	manifests, err := registry.OCILayoutManifests(s.layoutPath)
+	if err != nil {
+		return Content{}, err
+	}
+	if len(manifests) == 0 {
+		return Content{}, fmt.Errorf("Expected OCI image layout '%s' to contain at least one image", s.layoutPath)
+	}
+
+	s.logger.Logf("reading OCI image layout from %s...\n", s.layoutPath)
+
+	foundImages := NewUnprocessedImageRefs()
+	for _, manifest := range manifests {
+		imgRef := UnprocessedImageRef{DigestRef: manifest.DigestRef, OrigRef: manifest.DigestRef}
+		for key, value := range manifest.Annotations {
+			switch key {
+			case registry.OCILayoutRefNameAnnotation:
+				imgRef.Tag = ociLayoutTag(value)
+			case registry.OCILayoutImageNameAnnotation:
+			default:
+				if imgRef.Labels == nil {
+					imgRef.Labels = map[string]string{}
+				}
+				imgRef.Labels[key] = value
+			}
+		}
+		foundImages.Add(imgRef)
+	}
+
+	return NewContentFromRefs(foundImages), nil
+}
+
+// ociLayoutTag Extracts the tag from the ref.name annotation. Some tools record only the tag
+// and others the full reference, when the value is not a valid tag the image is not tagged
+func ociLayoutTag(refName string) string {
+	tag := refName
+	if idx := strings.LastIndex(tag, ":"); idx >= 0 && !strings.Contains(tag[idx:], "/") {
+		tag = tag[idx+1:]
+	}
+	if _, err := regname.NewTag("repo:"+tag, regname.StrictValidation); err != nil {
+		return ""
+	}
+	return tag
+}
+
+// OCILayoutDestination Writes the images to an OCI image layout
+type OCILayoutDestination struct {
+	layoutImageSet        OCILayoutImageSet
+	layoutPath            string
+	imageLayerWriterCheck imagetar.ImageLayerWriterFilter
+}
+
+// NewOCILayoutDestination Creates a ContentDestination that writes the images to the OCI image layout in layoutPath, see OCILayoutImageSet.Export
+func NewOCILayoutDestination(layoutImageSet OCILayoutImageSet, layoutPath string, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) OCILayoutDestination {
+	return OCILayoutDestination{layoutImageSet, layoutPath, imageLayerWriterCheck}
+}
+
+// Write Exports the images to the layout, only images that can be fetched from the registry can be written
+func (d OCILayoutDestination) Write(content Content, registry registry.ImagesReaderWriter) (WrittenContent, error) {
+	refs, ok := content.Refs()
+	if !ok {
+		return WrittenContent{}, fmt.Errorf("Writing images that were not read from a registry to an OCI image layout is not supported")
+	}
+
+	ids, err := d.layoutImageSet.Export(refs, d.layoutPath, registry, d.imageLayerWriterCheck)
+	return WrittenContent{Descriptors: ids}, err
+}
@@ -37,6 +37,24 @@ func (p ProcessedImage) Validate() {
 	p.UnprocessedImageRef.Validate()
 }
 
+// ProcessedImageHandler Receives each image as soon as it is processed, while the other images are still being copied
+type ProcessedImageHandler func(ProcessedImage)
+
+// serializedHandler Calls the handler one image at a time, so that handlers do not need to be safe for concurrent use
+type serializedHandler struct {
+	handler ProcessedImageHandler
+	lock    *sync.Mutex
+}
+
+func (s serializedHandler) handle(img ProcessedImage) {
+	if s.handler == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handler(img)
+}
+
 type ProcessedImages struct {
 	imgs     map[string]ProcessedImage
 	imgsLock sync.Mutex
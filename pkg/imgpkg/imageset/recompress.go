@@ -0,0 +1,398 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/artifact"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Recompression Compression the gzip layers are converted to while copying to a registry
+type Recompression string
+
+const (
+	// RecompressionNone keeps the layers as they are in the source
+	RecompressionNone Recompression = ""
+	// RecompressionZstd converts the gzip layers to zstd
+	RecompressionZstd Recompression = "zstd"
+
+	// RecompressedFromAnnotation Annotation added to the recompressed layers, images and indexes, with the digest they had in the source
+	RecompressedFromAnnotation = "dev.carvel.imgpkg.recompressed-from"
+
+	// bundleConfigLabel same as bundle.BundleConfigLabel, that package depends on this one
+	bundleConfigLabel = "dev.carvel.imgpkg.bundle"
+)
+
+// recompressor Converts the gzip layers of the images to zstd, storing the converted layers in dir until the copy ends.
+// Layers shared by several images are only converted once
+type recompressor struct {
+	dir    string
+	logger Logger
+
+	lock   *sync.Mutex
+	layers map[regv1.Hash]*recompressedLayerEntry
+}
+
+type recompressedLayerEntry struct {
+	once  sync.Once
+	layer regv1.Layer
+	err   error
+}
+
+func newRecompressor(logger Logger) (*recompressor, error) {
+	dir, err := os.MkdirTemp("", "imgpkg-recompress-")
+	if err != nil {
+		return nil, fmt.Errorf("Creating folder for the recompressed layers: %s", err)
+	}
+	return &recompressor{dir: dir, logger: logger, lock: &sync.Mutex{}, layers: map[regv1.Hash]*recompressedLayerEntry{}}, nil
+}
+
+// Cleanup Removes the recompressed layers
+func (r *recompressor) Cleanup() {
+	os.RemoveAll(r.dir)
+}
+
+// Recompress Returns the item with its gzip layers, and the ones of the images in the index, converted to zstd.
+// Items without gzip layers are returned as they are
+func (r *recompressor) Recompress(item imagedesc.ImageOrIndex) (imagedesc.ImageOrIndex, error) {
+	switch {
+	case item.Image != nil:
+		img, changed, err := r.recompressImage(*item.Image)
+		if err != nil {
+			return imagedesc.ImageOrIndex{}, fmt.Errorf("Recompressing image %s: %s", item.Ref(), err)
+		}
+		if changed {
+			var imageWithRef imagedesc.ImageWithRef = recompressedImageWithRef{Image: img, ref: item.Ref(), tag: item.Tag()}
+			item.Image = &imageWithRef
+		}
+	case item.Index != nil:
+		index, changed, err := r.recompressIndex(*item.Index)
+		if err != nil {
+			return imagedesc.ImageOrIndex{}, fmt.Errorf("Recompressing image index %s: %s", item.Ref(), err)
+		}
+		if changed {
+			var indexWithRef imagedesc.ImageIndexWithRef = recompressedIndexWithRef{index: index, ref: item.Ref(), tag: item.Tag()}
+			item.Index = &indexWithRef
+		}
+	}
+	return item, nil
+}
+
+func (r *recompressor) recompressIndex(index regv1.ImageIndex) (regv1.ImageIndex, bool, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	var addenda []mutate.IndexAddendum
+	for _, child := range manifest.Manifests {
+		var add mutate.Appendable
+		switch {
+		case child.MediaType.IsIndex():
+			childIndex, err := index.ImageIndex(child.Digest)
+			if err != nil {
+				return nil, false, err
+			}
+			newIndex, indexChanged, err := r.recompressIndex(childIndex)
+			if err != nil {
+				return nil, false, err
+			}
+			add, changed = newIndex, changed || indexChanged
+		case child.MediaType.IsImage():
+			childImage, err := index.Image(child.Digest)
+			if err != nil {
+				return nil, false, err
+			}
+			newImage, imageChanged, err := r.recompressImage(childImage)
+			if err != nil {
+				return nil, false, err
+			}
+			add, changed = newImage, changed || imageChanged
+		default:
+			return nil, false, fmt.Errorf("Unable to recompress child %s with media type %s", child.Digest, child.MediaType)
+		}
+		addenda = append(addenda, mutate.IndexAddendum{
+			Add:        add,
+			Descriptor: regv1.Descriptor{Platform: child.Platform, Annotations: child.Annotations},
+		})
+	}
+	if !changed {
+		return index, false, nil
+	}
+
+	digest, err := index.Digest()
+	if err != nil {
+		return nil, false, err
+	}
+	annotations := map[string]string{}
+	for key, value := range manifest.Annotations {
+		annotations[key] = value
+	}
+	annotations[RecompressedFromAnnotation] = digest.String()
+
+	// Docker manifest lists cannot reference OCI manifests, so the index is always an OCI index
+	newIndex := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+	newIndex = mutate.AppendManifests(newIndex, addenda...)
+	return mutate.Annotations(newIndex, annotations).(regv1.ImageIndex), true, nil
+}
+
+func (r *recompressor) recompressImage(img regv1.Image) (regv1.Image, bool, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasGzipLayers := false
+	for _, layer := range manifest.Layers {
+		if isGzipLayer(layer.MediaType) {
+			hasGzipLayers = true
+		}
+	}
+	// Artifacts, like CNAB bundles, are relocated as they are, their layers are not necessarily tars
+	if !hasGzipLayers || !artifact.IsImageConfig(manifest.Config.MediaType) {
+		return img, false, nil
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, false, err
+	}
+	if _, found := config.Config.Labels[bundleConfigLabel]; found {
+		return nil, false, fmt.Errorf("Bundles cannot be recompressed, the images they reference would change digest")
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, false, err
+	}
+	newManifest := manifest.DeepCopy()
+	newManifest.MediaType = types.OCIManifestSchema1
+	if newManifest.Config.MediaType == types.DockerConfigJSON {
+		newManifest.Config.MediaType = types.OCIConfigJSON
+	}
+	if newManifest.Annotations == nil {
+		newManifest.Annotations = map[string]string{}
+	}
+	newManifest.Annotations[RecompressedFromAnnotation] = digest.String()
+
+	layers := map[regv1.Hash]regv1.Layer{}
+	var originalSize, recompressedSize int64
+	for idx, desc := range manifest.Layers {
+		if !isGzipLayer(desc.MediaType) {
+			newManifest.Layers[idx].MediaType = ociLayerMediaType(desc.MediaType)
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, false, err
+		}
+		newLayer, err := r.recompressLayer(desc.Digest, layer)
+		if err != nil {
+			return nil, false, fmt.Errorf("Layer %s: %s", desc.Digest, err)
+		}
+		newDesc, err := partial.Descriptor(newLayer)
+		if err != nil {
+			return nil, false, err
+		}
+
+		annotations := map[string]string{}
+		for key, value := range desc.Annotations {
+			annotations[key] = value
+		}
+		annotations[RecompressedFromAnnotation] = desc.Digest.String()
+		newManifest.Layers[idx] = regv1.Descriptor{MediaType: newDesc.MediaType, Size: newDesc.Size, Digest: newDesc.Digest, Annotations: annotations}
+
+		layers[newDesc.Digest] = newLayer
+		originalSize += desc.Size
+		recompressedSize += newDesc.Size
+	}
+
+	rawManifest, err := json.Marshal(newManifest)
+	if err != nil {
+		return nil, false, err
+	}
+	r.logger.Logf("recompressed layers of %s from %d to %d bytes\n", digest, originalSize, recompressedSize)
+
+	newImg, err := partial.CompressedToImage(recompressedImage{original: img, rawManifest: rawManifest, layers: layers})
+	return newImg, true, err
+}
+
+// recompressLayer Converts the layer to zstd, or returns the layer already converted when it is shared with another image
+func (r *recompressor) recompressLayer(digest regv1.Hash, layer regv1.Layer) (regv1.Layer, error) {
+	r.lock.Lock()
+	entry, found := r.layers[digest]
+	if !found {
+		entry = &recompressedLayerEntry{}
+		r.layers[digest] = entry
+	}
+	r.lock.Unlock()
+
+	entry.once.Do(func() {
+		entry.layer, entry.err = r.writeZstdLayer(digest, layer)
+	})
+	return entry.layer, entry.err
+}
+
+func (r *recompressor) writeZstdLayer(digest regv1.Hash, layer regv1.Layer) (regv1.Layer, error) {
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, err
+	}
+	uncompressed, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer uncompressed.Close()
+
+	path := filepath.Join(r.dir, digest.Hex+".zst")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	// A single goroutine per layer keeps the output the same on every run, so that resumed copies find the same digests
+	encoder, err := zstd.NewWriter(io.MultiWriter(file, hasher, counter), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(encoder, uncompressed)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return partial.CompressedToLayer(&zstdLayer{
+		path:   path,
+		digest: regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(hasher.Sum(nil))},
+		diffID: diffID,
+		size:   counter.size,
+	})
+}
+
+func isGzipLayer(mediaType types.MediaType) bool {
+	return mediaType == types.DockerLayer || mediaType == types.OCILayer
+}
+
+// ociLayerMediaType Returns the OCI media type of the layers that are not recompressed,
+// that are referenced by an OCI manifest once the image is recompressed
+func ociLayerMediaType(mediaType types.MediaType) types.MediaType {
+	switch mediaType {
+	case types.DockerUncompressedLayer:
+		return types.OCIUncompressedLayer
+	case types.DockerForeignLayer:
+		return types.OCIRestrictedLayer
+	default:
+		return mediaType
+	}
+}
+
+type countingWriter struct {
+	size int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	return len(p), nil
+}
+
+// zstdLayer Layer recompressed to zstd, stored in a file
+type zstdLayer struct {
+	path   string
+	digest regv1.Hash
+	diffID regv1.Hash
+	size   int64
+}
+
+func (l *zstdLayer) Digest() (regv1.Hash, error) { return l.digest, nil }
+
+func (l *zstdLayer) DiffID() (regv1.Hash, error) { return l.diffID, nil }
+
+func (l *zstdLayer) Compressed() (io.ReadCloser, error) { return os.Open(l.path) }
+
+func (l *zstdLayer) Size() (int64, error) { return l.size, nil }
+
+func (l *zstdLayer) MediaType() (types.MediaType, error) { return types.OCILayerZStd, nil }
+
+// recompressedImage Image with the manifest referencing the recompressed layers, the configuration and the other layers
+// are read from the original image
+type recompressedImage struct {
+	original    regv1.Image
+	rawManifest []byte
+	layers      map[regv1.Hash]regv1.Layer
+}
+
+func (i recompressedImage) RawConfigFile() ([]byte, error) { return i.original.RawConfigFile() }
+
+func (i recompressedImage) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (i recompressedImage) RawManifest() ([]byte, error) { return i.rawManifest, nil }
+
+func (i recompressedImage) LayerByDigest(digest regv1.Hash) (partial.CompressedLayer, error) {
+	if layer, found := i.layers[digest]; found {
+		return layer, nil
+	}
+	return i.original.LayerByDigest(digest)
+}
+
+type recompressedImageWithRef struct {
+	regv1.Image
+	ref string
+	tag string
+}
+
+func (i recompressedImageWithRef) Ref() string { return i.ref }
+func (i recompressedImageWithRef) Tag() string { return i.tag }
+
+type recompressedIndexWithRef struct {
+	index regv1.ImageIndex
+	ref   string
+	tag   string
+}
+
+func (i recompressedIndexWithRef) Ref() string { return i.ref }
+func (i recompressedIndexWithRef) Tag() string { return i.tag }
+
+func (i recompressedIndexWithRef) MediaType() (types.MediaType, error) { return i.index.MediaType() }
+
+func (i recompressedIndexWithRef) Digest() (regv1.Hash, error) { return i.index.Digest() }
+
+func (i recompressedIndexWithRef) Size() (int64, error) { return i.index.Size() }
+
+func (i recompressedIndexWithRef) IndexManifest() (*regv1.IndexManifest, error) {
+	return i.index.IndexManifest()
+}
+
+func (i recompressedIndexWithRef) RawManifest() ([]byte, error) { return i.index.RawManifest() }
+
+func (i recompressedIndexWithRef) Image(digest regv1.Hash) (regv1.Image, error) {
+	return i.index.Image(digest)
+}
+
+func (i recompressedIndexWithRef) ImageIndex(digest regv1.Hash) (regv1.ImageIndex, error) {
+	return i.index.ImageIndex(digest)
+}
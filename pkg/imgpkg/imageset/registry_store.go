@@ -0,0 +1,173 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"sort"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// RepositoryDestination Copies all the images into a single repository
+type RepositoryDestination struct {
+	imageSet   ImageSet
+	importRepo regname.Repository
+}
+
+// NewRepositoryDestination Creates a ContentDestination that writes all the images to importRepo
+func NewRepositoryDestination(imageSet ImageSet, importRepo regname.Repository) RepositoryDestination {
+	return RepositoryDestination{imageSet, importRepo}
+}
+
+// Write Copies the content into the repository
+func (d RepositoryDestination) Write(content Content, registry registry.ImagesReaderWriter) (WrittenContent, error) {
+	if refs, ok := content.Refs(); ok {
+		processedImages, err := d.imageSet.Relocate(refs, d.importRepo, registry)
+		return WrittenContent{ProcessedImages: processedImages}, err
+	}
+
+	imgOrIndexes, _ := content.ImagesOrIndexes()
+	processedImages, err := d.imageSet.importImages(imgOrIndexes, d.importRepo, registry)
+	return WrittenContent{ProcessedImages: processedImages}, err
+}
+
+// Repositories Returns the repository all the images are written to
+func (d RepositoryDestination) Repositories(_ *UnprocessedImageRefs) ([]regname.Repository, error) {
+	return []regname.Repository{d.importRepo}, nil
+}
+
+// RegistryDestination Copies the images into a registry keeping the repository path they had in their original registry,
+// e.g. index.docker.io/library/nginx is copied to <importRegistry>/library/nginx
+type RegistryDestination struct {
+	imageSet       ImageSet
+	importRegistry regname.Registry
+}
+
+// NewRegistryDestination Creates a ContentDestination that writes each image to the repository of importRegistry
+// with the same path as its original repository
+func NewRegistryDestination(imageSet ImageSet, importRegistry regname.Registry) RegistryDestination {
+	return RegistryDestination{imageSet, importRegistry}
+}
+
+// Write Copies the content into the registry, one repository at a time
+func (d RegistryDestination) Write(content Content, registry registry.ImagesReaderWriter) (WrittenContent, error) {
+	if refs, ok := content.Refs(); ok {
+		processedImages, err := d.relocate(refs, registry)
+		return WrittenContent{ProcessedImages: processedImages}, err
+	}
+
+	imgOrIndexes, _ := content.ImagesOrIndexes()
+	processedImages, err := d.importImages(imgOrIndexes, registry)
+	return WrittenContent{ProcessedImages: processedImages}, err
+}
+
+// Repositories Returns the repositories of the registry the images of refs are written to
+func (d RegistryDestination) Repositories(refs *UnprocessedImageRefs) ([]regname.Repository, error) {
+	importRepos := map[string]regname.Repository{}
+	for _, img := range refs.All() {
+		importRepo, err := registryRepository(d.importRegistry, originalRef(img.OrigRef, img.DigestRef))
+		if err != nil {
+			return nil, err
+		}
+		importRepos[importRepo.Name()] = importRepo
+	}
+
+	var repos []regname.Repository
+	for _, repoName := range sortedKeys(importRepos) {
+		repos = append(repos, importRepos[repoName])
+	}
+	return repos, nil
+}
+
+func (d RegistryDestination) relocate(foundImages *UnprocessedImageRefs, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
+	imagesPerRepo := map[string]*UnprocessedImageRefs{}
+	importRepos := map[string]regname.Repository{}
+	for _, img := range foundImages.All() {
+		importRepo, err := registryRepository(d.importRegistry, originalRef(img.OrigRef, img.DigestRef))
+		if err != nil {
+			return nil, err
+		}
+
+		if _, found := imagesPerRepo[importRepo.Name()]; !found {
+			imagesPerRepo[importRepo.Name()] = NewUnprocessedImageRefs()
+			importRepos[importRepo.Name()] = importRepo
+		}
+		imagesPerRepo[importRepo.Name()].Add(img)
+	}
+
+	processedImages := NewProcessedImages()
+	for _, repoName := range sortedKeys(importRepos) {
+		repoImages, err := d.imageSet.Relocate(imagesPerRepo[repoName], importRepos[repoName], registry)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range repoImages.All() {
+			processedImages.Add(img)
+		}
+	}
+
+	return processedImages, nil
+}
+
+func (d RegistryDestination) importImages(imgOrIndexes []imagedesc.ImageOrIndex, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
+	err := d.imageSet.mediaTypePolicy.Check(imgOrIndexes, d.imageSet.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	imagesPerRepo := map[string][]imagedesc.ImageOrIndex{}
+	importRepos := map[string]regname.Repository{}
+	for _, item := range imgOrIndexes {
+		importRepo, err := registryRepository(d.importRegistry, originalRef(item.OrigRef, item.Ref()))
+		if err != nil {
+			return nil, err
+		}
+
+		importRepos[importRepo.Name()] = importRepo
+		imagesPerRepo[importRepo.Name()] = append(imagesPerRepo[importRepo.Name()], item)
+	}
+
+	processedImages := NewProcessedImages()
+	for _, repoName := range sortedKeys(importRepos) {
+		repoImages, err := d.imageSet.Import(imagesPerRepo[repoName], importRepos[repoName], registry)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range repoImages.All() {
+			processedImages.Add(img)
+		}
+	}
+
+	return processedImages, nil
+}
+
+// registryRepository Returns the repository in importRegistry that has the same path as the repository of imageRef
+func registryRepository(importRegistry regname.Registry, imageRef string) (regname.Repository, error) {
+	ref, err := regname.ParseReference(imageRef)
+	if err != nil {
+		return regname.Repository{}, fmt.Errorf("Parsing reference %s: %s", imageRef, err)
+	}
+	return importRegistry.Repo(ref.Context().RepositoryStr()), nil
+}
+
+// originalRef Returns the location where the image was first found, images referenced by a bundle
+// might have been retrieved from the bundle repository instead
+func originalRef(origRef, ref string) string {
+	if origRef != "" {
+		return origRef
+	}
+	return ref
+}
+
+func sortedKeys(repos map[string]regname.Repository) []string {
+	var keys []string
+	for key := range repos {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
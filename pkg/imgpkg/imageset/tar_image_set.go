@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
 	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
-	regname "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
@@ -19,120 +22,327 @@ type TarImageSet struct {
 	imageSet    ImageSet
 	concurrency int
 	logger      Logger
+	// splitSize when positive, maximum size of each of the parts the tar is split into
+	splitSize int64
+	// checksums when true, checksum files are written next to the tar, see imagetar.WriteChecksums
+	checksums bool
+	// chunking when true, layers are written as content-defined chunks, see imagetar.TarWriterOpts
+	chunking bool
 }
 
 // NewTarImageSet provides export/import operations on a tarball for a set of images
 func NewTarImageSet(imageSet ImageSet, concurrency int, logger Logger) TarImageSet {
-	return TarImageSet{imageSet, concurrency, logger}
+	return TarImageSet{imageSet: imageSet, concurrency: concurrency, logger: logger}
 }
 
-// Export Creates a Tar with the provided Images
-func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string, registry registry.ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter, resume bool) (d *imagedesc.ImageRefDescriptors, err error) {
+// WithSplitSize Returns a TarImageSet that splits the tar it exports into parts of at most size bytes,
+// named after the tar with a numeric suffix (example: bundle.tar.000, bundle.tar.001)
+func (i TarImageSet) WithSplitSize(size int64) TarImageSet {
+	i.splitSize = size
+	return i
+}
+
+// WithChecksums Returns a TarImageSet that writes, next to the tar it exports, the SHA256 of the tar and of each blob in it
+func (i TarImageSet) WithChecksums(checksums bool) TarImageSet {
+	i.checksums = checksums
+	return i
+}
+
+// WithChunking Returns a TarImageSet that writes the layers as content-defined chunks, each chunk once,
+// so that layers with partially the same content, like versions of the same image, take less space in the tar
+func (i TarImageSet) WithChunking(chunking bool) TarImageSet {
+	i.chunking = chunking
+	return i
+}
+
+// Export Creates a Tar with the provided Images.
+// The tar is written to a temporary file next to outputPath and only renamed to outputPath once complete,
+// so outputPath never contains a partially written tar. Layers are recorded in a journal as they reach the disk,
+// and when resuming, the layers in the journal or in an already existing tar are reused instead of downloaded.
+func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string, registry registry.ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter, resume bool) (*imagedesc.ImageRefDescriptors, error) {
 	ids, err := i.imageSet.Export(foundImages, registry)
 	if err != nil {
 		return nil, err
 	}
 
-	var outputFile *os.File
-	var alreadyDownloadedLayers []v1.Layer
+	outputDir := filepath.Dir(outputPath)
+	copyJournal, err := journal.Open(tarJournalPath(outputPath))
+	if err != nil {
+		return nil, err
+	}
+	defer copyJournal.Close()
 
-	// this temporary file is used only in the case were we are resuming the copy of an image to a tar
-	// we are creating a temporary copy of the existing tar. This is done to be able to read the layers
-	// when we are filling up the destination tar.
-	var tmpFile *os.File
-	if resume {
-		// If the file cannot be open we assume that this is not a resume action.
-		// This will just follow the normal path of resume == false
-		outputFile, err = os.Open(outputPath)
-		if err == nil {
-			tmpFile, err = os.CreateTemp("", "imgpkg-tar-imageset-")
-			if err != nil {
-				return nil, fmt.Errorf("Creating tmp folder: %s", err)
-			}
-			defer os.Remove(tmpFile.Name())
+	previousPartialFiles := journaledFiles(outputDir, copyJournal)
 
-			var cErr error
-			_, cErr = io.Copy(tmpFile, outputFile)
-			err = tmpFile.Close()
-			if err != nil {
-				return nil, err
-			}
-			err = outputFile.Close()
+	var alreadyDownloadedLayers []v1.Layer
+	if resume {
+		// If the file cannot be open we assume that there is no previous tar to reuse.
+		tarExists := imagetar.TarExists(outputPath)
+		if tarExists {
+			err = i.checkTarOptions(outputPath)
 			if err != nil {
 				return nil, err
 			}
-			if cErr != nil {
-				return nil, err
-			}
+		}
 
-			alreadyDownloadedLayers, err = imagetar.NewTarReader(tmpFile.Name()).PresentLayers()
+		err = copyJournal.CheckOptions(i.imageSet.copyOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		alreadyDownloadedLayers, err = i.journaledLayers(outputDir, copyJournal)
+		if err != nil {
+			return nil, err
+		}
+
+		if tarExists {
+			layersInTar, err := imagetar.NewTarReader(outputPath).PresentLayers()
 			if err != nil {
 				return nil, fmt.Errorf("Reading previously created tar '%s': %s", outputPath, err)
 			}
+			alreadyDownloadedLayers = append(alreadyDownloadedLayers, layersInTar...)
+		}
+
+		i.logger.Logf("Going to reuse %d layers from the tar already in disk\n", len(alreadyDownloadedLayers))
+	} else {
+		err = removeFiles(previousPartialFiles)
+		if err != nil {
+			return nil, err
+		}
+		previousPartialFiles = nil
 
-			i.logger.Logf("Going to reuse %d layers from the tar already in disk\n", len(alreadyDownloadedLayers))
+		err = copyJournal.Reset()
+		if err != nil {
+			return nil, err
+		}
+
+		err = copyJournal.CheckOptions(i.imageSet.copyOptions)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	outputFile, err = os.Create(outputPath)
+	partialFile, err := os.CreateTemp(outputDir, filepath.Base(outputPath)+".partial-")
 	if err != nil {
 		return nil, fmt.Errorf("Creating file '%s': %s", outputPath, err)
 	}
-	err = outputFile.Close()
+	partialPath := partialFile.Name()
+	err = partialFile.Chmod(0644)
+	if err != nil {
+		partialFile.Close()
+		return nil, fmt.Errorf("Creating file '%s': %s", outputPath, err)
+	}
+	err = partialFile.Close()
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err == nil {
-			return
-		}
-		if tmpFile != nil {
-			var err1 error
-			outputFile, err1 = os.Open(outputPath)
-			if err1 != nil {
-				err = fmt.Errorf("original error: %s, post exit error: %s", err, err1)
-				return
-			}
-			lTmpFile, err1 := os.Open(tmpFile.Name())
-			if err1 != nil {
-				outputFile.Close()
-				err = fmt.Errorf("original error: %s, post exit error: %s", err, err1)
-				return
-			}
-
-			_, err1 = io.Copy(outputFile, lTmpFile)
-			outputFile.Close()
-			lTmpFile.Close()
-			if err1 != nil {
-				err = fmt.Errorf("original error: %s, post exit error: %s", err, err1)
-				return
-			}
-		}
-	}()
 
 	outputFileOpener := func() (io.WriteCloser, error) {
-		return os.OpenFile(outputPath, os.O_RDWR, 0755)
+		return os.OpenFile(partialPath, os.O_RDWR, 0755)
 	}
 
 	i.logger.Logf("writing layers...\n")
 
-	opts := imagetar.TarWriterOpts{Concurrency: i.concurrency}
+	opts := imagetar.TarWriterOpts{
+		Concurrency: i.concurrency,
+		Journal:     tarLayerJournal{journal: copyJournal, file: filepath.Base(partialPath)},
+		Options:     &i.imageSet.copyOptions,
+		Chunking:    i.chunking,
+	}
 
 	err = imagetar.NewTarWriter(ids, outputFileOpener, opts, i.logger, imageLayerWriterCheck, alreadyDownloadedLayers).Write()
-	return ids, err
+	if err != nil {
+		// The partial tar and the journal are kept to allow the copy to be resumed
+		return ids, err
+	}
+
+	if atomicfile.FsyncEnabled() {
+		err = syncFile(partialPath)
+		if err != nil {
+			return ids, err
+		}
+	}
+
+	// The parts and the checksums of a previous tar are replaced by the new tar
+	err = imagetar.RemoveSplitParts(outputPath)
+	if err != nil {
+		return ids, err
+	}
+	err = imagetar.RemoveChecksumFiles(outputPath)
+	if err != nil {
+		return ids, err
+	}
+
+	// The partial tar is split before it is moved, so that an interrupted split never leaves a tar in outputPath
+	if i.splitSize > 0 {
+		parts, err := imagetar.SplitTar(partialPath, outputPath, i.splitSize)
+		if err != nil {
+			return ids, err
+		}
+		// A tar that was not split would be read instead of the parts
+		err = os.Remove(outputPath)
+		if err != nil && !os.IsNotExist(err) {
+			return ids, fmt.Errorf("Removing previous tar '%s': %s", outputPath, err)
+		}
+		i.logger.Logf("split tar into %d parts of at most %d bytes\n", len(parts), i.splitSize)
+	} else {
+		err = os.Rename(partialPath, outputPath)
+		if err != nil {
+			return ids, fmt.Errorf("Moving tar to '%s': %s", outputPath, err)
+		}
+	}
+
+	if i.checksums {
+		checksumFiles, err := imagetar.WriteChecksums(outputPath)
+		if err != nil {
+			return ids, err
+		}
+		i.logger.Logf("wrote checksums to %s\n", strings.Join(checksumFiles, ", "))
+	}
+
+	if atomicfile.FsyncEnabled() {
+		err = atomicfile.SyncDir(outputDir)
+		if err != nil {
+			return ids, err
+		}
+	}
+
+	err = removeFiles(previousPartialFiles)
+	if err != nil {
+		return ids, err
+	}
+
+	return ids, copyJournal.Remove()
 }
 
-// Import Copy tar with Images to the Registry
-func (i *TarImageSet) Import(path string, importRepo regname.Repository, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
-	imgOrIndexes, err := imagetar.NewTarReader(path).Read()
+// checkTarOptions verifies that the layers of a tar created by a previous copy can be reused by this copy
+func (i TarImageSet) checkTarOptions(path string) error {
+	tarOptions, found, err := imagetar.NewTarReader(path).Options()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("Reading previously created tar '%s': %s", path, err)
+	}
+	if !found {
+		return fmt.Errorf("Unable to resume a copy into tar '%s' created by a version of imgpkg that does not record the copy options (hint: copy without --resume to start over)", path)
+	}
+	return tarOptions.CheckResumableBy(i.imageSet.copyOptions)
+}
+
+func tarJournalPath(outputPath string) string {
+	return outputPath + ".journal"
+}
+
+// journaledLayers layers recorded in the journal whose partial tar is still present on disk
+func (i TarImageSet) journaledLayers(outputDir string, copyJournal *journal.Journal) ([]v1.Layer, error) {
+	var layers []v1.Layer
+	for _, entry := range copyJournal.Entries() {
+		if entry.Kind != journal.LayerKind {
+			continue
+		}
+
+		path := filepath.Join(outputDir, entry.File)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		digest, err := v1.NewHash(entry.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("Reading journal '%s': %s", copyJournal.Path(), err)
+		}
+
+		layer, err := imagetar.NewTarEntryLayer(path, entry.Offset, digest, entry.Size)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
 	}
+	return layers, nil
+}
+
+func journaledFiles(outputDir string, copyJournal *journal.Journal) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, entry := range copyJournal.Entries() {
+		if entry.File == "" || seen[entry.File] {
+			continue
+		}
+		seen[entry.File] = true
+		files = append(files, filepath.Join(outputDir, entry.File))
+	}
+	return files
+}
 
-	processedImages, err := i.imageSet.Import(imgOrIndexes, importRepo, registry)
+func removeFiles(paths []string) error {
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Removing partial tar '%s': %s", path, err)
+		}
+	}
+	return nil
+}
+
+func syncFile(path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer file.Close()
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("Syncing file '%s': %s", path, err)
+	}
+	return nil
+}
+
+type tarLayerJournal struct {
+	journal *journal.Journal
+	file    string
+}
+
+func (t tarLayerJournal) LayerWritten(_ string, digest string, offset int64, size int64) error {
+	return t.journal.Append(journal.Entry{Kind: journal.LayerKind, Digest: digest, File: t.file, Offset: offset, Size: size})
+}
+
+// TarSource Images that are read from a tarball created by imgpkg
+type TarSource struct {
+	path string
+}
+
+// NewTarSource Creates a ContentSource for the images in the tarball in path
+func NewTarSource(path string) TarSource {
+	return TarSource{path}
+}
+
+// Read Returns the images in the tarball
+func (s TarSource) Read(_ registry.ImagesReaderWriter) (Content, error) {
+	imgOrIndexes, err := imagetar.NewTarReader(s.path).Read()
+	if err != nil {
+		return Content{}, err
+	}
+	return NewContentFromImages(imgOrIndexes), nil
+}
+
+// TarDestination Writes the images to a tarball
+type TarDestination struct {
+	tarImageSet           TarImageSet
+	outputPath            string
+	imageLayerWriterCheck imagetar.ImageLayerWriterFilter
+	resume                bool
+}
+
+// NewTarDestination Creates a ContentDestination that writes the images to the tarball in outputPath, see TarImageSet.Export
+func NewTarDestination(tarImageSet TarImageSet, outputPath string, imageLayerWriterCheck imagetar.ImageLayerWriterFilter, resume bool) TarDestination {
+	return TarDestination{tarImageSet, outputPath, imageLayerWriterCheck, resume}
+}
+
+// Write Exports the images to the tarball, only images that can be fetched from the registry can be written
+func (d TarDestination) Write(content Content, registry registry.ImagesReaderWriter) (WrittenContent, error) {
+	refs, ok := content.Refs()
+	if !ok {
+		return WrittenContent{}, fmt.Errorf("Writing images that were not read from a registry to a tar is not supported")
 	}
 
-	return processedImages, err
+	ids, err := d.tarImageSet.Export(refs, d.outputPath, registry, d.imageLayerWriterCheck, d.resume)
+	return WrittenContent{Descriptors: ids}, err
 }
@@ -0,0 +1,130 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+)
+
+const (
+	// ChecksumFileSuffix Suffix of the file, next to the tar, with the SHA256 of the tar or of each of its parts
+	ChecksumFileSuffix = ".sha256"
+	// BlobsChecksumFileSuffix Suffix of the file, next to the tar, with the SHA256 of each entry of the tar
+	BlobsChecksumFileSuffix = ".blobs.sha256"
+)
+
+// ChecksumFiles Paths of the checksum files written for the tar in path
+func ChecksumFiles(path string) []string {
+	return []string{path + ChecksumFileSuffix, path + BlobsChecksumFileSuffix}
+}
+
+// RemoveChecksumFiles Removes the checksum files of the tar in path, so that they do not describe a tar that was replaced
+func RemoveChecksumFiles(path string) error {
+	for _, checksumPath := range ChecksumFiles(path) {
+		err := os.Remove(checksumPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Removing checksum file '%s': %s", checksumPath, err)
+		}
+	}
+	return nil
+}
+
+// WriteChecksums Reads the tar in path, or its parts when it is split, once and writes next to it, in the format
+// of sha256sum, the SHA256 of the tar or of each part (<tar>.sha256) and of each entry of the tar (<tar>.blobs.sha256).
+// The tar can then be verified with 'sha256sum -c' after it is transported, without imgpkg or a registry, and the blobs
+// after extracting it
+func WriteChecksums(path string) ([]string, error) {
+	file, err := openTar(path)
+	if err != nil {
+		return nil, fmt.Errorf("Opening tar '%s': %s", path, err)
+	}
+	defer file.Close()
+
+	paths := tarPaths(path)
+	filesHash := &partsHash{offsets: file.offsets}
+	for range paths {
+		filesHash.hashes = append(filesHash.hashes, sha256.New())
+	}
+
+	blobsChecksums := &bytes.Buffer{}
+	reader := io.TeeReader(file, filesHash)
+	tarReader := tar.NewReader(reader)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading tar '%s': %s", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entryHash := sha256.New()
+		_, err = io.Copy(entryHash, tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("Reading entry '%s' of tar '%s': %s", hdr.Name, path, err)
+		}
+		fmt.Fprintf(blobsChecksums, "%s  %s\n", hex.EncodeToString(entryHash.Sum(nil)), hdr.Name)
+	}
+
+	// The end of archive padding is also part of the tar
+	_, err = io.Copy(io.Discard, reader)
+	if err != nil {
+		return nil, fmt.Errorf("Reading tar '%s': %s", path, err)
+	}
+
+	filesChecksums := &bytes.Buffer{}
+	for idx, filePath := range paths {
+		fmt.Fprintf(filesChecksums, "%s  %s\n", hex.EncodeToString(filesHash.hashes[idx].Sum(nil)), filepath.Base(filePath))
+	}
+
+	checksumPaths := ChecksumFiles(path)
+	for idx, content := range []*bytes.Buffer{filesChecksums, blobsChecksums} {
+		err = atomicfile.WriteFile(checksumPaths[idx], content.Bytes(), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return checksumPaths, nil
+}
+
+// partsHash Hashes the bytes written to it in the hash of the part of the tar they belong to
+type partsHash struct {
+	offsets []int64
+	hashes  []hash.Hash
+	written int64
+}
+
+func (p *partsHash) Write(data []byte) (int, error) {
+	total := len(data)
+	for len(data) > 0 {
+		index := len(p.offsets) - 1
+		for index > 0 && p.offsets[index] > p.written {
+			index--
+		}
+
+		chunk := data
+		if index+1 < len(p.offsets) {
+			if remaining := p.offsets[index+1] - p.written; int64(len(chunk)) > remaining {
+				chunk = chunk[:remaining]
+			}
+		}
+		p.hashes[index].Write(chunk)
+		p.written += int64(len(chunk))
+		data = data[len(chunk):]
+	}
+	return total, nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/cdc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	// chunksDir folder of the tar with the chunks of the layers written with TarWriterOpts.Chunking
+	chunksDir = "chunks/"
+	// chunkIndexSuffix suffix of the entry, named after the layer digest, with the chunks of a layer
+	chunkIndexSuffix = ".chunks.json"
+)
+
+// chunkSizes sizes of the chunks of the layers, big enough for the tar headers of the chunks to not add up
+var chunkSizes = cdc.Sizes{Min: 256 << 10, Avg: 1 << 20, Max: 4 << 20}
+
+// chunkIndex Chunks, in order, whose content concatenated is the layer with Digest
+type chunkIndex struct {
+	Digest string     `json:"digest"`
+	Size   int64      `json:"size"`
+	Chunks []chunkRef `json:"chunks"`
+}
+
+type chunkRef struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+func chunkEntryName(digest regv1.Hash) string {
+	return chunksDir + digest.Algorithm + "-" + digest.Hex
+}
+
+func chunkIndexEntryName(digest regv1.Hash) string {
+	return digest.Algorithm + "-" + digest.Hex + chunkIndexSuffix
+}
+
+// writeChunkedLayers Writes each layer as the chunks of its content that were not written yet, by this or a previous
+// layer, followed by the index of its chunks. The chunks are cut from the compressed content, as stored in the
+// registry, so that the digest of the layer can still be verified when it is read back. A change in the uncompressed
+// content changes the compressed content from that point on, so layers only share the chunks before their first difference
+func (w *TarWriter) writeChunkedLayers() error {
+	writtenIndexes := map[string]bool{}
+	writtenChunks := map[string]bool{}
+	var layersSize, chunksSize int64
+
+	for _, imgLayer := range w.layersToWrite {
+		digest, err := regv1.NewHash(imgLayer.Digest)
+		if err != nil {
+			return err
+		}
+
+		name := chunkIndexEntryName(digest)
+		// Dedup layers
+		if writtenIndexes[name] {
+			continue
+		}
+		writtenIndexes[name] = true
+
+		stream, err := w.openLayer(imgLayer)
+		if err != nil {
+			return err
+		}
+		index, written, err := w.writeChunks(stream, imgLayer, writtenChunks)
+		stream.Close()
+		if err != nil {
+			return fmt.Errorf("Writing chunks of layer '%s': %s", imgLayer.Digest, err)
+		}
+		layersSize += index.Size
+		chunksSize += written
+
+		indexBytes, err := json.Marshal(index)
+		if err != nil {
+			return err
+		}
+		err = w.writeTarEntry(w.tf, name, bytes.NewReader(indexBytes), int64(len(indexBytes)))
+		if err != nil {
+			return fmt.Errorf("Writing tar entry: %s", err)
+		}
+	}
+
+	w.logger.Logf("wrote %d bytes of chunks for %d bytes of layers\n", chunksSize, layersSize)
+	return w.tf.Flush()
+}
+
+// writeChunks Splits the content of the layer into chunks and writes the ones that are not in writtenChunks.
+// Returns the index of the chunks and the bytes written
+func (w *TarWriter) writeChunks(stream io.Reader, imgLayer imagedesc.ImageLayerDescriptor, writtenChunks map[string]bool) (chunkIndex, int64, error) {
+	t1 := time.Now()
+	index := chunkIndex{Digest: imgLayer.Digest}
+	var written int64
+
+	chunker := cdc.NewChunker(stream, chunkSizes)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return chunkIndex{}, 0, err
+		}
+
+		sum := sha256.Sum256(chunk)
+		digest := regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+		index.Chunks = append(index.Chunks, chunkRef{Digest: digest.String(), Size: int64(len(chunk))})
+		index.Size += int64(len(chunk))
+
+		name := chunkEntryName(digest)
+		if writtenChunks[name] {
+			continue
+		}
+		err = w.tf.WriteHeader(&tar.Header{Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(chunk)), Name: name})
+		if err != nil {
+			return chunkIndex{}, 0, fmt.Errorf("Writing header: %s", err)
+		}
+		_, err = w.tf.Write(chunk)
+		if err != nil {
+			return chunkIndex{}, 0, fmt.Errorf("Copying data: %s", err)
+		}
+		writtenChunks[name] = true
+		written += int64(len(chunk))
+	}
+
+	if index.Size != imgLayer.Size {
+		return chunkIndex{}, 0, fmt.Errorf("Expected size %d but read %d bytes", imgLayer.Size, index.Size)
+	}
+
+	w.logger.Logf("done: layer '%s' in %d chunks, %d bytes new (%s)\n", imgLayer.Digest, len(index.Chunks), written, time.Now().Sub(t1))
+	return index, written, nil
+}
+
+// tarEntryPosition Position in the tar of the content of an entry
+type tarEntryPosition struct {
+	offset int64
+	size   int64
+}
+
+// readChunkIndex Returns the index of the chunks of the layer with digest, false when the layer was not chunked
+func (f tarFile) readChunkIndex(digest regv1.Hash) (chunkIndex, bool, error) {
+	stream, err := f.openChunk(chunkIndexEntryName(digest))
+	if err != nil {
+		if _, notFound := err.(util.NonRetryableError); notFound {
+			return chunkIndex{}, false, nil
+		}
+		return chunkIndex{}, false, err
+	}
+	defer stream.Close()
+
+	var index chunkIndex
+	err = json.NewDecoder(stream).Decode(&index)
+	if err != nil {
+		return chunkIndex{}, false, fmt.Errorf("Parsing '%s': %s", chunkIndexEntryName(digest), err)
+	}
+	return index, true, nil
+}
+
+// tarChunkPositions Positions of the chunks in the tar, found by reading the headers of the tar once, the first time
+// a chunked layer is opened
+type tarChunkPositions struct {
+	once      sync.Once
+	positions map[string]tarEntryPosition
+	err       error
+}
+
+// chunkPositions Returns the position of each chunk in the tar
+func (f tarFile) chunkPositions() (map[string]tarEntryPosition, error) {
+	f.chunks.once.Do(func() {
+		f.chunks.positions, f.chunks.err = f.readChunkPositions()
+	})
+	return f.chunks.positions, f.chunks.err
+}
+
+func (f tarFile) readChunkPositions() (map[string]tarEntryPosition, error) {
+	file, err := openTar(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	positions := map[string]tarEntryPosition{}
+	tf := tar.NewReader(file)
+	for {
+		hdr, err := tf.Next()
+		if err == io.EOF {
+			return positions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(hdr.Name, chunksDir) {
+			continue
+		}
+		// The tar reader does not read ahead, the content of the entry starts at the current position
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("Find current pos: %s", err)
+		}
+		positions[hdr.Name] = tarEntryPosition{offset: offset, size: hdr.Size}
+	}
+}
+
+// openChunkedLayer Returns the content of the layer, reading its chunks one after the other
+func (f tarFile) openChunkedLayer(index chunkIndex) (io.ReadCloser, error) {
+	positions, err := f.chunkPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []tarEntryPosition
+	for _, chunk := range index.Chunks {
+		digest, err := regv1.NewHash(chunk.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing chunk digest of layer '%s': %s", index.Digest, err)
+		}
+		position, found := positions[chunkEntryName(digest)]
+		if !found || position.size != chunk.Size {
+			return nil, util.NonRetryableError{Message: fmt.Sprintf("chunk %s of layer %s not found in tar", chunkEntryName(digest), index.Digest)}
+		}
+		chunks = append(chunks, position)
+	}
+
+	file, err := openTar(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return tarFileChunkReadCloser{
+		DebugID: fmt.Sprintf("%s/%p", chunkIndexSuffix, file),
+		Reader:  &chunksReader{file: file, chunks: chunks}, Closer: file}, nil
+}
+
+// chunksReader Reads the content of the chunks one after the other
+type chunksReader struct {
+	file    io.ReadSeeker
+	chunks  []tarEntryPosition
+	current io.Reader
+}
+
+func (c *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.chunks) == 0 {
+				return 0, io.EOF
+			}
+			_, err := c.file.Seek(c.chunks[0].offset, io.SeekStart)
+			if err != nil {
+				return 0, fmt.Errorf("Seeking to offset: %s", err)
+			}
+			c.current = io.LimitReader(c.file, c.chunks[0].size)
+			c.chunks = c.chunks[1:]
+		}
+
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
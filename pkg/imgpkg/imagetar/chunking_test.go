@@ -0,0 +1,181 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarWriterChunking(t *testing.T) {
+	// Two versions of a gzip layer where only a small part near the end of its file changed. The compressed
+	// content of the layers is the same up to the change, so they share the chunks before it
+	// The file is text, random data is stored by gzip as is and would be shared after the change too
+	file := randomText(rand.New(rand.NewSource(1)), 12<<20)
+	v1Content := gzipTarLayer(t, "app.bin", file)
+	changedFile := append([]byte{}, file...)
+	copy(changedFile[9<<20:], bytes.Repeat([]byte("changed"), 1000))
+	v2Content := gzipTarLayer(t, "app.bin", changedFile)
+
+	var layers []regv1.Layer
+	var descs []imagedesc.ImageOrImageIndexDescriptor
+	for _, content := range [][]byte{v1Content, v2Content} {
+		layer := static.NewLayer(content, types.DockerLayer)
+		layers = append(layers, layer)
+		img, err := mutate.AppendLayers(empty.Image, layer)
+		require.NoError(t, err)
+		desc, _ := describeImage(t, img)
+		descs = append(descs, imagedesc.ImageOrImageIndexDescriptor{Image: &desc})
+	}
+	idsBytes, err := json.Marshal(descs)
+	require.NoError(t, err)
+	ids, err := imagedesc.NewImageRefDescriptorsFromBytes(idsBytes)
+	require.NoError(t, err)
+
+	tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+	opener := func() (io.WriteCloser, error) { return os.Create(tarPath) }
+	err = NewTarWriter(ids, opener, TarWriterOpts{Concurrency: 1, Chunking: true}, util.NewNoopLogger(), NewImageLayerWriterCheck(true), layers).Write()
+	require.NoError(t, err)
+
+	t.Run("the chunks shared by the layers are only written once", func(t *testing.T) {
+		info, err := os.Stat(tarPath)
+		require.NoError(t, err)
+		assert.Less(t, info.Size(), int64(len(v1Content)+len(v2Content)/2))
+
+		indexes, chunks := 0, 0
+		file, err := os.Open(tarPath)
+		require.NoError(t, err)
+		defer file.Close()
+		tarReader := tar.NewReader(file)
+		for {
+			hdr, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			switch {
+			case strings.HasSuffix(hdr.Name, chunkIndexSuffix):
+				indexes++
+			case strings.HasPrefix(hdr.Name, chunksDir):
+				chunks++
+			default:
+				assert.Equal(t, "manifest.json", hdr.Name)
+			}
+		}
+		assert.Equal(t, 2, indexes)
+		assert.Greater(t, chunks, 2)
+	})
+
+	t.Run("the layers are read back from their chunks", func(t *testing.T) {
+		images, err := NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+		require.Len(t, images, 2)
+
+		var contents [][]byte
+		for _, image := range images {
+			imgLayers, err := (*image.Image).Layers()
+			require.NoError(t, err)
+			require.Len(t, imgLayers, 1)
+			reader, err := imgLayers[0].Compressed()
+			require.NoError(t, err)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			contents = append(contents, content)
+		}
+		assert.ElementsMatch(t, [][]byte{v1Content, v2Content}, contents)
+	})
+
+	t.Run("when the layers differ from the start, they do not share chunks", func(t *testing.T) {
+		changedFile := append([]byte{}, file...)
+		copy(changedFile, "changed")
+		otherContent := gzipTarLayer(t, "app.bin", changedFile)
+		layer := static.NewLayer(otherContent, types.DockerLayer)
+		img, err := mutate.AppendLayers(empty.Image, layer)
+		require.NoError(t, err)
+		desc, _ := describeImage(t, img)
+		idsBytes, err := json.Marshal([]imagedesc.ImageOrImageIndexDescriptor{descs[0], {Image: &desc}})
+		require.NoError(t, err)
+		ids, err := imagedesc.NewImageRefDescriptorsFromBytes(idsBytes)
+		require.NoError(t, err)
+
+		otherTarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		opener := func() (io.WriteCloser, error) { return os.Create(otherTarPath) }
+		err = NewTarWriter(ids, opener, TarWriterOpts{Concurrency: 1, Chunking: true}, util.NewNoopLogger(), NewImageLayerWriterCheck(true), []regv1.Layer{layers[0], layer}).Write()
+		require.NoError(t, err)
+
+		info, err := os.Stat(otherTarPath)
+		require.NoError(t, err)
+		assert.Greater(t, info.Size(), int64(len(v1Content)+len(otherContent)))
+	})
+
+	t.Run("the tar is valid", func(t *testing.T) {
+		result, err := NewTarVerifier(tarPath).Verify(true, nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Problems)
+		assert.Equal(t, 2, result.Layers)
+	})
+
+	t.Run("when a chunk is corrupted, it is detected by the deep verification", func(t *testing.T) {
+		content, err := os.ReadFile(tarPath)
+		require.NoError(t, err)
+		// Corrupts a byte of the content of the first chunk, that follows its 512 bytes header
+		corruptedPath := filepath.Join(t.TempDir(), "corrupted.tar")
+		offset := bytes.Index(content, []byte(chunksDir)) + 512 + 100
+		content[offset]++
+		require.NoError(t, os.WriteFile(corruptedPath, content, 0600))
+
+		result, err := NewTarVerifier(corruptedPath).Verify(false, nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Problems)
+
+		result, err = NewTarVerifier(corruptedPath).Verify(true, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.NotEmpty(t, result.Problems)
+		assert.Contains(t, result.Problems[0].Problem, "Expected digest")
+	})
+}
+
+// gzipTarLayer Returns a layer, compressed with gzip, with a single file
+func gzipTarLayer(t *testing.T, name string, content []byte) []byte {
+	var layer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&layer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(content))}))
+	_, err := tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return layer.Bytes()
+}
+
+// randomText Returns size bytes of random words, that gzip compresses
+func randomText(random *rand.Rand, size int) []byte {
+	words := strings.Fields("image bundle layer registry copy push pull tag digest manifest index config blob chunk tar lock")
+	var text bytes.Buffer
+	for text.Len() < size {
+		text.WriteString(words[random.Intn(len(words))])
+		text.WriteString(" ")
+	}
+	return text.Bytes()[:size]
+}
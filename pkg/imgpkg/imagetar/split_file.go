@@ -0,0 +1,245 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+)
+
+// SplitPartPath Path of the part with index of the tar in path, example: bundle.tar.001
+func SplitPartPath(path string, index int) string {
+	return fmt.Sprintf("%s.%03d", path, index)
+}
+
+// SplitParts Returns the paths of the parts of the tar in path, in order, or nothing when the tar is not split
+func SplitParts(path string) []string {
+	var parts []string
+	for index := 0; ; index++ {
+		part := SplitPartPath(path, index)
+		if _, err := os.Stat(part); err != nil {
+			return parts
+		}
+		parts = append(parts, part)
+	}
+}
+
+// TarExists Returns true when the tar in path, or its parts when it is split, are present
+func TarExists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	return len(SplitParts(path)) > 0
+}
+
+// RemoveSplitParts Removes the parts of a split tar in path
+func RemoveSplitParts(path string) error {
+	for _, part := range SplitParts(path) {
+		err := os.Remove(part)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Removing tar part '%s': %s", part, err)
+		}
+	}
+	return nil
+}
+
+// SplitTar Splits the tar in path into parts of at most partSize bytes, named outputPath.000, outputPath.001, ...
+// The parts after the first one are copied out of the tar, which is then truncated and moved to outputPath.000,
+// so that the tar in outputPath only exists once every part is written. The paths of the parts are returned
+func SplitTar(path string, outputPath string, partSize int64) ([]string, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("Expected tar part size to be positive, got %d", partSize)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Opening tar '%s': %s", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("Opening tar '%s': %s", path, err)
+	}
+
+	// Parts left behind by a split that was interrupted are not listed by SplitParts, since the first part is missing
+	for index := 1; ; index++ {
+		err = os.Remove(SplitPartPath(outputPath, index))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Removing tar part '%s': %s", SplitPartPath(outputPath, index), err)
+		}
+	}
+
+	numParts := int((info.Size() + partSize - 1) / partSize)
+	if numParts == 0 {
+		numParts = 1
+	}
+
+	parts := make([]string, numParts)
+	for index := 1; index < numParts; index++ {
+		offset := int64(index) * partSize
+		parts[index] = SplitPartPath(outputPath, index)
+
+		err = writePart(parts[index], io.NewSectionReader(file, offset, partSize), info.Mode())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// What is left of the tar is the first part
+	if numParts > 1 {
+		err = file.Truncate(partSize)
+		if err != nil {
+			return nil, fmt.Errorf("Splitting tar '%s': %s", path, err)
+		}
+	}
+	if atomicfile.FsyncEnabled() {
+		err = file.Sync()
+		if err != nil {
+			return nil, fmt.Errorf("Syncing file '%s': %s", path, err)
+		}
+	}
+	parts[0] = SplitPartPath(outputPath, 0)
+	err = os.Rename(path, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("Moving tar part to '%s': %s", parts[0], err)
+	}
+	return parts, nil
+}
+
+func writePart(path string, content io.Reader, mode os.FileMode) error {
+	part, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return fmt.Errorf("Creating tar part '%s': %s", path, err)
+	}
+	defer part.Close()
+
+	_, err = io.Copy(part, content)
+	if err != nil {
+		return fmt.Errorf("Writing tar part '%s': %s", path, err)
+	}
+	if atomicfile.FsyncEnabled() {
+		err = part.Sync()
+		if err != nil {
+			return fmt.Errorf("Syncing file '%s': %s", path, err)
+		}
+	}
+	return part.Close()
+}
+
+// splitFile Reads the tar in path, or its parts one after the other when it is split, as a single file
+type splitFile struct {
+	files   []*os.File
+	offsets []int64
+	size    int64
+	pos     int64
+}
+
+var _ io.ReadSeekCloser = &splitFile{}
+
+// openTar Opens the tar in path. When it is not present the parts of the split tar are opened instead,
+// and when path is the first part of a split tar all its parts are opened
+func openTar(path string) (*splitFile, error) {
+	result := &splitFile{}
+	for _, filePath := range tarPaths(path) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			result.Close()
+			return nil, err
+		}
+		result.files = append(result.files, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			result.Close()
+			return nil, err
+		}
+		result.offsets = append(result.offsets, result.size)
+		result.size += info.Size()
+	}
+	return result, nil
+}
+
+func tarPaths(path string) []string {
+	if _, err := os.Stat(path); err == nil {
+		if tarPath := strings.TrimSuffix(path, SplitPartPath("", 0)); tarPath != path {
+			if _, err := os.Stat(tarPath); os.IsNotExist(err) {
+				return SplitParts(tarPath)
+			}
+		}
+		return []string{path}
+	}
+	if parts := SplitParts(path); len(parts) > 0 {
+		return parts
+	}
+	return []string{path}
+}
+
+// Size Returns the size of the tar, adding up all its parts
+func (f *splitFile) Size() int64 { return f.size }
+
+// Read Reads from the part that contains the current position
+func (f *splitFile) Read(p []byte) (int, error) {
+	if f.pos >= f.size {
+		return 0, io.EOF
+	}
+
+	index := len(f.files) - 1
+	for index > 0 && f.offsets[index] > f.pos {
+		index--
+	}
+
+	partEnd := f.size
+	if index+1 < len(f.offsets) {
+		partEnd = f.offsets[index+1]
+	}
+	if remaining := partEnd - f.pos; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := f.files[index].ReadAt(p, f.pos-f.offsets[index])
+	f.pos += int64(n)
+	if err == io.EOF && n == len(p) {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek Moves the current position, across parts
+func (f *splitFile) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		pos = f.size + offset
+	default:
+		return 0, fmt.Errorf("Seeking tar: invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("Seeking tar: negative position %d", pos)
+	}
+	f.pos = pos
+	return pos, nil
+}
+
+// Close Closes all the parts
+func (f *splitFile) Close() error {
+	var firstErr error
+	for _, file := range f.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
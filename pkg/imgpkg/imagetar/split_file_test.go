@@ -0,0 +1,55 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTar(t *testing.T) {
+	t.Run("when the partial tar is split, the parts are named after the output and the partial tar is removed", func(t *testing.T) {
+		dir := t.TempDir()
+		partialPath := filepath.Join(dir, "bundle.tar.partial")
+		outputPath := filepath.Join(dir, "bundle.tar")
+		require.NoError(t, os.WriteFile(partialPath, []byte("0123456789"), 0600))
+
+		parts, err := SplitTar(partialPath, outputPath, 4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{SplitPartPath(outputPath, 0), SplitPartPath(outputPath, 1), SplitPartPath(outputPath, 2)}, parts)
+		assert.NoFileExists(t, partialPath)
+		assert.NoFileExists(t, outputPath)
+
+		var content []byte
+		for _, part := range SplitParts(outputPath) {
+			partContent, err := os.ReadFile(part)
+			require.NoError(t, err)
+			content = append(content, partContent...)
+		}
+		assert.Equal(t, "0123456789", string(content))
+	})
+
+	t.Run("when a previous split was interrupted, the parts it left behind are replaced", func(t *testing.T) {
+		dir := t.TempDir()
+		partialPath := filepath.Join(dir, "bundle.tar.partial")
+		outputPath := filepath.Join(dir, "bundle.tar")
+		require.NoError(t, os.WriteFile(partialPath, []byte("01234"), 0600))
+		require.NoError(t, os.WriteFile(SplitPartPath(outputPath, 1), []byte("stale"), 0600))
+		require.NoError(t, os.WriteFile(SplitPartPath(outputPath, 2), []byte("stale"), 0600))
+		assert.False(t, TarExists(outputPath))
+
+		parts, err := SplitTar(partialPath, outputPath, 4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{SplitPartPath(outputPath, 0), SplitPartPath(outputPath, 1)}, parts)
+		assert.Equal(t, parts, SplitParts(outputPath))
+
+		lastPart, err := os.ReadFile(SplitPartPath(outputPath, 1))
+		require.NoError(t, err)
+		assert.Equal(t, "4", string(lastPart))
+	})
+}
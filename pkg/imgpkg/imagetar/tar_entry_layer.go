@@ -0,0 +1,65 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/verify"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// tarEntryLayer layer stored in the tar entry whose header starts at offset.
+// Tar files that were not completely written cannot be read from the beginning,
+// so the position of the entry has to be known upfront.
+type tarEntryLayer struct {
+	path   string
+	offset int64
+	digest regv1.Hash
+	size   int64
+}
+
+// NewTarEntryLayer returns a layer read from the tar entry that starts at offset of the file in path.
+// The content is verified against the digest and size while it is read.
+func NewTarEntryLayer(path string, offset int64, digest regv1.Hash, size int64) (regv1.Layer, error) {
+	return partial.CompressedToLayer(tarEntryLayer{path: path, offset: offset, digest: digest, size: size})
+}
+
+func (l tarEntryLayer) Digest() (regv1.Hash, error) { return l.digest, nil }
+
+func (l tarEntryLayer) Size() (int64, error) { return l.size, nil }
+
+func (l tarEntryLayer) MediaType() (types.MediaType, error) { return types.DockerLayer, nil }
+
+func (l tarEntryLayer) Compressed() (io.ReadCloser, error) {
+	file, err := openTar(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Seek(l.offset, 0)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Seeking to offset: %s", err)
+	}
+
+	tf := tar.NewReader(file)
+	hdr, err := tf.Next()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Reading tar entry at offset %d: %s", l.offset, err)
+	}
+	if hdr.Size != l.size {
+		file.Close()
+		return nil, fmt.Errorf("Expected tar entry '%s' to have size %d but was %d", hdr.Name, l.size, hdr.Size)
+	}
+
+	return verify.ReadCloser(tarFileChunkReadCloser{
+		DebugID: fmt.Sprintf("%s/%p", hdr.Name, tf),
+		Reader:  tf, Closer: file}, l.size, l.digest)
+}
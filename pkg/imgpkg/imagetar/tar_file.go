@@ -7,7 +7,6 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
@@ -17,6 +16,12 @@ import (
 
 type tarFile struct {
 	path string
+	// chunks positions of the chunks in the tar, shared by the copies of the tarFile
+	chunks *tarChunkPositions
+}
+
+func newTarFile(path string) tarFile {
+	return tarFile{path: path, chunks: &tarChunkPositions{}}
 }
 
 var _ imagedesc.LayerProvider = tarFile{}
@@ -43,7 +48,34 @@ func (f tarFile) FindLayer(layerTD imagedesc.ImageLayerDescriptor) (imagedesc.La
 	if err != nil {
 		return nil, err
 	}
-	return tarFileChunk{f, digest.Algorithm + "-" + digest.Hex + ".tar.gz"}, nil
+	return tarFileLayer{f, digest}, nil
+}
+
+// tarFileLayer Layer stored in the tar in a single entry, or as chunks when the tar was written with TarWriterOpts.Chunking
+type tarFileLayer struct {
+	file   tarFile
+	digest regv1.Hash
+}
+
+var _ imagedesc.LayerContents = tarFileLayer{}
+
+func (l tarFileLayer) Open() (io.ReadCloser, error) {
+	stream, err := l.file.openChunk(l.digest.Algorithm + "-" + l.digest.Hex + ".tar.gz")
+	if err == nil {
+		return stream, nil
+	}
+	if _, notFound := err.(util.NonRetryableError); !notFound {
+		return nil, err
+	}
+
+	index, found, indexErr := l.file.readChunkIndex(l.digest)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+	if !found {
+		return nil, err
+	}
+	return l.file.openChunkedLayer(index)
 }
 
 func (f tarFileChunk) Open() (io.ReadCloser, error) {
@@ -51,7 +83,7 @@ func (f tarFileChunk) Open() (io.ReadCloser, error) {
 }
 
 func (f tarFile) openChunk(path string) (io.ReadCloser, error) {
-	file, err := os.Open(f.path)
+	file, err := openTar(f.path)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +94,7 @@ func (f tarFile) openChunk(path string) (io.ReadCloser, error) {
 			break
 		}
 		if err != nil {
+			file.Close()
 			return nil, err
 		}
 		if hdr.Name == path {
@@ -70,6 +103,7 @@ func (f tarFile) openChunk(path string) (io.ReadCloser, error) {
 				Reader:  tf, Closer: file}, nil
 		}
 	}
+	file.Close()
 	return nil, util.NonRetryableError{Message: fmt.Sprintf("file %s not found in tar (hint: This may be because when copying to a tarball, the --include-non-distributable-layers flag should have been provided.)", path)}
 }
 
@@ -4,11 +4,14 @@
 package imagetar
 
 import (
+	"archive/tar"
+	"encoding/json"
 	"fmt"
 	"io"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
 	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/verify"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
@@ -22,7 +25,7 @@ func NewTarReader(path string) TarReader {
 }
 
 func (r TarReader) Read() ([]imagedesc.ImageOrIndex, error) {
-	file := tarFile{r.path}
+	file := newTarFile(r.path)
 
 	ids, err := r.getIdsFromManifest(file)
 	if err != nil {
@@ -130,6 +133,37 @@ func (r TarReader) presentLayersForIndex(indexRef string, idx v1.ImageIndex) ([]
 	return result, nil
 }
 
+// Options Retrieves the imgpkg version and options of the copy that created the tar,
+// returns false when the tar was created by a version of imgpkg that does not record them
+func (r TarReader) Options() (journal.Options, bool, error) {
+	file, err := openTar(r.path)
+	if err != nil {
+		return journal.Options{}, false, err
+	}
+	defer file.Close()
+
+	tarReader := tar.NewReader(file)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			return journal.Options{}, false, nil
+		}
+		if err != nil {
+			return journal.Options{}, false, err
+		}
+		if hdr.Name != optionsEntry {
+			continue
+		}
+
+		var options journal.Options
+		err = json.NewDecoder(tarReader).Decode(&options)
+		if err != nil {
+			return journal.Options{}, false, fmt.Errorf("Parsing '%s': %s", optionsEntry, err)
+		}
+		return options, true, nil
+	}
+}
+
 func (r TarReader) getIdsFromManifest(file tarFile) (*imagedesc.ImageRefDescriptors, error) {
 	manifestFile, err := file.Chunk("manifest.json").Open()
 	if err != nil {
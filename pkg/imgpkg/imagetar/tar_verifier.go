@@ -0,0 +1,349 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	manifestEntry = "manifest.json"
+	// optionsEntry imgpkg version and options of the copy that created the tar
+	optionsEntry = "imgpkg-options.json"
+)
+
+// VerifyProblem Inconsistency found in an entry of the tar
+type VerifyProblem struct {
+	Entry   string `json:"entry"`
+	Problem string `json:"problem"`
+}
+
+// VerifyResult Outcome of the verification of a tar
+type VerifyResult struct {
+	Path  string `json:"path"`
+	Deep  bool   `json:"deep"`
+	Valid bool   `json:"valid"`
+
+	Images  int `json:"images"`
+	Indexes int `json:"indexes"`
+	// Layers number of layers present in the tar, when Deep is true their digest was verified
+	Layers int `json:"layers"`
+	// NonDistributableLayersSkipped non-distributable layers that were not included in the tar
+	NonDistributableLayersSkipped int   `json:"nonDistributableLayersSkipped"`
+	BytesRead                     int64 `json:"bytesRead"`
+
+	Problems []VerifyProblem `json:"problems,omitempty"`
+}
+
+// TarVerifier Checks that a tar created by imgpkg was not corrupted, for example while transferred into an air gapped environment
+type TarVerifier struct {
+	path string
+	file tarFile
+}
+
+// NewTarVerifier Creates a verifier for the tar in path
+func NewTarVerifier(path string) TarVerifier {
+	return TarVerifier{path: path, file: newTarFile(path)}
+}
+
+// Verify Reads the tar once, from start to finish, checking that the internal manifest is consistent and that all the
+// layers it references are present. When deep is true the digest of every layer is also verified.
+// The progress of the verification, in bytes, is sent to updatesCh when it is not nil
+func (v TarVerifier) Verify(deep bool, updatesCh chan regv1.Update) (VerifyResult, error) {
+	result := VerifyResult{Path: v.path, Deep: deep}
+
+	file, err := openTar(v.path)
+	if err != nil {
+		return result, fmt.Errorf("Opening tar '%s': %s", v.path, err)
+	}
+	defer file.Close()
+
+	reader := &progressReader{reader: file, total: file.Size(), updatesCh: updatesCh}
+	presentLayers := map[string]int64{}
+	presentChunks := map[string]int64{}
+	var chunkedLayers []chunkedLayerEntry
+	var ids *imagedesc.ImageRefDescriptors
+
+	tarReader := tar.NewReader(reader)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.addProblem(v.path, fmt.Sprintf("Reading tar: %s", err))
+			break
+		}
+
+		switch {
+		case hdr.Name == manifestEntry:
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Reading entry: %s", err))
+				continue
+			}
+			ids, err = imagedesc.NewImageRefDescriptorsFromBytes(content)
+			if err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Parsing internal manifest: %s", err))
+			}
+
+		case hdr.Name == optionsEntry:
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Reading entry: %s", err))
+				continue
+			}
+			var options journal.Options
+			if err := json.Unmarshal(content, &options); err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Parsing copy options: %s", err))
+			}
+
+		case strings.HasPrefix(hdr.Name, chunksDir):
+			digest, err := regv1.NewHash(strings.Replace(strings.TrimPrefix(hdr.Name, chunksDir), "-", ":", 1))
+			if err != nil {
+				result.addProblem(hdr.Name, "Unexpected entry")
+				continue
+			}
+			presentChunks[hdr.Name] = hdr.Size
+
+			if deep {
+				err = verifyDigest(digest, tarReader)
+				if err != nil {
+					result.addProblem(hdr.Name, err.Error())
+				}
+			}
+
+		case strings.HasSuffix(hdr.Name, chunkIndexSuffix):
+			digest, err := regv1.NewHash(strings.Replace(strings.TrimSuffix(hdr.Name, chunkIndexSuffix), "-", ":", 1))
+			if err != nil {
+				result.addProblem(hdr.Name, "Unexpected entry")
+				continue
+			}
+			content, err := io.ReadAll(tarReader)
+			if err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Reading entry: %s", err))
+				continue
+			}
+			var index chunkIndex
+			if err := json.Unmarshal(content, &index); err != nil {
+				result.addProblem(hdr.Name, fmt.Sprintf("Parsing chunk index: %s", err))
+				continue
+			}
+			if index.Digest != digest.String() {
+				result.addProblem(hdr.Name, fmt.Sprintf("Expected chunk index of layer '%s' but found '%s'", digest, index.Digest))
+				continue
+			}
+			presentLayers[digest.String()] = index.Size
+			chunkedLayers = append(chunkedLayers, chunkedLayerEntry{name: hdr.Name, digest: digest, index: index})
+
+		case strings.HasSuffix(hdr.Name, ".tar.gz"):
+			digest, err := regv1.NewHash(strings.Replace(strings.TrimSuffix(hdr.Name, ".tar.gz"), "-", ":", 1))
+			if err != nil {
+				result.addProblem(hdr.Name, "Unexpected entry")
+				continue
+			}
+			presentLayers[digest.String()] = hdr.Size
+
+			if deep {
+				err = verifyDigest(digest, tarReader)
+				if err != nil {
+					result.addProblem(hdr.Name, err.Error())
+				}
+			}
+
+		default:
+			result.addProblem(hdr.Name, "Unexpected entry")
+		}
+	}
+	result.BytesRead = reader.read
+
+	// The chunks of a layer are written before its index, but they can be shared with the layers written after it
+	for _, layer := range chunkedLayers {
+		v.verifyChunkedLayer(&result, layer, presentChunks, deep)
+	}
+
+	if ids == nil {
+		result.addProblem(manifestEntry, "Missing internal manifest (hint: was the tar created by imgpkg copy --to-tar?)")
+	} else {
+		verifier := descriptorsVerifier{result: &result, presentLayers: presentLayers, checkedLayers: map[string]bool{}}
+		for _, desc := range ids.Descriptors() {
+			switch {
+			case desc.Image != nil:
+				verifier.verifyImage(*desc.Image)
+			case desc.ImageIndex != nil:
+				verifier.verifyIndex(*desc.ImageIndex)
+			}
+		}
+		result.Layers = len(presentLayers)
+	}
+
+	sort.SliceStable(result.Problems, func(i, j int) bool {
+		return result.Problems[i].Entry < result.Problems[j].Entry
+	})
+	result.Valid = len(result.Problems) == 0
+	return result, nil
+}
+
+// chunkedLayerEntry Layer of the tar stored as chunks, see TarWriterOpts.Chunking
+type chunkedLayerEntry struct {
+	name   string
+	digest regv1.Hash
+	index  chunkIndex
+}
+
+// verifyChunkedLayer Checks that all the chunks of the layer are present with the expected sizes.
+// When deep is true the digest of the layer is also verified, by reading its chunks one after the other
+func (v TarVerifier) verifyChunkedLayer(result *VerifyResult, layer chunkedLayerEntry, presentChunks map[string]int64, deep bool) {
+	valid := true
+	var size int64
+	for _, chunk := range layer.index.Chunks {
+		digest, err := regv1.NewHash(chunk.Digest)
+		if err != nil {
+			result.addProblem(layer.name, fmt.Sprintf("Invalid chunk digest '%s': %s", chunk.Digest, err))
+			valid = false
+			continue
+		}
+		presentSize, present := presentChunks[chunkEntryName(digest)]
+		switch {
+		case !present:
+			result.addProblem(layer.name, fmt.Sprintf("Missing chunk '%s'", chunkEntryName(digest)))
+			valid = false
+		case presentSize != chunk.Size:
+			result.addProblem(layer.name, fmt.Sprintf("Expected chunk '%s' to have size %d but found %d", chunkEntryName(digest), chunk.Size, presentSize))
+			valid = false
+		}
+		size += chunk.Size
+	}
+	if size != layer.index.Size {
+		result.addProblem(layer.name, fmt.Sprintf("Expected chunks to add up to size %d but found %d", layer.index.Size, size))
+		valid = false
+	}
+	if !deep || !valid {
+		return
+	}
+
+	stream, err := v.file.openChunkedLayer(layer.index)
+	if err != nil {
+		result.addProblem(layer.name, fmt.Sprintf("Reading chunks: %s", err))
+		return
+	}
+	defer stream.Close()
+	err = verifyDigest(layer.digest, stream)
+	if err != nil {
+		result.addProblem(layer.name, err.Error())
+	}
+}
+
+func (r *VerifyResult) addProblem(entry, problem string) {
+	r.Problems = append(r.Problems, VerifyProblem{Entry: entry, Problem: problem})
+}
+
+type descriptorsVerifier struct {
+	result        *VerifyResult
+	presentLayers map[string]int64
+	checkedLayers map[string]bool
+}
+
+func (d descriptorsVerifier) verifyIndex(td imagedesc.ImageIndexDescriptor) {
+	d.result.Indexes++
+	d.verifyRaw(td.Digest, td.Raw, "image index")
+
+	for _, idx := range td.Indexes {
+		d.verifyIndex(idx)
+	}
+	for _, img := range td.Images {
+		d.verifyImage(img)
+	}
+}
+
+func (d descriptorsVerifier) verifyImage(td imagedesc.ImageDescriptor) {
+	d.result.Images++
+	d.verifyRaw(td.Manifest.Digest, td.Manifest.Raw, "manifest")
+	d.verifyRaw(td.Config.Digest, string(td.Config.Bytes()), "config")
+
+	for _, layer := range td.Layers {
+		if d.checkedLayers[layer.Digest] {
+			continue
+		}
+		d.checkedLayers[layer.Digest] = true
+
+		digest, err := regv1.NewHash(layer.Digest)
+		if err != nil {
+			d.result.addProblem(manifestEntry, fmt.Sprintf("Invalid layer digest '%s': %s", layer.Digest, err))
+			continue
+		}
+		entry := digest.Algorithm + "-" + digest.Hex + ".tar.gz"
+
+		size, present := d.presentLayers[layer.Digest]
+		switch {
+		case !present && !layer.IsDistributable():
+			d.result.NonDistributableLayersSkipped++
+		case !present:
+			d.result.addProblem(entry, "Missing layer")
+		case size != layer.Size:
+			d.result.addProblem(entry, fmt.Sprintf("Expected size %d but found %d", layer.Size, size))
+		}
+	}
+}
+
+func (d descriptorsVerifier) verifyRaw(expectedDigest, raw, kind string) {
+	digest, _, err := regv1.SHA256(strings.NewReader(raw))
+	if err != nil {
+		d.result.addProblem(manifestEntry, fmt.Sprintf("Computing digest of %s '%s': %s", kind, expectedDigest, err))
+		return
+	}
+	if digest.String() != expectedDigest {
+		d.result.addProblem(manifestEntry, fmt.Sprintf("Expected %s digest '%s' but content has digest '%s'", kind, expectedDigest, digest))
+	}
+}
+
+func verifyDigest(expected regv1.Hash, reader io.Reader) error {
+	if expected.Algorithm != "sha256" {
+		return fmt.Errorf("Unsupported digest algorithm '%s'", expected.Algorithm)
+	}
+
+	hasher := sha256.New()
+	_, err := io.Copy(hasher, reader)
+	if err != nil {
+		return fmt.Errorf("Reading entry: %s", err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if actual != expected.Hex {
+		return fmt.Errorf("Expected digest '%s' but content has digest 'sha256:%s'", expected, actual)
+	}
+	return nil
+}
+
+// progressReader Reports the number of bytes read to updatesCh
+type progressReader struct {
+	reader    io.Reader
+	total     int64
+	read      int64
+	updatesCh chan regv1.Update
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+	if p.updatesCh != nil && n > 0 {
+		// Updates are dropped while the previous one is being displayed to avoid slowing down the read
+		select {
+		case p.updatesCh <- regv1.Update{Total: p.total, Complete: p.read}:
+		default:
+		}
+	}
+	return n, err
+}
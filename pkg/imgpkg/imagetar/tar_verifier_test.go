@@ -0,0 +1,212 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarVerifier(t *testing.T) {
+	img, err := random.Image(500, 2)
+	require.NoError(t, err)
+	desc, layers := describeImage(t, img)
+	firstLayer := desc.Layers[0]
+	firstLayerEntry := layerEntryName(t, firstLayer.Digest)
+
+	t.Run("when the tar is complete, it is valid", func(t *testing.T) {
+		tarPath := writeVerifierTar(t, []imagedesc.ImageDescriptor{desc}, layers)
+
+		updatesCh := make(chan regv1.Update, 100)
+		result, err := NewTarVerifier(tarPath).Verify(true, updatesCh)
+		require.NoError(t, err)
+
+		assert.True(t, result.Valid)
+		assert.Empty(t, result.Problems)
+		assert.Equal(t, 1, result.Images)
+		assert.Equal(t, 2, result.Layers)
+
+		info, err := os.Stat(tarPath)
+		require.NoError(t, err)
+		assert.Equal(t, info.Size(), result.BytesRead)
+		assert.NotEmpty(t, updatesCh)
+	})
+
+	t.Run("when a layer is corrupted, it is only detected by the deep verification", func(t *testing.T) {
+		corruptedLayers := map[string][]byte{}
+		for digest, content := range layers {
+			corruptedLayers[digest] = content
+		}
+		corrupted := append([]byte{}, layers[firstLayer.Digest]...)
+		corrupted[len(corrupted)-1]++
+		corruptedLayers[firstLayer.Digest] = corrupted
+		tarPath := writeVerifierTar(t, []imagedesc.ImageDescriptor{desc}, corruptedLayers)
+
+		result, err := NewTarVerifier(tarPath).Verify(false, nil)
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+
+		result, err = NewTarVerifier(tarPath).Verify(true, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.Len(t, result.Problems, 1)
+		assert.Equal(t, firstLayerEntry, result.Problems[0].Entry)
+		assert.Contains(t, result.Problems[0].Problem, "Expected digest '"+firstLayer.Digest+"'")
+	})
+
+	t.Run("when a layer is missing, it reports the missing layer", func(t *testing.T) {
+		missingLayers := map[string][]byte{}
+		for digest, content := range layers {
+			if digest != firstLayer.Digest {
+				missingLayers[digest] = content
+			}
+		}
+		tarPath := writeVerifierTar(t, []imagedesc.ImageDescriptor{desc}, missingLayers)
+
+		result, err := NewTarVerifier(tarPath).Verify(false, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, []VerifyProblem{{Entry: firstLayerEntry, Problem: "Missing layer"}}, result.Problems)
+	})
+
+	t.Run("when a non-distributable layer was not included, it is skipped", func(t *testing.T) {
+		nonDistributableDesc := desc
+		nonDistributableDesc.Layers = append([]imagedesc.ImageLayerDescriptor{}, desc.Layers...)
+		nonDistributableDesc.Layers[0].MediaType = "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"
+		missingLayers := map[string][]byte{}
+		for digest, content := range layers {
+			if digest != firstLayer.Digest {
+				missingLayers[digest] = content
+			}
+		}
+		tarPath := writeVerifierTar(t, []imagedesc.ImageDescriptor{nonDistributableDesc}, missingLayers)
+
+		result, err := NewTarVerifier(tarPath).Verify(true, nil)
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, 1, result.NonDistributableLayersSkipped)
+	})
+
+	t.Run("when the manifest of an image does not match its digest, it reports the internal manifest", func(t *testing.T) {
+		tamperedDesc := desc
+		tamperedDesc.Manifest.Raw += " "
+		tarPath := writeVerifierTar(t, []imagedesc.ImageDescriptor{tamperedDesc}, layers)
+
+		result, err := NewTarVerifier(tarPath).Verify(false, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.Len(t, result.Problems, 1)
+		assert.Equal(t, "manifest.json", result.Problems[0].Entry)
+		assert.Contains(t, result.Problems[0].Problem, "Expected manifest digest '"+desc.Manifest.Digest+"'")
+	})
+
+	t.Run("when the internal manifest is missing, it is not valid", func(t *testing.T) {
+		tarPath := writeVerifierTar(t, nil, layers)
+
+		result, err := NewTarVerifier(tarPath).Verify(true, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.Len(t, result.Problems, 1)
+		assert.Equal(t, "manifest.json", result.Problems[0].Entry)
+		assert.Contains(t, result.Problems[0].Problem, "Missing internal manifest")
+	})
+
+	t.Run("when the file does not exist, it returns an error", func(t *testing.T) {
+		_, err := NewTarVerifier(filepath.Join(t.TempDir(), "missing.tar")).Verify(true, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Opening tar")
+	})
+}
+
+func describeImage(t *testing.T, img regv1.Image) (imagedesc.ImageDescriptor, map[string][]byte) {
+	manifest, err := img.RawManifest()
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	config, err := img.RawConfigFile()
+	require.NoError(t, err)
+	configName, err := img.ConfigName()
+	require.NoError(t, err)
+	mediaType, err := img.MediaType()
+	require.NoError(t, err)
+
+	desc := imagedesc.ImageDescriptor{
+		Refs:     []string{"index.docker.io/library/img@" + digest.String()},
+		Config:   imagedesc.ConfigDescriptor{Digest: configName.String(), Raw: string(config)},
+		Manifest: imagedesc.ManifestDescriptor{MediaType: string(mediaType), Digest: digest.String(), Raw: string(manifest)},
+	}
+	layerContents := map[string][]byte{}
+
+	imgLayers, err := img.Layers()
+	require.NoError(t, err)
+	for _, layer := range imgLayers {
+		layerDigest, err := layer.Digest()
+		require.NoError(t, err)
+		diffID, err := layer.DiffID()
+		require.NoError(t, err)
+		layerMediaType, err := layer.MediaType()
+		require.NoError(t, err)
+		reader, err := layer.Compressed()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		desc.Layers = append(desc.Layers, imagedesc.ImageLayerDescriptor{
+			MediaType: string(layerMediaType),
+			Digest:    layerDigest.String(),
+			DiffID:    diffID.String(),
+			Size:      int64(len(content)),
+		})
+		layerContents[layerDigest.String()] = content
+	}
+	return desc, layerContents
+}
+
+// writeVerifierTar Writes a tar with the same structure as the ones created by TarWriter
+func writeVerifierTar(t *testing.T, images []imagedesc.ImageDescriptor, layers map[string][]byte) string {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	if images != nil {
+		var descs []imagedesc.ImageOrImageIndexDescriptor
+		for i := range images {
+			descs = append(descs, imagedesc.ImageOrImageIndexDescriptor{Image: &images[i]})
+		}
+		manifest, err := json.Marshal(descs)
+		require.NoError(t, err)
+		writeVerifierTarEntry(t, tw, "manifest.json", manifest)
+	}
+
+	for digest, content := range layers {
+		writeVerifierTarEntry(t, tw, layerEntryName(t, digest), content)
+	}
+	require.NoError(t, tw.Close())
+
+	tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+	require.NoError(t, os.WriteFile(tarPath, buf.Bytes(), 0600))
+	return tarPath
+}
+
+func writeVerifierTarEntry(t *testing.T, tw *tar.Writer, name string, content []byte) {
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0400, Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+}
+
+func layerEntryName(t *testing.T, digest string) string {
+	hash, err := regv1.NewHash(digest)
+	require.NoError(t, err)
+	return hash.Algorithm + "-" + hash.Hex + ".tar.gz"
+}
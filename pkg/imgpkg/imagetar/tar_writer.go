@@ -6,6 +6,7 @@ package imagetar
 import (
 	"archive/tar"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 )
@@ -21,8 +23,20 @@ type Logger interface {
 	Logf(str string, args ...interface{})
 }
 
+// LayerJournal is notified of every layer once its content was fully written and synced to disk
+type LayerJournal interface {
+	LayerWritten(name string, digest string, offset int64, size int64) error
+}
+
 type TarWriterOpts struct {
 	Concurrency int
+	// Journal when provided layers are synced to disk as they are written, and recorded in the journal
+	Journal LayerJournal
+	// Options when provided the imgpkg version and options of the copy are recorded in the tar
+	Options *journal.Options
+	// Chunking when true layers are written as content-defined chunks, each chunk once, so that layers with
+	// partially the same content share their chunks. Layers are written one at a time and are not journaled
+	Chunking bool
 }
 
 type TarWriter struct {
@@ -75,6 +89,17 @@ func (w *TarWriter) Write() error {
 		return err
 	}
 
+	if w.opts.Options != nil {
+		optionsBytes, err := json.Marshal(w.opts.Options)
+		if err != nil {
+			return err
+		}
+		err = w.writeTarEntry(w.tf, optionsEntry, bytes.NewReader(optionsBytes), int64(len(optionsBytes)))
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, td := range w.ids.Descriptors() {
 		switch {
 		case td.Image != nil:
@@ -140,6 +165,10 @@ func (w *TarWriter) writeLayers() error {
 		return w.layersToWrite[i].Digest < w.layersToWrite[j].Digest
 	})
 
+	if w.opts.Chunking {
+		return w.writeChunkedLayers()
+	}
+
 	seekableDst, isSeekable := w.dst.(*os.File)
 	isInflatable := (w.opts.Concurrency > 1) && isSeekable
 	writtenLayers := map[string]writtenLayer{}
@@ -163,7 +192,7 @@ func (w *TarWriter) writeLayers() error {
 			return err
 		}
 
-		var stream io.Reader
+		var stream io.ReadCloser
 		var currPos int64
 
 		if isSeekable {
@@ -173,41 +202,32 @@ func (w *TarWriter) writeLayers() error {
 			}
 		}
 
-		if isInflatable {
-			stream = nil
-		} else {
-			for _, layer := range w.layersFromOtherSource {
-				d, err := layer.Digest()
-				if err != nil {
-					return fmt.Errorf("Retrieving digest: %s", err)
-				}
-				if d.String() == imgLayer.Digest {
-					stream, err = layer.Compressed()
-					if err != nil {
-						return fmt.Errorf("Retrieve layer from file: %s", err)
-					}
-					break
-				}
-			}
-
-			if stream == nil {
-				foundLayer, err := w.ids.FindLayer(imgLayer)
-				if err != nil {
-					return err
-				}
-
-				stream, err = foundLayer.Open()
-				if err != nil {
-					return err
-				}
+		if !isInflatable {
+			stream, err = w.openLayer(imgLayer)
+			if err != nil {
+				return err
 			}
 		}
 
 		err = w.writeTarEntry(w.tf, name, stream, imgLayer.Size)
+		if stream != nil {
+			stream.Close()
+		}
 		if err != nil {
 			return fmt.Errorf("Writing tar entry: %s", err)
 		}
 
+		if !isInflatable && isSeekable {
+			err = w.tf.Flush()
+			if err != nil {
+				return err
+			}
+			err = w.recordLayer(seekableDst, name, imgLayer, currPos)
+			if err != nil {
+				return err
+			}
+		}
+
 		writtenLayers[name] = writtenLayer{
 			Name:   name,
 			Layer:  imgLayer,
@@ -282,22 +302,62 @@ func (w *TarWriter) fillInLayer(wl writtenLayer) error {
 	tw := tar.NewWriter(file)
 	// Do not close tar writer as it would add unwanted footer
 
-	foundLayer, err := w.ids.FindLayer(wl.Layer)
+	stream, err := w.openLayer(wl.Layer)
 	if err != nil {
 		return err
 	}
+	defer stream.Close()
+
+	err = w.writeTarEntry(tw, wl.Name, stream, wl.Layer.Size)
+	if err != nil {
+		return fmt.Errorf("Rewriting tar entry (%s): %s", wl.Name, err)
+	}
 
-	stream, err := foundLayer.Open()
+	err = tw.Flush()
 	if err != nil {
 		return err
 	}
 
-	err = w.writeTarEntry(tw, wl.Name, stream, wl.Layer.Size)
+	return w.recordLayer(file.(*os.File), wl.Name, wl.Layer, wl.Offset)
+}
+
+// openLayer prefers the layers provided by other sources, like a previous tar, before downloading the layer
+func (w *TarWriter) openLayer(imgLayer imagedesc.ImageLayerDescriptor) (io.ReadCloser, error) {
+	for _, layer := range w.layersFromOtherSource {
+		d, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("Retrieving digest: %s", err)
+		}
+		if d.String() == imgLayer.Digest {
+			stream, err := layer.Compressed()
+			if err != nil {
+				return nil, fmt.Errorf("Retrieve layer from file: %s", err)
+			}
+			return stream, nil
+		}
+	}
+
+	foundLayer, err := w.ids.FindLayer(imgLayer)
 	if err != nil {
-		return fmt.Errorf("Rewriting tar entry (%s): %s", wl.Name, err)
+		return nil, err
+	}
+
+	return foundLayer.Open()
+}
+
+// recordLayer syncs the tar file before recording the layer in the journal.
+// Only after the content reached the disk the layer can be reused by a resumed copy
+func (w *TarWriter) recordLayer(file *os.File, name string, imgLayer imagedesc.ImageLayerDescriptor, offset int64) error {
+	if w.opts.Journal == nil {
+		return nil
+	}
+
+	err := file.Sync()
+	if err != nil {
+		return fmt.Errorf("Syncing tar file: %s", err)
 	}
 
-	return tw.Flush()
+	return w.opts.Journal.LayerWritten(name, imgLayer.Digest, offset, imgLayer.Size)
 }
 
 func (w *TarWriter) writeTarEntry(tw *tar.Writer, path string, r io.Reader, size int64) error {
@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package artifact identifies the OCI artifacts, like CNAB bundles or ORAS artifacts, whose config is not an image config,
+// so that they are relocated as they are instead of being read as container images
+package artifact
+
+import (
+	"encoding/json"
+	"fmt"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// manifest Fields of a manifest identifying the type of the artifact, artifactType is not part of regv1.Manifest
+type manifest struct {
+	ArtifactType string `json:"artifactType,omitempty"`
+	Config       struct {
+		MediaType types.MediaType `json:"mediaType"`
+	} `json:"config"`
+}
+
+// IsImageConfig Returns true when the config with mediaType is the config of a container image
+func IsImageConfig(mediaType types.MediaType) bool {
+	switch mediaType {
+	case types.OCIConfigJSON, types.DockerConfigJSON, "":
+		return true
+	default:
+		return false
+	}
+}
+
+// TypeFromManifest Returns the type of the artifact of the raw manifest, its artifactType or, when it does not have one,
+// the media type of its config when it is not an image config. Returns "" for container images
+func TypeFromManifest(rawManifest []byte) (string, error) {
+	var m manifest
+	err := json.Unmarshal(rawManifest, &m)
+	if err != nil {
+		return "", fmt.Errorf("Parsing manifest: %s", err)
+	}
+	if m.ArtifactType != "" {
+		return m.ArtifactType, nil
+	}
+	if !IsImageConfig(m.Config.MediaType) {
+		return string(m.Config.MediaType), nil
+	}
+	return "", nil
+}
+
+// HasImageConfig Returns true when the config of img is an image config, that can be read with ConfigFile,
+// false for the artifacts whose config has another format, which is only readable as raw bytes
+func HasImageConfig(img regv1.Image) (bool, error) {
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return false, err
+	}
+	var m manifest
+	err = json.Unmarshal(rawManifest, &m)
+	if err != nil {
+		return false, fmt.Errorf("Parsing manifest: %s", err)
+	}
+	return IsImageConfig(m.Config.MediaType), nil
+}
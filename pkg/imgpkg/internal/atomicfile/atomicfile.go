@@ -0,0 +1,83 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package atomicfile writes files through a temporary file that is renamed into place, so that a crash
+// leaves either the previous content or the new one, never a truncated file that looks valid.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var fsyncDisabled atomic.Bool
+
+// SetFsync Configures if the files are flushed to disk before being renamed into place (default: true).
+// Disabling it keeps the writes atomic for the processes reading the files, but a power loss might leave
+// an empty file behind
+func SetFsync(enabled bool) {
+	fsyncDisabled.Store(!enabled)
+}
+
+// FsyncEnabled Returns true when the files should be flushed to disk before being renamed into place
+func FsyncEnabled() bool {
+	return !fsyncDisabled.Load()
+}
+
+// WriteFile Writes data to the file in path, replacing it atomically when it already exists
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmpFile, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("Creating temporary file for '%s': %s", path, err)
+	}
+	tmpPath := tmpFile.Name()
+
+	err = writeAndClose(tmpFile, data, perm)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("Writing '%s': %s", path, err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("Moving temporary file to '%s': %s", path, err)
+	}
+
+	if FsyncEnabled() {
+		return SyncDir(dir)
+	}
+	return nil
+}
+
+// SyncDir fsyncs a folder, making sure files created, renamed or removed in it survive a crash
+func SyncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Opening folder '%s': %s", path, err)
+	}
+	defer dir.Close()
+
+	err = dir.Sync()
+	if err != nil {
+		return fmt.Errorf("Syncing folder '%s': %s", path, err)
+	}
+	return nil
+}
+
+func writeAndClose(file *os.File, data []byte, perm os.FileMode) error {
+	_, err := file.Write(data)
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if err == nil && FsyncEnabled() {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
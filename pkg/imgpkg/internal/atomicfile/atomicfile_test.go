@@ -0,0 +1,62 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package atomicfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Run("when the file exists, it replaces the content and leaves no temporary files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "images.lock.yml")
+		require.NoError(t, os.WriteFile(path, []byte("previous"), 0644))
+
+		require.NoError(t, atomicfile.WriteFile(path, []byte("new content"), 0600))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new content", string(content))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("when the file cannot be moved into place, it removes the temporary file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "taken")
+		require.NoError(t, os.MkdirAll(filepath.Join(path, "child"), 0755))
+
+		err := atomicfile.WriteFile(path, []byte("content"), 0600)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Moving temporary file to")
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("when fsync is disabled, it still writes the file", func(t *testing.T) {
+		atomicfile.SetFsync(false)
+		defer atomicfile.SetFsync(true)
+
+		path := filepath.Join(t.TempDir(), "relocation.yml")
+		require.NoError(t, atomicfile.WriteFile(path, []byte("content"), 0600))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cdc splits content into chunks whose boundaries are chosen by a rolling hash of the last bytes read
+// (content-defined chunking, in the style of FastCDC), so that the content that did not change between two versions
+// results in the same chunks, even when bytes were added or removed before it.
+package cdc
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+)
+
+// gearTable random values of the bytes in the rolling hash. They are generated from a fixed seed so that the
+// same content is split at the same boundaries by every version of imgpkg
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x696d67706b67)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		value := seed
+		value = (value ^ (value >> 30)) * 0xbf58476d1ce4e5b9
+		value = (value ^ (value >> 27)) * 0x94d049bb133111eb
+		table[i] = value ^ (value >> 31)
+	}
+	return table
+}()
+
+// Sizes Sizes, in bytes, of the chunks
+type Sizes struct {
+	Min int
+	// Avg average size of the chunks, a power of 2
+	Avg int
+	Max int
+}
+
+// Chunker Splits the content of a reader into chunks
+type Chunker struct {
+	reader *bufio.Reader
+	sizes  Sizes
+	buf    []byte
+	// maskSmall checked before the average size is reached, it has more bits so that boundaries are less likely,
+	// and maskLarge after it, so that the sizes of the chunks are normalized around the average
+	maskSmall uint64
+	maskLarge uint64
+}
+
+// NewChunker Creates a Chunker of the content of reader, sizes.Avg is expected to be a power of 2 between sizes.Min and sizes.Max
+func NewChunker(reader io.Reader, sizes Sizes) *Chunker {
+	avgBits := bits.Len(uint(sizes.Avg)) - 1
+	return &Chunker{
+		reader:    bufio.NewReaderSize(reader, 64<<10),
+		sizes:     sizes,
+		maskSmall: topBits(avgBits + 1),
+		maskLarge: topBits(avgBits - 1),
+	}
+}
+
+// topBits Returns a mask with the n top bits set, the top bits of the rolling hash depend on the last 64 bytes read
+func topBits(n int) uint64 {
+	return ^uint64(0) << (64 - n)
+}
+
+// Next Returns the next chunk, valid until the following call, or io.EOF once all the content was read
+func (c *Chunker) Next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var hash uint64
+	for len(c.buf) < c.sizes.Max {
+		b, err := c.reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, b)
+		hash = (hash << 1) + gearTable[b]
+
+		switch {
+		case len(c.buf) < c.sizes.Min:
+		case len(c.buf) < c.sizes.Avg:
+			if hash&c.maskSmall == 0 {
+				return c.buf, nil
+			}
+		default:
+			if hash&c.maskLarge == 0 {
+				return c.buf, nil
+			}
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+	return c.buf, nil
+}
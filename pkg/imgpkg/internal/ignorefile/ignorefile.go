@@ -0,0 +1,145 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ignorefile reads the .imgpkgignore file of the directories pushed, written with the gitignore syntax,
+// so that build artifacts and editor files are left out of the images without restructuring the directories.
+package ignorefile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileName name of the file, in the root of a directory pushed, with the patterns of the paths left out
+const FileName = ".imgpkgignore"
+
+// pattern Line of the ignore file
+type pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher Matches the paths, relative to the directory of the ignore file, against its patterns.
+// A nil Matcher does not match any path
+type Matcher struct {
+	patterns []pattern
+}
+
+// Load Reads the ignore file in the root of dir, returns a nil Matcher when dir does not have one
+func Load(dir string) (*Matcher, error) {
+	contents, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Reading '%s': %s", filepath.Join(dir, FileName), err)
+	}
+	return Parse(contents)
+}
+
+// Parse Parses the patterns of an ignore file, with the gitignore syntax
+func Parse(contents []byte) (*Matcher, error) {
+	m := &Matcher{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		p, ok := parsePattern(line)
+		if !ok {
+			continue
+		}
+		for _, segment := range p.segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("Parsing %s line %d '%s': %s", FileName, lineNum, line, err)
+			}
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, scanner.Err()
+}
+
+// parsePattern Parses a line, returns false for blank lines and comments
+func parsePattern(line string) (pattern, bool) {
+	// trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimSuffix(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// patterns without a slash, other than the trailing one, match at any level
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	p.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	return p, true
+}
+
+// Match Returns true when relPath, relative to the directory of the ignore file, is left out.
+// As with git, the last pattern matching the path decides, and the paths inside a directory
+// left out are expected to be skipped without being matched
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, segments) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments Matches the segments of a path against the segments of a pattern, where ** matches any number of them
+func matchSegments(patternSegments, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+	if patternSegments[0] == "**" {
+		// a trailing ** matches everything inside, but not the directory itself
+		if len(patternSegments) == 1 {
+			return len(pathSegments) > 0
+		}
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pathSegments) == 0 {
+		return false
+	}
+	matched, err := path.Match(patternSegments[0], pathSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchSegments(patternSegments[1:], pathSegments[1:])
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package ignorefile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/ignorefile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	matcher, err := ignorefile.Parse([]byte(`# build artifacts
+build/
+/dist
+*.swp
+!keep.swp
+docs/**/*.png
+logs/**
+\#notes.txt
+`))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{path: "build", isDir: true, ignored: true},
+		{path: "config/build", isDir: true, ignored: true},
+		{path: "build", isDir: false, ignored: false},
+		{path: "dist", isDir: true, ignored: true},
+		{path: "config/dist", isDir: true, ignored: false},
+		{path: "values.yml.swp", ignored: true},
+		{path: "config/values.yml.swp", ignored: true},
+		{path: "config/keep.swp", ignored: false},
+		{path: "docs/logo.png", ignored: true},
+		{path: "docs/images/logo.png", ignored: true},
+		{path: "logo.png", ignored: false},
+		{path: "logs", isDir: true, ignored: false},
+		{path: "logs/app.log", ignored: true},
+		{path: "#notes.txt", ignored: true},
+		{path: "config/values.yml", ignored: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.ignored, matcher.Match(filepath.FromSlash(tc.path), tc.isDir), tc.path)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("returns a matcher that does not match any path when the directory does not have an ignore file", func(t *testing.T) {
+		matcher, err := ignorefile.Load(t.TempDir())
+		require.NoError(t, err)
+		assert.False(t, matcher.Match("build", true))
+	})
+
+	t.Run("reads the ignore file in the root of the directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ignorefile.FileName), []byte("build/\r\n"), 0600))
+		matcher, err := ignorefile.Load(dir)
+		require.NoError(t, err)
+		assert.True(t, matcher.Match("build", true))
+	})
+
+	t.Run("fails when a pattern is malformed", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ignorefile.FileName), []byte("*.swp\n[abc\n"), 0600))
+		_, err := ignorefile.Load(dir)
+		require.ErrorContains(t, err, "Parsing .imgpkgignore line 2 '[abc'")
+	})
+}
@@ -0,0 +1,273 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package journal keeps an append only record of the work completed by a copy, so that an
+// interrupted copy can be resumed without trusting the state of a partially written destination.
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+)
+
+// Kind Type of artifact recorded in the journal
+type Kind string
+
+const (
+	// LayerKind Layer fully written to a tar file
+	LayerKind Kind = "layer"
+	// ImageKind Image or Index written and verified in a repository
+	ImageKind Kind = "image"
+	// OptionsKind imgpkg version and options of the copy that created the journal, always the first entry
+	OptionsKind Kind = "options"
+)
+
+// Entry Single unit of work that was completed
+type Entry struct {
+	Kind   Kind   `json:"kind"`
+	Digest string `json:"digest"`
+	// Ref destination tag for images
+	Ref string `json:"ref,omitempty"`
+	// File name, relative to the journal folder, of the tar containing the layer
+	File string `json:"file,omitempty"`
+	// Offset position of the tar header of the layer
+	Offset int64 `json:"offset,omitempty"`
+	Size   int64 `json:"size,omitempty"`
+	// Options of the copy, only present in OptionsKind entries
+	Options *Options `json:"options,omitempty"`
+}
+
+// Options imgpkg version and settings of a copy. Settings are the options that change what is written,
+// for example the layers that are filtered out, so a copy can only be resumed with the same settings
+type Options struct {
+	ImgpkgVersion string            `json:"imgpkgVersion"`
+	Settings      map[string]string `json:"settings,omitempty"`
+}
+
+// CheckResumableBy Returns an error explaining the differences when a copy with the current options cannot
+// resume the work done by a copy with these options
+func (o Options) CheckResumableBy(current Options) error {
+	var keys []string
+	for key := range o.Settings {
+		keys = append(keys, key)
+	}
+	for key := range current.Settings {
+		if _, found := o.Settings[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var differences []string
+	for _, key := range keys {
+		if o.Settings[key] != current.Settings[key] {
+			differences = append(differences, fmt.Sprintf("- %s: was '%s', is now '%s'", key, o.Settings[key], current.Settings[key]))
+		}
+	}
+	if len(differences) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Unable to resume a copy started by imgpkg %s with different options (current imgpkg %s):\n%s\n(hint: use the same options as the interrupted copy, or copy without --resume to start over)",
+		o.ImgpkgVersion, current.ImgpkgVersion, strings.Join(differences, "\n"))
+}
+
+// LockedError Returned when the journal is already open by another copy
+type LockedError struct {
+	Path string
+	Err  error
+}
+
+func (e LockedError) Error() string {
+	return fmt.Sprintf("Locking journal '%s', it is being used by another copy: %s", e.Path, e.Err)
+}
+
+// Journal File backed list of Entry. Every entry is fsynced before Append returns
+type Journal struct {
+	path    string
+	file    *os.File
+	entries []Entry
+	lock    sync.Mutex
+}
+
+// Open reads the journal present in path, creating it when it does not exist, and locks it until it is closed.
+// An entry that was only partially written, because the process was killed while appending it, is discarded.
+func Open(path string) (*Journal, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Creating journal folder: %s", err)
+	}
+
+	_, statErr := os.Stat(path)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Opening journal '%s': %s", path, err)
+	}
+	// Copies resuming from the same journal would discard each other's entries
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, LockedError{Path: path, Err: err}
+	}
+	if os.IsNotExist(statErr) {
+		err = atomicfile.SyncDir(filepath.Dir(path))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	entries, validSize, err := readEntries(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Reading journal '%s': %s", path, err)
+	}
+
+	err = file.Truncate(validSize)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Truncating journal '%s': %s", path, err)
+	}
+	_, err = file.Seek(validSize, 0)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Seeking journal '%s': %s", path, err)
+	}
+
+	return &Journal{path: path, file: file, entries: entries}, nil
+}
+
+// Path location of the journal on disk
+func (j *Journal) Path() string { return j.path }
+
+// Entries returns all the entries recorded so far
+func (j *Journal) Entries() []Entry {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return append([]Entry{}, j.entries...)
+}
+
+// Find returns the entry of the provided kind and digest
+func (j *Journal) Find(kind Kind, digest string) (Entry, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	for _, entry := range j.entries {
+		if entry.Kind == kind && entry.Digest == digest {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// CheckOptions Verifies that the work recorded in the journal was done by a copy with compatible options.
+// The options are recorded when the journal is empty
+func (j *Journal) CheckOptions(current Options) error {
+	entries := j.Entries()
+	if len(entries) == 0 {
+		return j.Append(Entry{Kind: OptionsKind, Options: &current})
+	}
+
+	if entries[0].Kind != OptionsKind || entries[0].Options == nil {
+		return fmt.Errorf("Unable to resume a copy started by a version of imgpkg that does not record the copy options in '%s' (hint: copy without --resume to start over)", j.path)
+	}
+	return entries[0].Options.CheckResumableBy(current)
+}
+
+// Append records an entry and only returns after it reached the disk
+func (j *Journal) Append(entry Entry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	_, err = j.file.Write(append(entryBytes, '\n'))
+	if err != nil {
+		return fmt.Errorf("Writing to journal '%s': %s", j.path, err)
+	}
+	err = j.file.Sync()
+	if err != nil {
+		return fmt.Errorf("Syncing journal '%s': %s", j.path, err)
+	}
+
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+// Reset discards all entries recorded
+func (j *Journal) Reset() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	err := j.file.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("Truncating journal '%s': %s", j.path, err)
+	}
+	_, err = j.file.Seek(0, 0)
+	if err != nil {
+		return fmt.Errorf("Seeking journal '%s': %s", j.path, err)
+	}
+
+	j.entries = nil
+	return j.file.Sync()
+}
+
+// Close releases the journal file, keeping it on disk
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal. Used once the copy finished successfully
+func (j *Journal) Remove() error {
+	j.file.Close()
+
+	err := os.Remove(j.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Removing journal '%s': %s", j.path, err)
+	}
+	return atomicfile.SyncDir(filepath.Dir(j.path))
+}
+
+func readEntries(file *os.File) ([]Entry, int64, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var entries []Entry
+	var validSize int64
+	for len(content) > 0 {
+		lineEnd := bytes.IndexByte(content, '\n')
+		if lineEnd == -1 {
+			// Last entry was not completely written
+			break
+		}
+
+		var entry Entry
+		err = json.Unmarshal(content[:lineEnd], &entry)
+		if err != nil {
+			if len(bytes.TrimSpace(content[lineEnd+1:])) == 0 {
+				break
+			}
+			return nil, 0, fmt.Errorf("Parsing entry at position %d: %s", validSize, err)
+		}
+
+		entries = append(entries, entry)
+		validSize += int64(lineEnd + 1)
+		content = content[lineEnd+1:]
+	}
+
+	return entries, validSize, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	t.Run("when entries are appended, it reads them after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "some", "folder", "copy.journal")
+
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+		require.NoError(t, subject.Append(journal.Entry{Kind: journal.LayerKind, Digest: "sha256:1", File: "some.tar", Offset: 1024, Size: 10}))
+		require.NoError(t, subject.Append(journal.Entry{Kind: journal.ImageKind, Digest: "sha256:2", Ref: "registry.io/repo:tag"}))
+		require.NoError(t, subject.Close())
+
+		subject, err = journal.Open(path)
+		require.NoError(t, err)
+		defer subject.Close()
+
+		assert.Equal(t, []journal.Entry{
+			{Kind: journal.LayerKind, Digest: "sha256:1", File: "some.tar", Offset: 1024, Size: 10},
+			{Kind: journal.ImageKind, Digest: "sha256:2", Ref: "registry.io/repo:tag"},
+		}, subject.Entries())
+
+		entry, found := subject.Find(journal.ImageKind, "sha256:2")
+		assert.True(t, found)
+		assert.Equal(t, "registry.io/repo:tag", entry.Ref)
+		_, found = subject.Find(journal.LayerKind, "sha256:2")
+		assert.False(t, found)
+	})
+
+	t.Run("when the last entry was only partially written, it discards it and keeps appending", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		require.NoError(t, os.WriteFile(path, []byte(`{"kind":"layer","digest":"sha256:1"}`+"\n"+`{"kind":"lay`), 0600))
+
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+		assert.Equal(t, []journal.Entry{{Kind: journal.LayerKind, Digest: "sha256:1"}}, subject.Entries())
+
+		require.NoError(t, subject.Append(journal.Entry{Kind: journal.LayerKind, Digest: "sha256:2"}))
+		require.NoError(t, subject.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `{"kind":"layer","digest":"sha256:1"}`+"\n"+`{"kind":"layer","digest":"sha256:2"}`+"\n", string(content))
+	})
+
+	t.Run("when an entry in the middle is corrupted, it fails", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		require.NoError(t, os.WriteFile(path, []byte(`{"kind":"lay`+"\n"+`{"kind":"layer","digest":"sha256:1"}`+"\n"), 0600))
+
+		_, err := journal.Open(path)
+		require.ErrorContains(t, err, "Parsing entry at position 0")
+	})
+
+	t.Run("when reset, it discards all entries", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+		require.NoError(t, subject.Append(journal.Entry{Kind: journal.LayerKind, Digest: "sha256:1"}))
+		require.NoError(t, subject.Reset())
+		assert.Empty(t, subject.Entries())
+
+		require.NoError(t, subject.Remove())
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("when the journal is already open, it fails until it is closed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+
+		_, err = journal.Open(path)
+		require.ErrorContains(t, err, "it is being used by another copy")
+
+		require.NoError(t, subject.Close())
+		subject, err = journal.Open(path)
+		require.NoError(t, err)
+		require.NoError(t, subject.Close())
+	})
+
+	t.Run("when the journal is empty, it records the options of the copy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+		options := journal.Options{ImgpkgVersion: "0.40.0", Settings: map[string]string{"include-non-distributable-layers": "false"}}
+		require.NoError(t, subject.CheckOptions(options))
+		require.NoError(t, subject.Close())
+
+		subject, err = journal.Open(path)
+		require.NoError(t, err)
+		defer subject.Close()
+		assert.Equal(t, []journal.Entry{{Kind: journal.OptionsKind, Options: &options}}, subject.Entries())
+
+		// A newer imgpkg with the same settings can resume the copy
+		require.NoError(t, subject.CheckOptions(journal.Options{ImgpkgVersion: "0.41.0", Settings: map[string]string{"include-non-distributable-layers": "false"}}))
+	})
+
+	t.Run("when the options of the copy changed, it fails listing the differences", func(t *testing.T) {
+		subject, err := journal.Open(filepath.Join(t.TempDir(), "copy.journal"))
+		require.NoError(t, err)
+		defer subject.Close()
+		require.NoError(t, subject.CheckOptions(journal.Options{ImgpkgVersion: "0.40.0", Settings: map[string]string{"include-non-distributable-layers": "false"}}))
+
+		err = subject.CheckOptions(journal.Options{ImgpkgVersion: "0.41.0", Settings: map[string]string{
+			"include-non-distributable-layers": "true",
+			"allowed-media-types":              "application/vnd.oci.image.layer.v1.tar+gzip",
+		}})
+		require.ErrorContains(t, err, "Unable to resume a copy started by imgpkg 0.40.0 with different options (current imgpkg 0.41.0):\n"+
+			"- allowed-media-types: was '', is now 'application/vnd.oci.image.layer.v1.tar+gzip'\n"+
+			"- include-non-distributable-layers: was 'false', is now 'true'")
+	})
+
+	t.Run("when the journal was created by a version of imgpkg that does not record the options, it fails", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copy.journal")
+		require.NoError(t, os.WriteFile(path, []byte(`{"kind":"layer","digest":"sha256:1"}`+"\n"), 0600))
+		subject, err := journal.Open(path)
+		require.NoError(t, err)
+		defer subject.Close()
+
+		err = subject.CheckOptions(journal.Options{ImgpkgVersion: "0.41.0"})
+		require.ErrorContains(t, err, "Unable to resume a copy started by a version of imgpkg that does not record the copy options")
+	})
+}
@@ -0,0 +1,18 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package journal
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile Takes an exclusive lock of file, released when the file is closed. Fails when another process holds it
+func lockFile(file *os.File) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics writes the metrics of a run in the Prometheus text exposition format, so that they can be
+// stored with the artifacts of the run and scraped later in environments where no metrics endpoint can be reached.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+)
+
+// Labels Labels of a sample, written sorted by name
+type Labels map[string]string
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// family Samples sharing a metric name
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+type sample struct {
+	suffix string
+	labels Labels
+	value  float64
+}
+
+// Set Metrics written together, the families are written sorted by name and their samples in the order they were added
+type Set struct {
+	families map[string]*family
+}
+
+// NewSet Creates a Set without metrics
+func NewSet() *Set {
+	return &Set{families: map[string]*family{}}
+}
+
+// Counter Adds a sample of the counter name, whose value only increases during a run
+func (s *Set) Counter(name, help string, labels Labels, value float64) {
+	s.family(name, help, kindCounter).add("", labels, value)
+}
+
+// Gauge Adds a sample of the gauge name
+func (s *Set) Gauge(name, help string, labels Labels, value float64) {
+	s.family(name, help, kindGauge).add("", labels, value)
+}
+
+// Histogram Adds the histogram name of the observations, counted in buckets with the upper bounds provided
+func (s *Set) Histogram(name, help string, labels Labels, upperBounds []float64, observations []float64) {
+	f := s.family(name, help, kindHistogram)
+
+	bounds := append([]float64{}, upperBounds...)
+	sort.Float64s(bounds)
+	sum := 0.0
+	for _, observation := range observations {
+		sum += observation
+	}
+	for _, bound := range append(bounds, math.Inf(1)) {
+		count := 0
+		for _, observation := range observations {
+			if observation <= bound {
+				count++
+			}
+		}
+		bucketLabels := Labels{"le": formatValue(bound)}
+		for key, value := range labels {
+			bucketLabels[key] = value
+		}
+		f.add("_bucket", bucketLabels, float64(count))
+	}
+	f.add("_sum", labels, sum)
+	f.add("_count", labels, float64(len(observations)))
+}
+
+func (s *Set) family(name, help, kind string) *family {
+	f, found := s.families[name]
+	if !found {
+		f = &family{name: name, help: help, kind: kind}
+		s.families[name] = f
+	}
+	if f.kind != kind {
+		panic(fmt.Sprintf("Internal inconsistency: metric '%s' is a %s, not a %s", name, f.kind, kind))
+	}
+	return f
+}
+
+func (f *family) add(suffix string, labels Labels, value float64) {
+	f.samples = append(f.samples, sample{suffix: suffix, labels: labels, value: value})
+}
+
+// Bytes Returns the metrics in the Prometheus text exposition format
+func (s *Set) Bytes() []byte {
+	var names []string
+	for name := range s.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := s.families[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.kind)
+		for _, sample := range f.samples {
+			fmt.Fprintf(&buf, "%s%s%s %s\n", f.name, sample.suffix, formatLabels(sample.labels), formatValue(sample.value))
+		}
+	}
+	return buf.Bytes()
+}
+
+// WriteFile Writes the metrics to path, replacing it atomically, so that a scraper never reads half of them
+func (s *Set) WriteFile(path string) error {
+	err := atomicfile.WriteFile(path, s.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("Writing metrics to '%s': %s", path, err)
+	}
+	return nil
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escapeLabelValue(labels[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string { return helpEscaper.Replace(help) }
+
+func escapeLabelValue(value string) string { return labelValueEscaper.Replace(value) }
@@ -0,0 +1,50 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	set := metrics.NewSet()
+	set.Gauge("imgpkg_copy_success", "1 when the copy succeeded", nil, 1)
+	set.Counter("imgpkg_registry_requests_total", "Requests sent\nto the host", metrics.Labels{"host": "index.docker.io", "scheme": `https "tls"`}, 12)
+	set.Counter("imgpkg_registry_requests_total", "Requests sent\nto the host", metrics.Labels{"host": "gcr.io", "scheme": "https"}, 3)
+	set.Histogram("imgpkg_copy_image_duration_seconds", "Time taken to copy each image", metrics.Labels{"dst": "repo"}, []float64{5, 1}, []float64{0.5, 2, 2, 30})
+
+	assert.Equal(t, `# HELP imgpkg_copy_image_duration_seconds Time taken to copy each image
+# TYPE imgpkg_copy_image_duration_seconds histogram
+imgpkg_copy_image_duration_seconds_bucket{dst="repo",le="1"} 1
+imgpkg_copy_image_duration_seconds_bucket{dst="repo",le="5"} 3
+imgpkg_copy_image_duration_seconds_bucket{dst="repo",le="+Inf"} 4
+imgpkg_copy_image_duration_seconds_sum{dst="repo"} 34.5
+imgpkg_copy_image_duration_seconds_count{dst="repo"} 4
+# HELP imgpkg_copy_success 1 when the copy succeeded
+# TYPE imgpkg_copy_success gauge
+imgpkg_copy_success 1
+# HELP imgpkg_registry_requests_total Requests sent\nto the host
+# TYPE imgpkg_registry_requests_total counter
+imgpkg_registry_requests_total{host="index.docker.io",scheme="https \"tls\""} 12
+imgpkg_registry_requests_total{host="gcr.io",scheme="https"} 3
+`, string(set.Bytes()))
+
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	require.NoError(t, set.WriteFile(path))
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, set.Bytes(), written)
+}
+
+func TestSetWithDifferentKinds(t *testing.T) {
+	set := metrics.NewSet()
+	set.Gauge("imgpkg_copy_success", "", nil, 1)
+	assert.Panics(t, func() { set.Counter("imgpkg_copy_success", "", nil, 1) })
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package runstate records what the last imgpkg command did, the arguments it received, its outcome and the
+// registry logs, so that it can be attached to bug reports with the support-bundle command.
+package runstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"github.com/google/go-containerregistry/pkg/logs"
+)
+
+const (
+	// ReportFile Name of the file, in the run state folder, with the report of the last run
+	ReportFile = "report.json"
+	// LogsFile Name of the file, in the run state folder, with the registry logs of the last run
+	LogsFile = "logs.txt"
+
+	redacted = "<redacted>"
+)
+
+// Report Describes the last imgpkg command that ran
+type Report struct {
+	Command   string    `json:"command"`
+	Args      []string  `json:"args"`
+	Version   string    `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+	Duration  string    `json:"duration"`
+	Succeeded bool      `json:"succeeded"`
+	Error     string    `json:"error,omitempty"`
+	// DebugLogs is true when the logs include the registry requests and responses (--debug)
+	DebugLogs bool `json:"debugLogs"`
+}
+
+// DefaultDir Folder where the state of the last run is kept, empty when there is no cache folder for the user
+func DefaultDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "imgpkg", "last-run")
+}
+
+// Recorder Records a single run in the run state folder
+type Recorder struct {
+	dir     string
+	report  Report
+	logFile *os.File
+
+	prevWarnOutput  io.Writer
+	prevDebugOutput io.Writer
+}
+
+// Start Starts recording a run of command, copying the registry logs to the run state folder until Finish is called.
+// Recording is best effort, a run state folder that cannot be written does not prevent the command from running
+func Start(dir, command string, args []string, version string) *Recorder {
+	r := &Recorder{
+		dir: dir,
+		report: Report{
+			Command:   command,
+			Args:      RedactArgs(args),
+			Version:   version,
+			StartedAt: time.Now().UTC(),
+			DebugLogs: logs.Enabled(logs.Debug),
+		},
+	}
+	if dir == "" || os.MkdirAll(dir, 0700) != nil {
+		return r
+	}
+
+	logFile, err := os.CreateTemp(dir, "."+LogsFile+".tmp-")
+	if err != nil {
+		return r
+	}
+	r.logFile = logFile
+
+	r.prevWarnOutput = logs.Warn.Writer()
+	logs.Warn.SetOutput(io.MultiWriter(r.prevWarnOutput, logFile))
+	// Debug logs are only copied when enabled, enabling them would log every request sent to the registries
+	if r.report.DebugLogs {
+		r.prevDebugOutput = logs.Debug.Writer()
+		logs.Debug.SetOutput(io.MultiWriter(r.prevDebugOutput, logFile))
+	}
+	return r
+}
+
+// Finish Stops copying the registry logs and writes the report of the run, that failed when err is not nil.
+// The state of the previous run is replaced only now, so that a failure can still be reported while another command runs
+func (r *Recorder) Finish(err error) {
+	r.report.Duration = time.Since(r.report.StartedAt).Round(time.Millisecond).String()
+	r.report.Succeeded = err == nil
+	if err != nil {
+		r.report.Error = err.Error()
+	}
+
+	if r.logFile == nil {
+		return
+	}
+	logs.Warn.SetOutput(r.prevWarnOutput)
+	if r.prevDebugOutput != nil {
+		logs.Debug.SetOutput(r.prevDebugOutput)
+	}
+
+	logsPath := r.logFile.Name()
+	if r.logFile.Close() != nil || os.Rename(logsPath, filepath.Join(r.dir, LogsFile)) != nil {
+		os.Remove(logsPath)
+		return
+	}
+
+	reportBytes, marshalErr := json.MarshalIndent(r.report, "", "  ")
+	if marshalErr != nil {
+		return
+	}
+	_ = atomicfile.WriteFile(filepath.Join(r.dir, ReportFile), reportBytes, 0600)
+}
+
+// ReadReport Reads the report of the last run from the run state folder
+func ReadReport(dir string) (Report, error) {
+	reportBytes, err := os.ReadFile(filepath.Join(dir, ReportFile))
+	if err != nil {
+		return Report{}, fmt.Errorf("Reading report of the last run: %s", err)
+	}
+
+	var report Report
+	err = json.Unmarshal(reportBytes, &report)
+	if err != nil {
+		return Report{}, fmt.Errorf("Unmarshaling report of the last run: %s", err)
+	}
+	return report, nil
+}
+
+// RedactArgs Replaces the values of the flags that hold credentials
+func RedactArgs(args []string) []string {
+	var result []string
+	redactNext := false
+	for _, arg := range args {
+		if redactNext {
+			result = append(result, redacted)
+			redactNext = false
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || !IsSensitiveName(arg) {
+			result = append(result, arg)
+			continue
+		}
+		if name, _, found := strings.Cut(arg, "="); found {
+			result = append(result, name+"="+redacted)
+			continue
+		}
+		result = append(result, arg)
+		redactNext = true
+	}
+	return result
+}
+
+// IsSensitiveName Returns true when a flag or environment variable with this name holds credentials
+func IsSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range []string{"password", "token", "secret", "auth", "key"} {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
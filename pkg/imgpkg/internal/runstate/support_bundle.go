@@ -0,0 +1,151 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package runstate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+)
+
+const (
+	environmentFile    = "environment.json"
+	registryProbesFile = "registry-probes.json"
+)
+
+// environmentPrefixes Environment variables that change how imgpkg reaches and authenticates to registries
+var environmentPrefixes = []string{"IMGPKG_", "DOCKER_", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "SSL_CERT_"}
+
+// Environment imgpkg build and the environment variables that affect it, with the credentials redacted
+type Environment struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"goVersion"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Variables map[string]string `json:"variables"`
+}
+
+// NewEnvironment Collects the relevant variables of environ, in the os.Environ format, redacting credentials
+// held in variables with sensitive names or in the user information of proxy URLs
+func NewEnvironment(version string, environ []string) Environment {
+	env := Environment{
+		Version:   version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Variables: map[string]string{},
+	}
+
+	for _, keyValue := range environ {
+		key, value, _ := strings.Cut(keyValue, "=")
+		if !isRelevantVariable(key) {
+			continue
+		}
+		if IsSensitiveName(key) {
+			value = redacted
+		} else if parsedURL, err := url.Parse(value); err == nil && parsedURL.User != nil {
+			redactedValue := strings.Replace(value, parsedURL.User.String()+"@", redacted+"@", 1)
+			if redactedValue == value {
+				redactedValue = redacted
+			}
+			value = redactedValue
+		}
+		env.Variables[key] = value
+	}
+	return env
+}
+
+func isRelevantVariable(key string) bool {
+	upperKey := strings.ToUpper(key)
+	for _, prefix := range environmentPrefixes {
+		if strings.HasPrefix(upperKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportBundle Information gathered to diagnose an issue
+type SupportBundle struct {
+	// RunStateDir folder with the state of the last run
+	RunStateDir    string
+	Environment    Environment
+	RegistryProbes []registry.ProbeReport
+}
+
+// Write Writes a gzipped tar with the report and logs of the last run, the environment and the registry probes.
+// Files missing from the run state folder are skipped, and the names of the files included are returned
+func (s SupportBundle) Write(path string) ([]string, error) {
+	files := map[string][]byte{}
+
+	for _, name := range []string{ReportFile, LogsFile} {
+		content, err := os.ReadFile(filepath.Join(s.RunStateDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("Reading '%s' of the last run: %s", name, err)
+		}
+		files[name] = content
+	}
+
+	envBytes, err := json.MarshalIndent(s.Environment, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Marshaling environment: %s", err)
+	}
+	files[environmentFile] = envBytes
+
+	if len(s.RegistryProbes) > 0 {
+		probesBytes, err := json.MarshalIndent(s.RegistryProbes, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Marshaling registry probes: %s", err)
+		}
+		files[registryProbesFile] = probesBytes
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archive := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := time.Now()
+	for _, name := range names {
+		err = tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(files[name])), ModTime: modTime})
+		if err != nil {
+			return nil, fmt.Errorf("Writing support bundle: %s", err)
+		}
+		_, err = tarWriter.Write(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("Writing support bundle: %s", err)
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("Writing support bundle: %s", err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("Writing support bundle: %s", err)
+	}
+
+	err = atomicfile.WriteFile(path, archive.Bytes(), 0600)
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secretscan detects likely secrets, like private keys and access tokens, in the files pushed,
+// so that credentials are not published by accident inside images and bundles that are widely mirrored.
+package secretscan
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/ignorefile"
+)
+
+// Policy What to do when likely secrets are found in the files pushed
+type Policy string
+
+const (
+	// PolicyOff does not scan the files
+	PolicyOff Policy = "off"
+	// PolicyWarn reports the likely secrets and pushes the files as they are
+	PolicyWarn Policy = "warn"
+	// PolicyFail reports the likely secrets and does not push anything
+	PolicyFail Policy = "fail"
+	// PolicyRedact reports the likely secrets and replaces them with RedactedValue in the files pushed
+	PolicyRedact Policy = "redact"
+)
+
+// RedactedValue replaces the likely secrets when redacting
+const RedactedValue = "<redacted-by-imgpkg>"
+
+// binarySniffLen bytes checked for a NUL byte to decide if a file is binary, the same heuristic used by git
+const binarySniffLen = 8000
+
+// ParsePolicy Parses the policy, one of off, warn, fail or redact
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(value); policy {
+	case PolicyOff, PolicyWarn, PolicyFail, PolicyRedact:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("Unknown secret scan policy '%s' (supported: %s, %s, %s, %s)", value, PolicyOff, PolicyWarn, PolicyFail, PolicyRedact)
+	}
+}
+
+// rule Kind of secret and the expression matching it
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var rules = []rule{
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----.*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+// Finding Likely secret found in a file
+type Finding struct {
+	Path string
+	Line int
+	Kind string
+
+	start int
+	end   int
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.Path, f.Line, f.Kind)
+}
+
+// Scan Returns the likely secrets in contents of the file in path, binary files are not scanned
+func Scan(path string, contents []byte) []Finding {
+	if bytes.IndexByte(contents[:min(len(contents), binarySniffLen)], 0) != -1 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, r := range rules {
+		for _, loc := range r.pattern.FindAllIndex(contents, -1) {
+			findings = append(findings, Finding{
+				Path:  path,
+				Line:  bytes.Count(contents[:loc[0]], []byte("\n")) + 1,
+				Kind:  r.name,
+				start: loc[0],
+				end:   loc[1],
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].start < findings[j].start })
+	return findings
+}
+
+// Redact Replaces the likely secrets in contents with RedactedValue
+func Redact(path string, contents []byte) []byte {
+	findings := Scan(path, contents)
+	if len(findings) == 0 {
+		return contents
+	}
+
+	var redacted bytes.Buffer
+	last := 0
+	for _, finding := range findings {
+		// matches of different rules can overlap, the first one is redacted
+		if finding.start < last {
+			last = max(last, finding.end)
+			continue
+		}
+		redacted.Write(contents[last:finding.start])
+		redacted.WriteString(RedactedValue)
+		last = finding.end
+	}
+	redacted.Write(contents[last:])
+	return redacted.Bytes()
+}
+
+// ScanPaths Returns the likely secrets in the files of paths, walking the directories and skipping
+// excludedPaths, which are relative to the directory provided, and the paths left out by its
+// .imgpkgignore, the same way the files are pushed
+func ScanPaths(paths []string, excludedPaths []string) ([]Finding, error) {
+	isExcluded := func(relPath string) bool {
+		for _, excluded := range excludedPaths {
+			if excluded == relPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	var findings []Finding
+	scanFile := func(path string) error {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, Scan(path, contents)...)
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			err = scanFile(path)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		ignored, err := ignorefile.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.Walk(path, func(walkedPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(path, walkedPath)
+			if err != nil {
+				return err
+			}
+			if isExcluded(relPath) || (relPath != "." && ignored.Match(relPath, info.IsDir())) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			return scanFile(walkedPath)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Scanning '%s' for secrets: %s", path, err)
+		}
+	}
+	return findings, nil
+}
+
+// Describe Describes the findings, one per line
+func Describe(findings []Finding) string {
+	var lines []string
+	for _, finding := range findings {
+		lines = append(lines, "- "+finding.String())
+	}
+	return strings.Join(lines, "\n")
+}
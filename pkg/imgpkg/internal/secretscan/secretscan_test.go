@@ -0,0 +1,77 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package secretscan_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/secretscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The secrets are split so that the source code does not look like it contains them
+var (
+	awsKey     = "AKIA" + "IOSFODNN7EXAMPLE"
+	githubKey  = "ghp_" + "0123456789abcdefghijABCDEFGHIJ012345"
+	privateKey = "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIEowIBAAKCAQEA\n-----END RSA " + "PRIVATE KEY-----"
+)
+
+func TestScan(t *testing.T) {
+	t.Run("it reports the kind and line of each likely secret", func(t *testing.T) {
+		contents := []byte("aws:\n  key: " + awsKey + "\ntls.key: |\n" + privateKey + "\ntoken: " + githubKey + "\n")
+
+		findings := secretscan.Scan("config.yml", contents)
+		require.Len(t, findings, 3)
+		assert.Equal(t, "config.yml:2: AWS access key", findings[0].String())
+		assert.Equal(t, "config.yml:4: private key", findings[1].String())
+		assert.Equal(t, "config.yml:7: GitHub token", findings[2].String())
+	})
+
+	t.Run("it does not report public keys or binary files", func(t *testing.T) {
+		assert.Empty(t, secretscan.Scan("key.pub", []byte("-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkq\n-----END PUBLIC KEY-----\n")))
+		assert.Empty(t, secretscan.Scan("app.bin", append([]byte{0, 1, 2}, []byte(awsKey)...)))
+	})
+}
+
+func TestRedact(t *testing.T) {
+	contents := []byte("key: " + awsKey + "\ntls.key: |\n" + privateKey + "\nname: app\n")
+
+	redacted := secretscan.Redact("config.yml", contents)
+	assert.Equal(t, "key: <redacted-by-imgpkg>\ntls.key: |\n<redacted-by-imgpkg>\nname: app\n", string(redacted))
+	assert.Empty(t, secretscan.Scan("config.yml", redacted))
+
+	withoutSecrets := []byte("name: app\n")
+	assert.Equal(t, withoutSecrets, secretscan.Redact("config.yml", withoutSecrets))
+}
+
+func TestScanPaths(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "excluded-dir"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "values.yml"), []byte("token: "+githubKey), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "excluded.yml"), []byte("token: "+githubKey), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "excluded-dir", "values.yml"), []byte("token: "+githubKey), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".imgpkgignore"), []byte("*.local.yml\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "values.local.yml"), []byte("token: "+githubKey), 0600))
+	singleFile := filepath.Join(t.TempDir(), "aws.yml")
+	require.NoError(t, os.WriteFile(singleFile, []byte("key: "+awsKey), 0600))
+
+	findings, err := secretscan.ScanPaths([]string{dir, singleFile}, []string{"excluded.yml", "excluded-dir"})
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	assert.Equal(t, filepath.Join(dir, "config", "values.yml")+":1: GitHub token", findings[0].String())
+	assert.Equal(t, singleFile+":1: AWS access key", findings[1].String())
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := secretscan.ParsePolicy("redact")
+	require.NoError(t, err)
+	assert.Equal(t, secretscan.PolicyRedact, policy)
+
+	_, err = secretscan.ParsePolicy("ignore")
+	require.ErrorContains(t, err, "Unknown secret scan policy 'ignore' (supported: off, warn, fail, redact)")
+}
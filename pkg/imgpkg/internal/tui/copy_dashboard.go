@@ -0,0 +1,365 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tui renders a full screen view of the progress of a copy, with the state of each one of the images
+package tui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	exitAltScreen  = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+
+	// throughputSamples number of samples, one per refresh, kept for the throughput graph
+	throughputSamples = 60
+	// stalledAfter time without progress after which an image is reported as stalled
+	stalledAfter = 30 * time.Second
+)
+
+var sparkline = []rune("▁▂▃▄▅▆▇█")
+
+// SizeFunc Returns the width and height of the terminal
+type SizeFunc func() (width, height int, err error)
+
+// CopyDashboard Full screen view of a copy that shows the images being uploaded, the ones that failed,
+// the throughput and the estimated time to finish. It implements imageset.CopyObserver
+type CopyDashboard struct {
+	out     io.Writer
+	size    SizeFunc
+	now     func() time.Time
+	refresh time.Duration
+
+	lock        *sync.Mutex
+	startedAt   time.Time
+	total       int
+	images      map[string]*imageState
+	finished    int
+	failed      []string
+	lastMessage string
+
+	bytesSinceSample int64
+	samples          []int64
+	sampledAt        time.Time
+
+	running bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+type imageState struct {
+	ref            string
+	startedAt      time.Time
+	lastProgressAt time.Time
+	complete       int64
+	total          int64
+	finished       bool
+	err            error
+}
+
+// NewCopyDashboard constructs a CopyDashboard that draws on out, a terminal whose size is returned by size
+func NewCopyDashboard(out io.Writer, size SizeFunc) *CopyDashboard {
+	return &CopyDashboard{
+		out:     out,
+		size:    size,
+		now:     time.Now,
+		refresh: 250 * time.Millisecond,
+		lock:    &sync.Mutex{},
+		images:  map[string]*imageState{},
+	}
+}
+
+// Start Switches the terminal to the alternate screen and redraws the dashboard until Stop is called
+func (d *CopyDashboard) Start() {
+	d.lock.Lock()
+	d.startedAt = d.now()
+	d.sampledAt = d.startedAt
+	d.running = true
+	d.lock.Unlock()
+
+	d.stopCh = make(chan struct{})
+	d.doneCh = make(chan struct{})
+	fmt.Fprint(d.out, enterAltScreen)
+
+	go func() {
+		defer close(d.doneCh)
+		ticker := time.NewTicker(d.refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.draw()
+			case <-d.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop Restores the terminal and prints a summary of the copy, that remains visible once imgpkg exits
+func (d *CopyDashboard) Stop() {
+	if d.stopCh == nil {
+		return
+	}
+	close(d.stopCh)
+	<-d.doneCh
+	d.stopCh = nil
+	fmt.Fprint(d.out, exitAltScreen)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.running = false
+	fmt.Fprintf(d.out, "copy | copied %d of %d images in %s\n", d.finished-len(d.failed), d.total, d.now().Sub(d.startedAt).Round(time.Second))
+	for _, ref := range d.failed {
+		fmt.Fprintf(d.out, "copy | failed %s: %s\n", ref, d.images[ref].err)
+	}
+}
+
+// Logf Keeps the last message logged to show it in the dashboard, instead of writing it over the dashboard.
+// Messages logged while the dashboard is not shown are written as they are
+func (d *CopyDashboard) Logf(msg string, args ...interface{}) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if !d.running {
+		fmt.Fprintf(d.out, msg, args...)
+		return
+	}
+	d.lastMessage = strings.TrimSpace(fmt.Sprintf(msg, args...))
+}
+
+// ImagesFound Records the number of images that will be copied. Copies to several repositories add up
+func (d *CopyDashboard) ImagesFound(total int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.total += total
+}
+
+// ImageStarted Records that the image started being copied
+func (d *CopyDashboard) ImageStarted(ref string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := d.now()
+	d.images[ref] = &imageState{ref: ref, startedAt: now, lastProgressAt: now}
+}
+
+// ImageProgress Records the bytes of the image that were already uploaded
+func (d *CopyDashboard) ImageProgress(ref string, complete, total int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	img, found := d.images[ref]
+	if !found {
+		return
+	}
+	if complete > img.complete {
+		d.bytesSinceSample += complete - img.complete
+		img.lastProgressAt = d.now()
+	}
+	img.complete = complete
+	img.total = total
+}
+
+// ImageFinished Records that the image was copied, or failed to be copied when err is not nil
+func (d *CopyDashboard) ImageFinished(ref string, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	img, found := d.images[ref]
+	if !found || img.finished {
+		return
+	}
+	img.finished = true
+	img.err = err
+	d.finished++
+	if err != nil {
+		d.failed = append(d.failed, ref)
+	}
+}
+
+func (d *CopyDashboard) draw() {
+	width, height, err := d.size()
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	d.lock.Lock()
+	d.sample()
+	view := d.render(width, height)
+	d.lock.Unlock()
+
+	fmt.Fprint(d.out, clearScreen+view)
+}
+
+// sample Adds to the throughput graph the bytes uploaded since the previous sample
+func (d *CopyDashboard) sample() {
+	now := d.now()
+	elapsed := now.Sub(d.sampledAt)
+	if elapsed <= 0 {
+		return
+	}
+	d.samples = append(d.samples, int64(float64(d.bytesSinceSample)/elapsed.Seconds()))
+	if len(d.samples) > throughputSamples {
+		d.samples = d.samples[len(d.samples)-throughputSamples:]
+	}
+	d.bytesSinceSample = 0
+	d.sampledAt = now
+}
+
+// Render Returns the dashboard that fits in a terminal of the provided size
+func (d *CopyDashboard) Render(width, height int) string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.render(width, height)
+}
+
+func (d *CopyDashboard) render(width, height int) string {
+	now := d.now()
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("imgpkg copy  %d/%d images  %d failed  %s/s  ETA %s",
+		d.finished, d.total, len(d.failed), util.HumanizeBytes(d.currentThroughput()), d.eta(now)))
+	lines = append(lines, "throughput "+d.graph(width-len("throughput ")))
+	if d.lastMessage != "" {
+		lines = append(lines, d.lastMessage)
+	}
+	lines = append(lines, "")
+
+	var inProgress []*imageState
+	for _, img := range d.images {
+		if !img.finished {
+			inProgress = append(inProgress, img)
+		}
+	}
+	// Images that have been waiting longer for progress go first, they are the ones that might be stuck
+	sort.Slice(inProgress, func(i, j int) bool {
+		if !inProgress[i].lastProgressAt.Equal(inProgress[j].lastProgressAt) {
+			return inProgress[i].lastProgressAt.Before(inProgress[j].lastProgressAt)
+		}
+		return inProgress[i].ref < inProgress[j].ref
+	})
+
+	failuresLines := 0
+	if len(d.failed) > 0 {
+		failuresLines = len(d.failed) + 2
+	}
+	inProgressRoom := height - len(lines) - 1 - failuresLines
+	if inProgressRoom < 1 {
+		inProgressRoom = 1
+	}
+
+	lines = append(lines, fmt.Sprintf("In progress (%d)", len(inProgress)))
+	for idx, img := range inProgress {
+		if idx == inProgressRoom-1 && len(inProgress) > inProgressRoom {
+			lines = append(lines, fmt.Sprintf("  ... %d more", len(inProgress)-idx))
+			break
+		}
+		lines = append(lines, d.imageLine(img, now, width))
+	}
+
+	if len(d.failed) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Failed (%d)", len(d.failed)))
+		for _, ref := range d.failed {
+			lines = append(lines, fmt.Sprintf("  %s: %s", ref, d.images[ref].err))
+		}
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for idx := range lines {
+		lines[idx] = truncate(lines[idx], width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *CopyDashboard) imageLine(img *imageState, now time.Time, width int) string {
+	const barWidth = 20
+	filled := 0
+	percentage := 0
+	if img.total > 0 {
+		filled = int(img.complete * barWidth / img.total)
+		percentage = int(img.complete * 100 / img.total)
+	}
+	status := fmt.Sprintf("%3d%% %s", percentage, util.HumanizeBytes(img.complete))
+	if stalled := now.Sub(img.lastProgressAt); stalled >= stalledAfter {
+		status += fmt.Sprintf("  stalled %s", stalled.Round(time.Second))
+	}
+	bar := "[" + strings.Repeat("#", filled) + strings.Repeat(".", barWidth-filled) + "] "
+	return truncate("  "+bar+img.ref, width-len(status)-2) + "  " + status
+}
+
+func (d *CopyDashboard) graph(width int) string {
+	samples := d.samples
+	if width < 1 {
+		return ""
+	}
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	var highest int64
+	for _, sample := range samples {
+		if sample > highest {
+			highest = sample
+		}
+	}
+	var graph strings.Builder
+	for _, sample := range samples {
+		idx := 0
+		if highest > 0 {
+			idx = int(sample * int64(len(sparkline)-1) / highest)
+		}
+		graph.WriteRune(sparkline[idx])
+	}
+	return graph.String()
+}
+
+// currentThroughput Average of the last samples, so that the number does not jump on every refresh
+func (d *CopyDashboard) currentThroughput() int64 {
+	const window = 8
+	samples := d.samples
+	if len(samples) > window {
+		samples = samples[len(samples)-window:]
+	}
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum int64
+	for _, sample := range samples {
+		sum += sample
+	}
+	return sum / int64(len(samples))
+}
+
+// eta Estimates the time left from the rate at which images were finished so far,
+// the size of the images that were not started yet is not known
+func (d *CopyDashboard) eta(now time.Time) string {
+	if d.finished == 0 || d.total == 0 {
+		return "--"
+	}
+	if d.finished >= d.total {
+		return "0s"
+	}
+	perImage := now.Sub(d.startedAt) / time.Duration(d.finished)
+	return (perImage * time.Duration(d.total-d.finished)).Round(time.Second).String()
+}
+
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if width < 1 {
+		return ""
+	}
+	if len(runes) <= width {
+		return line
+	}
+	if width < 4 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-3]) + "..."
+}
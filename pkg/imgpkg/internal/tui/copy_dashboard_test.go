@@ -0,0 +1,120 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyDashboard(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSubject := func(out *bytes.Buffer) *CopyDashboard {
+		subject := NewCopyDashboard(out, func() (int, int, error) { return 100, 20, nil })
+		subject.now = func() time.Time { return clock }
+		subject.startedAt = clock
+		subject.sampledAt = clock
+		return subject
+	}
+
+	t.Run("it shows the images in progress, the ones that failed and the estimated time left", func(t *testing.T) {
+		subject := newSubject(&bytes.Buffer{})
+		subject.ImagesFound(4)
+		subject.ImageStarted("registry.io/app@sha256:aaa")
+		subject.ImageStarted("registry.io/db@sha256:bbb")
+		subject.ImageStarted("registry.io/web@sha256:ccc")
+		subject.ImageProgress("registry.io/app@sha256:aaa", 50, 100)
+		subject.ImageFinished("registry.io/db@sha256:bbb", errors.New("unauthorized"))
+
+		clock = clock.Add(10 * time.Second)
+		subject.ImageFinished("registry.io/web@sha256:ccc", nil)
+
+		view := subject.Render(100, 20)
+		assert.Contains(t, view, "2/4 images  1 failed")
+		assert.Contains(t, view, "ETA 10s")
+		assert.Contains(t, view, "In progress (1)")
+		assert.Contains(t, view, "[##########..........] registry.io/app@sha256:aaa")
+		assert.Contains(t, view, " 50% 50 B")
+		assert.Contains(t, view, "Failed (1)")
+		assert.Contains(t, view, "registry.io/db@sha256:bbb: unauthorized")
+		assert.NotContains(t, view, "registry.io/web@sha256:ccc")
+	})
+
+	t.Run("it marks the images that have not made progress for a while as stalled", func(t *testing.T) {
+		subject := newSubject(&bytes.Buffer{})
+		subject.ImagesFound(2)
+		subject.ImageStarted("registry.io/app@sha256:aaa")
+		subject.ImageStarted("registry.io/db@sha256:bbb")
+
+		clock = clock.Add(45 * time.Second)
+		subject.ImageProgress("registry.io/db@sha256:bbb", 10, 100)
+
+		lines := strings.Split(subject.Render(100, 20), "\n")
+		var appLine, dbLine string
+		for _, line := range lines {
+			if strings.Contains(line, "registry.io/app") {
+				appLine = line
+			}
+			if strings.Contains(line, "registry.io/db") {
+				dbLine = line
+			}
+		}
+		assert.Contains(t, appLine, "stalled 45s")
+		assert.NotContains(t, dbLine, "stalled")
+	})
+
+	t.Run("it draws the throughput of each refresh in the graph", func(t *testing.T) {
+		subject := newSubject(&bytes.Buffer{})
+		subject.ImagesFound(1)
+		subject.ImageStarted("registry.io/app@sha256:aaa")
+
+		clock = clock.Add(time.Second)
+		subject.ImageProgress("registry.io/app@sha256:aaa", 1024, 4096)
+		subject.sample()
+		clock = clock.Add(time.Second)
+		subject.ImageProgress("registry.io/app@sha256:aaa", 4096, 4096)
+		subject.sample()
+
+		view := subject.Render(100, 20)
+		assert.Contains(t, view, "throughput ▃█")
+		assert.Contains(t, view, "2.0 KiB/s")
+	})
+
+	t.Run("it fits the view in the terminal", func(t *testing.T) {
+		subject := newSubject(&bytes.Buffer{})
+		subject.ImagesFound(30)
+		for _, name := range strings.Split("abcdefghijklmnopqrstuvwxyz", "") {
+			subject.ImageStarted("registry.io/" + name + "@sha256:aaa")
+		}
+
+		lines := strings.Split(subject.Render(40, 10), "\n")
+		assert.Len(t, lines, 10)
+		assert.Contains(t, lines[len(lines)-1], "more")
+		for _, line := range lines {
+			assert.LessOrEqual(t, len([]rune(line)), 40)
+		}
+	})
+
+	t.Run("when stopped, it restores the terminal and prints a summary", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		subject := newSubject(out)
+		subject.Start()
+		subject.ImagesFound(2)
+		subject.ImageStarted("registry.io/app@sha256:aaa")
+		subject.ImageStarted("registry.io/db@sha256:bbb")
+		subject.ImageFinished("registry.io/app@sha256:aaa", nil)
+		subject.ImageFinished("registry.io/db@sha256:bbb", errors.New("unauthorized"))
+		subject.Stop()
+
+		require.True(t, strings.HasPrefix(out.String(), enterAltScreen))
+		summary := out.String()[strings.LastIndex(out.String(), exitAltScreen)+len(exitAltScreen):]
+		assert.Equal(t, "copy | copied 1 of 2 images in 0s\ncopy | failed registry.io/db@sha256:bbb: unauthorized\n", summary)
+	})
+}
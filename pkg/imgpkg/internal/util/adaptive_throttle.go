@@ -0,0 +1,71 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AdaptiveThrottle Throttle whose maximum can be changed while it is used.
+// Lowering the maximum does not interrupt the holders, new ones wait until enough of them are done
+type AdaptiveThrottle struct {
+	lock  sync.Mutex
+	cond  *sync.Cond
+	max   int
+	inUse int
+}
+
+// NewAdaptiveThrottle Creates an AdaptiveThrottle that allows max holders
+func NewAdaptiveThrottle(max int) *AdaptiveThrottle {
+	if max < 1 {
+		panic(fmt.Sprintf("Expected maximum throttle to be >= 1, but was %d", max))
+	}
+	t := &AdaptiveThrottle{max: max}
+	t.cond = sync.NewCond(&t.lock)
+	return t
+}
+
+// Take Blocks until there are less holders than the maximum
+func (t *AdaptiveThrottle) Take() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for t.inUse >= t.max {
+		t.cond.Wait()
+	}
+	t.inUse++
+}
+
+// Done Releases the throttle taken with Take
+func (t *AdaptiveThrottle) Done() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.inUse--
+	t.cond.Broadcast()
+}
+
+// SetMax Changes the maximum number of holders, values lower than 1 are replaced by 1
+func (t *AdaptiveThrottle) SetMax(max int) {
+	if max < 1 {
+		max = 1
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.max = max
+	t.cond.Broadcast()
+}
+
+// Max Returns the current maximum number of holders
+func (t *AdaptiveThrottle) Max() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.max
+}
+
+// InUse Returns the number of holders
+func (t *AdaptiveThrottle) InUse() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.inUse
+}
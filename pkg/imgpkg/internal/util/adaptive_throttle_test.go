@@ -0,0 +1,84 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveThrottle(t *testing.T) {
+	t.Run("blocks when the maximum is reached until a holder is done", func(t *testing.T) {
+		subject := util.NewAdaptiveThrottle(1)
+		subject.Take()
+
+		taken := make(chan struct{})
+		go func() {
+			subject.Take()
+			close(taken)
+		}()
+
+		select {
+		case <-taken:
+			t.Fatalf("Expected Take to block while the throttle is in use")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		subject.Done()
+		<-taken
+		assert.Equal(t, 1, subject.InUse())
+	})
+
+	t.Run("raising the maximum lets the waiting holders in", func(t *testing.T) {
+		subject := util.NewAdaptiveThrottle(1)
+		subject.Take()
+
+		taken := make(chan struct{})
+		go func() {
+			subject.Take()
+			close(taken)
+		}()
+
+		subject.SetMax(2)
+		<-taken
+		assert.Equal(t, 2, subject.InUse())
+		assert.Equal(t, 2, subject.Max())
+	})
+
+	t.Run("lowering the maximum keeps the current holders and blocks new ones", func(t *testing.T) {
+		subject := util.NewAdaptiveThrottle(3)
+		subject.Take()
+		subject.Take()
+
+		subject.SetMax(1)
+		assert.Equal(t, 2, subject.InUse())
+
+		taken := make(chan struct{})
+		go func() {
+			subject.Take()
+			close(taken)
+		}()
+
+		subject.Done()
+		select {
+		case <-taken:
+			t.Fatalf("Expected Take to block until the holders are below the maximum")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		subject.Done()
+		<-taken
+		assert.Equal(t, 1, subject.InUse())
+	})
+
+	t.Run("the maximum is at least 1", func(t *testing.T) {
+		subject := util.NewAdaptiveThrottle(2)
+		subject.SetMax(0)
+		require.Equal(t, 1, subject.Max())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	pb "github.com/cheggaaa/pb/v3"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -19,6 +20,18 @@ type ProgressLogger interface {
 	End()
 }
 
+// ProgressMode How the progress of a transfer is displayed
+type ProgressMode string
+
+const (
+	// ProgressModeAuto displays a progress bar when the output is a terminal, and nothing otherwise
+	ProgressModeAuto ProgressMode = "auto"
+	// ProgressModePlain logs a line with the progress periodically
+	ProgressModePlain ProgressMode = "plain"
+	// ProgressModeNone does not display the progress, only the final summary
+	ProgressModeNone ProgressMode = "none"
+)
+
 // NewProgressBar constructor to build a ProgressLogger responsible for printing out a progress bar using updates when
 // writing to a registry via ggcr
 func NewProgressBar(logger LoggerWithLevels, finalMessage, errorMessagePrefix string) ProgressLogger {
@@ -117,3 +130,91 @@ func (l *ProgressBarNoTTYLogger) End() {
 		l.logger.Logf(l.finalMessage)
 	}
 }
+
+// NewProgressLines constructs a ProgressLogger that, instead of a progress bar, logs a line with the bytes transferred
+// every interval, or no line at all when interval is 0, and always ends with a summary of the transfer
+func NewProgressLines(logger LoggerWithLevels, interval time.Duration, finalMessage, errorMessagePrefix string) ProgressLogger {
+	return &ProgressLinesLogger{logger: logger, interval: interval, finalMessage: finalMessage, errorMessagePrefix: errorMessagePrefix}
+}
+
+// ProgressLinesLogger logs the progress as plain lines, that can be read in logs that do not support terminal control sequences
+type ProgressLinesLogger struct {
+	logger             LoggerWithLevels
+	interval           time.Duration
+	finalMessage       string
+	errorMessagePrefix string
+
+	cancelFunc context.CancelFunc
+	doneCh     chan struct{}
+	startedAt  time.Time
+	last       regv1.Update
+}
+
+// Start consuming the progress channel, logging the progress every interval
+func (l *ProgressLinesLogger) Start(ctx context.Context, progressChan <-chan regv1.Update) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	l.cancelFunc = cancelFunc
+	l.doneCh = make(chan struct{})
+	l.startedAt = time.Now()
+
+	var tickerCh <-chan time.Time
+	if l.interval > 0 {
+		ticker := time.NewTicker(l.interval)
+		tickerCh = ticker.C
+		go func() {
+			<-l.doneCh
+			ticker.Stop()
+		}()
+	}
+
+	go func() {
+		defer close(l.doneCh)
+		var logged regv1.Update
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-progressChan:
+				if !ok {
+					progressChan = nil
+					continue
+				}
+				if update.Error != nil {
+					l.logger.Errorf("%s: %s\n", l.errorMessagePrefix, update.Error)
+					continue
+				}
+				l.last = update
+			case <-tickerCh:
+				if l.last.Total == 0 || l.last == logged {
+					continue
+				}
+				logged = l.last
+				l.logger.Logf("%s of %s (%d%%)\n", HumanizeBytes(l.last.Complete), HumanizeBytes(l.last.Total), l.last.Complete*100/l.last.Total)
+			}
+		}
+	}()
+}
+
+// End stops logging the progress and writes the final message with a summary of the transfer
+func (l *ProgressLinesLogger) End() {
+	if l.cancelFunc == nil {
+		return
+	}
+	l.cancelFunc()
+	<-l.doneCh
+	l.logger.Logf("%s (%s in %s)\n", l.finalMessage, HumanizeBytes(l.last.Complete), time.Since(l.startedAt).Round(time.Second))
+}
+
+// HumanizeBytes Formats a number of bytes using binary units, example: 1.5 MiB
+func HumanizeBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressLines(t *testing.T) {
+	t.Run("it logs the progress periodically and ends with a summary", func(t *testing.T) {
+		logger := &syncLogger{}
+		subject := util.NewProgressLines(util.NewUILevelLogger(util.LogWarn, logger), 10*time.Millisecond, "done uploading images", "Error uploading images")
+
+		progressCh := make(chan regv1.Update)
+		subject.Start(context.Background(), progressCh)
+		progressCh <- regv1.Update{Complete: 512, Total: 2048}
+		require.Eventually(t, func() bool { return strings.Contains(logger.String(), "512 B") }, time.Second, 5*time.Millisecond)
+		progressCh <- regv1.Update{Complete: 2048, Total: 2048}
+		close(progressCh)
+		subject.End()
+
+		lines := strings.Split(strings.TrimSpace(logger.String()), "\n")
+		assert.Equal(t, "512 B of 2.0 KiB (25%)", lines[0])
+		assert.Regexp(t, `^done uploading images \(2\.0 KiB in \d+s\)$`, lines[len(lines)-1])
+	})
+
+	t.Run("when there is no interval, it only logs the errors and the summary", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		subject := util.NewProgressLines(util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(buf)), 0, "done uploading images", "Error uploading images")
+
+		progressCh := make(chan regv1.Update)
+		subject.Start(context.Background(), progressCh)
+		progressCh <- regv1.Update{Complete: 1024, Total: 2048}
+		progressCh <- regv1.Update{Error: errors.New("connection reset")}
+		progressCh <- regv1.Update{Complete: 2048, Total: 2048}
+		subject.End()
+
+		out := buf.String()
+		assert.NotContains(t, out, "of 2.0 KiB")
+		assert.Contains(t, out, "Error uploading images: connection reset")
+		assert.Contains(t, out, "done uploading images (2.0 KiB in 0s)")
+	})
+}
+
+type syncLogger struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (s *syncLogger) Logf(msg string, args ...interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fmt.Fprintf(&s.buf, msg, args...)
+}
+
+func (s *syncLogger) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buf.String()
+}
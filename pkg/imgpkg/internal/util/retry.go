@@ -4,6 +4,7 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,6 +19,12 @@ func (n NonRetryableError) Error() string {
 	return n.Message
 }
 
+// NonRetryable Implemented by the errors that Retry returns without retrying, even when they are wrapped
+// (example: the errors returned by a http.RoundTripper, that reach the caller wrapped in a *url.Error)
+type NonRetryable interface {
+	NonRetryable()
+}
+
 func Retry(doFunc func() error) error {
 	var lastErr error
 
@@ -27,7 +34,8 @@ func Retry(doFunc func() error) error {
 			return nil
 		}
 
-		if tranErr, ok := lastErr.(*transport.Error); ok {
+		var tranErr *transport.Error
+		if errors.As(lastErr, &tranErr) {
 			if len(tranErr.Errors) > 0 {
 				if tranErr.Errors[0].Code == transport.UnauthorizedErrorCode {
 					return fmt.Errorf("Non-retryable error: %s", lastErr)
@@ -37,6 +45,10 @@ func Retry(doFunc func() error) error {
 		if nonRetryableError, ok := lastErr.(NonRetryableError); ok {
 			return nonRetryableError
 		}
+		var nonRetryable NonRetryable
+		if errors.As(lastErr, &nonRetryable) {
+			return lastErr
+		}
 
 		time.Sleep(1 * time.Second)
 	}
@@ -5,6 +5,7 @@ package util
 
 import (
 	"errors"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -60,3 +61,27 @@ func TestNonRetryableErrorDoesNotRetry(t *testing.T) {
 		t.Fatalf("Expected error message to contain %s, but got: %s", expectedError, err)
 	}
 }
+
+type budgetExceededError struct{}
+
+func (budgetExceededError) Error() string { return "budget exceeded" }
+
+func (budgetExceededError) NonRetryable() {}
+
+func TestWrappedNonRetryableErrorDoesNotRetry(t *testing.T) {
+	numOfRetries := 0
+
+	err := Retry(func() error {
+		numOfRetries++
+		return &url.Error{Op: "Get", URL: "https://registry.corp/v2/", Err: budgetExceededError{}}
+	})
+
+	if numOfRetries != 1 {
+		t.Fatalf("Expected to retry 1 times, but ran %d", numOfRetries)
+	}
+
+	expectedError := "budget exceeded"
+	if !strings.Contains(err.Error(), expectedError) {
+		t.Fatalf("Expected error message to contain %s, but got: %s", expectedError, err)
+	}
+}
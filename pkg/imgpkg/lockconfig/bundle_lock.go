@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	"sigs.k8s.io/yaml"
 )
@@ -18,7 +19,9 @@ const (
 
 type BundleLock struct {
 	LockVersion
-	Bundle BundleRef `json:"bundle"` // This generated yaml, but due to lib we need to use `json`
+	// Annotations describe how the lock file was generated (example: imgpkg.carvel.dev/source)
+	Annotations map[string]string `json:"annotations,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	Bundle      BundleRef         `json:"bundle"`                // This generated yaml, but due to lib we need to use `json`
 }
 
 type BundleRef struct {
@@ -32,59 +35,75 @@ func NewBundleLockFromPath(path string) (BundleLock, error) {
 		return BundleLock{}, fmt.Errorf("Reading path %s: %s", path, err)
 	}
 
-	return NewBundleLockFromBytes(bs)
+	lock, err := NewBundleLockFromBytes(bs)
+	return lock, withPath(err, path)
 }
 
+// NewBundleLockFromBytes Parses and validates a BundleLock, when the lock is not valid a *ValidationError is returned
 func NewBundleLockFromBytes(data []byte) (BundleLock, error) {
 	var lock BundleLock
 
-	err := yaml.UnmarshalStrict(data, &lock)
+	err := validateBundleLockDocument(data)
+	if err != nil {
+		return lock, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &lock)
 	if err != nil {
 		return lock, fmt.Errorf("Unmarshaling bundle lock: %s", err)
 	}
 
 	err = lock.Validate()
 	if err != nil {
-		return lock, fmt.Errorf("Validating bundle lock: %s", err)
+		return lock, err
 	}
 
 	return lock, nil
 }
 
+// Validate Checks that the BundleLock is valid, when it is not a *ValidationError is returned
 func (b BundleLock) Validate() error {
+	var errs []FieldError
 	if b.APIVersion != BundleLockAPIVersion {
-		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", BundleLockAPIVersion)
+		errs = append(errs, FieldError{Field: "apiVersion", Message: fmt.Sprintf("Unknown version (known: %s)", BundleLockAPIVersion)})
 	}
 	if b.Kind != BundleLockKind {
-		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", BundleLockKind)
+		errs = append(errs, FieldError{Field: "kind", Message: fmt.Sprintf("Unknown kind (known: %s)", BundleLockKind)})
 	}
 	if _, err := regname.NewDigest(b.Bundle.Image); err != nil {
-		return fmt.Errorf("Expected ref to be in digest form, got '%s'", b.Bundle.Image)
+		errs = append(errs, FieldError{Field: "bundle.image", Message: fmt.Sprintf("Expected ref to be in digest form, got '%s'", b.Bundle.Image)})
 	}
-	return nil
+	return newValidationError("bundle lock", errs)
 }
 
+// AsBytes Returns the lock as a YAML document
 func (b BundleLock) AsBytes() ([]byte, error) {
-	err := b.Validate()
-	if err != nil {
-		return nil, fmt.Errorf("Validating bundle lock: %s", err)
-	}
+	return b.AsBytesInFormat(FormatYAML)
+}
 
-	bs, err := yaml.Marshal(b)
+// AsBytesInFormat Returns the lock as a document in the format provided
+func (b BundleLock) AsBytesInFormat(format Format) ([]byte, error) {
+	err := b.Validate()
 	if err != nil {
-		return nil, fmt.Errorf("Marshaling config: %s", err)
+		return nil, err
 	}
 
-	return []byte(fmt.Sprintf("---\n%s", bs)), nil
+	return marshal(b, format)
 }
 
+// WriteToPath Writes the lock to path as a YAML document
 func (b BundleLock) WriteToPath(path string) error {
-	bs, err := b.AsBytes()
+	return b.WriteToPathInFormat(path, FormatYAML)
+}
+
+// WriteToPathInFormat Writes the lock to path as a document in the format provided
+func (b BundleLock) WriteToPathInFormat(path string, format Format) error {
+	bs, err := b.AsBytesInFormat(format)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(path, bs, 0600)
+	err = atomicfile.WriteFile(path, bs, 0600)
 	if err != nil {
 		return fmt.Errorf("Writing bundle config: %s", err)
 	}
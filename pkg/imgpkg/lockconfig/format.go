@@ -0,0 +1,61 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Format Format in which the locks are written, both of them can be read back
+type Format string
+
+const (
+	// FormatYAML YAML document, the default
+	FormatYAML Format = "yaml"
+	// FormatJSON JSON document, for automation that reads the locks with jq or JSON parsers
+	FormatJSON Format = "json"
+)
+
+// Formats Returns the names of the formats in which the locks can be written
+func Formats() []string {
+	return []string{string(FormatYAML), string(FormatJSON)}
+}
+
+// ParseFormat Returns the format with the name provided, or the one of the extension of path when name is empty:
+// JSON for .json files and YAML for any other file
+func ParseFormat(name, path string) (Format, error) {
+	switch Format(name) {
+	case FormatYAML, FormatJSON:
+		return Format(name), nil
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return FormatJSON, nil
+		}
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("Unknown lock format '%s' (known: %s)", name, strings.Join(Formats(), ", "))
+	}
+}
+
+// marshal Returns the lock as a document in the format provided
+func marshal(lock interface{}, format Format) ([]byte, error) {
+	if format == FormatJSON {
+		bs, err := json.MarshalIndent(lock, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Marshaling config: %s", err)
+		}
+		return append(bs, '\n'), nil
+	}
+
+	bs, err := yaml.Marshal(lock)
+	if err != nil {
+		return nil, fmt.Errorf("Marshaling config: %s", err)
+	}
+	return []byte(fmt.Sprintf("---\n%s", bs)), nil
+}
@@ -7,6 +7,20 @@ import (
 	"fmt"
 )
 
+const (
+	// LockGeneratedByAnnotationKey Annotation of a lock file with the version of imgpkg that generated it
+	LockGeneratedByAnnotationKey = "imgpkg.carvel.dev/generated-by"
+	// LockGeneratedAtAnnotationKey Annotation of a lock file with the time it was generated (format: RFC3339)
+	LockGeneratedAtAnnotationKey = "imgpkg.carvel.dev/generated-at"
+	// LockSourceAnnotationKey Annotation of a lock file with the references, or paths, the images were copied or pushed from
+	LockSourceAnnotationKey = "imgpkg.carvel.dev/source"
+	// LockDestinationAnnotationKey Annotation of a lock file with the location the images were copied or pushed to
+	LockDestinationAnnotationKey = "imgpkg.carvel.dev/destination"
+	// LockOptionsAnnotationKey Annotation of a lock file with the options that changed what was copied or pushed
+	// (format: key=value, separated by spaces, example: platform=linux/amd64,linux/arm64 include-non-distributable-layers=false)
+	LockOptionsAnnotationKey = "imgpkg.carvel.dev/options"
+)
+
 type LockVersion struct {
 	APIVersion string `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
 	Kind       string `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
@@ -0,0 +1,61 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// NewImagesLockFromDigestFiles Reads the files with an image reference with digest per line, like the ones written by
+// ko (--image-refs) or the Bazel rules, into an ImagesLock. Empty lines and lines starting with # are ignored,
+// the tag of the references that have one is recorded as the reference the image was resolved from
+func NewImagesLockFromDigestFiles(paths []string) (ImagesLock, error) {
+	lock := NewEmptyImagesLock()
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return ImagesLock{}, fmt.Errorf("Reading image digest file: %s", err)
+		}
+
+		for i, line := range strings.Split(string(bs), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			imageRef, err := newImageRefFromDigestLine(line)
+			if err != nil {
+				return ImagesLock{}, fmt.Errorf("Parsing line %d of image digest file '%s': %s", i+1, path, err)
+			}
+			lock.AddImageRef(imageRef)
+		}
+	}
+
+	if len(lock.Images) == 0 {
+		return ImagesLock{}, fmt.Errorf("Expected image digest files %s to contain at least one image reference", strings.Join(paths, ", "))
+	}
+	return lock, nil
+}
+
+// newImageRefFromDigestLine Parses a reference with digest, optionally with a tag (example: registry.corp/app:1.0@sha256:...)
+func newImageRefFromDigestLine(line string) (ImageRef, error) {
+	if strings.HasPrefix(line, "sha256:") {
+		return ImageRef{}, fmt.Errorf("Expected '%s' to be an image reference with digest (example: registry.corp/app@sha256:...), "+
+			"but it does not have a repository", line)
+	}
+	digestRef, err := regname.NewDigest(line)
+	if err != nil {
+		return ImageRef{}, fmt.Errorf("Expected '%s' to be an image reference with digest (example: registry.corp/app@sha256:...): %s", line, err)
+	}
+
+	imageRef := ImageRef{Image: digestRef.Name()}
+	taggedRef := strings.SplitN(line, "@", 2)[0]
+	if tagRef, err := regname.NewTag(taggedRef); err == nil && strings.HasSuffix(taggedRef, ":"+tagRef.TagStr()) {
+		imageRef.Annotations = map[string]string{ImageRefOriginAnnotationKey: taggedRef}
+	}
+	return imageRef, nil
+}
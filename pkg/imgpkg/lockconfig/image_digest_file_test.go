@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	appDigest    = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	workerDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestNewImagesLockFromDigestFiles(t *testing.T) {
+	t.Run("it reads a reference per line, recording the tag the image was resolved from", func(t *testing.T) {
+		first := writeDigestFile(t, "# written by ko\nregistry.corp/app:1.0@"+appDigest+"\n\nregistry.corp/worker@"+workerDigest+"\n")
+		second := writeDigestFile(t, "registry.corp/app@"+appDigest)
+
+		lock, err := lockconfig.NewImagesLockFromDigestFiles([]string{first, second})
+		require.NoError(t, err)
+		assert.Equal(t, lockconfig.ImagesLockKind, lock.Kind)
+		require.Len(t, lock.Images, 2, "the images listed in several files are copied once")
+		assert.Equal(t, "registry.corp/app@"+appDigest, lock.Images[0].Image)
+		assert.Equal(t, "1.0", lock.Images[0].OriginalTag())
+		assert.Equal(t, "registry.corp/worker@"+workerDigest, lock.Images[1].Image)
+		assert.Equal(t, "", lock.Images[1].OriginalTag())
+	})
+
+	t.Run("when a line only has a digest, it errors", func(t *testing.T) {
+		path := writeDigestFile(t, "registry.corp/app@"+appDigest+"\n"+workerDigest+"\n")
+
+		_, err := lockconfig.NewImagesLockFromDigestFiles([]string{path})
+		require.EqualError(t, err, "Parsing line 2 of image digest file '"+path+"': Expected '"+workerDigest+
+			"' to be an image reference with digest (example: registry.corp/app@sha256:...), but it does not have a repository")
+	})
+
+	t.Run("when a line is a tag, it errors", func(t *testing.T) {
+		path := writeDigestFile(t, "registry.corp/app:1.0\n")
+
+		_, err := lockconfig.NewImagesLockFromDigestFiles([]string{path})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Parsing line 1 of image digest file")
+	})
+
+	t.Run("when the files do not have references, it errors", func(t *testing.T) {
+		path := writeDigestFile(t, "# nothing was built\n")
+
+		_, err := lockconfig.NewImagesLockFromDigestFiles([]string{path})
+		require.EqualError(t, err, "Expected image digest files "+path+" to contain at least one image reference")
+	})
+}
+
+func writeDigestFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "image.digest")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
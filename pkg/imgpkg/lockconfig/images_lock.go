@@ -6,19 +6,34 @@ package lockconfig
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/atomicfile"
 	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"sigs.k8s.io/yaml"
 )
 
 const (
 	ImagesLockKind       = "ImagesLock"
 	ImagesLockAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+
+	// ImageRefOriginAnnotationKey Annotation added by kbld with the reference the image was resolved from (example: nginx:1.21)
+	ImageRefOriginAnnotationKey = "kbld.carvel.dev/id"
+	// ImageRefTagAnnotationKey Annotation with the tagged reference of the image in the location it was copied to
+	ImageRefTagAnnotationKey = "imgpkg.carvel.dev/tag"
+	// ImageRefResolvedTagAnnotationKey Annotation with the tagged reference the image was resolved from by ResolveTags
+	ImageRefResolvedTagAnnotationKey = "imgpkg.carvel.dev/resolved-tag"
+	// ImageRefExcludedAnnotationKey Annotation of the images that were excluded when the bundle was copied,
+	// they are only available in their original location
+	ImageRefExcludedAnnotationKey = "imgpkg.carvel.dev/excluded"
 )
 
 type ImagesLock struct {
 	LockVersion
-	Images []ImageRef `json:"images,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// Annotations describe how the lock file was generated (example: imgpkg.carvel.dev/source)
+	Annotations map[string]string `json:"annotations,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	Images      []ImageRef        `json:"images,omitempty"`      // This generated yaml, but due to lib we need to use `json`
 }
 
 type ImageRef struct {
@@ -42,20 +57,27 @@ func NewImagesLockFromPath(path string) (ImagesLock, error) {
 		return ImagesLock{}, fmt.Errorf("Reading path %s: %s", path, err)
 	}
 
-	return NewImagesLockFromBytes(bs)
+	lock, err := NewImagesLockFromBytes(bs)
+	return lock, withPath(err, path)
 }
 
+// NewImagesLockFromBytes Parses and validates an ImagesLock, when the lock is not valid a *ValidationError is returned
 func NewImagesLockFromBytes(data []byte) (ImagesLock, error) {
 	var lock ImagesLock
 
-	err := yaml.UnmarshalStrict(data, &lock)
+	err := validateImagesLockDocument(data, false)
+	if err != nil {
+		return lock, err
+	}
+
+	err = yaml.UnmarshalStrict(data, &lock)
 	if err != nil {
 		return lock, fmt.Errorf("Unmarshaling images lock: %s", err)
 	}
 
 	err = lock.Validate()
 	if err != nil {
-		return lock, fmt.Errorf("Validating images lock: %s", err)
+		return lock, err
 	}
 
 	// Update the image lock file to use a fully qualified name
@@ -72,6 +94,63 @@ func NewImagesLockFromBytes(data []byte) (ImagesLock, error) {
 	return lock, nil
 }
 
+// NewImagesLockWithTagsFromPath Reads the ImagesLock in path, allowing images to be referenced by tag so that they
+// can be resolved to their digest with ResolveTags
+func NewImagesLockWithTagsFromPath(path string) (ImagesLock, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return ImagesLock{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	var lock ImagesLock
+	err = validateImagesLockDocument(bs, true)
+	if err != nil {
+		return lock, withPath(err, path)
+	}
+
+	err = yaml.UnmarshalStrict(bs, &lock)
+	if err != nil {
+		return lock, fmt.Errorf("Unmarshaling images lock %s: %s", path, err)
+	}
+
+	return lock, nil
+}
+
+// ResolveTags Returns a copy of the ImagesLock where the images referenced by tag are referenced by the digest
+// returned by resolve. The tagged reference is recorded in the ImageRefResolvedTagAnnotationKey annotation,
+// and in the ImageRefOriginAnnotationKey annotation when the image does not have one yet
+func (i ImagesLock) ResolveTags(resolve func(regname.Tag) (regv1.Hash, error)) (ImagesLock, error) {
+	resolvedLock := i
+	resolvedLock.Images = nil
+
+	for _, image := range i.Images {
+		image = image.DeepCopy()
+
+		ref, err := regname.ParseReference(image.Image)
+		if err != nil {
+			return ImagesLock{}, fmt.Errorf("Parsing reference '%s': %s", image.Image, err)
+		}
+
+		if tagRef, isTag := ref.(regname.Tag); isTag {
+			digest, err := resolve(tagRef)
+			if err != nil {
+				return ImagesLock{}, fmt.Errorf("Resolving tag of image '%s': %s", image.Image, err)
+			}
+
+			image.Annotations[ImageRefResolvedTagAnnotationKey] = image.Image
+			if _, found := image.Annotations[ImageRefOriginAnnotationKey]; !found {
+				image.Annotations[ImageRefOriginAnnotationKey] = image.Image
+			}
+			image.Image = tagRef.Context().Digest(digest.String()).Name()
+			image.locations = nil
+		}
+
+		resolvedLock.Images = append(resolvedLock.Images, image)
+	}
+
+	return resolvedLock, nil
+}
+
 func (i *ImagesLock) AddImageRef(ref ImageRef) {
 	for _, image := range i.Images {
 		if image.Image == ref.Image {
@@ -81,25 +160,33 @@ func (i *ImagesLock) AddImageRef(ref ImageRef) {
 	i.Images = append(i.Images, ref)
 }
 
+// Validate Checks that the ImagesLock is valid, when it is not a *ValidationError is returned
 func (i ImagesLock) Validate() error {
+	var errs []FieldError
 	if i.APIVersion != ImagesLockAPIVersion {
-		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", ImagesLockAPIVersion)
+		errs = append(errs, FieldError{Field: "apiVersion", Message: fmt.Sprintf("Unknown version (known: %s)", ImagesLockAPIVersion)})
 	}
 	if i.Kind != ImagesLockKind {
-		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", ImagesLockKind)
+		errs = append(errs, FieldError{Field: "kind", Message: fmt.Sprintf("Unknown kind (known: %s)", ImagesLockKind)})
 	}
-	for _, imageRef := range i.Images {
+	for idx, imageRef := range i.Images {
 		if _, err := regname.NewDigest(imageRef.Image); err != nil {
-			return fmt.Errorf("Expected ref to be in digest form, got '%s'", imageRef.Image)
+			errs = append(errs, FieldError{Field: fmt.Sprintf("images[%d].image", idx), Message: fmt.Sprintf("Expected ref to be in digest form, got '%s'", imageRef.Image)})
 		}
 	}
-	return nil
+	return newValidationError("images lock", errs)
 }
 
+// AsBytes Returns the lock as a YAML document
 func (i ImagesLock) AsBytes() ([]byte, error) {
+	return i.AsBytesInFormat(FormatYAML)
+}
+
+// AsBytesInFormat Returns the lock as a document in the format provided
+func (i ImagesLock) AsBytesInFormat(format Format) ([]byte, error) {
 	err := i.Validate()
 	if err != nil {
-		return nil, fmt.Errorf("Validating images lock: %s", err)
+		return nil, err
 	}
 
 	// Use the first location instead of the value present in Image
@@ -111,21 +198,22 @@ func (i ImagesLock) AsBytes() ([]byte, error) {
 	updatedImagesLock := i
 	updatedImagesLock.Images = imgRefs
 
-	bs, err := yaml.Marshal(updatedImagesLock)
-	if err != nil {
-		return nil, fmt.Errorf("Marshaling config: %s", err)
-	}
-
-	return []byte(fmt.Sprintf("---\n%s", bs)), nil
+	return marshal(updatedImagesLock, format)
 }
 
+// WriteToPath Writes the lock to path as a YAML document
 func (i ImagesLock) WriteToPath(path string) error {
-	bs, err := i.AsBytes()
+	return i.WriteToPathInFormat(path, FormatYAML)
+}
+
+// WriteToPathInFormat Writes the lock to path as a document in the format provided
+func (i ImagesLock) WriteToPathInFormat(path string, format Format) error {
+	bs, err := i.AsBytesInFormat(format)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(path, bs, 0600)
+	err = atomicfile.WriteFile(path, bs, 0600)
 	if err != nil {
 		return fmt.Errorf("Writing images config: %s", err)
 	}
@@ -146,6 +234,24 @@ func (i ImageRef) DeepCopy() ImageRef {
 	}
 }
 
+// OriginalTag Tag of the reference the image was resolved from, or "" when the reference did not contain a tag
+func (i ImageRef) OriginalTag() string {
+	origin, found := i.Annotations[ImageRefOriginAnnotationKey]
+	if !found {
+		return ""
+	}
+
+	tagRef, err := regname.NewTag(origin, regname.WeakValidation)
+	if err != nil {
+		return ""
+	}
+	// References without a tag are defaulted to latest, which is not the tag the image was resolved from
+	if !strings.HasSuffix(origin, ":"+tagRef.TagStr()) {
+		return ""
+	}
+	return tagRef.TagStr()
+}
+
 func (i ImageRef) Locations() []string {
 	if i.locations == nil {
 		return []string{i.Image}
@@ -4,9 +4,12 @@
 package lockconfig_test
 
 import (
+	"fmt"
 	"testing"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +24,7 @@ images:
 `
 
 		_, err := lockconfig.NewImagesLockFromBytes([]byte(data))
-		require.EqualError(t, err, "Validating images lock: Expected ref to be in digest form, got 'nginx:v1'")
+		require.EqualError(t, err, "Validating images lock: line 5, column 10: images[0].image: Expected ref to be in digest form, got 'nginx:v1'")
 	})
 
 	t.Run("when yaml contain keys that are unknown, it errors", func(t *testing.T) {
@@ -74,3 +77,60 @@ kind: ImagesLock
 		assert.Contains(t, subject.Images[0].Locations(), "some.image.io/test@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0")
 	})
 }
+
+func TestImageRefOriginalTag(t *testing.T) {
+	t.Run("when the image was resolved from a tag, it returns the tag", func(t *testing.T) {
+		imageRef := lockconfig.ImageRef{Image: "nginx@sha256:8fb0df9a18a10151fc81e1ccda83dae5fac0dd23f7c1a5d1e58b1d1e84d1b4c7", Annotations: map[string]string{"kbld.carvel.dev/id": "index.docker.io/library/nginx:1.21"}}
+		assert.Equal(t, "1.21", imageRef.OriginalTag())
+	})
+
+	t.Run("when the image was resolved from a reference without a tag, it returns empty", func(t *testing.T) {
+		imageRef := lockconfig.ImageRef{Image: "nginx@sha256:8fb0df9a18a10151fc81e1ccda83dae5fac0dd23f7c1a5d1e58b1d1e84d1b4c7", Annotations: map[string]string{"kbld.carvel.dev/id": "nginx"}}
+		assert.Equal(t, "", imageRef.OriginalTag())
+	})
+
+	t.Run("when the image has no kbld annotation, it returns empty", func(t *testing.T) {
+		imageRef := lockconfig.ImageRef{Image: "nginx@sha256:8fb0df9a18a10151fc81e1ccda83dae5fac0dd23f7c1a5d1e58b1d1e84d1b4c7"}
+		assert.Equal(t, "", imageRef.OriginalTag())
+	})
+}
+
+func TestImagesLockResolveTags(t *testing.T) {
+	digest, err := regv1.NewHash("sha256:8fb0df9a18a10151fc81e1ccda83dae5fac0dd23f7c1a5d1e58b1d1e84d1b4c7")
+	require.NoError(t, err)
+	resolve := func(ref regname.Tag) (regv1.Hash, error) {
+		if ref.TagStr() != "1.21" {
+			return regv1.Hash{}, fmt.Errorf("tag not found")
+		}
+		return digest, nil
+	}
+
+	t.Run("replaces the images referenced by tag with their digest, and records the tagged reference", func(t *testing.T) {
+		imagesLock := lockconfig.NewEmptyImagesLock()
+		imagesLock.Images = []lockconfig.ImageRef{
+			{Image: "nginx:1.21"},
+			{Image: "registry.example.com/app:1.21", Annotations: map[string]string{"kbld.carvel.dev/id": "app"}},
+			{Image: "registry.example.com/other@sha256:8fb0df9a18a10151fc81e1ccda83dae5fac0dd23f7c1a5d1e58b1d1e84d1b4c7"},
+		}
+
+		resolvedLock, err := imagesLock.ResolveTags(resolve)
+		require.NoError(t, err)
+		require.Len(t, resolvedLock.Images, 3)
+		assert.Equal(t, "index.docker.io/library/nginx@"+digest.String(), resolvedLock.Images[0].Image)
+		assert.Equal(t, map[string]string{"kbld.carvel.dev/id": "nginx:1.21", "imgpkg.carvel.dev/resolved-tag": "nginx:1.21"}, resolvedLock.Images[0].Annotations)
+		assert.Equal(t, "registry.example.com/app@"+digest.String(), resolvedLock.Images[1].Image)
+		assert.Equal(t, map[string]string{"kbld.carvel.dev/id": "app", "imgpkg.carvel.dev/resolved-tag": "registry.example.com/app:1.21"}, resolvedLock.Images[1].Annotations)
+		assert.Equal(t, "registry.example.com/other@"+digest.String(), resolvedLock.Images[2].Image)
+		require.NoError(t, resolvedLock.Validate())
+
+		assert.Equal(t, "nginx:1.21", imagesLock.Images[0].Image, "the original ImagesLock should not be changed")
+	})
+
+	t.Run("when a tag cannot be resolved, it errors", func(t *testing.T) {
+		imagesLock := lockconfig.NewEmptyImagesLock()
+		imagesLock.Images = []lockconfig.ImageRef{{Image: "nginx:1.20"}}
+
+		_, err := imagesLock.ResolveTags(resolve)
+		require.EqualError(t, err, "Resolving tag of image 'nginx:1.20': tag not found")
+	})
+}
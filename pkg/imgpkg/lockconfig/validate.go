@@ -0,0 +1,297 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// FieldError Problem found in a lock file field, Line and Column are 0 when the position is not known
+type FieldError struct {
+	Field   string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error Returns the problem prefixed by its position and field name
+func (e FieldError) Error() string {
+	position := ""
+	if e.Line > 0 {
+		position = fmt.Sprintf("line %d, column %d: ", e.Line, e.Column)
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("%s%s: %s", position, e.Field, e.Message)
+	}
+	return position + e.Message
+}
+
+// ValidationError Contains all the problems found while validating a lock file
+type ValidationError struct {
+	// Kind of lock file being validated, i.e. images lock or bundle lock
+	Kind string
+	// Path of the lock file, empty when the lock file was not read from disk
+	Path   string
+	Errors []FieldError
+}
+
+// Error Returns all the problems found in the lock file
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, fieldErr := range e.Errors {
+		msgs = append(msgs, fieldErr.Error())
+	}
+
+	prefix := "Validating " + e.Kind
+	if e.Path != "" {
+		prefix += fmt.Sprintf(" '%s'", e.Path)
+	}
+	return fmt.Sprintf("%s: %s", prefix, strings.Join(msgs, ", "))
+}
+
+// Validate Validates the content of a BundleLock or ImagesLock. When problems are found a *ValidationError is returned
+// with the field and position of each one of them
+func Validate(data []byte) error {
+	root, fieldErr := parseDocument(data)
+	if fieldErr != nil {
+		return &ValidationError{Kind: "lock", Errors: []FieldError{*fieldErr}}
+	}
+
+	switch kind := scalarValue(mappingValue(root, "kind")); kind {
+	case BundleLockKind:
+		return newValidationError("bundle lock", validateBundleLockNode(root))
+	case ImagesLockKind:
+		return newValidationError("images lock", validateImagesLockNode(root, false))
+	default:
+		return &ValidationError{Kind: "lock", Errors: []FieldError{
+			newFieldError("kind", nodeOrParent(mappingValue(root, "kind"), root),
+				fmt.Sprintf("Unknown kind (known: %s, %s)", BundleLockKind, ImagesLockKind)),
+		}}
+	}
+}
+
+// ValidateFile Validates the BundleLock or ImagesLock stored in path
+func ValidateFile(path string) error {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	return withPath(Validate(bs), path)
+}
+
+// validateImagesLockDocument Validates the ImagesLock, when allowTags is true the images can also be referenced by tag
+func validateImagesLockDocument(data []byte, allowTags bool) error {
+	root, fieldErr := parseDocument(data)
+	if fieldErr != nil {
+		return &ValidationError{Kind: "images lock", Errors: []FieldError{*fieldErr}}
+	}
+	return newValidationError("images lock", validateImagesLockNode(root, allowTags))
+}
+
+func validateBundleLockDocument(data []byte) error {
+	root, fieldErr := parseDocument(data)
+	if fieldErr != nil {
+		return &ValidationError{Kind: "bundle lock", Errors: []FieldError{*fieldErr}}
+	}
+	return newValidationError("bundle lock", validateBundleLockNode(root))
+}
+
+func validateImagesLockNode(root *yamlv3.Node, allowTags bool) []FieldError {
+	errs := validateLockVersionNode(root, ImagesLockAPIVersion, ImagesLockKind)
+	errs = append(errs, unknownFields(root, "", "apiVersion", "kind", "annotations", "images")...)
+	errs = append(errs, validateAnnotationsNode("annotations", mappingValue(root, "annotations"))...)
+
+	images := mappingValue(root, "images")
+	if images == nil || isNull(images) {
+		return errs
+	}
+	if images.Kind != yamlv3.SequenceNode {
+		return append(errs, newFieldError("images", images, "Expected a list of images"))
+	}
+
+	for i, imageNode := range images.Content {
+		field := fmt.Sprintf("images[%d]", i)
+		if imageNode.Kind != yamlv3.MappingNode {
+			errs = append(errs, newFieldError(field, imageNode, "Expected a map"))
+			continue
+		}
+		errs = append(errs, unknownFields(imageNode, field+".", "image", "annotations")...)
+		if allowTags {
+			errs = append(errs, validateRefNode(field+".image", mappingValue(imageNode, "image"), imageNode)...)
+		} else {
+			errs = append(errs, validateDigestRefNode(field+".image", mappingValue(imageNode, "image"), imageNode)...)
+		}
+		errs = append(errs, validateAnnotationsNode(field+".annotations", mappingValue(imageNode, "annotations"))...)
+	}
+	return errs
+}
+
+func validateAnnotationsNode(field string, annotations *yamlv3.Node) []FieldError {
+	if annotations == nil || isNull(annotations) {
+		return nil
+	}
+	if annotations.Kind != yamlv3.MappingNode {
+		return []FieldError{newFieldError(field, annotations, "Expected a map of strings")}
+	}
+
+	var errs []FieldError
+	for j := 1; j < len(annotations.Content); j += 2 {
+		if annotations.Content[j].Kind != yamlv3.ScalarNode {
+			errs = append(errs, newFieldError(fmt.Sprintf("%s.%s", field, annotations.Content[j-1].Value),
+				annotations.Content[j], "Expected a string"))
+		}
+	}
+	return errs
+}
+
+func validateBundleLockNode(root *yamlv3.Node) []FieldError {
+	errs := validateLockVersionNode(root, BundleLockAPIVersion, BundleLockKind)
+	errs = append(errs, unknownFields(root, "", "apiVersion", "kind", "annotations", "bundle")...)
+	errs = append(errs, validateAnnotationsNode("annotations", mappingValue(root, "annotations"))...)
+
+	bundle := mappingValue(root, "bundle")
+	if bundle != nil && !isNull(bundle) {
+		if bundle.Kind != yamlv3.MappingNode {
+			return append(errs, newFieldError("bundle", bundle, "Expected a map"))
+		}
+		errs = append(errs, unknownFields(bundle, "bundle.", "image", "tag")...)
+		if tag := mappingValue(bundle, "tag"); tag != nil && tag.Kind != yamlv3.ScalarNode {
+			errs = append(errs, newFieldError("bundle.tag", tag, "Expected a string"))
+		}
+		return append(errs, validateDigestRefNode("bundle.image", mappingValue(bundle, "image"), bundle)...)
+	}
+	return append(errs, validateDigestRefNode("bundle.image", nil, nodeOrParent(bundle, root))...)
+}
+
+func validateLockVersionNode(root *yamlv3.Node, apiVersion, kind string) []FieldError {
+	var errs []FieldError
+	apiVersionNode := mappingValue(root, "apiVersion")
+	if scalarValue(apiVersionNode) != apiVersion {
+		errs = append(errs, newFieldError("apiVersion", nodeOrParent(apiVersionNode, root), fmt.Sprintf("Unknown version (known: %s)", apiVersion)))
+	}
+	kindNode := mappingValue(root, "kind")
+	if scalarValue(kindNode) != kind {
+		errs = append(errs, newFieldError("kind", nodeOrParent(kindNode, root), fmt.Sprintf("Unknown kind (known: %s)", kind)))
+	}
+	return errs
+}
+
+func validateDigestRefNode(field string, node *yamlv3.Node, parent *yamlv3.Node) []FieldError {
+	if node != nil && node.Kind != yamlv3.ScalarNode {
+		return []FieldError{newFieldError(field, node, "Expected a string")}
+	}
+	ref := scalarValue(node)
+	if _, err := regname.NewDigest(ref); err != nil {
+		return []FieldError{newFieldError(field, nodeOrParent(node, parent), fmt.Sprintf("Expected ref to be in digest form, got '%s'", ref))}
+	}
+	return nil
+}
+
+func validateRefNode(field string, node *yamlv3.Node, parent *yamlv3.Node) []FieldError {
+	if node != nil && node.Kind != yamlv3.ScalarNode {
+		return []FieldError{newFieldError(field, node, "Expected a string")}
+	}
+	ref := scalarValue(node)
+	if _, err := regname.ParseReference(ref); err != nil {
+		return []FieldError{newFieldError(field, nodeOrParent(node, parent), fmt.Sprintf("Expected ref to be in digest or tag form, got '%s'", ref))}
+	}
+	return nil
+}
+
+func unknownFields(node *yamlv3.Node, fieldPrefix string, knownFields ...string) []FieldError {
+	var errs []FieldError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		known := false
+		for _, knownField := range knownFields {
+			if key.Value == knownField {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errs = append(errs, newFieldError(fieldPrefix+key.Value, key, fmt.Sprintf("unknown field %q", key.Value)))
+		}
+	}
+	return errs
+}
+
+var yamlErrLine = regexp.MustCompile(`line (\d+)`)
+
+// parseDocument Parses the lock file and returns the root map of the document
+func parseDocument(data []byte) (*yamlv3.Node, *FieldError) {
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(data, &doc)
+	if err != nil {
+		fieldErr := &FieldError{Message: fmt.Sprintf("Unmarshaling: %s", err)}
+		if matches := yamlErrLine.FindStringSubmatch(err.Error()); matches != nil {
+			fieldErr.Line, _ = strconv.Atoi(matches[1])
+		}
+		return nil, fieldErr
+	}
+
+	if len(doc.Content) == 0 {
+		// Empty documents are validated as an empty map
+		return &yamlv3.Node{Kind: yamlv3.MappingNode, Line: 1, Column: 1}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil, &FieldError{Line: root.Line, Column: root.Column, Message: "Expected lock file to be a map"}
+	}
+	return root, nil
+}
+
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarValue(node *yamlv3.Node) string {
+	if node == nil || node.Kind != yamlv3.ScalarNode || isNull(node) {
+		return ""
+	}
+	return node.Value
+}
+
+func isNull(node *yamlv3.Node) bool {
+	return node.Kind == yamlv3.ScalarNode && node.Tag == "!!null"
+}
+
+func nodeOrParent(node, parent *yamlv3.Node) *yamlv3.Node {
+	if node != nil {
+		return node
+	}
+	return parent
+}
+
+func newFieldError(field string, node *yamlv3.Node, message string) FieldError {
+	return FieldError{Field: field, Line: node.Line, Column: node.Column, Message: message}
+}
+
+func newValidationError(kind string, errs []FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Kind: kind, Errors: errs}
+}
+
+func withPath(err error, path string) error {
+	if validationErr, ok := err.(*ValidationError); ok {
+		validationErr.Path = path
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("when lock file is valid, it does not error", func(t *testing.T) {
+		require.NoError(t, lockconfig.Validate([]byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: index.docker.io/library/nginx@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0
+  annotations:
+    some: annotation
+`)))
+		require.NoError(t, lockconfig.Validate([]byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: BundleLock
+bundle:
+  image: index.docker.io/library/bundle@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0
+  tag: v1
+`)))
+	})
+
+	t.Run("when images lock has multiple problems, it returns all of them with their position", func(t *testing.T) {
+		err := lockconfig.Validate([]byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: index.docker.io/library/nginx@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0
+- image: nginx:v1
+  other: field
+`))
+		var validationErr *lockconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr), "expected a ValidationError but got: %s", err)
+		assert.Equal(t, "images lock", validationErr.Kind)
+		require.Len(t, validationErr.Errors, 2)
+
+		assert.Equal(t, lockconfig.FieldError{
+			Field: "images[1].other", Line: 7, Column: 3, Message: `unknown field "other"`,
+		}, validationErr.Errors[0])
+		assert.Equal(t, lockconfig.FieldError{
+			Field: "images[1].image", Line: 6, Column: 10, Message: "Expected ref to be in digest form, got 'nginx:v1'",
+		}, validationErr.Errors[1])
+	})
+
+	t.Run("when bundle lock does not have a bundle, it points to the field that is missing", func(t *testing.T) {
+		err := lockconfig.Validate([]byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: BundleLock
+bundle:
+  tag: v1
+`))
+		var validationErr *lockconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr), "expected a ValidationError but got: %s", err)
+		assert.Equal(t, "bundle lock", validationErr.Kind)
+		require.Len(t, validationErr.Errors, 1)
+		assert.Equal(t, "bundle.image", validationErr.Errors[0].Field)
+		assert.Equal(t, 4, validationErr.Errors[0].Line)
+	})
+
+	t.Run("when lock file has annotations, it checks they are strings", func(t *testing.T) {
+		require.NoError(t, lockconfig.Validate([]byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: BundleLock
+annotations:
+  imgpkg.carvel.dev/source: index.docker.io/library/bundle:v1
+bundle:
+  image: index.docker.io/library/bundle@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0
+`)))
+
+		err := lockconfig.Validate([]byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+annotations:
+  imgpkg.carvel.dev/source: [nginx]
+`))
+		require.EqualError(t, err, "Validating images lock: line 4, column 29: annotations.imgpkg.carvel.dev/source: Expected a string")
+	})
+
+	t.Run("when kind is unknown, it errors", func(t *testing.T) {
+		err := lockconfig.Validate([]byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: SomethingElse
+`))
+		require.EqualError(t, err, "Validating lock: line 2, column 7: kind: Unknown kind (known: BundleLock, ImagesLock)")
+	})
+
+	t.Run("when yaml is malformed, it returns the line of the problem", func(t *testing.T) {
+		err := lockconfig.Validate([]byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: [
+`))
+		var validationErr *lockconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr), "expected a ValidationError but got: %s", err)
+		require.Len(t, validationErr.Errors, 1)
+		assert.NotZero(t, validationErr.Errors[0].Line)
+	})
+}
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: BundleLock
+bundle:
+  image: nginx:v1
+`), 0600))
+
+	err := lockconfig.ValidateFile(path)
+	var validationErr *lockconfig.ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a ValidationError but got: %s", err)
+	assert.Equal(t, path, validationErr.Path)
+	assert.Contains(t, err.Error(), "line 4, column 10: bundle.image: Expected ref to be in digest form, got 'nginx:v1'")
+
+	_, err = lockconfig.NewBundleLockFromPath(path)
+	require.True(t, errors.As(err, &validationErr), "expected a ValidationError but got: %s", err)
+	assert.Equal(t, path, validationErr.Path)
+}
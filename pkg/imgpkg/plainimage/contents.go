@@ -8,11 +8,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
@@ -21,6 +23,12 @@ type Contents struct {
 	paths               []string
 	excludedPaths       []string
 	preservePermissions bool
+	compression         ctlimg.LayerCompression
+	created             time.Time
+	annotations         map[string]string
+	fileFilter          ctlimg.FileFilter
+	fileAttributes      ctlimg.FileAttributes
+	layerSplit          ctlimg.LayerSplit
 }
 
 // ImagesWriter defines the needed functions to write to the registry
@@ -34,6 +42,42 @@ func NewContents(paths []string, excludedPaths []string, preservePermissions boo
 	return Contents{paths: paths, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
 }
 
+// WithCompression Compresses the layer of the image with compression instead of gzip
+func (i Contents) WithCompression(compression ctlimg.LayerCompression) Contents {
+	i.compression = compression
+	return i
+}
+
+// WithCreated Records created as the creation time of the image and as the modification time of its files
+func (i Contents) WithCreated(created time.Time) Contents {
+	i.created = created
+	return i
+}
+
+// WithAnnotations Adds annotations to the manifest of the image
+func (i Contents) WithAnnotations(annotations map[string]string) Contents {
+	i.annotations = annotations
+	return i
+}
+
+// WithFileFilter Adds the contents returned by fileFilter, instead of the contents on disk, for each file of the image
+func (i Contents) WithFileFilter(fileFilter ctlimg.FileFilter) Contents {
+	i.fileFilter = fileFilter
+	return i
+}
+
+// WithFileAttributes Records the owner and the permissions of fileAttributes for the files and directories of the image
+func (i Contents) WithFileAttributes(fileAttributes ctlimg.FileAttributes) Contents {
+	i.fileAttributes = fileAttributes
+	return i
+}
+
+// WithLayerSplit Splits the files of the image into the layers of layerSplit instead of a single layer
+func (i Contents) WithLayerSplit(layerSplit ctlimg.LayerSplit) Contents {
+	i.layerSplit = layerSplit
+	return i
+}
+
 // Push the OCI Image to the registry
 func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer ImagesWriter, logger Logger) (string, error) {
 	err := i.validate()
@@ -41,7 +85,7 @@ func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer I
 		return "", err
 	}
 
-	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, logger, i.preservePermissions)
+	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, logger, i.preservePermissions).WithCompression(i.compression).WithCreated(i.created).WithFileFilter(i.fileFilter).WithFileAttributes(i.fileAttributes).WithLayerSplit(i.layerSplit)
 
 	img, err := tarImg.AsFileImage(labels)
 	if err != nil {
@@ -50,6 +94,10 @@ func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer I
 
 	defer img.Remove()
 
+	if len(i.annotations) > 0 {
+		img.Image = mutate.Annotations(img.Image, i.annotations).(regv1.Image)
+	}
+
 	err = writer.WriteImage(uploadRef, img, nil)
 
 	if err != nil {
@@ -0,0 +1,69 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package plainimage
+
+import (
+	"fmt"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// ResolveIndexChild returns the digest reference of the child manifest, of the image index referenced by ref,
+// that matches platform (format: os/arch[/variant], example: linux/arm64)
+func ResolveIndexChild(ref string, platform string, imgDescriptor ImagesDescriptor) (string, error) {
+	wantedPlatform, err := regv1.ParsePlatform(platform)
+	if err != nil {
+		return "", fmt.Errorf("Parsing platform '%s': %s", platform, err)
+	}
+
+	parsedRef, err := regname.ParseReference(ref, regname.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+
+	desc, err := imgDescriptor.Get(parsedRef)
+	if err != nil {
+		return "", fmt.Errorf("Fetching image index %s: %s", ref, err)
+	}
+	if !desc.MediaType.IsIndex() {
+		return "", fmt.Errorf("Expected %s to be an image index to select the platform %s, but found media type %s", ref, platform, desc.MediaType)
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return "", fmt.Errorf("Reading image index %s: %s", ref, err)
+	}
+	idxManifest, err := idx.IndexManifest()
+	if err != nil {
+		return "", fmt.Errorf("Reading image index %s: %s", ref, err)
+	}
+
+	var matches []regv1.Descriptor
+	var available []string
+	for _, child := range idxManifest.Manifests {
+		if child.Platform == nil {
+			continue
+		}
+		available = append(available, child.Platform.String())
+		if child.Platform.Satisfies(*wantedPlatform) {
+			matches = append(matches, child)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Unable to find platform %s in image index %s (available platforms: %s)", platform, ref, strings.Join(available, ", "))
+	case 1:
+		return parsedRef.Context().Digest(matches[0].Digest.String()).Name(), nil
+	default:
+		var matchingChildren []string
+		for _, match := range matches {
+			matchingChildren = append(matchingChildren, fmt.Sprintf("%s (%s)", match.Digest, match.Platform))
+		}
+		return "", fmt.Errorf("Expected platform %s to match a single manifest in image index %s, but found: %s (hint: be more specific, for example by including the variant)",
+			platform, ref, strings.Join(matchingChildren, ", "))
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package plainimage
+
+import (
+	"fmt"
+	"time"
+
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// PlatformContents Paths of the OCI Image of a single platform of an OCI Image Index
+type PlatformContents struct {
+	Platform regv1.Platform
+	Paths    []string
+	// Labels added to the labels of this platform image only
+	Labels map[string]string
+}
+
+// IndexWriter defines the needed functions to write an Image Index to the registry
+type IndexWriter interface {
+	ImagesWriter
+	WriteIndex(regname.Reference, regv1.ImageIndex) error
+}
+
+// MultiPlatformContents Contents of an OCI Image Index with an OCI Image per platform
+type MultiPlatformContents struct {
+	platforms           []PlatformContents
+	excludedPaths       []string
+	preservePermissions bool
+	compression         ctlimg.LayerCompression
+	created             time.Time
+	annotations         map[string]string
+	indexAnnotations    map[string]string
+	fileFilter          ctlimg.FileFilter
+	fileAttributes      ctlimg.FileAttributes
+	layerSplit          ctlimg.LayerSplit
+}
+
+// NewMultiPlatformContents creates the struct that represent an OCI Image Index based on the provided paths of each platform
+func NewMultiPlatformContents(platforms []PlatformContents, excludedPaths []string, preservePermissions bool) MultiPlatformContents {
+	return MultiPlatformContents{platforms: platforms, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
+}
+
+// WithCompression Compresses the layers of the images with compression instead of gzip
+func (m MultiPlatformContents) WithCompression(compression ctlimg.LayerCompression) MultiPlatformContents {
+	m.compression = compression
+	return m
+}
+
+// WithCreated Records created as the creation time of the images and as the modification time of its files
+func (m MultiPlatformContents) WithCreated(created time.Time) MultiPlatformContents {
+	m.created = created
+	return m
+}
+
+// WithAnnotations Adds annotations to the image index and to the manifest of each image
+func (m MultiPlatformContents) WithAnnotations(annotations map[string]string) MultiPlatformContents {
+	m.annotations = annotations
+	return m
+}
+
+// WithIndexAnnotations Adds annotations to the image index only
+func (m MultiPlatformContents) WithIndexAnnotations(annotations map[string]string) MultiPlatformContents {
+	m.indexAnnotations = annotations
+	return m
+}
+
+// WithFileFilter Adds the contents returned by fileFilter, instead of the contents on disk, for each file of the images
+func (m MultiPlatformContents) WithFileFilter(fileFilter ctlimg.FileFilter) MultiPlatformContents {
+	m.fileFilter = fileFilter
+	return m
+}
+
+// WithFileAttributes Records the owner and the permissions of fileAttributes for the files and directories of the images
+func (m MultiPlatformContents) WithFileAttributes(fileAttributes ctlimg.FileAttributes) MultiPlatformContents {
+	m.fileAttributes = fileAttributes
+	return m
+}
+
+// WithLayerSplit Splits the files of the images into the layers of layerSplit instead of a single layer
+func (m MultiPlatformContents) WithLayerSplit(layerSplit ctlimg.LayerSplit) MultiPlatformContents {
+	m.layerSplit = layerSplit
+	return m
+}
+
+// Push the OCI Image Index, and the OCI Image of each platform, to the registry.
+// Images of an OCI Image Index always use the OCI media types
+func (m MultiPlatformContents) Push(uploadRef regname.Tag, labels map[string]string, writer IndexWriter, logger Logger) (string, error) {
+	if len(m.platforms) == 0 {
+		return "", fmt.Errorf("Expected at least one platform")
+	}
+
+	seenPlatforms := map[string]bool{}
+	for _, platform := range m.platforms {
+		if seenPlatforms[platform.Platform.String()] {
+			return "", fmt.Errorf("Expected platform '%s' to be provided only once", platform.Platform.String())
+		}
+		seenPlatforms[platform.Platform.String()] = true
+
+		err := NewContents(platform.Paths, m.excludedPaths, m.preservePermissions).validate()
+		if err != nil {
+			return "", fmt.Errorf("Validating contents of platform '%s': %s", platform.Platform.String(), err)
+		}
+	}
+
+	m.compression.OCIMediaTypes = true
+
+	var idx regv1.ImageIndex = empty.Index
+	for _, platform := range m.platforms {
+		img, err := m.platformImage(platform, labels, logger)
+		if err != nil {
+			return "", fmt.Errorf("Building image of platform '%s': %s", platform.Platform.String(), err)
+		}
+		defer img.Remove()
+
+		imgPlatform := platform.Platform
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: regv1.Descriptor{Platform: &imgPlatform},
+		})
+	}
+	idx = mutate.IndexMediaType(idx, types.OCIImageIndex)
+	indexAnnotations := map[string]string{}
+	for key, value := range m.annotations {
+		indexAnnotations[key] = value
+	}
+	for key, value := range m.indexAnnotations {
+		indexAnnotations[key] = value
+	}
+	if len(indexAnnotations) > 0 {
+		idx = mutate.Annotations(idx, indexAnnotations).(regv1.ImageIndex)
+	}
+
+	err := writer.WriteIndex(uploadRef, idx)
+	if err != nil {
+		return "", fmt.Errorf("Writing '%s': %s", uploadRef.Name(), err)
+	}
+
+	digest, err := idx.Digest()
+	if err != nil {
+		return "", err
+	}
+
+	uploadTagRef, err := util.BuildDefaultUploadTagRef(idx, uploadRef.Repository)
+	if err != nil {
+		return "", fmt.Errorf("Building default upload tag image ref: %s", err)
+	}
+
+	err = writer.WriteTag(uploadTagRef, idx)
+	if err != nil {
+		return "", fmt.Errorf("Writing Tag '%s': %s", uploadRef.Name(), err)
+	}
+
+	return fmt.Sprintf("%s@%s", uploadRef.Context(), digest), nil
+}
+
+// platformImage Builds the image of the platform, whose config records the platform and the labels
+func (m MultiPlatformContents) platformImage(platform PlatformContents, labels map[string]string, logger Logger) (*ctlimg.FileImage, error) {
+	imgLabels := map[string]string{}
+	for key, value := range labels {
+		imgLabels[key] = value
+	}
+	for key, value := range platform.Labels {
+		imgLabels[key] = value
+	}
+
+	img, err := ctlimg.NewTarImage(platform.Paths, m.excludedPaths, logger, m.preservePermissions).WithCompression(m.compression).WithCreated(m.created).WithFileFilter(m.fileFilter).WithFileAttributes(m.fileAttributes).WithLayerSplit(m.layerSplit).AsFileImage(imgLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		_ = img.Remove()
+		return nil, fmt.Errorf("Fetching image config: %s", err)
+	}
+	cfg = cfg.DeepCopy()
+	cfg.OS = platform.Platform.OS
+	cfg.Architecture = platform.Platform.Architecture
+	cfg.Variant = platform.Platform.Variant
+	cfg.OSVersion = platform.Platform.OSVersion
+
+	img.Image, err = mutate.ConfigFile(img.Image, cfg)
+	if err != nil {
+		_ = img.Remove()
+		return nil, err
+	}
+	if len(m.annotations) > 0 {
+		img.Image = mutate.Annotations(img.Image, m.annotations).(regv1.Image)
+	}
+	return img, nil
+}
@@ -164,6 +164,12 @@ func (i *PlainImage) IsImage() (bool, error) {
 
 // Pull the OCI Image to disk
 func (i *PlainImage) Pull(outputPath string, logger Logger) error {
+	return i.PullWithPaths(outputPath, logger, nil, nil)
+}
+
+// PullWithPaths Pulls only the files and directories of paths of the OCI Image to disk, all of them when paths is empty.
+// The layers compressed with zstd:chunked are read partially from blobs, when it is provided
+func (i *PlainImage) PullWithPaths(outputPath string, logger Logger, paths []string, blobs ctlimg.BlobRangeReader) error {
 	img, err := i.Fetch()
 	if err != nil {
 		return err
@@ -175,7 +181,11 @@ func (i *PlainImage) Pull(outputPath string, logger Logger) error {
 
 	logger.Logf("Pulling image '%s'\n", i.DigestRef())
 
-	err = ctlimg.NewDirImage(outputPath, img, logger).AsDirectory()
+	dirImage := ctlimg.NewDirImage(outputPath, img, logger)
+	if len(paths) > 0 {
+		dirImage = dirImage.WithPaths(paths, blobs, i.parsedRef.Context())
+	}
+	err = dirImage.AsDirectory()
 	if err != nil {
 		return fmt.Errorf("Extracting image into directory: %s", err)
 	}
@@ -64,7 +64,7 @@ func (k CustomRegistryKeychain) Resolve(res regauthn.Resource) (regauthn.Authent
 	case len(k.Opts.Username) > 0:
 		return &regauthn.Basic{Username: k.Opts.Username, Password: k.Opts.Password}, nil
 	case len(k.Opts.Token) > 0:
-		return &regauthn.Bearer{Token: k.Opts.Token}, nil
+		return TokenAuthenticator(res.RegistryStr(), k.Opts.Token), nil
 	case k.Opts.Anon:
 		return regauthn.Anonymous, nil
 	default:
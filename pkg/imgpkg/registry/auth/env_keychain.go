@@ -61,6 +61,9 @@ func (k *EnvKeychain) Resolve(target regauthn.Resource) (regauthn.Authenticator,
 		}
 
 		if registryURLMatches {
+			if info.RegistryToken != "" && info.Username == "" && info.Password == "" {
+				return TokenAuthenticator(target.RegistryStr(), info.RegistryToken), nil
+			}
 			return regauthn.FromConfig(regauthn.AuthConfig{
 				Username:      info.Username,
 				Password:      info.Password,
@@ -0,0 +1,47 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"strings"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+)
+
+const (
+	// GHCRRegistry GitHub Container Registry host
+	GHCRRegistry = "ghcr.io"
+	// ECRPublicRegistry Amazon ECR Public host
+	ECRPublicRegistry = "public.ecr.aws"
+
+	// ghcrUsername GHCR ignores the username when a personal access token is used as the password
+	ghcrUsername = "imgpkg"
+)
+
+// githubTokenPrefixes prefixes of the personal access, OAuth, app and Actions tokens issued by GitHub
+var githubTokenPrefixes = []string{"ghp_", "github_pat_", "gho_", "ghu_", "ghs_", "ghr_"}
+
+// IsGHCR Returns true when registry is the GitHub Container Registry
+func IsGHCR(registry string) bool {
+	return registry == GHCRRegistry
+}
+
+// TokenAuthenticator Returns the authenticator that sends token to registry.
+// GHCR does not accept GitHub tokens as bearer tokens, they have to be exchanged for a registry token
+// using basic authentication, so they are sent as the password
+func TokenAuthenticator(registry, token string) regauthn.Authenticator {
+	if IsGHCR(registry) && isGitHubToken(token) {
+		return &regauthn.Basic{Username: ghcrUsername, Password: token}
+	}
+	return &regauthn.Bearer{Token: token}
+}
+
+func isGitHubToken(token string) bool {
+	for _, prefix := range githubTokenPrefixes {
+		if strings.HasPrefix(token, prefix) {
+			return true
+		}
+	}
+	return false
+}
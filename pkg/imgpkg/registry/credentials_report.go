@@ -0,0 +1,116 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/logs"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ScopeAccess Result of checking if the selected credentials can execute an action on a repository
+type ScopeAccess struct {
+	Scope   string
+	Granted bool
+	Reason  string
+}
+
+// CredentialsReport Describes which credentials were selected for a repository and which scopes they were granted
+type CredentialsReport struct {
+	Registry   string
+	Repository string
+	Source     CredentialSource
+	Pull       ScopeAccess
+	Push       ScopeAccess
+}
+
+// NewCredentialsReports Resolves the credentials used for each repository and checks if they can pull from and push to it.
+// The push check starts a blob upload session and cancels it right away, no content is written to the repository
+func NewCredentialsReports(opts Opts, repos []regname.Repository) ([]CredentialsReport, error) {
+	httpTran, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, fmt.Errorf("Creating registry HTTP transport: %s", err)
+	}
+
+	rTripper := withInsecureRegistries(httpTran, opts.InsecureRegistries)
+	if logs.Enabled(logs.Debug) {
+		rTripper = transport.NewLogger(rTripper)
+	}
+
+	var reports []CredentialsReport
+	for _, repo := range repos {
+		report := CredentialsReport{
+			Registry:   repo.RegistryStr(),
+			Repository: repo.RepositoryStr(),
+		}
+
+		source, resolvedAuth, err := ResolveCredentialSource(opts.keychainOpts(), opts.EnvironFunc, repo)
+		if err != nil {
+			return nil, fmt.Errorf("Resolving credentials for '%s': %s", repo.Name(), err)
+		}
+		report.Source = source
+
+		report.Pull = checkScope(repo, resolvedAuth, rTripper, transport.PullScope, http.MethodGet,
+			fmt.Sprintf("%s://%s/v2/%s/tags/list", repo.Scheme(), repo.RegistryStr(), repo.RepositoryStr()),
+			http.StatusOK, http.StatusNotFound)
+		report.Push = checkScope(repo, resolvedAuth, rTripper, transport.PushScope, http.MethodPost,
+			fmt.Sprintf("%s://%s/v2/%s/blobs/uploads/", repo.Scheme(), repo.RegistryStr(), repo.RepositoryStr()),
+			http.StatusAccepted)
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func checkScope(repo regname.Repository, resolvedAuth regauthn.Authenticator, rTripper http.RoundTripper, action, method, url string, grantedStatusCodes ...int) ScopeAccess {
+	scope := repo.Scope(action)
+	result := ScopeAccess{Scope: scope}
+
+	scopedTransport, err := transport.NewWithContext(context.Background(), repo.Registry, resolvedAuth, rTripper, []string{scope})
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	client := &http.Client{Transport: scopedTransport}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	if err := transport.CheckError(resp, grantedStatusCodes...); err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	result.Granted = true
+
+	// Cancel the upload session that was started to check the push scope
+	if location := resp.Header.Get("Location"); method == http.MethodPost && location != "" {
+		cancelURL, err := req.URL.Parse(location)
+		if err == nil {
+			cancelReq, err := http.NewRequest(http.MethodDelete, cancelURL.String(), nil)
+			if err == nil {
+				if cancelResp, err := client.Do(cancelReq); err == nil {
+					cancelResp.Body.Close()
+				}
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCredentialsReports(t *testing.T) {
+	uploadCanceled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.Header().Add("WWW-Authenticate", "Basic")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/repo/tags/list":
+			w.Write([]byte(`{"name":"repo","tags":["latest"]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/repo/blobs/uploads/":
+			if username != "writer" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Location", "/v2/repo/blobs/uploads/some-session")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v2/repo/blobs/uploads/some-session":
+			uploadCanceled = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	repo, err := name.NewRepository(fmt.Sprintf("%s/repo", u.Host))
+	require.NoError(t, err)
+
+	t.Run("when credentials are provided via environment variables, it reports env as the source", func(t *testing.T) {
+		uploadCanceled = false
+		reports, err := registry.NewCredentialsReports(registry.Opts{
+			EnvironFunc: func() []string {
+				return []string{
+					"IMGPKG_REGISTRY_HOSTNAME=" + u.Host,
+					"IMGPKG_REGISTRY_USERNAME=writer",
+					"IMGPKG_REGISTRY_PASSWORD=secret",
+				}
+			},
+		}, []name.Repository{repo})
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+
+		assert.Equal(t, u.Host, reports[0].Registry)
+		assert.Equal(t, "repo", reports[0].Repository)
+		assert.Equal(t, registry.EnvCredentialSource, reports[0].Source)
+		assert.True(t, reports[0].Pull.Granted, reports[0].Pull.Reason)
+		assert.True(t, reports[0].Push.Granted, reports[0].Push.Reason)
+		assert.True(t, uploadCanceled, "expected the upload session used to check push to be canceled")
+	})
+
+	t.Run("when credentials are provided via flags and only allow pull, it reports push as denied", func(t *testing.T) {
+		reports, err := registry.NewCredentialsReports(registry.Opts{
+			Username:    "reader",
+			Password:    "secret",
+			EnvironFunc: func() []string { return nil },
+		}, []name.Repository{repo})
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+
+		assert.Equal(t, registry.FlagsCredentialSource, reports[0].Source)
+		assert.True(t, reports[0].Pull.Granted, reports[0].Pull.Reason)
+		assert.False(t, reports[0].Push.Granted)
+		assert.Contains(t, reports[0].Push.Reason, "403")
+	})
+
+	t.Run("when anonymous access is requested, it reports anonymous as the source and denies all scopes", func(t *testing.T) {
+		reports, err := registry.NewCredentialsReports(registry.Opts{
+			Anon:        true,
+			EnvironFunc: func() []string { return nil },
+		}, []name.Repository{repo})
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+
+		assert.Equal(t, registry.AnonymousCredentialSource, reports[0].Source)
+		assert.False(t, reports[0].Pull.Granted)
+		assert.False(t, reports[0].Push.Granted)
+	})
+}
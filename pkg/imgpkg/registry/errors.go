@@ -0,0 +1,150 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry/auth"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ErrorReason Common registry failure that can be explained to the user
+type ErrorReason string
+
+const (
+	// PushDeniedReason the credentials used are not allowed to push to the repository
+	PushDeniedReason ErrorReason = "PushDenied"
+	// ManifestUnknownReason the tag or digest does not exist in the repository
+	ManifestUnknownReason ErrorReason = "ManifestUnknown"
+	// TooManyRequestsReason the registry is rate limiting the requests
+	TooManyRequestsReason ErrorReason = "TooManyRequests"
+	// BlobTooLargeReason the registry, or a proxy in front of it, refused a blob because of its size
+	BlobTooLargeReason ErrorReason = "BlobTooLarge"
+	// FIPSNonCompliantReason the registry or the artifact cannot be used in FIPS mode
+	FIPSNonCompliantReason ErrorReason = "FIPSNonCompliant"
+	// TokenScopesReason the token used does not have the scopes the registry requires for the request
+	TokenScopesReason ErrorReason = "TokenScopes"
+	// ExpiredTokenReason the registry token obtained from the cloud provider expired
+	ExpiredTokenReason ErrorReason = "ExpiredToken"
+)
+
+// Error Registry failure, with a suggestion of how to fix it, returned instead of the raw transport error
+type Error struct {
+	Reason     ErrorReason
+	Registry   string
+	Repository string
+	Message    string
+	Hint       string
+	// Err the transport error returned by the registry
+	Err error
+}
+
+// Error Message explaining what happened followed by the original error and the hint
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (hint: %s)", e.Message, e.Err, e.Hint)
+}
+
+// Unwrap Returns the transport error returned by the registry
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// translateError Replaces the transport errors that new users frequently run into with an Error
+// that names the registry and repository involved and suggests a fix. Any other error is returned as is
+func translateError(repo regname.Repository, isWrite bool, err error) error {
+	var tErr *transport.Error
+	if err == nil || !errors.As(err, &tErr) {
+		return err
+	}
+
+	registry, repository := repo.RegistryStr(), repo.RepositoryStr()
+	if tErr.Request != nil && tErr.Request.URL != nil {
+		registry = tErr.Request.URL.Host
+		if requestRepo, ok := repositoryFromPath(tErr.Request.URL.Path); ok {
+			repository = requestRepo
+		}
+	}
+	location := fmt.Sprintf("repository '%s' of registry '%s'", repository, registry)
+
+	result := &Error{Registry: registry, Repository: repository, Err: err}
+	switch {
+	case hasErrorMessage(tErr, "does not match expected scopes"):
+		result.Reason = TokenScopesReason
+		result.Message = fmt.Sprintf("The token used for %s does not have the required scopes", location)
+		result.Hint = "for GitHub Container Registry, use a token with the read:packages scope to pull and write:packages to push, " +
+			"in GitHub Actions grant 'packages: write' to the workflow and give the repository access to the package"
+
+	case hasErrorMessage(tErr, "authorization token has expired"):
+		result.Reason = ExpiredTokenReason
+		result.Message = fmt.Sprintf("The token used for %s expired", location)
+		result.Hint = fmt.Sprintf("log in again with 'aws ecr get-login-password --region <region>', or let imgpkg get a new token by removing '%s' from the docker config and setting IMGPKG_ENABLE_IAAS_AUTH=true", registry)
+		if registry == auth.ECRPublicRegistry {
+			result.Hint = fmt.Sprintf("log in again with 'aws ecr-public get-login-password --region us-east-1', ECR Public only issues tokens in us-east-1, "+
+				"or let imgpkg get a new token by removing '%s' from the docker config and setting IMGPKG_ENABLE_IAAS_AUTH=true", registry)
+		}
+
+	case isWrite && (hasErrorCode(tErr, transport.DeniedErrorCode) || tErr.StatusCode == http.StatusForbidden):
+		result.Reason = PushDeniedReason
+		result.Message = fmt.Sprintf("Pushing to %s was denied", location)
+		result.Hint = "ensure that the provided credentials have permission to push to this repository, and that the repository exists when the registry does not create it on push"
+
+	case hasErrorCode(tErr, transport.ManifestUnknownErrorCode):
+		result.Reason = ManifestUnknownReason
+		result.Message = fmt.Sprintf("Unable to find the requested tag or digest in %s", location)
+		result.Hint = fmt.Sprintf("check the spelling of the reference, and use 'imgpkg tag list -i %s/%s' to see the available tags", registry, repository)
+
+	case hasErrorCode(tErr, transport.TooManyRequestsErrorCode) || tErr.StatusCode == http.StatusTooManyRequests:
+		result.Reason = TooManyRequestsReason
+		result.Message = fmt.Sprintf("Registry '%s' is rate limiting the requests to repository '%s'", registry, repository)
+		result.Hint = "wait before retrying, authenticate to the registry to get a higher limit, or reduce the number of parallel requests with --concurrency"
+
+	case isWrite && tErr.StatusCode == http.StatusRequestEntityTooLarge:
+		result.Reason = BlobTooLargeReason
+		result.Message = fmt.Sprintf("Registry '%s' refused a blob pushed to repository '%s' because it is too large", registry, repository)
+		result.Hint = "increase the maximum upload size of the registry, or of the proxy in front of it (for example client_max_body_size in nginx)"
+
+	default:
+		return err
+	}
+
+	return result
+}
+
+func hasErrorMessage(tErr *transport.Error, message string) bool {
+	for _, diagnostic := range tErr.Errors {
+		if strings.Contains(diagnostic.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasErrorCode(tErr *transport.Error, code transport.ErrorCode) bool {
+	for _, diagnostic := range tErr.Errors {
+		if diagnostic.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// repositoryFromPath Extracts the repository from registry API paths like /v2/<repository>/manifests/<reference>
+func repositoryFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/v2/") {
+		return "", false
+	}
+	path = strings.TrimPrefix(path, "/v2/")
+
+	for _, endpoint := range []string{"/manifests/", "/blobs/", "/tags/"} {
+		if idx := strings.LastIndex(path, endpoint); idx > 0 {
+			return path[:idx], true
+		}
+	}
+	return "", false
+}
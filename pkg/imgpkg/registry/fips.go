@@ -0,0 +1,141 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// fipsApprovedDigestAlgorithms Algorithms that can be used to verify the digest of manifests and blobs in FIPS mode
+var fipsApprovedDigestAlgorithms = map[string]bool{
+	"sha256": true,
+	"sha384": true,
+	"sha512": true,
+}
+
+// fipsApprovedCipherSuites Cipher suites of TLS 1.2 and TLS 1.3 that can be negotiated with the registries in FIPS mode
+var fipsApprovedCipherSuites = map[uint16]bool{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: true,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: true,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   true,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   true,
+	tls.TLS_AES_128_GCM_SHA256:                  true,
+	tls.TLS_AES_256_GCM_SHA384:                  true,
+}
+
+// FIPSEnabled Returns true when the FIPS mode is requested in the options or enforced by the build
+func (o Opts) FIPSEnabled() bool {
+	return o.FIPS || fipsBuild
+}
+
+// configureFIPSTLS Restricts the TLS configuration to FIPS-approved versions, cipher suites and curves
+func configureFIPSTLS(config *tls.Config) {
+	config.MinVersion = tls.VersionTLS12
+	// Only applies to TLS 1.2, the cipher suites of TLS 1.3 cannot be configured and are checked by fipsRoundTripper
+	config.CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+	config.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+}
+
+// fipsRoundTripper Reports the registries and artifacts that cannot be used in FIPS mode
+type fipsRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+// RoundTrip Refuses plain HTTP connections, TLS connections and digests that do not use FIPS-approved algorithms
+func (f *fipsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Traffic to the loopback interface does not leave the machine, so local registries can still be used over HTTP
+	if req.URL.Scheme == "http" && !isLoopbackHost(req.URL.Hostname()) {
+		return nil, newFIPSError(req, fmt.Sprintf("Registry '%s' was accessed over plain HTTP", req.URL.Host),
+			fmt.Errorf("Request to %s is not encrypted", req.URL.Redacted()),
+			"access the registry using HTTPS, do not use --registry-insecure in FIPS mode")
+	}
+
+	// Digests are present in the path when fetching, and in the query when finishing the upload of a blob
+	requestDigest := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if uploadDigest := req.URL.Query().Get("digest"); uploadDigest != "" {
+		requestDigest = uploadDigest
+	}
+	if algorithm, ok := digestAlgorithm(requestDigest); ok && !fipsApprovedDigestAlgorithms[algorithm] {
+		return nil, newFIPSError(req, fmt.Sprintf("Artifact '%s' uses a digest algorithm that is not FIPS-approved", requestDigest),
+			fmt.Errorf("Unsupported digest algorithm '%s'", algorithm),
+			"only artifacts with sha256, sha384 or sha512 digests can be used in FIPS mode")
+	}
+
+	resp, err := f.delegate.RoundTrip(req)
+	if err != nil {
+		if req.URL.Scheme == "https" && isTLSHandshakeError(err) {
+			return nil, newFIPSError(req, fmt.Sprintf("Registry '%s' did not complete a TLS handshake using FIPS-approved algorithms", req.URL.Host),
+				err, "configure the registry to support TLS 1.3, or TLS 1.2 with ECDHE and AES-GCM cipher suites")
+		}
+		return nil, err
+	}
+
+	// TLS 1.3 connections can negotiate CHACHA20-POLY1305, that cannot be excluded in the TLS configuration
+	if resp.TLS != nil && !fipsApprovedCipherSuites[resp.TLS.CipherSuite] {
+		resp.Body.Close()
+		return nil, newFIPSError(req, fmt.Sprintf("Registry '%s' did not complete a TLS handshake using FIPS-approved algorithms", req.URL.Host),
+			fmt.Errorf("Negotiated cipher suite %s is not FIPS-approved", tls.CipherSuiteName(resp.TLS.CipherSuite)),
+			"configure the registry to support TLS 1.3 with AES-GCM cipher suites, or TLS 1.2 with ECDHE and AES-GCM cipher suites")
+	}
+
+	responseDigest := resp.Header.Get("Docker-Content-Digest")
+	if algorithm, ok := digestAlgorithm(responseDigest); ok && !fipsApprovedDigestAlgorithms[algorithm] {
+		resp.Body.Close()
+		return nil, newFIPSError(req, fmt.Sprintf("Artifact '%s' uses a digest algorithm that is not FIPS-approved", responseDigest),
+			fmt.Errorf("Unsupported digest algorithm '%s' returned by the registry", algorithm),
+			"only artifacts with sha256, sha384 or sha512 digests can be used in FIPS mode")
+	}
+
+	return resp, nil
+}
+
+// isTLSHandshakeError Returns true when the TLS handshake was refused with a TLS alert, like the one
+// the registry sends when it does not support any of the versions and cipher suites offered
+func isTLSHandshakeError(err error) bool {
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "remote error"
+}
+
+func newFIPSError(req *http.Request, message string, err error, hint string) *Error {
+	repository, _ := repositoryFromPath(req.URL.Path)
+	return &Error{
+		Reason:     FIPSNonCompliantReason,
+		Registry:   req.URL.Host,
+		Repository: repository,
+		Message:    message,
+		Hint:       hint,
+		Err:        err,
+	}
+}
+
+// digestAlgorithm Returns the algorithm of digests like sha256:<hex>
+func digestAlgorithm(value string) (string, bool) {
+	algorithm, hex, found := strings.Cut(value, ":")
+	if !found || algorithm == "" || hex == "" || strings.Trim(hex, "0123456789abcdef") != "" {
+		return "", false
+	}
+	return algorithm, true
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
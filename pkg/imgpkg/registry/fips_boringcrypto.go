@@ -0,0 +1,12 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build boringcrypto
+
+package registry
+
+// Restricts crypto/tls to FIPS-approved settings in the whole binary
+import _ "crypto/tls/fipsonly"
+
+// fipsBuild imgpkg was built with GOEXPERIMENT=boringcrypto, FIPS mode is always enabled
+const fipsBuild = true
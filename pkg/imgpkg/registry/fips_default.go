@@ -0,0 +1,9 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !boringcrypto
+
+package registry
+
+// fipsBuild FIPS mode is only enabled when requested in the options
+const fipsBuild = false
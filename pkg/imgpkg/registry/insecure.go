@@ -0,0 +1,68 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"net"
+	"net/http"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// IsInsecure Returns true when the registry, with the format host[:port], is accessed over plain HTTP,
+// either because all the registries are (Insecure) or because it is one of the InsecureRegistries
+func (o Opts) IsInsecure(registryName string) bool {
+	return o.Insecure || isInsecureRegistry(o.InsecureRegistries, registryName)
+}
+
+// isInsecureRegistry Returns true when registryName, with the format host[:port], is one of insecureRegistries.
+// The registries provided without a port match every port of their host
+func isInsecureRegistry(insecureRegistries []string, registryName string) bool {
+	host, _, err := net.SplitHostPort(registryName)
+	if err != nil {
+		host = registryName
+	}
+	for _, insecureRegistry := range insecureRegistries {
+		if insecureRegistry == registryName || insecureRegistry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// refOptsFor Returns the options used to parse the references of reg, which can be accessed over plain HTTP
+// when it is one of the insecure registries
+func (r *SimpleRegistry) refOptsFor(reg regname.Registry) []regname.Option {
+	if !isInsecureRegistry(r.insecureRegistries, reg.RegistryStr()) {
+		return r.refOpts
+	}
+	return append([]regname.Option{regname.Insecure}, r.refOpts...)
+}
+
+// insecureRegistriesRoundTripper Sends the requests to the insecure registries with a transport that does not verify
+// their certificates, the requests to every other registry are still verified
+type insecureRegistriesRoundTripper struct {
+	secure             http.RoundTripper
+	insecure           http.RoundTripper
+	insecureRegistries []string
+}
+
+// withInsecureRegistries Returns a RoundTripper that uses tran for every registry but the insecure ones,
+// for which it uses a copy of tran that skips the verification of the certificates
+func withInsecureRegistries(tran *http.Transport, insecureRegistries []string) http.RoundTripper {
+	if len(insecureRegistries) == 0 {
+		return tran
+	}
+	insecureTran := tran.Clone()
+	insecureTran.TLSClientConfig.InsecureSkipVerify = true
+	return &insecureRegistriesRoundTripper{secure: tran, insecure: insecureTran, insecureRegistries: insecureRegistries}
+}
+
+// RoundTrip Sends the request with the transport of its registry
+func (r *insecureRegistriesRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isInsecureRegistry(r.insecureRegistries, req.URL.Host) {
+		return r.insecure.RoundTrip(req)
+	}
+	return r.secure.RoundTrip(req)
+}
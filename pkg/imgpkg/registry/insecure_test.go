@@ -0,0 +1,92 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_InsecureRegistries(t *testing.T) {
+	// Both registries use a self-signed certificate
+	labServer := httptest.NewTLSServer(ggcrregistry.New())
+	defer labServer.Close()
+	otherServer := httptest.NewTLSServer(ggcrregistry.New())
+	defer otherServer.Close()
+
+	unverifiedTransport := http.DefaultTransport.(*http.Transport).Clone()
+	unverifiedTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+	img, err := random.Image(500, 1)
+	require.NoError(t, err)
+	var labRef, otherRef name.Reference
+	for _, server := range []*httptest.Server{labServer, otherServer} {
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		ref, err := name.NewTag(fmt.Sprintf("%s/repo/app:1.0.0", serverURL.Host))
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img, remote.WithTransport(unverifiedTransport)))
+		if server == labServer {
+			labRef = ref
+		} else {
+			otherRef = ref
+		}
+	}
+	expectedDigest, err := img.Digest()
+	require.NoError(t, err)
+
+	t.Run("when the registry is in the insecure registries, it does not verify its certificate", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{VerifyCerts: true, InsecureRegistries: []string{labRef.Context().RegistryStr()}})
+		require.NoError(t, err)
+
+		digest, err := subject.Digest(labRef)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+	})
+
+	t.Run("when the registry is not in the insecure registries, it still verifies its certificate", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{VerifyCerts: true, InsecureRegistries: []string{labRef.Context().RegistryStr()}})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(otherRef)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate")
+	})
+
+	t.Run("when the insecure registry does not have a port, it matches every port of the host", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{VerifyCerts: true, InsecureRegistries: []string{"127.0.0.1"}})
+		require.NoError(t, err)
+
+		for _, ref := range []name.Reference{labRef, otherRef} {
+			digest, err := subject.Digest(ref)
+			require.NoError(t, err)
+			assert.Equal(t, expectedDigest, digest)
+		}
+	})
+}
+
+func TestOpts_IsInsecure(t *testing.T) {
+	opts := registry.Opts{InsecureRegistries: []string{"lab.registry.io:5000", "localhost"}}
+
+	assert.True(t, opts.IsInsecure("lab.registry.io:5000"))
+	assert.False(t, opts.IsInsecure("lab.registry.io"))
+	assert.False(t, opts.IsInsecure("lab.registry.io:443"))
+	assert.True(t, opts.IsInsecure("localhost"))
+	assert.True(t, opts.IsInsecure("localhost:5000"))
+	assert.False(t, opts.IsInsecure("index.docker.io"))
+
+	assert.True(t, registry.Opts{Insecure: true}.IsInsecure("index.docker.io"))
+}
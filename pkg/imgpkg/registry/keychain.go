@@ -15,21 +15,79 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/google"
 )
 
+// CredentialSource names the keychain that provided the credentials used to talk with a registry
+type CredentialSource string
+
+const (
+	// EnvCredentialSource credentials provided via IMGPKG_REGISTRY_* environment variables
+	EnvCredentialSource CredentialSource = "env"
+	// FlagsCredentialSource credentials provided via --registry-username/--registry-password/--registry-token
+	FlagsCredentialSource CredentialSource = "flags"
+	// DockerConfigCredentialSource credentials provided by the docker config file or a docker credential helper
+	DockerConfigCredentialSource CredentialSource = "docker-config"
+	// AnonymousCredentialSource no keychain provided credentials
+	AnonymousCredentialSource CredentialSource = "anonymous"
+)
+
+// IaasCredentialSource credentials provided by an IaaS keychain (gke, ecr, aks, github)
+func IaasCredentialSource(keychain auth.IAASKeychain) CredentialSource {
+	return CredentialSource("iaas:" + string(keychain))
+}
+
+type sourcedKeychain struct {
+	source   CredentialSource
+	keychain regauthn.Keychain
+}
+
 // Keychain implements an authn.Keychain interface by composing multiple keychains.
 // It enforces an order, where the keychains that contain credentials for a specific target take precedence over
 // keychains that contain credentials for 'any' target. i.e. env keychain takes precedence over the custom keychain.
 // Since env keychain contains credentials per HOSTNAME, and custom keychain doesn't.
 func Keychain(keychainOpts auth.KeychainOpts, environFunc func() []string) (regauthn.Keychain, error) {
+	sourced, err := keychains(keychainOpts, environFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	var keychain []regauthn.Keychain
+	for _, k := range sourced {
+		keychain = append(keychain, k.keychain)
+	}
+	return regauthn.NewMultiKeychain(keychain...), nil
+}
+
+// ResolveCredentialSource resolves the credentials for target in the same order as Keychain does
+// and reports which keychain provided them
+func ResolveCredentialSource(keychainOpts auth.KeychainOpts, environFunc func() []string, target regauthn.Resource) (CredentialSource, regauthn.Authenticator, error) {
+	sourced, err := keychains(keychainOpts, environFunc)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, k := range sourced {
+		resolvedAuth, err := k.keychain.Resolve(target)
+		if err != nil {
+			return k.source, nil, err
+		}
+		if resolvedAuth != regauthn.Anonymous {
+			return k.source, resolvedAuth, nil
+		}
+	}
+
+	return AnonymousCredentialSource, regauthn.Anonymous, nil
+}
+
+func keychains(keychainOpts auth.KeychainOpts, environFunc func() []string) ([]sourcedKeychain, error) {
 	// env keychain comes first
-	keychain := []regauthn.Keychain{auth.NewEnvKeychain(environFunc)}
+	keychain := []sourcedKeychain{{EnvCredentialSource, auth.NewEnvKeychain(environFunc)}}
 
 	if keychainOpts.EnableIaasAuthProviders {
 		// if enabled, fall back to iaas keychains
 		keychain = append(keychain,
-			google.Keychain,
-			regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard))),
-			regauthn.NewKeychainFromHelper(credhelper.NewACRCredentialsHelper()),
-			github.Keychain,
+			sourcedKeychain{IaasCredentialSource(auth.GKEKeychain), google.Keychain},
+			sourcedKeychain{IaasCredentialSource(auth.ECRKeychain), regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard)))},
+			sourcedKeychain{IaasCredentialSource(auth.AKSKeychain), regauthn.NewKeychainFromHelper(credhelper.NewACRCredentialsHelper())},
+			sourcedKeychain{IaasCredentialSource(auth.GithubKeychain), github.Keychain},
 		)
 	} else {
 		for _, activeKeychain := range keychainOpts.ActiveKeychains {
@@ -46,12 +104,19 @@ func Keychain(keychainOpts auth.KeychainOpts, environFunc func() []string) (rega
 			default:
 				return nil, fmt.Errorf("Unable to load keychain for %s, available keychains [aks, ecr, gke, github]]", string(activeKeychain))
 			}
-			keychain = append(keychain, k)
+			keychain = append(keychain, sourcedKeychain{IaasCredentialSource(activeKeychain), k})
 		}
 	}
 
 	// command-line flags and docker keychain comes last
-	keychain = append(keychain, auth.CustomRegistryKeychain{Opts: keychainOpts})
+	customSource := DockerConfigCredentialSource
+	switch {
+	case len(keychainOpts.Username) > 0 || len(keychainOpts.Token) > 0:
+		customSource = FlagsCredentialSource
+	case keychainOpts.Anon:
+		customSource = AnonymousCredentialSource
+	}
+	keychain = append(keychain, sourcedKeychain{customSource, auth.CustomRegistryKeychain{Opts: keychainOpts}})
 
-	return regauthn.NewMultiKeychain(keychain...), nil
+	return keychain, nil
 }
@@ -0,0 +1,74 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry/auth"
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeychain_HostedRegistries(t *testing.T) {
+	noEnv := func() []string { return nil }
+	isolateHome := func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		t.Setenv("DOCKER_CONFIG", t.TempDir())
+		t.Setenv("GITHUB_TOKEN", "")
+	}
+	resolve := func(t *testing.T, keychain regauthn.Keychain, repo string) *regauthn.AuthConfig {
+		ref, err := name.ParseReference(repo)
+		require.NoError(t, err)
+		authenticator, err := keychain.Resolve(ref.Context())
+		require.NoError(t, err)
+		authConfig, err := authenticator.Authorization()
+		require.NoError(t, err)
+		return authConfig
+	}
+
+	t.Run("when a GitHub token is provided for GHCR, it is sent as the password to be exchanged for a registry token", func(t *testing.T) {
+		isolateHome(t)
+		keychain, err := registry.Keychain(auth.KeychainOpts{Token: "ghp_sometoken"}, noEnv)
+		require.NoError(t, err)
+
+		authConfig := resolve(t, keychain, "ghcr.io/org/repo:latest")
+		assert.Equal(t, "ghp_sometoken", authConfig.Password)
+		assert.NotEmpty(t, authConfig.Username)
+		assert.Empty(t, authConfig.RegistryToken)
+
+		authConfig = resolve(t, keychain, "registry.example.com/org/repo:latest")
+		assert.Equal(t, "ghp_sometoken", authConfig.RegistryToken)
+		assert.Empty(t, authConfig.Password)
+	})
+
+	t.Run("when no other credentials are found for GHCR, it only uses GITHUB_TOKEN when the IaaS keychains are enabled", func(t *testing.T) {
+		isolateHome(t)
+		t.Setenv("GITHUB_TOKEN", "ghs_actionstoken")
+
+		source, authenticator, err := registry.ResolveCredentialSource(auth.KeychainOpts{}, noEnv, name.MustParseReference("ghcr.io/org/repo").Context())
+		require.NoError(t, err)
+		assert.Equal(t, registry.AnonymousCredentialSource, source)
+		assert.Equal(t, regauthn.Anonymous, authenticator)
+
+		source, authenticator, err = registry.ResolveCredentialSource(auth.KeychainOpts{EnableIaasAuthProviders: true}, noEnv, name.MustParseReference("ghcr.io/org/repo").Context())
+		require.NoError(t, err)
+		assert.Equal(t, registry.IaasCredentialSource(auth.GithubKeychain), source)
+		authConfig, err := authenticator.Authorization()
+		require.NoError(t, err)
+		assert.Equal(t, "ghs_actionstoken", authConfig.Password)
+	})
+
+	t.Run("when there are no AWS credentials, it pulls anonymously from ECR Public", func(t *testing.T) {
+		isolateHome(t)
+
+		source, authenticator, err := registry.ResolveCredentialSource(auth.KeychainOpts{}, noEnv, name.MustParseReference("public.ecr.aws/org/repo").Context())
+		require.NoError(t, err)
+		assert.Equal(t, registry.AnonymousCredentialSource, source)
+		assert.Equal(t, regauthn.Anonymous, authenticator)
+	})
+}
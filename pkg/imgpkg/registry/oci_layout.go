@@ -0,0 +1,338 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// OCILayoutPrefix Prefix used in references that point to a local OCI image layout (example: oci:./layout)
+	OCILayoutPrefix = "oci:"
+	// OCILayoutImageNameAnnotation Annotation of the manifests in the index of an OCI image layout with the reference of the image,
+	// also written by containerd and docker
+	OCILayoutImageNameAnnotation = "io.containerd.image.name"
+	// OCILayoutRefNameAnnotation Annotation of the manifests in the index of an OCI image layout with the tag of the image,
+	// some tools record the reference of the image instead
+	OCILayoutRefNameAnnotation = "org.opencontainers.image.ref.name"
+	// ociLayoutRootBundleAnnotation Annotation added by imgpkg copy to the bundle that was copied to the layout
+	ociLayoutRootBundleAnnotation = "dev.carvel.imgpkg.copy.root-bundle"
+	// ociLayoutRepository Repository used to address the images of a layout whose manifests do not have a reference.
+	// It uses the reserved .invalid TLD, so it is never resolved to a real registry
+	ociLayoutRepository = "oci-layout.imgpkg.invalid/layout"
+)
+
+var (
+	ociLayoutManifestPath = regexp.MustCompile(`\A/v2/(.+)/manifests/([^/]+)\z`)
+	ociLayoutBlobPath     = regexp.MustCompile(`\A/v2/(.+)/blobs/([^/]+)\z`)
+)
+
+// IsOCILayoutRef Checks if the reference points to a local OCI image layout
+func IsOCILayoutRef(ref string) bool {
+	return strings.HasPrefix(ref, OCILayoutPrefix)
+}
+
+// OCILayoutReference Resolves a reference in the format oci:<path>[@<digest>] to the reference of the image, named after the
+// annotations of its manifest in the layout's index.json, and returns the layout. The image can be read with NewOCILayoutRegistry.
+// When no digest is provided the layout's index.json must contain a single manifest, or a single bundle copied by imgpkg
+func OCILayoutReference(ref string) (string, layout.Path, error) {
+	if !IsOCILayoutRef(ref) {
+		return "", "", fmt.Errorf("Expected reference '%s' to start with '%s'", ref, OCILayoutPrefix)
+	}
+
+	layoutDir := strings.TrimPrefix(ref, OCILayoutPrefix)
+	digest := ""
+	if idx := strings.LastIndex(layoutDir, "@"); idx >= 0 {
+		digest = layoutDir[idx+1:]
+		layoutDir = layoutDir[:idx]
+	}
+	if layoutDir == "" {
+		return "", "", fmt.Errorf("Expected reference '%s' to include the path to the OCI image layout", ref)
+	}
+
+	layoutPath, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return "", "", fmt.Errorf("Reading OCI image layout index: %s", err)
+	}
+	index, err := ociLayoutIndex(layoutPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	if digest == "" {
+		digest = rootBundleDigest(index)
+	}
+	if digest == "" {
+		if len(index.Manifests) != 1 {
+			var digests []string
+			for _, manifest := range index.Manifests {
+				digests = append(digests, manifest.Digest.String())
+			}
+			return "", "", fmt.Errorf("Expected OCI image layout '%s' to contain a single manifest but found %d, select one using oci:<path>@<digest> (found: %s)",
+				layoutDir, len(index.Manifests), strings.Join(digests, ", "))
+		}
+		digest = index.Manifests[0].Digest.String()
+	}
+
+	hash, err := regv1.NewHash(digest)
+	if err != nil {
+		return "", "", fmt.Errorf("Parsing digest '%s': %s", digest, err)
+	}
+	var desc *regv1.Descriptor
+	for i := range index.Manifests {
+		if index.Manifests[i].Digest == hash {
+			desc = &index.Manifests[i]
+		}
+	}
+	if desc == nil {
+		return "", "", fmt.Errorf("Unable to find '%s' in the index of OCI image layout '%s'", digest, layoutDir)
+	}
+
+	repository, found := ociLayoutImageRepository(*desc)
+	if !found {
+		return "", "", fmt.Errorf("Expected manifest '%s' of OCI image layout '%s' to have the reference of the image in the annotation '%s' or '%s'",
+			digest, layoutDir, OCILayoutImageNameAnnotation, OCILayoutRefNameAnnotation)
+	}
+	return repository.Digest(hash.String()).Name(), layoutPath, nil
+}
+
+// OCILayoutManifest Manifest listed in the index.json of an OCI image layout
+type OCILayoutManifest struct {
+	// DigestRef reference that retrieves the manifest from the layout with NewOCILayoutRegistry
+	DigestRef   string
+	Annotations map[string]string
+}
+
+// OCILayoutManifests Lists the images and indexes present in the index.json of the OCI image layout. Each one is named after
+// the annotations of its manifest, the ones without a reference, written by other tools, are named after ociLayoutRepository
+func OCILayoutManifests(layoutPath layout.Path) ([]OCILayoutManifest, error) {
+	index, err := ociLayoutIndex(layoutPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []OCILayoutManifest
+	for _, desc := range index.Manifests {
+		if !desc.MediaType.IsImage() && !desc.MediaType.IsIndex() {
+			continue
+		}
+		repository, found := ociLayoutImageRepository(desc)
+		if !found {
+			repository, err = regname.NewRepository(ociLayoutRepository)
+			if err != nil {
+				return nil, fmt.Errorf("Internal inconsistency: building reference for OCI image layout: %s", err)
+			}
+		}
+		manifests = append(manifests, OCILayoutManifest{DigestRef: repository.Digest(desc.Digest.String()).Name(), Annotations: desc.Annotations})
+	}
+	return manifests, nil
+}
+
+// ociLayoutImageRepository Returns the repository of the image, from the annotations of its manifest in the layout index
+func ociLayoutImageRepository(desc regv1.Descriptor) (regname.Repository, bool) {
+	for _, annotation := range []string{OCILayoutImageNameAnnotation, OCILayoutRefNameAnnotation} {
+		value := desc.Annotations[annotation]
+		// Tags are also valid references, of images in Docker Hub
+		if !strings.Contains(value, "/") {
+			continue
+		}
+		ref, err := regname.ParseReference(value, regname.WeakValidation)
+		if err == nil {
+			return ref.Context(), true
+		}
+	}
+	return regname.Repository{}, false
+}
+
+// rootBundleDigest Returns the digest of the bundle copied to the layout by imgpkg, when there is only one
+func rootBundleDigest(index *regv1.IndexManifest) string {
+	var digests []string
+	for _, manifest := range index.Manifests {
+		if _, ok := manifest.Annotations[ociLayoutRootBundleAnnotation]; ok {
+			digests = append(digests, manifest.Digest.String())
+		}
+	}
+	if len(digests) != 1 {
+		return ""
+	}
+	return digests[0]
+}
+
+func ociLayoutIndex(layoutPath layout.Path) (*regv1.IndexManifest, error) {
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("Reading OCI image layout index: %s", err)
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("Parsing OCI image layout index '%s': %s", filepath.Join(string(layoutPath), "index.json"), err)
+	}
+	return indexManifest, nil
+}
+
+// ociLayoutTransport Serves a read only version of the registry API with the manifests and blobs of an OCI image layout.
+// The manifests and blobs are served by digest for every repository, so that the images in the layout are read with their own references
+type ociLayoutTransport struct {
+	layoutPath layout.Path
+}
+
+// RoundTrip Answers the request with the content of the layout
+func (o ociLayoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return o.errorResponse(req, http.StatusMethodNotAllowed, "UNSUPPORTED", "OCI image layouts are read only"), nil
+	}
+
+	if req.URL.Path == "/v2/" || req.URL.Path == "/v2" {
+		return o.response(req, http.StatusOK, "application/json", []byte("{}"), ""), nil
+	}
+
+	if matches := ociLayoutManifestPath.FindStringSubmatch(req.URL.Path); matches != nil {
+		hash, err := regv1.NewHash(matches[2])
+		if err != nil {
+			// Tags are not supported, only manifests that can be addressed by digest
+			return o.errorResponse(req, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown"), nil
+		}
+		content, mediaType, err := o.manifest(hash)
+		if err != nil {
+			return o.errorResponse(req, http.StatusNotFound, "MANIFEST_UNKNOWN", err.Error()), nil
+		}
+		return o.response(req, http.StatusOK, string(mediaType), content, hash.String()), nil
+	}
+
+	if matches := ociLayoutBlobPath.FindStringSubmatch(req.URL.Path); matches != nil {
+		hash, err := regv1.NewHash(matches[2])
+		if err != nil {
+			return o.errorResponse(req, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown"), nil
+		}
+		// Blobs are streamed from the file, layers can be too big to be read into memory
+		blob, err := o.layoutPath.Blob(hash)
+		if err != nil {
+			return o.errorResponse(req, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown"), nil
+		}
+		size, err := blobSize(blob)
+		if err != nil {
+			blob.Close()
+			return o.errorResponse(req, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown"), nil
+		}
+		return o.bodyResponse(req, http.StatusOK, "application/octet-stream", blob, size, hash.String()), nil
+	}
+
+	return o.errorResponse(req, http.StatusNotFound, "UNSUPPORTED", "not supported by OCI image layouts"), nil
+}
+
+// blobSize Returns the size of the file of the blob
+func blobSize(blob io.ReadCloser) (int64, error) {
+	file, ok := blob.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return 0, fmt.Errorf("Unknown size")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// manifest Retrieves the manifest and its media type. The media type is read from the descriptors in
+// the layout indexes and when not present there from the manifest itself
+func (o ociLayoutTransport) manifest(hash regv1.Hash) ([]byte, types.MediaType, error) {
+	content, err := o.layoutPath.Bytes(hash)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to find manifest '%s' in OCI image layout", hash)
+	}
+
+	if index, err := o.layoutPath.ImageIndex(); err == nil {
+		if mediaType, found := findMediaType(index, hash, map[regv1.Hash]struct{}{}); found {
+			return content, mediaType, nil
+		}
+	}
+
+	manifest := struct {
+		MediaType types.MediaType `json:"mediaType"`
+	}{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, "", fmt.Errorf("Parsing manifest '%s': %s", hash, err)
+	}
+	if manifest.MediaType == "" {
+		return content, types.OCIManifestSchema1, nil
+	}
+	return content, manifest.MediaType, nil
+}
+
+func findMediaType(index regv1.ImageIndex, hash regv1.Hash, visited map[regv1.Hash]struct{}) (types.MediaType, bool) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return "", false
+	}
+	for _, desc := range indexManifest.Manifests {
+		if desc.Digest == hash {
+			return desc.MediaType, true
+		}
+		if !desc.MediaType.IsIndex() {
+			continue
+		}
+		if _, ok := visited[desc.Digest]; ok {
+			continue
+		}
+		visited[desc.Digest] = struct{}{}
+
+		nestedIndex, err := index.ImageIndex(desc.Digest)
+		if err != nil {
+			continue
+		}
+		if mediaType, found := findMediaType(nestedIndex, hash, visited); found {
+			return mediaType, true
+		}
+	}
+	return "", false
+}
+
+func (o ociLayoutTransport) response(req *http.Request, statusCode int, contentType string, content []byte, digest string) *http.Response {
+	return o.bodyResponse(req, statusCode, contentType, io.NopCloser(bytes.NewReader(content)), int64(len(content)), digest)
+}
+
+func (o ociLayoutTransport) bodyResponse(req *http.Request, statusCode int, contentType string, body io.ReadCloser, size int64, digest string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if digest != "" {
+		header.Set("Docker-Content-Digest", digest)
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		ContentLength: size,
+		Request:       req,
+		Body:          body,
+	}
+	if req.Method == http.MethodHead {
+		body.Close()
+		resp.Body = http.NoBody
+	}
+	return resp
+}
+
+func (o ociLayoutTransport) errorResponse(req *http.Request, statusCode int, code, message string) *http.Response {
+	content, _ := json.Marshal(map[string][]map[string]string{
+		"errors": {{"code": code, "message": message}},
+	})
+	return o.response(req, statusCode, "application/json", content, "")
+}
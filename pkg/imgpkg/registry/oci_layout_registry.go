@@ -0,0 +1,376 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// OCILayoutRegistry Registry that reads the images stored in an OCI image layout from the layout, using their own references,
+// while the other images are read and written through a delegate.
+// When created with NewTemporaryOCILayoutRegistry, the images of a single repository are written to a layout in a temporary
+// folder without reaching the registry of the repository. It allows building images, and exporting them with the images they
+// reference, without access to their registry. The layers are streamed to the folder, so the images do not need to fit in memory
+type OCILayoutRegistry struct {
+	layoutPath layout.Path
+	// local reads the images of the layout, see ociLayoutTransport
+	local    *SimpleRegistry
+	delegate Registry
+	// localRepositories repositories named by the manifests of the layout index, only their images are read from the layout
+	localRepositories map[string]struct{}
+
+	// repository when provided, its images are written to the layout
+	repository *regname.Repository
+	temporary  bool
+	tagsLock   *sync.Mutex
+	tags       map[string]regv1.Hash
+}
+
+var _ Registry = &OCILayoutRegistry{}
+
+// NewOCILayoutRegistry Creates a Registry that reads the images in the OCI image layout in layoutPath, and accesses the other
+// images through delegate. Only the references to the repositories named by the manifests of the layout index, see
+// OCILayoutManifests, are read from the layout. Works with the path returned by OCILayoutReference
+func NewOCILayoutRegistry(layoutPath layout.Path, delegate Registry) (*OCILayoutRegistry, error) {
+	local, err := NewSimpleRegistryWithTransport(Opts{Anon: true}, ociLayoutTransport{layoutPath: layoutPath})
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := OCILayoutManifests(layoutPath)
+	if err != nil {
+		return nil, err
+	}
+	localRepositories := map[string]struct{}{}
+	for _, manifest := range manifests {
+		ref, err := regname.NewDigest(manifest.DigestRef)
+		if err != nil {
+			return nil, fmt.Errorf("Internal inconsistency: parsing reference '%s': %s", manifest.DigestRef, err)
+		}
+		localRepositories[ref.Context().Name()] = struct{}{}
+	}
+
+	return &OCILayoutRegistry{layoutPath: layoutPath, local: local, delegate: delegate, localRepositories: localRepositories,
+		tagsLock: &sync.Mutex{}, tags: map[string]regv1.Hash{}}, nil
+}
+
+// NewTemporaryOCILayoutRegistry Creates a Registry that writes the images of repository to an OCI image layout in a temporary
+// folder and accesses the other repositories through delegate. Cleanup removes the folder
+func NewTemporaryOCILayoutRegistry(repository regname.Repository, delegate Registry) (*OCILayoutRegistry, error) {
+	dir, err := os.MkdirTemp("", "imgpkg-local-repository")
+	if err != nil {
+		return nil, fmt.Errorf("Creating local repository folder: %s", err)
+	}
+
+	layoutPath, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("Creating local repository: %s", err)
+	}
+	reg, err := NewOCILayoutRegistry(layoutPath, delegate)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	reg.repository = &repository
+	reg.temporary = true
+	return reg, nil
+}
+
+// Cleanup Removes the temporary layout with the images written, layouts provided by the user are kept
+func (r *OCILayoutRegistry) Cleanup() error {
+	if !r.temporary {
+		return nil
+	}
+	return os.RemoveAll(string(r.layoutPath))
+}
+
+// isWritable Returns true when the images of the repository are written to the layout
+func (r *OCILayoutRegistry) isWritable(repository regname.Repository) bool {
+	return r.repository != nil && repository.Name() == r.repository.Name()
+}
+
+// isLocal Returns true when the image or blob of the reference is read from the layout, only the digest references to
+// a repository of the layout are, the same digest in any other repository, like the destination of a copy, is read from its registry
+func (r *OCILayoutRegistry) isLocal(ref regname.Reference) bool {
+	if r.isWritable(ref.Context()) {
+		return true
+	}
+	digest, ok := ref.(regname.Digest)
+	if !ok {
+		return false
+	}
+	if _, found := r.localRepositories[ref.Context().Name()]; !found {
+		return false
+	}
+	hash, err := regv1.NewHash(digest.DigestStr())
+	if err != nil {
+		return false
+	}
+	blob, err := r.layoutPath.Blob(hash)
+	if err != nil {
+		return false
+	}
+	blob.Close()
+	return true
+}
+
+// localRef Returns the reference that retrieves the image of ref from the layout, the tags are resolved to the digests written
+func (r *OCILayoutRegistry) localRef(ref regname.Reference) regname.Reference {
+	tag, ok := ref.(regname.Tag)
+	if !ok {
+		return ref
+	}
+
+	r.tagsLock.Lock()
+	digest, found := r.tags[tag.TagStr()]
+	r.tagsLock.Unlock()
+	if !found {
+		// Tags are not served by the layout, so the request fails the same way as for a missing tag in a registry
+		return ref
+	}
+	return tag.Context().Digest(digest.String())
+}
+
+// tag Records that the tag ref points to digest, references by digest are ignored
+func (r *OCILayoutRegistry) tag(ref regname.Reference, digest regv1.Hash) {
+	if tag, ok := ref.(regname.Tag); ok {
+		r.tagsLock.Lock()
+		r.tags[tag.TagStr()] = digest
+		r.tagsLock.Unlock()
+	}
+}
+
+// write Writes the image or index to the layout and tags it
+func (r *OCILayoutRegistry) write(ref regname.Reference, taggable regremote.Taggable) error {
+	var digest regv1.Hash
+	var err error
+	switch item := taggable.(type) {
+	case regv1.ImageIndex:
+		err = r.layoutPath.WriteIndex(item)
+		if err == nil {
+			digest, err = item.Digest()
+		}
+	case regv1.Image:
+		err = r.layoutPath.WriteImage(item)
+		if err == nil {
+			digest, err = item.Digest()
+		}
+	default:
+		return fmt.Errorf("Writing '%s': only images and indexes can be written locally", ref.Name())
+	}
+	if err != nil {
+		return fmt.Errorf("Writing '%s' locally: %s", ref.Name(), err)
+	}
+
+	r.tag(ref, digest)
+	return nil
+}
+
+// Get Retrieve Image descriptor for an Image reference
+func (r *OCILayoutRegistry) Get(ref regname.Reference) (*regremote.Descriptor, error) {
+	if !r.isLocal(ref) {
+		return r.delegate.Get(ref)
+	}
+	return r.local.Get(r.localRef(ref))
+}
+
+// Digest Retrieve the Digest for an Image reference
+func (r *OCILayoutRegistry) Digest(ref regname.Reference) (regv1.Hash, error) {
+	if !r.isLocal(ref) {
+		return r.delegate.Digest(ref)
+	}
+	return r.local.Digest(r.localRef(ref))
+}
+
+// Index Retrieve regv1.ImageIndex struct for an Index reference
+func (r *OCILayoutRegistry) Index(ref regname.Reference) (regv1.ImageIndex, error) {
+	if !r.isLocal(ref) {
+		return r.delegate.Index(ref)
+	}
+	return r.local.Index(r.localRef(ref))
+}
+
+// Image Retrieve the regv1.Image struct for an Image reference
+func (r *OCILayoutRegistry) Image(ref regname.Reference) (regv1.Image, error) {
+	if !r.isLocal(ref) {
+		return r.delegate.Image(ref)
+	}
+	return r.local.Image(r.localRef(ref))
+}
+
+// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
+func (r *OCILayoutRegistry) FirstImageExists(digests []string) (string, error) {
+	var err error
+	for _, img := range digests {
+		ref, parseErr := regname.NewDigest(img)
+		if parseErr != nil {
+			return "", parseErr
+		}
+		_, err = r.Digest(ref)
+		if err == nil {
+			return img, nil
+		}
+	}
+	return "", fmt.Errorf("Checking image existence: %s", err)
+}
+
+// BlobExists Checks if the blob (layer or config) is present in the repository of the digest reference
+func (r *OCILayoutRegistry) BlobExists(ref regname.Digest) (bool, error) {
+	if !r.isLocal(ref) {
+		return BlobExists(r.delegate, ref)
+	}
+	return r.local.BlobExists(ref)
+}
+
+// Layer Retrieves the blob (layer or config) of the digest reference
+func (r *OCILayoutRegistry) Layer(ref regname.Digest) (regv1.Layer, error) {
+	if !r.isLocal(ref) {
+		return Layer(r.delegate, ref)
+	}
+	return r.local.Layer(ref)
+}
+
+// BlobRange Retrieves length bytes of the blob of the digest reference, starting at offset
+func (r *OCILayoutRegistry) BlobRange(ref regname.Digest, offset, length int64) (io.ReadCloser, error) {
+	if !r.isLocal(ref) {
+		return BlobRange(r.delegate, ref, offset, length)
+	}
+	hash, err := regv1.NewHash(ref.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+	blob, err := r.layoutPath.Blob(hash)
+	if err != nil {
+		return nil, fmt.Errorf("Reading blob '%s': %s", ref.Name(), err)
+	}
+	file, ok := blob.(io.ReadSeekCloser)
+	if !ok {
+		blob.Close()
+		return nil, fmt.Errorf("Reading blob '%s': unable to seek", ref.Name())
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Reading blob '%s': %s", ref.Name(), err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// Referrers Lists the manifests that refer to the digest reference through their subject
+func (r *OCILayoutRegistry) Referrers(ref regname.Digest, artifactType string) ([]regv1.Descriptor, error) {
+	if !r.isLocal(ref) {
+		return Referrers(r.delegate, ref, artifactType)
+	}
+	return r.local.Referrers(ref, artifactType)
+}
+
+// MultiWrite Upload multiple Images in Parallel, the ones of the local repository are written to the layout
+func (r *OCILayoutRegistry) MultiWrite(imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	return r.MultiWriteWithContext(context.Background(), imageOrIndexesToUpload, concurrency, updatesCh)
+}
+
+// MultiWriteWithContext Upload multiple Images in Parallel, the ones of the local repository are written to the layout.
+// The writes to the registry stop when ctx is done
+func (r *OCILayoutRegistry) MultiWriteWithContext(ctx context.Context, imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	delegated := map[regname.Reference]regremote.Taggable{}
+	for ref, taggable := range imageOrIndexesToUpload {
+		if !r.isWritable(ref.Context()) {
+			delegated[ref] = taggable
+			continue
+		}
+		err := r.write(ref, taggable)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(delegated) == 0 {
+		if updatesCh != nil {
+			close(updatesCh)
+		}
+		return nil
+	}
+	return MultiWriteWithContext(ctx, r.delegate, delegated, concurrency, updatesCh)
+}
+
+// WriteImage Upload Image to registry
+func (r *OCILayoutRegistry) WriteImage(ref regname.Reference, img regv1.Image, updatesCh chan regv1.Update) error {
+	if !r.isWritable(ref.Context()) {
+		return r.delegate.WriteImage(ref, img, updatesCh)
+	}
+	if updatesCh != nil {
+		defer close(updatesCh)
+	}
+	return r.write(ref, img)
+}
+
+// WriteIndex Uploads the Index manifest to the registry
+func (r *OCILayoutRegistry) WriteIndex(ref regname.Reference, index regv1.ImageIndex) error {
+	if !r.isWritable(ref.Context()) {
+		return r.delegate.WriteIndex(ref, index)
+	}
+	return r.write(ref, index)
+}
+
+// WriteTag Tag the referenced Image
+func (r *OCILayoutRegistry) WriteTag(ref regname.Tag, taggable regremote.Taggable) error {
+	if !r.isWritable(ref.Context()) {
+		return r.delegate.WriteTag(ref, taggable)
+	}
+	return r.write(ref, taggable)
+}
+
+// ListTags Retrieve all tags associated with a Repository
+func (r *OCILayoutRegistry) ListTags(repo regname.Repository) ([]string, error) {
+	if !r.isWritable(repo) {
+		return r.delegate.ListTags(repo)
+	}
+
+	r.tagsLock.Lock()
+	defer r.tagsLock.Unlock()
+	tags := []string{}
+	for tag := range r.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// CloneWithSingleAuth Clones the delegate with the single auth needed to access imageRef, keeping the layout
+func (r *OCILayoutRegistry) CloneWithSingleAuth(imageRef regname.Tag) (Registry, error) {
+	if r.isWritable(imageRef.Context()) {
+		return r, nil
+	}
+	delegate, err := r.delegate.CloneWithSingleAuth(imageRef)
+	if err != nil {
+		return nil, err
+	}
+	clone := *r
+	clone.delegate = delegate
+	return &clone, nil
+}
+
+// CloneWithLogger Clones the delegate with the provided logger, keeping the layout
+func (r *OCILayoutRegistry) CloneWithLogger(logger util.ProgressLogger) Registry {
+	clone := *r
+	clone.delegate = r.delegate.CloneWithLogger(logger)
+	return &clone
+}
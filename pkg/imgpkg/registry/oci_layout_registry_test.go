@@ -0,0 +1,209 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	regregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCILayoutRegistry(t *testing.T) {
+	server := httptest.NewServer(regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(100, 3)
+	require.NoError(t, err)
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	layoutDir := t.TempDir()
+	helpers.WriteOCILayout(t, layoutDir, "registry.invalid/app/image", img)
+
+	ref, layoutPath, err := registry.OCILayoutReference("oci:" + layoutDir)
+	require.NoError(t, err)
+
+	delegate, err := registry.NewSimpleRegistry(registry.Opts{EnvironFunc: os.Environ})
+	require.NoError(t, err)
+	subject, err := registry.NewOCILayoutRegistry(layoutPath, delegate)
+	require.NoError(t, err)
+
+	t.Run("it reads the image from the layout with its own reference", func(t *testing.T) {
+		digestRef, err := name.NewDigest(ref)
+		require.NoError(t, err)
+
+		readImg, err := subject.Image(digestRef)
+		require.NoError(t, err)
+
+		readDigest, err := readImg.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, imgDigest, readDigest)
+
+		layers, err := readImg.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 3)
+		_, err = layers[0].Compressed()
+		require.NoError(t, err)
+
+		hash, err := subject.Digest(digestRef)
+		require.NoError(t, err)
+		assert.Equal(t, imgDigest, hash)
+		assert.Equal(t, "registry.invalid/app/image@"+imgDigest.String(), digestRef.Name())
+	})
+
+	t.Run("when offline mode is enabled, it reads the image from the layout", func(t *testing.T) {
+		offlineDelegate, err := registry.NewSimpleRegistry(registry.Opts{Offline: true})
+		require.NoError(t, err)
+		offline, err := registry.NewOCILayoutRegistry(layoutPath, offlineDelegate)
+		require.NoError(t, err)
+
+		digestRef, err := name.NewDigest(ref)
+		require.NoError(t, err)
+
+		readImg, err := offline.Image(digestRef)
+		require.NoError(t, err)
+		layers, err := readImg.Layers()
+		require.NoError(t, err)
+		_, err = layers[0].Compressed()
+		require.NoError(t, err)
+	})
+
+	t.Run("when the image is not in the layout, it is read through the delegate", func(t *testing.T) {
+		otherImg, err := random.Image(100, 1)
+		require.NoError(t, err)
+		otherRef, err := name.NewTag(fmt.Sprintf("%s/app/other:latest", serverURL.Host))
+		require.NoError(t, err)
+		require.NoError(t, delegate.WriteImage(otherRef, otherImg, nil))
+
+		expectedDigest, err := otherImg.Digest()
+		require.NoError(t, err)
+		digest, err := subject.Digest(otherRef)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+	})
+
+	t.Run("when the image is in the layout but the reference names another repository, it is read through the delegate", func(t *testing.T) {
+		otherRef, err := name.NewDigest(fmt.Sprintf("%s/app/copied@%s", serverURL.Host, imgDigest))
+		require.NoError(t, err)
+
+		_, err = subject.Digest(otherRef)
+		require.Error(t, err)
+	})
+
+	t.Run("when cleaned up, it keeps the layout", func(t *testing.T) {
+		require.NoError(t, subject.Cleanup())
+		assert.DirExists(t, layoutDir)
+	})
+}
+
+func TestTemporaryOCILayoutRegistry(t *testing.T) {
+	server := httptest.NewServer(regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	delegate, err := registry.NewSimpleRegistry(registry.Opts{})
+	require.NoError(t, err)
+	localRepo, err := name.NewRepository("registry.invalid/app/bundle")
+	require.NoError(t, err)
+	subject, err := registry.NewTemporaryOCILayoutRegistry(localRepo, delegate)
+	require.NoError(t, err)
+	defer subject.Cleanup()
+
+	t.Run("keeps the images of the repository locally", func(t *testing.T) {
+		img, err := random.Image(512, 2)
+		require.NoError(t, err)
+		ref, err := name.NewTag("registry.invalid/app/bundle:1.0.0")
+		require.NoError(t, err)
+
+		require.NoError(t, subject.MultiWrite(map[name.Reference]regremote.Taggable{ref: img}, 2, nil))
+
+		expectedDigest, err := img.Digest()
+		require.NoError(t, err)
+		digest, err := subject.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+
+		tags, err := subject.ListTags(localRepo)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0.0"}, tags)
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		layerDigest, err := layers[1].Digest()
+		require.NoError(t, err)
+		expectedContent, err := layers[1].Compressed()
+		require.NoError(t, err)
+		expectedBytes, err := io.ReadAll(expectedContent)
+		require.NoError(t, err)
+
+		layer, err := subject.Layer(localRepo.Digest(layerDigest.String()))
+		require.NoError(t, err)
+		content, err := layer.Compressed()
+		require.NoError(t, err)
+		readBytes, err := io.ReadAll(content)
+		require.NoError(t, err)
+		assert.Equal(t, expectedBytes, readBytes)
+
+		blobRange, err := subject.BlobRange(localRepo.Digest(layerDigest.String()), 10, 20)
+		require.NoError(t, err)
+		defer blobRange.Close()
+		readBytes, err = io.ReadAll(blobRange)
+		require.NoError(t, err)
+		assert.Equal(t, expectedBytes[10:30], readBytes)
+	})
+
+	t.Run("when a tag was not written, it is not found", func(t *testing.T) {
+		ref, err := name.NewTag("registry.invalid/app/bundle:missing")
+		require.NoError(t, err)
+		_, err = subject.Digest(ref)
+		require.Error(t, err)
+	})
+
+	t.Run("when cleaned up, it removes the images written", func(t *testing.T) {
+		other, err := registry.NewTemporaryOCILayoutRegistry(localRepo, delegate)
+		require.NoError(t, err)
+		img, err := random.Image(512, 1)
+		require.NoError(t, err)
+		ref, err := name.NewTag("registry.invalid/app/bundle:1.0.0")
+		require.NoError(t, err)
+		require.NoError(t, other.WriteImage(ref, img, nil))
+
+		require.NoError(t, other.Cleanup())
+		_, err = other.Digest(ref)
+		require.Error(t, err)
+	})
+
+	t.Run("reads and writes the other repositories through the delegate", func(t *testing.T) {
+		img, err := random.Image(512, 1)
+		require.NoError(t, err)
+		ref, err := name.NewTag(fmt.Sprintf("%s/app/image:latest", serverURL.Host))
+		require.NoError(t, err)
+
+		require.NoError(t, subject.WriteImage(ref, img, nil))
+
+		expectedDigest, err := img.Digest()
+		require.NoError(t, err)
+		digest, err := delegate.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+		digest, err = subject.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCILayoutReference(t *testing.T) {
+	img1, err := random.Image(100, 2)
+	require.NoError(t, err)
+	img1Digest, err := img1.Digest()
+	require.NoError(t, err)
+	img2, err := random.Image(100, 2)
+	require.NoError(t, err)
+
+	t.Run("when the layout contains a single manifest, it resolves to that manifest", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		helpers.WriteOCILayout(t, layoutDir, "registry.example.com/app/image", img1)
+
+		ref, layoutPath, err := registry.OCILayoutReference("oci:" + layoutDir)
+		require.NoError(t, err)
+		assert.Equal(t, layoutDir, string(layoutPath))
+		assert.Equal(t, "registry.example.com/app/image@"+img1Digest.String(), ref)
+	})
+
+	t.Run("when the manifest is named after its reference with a tag, it resolves to the repository of the image", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		layoutPath, err := layout.Write(layoutDir, empty.Index)
+		require.NoError(t, err)
+		require.NoError(t, layoutPath.AppendImage(img1, layout.WithAnnotations(map[string]string{
+			"org.opencontainers.image.ref.name": "registry.example.com/app/image:1.0.0",
+		})))
+
+		ref, _, err := registry.OCILayoutReference("oci:" + layoutDir)
+		require.NoError(t, err)
+		assert.Equal(t, "registry.example.com/app/image@"+img1Digest.String(), ref)
+	})
+
+	t.Run("when the manifest is only named after a tag, it errors", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		layoutPath, err := layout.Write(layoutDir, empty.Index)
+		require.NoError(t, err)
+		require.NoError(t, layoutPath.AppendImage(img1, layout.WithAnnotations(map[string]string{
+			"org.opencontainers.image.ref.name": "1.0.0",
+		})))
+
+		_, _, err = registry.OCILayoutReference("oci:" + layoutDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to have the reference of the image in the annotation")
+	})
+
+	t.Run("when the layout contains multiple manifests and no digest is provided, it errors", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		helpers.WriteOCILayout(t, layoutDir, "registry.example.com/app/image", img1, img2)
+
+		_, _, err := registry.OCILayoutReference("oci:" + layoutDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "select one using oci:<path>@<digest>")
+
+		ref, _, err := registry.OCILayoutReference("oci:" + layoutDir + "@" + img1Digest.String())
+		require.NoError(t, err)
+		assert.Contains(t, ref, img1Digest.String())
+	})
+
+	t.Run("when the digest is not present in the layout, it errors", func(t *testing.T) {
+		layoutDir := t.TempDir()
+		helpers.WriteOCILayout(t, layoutDir, "registry.example.com/app/image", img2)
+
+		_, _, err := registry.OCILayoutReference("oci:" + layoutDir + "@" + img1Digest.String())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unable to find")
+	})
+
+	t.Run("when the path is not an OCI image layout, it errors", func(t *testing.T) {
+		_, _, err := registry.OCILayoutReference("oci:" + filepath.Join(t.TempDir(), "does-not-exist"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Reading OCI image layout index")
+	})
+}
+
+func TestOCILayoutManifests(t *testing.T) {
+	img1, err := random.Image(100, 2)
+	require.NoError(t, err)
+	img1Digest, err := img1.Digest()
+	require.NoError(t, err)
+	img2, err := random.Image(100, 2)
+	require.NoError(t, err)
+	img2Digest, err := img2.Digest()
+	require.NoError(t, err)
+
+	layoutPath, err := layout.Write(t.TempDir(), empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, layoutPath.AppendImage(img1, layout.WithAnnotations(map[string]string{
+		"io.containerd.image.name": "registry.example.com/app/image:1.0.0",
+	})))
+	require.NoError(t, layoutPath.AppendImage(img2))
+
+	manifests, err := registry.OCILayoutManifests(layoutPath)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	t.Run("the manifests with a reference are named after the repository of the image", func(t *testing.T) {
+		assert.Equal(t, "registry.example.com/app/image@"+img1Digest.String(), manifests[0].DigestRef)
+		assert.Equal(t, "registry.example.com/app/image:1.0.0", manifests[0].Annotations["io.containerd.image.name"])
+	})
+
+	t.Run("the manifests without a reference are named after a repository that is never resolved", func(t *testing.T) {
+		assert.Equal(t, "oci-layout.imgpkg.invalid/layout@"+img2Digest.String(), manifests[1].DigestRef)
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// OfflineError Returned when a request would reach the network while the offline mode is enabled
+type OfflineError struct {
+	Host string
+}
+
+// Error Describes the host that would have been reached
+func (e OfflineError) Error() string {
+	return fmt.Sprintf("Expected no network access in offline mode, but a connection to '%s' was attempted "+
+		"(hint: only tar and OCI image layout sources and destinations can be used with --offline)", e.Host)
+}
+
+// configureOffline Prevents the transport from resolving hosts, looking up proxies or opening any connection
+func configureOffline(tran *http.Transport) {
+	tran.Proxy = nil
+	tran.DialContext = func(_ context.Context, _, addr string) (net.Conn, error) {
+		return nil, OfflineError{Host: addr}
+	}
+	tran.DialTLSContext = tran.DialContext
+}
+
+// offlineRoundTripper Fails every request before it is sent, the images of OCI image layouts are read through
+// OCILayoutRegistry and never reach it
+type offlineRoundTripper struct{}
+
+// RoundTrip Fails the request without sending it
+func (offlineRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, OfflineError{Host: req.URL.Host}
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// ProbeReport Result of checking that a registry can be reached and implements the registry API
+type ProbeReport struct {
+	Registry   string `json:"registry"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	// APIVersion value of the Docker-Distribution-API-Version header
+	APIVersion string `json:"apiVersion,omitempty"`
+	// AuthScheme scheme of the WWW-Authenticate challenge, example: Bearer
+	AuthScheme string `json:"authScheme,omitempty"`
+	Duration   string `json:"duration"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ProbeRegistries Sends an anonymous request to the base endpoint of each registry, using the same proxy and TLS
+// configuration as the other commands. No credentials are sent, so an unauthorized response means the registry is reachable
+func ProbeRegistries(opts Opts, registries []regname.Registry) ([]ProbeReport, error) {
+	httpTran, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, fmt.Errorf("Creating registry HTTP transport: %s", err)
+	}
+	client := &http.Client{Transport: withInsecureRegistries(httpTran, opts.InsecureRegistries), Timeout: opts.ResponseHeaderTimeout + 10*time.Second}
+
+	var reports []ProbeReport
+	for _, reg := range registries {
+		report := ProbeReport{
+			Registry: reg.RegistryStr(),
+			URL:      fmt.Sprintf("%s://%s/v2/", reg.Scheme(), reg.RegistryStr()),
+		}
+
+		start := time.Now()
+		resp, err := client.Get(report.URL)
+		report.Duration = time.Since(start).Round(time.Millisecond).String()
+		if err != nil {
+			report.Error = err.Error()
+			reports = append(reports, report)
+			continue
+		}
+		resp.Body.Close()
+
+		report.StatusCode = resp.StatusCode
+		report.APIVersion = resp.Header.Get("Docker-Distribution-API-Version")
+		if challenge := resp.Header.Get("WWW-Authenticate"); challenge != "" {
+			report.AuthScheme, _, _ = strings.Cut(challenge, " ")
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+			report.Error = fmt.Sprintf("Unexpected status code %d (expected 200 or 401)", resp.StatusCode)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
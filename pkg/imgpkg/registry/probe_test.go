@@ -0,0 +1,48 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeRegistries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Add("Docker-Distribution-API-Version", "registry/2.0")
+		w.Header().Add("WWW-Authenticate", `Bearer realm="https://auth.io/token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	reachable, err := name.NewRegistry(serverURL.Host, name.Insecure)
+	require.NoError(t, err)
+	unreachable, err := name.NewRegistry("127.0.0.1:1", name.Insecure)
+	require.NoError(t, err)
+
+	reports, err := registry.ProbeRegistries(registry.Opts{Insecure: true}, []name.Registry{reachable, unreachable})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, http.StatusUnauthorized, reports[0].StatusCode)
+	assert.Equal(t, "registry/2.0", reports[0].APIVersion)
+	assert.Equal(t, "Bearer", reports[0].AuthScheme)
+	assert.Empty(t, reports[0].Error)
+
+	assert.Equal(t, "http://127.0.0.1:1/v2/", reports[1].URL)
+	assert.NotEmpty(t, reports[1].Error)
+}
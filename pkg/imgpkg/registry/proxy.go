@@ -0,0 +1,165 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ProxySelector Chooses the proxy used to reach each registry, like proxy auto-config (PAC) files do.
+// When no ProxySelector is provided the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+type ProxySelector interface {
+	// Proxy Returns the URL of the proxy to use for the request, or nil when the request is sent directly
+	Proxy(req *http.Request) (*url.URL, error)
+}
+
+// ProxySelectorFunc Adapter that allows a function to be used as a ProxySelector
+type ProxySelectorFunc func(req *http.Request) (*url.URL, error)
+
+// Proxy Calls f(req)
+func (f ProxySelectorFunc) Proxy(req *http.Request) (*url.URL, error) { return f(req) }
+
+// ProxyAuthenticator Provides the credentials sent to proxies that require authentication, like Kerberos (SPNEGO) proxies,
+// when opening the tunnel to a registry
+type ProxyAuthenticator interface {
+	// ProxyAuthorization Returns the value of the Proxy-Authorization header sent to proxyURL to reach target (host:port),
+	// or an empty string when no credentials are needed
+	ProxyAuthorization(ctx context.Context, proxyURL *url.URL, target string) (string, error)
+}
+
+// ProxyAuthenticatorFunc Adapter that allows a function to be used as a ProxyAuthenticator
+type ProxyAuthenticatorFunc func(ctx context.Context, proxyURL *url.URL, target string) (string, error)
+
+// ProxyAuthorization Calls f(ctx, proxyURL, target)
+func (f ProxyAuthenticatorFunc) ProxyAuthorization(ctx context.Context, proxyURL *url.URL, target string) (string, error) {
+	return f(ctx, proxyURL, target)
+}
+
+// ParsePACResult Converts the result of the FindProxyForURL function of a PAC file (example: "PROXY proxy.corp:8080; DIRECT")
+// into the URL of the proxy to use. The first entry is used, and nil is returned when it is DIRECT
+func ParsePACResult(result string) (*url.URL, error) {
+	entry := strings.TrimSpace(strings.Split(result, ";")[0])
+	fields := strings.Fields(entry)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Expected proxy auto-config result to not be empty")
+	}
+
+	var scheme string
+	switch strings.ToUpper(fields[0]) {
+	case "DIRECT":
+		return nil, nil
+	case "PROXY", "HTTP":
+		scheme = "http"
+	case "HTTPS":
+		scheme = "https"
+	case "SOCKS", "SOCKS5":
+		scheme = "socks5"
+	default:
+		return nil, fmt.Errorf("Unsupported proxy auto-config result '%s' (hint: supported types are DIRECT, PROXY, HTTPS and SOCKS)", entry)
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Expected proxy auto-config result '%s' to have the format '%s host:port'", entry, fields[0])
+	}
+
+	return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+}
+
+// NewCommandProxySelector Creates a ProxySelector that executes the command with the URL of the registry as argument
+// and expects it to print a proxy auto-config result, see ParsePACResult. The result is cached per registry host
+func NewCommandProxySelector(command string) ProxySelector {
+	selector := &commandProxySelector{command: command, proxies: map[string]*url.URL{}}
+	return ProxySelectorFunc(selector.proxy)
+}
+
+type commandProxySelector struct {
+	command string
+	// helperCalls runs the command once per host, requests to the same host wait for it and requests to other hosts do not
+	helperCalls singleflight.Group
+
+	proxiesLock sync.Mutex
+	proxies     map[string]*url.URL
+}
+
+func (s *commandProxySelector) proxy(req *http.Request) (*url.URL, error) {
+	host := req.URL.Scheme + "://" + req.URL.Host
+
+	s.proxiesLock.Lock()
+	proxyURL, found := s.proxies[host]
+	s.proxiesLock.Unlock()
+	if found {
+		return proxyURL, nil
+	}
+
+	result, err, _ := s.helperCalls.Do(host, func() (interface{}, error) {
+		// The requests waiting for the result are not affected when the request running the command is cancelled
+		output, err := runHelper(context.WithoutCancel(req.Context()), s.command, host+req.URL.Path)
+		if err != nil {
+			return nil, fmt.Errorf("Selecting proxy for '%s': %s", host, err)
+		}
+		proxyURL, err := ParsePACResult(output)
+		if err != nil {
+			return nil, fmt.Errorf("Selecting proxy for '%s': %s", host, err)
+		}
+
+		s.proxiesLock.Lock()
+		s.proxies[host] = proxyURL
+		s.proxiesLock.Unlock()
+		return proxyURL, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*url.URL), nil
+}
+
+// NewCommandProxyAuthenticator Creates a ProxyAuthenticator that executes the command with the URL of the proxy and
+// the target host:port as arguments and expects it to print the value of the Proxy-Authorization header (example: "Negotiate <token>")
+func NewCommandProxyAuthenticator(command string) ProxyAuthenticator {
+	return ProxyAuthenticatorFunc(func(ctx context.Context, proxyURL *url.URL, target string) (string, error) {
+		output, err := runHelper(ctx, command, proxyURL.String(), target)
+		if err != nil {
+			return "", fmt.Errorf("Authenticating with proxy '%s': %s", proxyURL.Host, err)
+		}
+		return output, nil
+	})
+}
+
+func runHelper(ctx context.Context, command string, args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("Running '%s': %s (stderr: %s)", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// configureProxy Uses the ProxySelector and ProxyAuthenticator in the transport, when they are provided
+func configureProxy(transport *http.Transport, opts Opts) {
+	if opts.ProxySelector != nil {
+		transport.Proxy = opts.ProxySelector.Proxy
+	}
+	if opts.ProxyAuthenticator != nil {
+		transport.GetProxyConnectHeader = func(ctx context.Context, proxyURL *url.URL, target string) (http.Header, error) {
+			authorization, err := opts.ProxyAuthenticator.ProxyAuthorization(ctx, proxyURL, target)
+			if err != nil || authorization == "" {
+				return nil, err
+			}
+			return http.Header{"Proxy-Authorization": []string{authorization}}, nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePACResult(t *testing.T) {
+	t.Run("when the first entry is a proxy, it returns its URL", func(t *testing.T) {
+		for result, expected := range map[string]string{
+			"PROXY proxy.corp:8080; DIRECT": "http://proxy.corp:8080",
+			"HTTPS proxy.corp:8443":         "https://proxy.corp:8443",
+			" SOCKS5 socks.corp:1080 ":      "socks5://socks.corp:1080",
+		} {
+			proxyURL, err := registry.ParsePACResult(result)
+			require.NoError(t, err)
+			assert.Equal(t, expected, proxyURL.String())
+		}
+	})
+
+	t.Run("when the first entry is DIRECT, it returns no proxy", func(t *testing.T) {
+		proxyURL, err := registry.ParsePACResult("DIRECT; PROXY proxy.corp:8080")
+		require.NoError(t, err)
+		assert.Nil(t, proxyURL)
+	})
+
+	t.Run("when the result is not valid, it returns an error", func(t *testing.T) {
+		_, err := registry.ParsePACResult("")
+		require.ErrorContains(t, err, "Expected proxy auto-config result to not be empty")
+
+		_, err = registry.ParsePACResult("PROXY")
+		require.ErrorContains(t, err, "Expected proxy auto-config result 'PROXY' to have the format 'PROXY host:port'")
+
+		_, err = registry.ParsePACResult("FTP proxy.corp:21")
+		require.ErrorContains(t, err, "Unsupported proxy auto-config result 'FTP proxy.corp:21'")
+	})
+}
+
+func TestCommandProxyHelpers(t *testing.T) {
+	helperDir := t.TempDir()
+	writeHelper := func(t *testing.T, name, script string) string {
+		path := filepath.Join(helperDir, name)
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+		return path
+	}
+
+	t.Run("when the proxy helper prints a proxy, it is used for the registry and the result is cached per host", func(t *testing.T) {
+		callsFile := filepath.Join(helperDir, "calls")
+		helper := writeHelper(t, "proxy-helper", `echo "$1" >> `+callsFile+`
+echo "PROXY proxy.corp:8080; DIRECT"
+`)
+		selector := registry.NewCommandProxySelector(helper)
+
+		for _, path := range []string{"/v2/", "/v2/repo/manifests/latest"} {
+			req, err := http.NewRequest(http.MethodGet, "https://registry.corp"+path, nil)
+			require.NoError(t, err)
+			proxyURL, err := selector.Proxy(req)
+			require.NoError(t, err)
+			assert.Equal(t, "http://proxy.corp:8080", proxyURL.String())
+		}
+
+		calls, err := os.ReadFile(callsFile)
+		require.NoError(t, err)
+		assert.Equal(t, "https://registry.corp/v2/\n", string(calls))
+	})
+
+	t.Run("when the proxy helper is slow for a host, the requests to other hosts do not wait for it", func(t *testing.T) {
+		callsFile := filepath.Join(helperDir, "slow-calls")
+		helper := writeHelper(t, "slow-proxy-helper", `echo "$1" >> `+callsFile+`
+case "$1" in *slow.corp*) sleep 2;; esac
+echo "DIRECT"
+`)
+		selector := registry.NewCommandProxySelector(helper)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, err := http.NewRequest(http.MethodGet, "https://slow.corp/v2/", nil)
+				assert.NoError(t, err)
+				_, err = selector.Proxy(req)
+				assert.NoError(t, err)
+			}()
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		start := time.Now()
+		req, err := http.NewRequest(http.MethodGet, "https://registry.corp/v2/", nil)
+		require.NoError(t, err)
+		_, err = selector.Proxy(req)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+
+		wg.Wait()
+		calls, err := os.ReadFile(callsFile)
+		require.NoError(t, err)
+		assert.Equal(t, "https://slow.corp/v2/\nhttps://registry.corp/v2/\n", string(calls))
+	})
+
+	t.Run("when the proxy helper fails, it returns an error", func(t *testing.T) {
+		helper := writeHelper(t, "failing-proxy-helper", "echo 'no pac file' >&2\nexit 1\n")
+		req, err := http.NewRequest(http.MethodGet, "https://registry.corp/v2/", nil)
+		require.NoError(t, err)
+
+		_, err = registry.NewCommandProxySelector(helper).Proxy(req)
+		require.ErrorContains(t, err, "Selecting proxy for 'https://registry.corp'")
+		require.ErrorContains(t, err, "no pac file")
+	})
+
+	t.Run("when the proxy auth helper prints a header, it is returned", func(t *testing.T) {
+		helper := writeHelper(t, "proxy-auth-helper", `echo "Negotiate $1 $2"`+"\n")
+		proxyURL, err := url.Parse("http://proxy.corp:8080")
+		require.NoError(t, err)
+
+		authorization, err := registry.NewCommandProxyAuthenticator(helper).ProxyAuthorization(context.Background(), proxyURL, "registry.corp:443")
+		require.NoError(t, err)
+		assert.Equal(t, "Negotiate http://proxy.corp:8080 registry.corp:443", authorization)
+	})
+}
@@ -4,9 +4,11 @@
 package registry
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"os"
@@ -20,6 +22,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/logs"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
@@ -28,6 +31,9 @@ type Opts struct {
 	CACertPaths []string
 	VerifyCerts bool
 	Insecure    bool
+	// InsecureRegistries registries, with the format host[:port], accessed over plain HTTP or over HTTPS without
+	// verifying their certificates, every other registry is still verified. Registries without a port match every port
+	InsecureRegistries []string
 
 	IncludeNonDistributableLayers bool
 
@@ -39,12 +45,41 @@ type Opts struct {
 	EnableIaasAuthProviders bool
 
 	ResponseHeaderTimeout time.Duration
-	RetryCount            int
+	// StallTimeout when greater than 0, responses that do not receive any byte for this time fail, so that they are retried on a new connection
+	StallTimeout time.Duration
+	RetryCount   int
+	// RetryBackoff time waited before the first retry of a request, doubled before each of the following ones (default: DefaultRetryBackoff)
+	RetryBackoff time.Duration
+	// RetryMaxElapsed when greater than 0, maximum time waited between the retries of a request
+	RetryMaxElapsed time.Duration
 
 	EnvironFunc     func() []string
 	ActiveKeychains []auth.IAASKeychain
 
 	SessionID string
+
+	// RateLimiter when provided, every request sent to a registry waits for it
+	RateLimiter RateLimiter
+	// RetryPolicy when provided, decides which requests sent to a registry are retried instead of the default retries on network errors
+	RetryPolicy RetryPolicy
+	// RetryBudget when provided, limits the failed attempts of the requests sent to each registry host
+	RetryBudget *RetryBudget
+	// MaxUploadRate and MaxDownloadRate when greater than 0, limit the bytes per second sent to and received from the registries
+	MaxUploadRate   int64
+	MaxDownloadRate int64
+	// ProxySelector when provided, chooses the proxy used for each registry instead of the proxy environment variables
+	ProxySelector ProxySelector
+	// ProxyAuthenticator when provided, authenticates with the proxy when connecting to a registry through it
+	ProxyAuthenticator ProxyAuthenticator
+
+	// TrafficMeter when provided, measures the traffic exchanged with each registry host
+	TrafficMeter *TrafficMeter
+
+	// FIPS restricts TLS and digest verification to FIPS-approved algorithms, and fails on registries or artifacts that do not comply
+	FIPS bool
+
+	// Offline fails every request that would reach the network, only OCI image layouts can be read and written
+	Offline bool
 }
 
 // DeepCopy the options to a new struct
@@ -59,18 +94,49 @@ func (o Opts) DeepCopy() Opts {
 		Anon:                          o.Anon,
 		EnableIaasAuthProviders:       o.EnableIaasAuthProviders,
 		ResponseHeaderTimeout:         o.ResponseHeaderTimeout,
+		StallTimeout:                  o.StallTimeout,
 		RetryCount:                    o.RetryCount,
+		RetryBackoff:                  o.RetryBackoff,
+		RetryMaxElapsed:               o.RetryMaxElapsed,
 		EnvironFunc:                   o.EnvironFunc,
+		RateLimiter:                   o.RateLimiter,
+		RetryPolicy:                   o.RetryPolicy,
+		RetryBudget:                   o.RetryBudget,
+		MaxUploadRate:                 o.MaxUploadRate,
+		MaxDownloadRate:               o.MaxDownloadRate,
+		ProxySelector:                 o.ProxySelector,
+		ProxyAuthenticator:            o.ProxyAuthenticator,
+		TrafficMeter:                  o.TrafficMeter,
+		FIPS:                          o.FIPS,
+		Offline:                       o.Offline,
 	}
 	for _, path := range o.CACertPaths {
 		result.CACertPaths = append(result.CACertPaths, path)
 	}
+	for _, insecureRegistry := range o.InsecureRegistries {
+		result.InsecureRegistries = append(result.InsecureRegistries, insecureRegistry)
+	}
 	for _, keychain := range o.ActiveKeychains {
 		result.ActiveKeychains = append(result.ActiveKeychains, keychain)
 	}
 	return result
 }
 
+func (o Opts) keychainOpts() auth.KeychainOpts {
+	if o.Offline {
+		// Credential helpers and IaaS metadata services may reach the network, only anonymous access is used
+		return auth.KeychainOpts{Anon: true}
+	}
+	return auth.KeychainOpts{
+		Username:                o.Username,
+		Password:                o.Password,
+		Token:                   o.Token,
+		Anon:                    o.Anon,
+		EnableIaasAuthProviders: o.EnableIaasAuthProviders,
+		ActiveKeychains:         o.ActiveKeychains,
+	}
+}
+
 // Registry Interface to access the registry
 type Registry interface {
 	Get(reference regname.Reference) (*regremote.Descriptor, error)
@@ -115,7 +181,37 @@ type ImagesReaderWriter interface {
 	CloneWithLogger(logger util.ProgressLogger) Registry
 }
 
+// ContextWriter Registry whose writes stop when the context is done, like when its deadline is exceeded
+type ContextWriter interface {
+	MultiWriteWithContext(ctx context.Context, imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error
+}
+
+// BlobChecker Registry that checks if a blob is present without reading it
+type BlobChecker interface {
+	BlobExists(ref regname.Digest) (bool, error)
+}
+
+// LayerReader Registry that reads a blob (layer or config) without reading the manifest that references it
+type LayerReader interface {
+	Layer(ref regname.Digest) (regv1.Layer, error)
+}
+
+// BlobRangeReader Registry that reads part of a blob
+type BlobRangeReader interface {
+	BlobRange(ref regname.Digest, offset, length int64) (io.ReadCloser, error)
+}
+
+// ReferrersLister Registry that lists the manifests that refer to a digest through their subject
+type ReferrersLister interface {
+	Referrers(ref regname.Digest, artifactType string) ([]regv1.Descriptor, error)
+}
+
 var _ Registry = &SimpleRegistry{}
+var _ ContextWriter = &SimpleRegistry{}
+var _ BlobChecker = &SimpleRegistry{}
+var _ LayerReader = &SimpleRegistry{}
+var _ BlobRangeReader = &SimpleRegistry{}
+var _ ReferrersLister = &SimpleRegistry{}
 
 // RoundTripperStorage Storage of RoundTripper that will be used to talk to the registry
 type RoundTripperStorage interface {
@@ -126,12 +222,14 @@ type RoundTripperStorage interface {
 
 // SimpleRegistry Implements Registry interface
 type SimpleRegistry struct {
-	remoteOpts      []regremote.Option
-	refOpts         []regname.Option
-	keychain        regauthn.Keychain
-	authn           map[string]regauthn.Authenticator
-	roundTrippers   RoundTripperStorage
-	transportAccess *sync.Mutex
+	remoteOpts []regremote.Option
+	refOpts    []regname.Option
+	// insecureRegistries registries parsed as insecure, so that they can be accessed over plain HTTP
+	insecureRegistries []string
+	keychain           regauthn.Keychain
+	authn              map[string]regauthn.Authenticator
+	roundTrippers      RoundTripperStorage
+	transportAccess    *sync.Mutex
 }
 
 // NewBasicRegistry does not provide any special behavior and all the options as passed as is to the underlying library
@@ -149,7 +247,7 @@ func NewSimpleRegistry(opts Opts) (*SimpleRegistry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Creating registry HTTP transport: %s", err)
 	}
-	return NewSimpleRegistryWithTransport(opts, httpTran)
+	return NewSimpleRegistryWithTransport(opts, withInsecureRegistries(httpTran, opts.InsecureRegistries))
 }
 
 // NewSimpleRegistryWithTransport Creates a new Simple Registry using the provided transport
@@ -159,17 +257,7 @@ func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*Sim
 		refOpts = append(refOpts, regname.Insecure)
 	}
 
-	keychain, err := Keychain(
-		auth.KeychainOpts{
-			Username:                opts.Username,
-			Password:                opts.Password,
-			Token:                   opts.Token,
-			Anon:                    opts.Anon,
-			EnableIaasAuthProviders: opts.EnableIaasAuthProviders,
-			ActiveKeychains:         opts.ActiveKeychains,
-		},
-		opts.EnvironFunc,
-	)
+	keychain, err := Keychain(opts.keychainOpts(), opts.EnvironFunc)
 	if err != nil {
 		return nil, fmt.Errorf("Creating registry keychain: %s", err)
 	}
@@ -178,23 +266,40 @@ func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*Sim
 	if opts.IncludeNonDistributableLayers {
 		regRemoteOptions = append(regRemoteOptions, regremote.WithNondistributable)
 	}
-	tries := opts.RetryCount
-	if tries == 0 {
-		tries = 1
-	}
-
-	retryBackoff := regremote.Backoff{
-		Duration: 100 * time.Millisecond,
-		Factor:   2,
-		Jitter:   0,
-		Steps:    tries,
-		Cap:      1 * time.Second,
+	retryBackoff, err := newRetryBackoff(opts.RetryCount, opts.RetryBackoff, opts.RetryMaxElapsed)
+	if err != nil {
+		return nil, err
 	}
-	regRemoteOptions = append(regRemoteOptions, regremote.WithRetryBackoff(retryBackoff))
+	regRemoteOptions = append(regRemoteOptions, regremote.WithRetryBackoff(retryBackoff), regremote.WithRetryPredicate(retryableError))
 
 	baseRoundTripper := rTripper
+	if opts.Offline {
+		baseRoundTripper = offlineRoundTripper{}
+	}
+	if opts.StallTimeout > 0 {
+		baseRoundTripper = &stallDetectingRoundTripper{delegate: baseRoundTripper, timeout: opts.StallTimeout}
+	}
+	if opts.TrafficMeter != nil {
+		baseRoundTripper = &trafficMeterRoundTripper{delegate: baseRoundTripper, meter: opts.TrafficMeter}
+	}
+	if opts.RateLimiter != nil {
+		baseRoundTripper = &rateLimitedRoundTripper{delegate: baseRoundTripper, limiter: opts.RateLimiter}
+	}
+	if opts.MaxUploadRate > 0 || opts.MaxDownloadRate > 0 {
+		bandwidthRoundTripper := &bandwidthLimitedRoundTripper{delegate: baseRoundTripper}
+		if opts.MaxUploadRate > 0 {
+			bandwidthRoundTripper.upload = NewBandwidthLimiter(opts.MaxUploadRate)
+		}
+		if opts.MaxDownloadRate > 0 {
+			bandwidthRoundTripper.download = NewBandwidthLimiter(opts.MaxDownloadRate)
+		}
+		baseRoundTripper = bandwidthRoundTripper
+	}
+	if opts.FIPSEnabled() {
+		baseRoundTripper = &fipsRoundTripper{delegate: baseRoundTripper}
+	}
 	if logs.Enabled(logs.Debug) {
-		baseRoundTripper = transport.NewLogger(rTripper)
+		baseRoundTripper = transport.NewLogger(baseRoundTripper)
 	}
 
 	sessionID := opts.SessionID
@@ -202,17 +307,26 @@ func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*Sim
 		sessionID = fmt.Sprint(rand.Int31())
 	}
 	baseRoundTripper = NewImgpkgRoundTripper(baseRoundTripper, sessionID)
+	if opts.RetryBudget != nil {
+		baseRoundTripper = &retryBudgetRoundTripper{delegate: baseRoundTripper, budget: opts.RetryBudget}
+	}
 
-	// Wrap the transport in something that can retry network flakes.
-	baseRoundTripper = transport.NewRetry(baseRoundTripper, transport.WithRetryBackoff(retryBackoff))
+	if opts.RetryPolicy != nil {
+		baseRoundTripper = &retryPolicyRoundTripper{delegate: baseRoundTripper, policy: opts.RetryPolicy}
+	} else {
+		// Wrap the transport in something that can retry network flakes.
+		baseRoundTripper = transport.NewRetry(baseRoundTripper, transport.WithRetryBackoff(retryBackoff))
+		baseRoundTripper = &readRetryRoundTripper{delegate: baseRoundTripper, backoff: retryBackoff}
+	}
 
 	return &SimpleRegistry{
-		remoteOpts:      regRemoteOptions,
-		refOpts:         refOpts,
-		keychain:        keychain,
-		roundTrippers:   NewMultiRoundTripperStorage(baseRoundTripper),
-		authn:           map[string]regauthn.Authenticator{},
-		transportAccess: &sync.Mutex{},
+		remoteOpts:         regRemoteOptions,
+		refOpts:            refOpts,
+		insecureRegistries: opts.InsecureRegistries,
+		keychain:           keychain,
+		roundTrippers:      NewMultiRoundTripperStorage(baseRoundTripper),
+		authn:              map[string]regauthn.Authenticator{},
+		transportAccess:    &sync.Mutex{},
 	}, nil
 }
 
@@ -241,12 +355,13 @@ func (r SimpleRegistry) CloneWithSingleAuth(imageRef regname.Tag) (Registry, err
 	}
 
 	return &SimpleRegistry{
-		remoteOpts:      r.remoteOpts,
-		refOpts:         r.refOpts,
-		keychain:        keychain,
-		roundTrippers:   singleRt,
-		authn:           map[string]regauthn.Authenticator{},
-		transportAccess: &sync.Mutex{},
+		remoteOpts:         r.remoteOpts,
+		refOpts:            r.refOpts,
+		insecureRegistries: r.insecureRegistries,
+		keychain:           keychain,
+		roundTrippers:      singleRt,
+		authn:              map[string]regauthn.Authenticator{},
+		transportAccess:    &sync.Mutex{},
 	}, nil
 }
 
@@ -254,12 +369,13 @@ func (r SimpleRegistry) CloneWithSingleAuth(imageRef regname.Tag) (Registry, err
 // that does not display the progress bar
 func (r SimpleRegistry) CloneWithLogger(_ util.ProgressLogger) Registry {
 	return &SimpleRegistry{
-		remoteOpts:      r.remoteOpts,
-		refOpts:         r.refOpts,
-		keychain:        r.keychain,
-		roundTrippers:   r.roundTrippers,
-		authn:           map[string]regauthn.Authenticator{},
-		transportAccess: &sync.Mutex{},
+		remoteOpts:         r.remoteOpts,
+		refOpts:            r.refOpts,
+		insecureRegistries: r.insecureRegistries,
+		keychain:           r.keychain,
+		roundTrippers:      r.roundTrippers,
+		authn:              map[string]regauthn.Authenticator{},
+		transportAccess:    &sync.Mutex{},
 	}
 }
 
@@ -308,7 +424,7 @@ func (r *SimpleRegistry) transport(ref regname.Reference, scope string) (http.Ro
 		r.authn[registryKey] = resolvedAuth
 		rt, err = r.roundTrippers.CreateRoundTripper(registry.Registry, resolvedAuth, scope)
 		if err != nil {
-			return nil, nil, fmt.Errorf("Error while preparing a transport to talk with the registry: %s", err)
+			return nil, nil, fmt.Errorf("Error while preparing a transport to talk with the registry: %w", err)
 		}
 	}
 
@@ -330,7 +446,7 @@ func (r *SimpleRegistry) Get(ref regname.Reference) (*regremote.Descriptor, erro
 	if err := r.validateRef(ref); err != nil {
 		return nil, err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return nil, err
 	}
@@ -338,7 +454,8 @@ func (r *SimpleRegistry) Get(ref regname.Reference) (*regremote.Descriptor, erro
 	if err != nil {
 		return nil, err
 	}
-	return regremote.Get(overriddenRef, opts...)
+	desc, err := regremote.Get(overriddenRef, opts...)
+	return desc, translateError(overriddenRef.Context(), false, err)
 }
 
 // Digest Retrieve the Digest for an Image reference
@@ -346,7 +463,7 @@ func (r *SimpleRegistry) Digest(ref regname.Reference) (regv1.Hash, error) {
 	if err := r.validateRef(ref); err != nil {
 		return regv1.Hash{}, err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return regv1.Hash{}, err
 	}
@@ -357,9 +474,10 @@ func (r *SimpleRegistry) Digest(ref regname.Reference) (regv1.Hash, error) {
 	}
 	desc, err := regremote.Head(overriddenRef, opts...)
 	if err != nil {
+		// Some registries, like GHCR, refuse HEAD requests of anonymous users, the GET request returns the actual outcome
 		getDesc, err := regremote.Get(overriddenRef, opts...)
 		if err != nil {
-			return regv1.Hash{}, err
+			return regv1.Hash{}, translateError(overriddenRef.Context(), false, err)
 		}
 		return getDesc.Digest, nil
 	}
@@ -372,7 +490,7 @@ func (r *SimpleRegistry) Image(ref regname.Reference) (regv1.Image, error) {
 	if err := r.validateRef(ref); err != nil {
 		return nil, err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return nil, err
 	}
@@ -381,11 +499,17 @@ func (r *SimpleRegistry) Image(ref regname.Reference) (regv1.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	return regremote.Image(overriddenRef, opts...)
+	img, err := regremote.Image(overriddenRef, opts...)
+	return img, translateError(overriddenRef.Context(), false, err)
 }
 
 // MultiWrite Upload multiple Images in Parallel to the Registry
 func (r *SimpleRegistry) MultiWrite(imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	return r.MultiWriteWithContext(context.Background(), imageOrIndexesToUpload, concurrency, updatesCh)
+}
+
+// MultiWriteWithContext Upload multiple Images in Parallel to the Registry, stopping when ctx is done
+func (r *SimpleRegistry) MultiWriteWithContext(ctx context.Context, imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
 	overriddenImageOrIndexesToUploadRef := map[regname.Reference]regremote.Taggable{}
 
 	var singleRef regname.Reference
@@ -393,7 +517,7 @@ func (r *SimpleRegistry) MultiWrite(imageOrIndexesToUpload map[regname.Reference
 		if err := r.validateRef(ref); err != nil {
 			return err
 		}
-		overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+		overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 		if err != nil {
 			return err
 		}
@@ -406,11 +530,15 @@ func (r *SimpleRegistry) MultiWrite(imageOrIndexesToUpload map[regname.Reference
 	if err != nil {
 		return err
 	}
-	rOpts := append(append([]regremote.Option{}, opts...), regremote.WithJobs(concurrency))
+	rOpts := append(append([]regremote.Option{}, opts...), regremote.WithJobs(concurrency), regremote.WithContext(ctx))
 	if updatesCh != nil {
 		rOpts = append(rOpts, regremote.WithProgress(updatesCh))
 	}
-	return regremote.MultiWrite(overriddenImageOrIndexesToUploadRef, rOpts...)
+	err = regremote.MultiWrite(overriddenImageOrIndexesToUploadRef, rOpts...)
+	if err != nil {
+		return translateError(singleRef.Context(), true, err)
+	}
+	return nil
 }
 
 // WriteImage Upload Image to registry
@@ -418,7 +546,7 @@ func (r *SimpleRegistry) WriteImage(ref regname.Reference, img regv1.Image, upda
 	if err := r.validateRef(ref); err != nil {
 		return err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return err
 	}
@@ -432,7 +560,7 @@ func (r *SimpleRegistry) WriteImage(ref regname.Reference, img regv1.Image, upda
 	}
 	err = regremote.Write(overriddenRef, img, opts...)
 	if err != nil {
-		return fmt.Errorf("Writing image: %s", err)
+		return fmt.Errorf("Writing image: %w", translateError(overriddenRef.Context(), true, err))
 	}
 
 	return nil
@@ -443,7 +571,7 @@ func (r *SimpleRegistry) Index(ref regname.Reference) (regv1.ImageIndex, error)
 	if err := r.validateRef(ref); err != nil {
 		return nil, err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return nil, err
 	}
@@ -451,7 +579,8 @@ func (r *SimpleRegistry) Index(ref regname.Reference) (regv1.ImageIndex, error)
 	if err != nil {
 		return nil, err
 	}
-	return regremote.Index(overriddenRef, opts...)
+	idx, err := regremote.Index(overriddenRef, opts...)
+	return idx, translateError(overriddenRef.Context(), false, err)
 }
 
 // WriteIndex Uploads the Index manifest to the registry
@@ -459,7 +588,7 @@ func (r *SimpleRegistry) WriteIndex(ref regname.Reference, idx regv1.ImageIndex)
 	if err := r.validateRef(ref); err != nil {
 		return err
 	}
-	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.ParseReference(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return err
 	}
@@ -471,7 +600,7 @@ func (r *SimpleRegistry) WriteIndex(ref regname.Reference, idx regv1.ImageIndex)
 
 	err = regremote.WriteIndex(overriddenRef, idx, opts...)
 	if err != nil {
-		return fmt.Errorf("Writing image index: %s", err)
+		return fmt.Errorf("Writing image index: %w", translateError(overriddenRef.Context(), true, err))
 	}
 
 	return nil
@@ -482,7 +611,7 @@ func (r *SimpleRegistry) WriteTag(ref regname.Tag, taggagle regremote.Taggable)
 	if err := r.validateRef(ref); err != nil {
 		return err
 	}
-	overriddenRef, err := regname.NewTag(ref.String(), r.refOpts...)
+	overriddenRef, err := regname.NewTag(ref.String(), r.refOptsFor(ref.Context().Registry)...)
 	if err != nil {
 		return err
 	}
@@ -494,7 +623,7 @@ func (r *SimpleRegistry) WriteTag(ref regname.Tag, taggagle regremote.Taggable)
 
 	err = regremote.Tag(overriddenRef, taggagle, opts...)
 	if err != nil {
-		return fmt.Errorf("Tagging image: %s", err)
+		return fmt.Errorf("Tagging image: %w", translateError(overriddenRef.Context(), true, err))
 	}
 
 	return nil
@@ -502,11 +631,11 @@ func (r *SimpleRegistry) WriteTag(ref regname.Tag, taggagle regremote.Taggable)
 
 // ListTags Retrieve all tags associated with a Repository
 func (r *SimpleRegistry) ListTags(repo regname.Repository) ([]string, error) {
-	overriddenRepo, err := regname.NewRepository(repo.Name(), r.refOpts...)
+	overriddenRepo, err := regname.NewRepository(repo.Name(), r.refOptsFor(repo.Registry)...)
 	if err != nil {
 		return nil, err
 	}
-	repoRef, err := regname.ParseReference(overriddenRepo.String(), r.refOpts...)
+	repoRef, err := regname.ParseReference(overriddenRepo.String(), r.refOptsFor(overriddenRepo.Registry)...)
 	if err != nil {
 		return nil, err
 	}
@@ -515,7 +644,8 @@ func (r *SimpleRegistry) ListTags(repo regname.Repository) ([]string, error) {
 		return nil, err
 	}
 
-	return regremote.List(overriddenRepo, opts...)
+	tags, err := regremote.List(overriddenRepo, opts...)
+	return tags, translateError(overriddenRepo, false, err)
 }
 
 // FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
@@ -534,6 +664,116 @@ func (r *SimpleRegistry) FirstImageExists(digests []string) (string, error) {
 	return "", fmt.Errorf("Checking image existence: %s", err)
 }
 
+// BlobExists Checks if the blob (layer or config) is present in the repository of the digest reference
+func (r *SimpleRegistry) BlobExists(ref regname.Digest) (bool, error) {
+	if err := r.validateRef(ref); err != nil {
+		return false, err
+	}
+	overriddenRef, err := regname.NewDigest(ref.String(), r.refOptsFor(ref.Context().Registry)...)
+	if err != nil {
+		return false, err
+	}
+
+	opts, err := r.readOpts(overriddenRef)
+	if err != nil {
+		return false, err
+	}
+	layer, err := regremote.Layer(overriddenRef, opts...)
+	if err != nil {
+		return false, translateError(overriddenRef.Context(), false, err)
+	}
+	exists, err := partial.Exists(layer)
+	return exists, translateError(overriddenRef.Context(), false, err)
+}
+
+// Layer Retrieves the blob (layer or config) of the digest reference, its contents are only fetched when read
+func (r *SimpleRegistry) Layer(ref regname.Digest) (regv1.Layer, error) {
+	if err := r.validateRef(ref); err != nil {
+		return nil, err
+	}
+	overriddenRef, err := regname.NewDigest(ref.String(), r.refOptsFor(ref.Context().Registry)...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := r.readOpts(overriddenRef)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := regremote.Layer(overriddenRef, opts...)
+	return layer, translateError(overriddenRef.Context(), false, err)
+}
+
+// BlobRange Retrieves length bytes of the blob of the digest reference, starting at offset, with an HTTP range request.
+// It fails when the registry answers with the whole blob, because it does not support range requests
+func (r *SimpleRegistry) BlobRange(ref regname.Digest, offset, length int64) (io.ReadCloser, error) {
+	if err := r.validateRef(ref); err != nil {
+		return nil, err
+	}
+	overriddenRef, err := regname.NewDigest(ref.String(), r.refOptsFor(ref.Context().Registry)...)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, _, err := r.transport(overriddenRef, overriddenRef.Scope(transport.PullScope))
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, fmt.Errorf("Range requests are not supported by registries without a keychain")
+	}
+
+	repo := overriddenRef.Context()
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s/v2/%s/blobs/%s", repo.Scheme(), repo.RegistryStr(), repo.RepositoryStr(), overriddenRef.DigestStr()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Expected registry to support range requests, but it returned the whole blob")
+	}
+	if err := transport.CheckError(resp, http.StatusPartialContent); err != nil {
+		resp.Body.Close()
+		return nil, translateError(repo, false, err)
+	}
+	return resp.Body, nil
+}
+
+// Referrers Lists the manifests that refer to the digest reference through their subject, only the ones of artifactType
+// when it is not empty. Registries that do not support the OCI Referrers API are queried using the referrers tag schema
+func (r *SimpleRegistry) Referrers(ref regname.Digest, artifactType string) ([]regv1.Descriptor, error) {
+	if err := r.validateRef(ref); err != nil {
+		return nil, err
+	}
+	overriddenRef, err := regname.NewDigest(ref.String(), r.refOptsFor(ref.Context().Registry)...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := r.readOpts(overriddenRef)
+	if err != nil {
+		return nil, err
+	}
+	if artifactType != "" {
+		opts = append(opts, regremote.WithFilter("artifactType", artifactType))
+	}
+	index, err := regremote.Referrers(overriddenRef, opts...)
+	if err != nil {
+		return nil, translateError(overriddenRef.Context(), false, err)
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	return indexManifest.Manifests, nil
+}
+
 func newHTTPTransport(opts Opts) (*http.Transport, error) {
 	var pool *x509.CertPool
 
@@ -560,6 +800,13 @@ func newHTTPTransport(opts Opts) (*http.Transport, error) {
 		RootCAs:            pool,
 		InsecureSkipVerify: opts.VerifyCerts == false,
 	}
+	if opts.FIPSEnabled() {
+		configureFIPSTLS(clonedDefaultTransport.TLSClientConfig)
+	}
+	configureProxy(clonedDefaultTransport, opts)
+	if opts.Offline {
+		configureOffline(clonedDefaultTransport)
+	}
 
 	return clonedDefaultTransport, nil
 }
@@ -572,3 +819,43 @@ func (SimpleRegistry) validateRef(ref regname.Reference) error {
 	}
 	return nil
 }
+
+// MultiWriteWithContext Writes the images with reg, stopping when ctx is done when reg is a ContextWriter
+func MultiWriteWithContext(ctx context.Context, reg ImagesReaderWriter, imageOrIndexesToUpload map[regname.Reference]regremote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	if ctxWriter, ok := reg.(ContextWriter); ok {
+		return ctxWriter.MultiWriteWithContext(ctx, imageOrIndexesToUpload, concurrency, updatesCh)
+	}
+	return reg.MultiWrite(imageOrIndexesToUpload, concurrency, updatesCh)
+}
+
+// BlobExists Checks if the blob of the digest reference is present with reg, when reg is a BlobChecker
+func BlobExists(reg ImagesReader, ref regname.Digest) (bool, error) {
+	if checker, ok := reg.(BlobChecker); ok {
+		return checker.BlobExists(ref)
+	}
+	return false, fmt.Errorf("Checking if blob '%s' exists: registry does not support checking blobs", ref.Name())
+}
+
+// Layer Retrieves the blob of the digest reference with reg, when reg is a LayerReader
+func Layer(reg ImagesReader, ref regname.Digest) (regv1.Layer, error) {
+	if reader, ok := reg.(LayerReader); ok {
+		return reader.Layer(ref)
+	}
+	return nil, fmt.Errorf("Retrieving blob '%s': registry does not support reading blobs", ref.Name())
+}
+
+// BlobRange Retrieves length bytes of the blob of the digest reference with reg, when reg is a BlobRangeReader
+func BlobRange(reg ImagesReader, ref regname.Digest, offset, length int64) (io.ReadCloser, error) {
+	if reader, ok := reg.(BlobRangeReader); ok {
+		return reader.BlobRange(ref, offset, length)
+	}
+	return nil, fmt.Errorf("Retrieving part of blob '%s': registry does not support reading part of blobs", ref.Name())
+}
+
+// Referrers Lists the manifests that refer to the digest reference with reg, when reg is a ReferrersLister
+func Referrers(reg ImagesReader, ref regname.Digest, artifactType string) ([]regv1.Descriptor, error) {
+	if lister, ok := reg.(ReferrersLister); ok {
+		return lister.Referrers(ref, artifactType)
+	}
+	return nil, fmt.Errorf("Listing referrers of '%s': registry does not support listing referrers", ref.Name())
+}
@@ -4,17 +4,28 @@
 package registry_test
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry/registryfakes"
 	"github.com/google/go-containerregistry/pkg/name"
+	regregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -108,6 +119,733 @@ func TestRegistry_TransportHeaders(t *testing.T) {
 	})
 }
 
+func TestRegistry_TrafficPolicies(t *testing.T) {
+	expectedDigest := "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65"
+
+	t.Run("when a rate limiter is provided, every request waits for it", func(t *testing.T) {
+		server := createServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Docker-Content-Digest", expectedDigest)
+		})
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		var hosts []string
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RateLimiter: registry.RateLimiterFunc(func(_ context.Context, host string) error {
+				hosts = append(hosts, host)
+				return nil
+			}),
+		})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, hosts)
+		for _, host := range hosts {
+			assert.Equal(t, u.Host, host)
+		}
+	})
+
+	t.Run("when the rate limiter fails, the request is not sent", func(t *testing.T) {
+		requestsReceived := 0
+		server := createServer(func(w http.ResponseWriter, r *http.Request) {
+			requestsReceived++
+		})
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RateLimiter: registry.RateLimiterFunc(func(_ context.Context, _ string) error {
+				return errors.New("quota exceeded")
+			}),
+		})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.ErrorContains(t, err, fmt.Sprintf("Waiting for rate limiter of host '%s': quota exceeded", u.Host))
+		assert.Equal(t, 0, requestsReceived)
+	})
+
+	t.Run("when a retry policy is provided, it decides which requests are retried", func(t *testing.T) {
+		manifestRequests := 0
+		server := createServer(func(w http.ResponseWriter, r *http.Request) {
+			manifestRequests++
+			if manifestRequests < 3 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", expectedDigest)
+		})
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		var attempts []int
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RetryPolicy: registry.RetryPolicyFunc(func(host string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+				assert.Equal(t, u.Host, host)
+				if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+					return 0, false
+				}
+				attempts = append(attempts, attempt)
+				return time.Millisecond, true
+			}),
+		})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+		require.NoError(t, err)
+		digest, err := subject.Digest(imgRef)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest.String())
+		assert.Equal(t, []int{1, 2}, attempts)
+		assert.Equal(t, 3, manifestRequests)
+	})
+}
+
+func TestRegistry_Retries(t *testing.T) {
+	handler := regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0)))
+	var lock sync.Mutex
+	failures := map[string]int{}
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		key := r.Method + " " + r.URL.Path
+		if strings.Contains(r.URL.Path, "/blobs/uploads/") {
+			key = r.Method + " uploads"
+		}
+		attempts[key]++
+		fail := failures[key] > 0
+		if fail {
+			failures[key]--
+		}
+		lock.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+	require.NoError(t, err)
+	manifestKey := "GET /v2/repo/manifests/latest"
+
+	setFailures := func(key string, count int) {
+		lock.Lock()
+		defer lock.Unlock()
+		failures = map[string]int{key: count}
+		attempts = map[string]int{}
+	}
+	getAttempts := func(key string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return attempts[key]
+	}
+
+	t.Run("when the registry is rate limiting uploads, they are retried", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{RetryCount: 3, RetryBackoff: time.Millisecond})
+		require.NoError(t, err)
+
+		setFailures("POST uploads", 2)
+		require.NoError(t, subject.WriteImage(imgRef, img, nil))
+	})
+
+	t.Run("when the registry is rate limiting reads of manifests, they are retried with the backoff", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{RetryCount: 3, RetryBackoff: 20 * time.Millisecond})
+		require.NoError(t, err)
+
+		setFailures(manifestKey, 2)
+		start := time.Now()
+		_, err = subject.Image(imgRef)
+		require.NoError(t, err)
+		assert.Equal(t, 3, getAttempts(manifestKey))
+		// 20ms before the first retry and 40ms before the second one
+		assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	})
+
+	t.Run("when a maximum elapsed time is provided, the retries stop once their waits would exceed it", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{RetryCount: 10, RetryBackoff: 20 * time.Millisecond, RetryMaxElapsed: 50 * time.Millisecond})
+		require.NoError(t, err)
+
+		setFailures(manifestKey, 5)
+		_, err = subject.Image(imgRef)
+		require.Error(t, err)
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.TooManyRequestsReason, regErr.Reason)
+		// Waiting 20ms and then 40ms would exceed 50ms, so there is a single retry
+		assert.Equal(t, 2, getAttempts(manifestKey))
+	})
+
+	t.Run("when the backoff is negative, it fails", func(t *testing.T) {
+		_, err := registry.NewSimpleRegistry(registry.Opts{RetryBackoff: -time.Second})
+		require.ErrorContains(t, err, "Expected retry backoff to not be negative, got -1s")
+	})
+}
+
+func TestRegistry_Bandwidth(t *testing.T) {
+	server := httptest.NewServer(regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// Random bytes do not compress, so the layer transferred is slightly bigger than 128 KiB
+	img, err := random.Image(128*1024, 1)
+	require.NoError(t, err)
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+	require.NoError(t, err)
+
+	t.Run("when a maximum upload rate is provided, images are uploaded at that rate", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{MaxUploadRate: 512 * 1024})
+		require.NoError(t, err)
+
+		start := time.Now()
+		require.NoError(t, subject.WriteImage(imgRef, img, nil))
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("when a maximum download rate is provided, images are downloaded at that rate", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{MaxDownloadRate: 512 * 1024})
+		require.NoError(t, err)
+
+		start := time.Now()
+		downloaded, err := subject.Image(imgRef)
+		require.NoError(t, err)
+		layers, err := downloaded.Layers()
+		require.NoError(t, err)
+		reader, err := layers[0].Compressed()
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+}
+
+func TestRegistry_TrafficMeter(t *testing.T) {
+	handler := regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0)))
+	throttle := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if throttle && r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			throttle = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(64*1024, 1)
+	require.NoError(t, err)
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+	require.NoError(t, err)
+
+	t.Run("when a traffic meter is provided, it measures the bytes exchanged with each host", func(t *testing.T) {
+		meter := registry.NewTrafficMeter()
+		retryPolicy := registry.RetryPolicyFunc(func(_ string, attempt int, resp *http.Response, _ error) (time.Duration, bool) {
+			return 0, attempt == 1 && resp != nil && resp.StatusCode == http.StatusTooManyRequests
+		})
+		subject, err := registry.NewSimpleRegistry(registry.Opts{TrafficMeter: meter, RetryPolicy: retryPolicy})
+		require.NoError(t, err)
+
+		require.NoError(t, subject.WriteImage(imgRef, img, nil))
+		uploaded := meter.Host(u.Host)
+		assert.Greater(t, uploaded.BytesSent, int64(64*1024))
+
+		downloaded, err := subject.Image(imgRef)
+		require.NoError(t, err)
+		layers, err := downloaded.Layers()
+		require.NoError(t, err)
+		reader, err := layers[0].Compressed()
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		hosts := meter.Hosts()
+		require.Len(t, hosts, 1)
+		assert.Equal(t, u.Host, hosts[0].Host)
+		assert.Greater(t, hosts[0].BytesReceived, int64(64*1024))
+		assert.Greater(t, hosts[0].Requests, uploaded.Requests)
+		assert.Equal(t, int64(1), hosts[0].ThrottledRequests)
+		assert.Greater(t, hosts[0].Busy, time.Duration(0))
+		assert.Greater(t, hosts[0].BytesPerSecond(), int64(0))
+	})
+}
+
+func TestRegistry_Proxy(t *testing.T) {
+	expectedDigest := "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Header().Set("Docker-Content-Digest", expectedDigest)
+		w.Write([]byte("doesn't matter"))
+	}))
+	defer server.Close()
+
+	var connectTargets, proxyAuthorizations []string
+	var connectLock sync.Mutex
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		connectLock.Lock()
+		connectTargets = append(connectTargets, r.Host)
+		proxyAuthorizations = append(proxyAuthorizations, r.Header.Get("Proxy-Authorization"))
+		connectLock.Unlock()
+
+		// Every tunnel reaches the registry server, whatever the requested host is
+		registryConn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		clientConn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			registryConn.Close()
+			return
+		}
+		go func() {
+			defer registryConn.Close()
+			io.Copy(registryConn, clientConn)
+		}()
+		go func() {
+			defer clientConn.Close()
+			io.Copy(clientConn, registryConn)
+		}()
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	imgRef, err := name.ParseReference("registry.imgpkg.test/repo:latest")
+	require.NoError(t, err)
+
+	t.Run("when a proxy selector and authenticator are provided, the requests go through the selected proxy with the credentials", func(t *testing.T) {
+		connectTargets, proxyAuthorizations = nil, nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			ProxySelector: registry.ProxySelectorFunc(func(req *http.Request) (*url.URL, error) {
+				assert.Equal(t, "registry.imgpkg.test", req.URL.Hostname())
+				return proxyURL, nil
+			}),
+			ProxyAuthenticator: registry.ProxyAuthenticatorFunc(func(_ context.Context, requestedProxy *url.URL, target string) (string, error) {
+				assert.Equal(t, proxyURL.Host, requestedProxy.Host)
+				return "Negotiate " + target, nil
+			}),
+		})
+		require.NoError(t, err)
+
+		digest, err := subject.Digest(imgRef)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest.String())
+
+		require.NotEmpty(t, connectTargets)
+		for i, target := range connectTargets {
+			assert.Equal(t, "registry.imgpkg.test:443", target)
+			assert.Equal(t, "Negotiate registry.imgpkg.test:443", proxyAuthorizations[i])
+		}
+	})
+
+	t.Run("when the proxy selector fails, the request is not sent", func(t *testing.T) {
+		connectTargets, proxyAuthorizations = nil, nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			ProxySelector: registry.ProxySelectorFunc(func(_ *http.Request) (*url.URL, error) {
+				return nil, errors.New("no proxy for you")
+			}),
+			RetryCount: 1,
+		})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.ErrorContains(t, err, "no proxy for you")
+		assert.Empty(t, connectTargets)
+	})
+}
+
+func TestRegistry_ErrorTranslation(t *testing.T) {
+	createErrorServer := func(statusCode int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			w.Write([]byte(body))
+		}))
+	}
+
+	t.Run("when the tag does not exist, it names the repository and suggests listing the tags", func(t *testing.T) {
+		server := createErrorServer(http.StatusNotFound, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.ManifestUnknownReason, regErr.Reason)
+		assert.Equal(t, u.Host, regErr.Registry)
+		assert.Equal(t, "some/repo", regErr.Repository)
+		assert.ErrorContains(t, err, fmt.Sprintf("Unable to find the requested tag or digest in repository 'some/repo' of registry '%s'", u.Host))
+		assert.ErrorContains(t, err, fmt.Sprintf("(hint: check the spelling of the reference, and use 'imgpkg tag list -i %s/some/repo' to see the available tags)", u.Host))
+
+		var tErr *transport.Error
+		require.ErrorAs(t, err, &tErr, "the transport error should still be accessible")
+		assert.Equal(t, http.StatusNotFound, tErr.StatusCode)
+	})
+
+	t.Run("when pushing is denied, it names the repository and suggests checking the credentials", func(t *testing.T) {
+		server := createErrorServer(http.StatusForbidden, `{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		img, err := random.Image(10, 1)
+		require.NoError(t, err)
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		err = subject.WriteImage(imgRef, img, nil)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.PushDeniedReason, regErr.Reason)
+		assert.ErrorContains(t, err, fmt.Sprintf("Pushing to repository 'some/repo' of registry '%s' was denied", u.Host))
+	})
+
+	t.Run("when the registry is rate limiting, it suggests reducing the concurrency", func(t *testing.T) {
+		server := createErrorServer(http.StatusTooManyRequests, `{"errors":[{"code":"TOOMANYREQUESTS","message":"slow down"}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Get(imgRef)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.TooManyRequestsReason, regErr.Reason)
+		assert.ErrorContains(t, err, "--concurrency")
+	})
+
+	t.Run("when a blob is too large, it suggests increasing the maximum upload size", func(t *testing.T) {
+		server := createErrorServer(http.StatusRequestEntityTooLarge, ``)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		img, err := random.Image(10, 1)
+		require.NoError(t, err)
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		err = subject.WriteImage(imgRef, img, nil)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.BlobTooLargeReason, regErr.Reason)
+		assert.Equal(t, "some/repo", regErr.Repository)
+	})
+
+	t.Run("when the token is missing scopes, it explains the scopes GHCR requires", func(t *testing.T) {
+		server := createErrorServer(http.StatusForbidden, `{"errors":[{"code":"DENIED","message":"permission_denied: The token provided does not match expected scopes."}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Get(imgRef)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.TokenScopesReason, regErr.Reason)
+		assert.ErrorContains(t, err, "read:packages")
+	})
+
+	t.Run("when the ECR token expired, it suggests logging in again", func(t *testing.T) {
+		server := createErrorServer(http.StatusForbidden, `{"errors":[{"code":"DENIED","message":"Your authorization token has expired. Reauthenticate and try again."}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Get(imgRef)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.ExpiredTokenReason, regErr.Reason)
+		assert.ErrorContains(t, err, "aws ecr get-login-password")
+	})
+
+	t.Run("when the failure is not a common one, it returns the transport error", func(t *testing.T) {
+		server := createErrorServer(http.StatusBadRequest, `{"errors":[{"code":"NAME_INVALID","message":"invalid name"}]}`)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Get(imgRef)
+
+		var regErr *registry.Error
+		require.False(t, errors.As(err, &regErr))
+		var tErr *transport.Error
+		require.ErrorAs(t, err, &tErr)
+	})
+}
+
+func TestRegistry_FIPS(t *testing.T) {
+	t.Run("when the registry returns a digest that is not FIPS-approved, it reports the artifact", func(t *testing.T) {
+		server := createServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Docker-Content-Digest", "md5:477c34d98f9e090a4441cf82d2f1f03e")
+		})
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{FIPS: true})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+
+		var regErr *registry.Error
+		require.ErrorAs(t, err, &regErr)
+		assert.Equal(t, registry.FIPSNonCompliantReason, regErr.Reason)
+		assert.Equal(t, "some/repo", regErr.Repository)
+		assert.ErrorContains(t, err, "Artifact 'md5:477c34d98f9e090a4441cf82d2f1f03e' uses a digest algorithm that is not FIPS-approved")
+	})
+
+	t.Run("when the registry only supports TLS cipher suites that are not FIPS-approved, it reports the registry", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = &tls.Config{
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+		}
+		server.StartTLS()
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{FIPS: true, VerifyCerts: false})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, fmt.Sprintf("Registry '%s' did not complete a TLS handshake using FIPS-approved algorithms", u.Host))
+	})
+
+	t.Run("when the registry cannot be reached over TLS, it does not report a FIPS error", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		server.Close()
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{FIPS: true, VerifyCerts: false})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "FIPS-approved")
+	})
+
+	t.Run("when the registry only supports TLS 1.3, it is used", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+		server.StartTLS()
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{FIPS: true, VerifyCerts: false})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+	})
+
+	t.Run("when FIPS mode is not enabled, the registry can use any cipher suite", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = &tls.Config{
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+		}
+		server.StartTLS()
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{VerifyCerts: false})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+	})
+}
+
+func TestRegistry_MultiWriteWithContext(t *testing.T) {
+	t.Run("when the registry does not respond before the context is done, the write is stopped", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				return
+			}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		reg, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+		tag, err := name.NewTag(u.Host + "/library/image:latest")
+		require.NoError(t, err)
+		img, err := random.Image(100, 1)
+		require.NoError(t, err)
+
+		started := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err = registry.MultiWriteWithContext(ctx, reg, map[name.Reference]regremote.Taggable{tag: img}, 1, nil)
+		require.Error(t, err)
+		assert.Less(t, time.Since(started), 5*time.Second)
+	})
+}
+
+func TestRegistry_OptionalReaders(t *testing.T) {
+	ref, err := name.NewDigest("registry.example.com/some/repo@sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+	require.NoError(t, err)
+
+	t.Run("when the registry does not implement the optional readers, it errors", func(t *testing.T) {
+		reg := &registryfakes.FakeImagesReader{}
+
+		_, err := registry.BlobExists(reg, ref)
+		require.ErrorContains(t, err, "registry does not support checking blobs")
+		_, err = registry.Layer(reg, ref)
+		require.ErrorContains(t, err, "registry does not support reading blobs")
+		_, err = registry.BlobRange(reg, ref, 0, 10)
+		require.ErrorContains(t, err, "registry does not support reading part of blobs")
+		_, err = registry.Referrers(reg, ref, "")
+		require.ErrorContains(t, err, "registry does not support listing referrers")
+	})
+}
+
+func TestRegistry_Offline(t *testing.T) {
+	requests := 0
+	server := createServer(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+	})
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/some/repo:latest", u.Host))
+	require.NoError(t, err)
+
+	t.Run("when offline mode is enabled, it fails without reaching the registry", func(t *testing.T) {
+		requests = 0
+		subject, err := registry.NewSimpleRegistry(registry.Opts{Offline: true, RetryCount: 3})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, fmt.Sprintf("Expected no network access in offline mode, but a connection to '%s' was attempted", u.Host))
+		assert.Equal(t, 0, requests)
+	})
+
+	t.Run("when offline mode is enabled and a custom transport is provided, it fails without using the transport", func(t *testing.T) {
+		requests = 0
+		subject, err := registry.NewSimpleRegistryWithTransport(registry.Opts{Offline: true}, http.DefaultTransport)
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Expected no network access in offline mode")
+		assert.Equal(t, 0, requests)
+	})
+
+	t.Run("when offline mode is not enabled, it reaches the registry", func(t *testing.T) {
+		requests = 0
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+	})
+}
+
 func createServer(handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	response := []byte("doesn't matter")
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,132 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/logs"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	// DefaultRetryBackoff Time waited before the first retry of a request when no backoff is provided
+	DefaultRetryBackoff = 100 * time.Millisecond
+
+	// retryBackoffCapFactor the time waited doubles after each retry, up to this factor of the first wait
+	retryBackoffCapFactor = 10
+)
+
+// retryStatusCodes Responses of registries that are overloaded, rate limiting or failing temporarily, the requests are retried
+var retryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// newRetryBackoff Returns the backoff of the retries: tries attempts, waiting backoff before the first retry and twice
+// as long before each of the following ones. When maxElapsed is provided, only the retries whose waits add up to at most
+// maxElapsed are attempted
+func newRetryBackoff(tries int, backoff, maxElapsed time.Duration) (regremote.Backoff, error) {
+	if tries < 0 {
+		return regremote.Backoff{}, fmt.Errorf("Expected retry count to not be negative, got %d", tries)
+	}
+	if backoff < 0 {
+		return regremote.Backoff{}, fmt.Errorf("Expected retry backoff to not be negative, got %s", backoff)
+	}
+	if maxElapsed < 0 {
+		return regremote.Backoff{}, fmt.Errorf("Expected retry maximum elapsed time to not be negative, got %s", maxElapsed)
+	}
+
+	if tries == 0 {
+		tries = 1
+	}
+	if backoff == 0 {
+		backoff = DefaultRetryBackoff
+	}
+	result := regremote.Backoff{
+		Duration: backoff,
+		Factor:   2,
+		Jitter:   0,
+		Steps:    tries,
+		Cap:      retryBackoffCapFactor * backoff,
+	}
+
+	if maxElapsed > 0 {
+		steps := 1
+		var elapsed time.Duration
+		for wait := backoff; steps < tries && elapsed+wait <= maxElapsed; wait = min(2*wait, result.Cap) {
+			elapsed += wait
+			steps++
+		}
+		result.Steps = steps
+	}
+	return result, nil
+}
+
+// retryableError Returns true when the operation that failed with err should be retried
+func retryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var tErr *transport.Error
+	var temporary interface{ Temporary() bool }
+	if (errors.As(err, &tErr) && slices.Contains(retryStatusCodes, tErr.StatusCode)) ||
+		(errors.As(err, &temporary) && temporary.Temporary()) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, net.ErrClosed) {
+		logs.Warn.Printf("retrying %v", err)
+		return true
+	}
+	return false
+}
+
+// readRetryRoundTripper Retries the requests that read from the registry, manifests, blobs and tags, when the registry
+// answers with one of the retryStatusCodes. Requests that write are retried by go-containerregistry instead, so that
+// blobs are uploaded again from the start
+type readRetryRoundTripper struct {
+	delegate http.RoundTripper
+	backoff  regremote.Backoff
+}
+
+// RoundTrip Sends the request until the registry does not answer with a retry status code or there are no retries left
+func (r *readRetryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return r.delegate.RoundTrip(req)
+	}
+
+	wait := r.backoff.Duration
+	for attempt := 1; ; attempt++ {
+		resp, err := r.delegate.RoundTrip(req)
+		if err != nil || attempt >= r.backoff.Steps || !slices.Contains(retryStatusCodes, resp.StatusCode) {
+			return resp, err
+		}
+		logs.Warn.Printf("retrying %s %s: unexpected status code %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+
+		if resp.Body != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		wait = min(time.Duration(float64(wait)*r.backoff.Factor), r.backoff.Cap)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// retryBudgetMinAttempts attempts sent to a host before its failure ratio is checked, so that the first failures
+// of a host do not exceed the ratio on their own
+const retryBudgetMinAttempts = 20
+
+// RetryBudgetExceededError Returned, without sending the request, for every request to a registry host once the failed
+// attempts of the requests sent to it exceeded the RetryBudget, so that the operation stops instead of retrying
+// against a host that keeps failing
+type RetryBudgetExceededError struct {
+	Host     string
+	Attempts int
+	Failures int
+	// Limit of the budget that was exceeded
+	Limit string
+	// LastFailure error, or status code, of the last attempt that failed
+	LastFailure string
+}
+
+// Error Describes the limit exceeded, the failures of the host and how to fix them
+func (e RetryBudgetExceededError) Error() string {
+	return fmt.Sprintf("Stopped sending requests to registry '%s' after %s: %d of the %d attempts failed, the last one with: %s (hint: %s)",
+		e.Host, e.Limit, e.Failures, e.Attempts, e.LastFailure, e.hint())
+}
+
+// NonRetryable Stops util.Retry, retrying does not send any request to the host
+func (e RetryBudgetExceededError) NonRetryable() {}
+
+// hint Returns a suggestion of how to fix the failures of the host, based on the last one
+func (e RetryBudgetExceededError) hint() string {
+	switch e.LastFailure {
+	case http.StatusText(http.StatusTooManyRequests):
+		return "the registry is rate limiting the requests, authenticate to the registry to get a higher limit, or reduce the number of parallel requests with --concurrency"
+	case http.StatusText(http.StatusInternalServerError), http.StatusText(http.StatusBadGateway),
+		http.StatusText(http.StatusServiceUnavailable), http.StatusText(http.StatusGatewayTimeout):
+		return "the registry, or a proxy in front of it, is failing, check its health and logs before trying again"
+	default:
+		return "check that the registry host is spelled correctly, and that it is reachable from this machine through the configured proxy and TLS certificates"
+	}
+}
+
+// RetryBudget Limits the failed attempts of the requests sent to each registry host, every failed attempt is retried
+// while the budget of its host lasts. Once it is exceeded, every request to the host fails with RetryBudgetExceededError,
+// instead of being retried for hours against a misconfigured host
+type RetryBudget struct {
+	// MaxRetries when greater than 0, maximum number of failed attempts of the requests sent to a host
+	MaxRetries int
+	// MaxFailureRatio when greater than 0, maximum ratio of failed attempts over all the attempts sent to a host,
+	// checked once retryBudgetMinAttempts were sent
+	MaxFailureRatio float64
+	// FailFast exceeds the budget of a host on its first failed attempt
+	FailFast bool
+
+	lock  sync.Mutex
+	hosts map[string]*hostRetries
+}
+
+type hostRetries struct {
+	attempts    int
+	failures    int
+	lastFailure string
+	exceeded    *RetryBudgetExceededError
+}
+
+// NewRetryBudget Creates a RetryBudget for which no attempt was sent yet
+func NewRetryBudget(maxRetries int, maxFailureRatio float64, failFast bool) *RetryBudget {
+	return &RetryBudget{MaxRetries: maxRetries, MaxFailureRatio: maxFailureRatio, FailFast: failFast}
+}
+
+// check Returns the RetryBudgetExceededError of the host of the URL when its budget was exceeded
+func (b *RetryBudget) check(reqURL *url.URL) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if retries, found := b.hosts[retryBudgetKey(reqURL)]; found && retries.exceeded != nil {
+		return *retries.exceeded
+	}
+	return nil
+}
+
+// record Counts the attempt sent to the host of the URL, and whether it failed with a network error or a retry status code
+func (b *RetryBudget) record(reqURL *url.URL, resp *http.Response, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.hosts == nil {
+		b.hosts = map[string]*hostRetries{}
+	}
+	retries, found := b.hosts[retryBudgetKey(reqURL)]
+	if !found {
+		retries = &hostRetries{}
+		b.hosts[retryBudgetKey(reqURL)] = retries
+	}
+	retries.attempts++
+
+	switch {
+	case err != nil:
+		retries.lastFailure = err.Error()
+	case slices.Contains(retryStatusCodes, resp.StatusCode):
+		retries.lastFailure = http.StatusText(resp.StatusCode)
+	default:
+		return
+	}
+	retries.failures++
+
+	if retries.exceeded != nil {
+		return
+	}
+	limit := ""
+	switch {
+	case b.FailFast:
+		limit = "the first failure (--fail-fast)"
+	case b.MaxRetries > 0 && retries.failures > b.MaxRetries:
+		limit = fmt.Sprintf("exceeding the retry budget of %d failed attempts", b.MaxRetries)
+	case b.MaxFailureRatio > 0 && retries.attempts >= retryBudgetMinAttempts &&
+		float64(retries.failures)/float64(retries.attempts) > b.MaxFailureRatio:
+		limit = fmt.Sprintf("exceeding the failure ratio of %g", b.MaxFailureRatio)
+	default:
+		return
+	}
+	retries.exceeded = &RetryBudgetExceededError{
+		Host:        reqURL.Host,
+		Attempts:    retries.attempts,
+		Failures:    retries.failures,
+		Limit:       limit,
+		LastFailure: retries.lastFailure,
+	}
+}
+
+// retryBudgetRoundTripper Counts the attempts of the requests, below the retries, and fails them without sending
+// them once the RetryBudget of their host is exceeded
+type retryBudgetRoundTripper struct {
+	delegate http.RoundTripper
+	budget   *RetryBudget
+}
+
+// RoundTrip Sends the request while the RetryBudget of its host is not exceeded
+func (r *retryBudgetRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := r.budget.check(req.URL); err != nil {
+		return nil, err
+	}
+	resp, err := r.delegate.RoundTrip(req)
+	r.budget.record(req.URL, resp, err)
+	return resp, err
+}
+
+// retryBudgetKey Returns the scheme and host of the URL, the failures of https do not exceed the budget of the http
+// requests that registries allowed to be insecure fall back to
+func retryBudgetKey(reqURL *url.URL) string {
+	return reqURL.Scheme + "://" + reqURL.Host
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_RetryBudget(t *testing.T) {
+	// The registry is always unavailable, like a destination behind a misconfigured proxy
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// digest Returns the error of getting the digest of two images, the second one after the budget was exceeded by the first one
+	digest := func(t *testing.T, subject registry.Registry) error {
+		var err error
+		for _, tag := range []string{"1.0.0", "2.0.0"} {
+			ref, refErr := name.NewTag(fmt.Sprintf("%s/repo/app:%s", serverURL.Host, tag))
+			require.NoError(t, refErr)
+			_, err = subject.Digest(ref)
+			require.Error(t, err)
+		}
+		return err
+	}
+
+	t.Run("when the failed attempts exceed the budget, the requests to the host fail without being sent", func(t *testing.T) {
+		requests.Store(0)
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RetryCount:   10,
+			RetryBackoff: time.Millisecond,
+			RetryBudget:  registry.NewRetryBudget(3, 0, false),
+		})
+		require.NoError(t, err)
+
+		err = digest(t, subject)
+		assert.Contains(t, err.Error(), fmt.Sprintf("Stopped sending requests to registry '%s' after exceeding the retry budget of 3 failed attempts: 4 of the 4 attempts failed", serverURL.Host))
+		assert.Contains(t, err.Error(), "Service Unavailable (hint: the registry, or a proxy in front of it, is failing")
+		assert.Equal(t, int32(4), requests.Load())
+	})
+
+	t.Run("when the budget is exceeded, the operations retried by util.Retry are not retried", func(t *testing.T) {
+		requests.Store(0)
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RetryCount:   10,
+			RetryBackoff: time.Millisecond,
+			RetryBudget:  registry.NewRetryBudget(3, 0, false),
+		})
+		require.NoError(t, err)
+		ref, err := name.NewTag(fmt.Sprintf("%s/repo/app:1.0.0", serverURL.Host))
+		require.NoError(t, err)
+
+		attempts := 0
+		start := time.Now()
+		err = util.Retry(func() error {
+			attempts++
+			_, err := subject.Digest(ref)
+			return err
+		})
+		assert.ErrorContains(t, err, "Stopped sending requests to registry")
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("when the failed attempts exceed the failure ratio, the requests to the host fail without being sent", func(t *testing.T) {
+		requests.Store(0)
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RetryCount:   30,
+			RetryBackoff: time.Millisecond,
+			RetryBudget:  registry.NewRetryBudget(0, 0.5, false),
+		})
+		require.NoError(t, err)
+
+		err = digest(t, subject)
+		assert.Contains(t, err.Error(), "after exceeding the failure ratio of 0.5: 20 of the 20 attempts failed")
+		assert.Equal(t, int32(20), requests.Load())
+	})
+
+	t.Run("when failing fast, the first failed attempt is not retried", func(t *testing.T) {
+		requests.Store(0)
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			RetryCount:   1,
+			RetryBackoff: time.Millisecond,
+			RetryBudget:  registry.NewRetryBudget(0, 0, true),
+		})
+		require.NoError(t, err)
+
+		err = digest(t, subject)
+		assert.Contains(t, err.Error(), "after the first failure (--fail-fast): 1 of the 1 attempts failed")
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StalledTransferError Returned while reading a response from a registry that did not send any byte for the stall timeout.
+// It is temporary, so the operation is retried, and the connection is closed, so the retry uses a new one
+type StalledTransferError struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (e StalledTransferError) Error() string {
+	return fmt.Sprintf("Transfer of '%s' stalled: no bytes received for %s", e.URL, e.Timeout)
+}
+
+// Temporary Always true, a stalled transfer is retried
+func (e StalledTransferError) Temporary() bool { return true }
+
+// stallDetectingRoundTripper Closes the responses of the requests that read from the registry, manifests and blobs,
+// when no byte is received for timeout, instead of waiting until TCP gives up on the connection
+type stallDetectingRoundTripper struct {
+	delegate http.RoundTripper
+	timeout  time.Duration
+}
+
+// RoundTrip Sends the request and returns the response, whose body fails with StalledTransferError when it stalls
+func (r *stallDetectingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.delegate.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	resp.Body = newStallDetectingReadCloser(resp.Body, r.timeout, req.URL.Redacted())
+	return resp, nil
+}
+
+// stallDetectingReadCloser Closes delegate when no byte is read from it for timeout
+type stallDetectingReadCloser struct {
+	delegate io.ReadCloser
+	timeout  time.Duration
+	url      string
+
+	lock    sync.Mutex
+	stalled bool
+	timer   *time.Timer
+}
+
+func newStallDetectingReadCloser(delegate io.ReadCloser, timeout time.Duration, url string) *stallDetectingReadCloser {
+	r := &stallDetectingReadCloser{delegate: delegate, timeout: timeout, url: url}
+	r.timer = time.AfterFunc(timeout, r.stall)
+	return r
+}
+
+// stall Closes the delegate, unblocking the Read waiting for bytes that are not coming
+func (r *stallDetectingReadCloser) stall() {
+	r.lock.Lock()
+	r.stalled = true
+	r.lock.Unlock()
+	_ = r.delegate.Close()
+}
+
+// Read Reads from delegate, the time without bytes starts again after each byte read
+func (r *stallDetectingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.delegate.Read(p)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.stalled {
+		return n, StalledTransferError{URL: r.url, Timeout: r.timeout}
+	}
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	if err != nil {
+		r.timer.Stop()
+	}
+	return n, err
+}
+
+// Close Stops the stall detection and closes delegate
+func (r *stallDetectingReadCloser) Close() error {
+	r.timer.Stop()
+	return r.delegate.Close()
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	regregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_StallTimeout(t *testing.T) {
+	img, err := random.Image(4096, 1)
+	require.NoError(t, err)
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	// The first download of the layer sends a few bytes and hangs until the connection is closed
+	var stalledDownloads atomic.Int32
+	registryHandler := regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0)))
+	srcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/blobs/"+layerDigest.String()) && stalledDownloads.Add(1) == 1 {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte{0x1f})
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer srcServer.Close()
+	dstServer := httptest.NewServer(regregistry.New(regregistry.Logger(log.New(io.Discard, "", 0))))
+	defer dstServer.Close()
+
+	srcURL, err := url.Parse(srcServer.URL)
+	require.NoError(t, err)
+	dstURL, err := url.Parse(dstServer.URL)
+	require.NoError(t, err)
+	srcRef, err := name.NewTag(fmt.Sprintf("%s/repo/app:latest", srcURL.Host))
+	require.NoError(t, err)
+	dstRef, err := name.NewTag(fmt.Sprintf("%s/repo/app:latest", dstURL.Host))
+	require.NoError(t, err)
+	require.NoError(t, regremote.Write(srcRef, img))
+
+	subject, err := registry.NewSimpleRegistry(registry.Opts{StallTimeout: 200 * time.Millisecond, RetryCount: 3})
+	require.NoError(t, err)
+
+	started := time.Now()
+	srcImg, err := subject.Image(srcRef)
+	require.NoError(t, err)
+	require.NoError(t, subject.WriteImage(dstRef, srcImg, nil))
+	assert.Less(t, time.Since(started), 5*time.Second, "the stalled download should not wait for the server to give up")
+	assert.GreaterOrEqual(t, stalledDownloads.Load(), int32(2), "the layer should be downloaded again after stalling")
+
+	expectedDigest, err := img.Digest()
+	require.NoError(t, err)
+	copiedDigest, err := subject.Digest(dstRef)
+	require.NoError(t, err)
+	assert.Equal(t, expectedDigest, copiedDigest)
+}
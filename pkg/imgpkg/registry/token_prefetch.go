@@ -0,0 +1,119 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// scopedRepository Repository and the scope of the token needed to access it
+type scopedRepository struct {
+	repo  regname.Repository
+	scope string
+}
+
+// PrefetchTokens Authenticates concurrently with every repository, fetching the tokens with the scopes needed to read
+// from pullRepos and to write to pushRepos. The tokens are reused by the following requests, so permission problems
+// are reported before anything is read or written, and hundreds of requests in flight do not request tokens at the same time
+func (r *SimpleRegistry) PrefetchTokens(pullRepos []regname.Repository, pushRepos []regname.Repository, concurrency int) error {
+	if r.keychain == nil {
+		return nil
+	}
+
+	var toFetch []scopedRepository
+	seen := map[string]bool{}
+	pushed := map[string]bool{}
+	for _, repo := range pushRepos {
+		pushed[repo.Name()] = true
+	}
+	addScoped := func(repo regname.Repository, action string) error {
+		overriddenRepo, err := regname.NewRepository(repo.Name(), r.refOptsFor(repo.Registry)...)
+		if err != nil {
+			return err
+		}
+		scope := overriddenRepo.Scope(action)
+		if seen[scope] || r.roundTrippers.RoundTripper(overriddenRepo, scope) != nil {
+			return nil
+		}
+		seen[scope] = true
+		toFetch = append(toFetch, scopedRepository{repo: overriddenRepo, scope: scope})
+		return nil
+	}
+	for _, repo := range pushRepos {
+		if err := addScoped(repo, transport.PushScope); err != nil {
+			return err
+		}
+	}
+	for _, repo := range pullRepos {
+		// The push token also grants pull access
+		if pushed[repo.Name()] {
+			continue
+		}
+		if err := addScoped(repo, transport.PullScope); err != nil {
+			return err
+		}
+	}
+
+	throttle := util.NewThrottle(max(concurrency, 1))
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var problems []string
+	for _, scoped := range toFetch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			throttle.Take()
+			defer throttle.Done()
+
+			err := r.prefetchToken(scoped)
+			if err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				problems = append(problems, fmt.Sprintf("%s: %s", scoped.scope, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("Fetching registry tokens: %d repository scope(s) cannot be accessed:\n- %s", len(problems), strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+// prefetchToken Creates, and stores, the RoundTripper of the repository, which fetches a token with its scope
+func (r *SimpleRegistry) prefetchToken(scoped scopedRepository) error {
+	resolvedAuth, err := r.resolveAuth(scoped.repo)
+	if err != nil {
+		return fmt.Errorf("Unable retrieve credentials for registry: %s", err)
+	}
+
+	_, err = r.roundTrippers.CreateRoundTripper(scoped.repo.Registry, resolvedAuth, scoped.scope)
+	return err
+}
+
+// resolveAuth Resolves, and stores, the authenticator of the repository, holding the same lock as transport
+func (r *SimpleRegistry) resolveAuth(repo regname.Repository) (regauthn.Authenticator, error) {
+	r.transportAccess.Lock()
+	defer r.transportAccess.Unlock()
+	if r.authn == nil {
+		r.authn = map[string]regauthn.Authenticator{}
+	}
+
+	resolvedAuth, err := r.keychain.Resolve(repo)
+	if err != nil {
+		return nil, err
+	}
+	r.authn[repo.Name()] = resolvedAuth
+	return resolvedAuth, nil
+}
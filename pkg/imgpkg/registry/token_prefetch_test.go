@@ -0,0 +1,88 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_PrefetchTokens(t *testing.T) {
+	var lock sync.Mutex
+	var tokenScopes []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			scope := r.URL.Query().Get("scope")
+			lock.Lock()
+			tokenScopes = append(tokenScopes, scope)
+			lock.Unlock()
+			if strings.Contains(scope, "denied") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"some-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer some-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	repo := func(path string) name.Repository {
+		repository, err := name.NewRepository(fmt.Sprintf("%s/%s", u.Host, path))
+		require.NoError(t, err)
+		return repository
+	}
+
+	t.Run("it fetches a token per repository and scope, which is reused by the following requests", func(t *testing.T) {
+		tokenScopes = nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		err = subject.PrefetchTokens(
+			[]name.Repository{repo("source/app"), repo("source/app"), repo("dest/app")},
+			[]name.Repository{repo("dest/app")}, 2)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"repository:source/app:pull", "repository:dest/app:push,pull"}, tokenScopes)
+
+		_, err = subject.Digest(repo("source/app").Tag("latest"))
+		require.NoError(t, err)
+		_, err = subject.Digest(repo("dest/app").Tag("latest"))
+		require.NoError(t, err)
+		assert.Len(t, tokenScopes, 2)
+	})
+
+	t.Run("when a repository cannot be accessed, it reports every repository denied", func(t *testing.T) {
+		tokenScopes = nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		err = subject.PrefetchTokens(
+			[]name.Repository{repo("source/app"), repo("source/denied")},
+			[]name.Repository{repo("dest/denied")}, 2)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Fetching registry tokens: 2 repository scope(s) cannot be accessed:\n- repository:dest/denied:push,pull: ")
+		assert.ErrorContains(t, err, "\n- repository:source/denied:pull: ")
+		assert.NotContains(t, err.Error(), "source/app")
+	})
+}
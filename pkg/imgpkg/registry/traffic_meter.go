@@ -0,0 +1,182 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostTraffic Traffic exchanged with a registry host, measured by a TrafficMeter
+type HostTraffic struct {
+	Host          string
+	BytesSent     int64
+	BytesReceived int64
+	Requests      int64
+	// ThrottledRequests requests that the host answered with 429 Too Many Requests or 503 Service Unavailable
+	ThrottledRequests int64
+	// Busy time during which at least one request to the host was in flight, including the transfer of its body
+	Busy time.Duration
+}
+
+// BytesPerSecond Bytes sent and received per second while the host was busy, 0 when nothing was transferred
+func (h HostTraffic) BytesPerSecond() int64 {
+	if h.Busy <= 0 {
+		return 0
+	}
+	return int64(float64(h.BytesSent+h.BytesReceived) / h.Busy.Seconds())
+}
+
+// TrafficMeter Measures the traffic exchanged with each registry host, so that the throughput of each host can be compared
+type TrafficMeter struct {
+	lock  sync.Mutex
+	hosts map[string]*hostTraffic
+	now   func() time.Time
+}
+
+type hostTraffic struct {
+	HostTraffic
+	inFlight  int
+	busySince time.Time
+}
+
+// NewTrafficMeter Creates a TrafficMeter that did not measure any traffic yet
+func NewTrafficMeter() *TrafficMeter {
+	return &TrafficMeter{hosts: map[string]*hostTraffic{}, now: time.Now}
+}
+
+// Host Returns the traffic measured so far for host
+func (m *TrafficMeter) Host(host string) HostTraffic {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	traffic, found := m.hosts[host]
+	if !found {
+		return HostTraffic{Host: host}
+	}
+	return traffic.snapshot(m.now())
+}
+
+// Hosts Returns the traffic measured so far for every host that received requests, sorted by host
+func (m *TrafficMeter) Hosts() []HostTraffic {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.now()
+	var result []HostTraffic
+	for _, traffic := range m.hosts {
+		result = append(result, traffic.snapshot(now))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Host < result[j].Host })
+	return result
+}
+
+func (t *hostTraffic) snapshot(now time.Time) HostTraffic {
+	result := t.HostTraffic
+	if t.inFlight > 0 {
+		result.Busy += now.Sub(t.busySince)
+	}
+	return result
+}
+
+func (m *TrafficMeter) update(host string, f func(*hostTraffic, time.Time)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	traffic, found := m.hosts[host]
+	if !found {
+		traffic = &hostTraffic{HostTraffic: HostTraffic{Host: host}}
+		m.hosts[host] = traffic
+	}
+	f(traffic, m.now())
+}
+
+func (m *TrafficMeter) started(host string) {
+	m.update(host, func(traffic *hostTraffic, now time.Time) {
+		traffic.Requests++
+		if traffic.inFlight == 0 {
+			traffic.busySince = now
+		}
+		traffic.inFlight++
+	})
+}
+
+func (m *TrafficMeter) finished(host string) {
+	m.update(host, func(traffic *hostTraffic, now time.Time) {
+		traffic.inFlight--
+		if traffic.inFlight == 0 {
+			traffic.Busy += now.Sub(traffic.busySince)
+		}
+	})
+}
+
+// trafficMeterRoundTripper Measures the requests sent, and the bodies of the requests and of their responses, with a TrafficMeter
+type trafficMeterRoundTripper struct {
+	delegate http.RoundTripper
+	meter    *TrafficMeter
+}
+
+// RoundTrip Sends the request and returns the response, whose body is measured while it is read
+func (r *trafficMeterRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = &meteredReadCloser{delegate: req.Body, count: func(n int) {
+			r.meter.update(host, func(traffic *hostTraffic, _ time.Time) { traffic.BytesSent += int64(n) })
+		}}
+	}
+
+	r.meter.started(host)
+	resp, err := r.delegate.RoundTrip(req)
+	if err != nil {
+		r.meter.finished(host)
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		r.meter.update(host, func(traffic *hostTraffic, _ time.Time) { traffic.ThrottledRequests++ })
+	}
+	if resp.Body == nil || resp.Body == http.NoBody {
+		r.meter.finished(host)
+		return resp, nil
+	}
+
+	// The request is in flight until its response is read, blobs are transferred while the body is read
+	once := &sync.Once{}
+	resp.Body = &meteredReadCloser{
+		delegate: resp.Body,
+		count: func(n int) {
+			r.meter.update(host, func(traffic *hostTraffic, _ time.Time) { traffic.BytesReceived += int64(n) })
+		},
+		done: func() { once.Do(func() { r.meter.finished(host) }) },
+	}
+	return resp, nil
+}
+
+type meteredReadCloser struct {
+	delegate io.ReadCloser
+	count    func(int)
+	done     func()
+}
+
+func (m *meteredReadCloser) Read(p []byte) (int, error) {
+	n, err := m.delegate.Read(p)
+	if n > 0 {
+		m.count(n)
+	}
+	if err != nil && m.done != nil {
+		m.done()
+	}
+	return n, err
+}
+
+func (m *meteredReadCloser) Close() error {
+	if m.done != nil {
+		m.done()
+	}
+	return m.delegate.Close()
+}
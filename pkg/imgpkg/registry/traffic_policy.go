@@ -0,0 +1,196 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter Controls the pace of the requests sent to each registry host.
+// Projects that embed imgpkg can provide their own implementation to share the limits with the rest of their registry traffic
+type RateLimiter interface {
+	// Wait Blocks until a request can be sent to host, or returns an error when ctx is done first
+	Wait(ctx context.Context, host string) error
+}
+
+// RateLimiterFunc Adapter that allows a function to be used as a RateLimiter
+type RateLimiterFunc func(ctx context.Context, host string) error
+
+// Wait Calls f(ctx, host)
+func (f RateLimiterFunc) Wait(ctx context.Context, host string) error { return f(ctx, host) }
+
+// RetryPolicy Decides if, and after how long, a request sent to a registry host is retried.
+// When a RetryPolicy is provided it replaces the retries done by default on network errors
+type RetryPolicy interface {
+	// Retry Receives the outcome of the attempt (starting at 1) of a request sent to host
+	// and returns the time to wait before the next attempt, or false when the request should not be retried
+	Retry(host string, attempt int, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// RetryPolicyFunc Adapter that allows a function to be used as a RetryPolicy
+type RetryPolicyFunc func(host string, attempt int, resp *http.Response, err error) (time.Duration, bool)
+
+// Retry Calls f(host, attempt, resp, err)
+func (f RetryPolicyFunc) Retry(host string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	return f(host, attempt, resp, err)
+}
+
+// rateLimitedRoundTripper Waits for the RateLimiter before sending each request
+type rateLimitedRoundTripper struct {
+	delegate http.RoundTripper
+	limiter  RateLimiter
+}
+
+// RoundTrip Sends the request once the RateLimiter allows it
+func (r *rateLimitedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	err := r.limiter.Wait(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("Waiting for rate limiter of host '%s': %s", req.URL.Host, err)
+	}
+	return r.delegate.RoundTrip(req)
+}
+
+// retryPolicyRoundTripper Retries the requests according to the RetryPolicy
+type retryPolicyRoundTripper struct {
+	delegate http.RoundTripper
+	policy   RetryPolicy
+}
+
+// RoundTrip Sends the request until it succeeds or the RetryPolicy stops retrying it
+func (r *retryPolicyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := r.delegate.RoundTrip(req)
+
+		wait, retry := r.policy.Retry(req.URL.Host, attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		// Requests whose body was already consumed cannot be sent again
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// BandwidthLimiter Caps the number of bytes per second transferred by all the requests that share it
+type BandwidthLimiter struct {
+	bytesPerSecond int64
+
+	lock sync.Mutex
+	// next time at which all the bytes reserved so far are within the limit
+	next time.Time
+}
+
+// NewBandwidthLimiter Creates a BandwidthLimiter that allows bytesPerSecond bytes per second
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{bytesPerSecond: bytesPerSecond}
+}
+
+// WaitN Blocks until n bytes can be transferred, or returns an error when ctx is done first
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	l.lock.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bandwidthLimitedRoundTripper Throttles the bodies of the requests sent and of the responses received
+type bandwidthLimitedRoundTripper struct {
+	delegate http.RoundTripper
+	upload   *BandwidthLimiter
+	download *BandwidthLimiter
+}
+
+// RoundTrip Sends the request reading its body, and returns the response whose body can be read, at the pace of the limiters
+func (r *bandwidthLimitedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.upload != nil && req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = &limitedReadCloser{ctx: req.Context(), delegate: req.Body, limiter: r.upload}
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return &limitedReadCloser{ctx: req.Context(), delegate: body, limiter: r.upload}, nil
+			}
+		}
+	}
+
+	resp, err := r.delegate.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if r.download != nil && resp.Body != nil {
+		resp.Body = &limitedReadCloser{ctx: req.Context(), delegate: resp.Body, limiter: r.download}
+	}
+	return resp, nil
+}
+
+// limitedReadChunk maximum number of bytes read at once, so that the transfer is spread evenly over time
+const limitedReadChunk = 32 * 1024
+
+type limitedReadCloser struct {
+	ctx      context.Context
+	delegate io.ReadCloser
+	limiter  *BandwidthLimiter
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if len(p) > limitedReadChunk {
+		p = p[:limitedReadChunk]
+	}
+	n, err := l.delegate.Read(p)
+	if n > 0 {
+		if waitErr := l.limiter.WaitN(l.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.delegate.Close()
+}
@@ -86,14 +86,15 @@ func (r *MultiRoundTripperStorage) RoundTripper(repo regname.Repository, scope s
 //
 //	for more information check https://github.com/distribution/distribution/blob/263da70ea6a4e96f61f7a6770273ec6baac38941/docs/spec/auth/token.md#requesting-a-token
 func (r *MultiRoundTripperStorage) CreateRoundTripper(reg regname.Registry, auth authn.Authenticator, scope string) (http.RoundTripper, error) {
-	r.readWriteAccess.Lock()
-	defer r.readWriteAccess.Unlock()
-
+	// The token is fetched without holding the lock, so that the RoundTripper of several repositories can be created concurrently
 	rt, err := transport.NewWithContext(context.Background(), reg, auth, r.baseRoundTripper, []string{scope})
 	if err != nil {
-		return nil, fmt.Errorf("Unable to create round tripper: %s", err)
+		return nil, fmt.Errorf("Unable to create round tripper: %w", err)
 	}
 
+	r.readWriteAccess.Lock()
+	defer r.readWriteAccess.Unlock()
+
 	if _, ok := r.transports[reg.RegistryStr()]; !ok {
 		r.transports[reg.RegistryStr()] = map[string]map[string]http.RoundTripper{}
 	}
@@ -144,7 +145,7 @@ func (r *SingleTripperStorage) CreateRoundTripper(reg regname.Registry, auth aut
 
 	rt, err := transport.NewWithContext(context.Background(), reg, auth, r.baseRoundTripper, []string{scope})
 	if err != nil {
-		return nil, fmt.Errorf("Unable to create round tripper: %s", err)
+		return nil, fmt.Errorf("Unable to create round tripper: %w", err)
 	}
 
 	r.transport = rt
@@ -5,6 +5,7 @@ package registry
 
 import (
 	"context"
+	"io"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	regname "github.com/google/go-containerregistry/pkg/name"
@@ -49,13 +50,38 @@ func (w *WithProgress) FirstImageExists(digests []string) (string, error) {
 	return w.delegate.FirstImageExists(digests)
 }
 
+// BlobExists Checks if the blob (layer or config) is present in the repository of the digest reference
+func (w *WithProgress) BlobExists(ref regname.Digest) (bool, error) {
+	return BlobExists(w.delegate, ref)
+}
+
+// Layer Retrieves the blob (layer or config) of the digest reference
+func (w *WithProgress) Layer(ref regname.Digest) (regv1.Layer, error) {
+	return Layer(w.delegate, ref)
+}
+
+// BlobRange Retrieves length bytes of the blob of the digest reference, starting at offset
+func (w *WithProgress) BlobRange(ref regname.Digest, offset, length int64) (io.ReadCloser, error) {
+	return BlobRange(w.delegate, ref, offset, length)
+}
+
+// Referrers Lists the manifests that refer to the digest reference through their subject
+func (w *WithProgress) Referrers(ref regname.Digest, artifactType string) ([]regv1.Descriptor, error) {
+	return Referrers(w.delegate, ref, artifactType)
+}
+
 // MultiWrite Upload multiple Images in Parallel to the Registry
-func (w *WithProgress) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, _ chan regv1.Update) error {
+func (w *WithProgress) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	return w.MultiWriteWithContext(context.Background(), imageOrIndexesToUpload, concurrency, updatesCh)
+}
+
+// MultiWriteWithContext Upload multiple Images in Parallel to the Registry, stopping when ctx is done
+func (w *WithProgress) MultiWriteWithContext(ctx context.Context, imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, _ chan regv1.Update) error {
 	uploadProgress := make(chan regv1.Update)
 	w.logger.Start(context.Background(), uploadProgress)
 	defer w.logger.End()
 
-	return w.delegate.MultiWrite(imageOrIndexesToUpload, concurrency, uploadProgress)
+	return MultiWriteWithContext(ctx, w.delegate, imageOrIndexesToUpload, concurrency, uploadProgress)
 }
 
 // WriteImage Upload Image to registry
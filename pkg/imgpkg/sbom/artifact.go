@@ -0,0 +1,108 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// emptyConfig Contents of the config of the artifact, its media type is the one of the SBOM so that the registries,
+// and the clients, that read the artifact type from the config when listing the referrers find the same type
+var emptyConfig = []byte("{}")
+
+// artifactManifest OCI 1.1 manifest, artifactType is not part of regv1.Manifest
+type artifactManifest struct {
+	SchemaVersion int64              `json:"schemaVersion"`
+	MediaType     types.MediaType    `json:"mediaType"`
+	ArtifactType  string             `json:"artifactType"`
+	Config        regv1.Descriptor   `json:"config"`
+	Layers        []regv1.Descriptor `json:"layers"`
+	Subject       *regv1.Descriptor  `json:"subject,omitempty"`
+}
+
+// artifact OCI artifact with the SBOM as its only layer
+type artifact struct {
+	manifest []byte
+	layer    regv1.Layer
+	config   regv1.Layer
+}
+
+// NewArtifact Returns the OCI artifact, with the SBOM doc in the format provided, that refers to subject.
+// Pushed by digest it is discovered through the OCI Referrers API
+func NewArtifact(format Format, doc []byte, subject regv1.Descriptor) (regv1.Image, error) {
+	mediaType := types.MediaType(format.MediaType())
+	layer := static.NewLayer(doc, mediaType)
+	layerDigest, err := layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	config := static.NewLayer(emptyConfig, mediaType)
+	configDigest, err := config.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := json.Marshal(artifactManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  format.MediaType(),
+		Config: regv1.Descriptor{
+			MediaType: mediaType,
+			Size:      int64(len(emptyConfig)),
+			Digest:    configDigest,
+			Data:      emptyConfig,
+		},
+		Layers: []regv1.Descriptor{{
+			MediaType: mediaType,
+			Size:      int64(len(doc)),
+			Digest:    layerDigest,
+		}},
+		Subject: &regv1.Descriptor{MediaType: subject.MediaType, Size: subject.Size, Digest: subject.Digest},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Building the manifest of the SBOM: %s", err)
+	}
+
+	return partial.CompressedToImage(artifact{manifest: manifest, layer: layer, config: config})
+}
+
+// RawConfigFile Returns the empty config of the artifact
+func (a artifact) RawConfigFile() ([]byte, error) {
+	return emptyConfig, nil
+}
+
+// MediaType Returns the media type of the manifest of the artifact
+func (a artifact) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+// RawManifest Returns the manifest of the artifact
+func (a artifact) RawManifest() ([]byte, error) {
+	return a.manifest, nil
+}
+
+// LayerByDigest Returns the SBOM layer, or the config of the artifact
+func (a artifact) LayerByDigest(digest regv1.Hash) (partial.CompressedLayer, error) {
+	layerDigest, err := a.layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if digest == layerDigest {
+		return a.layer, nil
+	}
+	configDigest, err := a.config.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if digest == configDigest {
+		return a.config, nil
+	}
+	return nil, fmt.Errorf("Blob '%s' is not part of the SBOM artifact", digest)
+}
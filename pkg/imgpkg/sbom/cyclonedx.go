@@ -0,0 +1,106 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+)
+
+// cycloneDXDocument CycloneDX 1.5 document (https://cyclonedx.org/docs/1.5/json/), only with the fields imgpkg fills
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies,omitempty"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef  string          `json:"bom-ref,omitempty"`
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Version string          `json:"version,omitempty"`
+	PURL    string          `json:"purl,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+	// Properties name-value pairs, like the annotations of the images in the images lock
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+func cycloneDX(subject Subject) ([]byte, error) {
+	subjectPURL := purl(subject.Ref)
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: subject.created(),
+			Tools: cycloneDXTools{Components: []cycloneDXComponent{
+				{Type: "application", Name: "imgpkg", Version: subject.ToolVersion},
+			}},
+			Component: cycloneDXComponent{
+				BOMRef:  subjectPURL,
+				Type:    "container",
+				Name:    subject.Ref.Context().Name(),
+				Version: subject.Ref.DigestStr(),
+				PURL:    subjectPURL,
+			},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	for _, file := range subject.Files {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			Type: "file",
+			Name: file.Path,
+			Hashes: []cycloneDXHash{
+				{Alg: "SHA-1", Content: file.SHA1},
+				{Alg: "SHA-256", Content: file.SHA256},
+			},
+		})
+	}
+
+	var images []string
+	for _, img := range subject.Images {
+		imgPURL := purl(img)
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef:  imgPURL,
+			Type:    "container",
+			Name:    img.Context().Name(),
+			Version: img.DigestStr(),
+			PURL:    imgPURL,
+		})
+		images = append(images, imgPURL)
+	}
+	if len(images) > 0 {
+		doc.Dependencies = []cycloneDXDependency{{Ref: subjectPURL, DependsOn: images}}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
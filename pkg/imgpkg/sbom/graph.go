@@ -0,0 +1,221 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	// graphTypeProperty property with the type of a node of the graph, bundle or image
+	graphTypeProperty = "imgpkg.carvel.dev/type"
+	// graphSizeProperty property with the size, in bytes, of the layers of a node of the graph
+	graphSizeProperty = "imgpkg.carvel.dev/size"
+)
+
+// GraphNode Bundle, or image, of a bundle graph with the bundles and images it references
+type GraphNode struct {
+	Ref      regname.Digest
+	IsBundle bool
+	// Annotations of the image in the images lock of the bundle that references it
+	Annotations map[string]string
+	// Size sum of the sizes of the layers, 0 when it is not known
+	Size  int64
+	Nodes []GraphNode
+}
+
+// Graph Bundle, and recursively the bundles and images it references, described by the SBOM
+type Graph struct {
+	Root GraphNode
+	// Created time recorded in the SBOM, so that exporting the same graph again results in the same document
+	Created time.Time
+	// ToolVersion version of imgpkg recorded as the tool that created the SBOM
+	ToolVersion string
+}
+
+// graphEdge Reference from a bundle to a bundle, or image, of its images lock
+type graphEdge struct {
+	from, to string
+}
+
+// flatten Returns the nodes of the graph once each, the root first and then sorted by name, and the references between them
+func (g Graph) flatten() ([]GraphNode, []graphEdge) {
+	nodes := map[string]GraphNode{}
+	edges := map[graphEdge]bool{}
+
+	var visit func(node GraphNode)
+	visit = func(node GraphNode) {
+		if visited, found := nodes[node.Ref.Name()]; found {
+			// The annotations come from the images lock of each bundle referencing the node, keep the ones of all of them
+			annotations := map[string]string{}
+			for key, value := range node.Annotations {
+				annotations[key] = value
+			}
+			for key, value := range visited.Annotations {
+				annotations[key] = value
+			}
+			visited.Annotations = annotations
+			nodes[node.Ref.Name()] = visited
+			return
+		}
+		nodes[node.Ref.Name()] = node
+		for _, child := range node.Nodes {
+			edges[graphEdge{from: node.Ref.Name(), to: child.Ref.Name()}] = true
+			visit(child)
+		}
+	}
+	visit(g.Root)
+
+	var sortedNodes []GraphNode
+	for name, node := range nodes {
+		if name != g.Root.Ref.Name() {
+			sortedNodes = append(sortedNodes, node)
+		}
+	}
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Ref.Name() < sortedNodes[j].Ref.Name() })
+
+	var sortedEdges []graphEdge
+	for edge := range edges {
+		sortedEdges = append(sortedEdges, edge)
+	}
+	sort.Slice(sortedEdges, func(i, j int) bool {
+		if sortedEdges[i].from != sortedEdges[j].from {
+			return sortedEdges[i].from < sortedEdges[j].from
+		}
+		return sortedEdges[i].to < sortedEdges[j].to
+	})
+
+	return append([]GraphNode{g.Root}, sortedNodes...), sortedEdges
+}
+
+// properties Returns the type, the size and the annotations of the node, sorted by name
+func (n GraphNode) properties() [][2]string {
+	nodeType := "image"
+	if n.IsBundle {
+		nodeType = "bundle"
+	}
+	properties := [][2]string{{graphTypeProperty, nodeType}}
+	if n.Size > 0 {
+		properties = append(properties, [2]string{graphSizeProperty, strconv.FormatInt(n.Size, 10)})
+	}
+
+	var keys []string
+	for key := range n.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		properties = append(properties, [2]string{key, n.Annotations[key]})
+	}
+	return properties
+}
+
+// GenerateGraph Returns the SBOM of the bundle graph in the format provided, with a package, or component, per
+// bundle and image and the references between them
+func GenerateGraph(format Format, graph Graph) ([]byte, error) {
+	if format == FormatCycloneDX {
+		return cycloneDXGraph(graph)
+	}
+	return spdxGraph(graph)
+}
+
+func spdxGraph(graph Graph) ([]byte, error) {
+	nodes, edges := graph.flatten()
+	created := createdTime(graph.Created)
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              graph.Root.Ref.Name(),
+		DocumentNamespace: "https://carvel.dev/imgpkg/sbom/graph/" + graph.Root.Ref.Name(),
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{"Tool: imgpkg-" + graph.ToolVersion},
+		},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelatedSPDXElement: spdxSubjectID, RelationshipType: "DESCRIBES"},
+		},
+	}
+
+	ids := map[string]string{}
+	for i, node := range nodes {
+		id := spdxSubjectID
+		if i > 0 {
+			id = fmt.Sprintf("SPDXRef-Package-image-%d", i-1)
+		}
+		ids[node.Ref.Name()] = id
+
+		pkg := spdxImagePackage(id, node.Ref.Context().Name(), node.Ref.DigestStr(), purl(node.Ref))
+		for _, property := range node.properties() {
+			pkg.Annotations = append(pkg.Annotations, spdxAnnotation{
+				Annotator:      "Tool: imgpkg-" + graph.ToolVersion,
+				AnnotationDate: created,
+				AnnotationType: "OTHER",
+				Comment:        property[0] + "=" + property[1],
+			})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	for _, edge := range edges {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: ids[edge.from], RelatedSPDXElement: ids[edge.to], RelationshipType: "DEPENDS_ON"})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func cycloneDXGraph(graph Graph) ([]byte, error) {
+	nodes, edges := graph.flatten()
+
+	components := map[string]cycloneDXComponent{}
+	for _, node := range nodes {
+		nodePURL := purl(node.Ref)
+		component := cycloneDXComponent{
+			BOMRef:  nodePURL,
+			Type:    "container",
+			Name:    node.Ref.Context().Name(),
+			Version: node.Ref.DigestStr(),
+			PURL:    nodePURL,
+		}
+		for _, property := range node.properties() {
+			component.Properties = append(component.Properties, cycloneDXProperty{Name: property[0], Value: property[1]})
+		}
+		components[node.Ref.Name()] = component
+	}
+
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: createdTime(graph.Created),
+			Tools: cycloneDXTools{Components: []cycloneDXComponent{
+				{Type: "application", Name: "imgpkg", Version: graph.ToolVersion},
+			}},
+			Component: components[graph.Root.Ref.Name()],
+		},
+		Components: []cycloneDXComponent{},
+	}
+	for _, node := range nodes[1:] {
+		doc.Components = append(doc.Components, components[node.Ref.Name()])
+	}
+
+	for _, edge := range edges {
+		from, to := components[edge.from].BOMRef, components[edge.to].BOMRef
+		if len(doc.Dependencies) == 0 || doc.Dependencies[len(doc.Dependencies)-1].Ref != from {
+			doc.Dependencies = append(doc.Dependencies, cycloneDXDependency{Ref: from})
+		}
+		dependency := &doc.Dependencies[len(doc.Dependencies)-1]
+		dependency.DependsOn = append(dependency.DependsOn, to)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
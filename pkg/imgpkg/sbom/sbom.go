@@ -0,0 +1,246 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sbom generates the Software Bill of Materials of the images and bundles pushed, listing their files
+// and, for bundles, the images they reference, so that it can be attached to them for downstream scanners. It also
+// exports the graph of the bundles and images referenced by a bundle, for the SBOM tooling of organizations
+package sbom
+
+import (
+	"archive/tar"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Format Format of the SBOM
+type Format string
+
+const (
+	// FormatSPDX SPDX 2.3 JSON document
+	FormatSPDX Format = "spdx"
+	// FormatCycloneDX CycloneDX 1.5 JSON document
+	FormatCycloneDX Format = "cyclonedx"
+)
+
+// imagesLockPath location of the images lock in the layer of a bundle
+const imagesLockPath = ".imgpkg/images.yml"
+
+// ParseFormat Parses the format of the SBOM, one of spdx or cyclonedx
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(value); format {
+	case FormatSPDX, FormatCycloneDX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("Unknown SBOM format '%s' (supported: %s, %s)", value, FormatSPDX, FormatCycloneDX)
+	}
+}
+
+// MediaType Media type of the documents with this format, also used as the artifact type of the SBOMs attached
+func (f Format) MediaType() string {
+	if f == FormatCycloneDX {
+		return "application/vnd.cyclonedx+json"
+	}
+	return "application/spdx+json"
+}
+
+// File Regular file of the image
+type File struct {
+	Path   string
+	SHA1   string
+	SHA256 string
+}
+
+// Subject Image, or bundle, described by the SBOM, with its files and the images it references
+type Subject struct {
+	Ref    regname.Digest
+	Files  []File
+	Images []regname.Digest
+	// Created time recorded in the SBOM, so that building it again results in the same document
+	Created time.Time
+	// ToolVersion version of imgpkg recorded as the tool that created the SBOM
+	ToolVersion string
+}
+
+// NewSubject Reads the files of the layers of img and, when it is a bundle, the images of its images lock
+func NewSubject(ref regname.Digest, img regv1.Image) (Subject, error) {
+	subject := Subject{Ref: ref}
+	layers, err := img.Layers()
+	if err != nil {
+		return Subject{}, err
+	}
+
+	files := map[string]File{}
+	parts := map[string]*filePart{}
+	for _, layer := range layers {
+		err = readLayer(layer, files, parts, &subject)
+		if err != nil {
+			return Subject{}, err
+		}
+	}
+	for name := range parts {
+		return Subject{}, fmt.Errorf("Expected the layers to have all the parts of file '%s'", name)
+	}
+	for _, file := range files {
+		subject.Files = append(subject.Files, file)
+	}
+	sort.Slice(subject.Files, func(i, j int) bool { return subject.Files[i].Path < subject.Files[j].Path })
+	return subject, nil
+}
+
+// filePart Checksums of the parts read of a file split across layers, added to the files once all its parts are read
+type filePart struct {
+	sha1Hash   hash.Hash
+	sha256Hash hash.Hash
+}
+
+// readLayer Adds the regular files of the layer to files, the ones of later layers replace the ones of earlier layers
+func readLayer(layer regv1.Layer, files map[string]File, parts map[string]*filePart, subject *Subject) error {
+	contents, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer contents.Close()
+
+	tarReader := tar.NewReader(contents)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Reading layer: %s", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != ctlimg.LayerPartTypeflag {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		position, isPart, err := ctlimg.LayerFilePart(header)
+		if err != nil {
+			return err
+		}
+		if isPart {
+			err = readFilePart(name, position, tarReader, files, parts)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// SPDX requires the SHA1 checksum of the files, next to any other checksum
+		sha1Hash := sha1.New()
+		sha256Hash := sha256.New()
+		var writer io.Writer = io.MultiWriter(sha1Hash, sha256Hash)
+
+		var lockContents strings.Builder
+		if name == imagesLockPath {
+			writer = io.MultiWriter(writer, &lockContents)
+		}
+		_, err = io.Copy(writer, tarReader)
+		if err != nil {
+			return fmt.Errorf("Reading file '%s': %s", name, err)
+		}
+		files[name] = File{Path: name, SHA1: hexSum(sha1Hash), SHA256: hexSum(sha256Hash)}
+
+		if name == imagesLockPath {
+			subject.Images, err = lockImages([]byte(lockContents.String()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readFilePart Adds the part of the file to its checksums, and adds the file to files when it is the last part.
+// The parts are in consecutive layers, in order
+func readFilePart(name, position string, contents io.Reader, files map[string]File, parts map[string]*filePart) error {
+	part, found := parts[name]
+	switch {
+	case position == ctlimg.LayerPartFirst:
+		part = &filePart{sha1Hash: sha1.New(), sha256Hash: sha256.New()}
+		parts[name] = part
+	case !found:
+		return fmt.Errorf("Expected the parts of file '%s' to start with the first part", name)
+	}
+
+	_, err := io.Copy(io.MultiWriter(part.sha1Hash, part.sha256Hash), contents)
+	if err != nil {
+		return fmt.Errorf("Reading file '%s': %s", name, err)
+	}
+
+	if position == ctlimg.LayerPartLast {
+		files[name] = File{Path: name, SHA1: hexSum(part.sha1Hash), SHA256: hexSum(part.sha256Hash)}
+		delete(parts, name)
+	}
+	return nil
+}
+
+// lockImages Returns the images of the images lock, once each and sorted so that the SBOM does not depend on their order
+func lockImages(contents []byte) ([]regname.Digest, error) {
+	imagesLock, err := lockconfig.NewImagesLockFromBytes(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Reading the images lock of the bundle: %s", err)
+	}
+	var images []regname.Digest
+	found := map[string]bool{}
+	for _, img := range imagesLock.Images {
+		ref, err := regname.NewDigest(img.Image)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing '%s': %s", img.Image, err)
+		}
+		if found[ref.Name()] {
+			continue
+		}
+		found[ref.Name()] = true
+		images = append(images, ref)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Name() < images[j].Name() })
+	return images, nil
+}
+
+// Generate Returns the SBOM of the subject in the format provided
+func Generate(format Format, subject Subject) ([]byte, error) {
+	if format == FormatCycloneDX {
+		return cycloneDX(subject)
+	}
+	return spdx(subject)
+}
+
+// created Returns the time recorded in the SBOM, the Unix epoch when the subject does not have one
+func (s Subject) created() string {
+	return createdTime(s.Created)
+}
+
+// createdTime Formats the time recorded in the SBOM, the Unix epoch when it is not set
+func createdTime(created time.Time) string {
+	if created.IsZero() {
+		created = time.Unix(0, 0)
+	}
+	return created.UTC().Format(time.RFC3339)
+}
+
+// purl Returns the package URL of the OCI image (https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#oci)
+func purl(ref regname.Digest) string {
+	repo := ref.Context()
+	name := path.Base(repo.RepositoryStr())
+	return fmt.Sprintf("pkg:oci/%s@%s?repository_url=%s", name, strings.ReplaceAll(ref.DigestStr(), ":", "%3A"), url.QueryEscape(repo.Name()))
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
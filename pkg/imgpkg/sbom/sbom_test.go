@@ -0,0 +1,292 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/sbom"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	bundleRef = "registry.corp/org/app-bundle@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	imageRef  = "registry.corp/org/app@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+	nestedBundleRef = "registry.corp/org/nested-bundle@sha256:3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+func TestNewSubject(t *testing.T) {
+	t.Run("it lists the files with their checksums and the images of the images lock", func(t *testing.T) {
+		subject := newTestSubject(t)
+
+		require.Len(t, subject.Files, 2)
+		assert.Equal(t, ".imgpkg/images.yml", subject.Files[0].Path)
+		sha1Sum := sha1.Sum([]byte("foo: bar"))
+		sha256Sum := sha256.Sum256([]byte("foo: bar"))
+		assert.Equal(t, sbom.File{Path: "config.yml", SHA1: hex.EncodeToString(sha1Sum[:]), SHA256: hex.EncodeToString(sha256Sum[:])}, subject.Files[1])
+		require.Len(t, subject.Images, 1, "the images referenced more than once are listed once")
+		assert.Equal(t, imageRef, subject.Images[0].Name())
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	subject := newTestSubject(t)
+	subject.Created = time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	subject.ToolVersion = "0.1.0"
+
+	t.Run("it generates an SPDX document describing the files and depending on the images", func(t *testing.T) {
+		doc, err := sbom.Generate(sbom.FormatSPDX, subject)
+		require.NoError(t, err)
+
+		var spdx struct {
+			SPDXVersion  string `json:"spdxVersion"`
+			CreationInfo struct {
+				Created  string   `json:"created"`
+				Creators []string `json:"creators"`
+			} `json:"creationInfo"`
+			Packages []struct {
+				Name         string `json:"name"`
+				VersionInfo  string `json:"versionInfo"`
+				ExternalRefs []struct {
+					ReferenceLocator string `json:"referenceLocator"`
+				} `json:"externalRefs"`
+			} `json:"packages"`
+			Files []struct {
+				FileName string `json:"fileName"`
+			} `json:"files"`
+			Relationships []struct {
+				RelationshipType string `json:"relationshipType"`
+			} `json:"relationships"`
+		}
+		require.NoError(t, json.Unmarshal(doc, &spdx))
+		assert.Equal(t, "SPDX-2.3", spdx.SPDXVersion)
+		assert.Equal(t, "2024-01-31T10:00:00Z", spdx.CreationInfo.Created)
+		assert.Equal(t, []string{"Tool: imgpkg-0.1.0"}, spdx.CreationInfo.Creators)
+		require.Len(t, spdx.Packages, 2)
+		assert.Equal(t, "registry.corp/org/app-bundle", spdx.Packages[0].Name)
+		assert.Equal(t, "pkg:oci/app-bundle@sha256%3A1111111111111111111111111111111111111111111111111111111111111111?repository_url=registry.corp%2Forg%2Fapp-bundle",
+			spdx.Packages[0].ExternalRefs[0].ReferenceLocator)
+		assert.Equal(t, "registry.corp/org/app", spdx.Packages[1].Name)
+		require.Len(t, spdx.Files, 2)
+		assert.Equal(t, "./config.yml", spdx.Files[1].FileName)
+		require.Len(t, spdx.Relationships, 4)
+		assert.Equal(t, "DESCRIBES", spdx.Relationships[0].RelationshipType)
+		assert.Equal(t, "DEPENDS_ON", spdx.Relationships[3].RelationshipType)
+	})
+
+	t.Run("it generates a CycloneDX document with the files and the images as components", func(t *testing.T) {
+		doc, err := sbom.Generate(sbom.FormatCycloneDX, subject)
+		require.NoError(t, err)
+
+		var cycloneDX struct {
+			BOMFormat   string `json:"bomFormat"`
+			SpecVersion string `json:"specVersion"`
+			Metadata    struct {
+				Timestamp string `json:"timestamp"`
+			} `json:"metadata"`
+			Components []struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			} `json:"components"`
+			Dependencies []struct {
+				DependsOn []string `json:"dependsOn"`
+			} `json:"dependencies"`
+		}
+		require.NoError(t, json.Unmarshal(doc, &cycloneDX))
+		assert.Equal(t, "CycloneDX", cycloneDX.BOMFormat)
+		assert.Equal(t, "1.5", cycloneDX.SpecVersion)
+		assert.Equal(t, "2024-01-31T10:00:00Z", cycloneDX.Metadata.Timestamp)
+		require.Len(t, cycloneDX.Components, 3)
+		assert.Equal(t, "file", cycloneDX.Components[1].Type)
+		assert.Equal(t, "config.yml", cycloneDX.Components[1].Name)
+		assert.Equal(t, "container", cycloneDX.Components[2].Type)
+		require.Len(t, cycloneDX.Dependencies, 1)
+		assert.Len(t, cycloneDX.Dependencies[0].DependsOn, 1)
+	})
+
+	t.Run("it always generates the same document", func(t *testing.T) {
+		first, err := sbom.Generate(sbom.FormatSPDX, subject)
+		require.NoError(t, err)
+		second, err := sbom.Generate(sbom.FormatSPDX, newTestSubjectWithTime(t, subject.Created, subject.ToolVersion))
+		require.NoError(t, err)
+		assert.Equal(t, string(first), string(second))
+	})
+}
+
+func TestGenerateGraph(t *testing.T) {
+	image := sbom.GraphNode{Ref: regname.MustParseReference(imageRef).(regname.Digest), Annotations: map[string]string{"kbld.carvel.dev/id": "app:1.0"}, Size: 1024}
+	graph := sbom.Graph{
+		Root: sbom.GraphNode{
+			Ref:      regname.MustParseReference(bundleRef).(regname.Digest),
+			IsBundle: true,
+			Size:     512,
+			Nodes: []sbom.GraphNode{
+				{Ref: regname.MustParseReference(nestedBundleRef).(regname.Digest), IsBundle: true, Nodes: []sbom.GraphNode{image}},
+				image,
+			},
+		},
+		Created:     time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+		ToolVersion: "0.1.0",
+	}
+
+	t.Run("it generates an SPDX document with a package per bundle and image, and their relationships", func(t *testing.T) {
+		doc, err := sbom.GenerateGraph(sbom.FormatSPDX, graph)
+		require.NoError(t, err)
+
+		var spdx struct {
+			Packages []struct {
+				SPDXID      string `json:"SPDXID"`
+				Name        string `json:"name"`
+				Annotations []struct {
+					Comment string `json:"comment"`
+				} `json:"annotations"`
+			} `json:"packages"`
+			Relationships []struct {
+				SPDXElementID      string `json:"spdxElementId"`
+				RelatedSPDXElement string `json:"relatedSpdxElement"`
+				RelationshipType   string `json:"relationshipType"`
+			} `json:"relationships"`
+		}
+		require.NoError(t, json.Unmarshal(doc, &spdx))
+		require.Len(t, spdx.Packages, 3, "the images referenced more than once are listed once")
+		assert.Equal(t, "registry.corp/org/app-bundle", spdx.Packages[0].Name)
+		assert.Equal(t, "registry.corp/org/app", spdx.Packages[1].Name)
+		assert.Equal(t, "registry.corp/org/nested-bundle", spdx.Packages[2].Name)
+		require.Len(t, spdx.Packages[1].Annotations, 3)
+		assert.Equal(t, "imgpkg.carvel.dev/type=image", spdx.Packages[1].Annotations[0].Comment)
+		assert.Equal(t, "imgpkg.carvel.dev/size=1024", spdx.Packages[1].Annotations[1].Comment)
+		assert.Equal(t, "kbld.carvel.dev/id=app:1.0", spdx.Packages[1].Annotations[2].Comment)
+
+		require.Len(t, spdx.Relationships, 4)
+		assert.Equal(t, "DESCRIBES", spdx.Relationships[0].RelationshipType)
+		for _, relationship := range spdx.Relationships[1:] {
+			assert.Equal(t, "DEPENDS_ON", relationship.RelationshipType)
+		}
+		assert.Equal(t, spdx.Packages[2].SPDXID, spdx.Relationships[3].SPDXElementID)
+		assert.Equal(t, spdx.Packages[1].SPDXID, spdx.Relationships[3].RelatedSPDXElement)
+	})
+
+	t.Run("it generates a CycloneDX document with a component per bundle and image, and their dependencies", func(t *testing.T) {
+		doc, err := sbom.GenerateGraph(sbom.FormatCycloneDX, graph)
+		require.NoError(t, err)
+
+		var cycloneDX struct {
+			Metadata struct {
+				Component struct {
+					BOMRef string `json:"bom-ref"`
+				} `json:"component"`
+			} `json:"metadata"`
+			Components []struct {
+				BOMRef     string `json:"bom-ref"`
+				Name       string `json:"name"`
+				Properties []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"properties"`
+			} `json:"components"`
+			Dependencies []struct {
+				Ref       string   `json:"ref"`
+				DependsOn []string `json:"dependsOn"`
+			} `json:"dependencies"`
+		}
+		require.NoError(t, json.Unmarshal(doc, &cycloneDX))
+		require.Len(t, cycloneDX.Components, 2)
+		assert.Equal(t, "registry.corp/org/app", cycloneDX.Components[0].Name)
+		assert.Equal(t, "imgpkg.carvel.dev/size", cycloneDX.Components[0].Properties[1].Name)
+		assert.Equal(t, "1024", cycloneDX.Components[0].Properties[1].Value)
+		assert.Equal(t, "registry.corp/org/nested-bundle", cycloneDX.Components[1].Name)
+		assert.Equal(t, "bundle", cycloneDX.Components[1].Properties[0].Value)
+
+		require.Len(t, cycloneDX.Dependencies, 2)
+		assert.Equal(t, cycloneDX.Metadata.Component.BOMRef, cycloneDX.Dependencies[0].Ref)
+		assert.Equal(t, []string{cycloneDX.Components[0].BOMRef, cycloneDX.Components[1].BOMRef}, cycloneDX.Dependencies[0].DependsOn)
+		assert.Equal(t, cycloneDX.Components[1].BOMRef, cycloneDX.Dependencies[1].Ref)
+		assert.Equal(t, []string{cycloneDX.Components[0].BOMRef}, cycloneDX.Dependencies[1].DependsOn)
+	})
+}
+
+func TestNewArtifact(t *testing.T) {
+	subject := regv1.Descriptor{MediaType: "application/vnd.oci.image.manifest.v1+json", Size: 100, Digest: regv1.Hash{Algorithm: "sha256", Hex: "1111111111111111111111111111111111111111111111111111111111111111"}}
+	artifact, err := sbom.NewArtifact(sbom.FormatCycloneDX, []byte(`{"bomFormat":"CycloneDX"}`), subject)
+	require.NoError(t, err)
+
+	rawManifest, err := artifact.RawManifest()
+	require.NoError(t, err)
+	var manifest struct {
+		ArtifactType string `json:"artifactType"`
+		Config       struct {
+			MediaType string `json:"mediaType"`
+		} `json:"config"`
+		Subject struct {
+			Digest string `json:"digest"`
+		} `json:"subject"`
+	}
+	require.NoError(t, json.Unmarshal(rawManifest, &manifest))
+	assert.Equal(t, "application/vnd.cyclonedx+json", manifest.ArtifactType)
+	assert.Equal(t, "application/vnd.cyclonedx+json", manifest.Config.MediaType)
+	assert.Equal(t, subject.Digest.String(), manifest.Subject.Digest)
+
+	layers, err := artifact.Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	contents, err := layers[0].Compressed()
+	require.NoError(t, err)
+	defer contents.Close()
+	doc, err := io.ReadAll(contents)
+	require.NoError(t, err)
+	assert.Equal(t, `{"bomFormat":"CycloneDX"}`, string(doc))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := sbom.ParseFormat("cyclonedx")
+	require.NoError(t, err)
+	assert.Equal(t, sbom.FormatCycloneDX, format)
+
+	_, err = sbom.ParseFormat("swid")
+	require.EqualError(t, err, "Unknown SBOM format 'swid' (supported: spdx, cyclonedx)")
+}
+
+func newTestSubject(t *testing.T) sbom.Subject {
+	return newTestSubjectWithTime(t, time.Time{}, "")
+}
+
+func newTestSubjectWithTime(t *testing.T, created time.Time, toolVersion string) sbom.Subject {
+	imagesLock := "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: ImagesLock\nimages:\n- image: " + imageRef + "\n- image: " + imageRef + "\n"
+	layerContents := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(layerContents)
+	for _, file := range []struct{ name, contents string }{{"config.yml", "foo: bar"}, {".imgpkg/images.yml", imagesLock}} {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: file.name, Size: int64(len(file.contents)), Mode: 0600}))
+		_, err := tarWriter.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(layerContents.Bytes())), nil
+	})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+
+	subject, err := sbom.NewSubject(regname.MustParseReference(bundleRef).(regname.Digest), img)
+	require.NoError(t, err)
+	subject.Created = created
+	subject.ToolVersion = toolVersion
+	return subject
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// spdxDocument SPDX 2.3 document (https://spdx.github.io/spdx-spec/v2.3/), only with the fields imgpkg fills
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+	Annotations      []spdxAnnotation  `json:"annotations,omitempty"`
+}
+
+type spdxAnnotation struct {
+	Annotator      string `json:"annotator"`
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Comment        string `json:"comment"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxFile struct {
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+	RelationshipType   string `json:"relationshipType"`
+}
+
+const spdxSubjectID = "SPDXRef-Package-subject"
+
+func spdx(subject Subject) ([]byte, error) {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject.Ref.Name(),
+		DocumentNamespace: "https://carvel.dev/imgpkg/sbom/" + subject.Ref.Name(),
+		CreationInfo: spdxCreationInfo{
+			Created:  subject.created(),
+			Creators: []string{"Tool: imgpkg-" + subject.ToolVersion},
+		},
+		Packages: []spdxPackage{spdxImagePackage(spdxSubjectID, subject.Ref.Context().Name(), subject.Ref.DigestStr(), purl(subject.Ref))},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelatedSPDXElement: spdxSubjectID, RelationshipType: "DESCRIBES"},
+		},
+	}
+
+	for i, file := range subject.Files {
+		id := fmt.Sprintf("SPDXRef-File-%d", i)
+		doc.Files = append(doc.Files, spdxFile{
+			FileName: "./" + file.Path,
+			SPDXID:   id,
+			Checksums: []spdxChecksum{
+				{Algorithm: "SHA1", ChecksumValue: file.SHA1},
+				{Algorithm: "SHA256", ChecksumValue: file.SHA256},
+			},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: spdxSubjectID, RelatedSPDXElement: id, RelationshipType: "CONTAINS"})
+	}
+
+	for i, img := range subject.Images {
+		id := fmt.Sprintf("SPDXRef-Package-image-%d", i)
+		doc.Packages = append(doc.Packages, spdxImagePackage(id, img.Context().Name(), img.DigestStr(), purl(img)))
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: spdxSubjectID, RelatedSPDXElement: id, RelationshipType: "DEPENDS_ON"})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func spdxImagePackage(id, name, digest, purl string) spdxPackage {
+	return spdxPackage{
+		SPDXID:           id,
+		Name:             name,
+		VersionInfo:      digest,
+		DownloadLocation: "NOASSERTION",
+		ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl}},
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// CosignBackend Signatures, attestations and SBOMs that cosign attaches to images using tags
+	CosignBackend = "cosign"
+	// NotationBackend Notation signatures discovered using the OCI Referrers API
+	NotationBackend = "notation"
+	// ReferrersBackend Every artifact that refers to the images, discovered using the OCI Referrers API
+	ReferrersBackend = "referrers"
+
+	// tagSuffixBackendPrefix Prefix of the backends that find the artifacts attached to images with a tag
+	// named after the image digest and the suffix, like cosign does (example: tag-suffix:intoto)
+	tagSuffixBackendPrefix = "tag-suffix:"
+)
+
+// Registry Interface that knows how to find the artifacts associated with images in a registry.
+// The backends that use the OCI Referrers API need a Registry that is also a ReferrersReader
+type Registry interface {
+	DigestReader
+}
+
+// Backend Signing scheme whose artifacts are found and copied together with the images
+type Backend interface {
+	// Name Identifies the backend when selecting it
+	Name() string
+	// Fetcher Constructs the Signature Fetcher that retrieves the artifacts of this scheme from reg
+	Fetcher(reg Registry, concurrency int) Fetcher
+}
+
+// BackendFunc Backend implemented by a function
+type BackendFunc struct {
+	BackendName string
+	NewFetcher  func(reg Registry, concurrency int) Fetcher
+}
+
+// Name Identifies the backend when selecting it
+func (b BackendFunc) Name() string { return b.BackendName }
+
+// Fetcher Constructs the Signature Fetcher that retrieves the artifacts of this scheme from reg
+func (b BackendFunc) Fetcher(reg Registry, concurrency int) Fetcher {
+	return b.NewFetcher(reg, concurrency)
+}
+
+var (
+	tagSuffixRegexp = regexp.MustCompile(`^[a-z0-9]+$`)
+
+	backendsLock = &sync.Mutex{}
+	backends     = map[string]Backend{}
+)
+
+func init() {
+	RegisterBackend(BackendFunc{BackendName: CosignBackend, NewFetcher: func(reg Registry, concurrency int) Fetcher {
+		return NewSignaturesWithFinders(NewCosignFinders(reg), concurrency)
+	}})
+	RegisterBackend(BackendFunc{BackendName: NotationBackend, NewFetcher: func(reg Registry, concurrency int) Fetcher {
+		return NewNotation(referrersReader(reg), concurrency)
+	}})
+	RegisterBackend(BackendFunc{BackendName: ReferrersBackend, NewFetcher: func(reg Registry, concurrency int) Fetcher {
+		return NewReferrers(referrersReader(reg), "", concurrency)
+	}})
+}
+
+// RegisterBackend Makes backend available to NewFetcherForBackends, replacing the backend with the same name
+func RegisterBackend(backend Backend) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	backends[backend.Name()] = backend
+}
+
+// BackendNames Names of the registered backends, sorted
+func BackendNames() []string {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewFetcherForBackends Constructs a Signature Fetcher that retrieves the artifacts of all the backends named.
+// Besides the registered backends, tag-suffix:<suffix> finds the artifacts attached to the images with a tag
+// named after the image digest and suffix, like cosign does. Without names a fetcher that does nothing is returned
+func NewFetcherForBackends(names []string, reg Registry, concurrency int) (Fetcher, error) {
+	var fetchers []Fetcher
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		backend, err := findBackend(name)
+		if err != nil {
+			return nil, err
+		}
+		fetchers = append(fetchers, backend.Fetcher(reg, concurrency))
+	}
+
+	switch len(fetchers) {
+	case 0:
+		return NewNoop(), nil
+	case 1:
+		return fetchers[0], nil
+	default:
+		return NewCombined(fetchers...), nil
+	}
+}
+
+// CheckBackends Returns an error when one of the names is not a known backend
+func CheckBackends(names []string) error {
+	for _, name := range names {
+		if _, err := findBackend(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findBackend(name string) (Backend, error) {
+	if suffix, found := strings.CutPrefix(name, tagSuffixBackendPrefix); found {
+		if !tagSuffixRegexp.MatchString(suffix) {
+			return nil, fmt.Errorf("Expected signature backend '%s' to have a tag suffix made of lowercase letters and digits", name)
+		}
+		return BackendFunc{BackendName: name, NewFetcher: func(reg Registry, concurrency int) Fetcher {
+			return NewSignatures(&Cosign{registry: reg, tagSuffix: suffix}, concurrency)
+		}}, nil
+	}
+
+	backendsLock.Lock()
+	backend, found := backends[name]
+	backendsLock.Unlock()
+	if !found {
+		return nil, fmt.Errorf("Unknown signature backend '%s' (expected one of: %s, %s<suffix>)",
+			name, strings.Join(BackendNames(), ", "), tagSuffixBackendPrefix)
+	}
+	return backend, nil
+}
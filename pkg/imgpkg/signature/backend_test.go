@@ -0,0 +1,81 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFetcherForBackends(t *testing.T) {
+	logger := &helpers.Logger{}
+	regBuilder := helpers.NewFakeRegistry(t, logger)
+	img := regBuilder.WithRandomImage("some-image")
+	cosignSig := regBuilder.WithRandomImage("some-image")
+	cosignSig.Tag = fmt.Sprintf("sha256-%s.sig", strings.Split(img.Digest, ":")[1])
+	intotoImg := regBuilder.WithRandomImage("some-image")
+	intotoImg.Tag = fmt.Sprintf("sha256-%s.intoto", strings.Split(img.Digest, ":")[1])
+	reg := regBuilder.Build()
+	defer regBuilder.CleanUp()
+
+	notationSigDigest := pushNotationSignature(t, reg, img.RefDigest, img.Image)
+	notationSigRef := strings.Split(img.RefDigest, "@")[0] + "@" + notationSigDigest.String()
+
+	fetch := func(t *testing.T, backends ...string) []string {
+		fetcher, err := signature.NewFetcherForBackends(backends, reg, 2)
+		require.NoError(t, err)
+		signatures, err := fetcher.FetchForImageRefs([]lockconfig.ImageRef{{Image: img.RefDigest}})
+		require.NoError(t, err)
+
+		var refs []string
+		for _, sig := range signatures {
+			refs = append(refs, sig.Image)
+		}
+		return refs
+	}
+
+	t.Run("when no backend is selected, it does not find anything", func(t *testing.T) {
+		assert.Empty(t, fetch(t))
+	})
+
+	t.Run("it combines the artifacts found by every backend", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{cosignSig.RefDigest, notationSigRef}, fetch(t, signature.CosignBackend, signature.NotationBackend))
+	})
+
+	t.Run("when the referrers backend is selected, it finds every artifact that refers to the image", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{notationSigRef}, fetch(t, signature.ReferrersBackend))
+	})
+
+	t.Run("when a tag suffix backend is selected, it finds the artifact tagged with the suffix", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{intotoImg.RefDigest}, fetch(t, "tag-suffix:intoto"))
+	})
+
+	t.Run("when a backend is registered, it can be selected", func(t *testing.T) {
+		signature.RegisterBackend(signature.BackendFunc{BackendName: "custom-intoto", NewFetcher: func(reg signature.Registry, concurrency int) signature.Fetcher {
+			fetcher, err := signature.NewFetcherForBackends([]string{"tag-suffix:intoto"}, reg, concurrency)
+			require.NoError(t, err)
+			return fetcher
+		}})
+		assert.Contains(t, signature.BackendNames(), "custom-intoto")
+		assert.ElementsMatch(t, []string{intotoImg.RefDigest}, fetch(t, "custom-intoto"))
+	})
+
+	t.Run("when the backend is unknown, it errors", func(t *testing.T) {
+		_, err := signature.NewFetcherForBackends([]string{"gpg"}, reg, 2)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unknown signature backend 'gpg' (expected one of: ")
+		assert.Contains(t, err.Error(), "cosign")
+
+		err = signature.CheckBackends([]string{"tag-suffix:Not Valid"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to have a tag suffix made of lowercase letters and digits")
+	})
+}
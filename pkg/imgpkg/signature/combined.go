@@ -0,0 +1,68 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"errors"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+)
+
+// Fetcher Retrieves the signatures associated with images
+type Fetcher interface {
+	Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error)
+	FetchForImageRefs(images []lockconfig.ImageRef) ([]lockconfig.ImageRef, error)
+}
+
+// Combined Signature fetcher that retrieves the signatures found by all the fetchers, like cosign and Notation signatures
+type Combined struct {
+	fetchers []Fetcher
+}
+
+// NewCombined constructs a Signature Fetcher that combines the signatures retrieved by the fetchers
+func NewCombined(fetchers ...Fetcher) *Combined {
+	return &Combined{fetchers: fetchers}
+}
+
+// Fetch Retrieve the signatures that any of the fetchers finds for the images provided
+func (c *Combined) Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error) {
+	signatures := imageset.NewUnprocessedImageRefs()
+	for _, fetcher := range c.fetchers {
+		found, err := fetcher.Fetch(images)
+		if err != nil {
+			return nil, err
+		}
+		for _, signature := range found.All() {
+			signatures.Add(signature)
+		}
+	}
+	return signatures, nil
+}
+
+// FetchForImageRefs Retrieve the signatures that any of the fetchers finds for the images provided.
+// Images that cannot be accessed by any of the fetchers are reported together in a FetchError
+func (c *Combined) FetchForImageRefs(images []lockconfig.ImageRef) ([]lockconfig.ImageRef, error) {
+	var signatures []lockconfig.ImageRef
+	allErrs := &FetchError{}
+
+	for _, fetcher := range c.fetchers {
+		found, err := fetcher.FetchForImageRefs(images)
+		signatures = append(signatures, found...)
+		if err != nil {
+			var fetchError *FetchError
+			if !errors.As(err, &fetchError) {
+				return signatures, err
+			}
+			for _, fError := range fetchError.AllErrors {
+				allErrs.Add(fError)
+			}
+		}
+	}
+
+	if allErrs.HasErrors() {
+		return signatures, allErrs
+	}
+	return signatures, nil
+}
@@ -4,6 +4,7 @@
 package signature
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -21,15 +22,32 @@ type DigestReader interface {
 
 // Cosign Signature retriever
 type Cosign struct {
-	registry DigestReader
+	registry  DigestReader
+	tagSuffix string
 }
 
 // NewCosign constructor for Signature retriever
 func NewCosign(reg DigestReader) *Cosign {
-	return &Cosign{registry: reg}
+	return &Cosign{registry: reg, tagSuffix: cosign.SignatureTagSuffix}
 }
 
-// Signature retrieves the Image information that contains the signature for the provided Image
+// NewCosignAttestation constructor for the retriever of the attestations cosign attaches to images (.att tags)
+func NewCosignAttestation(reg DigestReader) *Cosign {
+	return &Cosign{registry: reg, tagSuffix: cosign.AttestationTagSuffix}
+}
+
+// NewCosignSBOM constructor for the retriever of the SBOMs cosign attaches to images (.sbom tags)
+func NewCosignSBOM(reg DigestReader) *Cosign {
+	return &Cosign{registry: reg, tagSuffix: cosign.SBOMTagSuffix}
+}
+
+// NewCosignFinders Finders for the signatures, attestations and SBOMs cosign attaches to images
+func NewCosignFinders(reg DigestReader) []Finder {
+	return []Finder{NewCosign(reg), NewCosignAttestation(reg), NewCosignSBOM(reg)}
+}
+
+// Signature retrieves the Image information that contains the signature for the provided Image.
+// When the Cosign was created for attestations or SBOMs the Image with that artifact is retrieved instead
 func (c Cosign) Signature(imageRef regname.Digest) (imageset.UnprocessedImageRef, error) {
 	sigTagRef, err := c.signatureTag(imageRef)
 	if err != nil {
@@ -38,7 +56,8 @@ func (c Cosign) Signature(imageRef regname.Digest) (imageset.UnprocessedImageRef
 
 	sigDigest, err := c.registry.Digest(sigTagRef)
 	if err != nil {
-		if transportErr, ok := err.(*transport.Error); ok {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) {
 			if transportErr.StatusCode == http.StatusNotFound {
 				return imageset.UnprocessedImageRef{}, NotFoundErr{}
 			}
@@ -60,5 +79,5 @@ func (c Cosign) signatureTag(reference regname.Digest) (regname.Tag, error) {
 	if err != nil {
 		return regname.Tag{}, fmt.Errorf("Converting to hash: %s", err)
 	}
-	return regname.NewTag(reference.Repository.Name() + ":" + cosign.Munge(regv1.Descriptor{Digest: digest}))
+	return regname.NewTag(reference.Repository.Name() + ":" + cosign.MungeWithSuffix(regv1.Descriptor{Digest: digest}, c.tagSuffix))
 }
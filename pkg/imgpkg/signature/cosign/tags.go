@@ -0,0 +1,31 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cosign
+
+import (
+	"regexp"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Suffixes of the tags cosign uses to attach artifacts to an image
+const (
+	SignatureTagSuffix   = "sig"
+	AttestationTagSuffix = "att"
+	SBOMTagSuffix        = "sbom"
+)
+
+// MungeWithSuffix Returns the tag of the artifact that cosign attaches to the image with the provided suffix
+func MungeWithSuffix(desc v1.Descriptor, suffix string) string {
+	// sha256:... -> sha256-....<suffix>
+	return strings.ReplaceAll(desc.Digest.String(), ":", "-") + "." + suffix
+}
+
+var attachedTagRegexp = regexp.MustCompile(`^[a-z0-9]+-[a-f0-9]+\.(` + SignatureTagSuffix + `|` + AttestationTagSuffix + `|` + SBOMTagSuffix + `)$`)
+
+// IsAttachedTag Checks if the tag is one that cosign uses to attach a signature, attestation or SBOM to an image
+func IsAttachedTag(tag string) bool {
+	return attachedTagRegexp.MatchString(tag)
+}
@@ -0,0 +1,284 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/signature/cosign"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// cosignKMSSchemes schemes of the KMS URIs supported by cosign, the payloads are signed by the cosign binary
+var cosignKMSSchemes = []string{"awskms://", "gcpkms://", "azurekms://", "hashivault://", "k8s://"}
+
+// ImageReadWriter Interface that knows how to read and write an Image from and to a registry
+type ImageReadWriter interface {
+	ImageReader
+	WriteImage(regname.Reference, regv1.Image, chan regv1.Update) error
+}
+
+// CosignSigningKey Signs the cosign payloads, with a private key or a KMS key
+type CosignSigningKey interface {
+	Sign(payload []byte) ([]byte, error)
+}
+
+// CosignSigner Signs images with cosign signatures attached to them (.sig tags)
+type CosignSigner struct {
+	registry ImageReadWriter
+	key      CosignSigningKey
+}
+
+// NewCosignSigner constructor for the signer of images with key
+func NewCosignSigner(reg ImageReadWriter, key CosignSigningKey) *CosignSigner {
+	return &CosignSigner{registry: reg, key: key}
+}
+
+// LoadCosignSigningKey Returns the key of keyRef, the path to a PEM encoded private key, as written by cosign generate-key-pair
+// and decrypted with password, or a KMS URI (awskms://, gcpkms://, azurekms://, hashivault:// or k8s://) whose payloads are signed by the cosign binary
+func LoadCosignSigningKey(keyRef string, password []byte) (CosignSigningKey, error) {
+	for _, scheme := range cosignKMSSchemes {
+		if strings.HasPrefix(keyRef, scheme) {
+			return cosignKMSKey{uri: keyRef}, nil
+		}
+	}
+
+	contents, err := os.ReadFile(keyRef)
+	if err != nil {
+		return nil, fmt.Errorf("Reading cosign private key: %s", err)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("Expected cosign private key '%s' to be PEM encoded", keyRef)
+	}
+
+	der := block.Bytes
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		der, err = decryptCosignPrivateKey(block.Bytes, password)
+		if err != nil {
+			return nil, fmt.Errorf("Decrypting cosign private key '%s': %s (hint: the password is provided with the environment variable COSIGN_PASSWORD)", keyRef, err)
+		}
+	case "EC PRIVATE KEY":
+		ecKey, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing cosign private key '%s': %s", keyRef, err)
+		}
+		return cosignPrivateKey{signer: ecKey}, nil
+	case "RSA PRIVATE KEY":
+		rsaKey, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing cosign private key '%s': %s", keyRef, err)
+		}
+		return cosignPrivateKey{signer: rsaKey}, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing cosign private key '%s': %s", keyRef, err)
+	}
+	switch privateKey := privateKey.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey, ed25519.PrivateKey:
+		return cosignPrivateKey{signer: privateKey.(crypto.Signer)}, nil
+	default:
+		return nil, fmt.Errorf("Expected cosign private key '%s' to be an ECDSA, RSA or Ed25519 key, but found %T", keyRef, privateKey)
+	}
+}
+
+// Sign Signs a payload referencing the digest of imageRef and adds it to the cosign signature of imageRef, keeping
+// the signatures already attached. The signature is not uploaded to the transparency log, as with cosign sign --tlog-upload=false
+func (c CosignSigner) Sign(imageRef regname.Digest) (regname.Tag, error) {
+	digest, err := regv1.NewHash(imageRef.DigestStr())
+	if err != nil {
+		return regname.Tag{}, fmt.Errorf("Converting to hash: %s", err)
+	}
+	sigTagRef, err := regname.NewTag(imageRef.Repository.Name() + ":" + cosign.MungeWithSuffix(regv1.Descriptor{Digest: digest}, cosign.SignatureTagSuffix))
+	if err != nil {
+		return regname.Tag{}, err
+	}
+
+	payload, err := cosignPayload(imageRef)
+	if err != nil {
+		return regname.Tag{}, err
+	}
+	rawSignature, err := c.key.Sign(payload)
+	if err != nil {
+		return regname.Tag{}, fmt.Errorf("Signing %s: %s", imageRef.Name(), err)
+	}
+
+	sigImg, err := c.registry.Image(sigTagRef)
+	if err != nil {
+		var transportErr *transport.Error
+		if !errors.As(err, &transportErr) || transportErr.StatusCode != http.StatusNotFound {
+			return regname.Tag{}, fmt.Errorf("Fetching cosign signature %s: %s", sigTagRef.Name(), err)
+		}
+		sigImg = mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	}
+	sigImg, err = mutate.Append(sigImg, mutate.Addendum{
+		Layer:       static.NewLayer(payload, cosignSimpleSigningMediaType),
+		Annotations: map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(rawSignature)},
+	})
+	if err != nil {
+		return regname.Tag{}, err
+	}
+
+	err = c.registry.WriteImage(sigTagRef, sigImg, nil)
+	if err != nil {
+		return regname.Tag{}, fmt.Errorf("Writing cosign signature %s: %s", sigTagRef.Name(), err)
+	}
+	return sigTagRef, nil
+}
+
+// cosignPayload Returns the simple signing payload cosign signs for imageRef
+func cosignPayload(imageRef regname.Digest) ([]byte, error) {
+	type identity struct {
+		DockerReference string `json:"docker-reference"`
+	}
+	type image struct {
+		DockerManifestDigest string `json:"docker-manifest-digest"`
+	}
+	type critical struct {
+		Identity identity `json:"identity"`
+		Image    image    `json:"image"`
+		Type     string   `json:"type"`
+	}
+	payload, err := json.Marshal(struct {
+		Critical critical          `json:"critical"`
+		Optional map[string]string `json:"optional"`
+	}{
+		Critical: critical{
+			Identity: identity{DockerReference: imageRef.Repository.Name()},
+			Image:    image{DockerManifestDigest: imageRef.DigestStr()},
+			Type:     "cosign container image signature",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Building the cosign payload of %s: %s", imageRef.Name(), err)
+	}
+	return payload, nil
+}
+
+// cosignPrivateKey Signs the payloads the same way the CosignVerifier verifies them
+type cosignPrivateKey struct {
+	signer crypto.Signer
+}
+
+// Sign Returns the signature of the SHA256 of payload, or of payload itself for Ed25519 keys
+func (k cosignPrivateKey) Sign(payload []byte) ([]byte, error) {
+	if _, ok := k.signer.(ed25519.PrivateKey); ok {
+		return k.signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	hash := sha256.Sum256(payload)
+	return k.signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
+
+// decryptCosignPrivateKey Decrypts the private key encrypted by cosign generate-key-pair, with a key derived from
+// password with scrypt, into its PKCS8 DER encoding
+func decryptCosignPrivateKey(encrypted, password []byte) ([]byte, error) {
+	var encryptedKey struct {
+		KDF struct {
+			Name   string `json:"name"`
+			Params struct {
+				N int `json:"N"`
+				R int `json:"r"`
+				P int `json:"p"`
+			} `json:"params"`
+			Salt []byte `json:"salt"`
+		} `json:"kdf"`
+		Cipher struct {
+			Name  string `json:"name"`
+			Nonce []byte `json:"nonce"`
+		} `json:"cipher"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	err := json.Unmarshal(encrypted, &encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing encrypted key: %s", err)
+	}
+	if encryptedKey.KDF.Name != "scrypt" || encryptedKey.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("Expected the key to be encrypted with scrypt and nacl/secretbox, but found %s and %s",
+			encryptedKey.KDF.Name, encryptedKey.Cipher.Name)
+	}
+	if len(encryptedKey.Cipher.Nonce) != 24 {
+		return nil, fmt.Errorf("Expected the nonce to be 24 bytes, but found %d", len(encryptedKey.Cipher.Nonce))
+	}
+
+	derivedKey, err := scrypt.Key(password, encryptedKey.KDF.Salt, encryptedKey.KDF.Params.N, encryptedKey.KDF.Params.R, encryptedKey.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Deriving key: %s", err)
+	}
+	var secretKey [32]byte
+	copy(secretKey[:], derivedKey)
+	var nonce [24]byte
+	copy(nonce[:], encryptedKey.Cipher.Nonce)
+
+	der, ok := secretbox.Open(nil, encryptedKey.Ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, fmt.Errorf("Wrong password")
+	}
+	return der, nil
+}
+
+// cosignKMSKey Signs the payloads with the cosign binary, that talks to the KMS of the URI
+type cosignKMSKey struct {
+	uri string
+}
+
+// Sign Returns the signature cosign sign-blob prints for payload
+func (k cosignKMSKey) Sign(payload []byte) ([]byte, error) {
+	payloadDir, err := os.MkdirTemp("", "imgpkg-cosign-payload")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(payloadDir)
+	payloadPath := filepath.Join(payloadDir, "payload.json")
+	err = os.WriteFile(payloadPath, payload, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(context.Background(), "cosign", "sign-blob", "--key", k.uri, "--tlog-upload=false", "--yes", payloadPath)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("Running 'cosign sign-blob' with key '%s' (cosign is required to sign with a KMS key): %s (stderr: %s)",
+			k.uri, err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	rawSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil {
+		return nil, fmt.Errorf("Decoding the signature printed by cosign sign-blob: %s", err)
+	}
+	return rawSignature, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestCosignSigner_Sign(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	logger := &helpers.Logger{}
+	regBuilder := helpers.NewFakeRegistry(t, logger)
+	img := regBuilder.WithRandomImage("some-image")
+	signedImg := regBuilder.WithRandomImage("other-image")
+	reg := regBuilder.Build()
+	defer regBuilder.CleanUp()
+
+	t.Run("when the key is encrypted by cosign, the signature is verified with its public key", func(t *testing.T) {
+		key, err := signature.LoadCosignSigningKey(writeEncryptedPrivateKey(t, signingKey, "some-password"), []byte("some-password"))
+		require.NoError(t, err)
+
+		sigTag, err := signature.NewCosignSigner(reg, key).Sign(mustDigest(t, img.RefDigest))
+		require.NoError(t, err)
+		assert.Equal(t, mustDigest(t, img.RefDigest).Repository.Name()+":"+"sha256-"+img.Digest[len("sha256:"):]+".sig", sigTag.Name())
+
+		publicKey, err := signature.LoadCosignPublicKey(writePublicKey(t, signingKey))
+		require.NoError(t, err)
+		require.NoError(t, signature.NewCosignVerifier(reg, publicKey).Verify(mustDigest(t, img.RefDigest)))
+	})
+
+	t.Run("when the image is already signed, the signatures already attached are kept", func(t *testing.T) {
+		pushCosignSignature(t, reg, signedImg.RefDigest, signedImg.Digest, otherKey)
+		key, err := signature.LoadCosignSigningKey(writePrivateKey(t, signingKey), nil)
+		require.NoError(t, err)
+
+		_, err = signature.NewCosignSigner(reg, key).Sign(mustDigest(t, signedImg.RefDigest))
+		require.NoError(t, err)
+
+		for _, privateKey := range []*ecdsa.PrivateKey{signingKey, otherKey} {
+			publicKey, err := signature.LoadCosignPublicKey(writePublicKey(t, privateKey))
+			require.NoError(t, err)
+			require.NoError(t, signature.NewCosignVerifier(reg, publicKey).Verify(mustDigest(t, signedImg.RefDigest)))
+		}
+	})
+}
+
+func TestLoadCosignSigningKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("when the password is wrong, it fails", func(t *testing.T) {
+		_, err := signature.LoadCosignSigningKey(writeEncryptedPrivateKey(t, key, "some-password"), []byte("other-password"))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Decrypting cosign private key")
+		assert.ErrorContains(t, err, "hint: the password is provided with the environment variable COSIGN_PASSWORD")
+	})
+
+	t.Run("when the file is not PEM encoded, it fails", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cosign.key")
+		require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+
+		_, err := signature.LoadCosignSigningKey(path, nil)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "to be PEM encoded")
+	})
+
+	t.Run("when it is a KMS URI, the file system is not read", func(t *testing.T) {
+		_, err := signature.LoadCosignSigningKey("awskms:///arn:aws:kms:us-east-1:111122223333:key/some-key", nil)
+		require.NoError(t, err)
+	})
+}
+
+func writePrivateKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// writeEncryptedPrivateKey Writes key encrypted the same way as cosign generate-key-pair, with a cheaper scrypt cost
+func writeEncryptedPrivateKey(t *testing.T, key *ecdsa.PrivateKey, password string) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	salt := make([]byte, 32)
+	_, err = rand.Read(salt)
+	require.NoError(t, err)
+	var nonce [24]byte
+	_, err = rand.Read(nonce[:])
+	require.NoError(t, err)
+	derivedKey, err := scrypt.Key([]byte(password), salt, 1024, 8, 1, 32)
+	require.NoError(t, err)
+	var secretKey [32]byte
+	copy(secretKey[:], derivedKey)
+
+	encrypted, err := json.Marshal(map[string]interface{}{
+		"kdf": map[string]interface{}{
+			"name":   "scrypt",
+			"params": map[string]int{"N": 1024, "r": 8, "p": 1},
+			"salt":   salt,
+		},
+		"cipher":     map[string]interface{}{"name": "nacl/secretbox", "nonce": nonce[:]},
+		"ciphertext": secretbox.Seal(nil, der, &nonce, &secretKey),
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: encrypted}), 0600))
+	return path
+}
@@ -51,4 +51,35 @@ func TestCosign_Signature(t *testing.T) {
 		_, ok := err.(signature.NotFoundErr)
 		require.True(t, ok)
 	})
+
+	t.Run("when retrieving attestations and SBOMs, it returns the images with the .att and .sbom tags", func(t *testing.T) {
+		logger := &helpers.Logger{}
+		regBuilder := helpers.NewFakeRegistry(t, logger)
+		img := regBuilder.WithRandomImage("some-image")
+		hex := strings.Split(img.Digest, ":")[1]
+		attImg := regBuilder.WithRandomImage("some-image")
+		attImg.Tag = fmt.Sprintf("sha256-%s.att", hex)
+		sbomImg := regBuilder.WithRandomImage("some-image")
+		sbomImg.Tag = fmt.Sprintf("sha256-%s.sbom", hex)
+		reg := regBuilder.Build()
+		defer regBuilder.CleanUp()
+
+		imgDigest, err := name.NewDigest(img.RefDigest)
+		require.NoError(t, err)
+
+		attestation, err := signature.NewCosignAttestation(reg).Signature(imgDigest)
+		require.NoError(t, err)
+		assert.Equal(t, attImg.RefDigest, attestation.DigestRef)
+		assert.Equal(t, attImg.Tag, attestation.Tag)
+
+		sbom, err := signature.NewCosignSBOM(reg).Signature(imgDigest)
+		require.NoError(t, err)
+		assert.Equal(t, sbomImg.RefDigest, sbom.DigestRef)
+		assert.Equal(t, sbomImg.Tag, sbom.Tag)
+
+		_, err = signature.NewCosign(reg).Signature(imgDigest)
+		require.Error(t, err)
+		_, ok := err.(signature.NotFoundErr)
+		require.True(t, ok)
+	})
 }
@@ -0,0 +1,174 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/signature/cosign"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	// cosignSimpleSigningMediaType media type of the layers of a cosign signature image, each one is a signed payload
+	cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// cosignSignatureAnnotation annotation of the layers of a cosign signature image with the base64 signature of the payload
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+)
+
+// ImageReader Interface that knows how to read an Image from a registry
+type ImageReader interface {
+	Image(reference regname.Reference) (regv1.Image, error)
+}
+
+// CosignVerifier Verifies, with a public key, the cosign signatures attached to images (.sig tags)
+type CosignVerifier struct {
+	registry  ImageReader
+	publicKey crypto.PublicKey
+}
+
+// NewCosignVerifier constructor for the verifier of the cosign signatures created with the private key of publicKey
+func NewCosignVerifier(reg ImageReader, publicKey crypto.PublicKey) *CosignVerifier {
+	return &CosignVerifier{registry: reg, publicKey: publicKey}
+}
+
+// LoadCosignPublicKey Reads a PEM encoded public key, as written by cosign generate-key-pair (ECDSA, RSA or Ed25519)
+func LoadCosignPublicKey(path string) (crypto.PublicKey, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading cosign public key: %s", err)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("Expected cosign public key '%s' to be PEM encoded", path)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing cosign public key '%s': %s", path, err)
+	}
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("Expected cosign public key '%s' to be an ECDSA, RSA or Ed25519 key, but found %T", path, publicKey)
+	}
+}
+
+// Verify Checks that imageRef has a cosign signature, created with the private key of the verifier, whose payload
+// references the digest of imageRef. The transparency log is not checked, as with cosign verify --insecure-ignore-tlog
+func (c CosignVerifier) Verify(imageRef regname.Digest) error {
+	digest, err := regv1.NewHash(imageRef.DigestStr())
+	if err != nil {
+		return fmt.Errorf("Converting to hash: %s", err)
+	}
+	sigTagRef, err := regname.NewTag(imageRef.Repository.Name() + ":" + cosign.MungeWithSuffix(regv1.Descriptor{Digest: digest}, cosign.SignatureTagSuffix))
+	if err != nil {
+		return err
+	}
+
+	sigImg, err := c.registry.Image(sigTagRef)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("No cosign signature found (expected it in %s)", sigTagRef.Name())
+		}
+		return fmt.Errorf("Fetching cosign signature %s: %s", sigTagRef.Name(), err)
+	}
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return fmt.Errorf("Reading cosign signature %s: %s", sigTagRef.Name(), err)
+	}
+
+	var problems []string
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosignSimpleSigningMediaType {
+			continue
+		}
+		err := c.verifyLayer(sigImg, layer, digest)
+		if err == nil {
+			return nil
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s", layer.Digest, err))
+	}
+	if len(problems) == 0 {
+		return fmt.Errorf("Expected cosign signature %s to contain signed payloads", sigTagRef.Name())
+	}
+	return fmt.Errorf("No valid cosign signature found in %s: %s", sigTagRef.Name(), strings.Join(problems, "; "))
+}
+
+// verifyLayer Verifies the signature of the payload in layer and that the payload references digest
+func (c CosignVerifier) verifyLayer(sigImg regv1.Image, layer regv1.Descriptor, digest regv1.Hash) error {
+	encodedSignature, found := layer.Annotations[cosignSignatureAnnotation]
+	if !found {
+		return fmt.Errorf("Expected payload to have the annotation %s", cosignSignatureAnnotation)
+	}
+	rawSignature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("Decoding signature: %s", err)
+	}
+
+	blob, err := sigImg.LayerByDigest(layer.Digest)
+	if err != nil {
+		return fmt.Errorf("Fetching payload: %s", err)
+	}
+	reader, err := blob.Compressed()
+	if err != nil {
+		return fmt.Errorf("Fetching payload: %s", err)
+	}
+	defer reader.Close()
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("Reading payload: %s", err)
+	}
+
+	if !c.verifySignature(payload, rawSignature) {
+		return fmt.Errorf("Signature does not match the public key")
+	}
+
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	err = json.Unmarshal(payload, &simpleSigning)
+	if err != nil {
+		return fmt.Errorf("Parsing payload: %s", err)
+	}
+	if simpleSigning.Critical.Image.DockerManifestDigest != digest.String() {
+		return fmt.Errorf("Expected payload to be signed for %s but it was signed for '%s'", digest, simpleSigning.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+func (c CosignVerifier) verifySignature(payload, rawSignature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch publicKey := c.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(publicKey, hash[:], rawSignature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], rawSignature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, payload, rawSignature)
+	default:
+		return false
+	}
+}
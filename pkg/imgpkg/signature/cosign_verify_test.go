@@ -0,0 +1,123 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCosignVerifier_Verify(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKeyPath := writePublicKey(t, signingKey)
+
+	logger := &helpers.Logger{}
+	regBuilder := helpers.NewFakeRegistry(t, logger)
+	signedImg := regBuilder.WithRandomImage("some-image")
+	otherKeyImg := regBuilder.WithRandomImage("some-image")
+	wrongDigestImg := regBuilder.WithRandomImage("some-image")
+	unsignedImg := regBuilder.WithRandomImage("some-image")
+	reg := regBuilder.Build()
+	defer regBuilder.CleanUp()
+
+	pushCosignSignature(t, reg, signedImg.RefDigest, signedImg.Digest, signingKey)
+	pushCosignSignature(t, reg, otherKeyImg.RefDigest, otherKeyImg.Digest, otherKey)
+	pushCosignSignature(t, reg, wrongDigestImg.RefDigest, signedImg.Digest, signingKey)
+
+	publicKey, err := signature.LoadCosignPublicKey(publicKeyPath)
+	require.NoError(t, err)
+	subject := signature.NewCosignVerifier(reg, publicKey)
+
+	t.Run("when the image is signed with the key, it succeeds", func(t *testing.T) {
+		require.NoError(t, subject.Verify(mustDigest(t, signedImg.RefDigest)))
+	})
+
+	t.Run("when the image is signed with another key, it fails", func(t *testing.T) {
+		err := subject.Verify(mustDigest(t, otherKeyImg.RefDigest))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Signature does not match the public key")
+	})
+
+	t.Run("when the signed payload references another image, it fails", func(t *testing.T) {
+		err := subject.Verify(mustDigest(t, wrongDigestImg.RefDigest))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, fmt.Sprintf("Expected payload to be signed for %s but it was signed for '%s'", wrongDigestImg.Digest, signedImg.Digest))
+	})
+
+	t.Run("when the image is not signed, it fails", func(t *testing.T) {
+		err := subject.Verify(mustDigest(t, unsignedImg.RefDigest))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "No cosign signature found")
+	})
+}
+
+func TestLoadCosignPublicKey(t *testing.T) {
+	t.Run("when the file is not PEM encoded, it fails", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cosign.pub")
+		require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+
+		_, err := signature.LoadCosignPublicKey(path)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "to be PEM encoded")
+	})
+}
+
+// pushCosignSignature Pushes to the .sig tag of imageRef a cosign signature of a payload referencing payloadDigest
+func pushCosignSignature(t *testing.T, reg registry.Registry, imageRef, payloadDigest string, key *ecdsa.PrivateKey) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`,
+		strings.Split(imageRef, "@")[0], payloadDigest))
+	hash := sha256.Sum256(payload)
+	rawSignature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+		Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(rawSignature)},
+	})
+	require.NoError(t, err)
+	sigImg = mutate.MediaType(sigImg, types.OCIManifestSchema1)
+
+	ref := mustDigest(t, imageRef)
+	sigTag, err := name.NewTag(ref.Repository.Name() + ":" + strings.ReplaceAll(ref.DigestStr(), ":", "-") + ".sig")
+	require.NoError(t, err)
+	require.NoError(t, reg.WriteImage(sigTag, sigImg, nil))
+}
+
+func writePublicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
+
+func mustDigest(t *testing.T, ref string) name.Digest {
+	digest, err := name.NewDigest(ref)
+	require.NoError(t, err)
+	return digest
+}
@@ -82,15 +82,21 @@ func (f *FetchError) Add(err FetchingError) {
 
 // Signatures Signature fetcher
 type Signatures struct {
-	signatureFinder Finder
-	concurrency     int
+	signatureFinders []Finder
+	concurrency      int
 }
 
 // NewSignatures constructs the Signature Fetcher
 func NewSignatures(finder Finder, concurrency int) *Signatures {
+	return NewSignaturesWithFinders([]Finder{finder}, concurrency)
+}
+
+// NewSignaturesWithFinders constructs a Signature Fetcher that retrieves the artifacts found by all the finders,
+// like the signatures, attestations and SBOMs attached to the images
+func NewSignaturesWithFinders(finders []Finder, concurrency int) *Signatures {
 	return &Signatures{
-		signatureFinder: finder,
-		concurrency:     concurrency,
+		signatureFinders: finders,
+		concurrency:      concurrency,
 	}
 }
 
@@ -137,38 +143,41 @@ func (s *Signatures) FetchForImageRefs(images []lockconfig.ImageRef) ([]lockconf
 	allErrs := &FetchError{}
 
 	for _, ref := range images {
-		ref := ref //copy
-		wg.Go(func() error {
-			imgDigest, err := name.NewDigest(ref.PrimaryLocation())
-			if err != nil {
-				return fmt.Errorf("Parsing '%s': %s", ref.Image, err)
-			}
-
-			throttle.Take()
-			defer throttle.Done()
-
-			signature, err := s.signatureFinder.Signature(imgDigest)
-			if err != nil {
-				if _, ok := err.(NotFoundErr); ok {
-					return nil
+		for _, finder := range s.signatureFinders {
+			ref := ref       //copy
+			finder := finder //copy
+			wg.Go(func() error {
+				imgDigest, err := name.NewDigest(ref.PrimaryLocation())
+				if err != nil {
+					return fmt.Errorf("Parsing '%s': %s", ref.Image, err)
 				}
-				if deniedErr, ok := err.(AccessDeniedErr); ok {
-					lock.Lock()
-					defer lock.Unlock()
-					allErrs.Add(deniedErr)
-					return nil
+
+				throttle.Take()
+				defer throttle.Done()
+
+				signature, err := finder.Signature(imgDigest)
+				if err != nil {
+					if _, ok := err.(NotFoundErr); ok {
+						return nil
+					}
+					if deniedErr, ok := err.(AccessDeniedErr); ok {
+						lock.Lock()
+						defer lock.Unlock()
+						allErrs.Add(deniedErr)
+						return nil
+					}
+					return fmt.Errorf("Fetching signature for image '%s': %s", imgDigest.Name(), err)
 				}
-				return fmt.Errorf("Fetching signature for image '%s': %s", imgDigest.Name(), err)
-			}
 
-			lock.Lock()
-			signatures = append(signatures, lockconfig.ImageRef{
-				Image:       signature.DigestRef,
-				Annotations: map[string]string{"tag": signature.Tag},
+				lock.Lock()
+				signatures = append(signatures, lockconfig.ImageRef{
+					Image:       signature.DigestRef,
+					Annotations: map[string]string{"tag": signature.Tag},
+				})
+				lock.Unlock()
+				return nil
 			})
-			lock.Unlock()
-			return nil
-		})
+		}
 	}
 
 	err := wg.Wait()
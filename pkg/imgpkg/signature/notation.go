@@ -0,0 +1,12 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+// NotationSignatureArtifactType Artifact type of the signatures created by Notation (Notary v2)
+const NotationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+
+// NewNotation constructs the Signature Fetcher that discovers the Notation signatures of images using the OCI Referrers API
+func NewNotation(reg ReferrersReader, concurrency int) *Referrers {
+	return NewReferrers(reg, NotationSignatureArtifactType, concurrency)
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotation_FetchForImageRefs(t *testing.T) {
+	logger := &helpers.Logger{}
+	regBuilder := helpers.NewFakeRegistry(t, logger)
+	signedImg := regBuilder.WithRandomImage("some-image")
+	unsignedImg := regBuilder.WithRandomImage("other-image")
+	reg := regBuilder.Build()
+	defer regBuilder.CleanUp()
+
+	sigDigest := pushNotationSignature(t, reg, signedImg.RefDigest, signedImg.Image)
+
+	t.Run("it returns the signatures that refer to the image", func(t *testing.T) {
+		subject := signature.NewNotation(reg.(signature.ReferrersReader), 2)
+		signatures, err := subject.FetchForImageRefs([]lockconfig.ImageRef{{Image: signedImg.RefDigest}})
+		require.NoError(t, err)
+
+		require.Len(t, signatures, 1)
+		assert.Equal(t, strings.Split(signedImg.RefDigest, "@")[0]+"@"+sigDigest.String(), signatures[0].Image)
+	})
+
+	t.Run("it does not return signatures for images that are not signed", func(t *testing.T) {
+		subject := signature.NewNotation(reg.(signature.ReferrersReader), 2)
+		signatures, err := subject.FetchForImageRefs([]lockconfig.ImageRef{{Image: unsignedImg.RefDigest}})
+		require.NoError(t, err)
+		assert.Empty(t, signatures)
+	})
+}
+
+// pushNotationSignature Pushes an artifact with the Notation signature artifact type that refers to the image
+func pushNotationSignature(t *testing.T, reg registry.Registry, imageRef string, img regv1.Image) regv1.Hash {
+	imgDigest, err := name.NewDigest(imageRef)
+	require.NoError(t, err)
+	imgHash, err := img.Digest()
+	require.NoError(t, err)
+	imgManifest, err := img.RawManifest()
+	require.NoError(t, err)
+	imgMediaType, err := img.MediaType()
+	require.NoError(t, err)
+
+	sigImg := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), signature.NotationSignatureArtifactType)
+	sigImg = mutate.Subject(sigImg, regv1.Descriptor{
+		MediaType: imgMediaType,
+		Digest:    imgHash,
+		Size:      int64(len(imgManifest)),
+	}).(regv1.Image)
+	sigHash, err := sigImg.Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, reg.WriteImage(imgDigest.Context().Digest(sigHash.String()), sigImg, nil))
+	return sigHash
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"golang.org/x/sync/errgroup"
+)
+
+// ReferrersReader Interface that knows how to list the artifacts that refer to an image
+type ReferrersReader interface {
+	Referrers(ref regname.Digest, artifactType string) ([]regv1.Descriptor, error)
+}
+
+// referrersReader Returns reg when it lists referrers, otherwise a ReferrersReader that fails for every image
+func referrersReader(reg Registry) ReferrersReader {
+	if reader, ok := reg.(ReferrersReader); ok {
+		return reader
+	}
+	return unsupportedReferrersReader{}
+}
+
+// unsupportedReferrersReader ReferrersReader of the registries that cannot list referrers
+type unsupportedReferrersReader struct{}
+
+// Referrers Fails, the registry does not support listing referrers
+func (unsupportedReferrersReader) Referrers(ref regname.Digest, _ string) ([]regv1.Descriptor, error) {
+	return nil, fmt.Errorf("Listing referrers of '%s': registry does not support listing referrers", ref.Name())
+}
+
+// Referrers Signature fetcher that discovers the artifacts that refer to images using the OCI Referrers API.
+// The artifacts are not tagged, they are copied by digest and keep the subject that links them to the image.
+// When all the referrers are retrieved, the artifacts that refer to them are retrieved as well, like the
+// signature of an SBOM attached to an image
+type Referrers struct {
+	registry ReferrersReader
+	// artifactType only the artifacts of this type are retrieved, all the referrers when empty
+	artifactType string
+	concurrency  int
+}
+
+// NewReferrers constructs a Signature Fetcher that retrieves the artifacts of artifactType that refer to the images,
+// or all of them when artifactType is empty
+func NewReferrers(reg ReferrersReader, artifactType string, concurrency int) *Referrers {
+	return &Referrers{registry: reg, artifactType: artifactType, concurrency: concurrency}
+}
+
+// Fetch Retrieve the artifacts that refer to the images provided
+func (r *Referrers) Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error) {
+	var imgs []lockconfig.ImageRef
+	for _, ref := range images.All() {
+		imgs = append(imgs, lockconfig.ImageRef{Image: ref.DigestRef})
+	}
+
+	signatures := imageset.NewUnprocessedImageRefs()
+	found := map[string]bool{}
+	for len(imgs) > 0 {
+		imagesRefs, err := r.FetchForImageRefs(imgs)
+		if err != nil {
+			var fetchError *FetchError
+			if !errors.As(err, &fetchError) {
+				return nil, err
+			}
+			// Images that cannot be accessed are skipped, like the ones without signatures
+		}
+
+		imgs = nil
+		for _, ref := range imagesRefs {
+			if found[ref.Image] {
+				continue
+			}
+			found[ref.Image] = true
+			signatures.Add(imageset.UnprocessedImageRef{DigestRef: ref.Image})
+			if r.artifactType == "" {
+				imgs = append(imgs, ref)
+			}
+		}
+	}
+	return signatures, nil
+}
+
+// FetchForImageRefs Retrieve the artifacts that refer to the images provided
+func (r *Referrers) FetchForImageRefs(images []lockconfig.ImageRef) ([]lockconfig.ImageRef, error) {
+	lock := &sync.Mutex{}
+	var signatures []lockconfig.ImageRef
+
+	throttle := util.NewThrottle(r.concurrency)
+	var wg errgroup.Group
+	allErrs := &FetchError{}
+
+	for _, ref := range images {
+		ref := ref //copy
+		wg.Go(func() error {
+			imgDigest, err := regname.NewDigest(ref.PrimaryLocation())
+			if err != nil {
+				return fmt.Errorf("Parsing '%s': %s", ref.Image, err)
+			}
+
+			throttle.Take()
+			defer throttle.Done()
+
+			descriptors, err := r.registry.Referrers(imgDigest, r.artifactType)
+			if err != nil {
+				var transportErr *transport.Error
+				if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusForbidden {
+					lock.Lock()
+					defer lock.Unlock()
+					allErrs.Add(AccessDeniedErr{imageRef: imgDigest.Name()})
+					return nil
+				}
+				return fmt.Errorf("Fetching referrers for image '%s': %s", imgDigest.Name(), err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			for _, desc := range descriptors {
+				signatures = append(signatures, lockconfig.ImageRef{Image: imgDigest.Digest(desc.Digest.String()).Name()})
+			}
+			return nil
+		})
+	}
+
+	err := wg.Wait()
+	if err != nil {
+		return signatures, err
+	}
+
+	if allErrs.HasErrors() {
+		return signatures, allErrs
+	}
+
+	return signatures, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrers_Fetch(t *testing.T) {
+	logger := &helpers.Logger{}
+	regBuilder := helpers.NewFakeRegistry(t, logger)
+	img := regBuilder.WithRandomImage("some-image")
+	reg := regBuilder.Build()
+	defer regBuilder.CleanUp()
+
+	repo, err := name.NewDigest(img.RefDigest)
+	require.NoError(t, err)
+	sbomRef, sbom := pushReferrer(t, reg, repo, img.Image, "application/spdx+json")
+	sbomSigRef, _ := pushReferrer(t, reg, repo, sbom, signature.NotationSignatureArtifactType)
+
+	images := imageset.NewUnprocessedImageRefs()
+	images.Add(imageset.UnprocessedImageRef{DigestRef: img.RefDigest})
+
+	t.Run("when retrieving all the referrers, it also retrieves the artifacts that refer to the referrers", func(t *testing.T) {
+		signatures, err := signature.NewReferrers(reg.(signature.ReferrersReader), "", 2).Fetch(images)
+		require.NoError(t, err)
+
+		var refs []string
+		for _, ref := range signatures.All() {
+			refs = append(refs, ref.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{sbomRef, sbomSigRef}, refs)
+	})
+
+	t.Run("when retrieving the referrers of an artifact type, it only retrieves the ones that refer to the images", func(t *testing.T) {
+		signatures, err := signature.NewReferrers(reg.(signature.ReferrersReader), "application/spdx+json", 2).Fetch(images)
+		require.NoError(t, err)
+
+		require.Len(t, signatures.All(), 1)
+		assert.Equal(t, sbomRef, signatures.All()[0].DigestRef)
+	})
+}
+
+// pushReferrer Pushes to repo an artifact of artifactType whose subject is subject, returns its reference and the artifact
+func pushReferrer(t *testing.T, reg registry.Registry, repo name.Digest, subject regv1.Image, artifactType string) (string, regv1.Image) {
+	subjectHash, err := subject.Digest()
+	require.NoError(t, err)
+	subjectManifest, err := subject.RawManifest()
+	require.NoError(t, err)
+	subjectMediaType, err := subject.MediaType()
+	require.NoError(t, err)
+
+	artifact := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.MediaType(artifactType))
+	artifact = mutate.Subject(artifact, regv1.Descriptor{
+		MediaType: subjectMediaType,
+		Digest:    subjectHash,
+		Size:      int64(len(subjectManifest)),
+	}).(regv1.Image)
+	artifactHash, err := artifact.Digest()
+	require.NoError(t, err)
+
+	artifactRef := repo.Context().Digest(artifactHash.String())
+	require.NoError(t, reg.WriteImage(artifactRef, artifact, nil))
+	return artifactRef.Name(), artifact
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// BundleImagesLockImage Image recorded in the ImagesLock of a Bundle
+type BundleImagesLockImage struct {
+	Image       string            `json:"image"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// BundleImagesLock ImagesLock stored in the .imgpkg folder of a Bundle
+type BundleImagesLock struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Images     []BundleImagesLockImage `json:"images,omitempty"`
+}
+
+// BundleDetails Metadata of a Bundle
+type BundleDetails struct {
+	// Image Location of the Bundle, including the digest
+	Image string `json:"image"`
+	// Root true for the Bundle that was requested, false for its nested Bundles
+	Root bool `json:"root"`
+	// Annotations present in the manifest of the Bundle
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// ImagesLock as it was pushed with the Bundle, the locations of the images are not updated after copies
+	ImagesLock BundleImagesLock `json:"imagesLock"`
+	// NestedBundles Locations, including the digest, where the Bundles referenced in the ImagesLock can be found
+	NestedBundles []string `json:"nestedBundles,omitempty"`
+}
+
+// BundleMetadataOpts Options used when calling the BundleMetadata function
+type BundleMetadataOpts struct {
+	Logger      bundle.Logger
+	Concurrency int
+}
+
+// BundleMetadata Given a Bundle URL retrieve the metadata of the Bundle and of all its Nested Bundles.
+// The requested Bundle is the first element of the result
+func BundleMetadata(bundleRef string, opts BundleMetadataOpts, registryOpts registry.Opts) ([]BundleDetails, error) {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return BundleMetadataWithRegistry(bundleRef, opts, reg)
+}
+
+// BundleMetadataWithRegistry Given a Bundle URL retrieve the metadata of the Bundle and of all its Nested Bundles.
+// The requested Bundle is the first element of the result
+func BundleMetadataWithRegistry(bundleRef string, opts BundleMetadataOpts, reg bundle.ImagesMetadata) ([]BundleDetails, error) {
+	lockReader := bundle.NewImagesLockReader()
+	rootBundle := bundle.NewBundleFromRef(bundleRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+	isBundle, err := rootBundle.IsBundle()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to check if %s is a bundle: %s", bundleRef, err)
+	}
+	if !isBundle {
+		return nil, &ErrIsNotBundle{}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	allBundles, _, err := rootBundle.AllImagesLockRefs(concurrency, opts.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("Retrieving Images from bundle: %s", err)
+	}
+
+	var result []BundleDetails
+	processed := map[string]bool{}
+	for _, b := range allBundles {
+		if processed[b.DigestRef()] {
+			continue
+		}
+		processed[b.DigestRef()] = true
+
+		details, err := newBundleDetails(b, reg, lockReader)
+		if err != nil {
+			return nil, err
+		}
+		details.Root = b == rootBundle
+		result = append(result, details)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Root != result[j].Root {
+			return result[i].Root
+		}
+		return result[i].Image < result[j].Image
+	})
+
+	return result, nil
+}
+
+func newBundleDetails(b *bundle.Bundle, reg bundle.ImagesMetadata, lockReader bundle.ImagesLockReader) (BundleDetails, error) {
+	ref, err := regname.NewDigest(b.DigestRef())
+	if err != nil {
+		return BundleDetails{}, fmt.Errorf("Internal inconsistency: bundle %s should be fully resolved", b.DigestRef())
+	}
+
+	img, err := reg.Image(ref)
+	if err != nil {
+		return BundleDetails{}, fmt.Errorf("Fetching bundle %s: %s", b.DigestRef(), err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return BundleDetails{}, fmt.Errorf("Reading manifest of bundle %s: %s", b.DigestRef(), err)
+	}
+
+	imagesLock, err := lockReader.Read(img)
+	if err != nil {
+		return BundleDetails{}, fmt.Errorf("Reading ImagesLock of bundle %s: %s", b.DigestRef(), err)
+	}
+
+	details := BundleDetails{
+		Image:       b.DigestRef(),
+		Annotations: manifest.Annotations,
+		ImagesLock: BundleImagesLock{
+			APIVersion: imagesLock.APIVersion,
+			Kind:       imagesLock.Kind,
+		},
+	}
+	for _, image := range imagesLock.Images {
+		details.ImagesLock.Images = append(details.ImagesLock.Images, BundleImagesLockImage{
+			Image:       image.Image,
+			Annotations: image.Annotations,
+		})
+	}
+
+	for _, imageRef := range b.ImagesRefsWithErrors() {
+		if imageRef.IsBundle != nil && *imageRef.IsBundle {
+			details.NestedBundles = append(details.NestedBundles, imageRef.PrimaryLocation())
+		}
+	}
+	sort.Strings(details.NestedBundles)
+
+	return details, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleMetadata(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	img1 := fakeRegistry.WithRandomImage("some/image-1")
+	img2 := fakeRegistry.WithRandomImage("some/image-2")
+	nestedBundle := fakeRegistry.WithRandomBundle("some/nested-bundle").WithImageRefs([]lockconfig.ImageRef{
+		{Image: img2.RefDigest, Annotations: map[string]string{"kbld.carvel.dev/id": "image-2"}},
+	})
+	rootBundle := fakeRegistry.WithRandomBundle("some/bundle").WithImageRefs([]lockconfig.ImageRef{
+		{Image: img1.RefDigest},
+		{Image: nestedBundle.RefDigest},
+	})
+	fakeRegistry.Build()
+
+	opts := v1.BundleMetadataOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 2}
+
+	t.Run("when the reference is a bundle, it returns the metadata of the bundle and its nested bundles", func(t *testing.T) {
+		details, err := v1.BundleMetadata(rootBundle.RefDigest, opts, registry.Opts{})
+		require.NoError(t, err)
+
+		require.Len(t, details, 2)
+		assert.Equal(t, v1.BundleDetails{
+			Image: rootBundle.RefDigest,
+			Root:  true,
+			ImagesLock: v1.BundleImagesLock{
+				APIVersion: lockconfig.ImagesLockAPIVersion,
+				Kind:       lockconfig.ImagesLockKind,
+				Images: []v1.BundleImagesLockImage{
+					{Image: img1.RefDigest},
+					{Image: nestedBundle.RefDigest},
+				},
+			},
+			NestedBundles: []string{nestedBundle.RefDigest},
+		}, details[0])
+		assert.Equal(t, v1.BundleDetails{
+			Image: nestedBundle.RefDigest,
+			Root:  false,
+			ImagesLock: v1.BundleImagesLock{
+				APIVersion: lockconfig.ImagesLockAPIVersion,
+				Kind:       lockconfig.ImagesLockKind,
+				Images: []v1.BundleImagesLockImage{
+					{Image: img2.RefDigest, Annotations: map[string]string{"kbld.carvel.dev/id": "image-2"}},
+				},
+			},
+		}, details[1])
+	})
+
+	t.Run("when the reference is not a bundle, it fails", func(t *testing.T) {
+		_, err := v1.BundleMetadata(img1.RefDigest, opts, registry.Opts{})
+		require.ErrorIs(t, err, &v1.ErrIsNotBundle{})
+	})
+}
@@ -5,6 +5,9 @@ package v1
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	ctlbundle "carvel.dev/imgpkg/pkg/imgpkg/bundle"
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
@@ -14,11 +17,19 @@ import (
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature/cosign"
 	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 )
 
 const rootBundleLabelKey string = "dev.carvel.imgpkg.copy.root-bundle"
 
+// rootBundlePlatformLabelKey labels the bundle of each platform of a multi-arch root bundle, whose OCI Image Index
+// is the one labeled with rootBundleLabelKey
+const rootBundlePlatformLabelKey string = "dev.carvel.imgpkg.copy.root-bundle-platform"
+
 // CopyOpts Option that can be provided to the copy request
 type CopyOpts struct {
 	Logger                  Logger
@@ -28,6 +39,35 @@ type CopyOpts struct {
 	SignatureRetriever      SignatureFetcher
 	IncludeNonDistributable bool
 	Resume                  bool
+	// SignatureAnnotations annotations added to the manifests of the cosign signatures, attestations and SBOMs
+	// copied to a repository or registry (example: the ticket that approved the copy), so that they can be audited
+	SignatureAnnotations map[string]string
+	// OnImageProcessed when copying to a repository or registry, called with each image as soon as it is verified in the
+	// destination, so that downstream work can start while the other images are still being copied.
+	// The calls are serialized, and the images have as source the ones in origin, even when copying to several repositories
+	OnImageProcessed func(ctlimgset.ProcessedImage)
+	// FailOnDeprecated when copying bundles, fail before copying anything if the bundle or one of its nested bundles
+	// is marked as deprecated in its metadata, instead of only warning about it
+	FailOnDeprecated bool
+	// SignatureVerifier when provided, the images copied from a registry are verified before copying anything,
+	// and the copy fails if the signatures of any of them cannot be verified
+	SignatureVerifier SignatureVerifier
+	// RootBundleAnnotations annotations added to the manifests of the root bundles copied to a repository or registry
+	// (example: the date of the relocation), which changes their digests. Their images and nested bundles are not modified
+	RootBundleAnnotations map[string]string
+	// TokenPrefetcher when provided, the tokens to read every image from a registry and to write to every destination
+	// repository are fetched concurrently before copying anything, so that permission problems fail the copy right away
+	TokenPrefetcher TokenPrefetcher
+}
+
+// TokenPrefetcher Fetches up front the registry tokens needed to read from and write to repositories
+type TokenPrefetcher interface {
+	PrefetchTokens(pullRepos []regname.Repository, pushRepos []regname.Repository, concurrency int) error
+}
+
+// SignatureVerifier Verifies the signatures of an image
+type SignatureVerifier interface {
+	Verify(imageRef regname.Digest) error
 }
 
 // CopyOrigin abstracts the original location to copy from
@@ -36,97 +76,553 @@ type CopyOrigin struct {
 	BundleRef    string
 	TarPath      string
 	LockfilePath string
+	// ImageDigestFilePaths files with an image reference with digest per line, like the ones written by ko or the Bazel rules,
+	// copied the same as an ImagesLock with those images
+	ImageDigestFilePaths []string
+	// PreserveLockTags when LockfilePath is an ImagesLock, or ImageDigestFilePaths are provided, tag each image in the destination with the tag
+	// of the reference it was resolved from (example: 1.21 for the kbld.carvel.dev/id annotation nginx:1.21)
+	PreserveLockTags bool
+	// OCILayoutPath OCI image layout directory, created by imgpkg or other tools, with the images to copy
+	OCILayoutPath string
+	// IndexChildPlatform when ImageRef is an image index, copy only the child manifest of this platform (example: linux/arm64)
+	IndexChildPlatform string
+	// OnlyNewTags when ImageRef is a repository, copy only its tags that do not point to the same digest in the destination
+	OnlyNewTags bool
+	// AdditionalTags when ImageRef is an image, also recreate in the destination the other tags of its repository
+	// that point to the same digest
+	AdditionalTags bool
+	// AllTags when ImageRef is an image, also copy every other tag of its repository and the images they point to
+	AllTags bool
+	// TagPatterns when copying only new tags, select the tags that match one of these patterns (example: v1.*)
+	TagPatterns []string
+	// ExcludeImages when BundleRef is a bundle, the images of its ImagesLocks that match one of these patterns are not copied
+	// (format: glob or regex:<expression> matched against the image repository, example: */app-docs)
+	ExcludeImages []string
+	// SignaturesOnly when copying to a repository, copy only the signatures, attestations and SBOMs of the images that
+	// were already copied to it and that are not in it yet, instead of the images
+	SignaturesOnly bool
+	// BundleRefs and ImageRefs bundles and images copied together in a single invocation,
+	// the images shared between them are only copied once
+	BundleRefs []string
+	ImageRefs  []string
 }
 
 // CopyToTar copy origin image/s to a tar file in disc
 func CopyToTar(origin CopyOrigin, outputTarPath string, opts CopyOpts, reg registry.Registry) (*imagedesc.ImageRefDescriptors, error) {
 	opts.Logger.Tracef("CopyToTar\n")
 
+	if origin.OnlyNewTags {
+		return nil, fmt.Errorf("Copying only new tags is only possible when copying to a repository")
+	}
+	if origin.OCILayoutPath != "" {
+		return nil, fmt.Errorf("Copying from an OCI image layout is only possible when copying to a repository")
+	}
+	if len(origin.ExcludeImages) > 0 {
+		return nil, fmt.Errorf("Excluding images is only possible when copying to a repository or a registry")
+	}
+	if origin.SignaturesOnly {
+		return nil, fmt.Errorf("Copying only signatures is only possible when copying to a repository")
+	}
+
 	unprocessedImageRefs, _, err := getAllSourceImages(origin, reg, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	opts.Logger.Tracef("Exporting images to tar\n")
-	ids, err := opts.TarImageSet.Export(unprocessedImageRefs, outputTarPath, reg, imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributable), opts.Resume)
+	destination := ctlimgset.NewTarDestination(opts.TarImageSet, outputTarPath, imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributable), opts.Resume)
+	written, err := ctlimgset.Copy(ctlimgset.NewRegistrySource(unprocessedImageRefs), destination, reg)
 	if err != nil {
 		return nil, err
 	}
 
-	return ids, nil
+	return written.Descriptors, nil
+}
+
+// CopyToOCILayout copy origin image/s to an OCI image layout directory in disc
+func CopyToOCILayout(origin CopyOrigin, layoutPath string, opts CopyOpts, reg registry.Registry) (*imagedesc.ImageRefDescriptors, error) {
+	opts.Logger.Tracef("CopyToOCILayout(%s)\n", layoutPath)
+
+	if origin.OnlyNewTags {
+		return nil, fmt.Errorf("Copying only new tags is only possible when copying to a repository")
+	}
+	if origin.TarPath != "" {
+		return nil, fmt.Errorf("Copying from a tar to an OCI image layout is not supported")
+	}
+	if origin.OCILayoutPath != "" {
+		return nil, fmt.Errorf("Copying from an OCI image layout is only possible when copying to a repository")
+	}
+	if len(origin.ExcludeImages) > 0 {
+		return nil, fmt.Errorf("Excluding images is only possible when copying to a repository or a registry")
+	}
+	if origin.SignaturesOnly {
+		return nil, fmt.Errorf("Copying only signatures is only possible when copying to a repository")
+	}
+
+	unprocessedImageRefs, _, err := getAllSourceImages(origin, reg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Logger.Tracef("Exporting images to OCI image layout\n")
+	layoutImageSet := ctlimgset.NewOCILayoutImageSet(opts.ImageSet, opts.Concurrency, opts.Logger)
+	destination := ctlimgset.NewOCILayoutDestination(layoutImageSet, layoutPath, imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributable))
+	written, err := ctlimgset.Copy(ctlimgset.NewRegistrySource(unprocessedImageRefs), destination, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return written.Descriptors, nil
 }
 
 // CopyToRepository copy origin image/s to a repository in a remote registry
 func CopyToRepository(origin CopyOrigin, repository string, opts CopyOpts, reg registry.Registry) (*ctlimgset.ProcessedImages, error) {
 	opts.Logger.Tracef("CopyToRepository(%s)\n", repository)
 
-	var processedImages *ctlimgset.ProcessedImages
 	importRepo, err := regname.NewRepository(repository)
 	if err != nil {
 		return nil, fmt.Errorf("Building import repository ref: %s", err)
 	}
 
-	if origin.TarPath != "" {
-		processedImages, err = opts.TarImageSet.Import(origin.TarPath, importRepo, reg)
+	if origin.SignaturesOnly {
+		return copySignaturesOnly(origin, importRepo, opts, reg)
+	}
+	if !origin.OnlyNewTags {
+		return copyToRegistryDestination(origin, repositoryDestination(importRepo), opts, reg)
+	}
+	destination := ctlimgset.NewRepositoryDestination(reportingImageSet(opts, nil), importRepo)
+
+	unprocessedImageRefs, err := getNewTagsSourceImages(origin, importRepo, reg, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = prefetchTokens(unprocessedImageRefs, []destinationFactory{repositoryDestination(importRepo)}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	processedImages := ctlimgset.NewProcessedImages()
+	if unprocessedImageRefs.Length() > 0 {
+		written, err := ctlimgset.Copy(ctlimgset.NewRegistrySource(unprocessedImageRefs), destination, reg)
 		if err != nil {
 			return nil, err
 		}
+		processedImages = written.ProcessedImages
+	}
 
-		var parentBundle *ctlbundle.Bundle
-		foundRootBundle := false
-		for _, processedImage := range processedImages.All() {
-			if processedImage.ImageIndex != nil {
-				continue
-			}
+	err = tagCopiedImages(reg, opts, processedImages)
+	if err != nil {
+		return nil, err
+	}
+	return processedImages, nil
+}
+
+// CopyToRepositories Copies origin to each one of the repositories. The images are only read from origin once,
+// they are copied to the first repository and from there to the other ones
+func CopyToRepositories(origin CopyOrigin, repositories []string, opts CopyOpts, reg registry.Registry) ([]*ctlimgset.ProcessedImages, error) {
+	opts.Logger.Tracef("CopyToRepositories(%s)\n", strings.Join(repositories, ", "))
+
+	if origin.OnlyNewTags {
+		return nil, fmt.Errorf("Copying only new tags is only possible when copying to a single repository")
+	}
+	if origin.SignaturesOnly {
+		return nil, fmt.Errorf("Copying only signatures is only possible when copying to a single repository")
+	}
+
+	var destinations []destinationFactory
+	for _, repository := range repositories {
+		importRepo, err := regname.NewRepository(repository)
+		if err != nil {
+			return nil, fmt.Errorf("Building import repository ref: %s", err)
+		}
+		destinations = append(destinations, repositoryDestination(importRepo))
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("Expected at least one repository to copy to")
+	}
+
+	return copyToRegistryDestinations(origin, destinations, opts, reg)
+}
+
+// CopyToRegistry Copies the images and bundles into the registry, keeping the repository path that each one of them
+// has in its source registry, e.g. index.docker.io/library/nginx is copied to <registry>/library/nginx
+func CopyToRegistry(origin CopyOrigin, registryName string, opts CopyOpts, reg registry.Registry) (*ctlimgset.ProcessedImages, error) {
+	opts.Logger.Tracef("CopyToRegistry(%s)\n", registryName)
+
+	if origin.OCILayoutPath != "" {
+		return nil, fmt.Errorf("Copying from an OCI image layout is only possible when copying to a repository")
+	}
+	if origin.OnlyNewTags {
+		return nil, fmt.Errorf("Copying only new tags is only possible when copying to a repository")
+	}
+	if origin.SignaturesOnly {
+		return nil, fmt.Errorf("Copying only signatures is only possible when copying to a repository")
+	}
+
+	importRegistry, err := regname.NewRegistry(registryName)
+	if err != nil {
+		return nil, fmt.Errorf("Building import registry ref: %s", err)
+	}
+
+	return copyToRegistryDestination(origin, func(imageSet ctlimgset.ImageSet) ctlimgset.RegistryContentDestination {
+		return ctlimgset.NewRegistryDestination(imageSet, importRegistry)
+	}, opts, reg)
+}
+
+// destinationFactory Creates a destination in a registry that writes the images with imageSet
+type destinationFactory func(imageSet ctlimgset.ImageSet) ctlimgset.RegistryContentDestination
+
+func repositoryDestination(importRepo regname.Repository) destinationFactory {
+	return func(imageSet ctlimgset.ImageSet) ctlimgset.RegistryContentDestination {
+		return ctlimgset.NewRepositoryDestination(imageSet, importRepo)
+	}
+}
+
+// reportingImageSet Returns the image set of opts that calls opts.OnImageProcessed with each image processed.
+// When sourcesOf is provided, the image is reported once for each one of the sources it returns
+func reportingImageSet(opts CopyOpts, sourcesOf func(ctlimgset.ProcessedImage) []ctlimgset.UnprocessedImageRef) ctlimgset.ImageSet {
+	if opts.OnImageProcessed == nil {
+		return opts.ImageSet
+	}
+	if sourcesOf == nil {
+		return opts.ImageSet.WithProcessedImageHandler(opts.OnImageProcessed)
+	}
+	return opts.ImageSet.WithProcessedImageHandler(func(img ctlimgset.ProcessedImage) {
+		for _, sourceRef := range sourcesOf(img) {
+			img.UnprocessedImageRef = sourceRef
+			opts.OnImageProcessed(img)
+		}
+	})
+}
+
+// copyToRegistryDestination Copies the images of origin to a destination in a registry, records where the images
+// of the bundles were copied to and tags the copied images
+func copyToRegistryDestination(origin CopyOrigin, destination destinationFactory, opts CopyOpts, reg registry.Registry) (*ctlimgset.ProcessedImages, error) {
+	allProcessedImages, err := copyToRegistryDestinations(origin, []destinationFactory{destination}, opts, reg)
+	if err != nil {
+		return nil, err
+	}
+	return allProcessedImages[0], nil
+}
+
+// copyToRegistryDestinations Copies the images of origin to each one of the destinations in a registry. The images are
+// read from origin once, into the first destination, and the following destinations are copied from the first one
+func copyToRegistryDestinations(origin CopyOrigin, destinations []destinationFactory, opts CopyOpts, reg registry.Registry) ([]*ctlimgset.ProcessedImages, error) {
+	var source ctlimgset.ContentSource
+	var noteCopy func(*ctlimgset.ProcessedImages) error
+	sourceRefs := ctlimgset.NewUnprocessedImageRefs()
+	switch {
+	case origin.TarPath != "":
+		source = ctlimgset.NewTarSource(origin.TarPath)
+		noteCopy = func(processedImages *ctlimgset.ProcessedImages) error {
+			return noteCopyOfRootBundles(processedImages, false, reg, opts)
+		}
 
-			if IsRootBundle(processedImage) {
-				if foundRootBundle {
-					panic("Internal inconsistency: expected only 1 root bundle")
+	case origin.OCILayoutPath != "":
+		var err error
+		source, reg, err = ociLayoutSource(origin.OCILayoutPath, opts, reg)
+		if err != nil {
+			return nil, err
+		}
+		noteCopy = func(processedImages *ctlimgset.ProcessedImages) error {
+			// Layouts created by other tools do not mark the root bundles
+			return noteCopyOfRootBundles(processedImages, true, reg, opts)
+		}
+
+	default:
+		exclusions, err := newImageExclusions(origin.ExcludeImages)
+		if err != nil {
+			return nil, err
+		}
+		unprocessedImageRefs, bundles, err := getAllSourceImages(origin, reg, opts)
+		if err != nil {
+			return nil, err
+		}
+		source = ctlimgset.NewRegistrySource(unprocessedImageRefs)
+		sourceRefs = unprocessedImageRefs
+		noteCopy = func(processedImages *ctlimgset.ProcessedImages) error {
+			var isExcluded func(string) bool
+			if !exclusions.Empty() {
+				isExcluded = exclusions.Excludes
+			}
+			for _, bundle := range bundles {
+				if err := bundle.NoteCopyExcluding(processedImages, isExcluded, reg, opts.Logger); err != nil {
+					return fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
 				}
-				foundRootBundle = true
-				pImage := plainimage.NewFetchedPlainImageWithTag(processedImage.DigestRef, processedImage.Tag, processedImage.Image)
-				lockReader := ctlbundle.NewImagesLockReader()
-				parentBundle = ctlbundle.NewBundle(pImage, reg, lockReader, ctlbundle.NewFetcherFromProcessedImages(processedImages.All(), reg, lockReader))
 			}
+			return nil
 		}
+	}
 
-		if foundRootBundle {
-			bundles, _, err := parentBundle.AllImagesLockRefs(opts.Concurrency, opts.Logger)
+	err := prefetchTokens(sourceRefs, destinations, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var allProcessedImages []*ctlimgset.ProcessedImages
+	for _, destination := range destinations {
+		var processedImages *ctlimgset.ProcessedImages
+		if len(allProcessedImages) == 0 {
+			written, err := ctlimgset.Copy(source, destination(reportingImageSet(opts, nil)), reg)
 			if err != nil {
 				return nil, err
 			}
-
-			for _, bundle := range bundles {
-				if err := bundle.NoteCopy(processedImages, reg, opts.Logger); err != nil {
-					return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
-				}
+			processedImages = written.ProcessedImages
+		} else {
+			var err error
+			processedImages, err = copyFromProcessedImages(allProcessedImages[0], destination, opts, reg)
+			if err != nil {
+				return nil, err
 			}
 		}
-	} else {
-		unprocessedImageRefs, bundles, err := getAllSourceImages(origin, reg, opts)
+
+		err := annotateRootBundles(processedImages, reg, opts)
 		if err != nil {
 			return nil, err
 		}
 
-		processedImages, err = opts.ImageSet.Relocate(unprocessedImageRefs, importRepo, reg)
+		err = noteCopy(processedImages)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, bundle := range bundles {
-			if err := bundle.NoteCopy(processedImages, reg, opts.Logger); err != nil {
-				return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
-			}
+		err = tagCopiedImages(reg, opts, processedImages)
+		if err != nil {
+			return nil, err
+		}
+		allProcessedImages = append(allProcessedImages, processedImages)
+	}
+	return allProcessedImages, nil
+}
+
+// prefetchTokens Fetches, when opts.TokenPrefetcher is provided, the tokens to read the images of sourceRefs and to write
+// to every destination, before anything is copied
+func prefetchTokens(sourceRefs *ctlimgset.UnprocessedImageRefs, destinations []destinationFactory, opts CopyOpts) error {
+	if opts.TokenPrefetcher == nil {
+		return nil
+	}
+
+	var pullRepos, pushRepos []regname.Repository
+	for _, img := range sourceRefs.All() {
+		ref, err := regname.ParseReference(img.DigestRef)
+		if err != nil {
+			return fmt.Errorf("Parsing image reference '%s': %s", img.DigestRef, err)
+		}
+		pullRepos = append(pullRepos, ref.Context())
+	}
+	for _, destination := range destinations {
+		repos, err := destination(opts.ImageSet).Repositories(sourceRefs)
+		if err != nil {
+			return err
 		}
+		pushRepos = append(pushRepos, repos...)
+	}
+
+	opts.Logger.Debugf("Fetching registry tokens\n")
+	return opts.TokenPrefetcher.PrefetchTokens(pullRepos, pushRepos, opts.Concurrency)
+}
+
+// copyFromProcessedImages Copies the images that were already copied to a destination to another one.
+// The resulting processed images refer to the images in the original source, as if they had been copied from it
+func copyFromProcessedImages(copiedImages *ctlimgset.ProcessedImages, destination destinationFactory, opts CopyOpts, reg registry.Registry) (*ctlimgset.ProcessedImages, error) {
+	refs := ctlimgset.NewUnprocessedImageRefs()
+	sourceRefs := map[string][]ctlimgset.UnprocessedImageRef{}
+	for _, img := range copiedImages.All() {
+		origRef := img.OrigRef
+		if origRef == "" {
+			origRef = img.UnprocessedImageRef.DigestRef
+		}
+		copiedRef := ctlimgset.UnprocessedImageRef{DigestRef: img.DigestRef, Tag: img.Tag, Labels: img.Labels, OrigRef: origRef}
+		refs.Add(copiedRef)
+		sourceRefs[copiedRef.Key()] = append(sourceRefs[copiedRef.Key()], img.UnprocessedImageRef)
+	}
+
+	imageSet := reportingImageSet(opts, func(img ctlimgset.ProcessedImage) []ctlimgset.UnprocessedImageRef {
+		return sourceRefs[img.UnprocessedImageRef.Key()]
+	})
+	written, err := ctlimgset.Copy(ctlimgset.NewRegistrySource(refs), destination(imageSet), reg)
+	if err != nil {
+		return nil, err
+	}
+
+	processedImages := ctlimgset.NewProcessedImages()
+	for _, img := range written.ProcessedImages.All() {
+		for _, sourceRef := range sourceRefs[img.UnprocessedImageRef.Key()] {
+			img.UnprocessedImageRef = sourceRef
+			processedImages.Add(img)
+		}
+	}
+	return processedImages, nil
+}
+
+func tagCopiedImages(reg registry.Registry, opts CopyOpts, processedImages *ctlimgset.ProcessedImages) error {
+	err := annotateSignatures(processedImages, reg, opts)
+	if err != nil {
+		return err
 	}
 
 	opts.Logger.Logf("Tagging images\n")
 	err = tagAllImages(reg, opts, processedImages)
 	if err != nil {
-		return nil, fmt.Errorf("Tagging images: %s", err)
+		return fmt.Errorf("Tagging images: %s", err)
 	}
+	return nil
+}
 
-	return processedImages, nil
+// ociLayoutSource Returns the source with the images of the OCI image layout in layoutDir and the registry that
+// reads them from the layout, the other images are accessed through reg
+func ociLayoutSource(layoutDir string, opts CopyOpts, reg registry.Registry) (ctlimgset.ContentSource, registry.Registry, error) {
+	layoutPath, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Reading OCI image layout index: %s", err)
+	}
+	layoutReg, err := registry.NewOCILayoutRegistry(layoutPath, reg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctlimgset.NewOCILayoutSource(layoutPath, opts.Logger), layoutReg, nil
+}
+
+// noteCopyOfRootBundles Records the copy information of the root bundles and of all the bundles nested in them.
+// Tarballs and layouts created from multiple bundles contain one root bundle per bundle, when none is
+// found and anyBundleIsRoot is true all the bundles are considered root bundles
+func noteCopyOfRootBundles(processedImages *ctlimgset.ProcessedImages, anyBundleIsRoot bool, reg registry.Registry, opts CopyOpts) error {
+	var parentBundles, allBundles []*ctlbundle.Bundle
+	for _, processedImage := range processedImages.All() {
+		if processedImage.ImageIndex != nil {
+			continue
+		}
+
+		pImage := plainimage.NewFetchedPlainImageWithTag(processedImage.DigestRef, processedImage.Tag, processedImage.Image)
+		lockReader := ctlbundle.NewImagesLockReader()
+		bundle := ctlbundle.NewBundle(pImage, reg, lockReader, ctlbundle.NewFetcherFromProcessedImages(processedImages.All(), reg, lockReader))
+
+		if IsRootBundle(processedImage) || isRootBundlePlatform(processedImage) {
+			parentBundles = append(parentBundles, bundle)
+			continue
+		}
+		if anyBundleIsRoot {
+			isBundle, err := bundle.IsBundle()
+			if err != nil {
+				return err
+			}
+			if isBundle {
+				allBundles = append(allBundles, bundle)
+			}
+		}
+	}
+	if len(parentBundles) == 0 {
+		parentBundles = allBundles
+	}
+
+	notedBundles := map[string]bool{}
+	for _, parentBundle := range parentBundles {
+		bundles, _, err := parentBundle.AllImagesLockRefs(opts.Concurrency, opts.Logger)
+		if err != nil {
+			return err
+		}
+
+		for _, bundle := range bundles {
+			if notedBundles[bundle.DigestRef()] {
+				continue
+			}
+			notedBundles[bundle.DigestRef()] = true
+			if err := bundle.NoteCopy(processedImages, reg, opts.Logger); err != nil {
+				return fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// annotateRootBundles Writes to the destination the root bundles with opts.RootBundleAnnotations added to their manifests,
+// and replaces them in processedImages so that they are the ones tagged and recorded as copied
+func annotateRootBundles(processedImages *ctlimgset.ProcessedImages, reg registry.Registry, opts CopyOpts) error {
+	if len(opts.RootBundleAnnotations) == 0 {
+		return nil
+	}
+
+	annotated := 0
+	for _, processedImage := range processedImages.All() {
+		if processedImage.Image == nil || !IsRootBundle(processedImage) {
+			continue
+		}
+
+		digestRef, err := regname.NewDigest(processedImage.DigestRef)
+		if err != nil {
+			panic(fmt.Sprintf("Internal consistency: %s should be a digest", processedImage.DigestRef))
+		}
+		img := mutate.Annotations(processedImage.Image, opts.RootBundleAnnotations).(regv1.Image)
+		digest, err := img.Digest()
+		if err != nil {
+			return fmt.Errorf("Annotating bundle %s: %s", processedImage.DigestRef, err)
+		}
+		annotatedRef := digestRef.Context().Digest(digest.String())
+
+		opts.Logger.Debugf("Annotating bundle %s as %s\n", processedImage.DigestRef, annotatedRef.Name())
+		err = reg.WriteImage(annotatedRef, img, nil)
+		if err != nil {
+			return fmt.Errorf("Writing annotated bundle %s: %s", annotatedRef.Name(), err)
+		}
+
+		processedImage.DigestRef = annotatedRef.Name()
+		processedImage.Image = img
+		processedImages.Add(processedImage)
+		annotated++
+	}
+	if annotated == 0 {
+		opts.Logger.Warnf("No bundle was copied, the annotations were not added to any image\n")
+	}
+	return nil
+}
+
+// annotateSignatures Writes to the destination the cosign signatures, attestations and SBOMs with opts.SignatureAnnotations
+// added to their manifests, and replaces them in processedImages so that they are the ones tagged and recorded as copied
+func annotateSignatures(processedImages *ctlimgset.ProcessedImages, reg registry.Registry, opts CopyOpts) error {
+	if len(opts.SignatureAnnotations) == 0 {
+		return nil
+	}
+
+	for _, processedImage := range processedImages.All() {
+		if !cosign.IsAttachedTag(processedImage.Tag) {
+			continue
+		}
+
+		digestRef, err := regname.NewDigest(processedImage.DigestRef)
+		if err != nil {
+			panic(fmt.Sprintf("Internal consistency: %s should be a digest", processedImage.DigestRef))
+		}
+
+		var digest regv1.Hash
+		switch {
+		case processedImage.Image != nil:
+			processedImage.Image = mutate.Annotations(processedImage.Image, opts.SignatureAnnotations).(regv1.Image)
+			digest, err = processedImage.Image.Digest()
+		case processedImage.ImageIndex != nil:
+			processedImage.ImageIndex = mutate.Annotations(processedImage.ImageIndex, opts.SignatureAnnotations).(regv1.ImageIndex)
+			digest, err = processedImage.ImageIndex.Digest()
+		default:
+			panic("Unknown item")
+		}
+		if err != nil {
+			return fmt.Errorf("Annotating %s: %s", processedImage.DigestRef, err)
+		}
+		annotatedRef := digestRef.Context().Digest(digest.String())
+
+		opts.Logger.Debugf("Annotating %s as %s\n", processedImage.DigestRef, annotatedRef.Name())
+		if processedImage.Image != nil {
+			err = reg.WriteImage(annotatedRef, processedImage.Image, nil)
+		} else {
+			err = reg.WriteIndex(annotatedRef, processedImage.ImageIndex)
+		}
+		if err != nil {
+			return fmt.Errorf("Writing annotated %s: %s", annotatedRef.Name(), err)
+		}
+
+		processedImage.DigestRef = annotatedRef.Name()
+		processedImages.Add(processedImage)
+	}
+	return nil
 }
 
 // ImageLabels used to retrieve the value of a label from an image
@@ -140,12 +636,63 @@ func IsRootBundle(img ImageLabels) bool {
 	return ok
 }
 
+// isRootBundlePlatform check if a particular bundle is the bundle of a platform of a multi-arch root bundle
+func isRootBundlePlatform(img ImageLabels) bool {
+	_, ok := img.LabelValue(rootBundlePlatformLabelKey)
+	return ok
+}
+
+// rootBundleImageRefs Returns the images to copy for a root bundle. A multi-arch bundle is copied with its
+// OCI Image Index, that is the root bundle, and with the bundle of each of its platforms
+func rootBundleImageRefs(bundle *ctlbundle.Bundle, tag string) ([]ctlimgset.UnprocessedImageRef, error) {
+	if bundle.IndexDigestRef() == "" {
+		return []ctlimgset.UnprocessedImageRef{{
+			DigestRef: bundle.DigestRef(),
+			Tag:       tag,
+			Labels: map[string]string{
+				rootBundleLabelKey: "",
+			},
+			OrigRef: bundle.DigestRef(),
+		}}, nil
+	}
+
+	platformBundles, err := bundle.PlatformBundles()
+	if err != nil {
+		return nil, err
+	}
+	var refs []ctlimgset.UnprocessedImageRef
+	for _, platformBundle := range platformBundles {
+		refs = append(refs, ctlimgset.UnprocessedImageRef{
+			DigestRef: platformBundle.DigestRef(),
+			Labels: map[string]string{
+				rootBundlePlatformLabelKey: "",
+			},
+			OrigRef: platformBundle.DigestRef(),
+		})
+	}
+	return append(refs, ctlimgset.UnprocessedImageRef{
+		DigestRef: bundle.IndexDigestRef(),
+		Tag:       tag,
+		Labels: map[string]string{
+			rootBundleLabelKey: "",
+		},
+		OrigRef: bundle.IndexDigestRef(),
+	}), nil
+}
+
 func getAllSourceImages(origin CopyOrigin, reg registry.Registry, opts CopyOpts) (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
 	unprocessedImageRefs, bundles, err := getProvidedSourceImages(origin, reg, opts)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if opts.SignatureVerifier != nil {
+		err = verifySignatures(unprocessedImageRefs, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	opts.Logger.Debugf("Fetching signatures\n")
 
 	signatures, err := opts.SignatureRetriever.Fetch(unprocessedImageRefs)
@@ -160,6 +707,64 @@ func getAllSourceImages(origin CopyOrigin, reg registry.Registry, opts CopyOpts)
 	return unprocessedImageRefs, bundles, nil
 }
 
+// verifySignatures Verifies the signatures of all the images concurrently, reporting every image that fails verification
+func verifySignatures(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs, opts CopyOpts) error {
+	opts.Logger.Debugf("Verifying signatures\n")
+
+	throttle := util.NewThrottle(max(opts.Concurrency, 1))
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var problems []string
+	for _, img := range unprocessedImageRefs.All() {
+		ref, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return fmt.Errorf("Parsing image reference '%s': %s", img.DigestRef, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			throttle.Take()
+			defer throttle.Done()
+
+			err := opts.SignatureVerifier.Verify(ref)
+			if err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				problems = append(problems, fmt.Sprintf("%s: %s", ref.Name(), err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("Verifying signatures: %d image(s) failed verification, nothing was copied:\n- %s", len(problems), strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+// checkLockTagsAreUnique Ensures that no two images of an ImagesLock would be given the same tag in the destination,
+// since all of them are copied to the same repository
+func checkLockTagsAreUnique(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs) error {
+	taggedImages := map[string]regname.Digest{}
+	for _, ref := range unprocessedImageRefs.All() {
+		if ref.Tag == "" {
+			continue
+		}
+		digest, err := regname.NewDigest(ref.DigestRef)
+		if err != nil {
+			return err
+		}
+		if otherImage, found := taggedImages[ref.Tag]; found && otherImage.DigestStr() != digest.DigestStr() {
+			return fmt.Errorf("Expected images '%s' and '%s' of the ImagesLock to have different tags, both would be tagged '%s' "+
+				"in the destination (hint: remove --preserve-lock-tags)", otherImage.Name(), digest.Name(), ref.Tag)
+		}
+		taggedImages[ref.Tag] = digest
+	}
+	return nil
+}
+
 func getProvidedSourceImages(origin CopyOrigin, reg registry.Registry, opts CopyOpts) (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
 	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
 	switch {
@@ -172,7 +777,7 @@ func getProvidedSourceImages(origin CopyOrigin, reg registry.Registry, opts Copy
 		switch {
 		case bundleLock != nil:
 			opts.Logger.Tracef("get images from BundleLock file\n")
-			_, bundles, imagesRef, err := getBundleImageRefs(bundleLock.Bundle.Image, reg, opts)
+			bundle, bundles, imagesRef, err := getBundleImageRefs(bundleLock.Bundle.Image, reg, opts)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -181,40 +786,48 @@ func getProvidedSourceImages(origin CopyOrigin, reg registry.Registry, opts Copy
 				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation()})
 			}
 
-			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{
-				DigestRef: bundleLock.Bundle.Image,
-				Tag:       bundleLock.Bundle.Tag,
-				Labels: map[string]string{
-					rootBundleLabelKey: "",
-				},
-			})
+			rootRefs, err := rootBundleImageRefs(bundle, bundleLock.Bundle.Tag)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, rootRef := range rootRefs {
+				rootRef.OrigRef = ""
+				unprocessedImageRefs.Add(rootRef)
+			}
 
 			return unprocessedImageRefs, bundles, nil
 
 		case imagesLock != nil:
 			opts.Logger.Tracef("get images from ImagesLock file\n")
-			for _, img := range imagesLock.Images {
-				plainImg := plainimage.NewPlainImage(img.Image, reg)
-
-				ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
-				if err != nil {
-					return nil, nil, err
-				}
-				if ok {
-					return nil, nil, fmt.Errorf("Unable to copy bundles using an Images Lock file (hint: Create a bundle with these images)")
-				}
-
-				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef()})
-			}
-			return unprocessedImageRefs, nil, nil
+			return getImagesLockSourceImages(*imagesLock, origin, reg)
 
 		default:
 			panic("Unreachable")
 		}
 
+	case len(origin.ImageDigestFilePaths) > 0:
+		opts.Logger.Tracef("get images from image digest files\n")
+		imagesLock, err := lockconfig.NewImagesLockFromDigestFiles(origin.ImageDigestFilePaths)
+		if err != nil {
+			return nil, nil, err
+		}
+		return getImagesLockSourceImages(imagesLock, origin, reg)
+
+	case len(origin.BundleRefs) > 0 || len(origin.ImageRefs) > 0:
+		opts.Logger.Tracef("copy multiple bundles and images\n")
+		return getMultipleSourceImages(origin, reg, opts)
+
 	case origin.ImageRef != "":
 		opts.Logger.Tracef("copy single image\n")
-		plainImg := plainimage.NewPlainImage(origin.ImageRef, reg)
+		imageRef := origin.ImageRef
+		if origin.IndexChildPlatform != "" {
+			childRef, err := plainimage.ResolveIndexChild(origin.ImageRef, origin.IndexChildPlatform, reg)
+			if err != nil {
+				return nil, nil, err
+			}
+			imageRef = childRef
+		}
+		plainImg := plainimage.NewPlainImage(imageRef, reg)
 
 		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
 		if err != nil {
@@ -225,6 +838,12 @@ func getProvidedSourceImages(origin CopyOrigin, reg registry.Registry, opts Copy
 		}
 
 		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
+		if origin.AdditionalTags || origin.AllTags {
+			err = addAdditionalTagsSourceImages(unprocessedImageRefs, plainImg, origin.AllTags, reg, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 		return unprocessedImageRefs, nil, nil
 
 	default:
@@ -234,22 +853,60 @@ func getProvidedSourceImages(origin CopyOrigin, reg registry.Registry, opts Copy
 			return nil, nil, err
 		}
 
+		exclusions, err := newImageExclusions(origin.ExcludeImages)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		for _, img := range imagesRef.ImageRefs() {
+			if !*img.IsBundle && exclusions.Excludes(img.Image) {
+				opts.Logger.Logf("Excluding image %s\n", img.Image)
+				continue
+			}
 			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation(), OrigRef: img.Image})
 		}
 
-		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{
-			DigestRef: bundle.DigestRef(),
-			Tag:       bundle.Tag(),
-			Labels: map[string]string{
-				rootBundleLabelKey: "",
-			},
-			OrigRef: bundle.DigestRef()},
-		)
+		rootRefs, err := rootBundleImageRefs(bundle, bundleTag(bundle))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rootRef := range rootRefs {
+			unprocessedImageRefs.Add(rootRef)
+		}
 		return unprocessedImageRefs, allBundles, nil
 	}
 }
 
+// getImagesLockSourceImages Returns the images of the ImagesLock, that cannot reference bundles
+func getImagesLockSourceImages(imagesLock lockconfig.ImagesLock, origin CopyOrigin, reg registry.Registry) (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
+	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
+	for _, img := range imagesLock.Images {
+		plainImg := plainimage.NewPlainImage(img.Image, reg)
+
+		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			return nil, nil, fmt.Errorf("Unable to copy bundles using an Images Lock file (hint: Create a bundle with these images)")
+		}
+
+		unprocessedImageRef := ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef()}
+		if origin.PreserveLockTags {
+			unprocessedImageRef.Tag = img.OriginalTag()
+		}
+		unprocessedImageRefs.Add(unprocessedImageRef)
+	}
+
+	if origin.PreserveLockTags {
+		err := checkLockTagsAreUnique(unprocessedImageRefs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return unprocessedImageRefs, nil, nil
+}
+
 func getBundleImageRefs(bundleRef string, reg registry.Registry, copyOpts CopyOpts) (*ctlbundle.Bundle, []*ctlbundle.Bundle, ctlbundle.ImageRefs, error) {
 	lockReader := ctlbundle.NewImagesLockReader()
 	bundle := ctlbundle.NewBundleFromRef(bundleRef, reg, lockReader, ctlbundle.NewRegistryFetcher(reg, lockReader))
@@ -261,13 +918,48 @@ func getBundleImageRefs(bundleRef string, reg registry.Registry, copyOpts CopyOp
 		return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
 	}
 
-	nestedBundles, imageRefs, err := bundle.AllImagesLockRefs(copyOpts.Concurrency, copyOpts.Logger)
+	platformBundles, err := bundle.PlatformBundles()
 	if err != nil {
-		return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Reading Images from Bundle: %s", err)
+		return nil, nil, ctlbundle.ImageRefs{}, err
+	}
+
+	var nestedBundles []*ctlbundle.Bundle
+	imageRefs := ctlbundle.NewImageRefs()
+	for _, platformBundle := range platformBundles {
+		platformNestedBundles, platformImageRefs, err := platformBundle.AllImagesLockRefs(copyOpts.Concurrency, copyOpts.Logger)
+		if err != nil {
+			return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Reading Images from Bundle: %s", err)
+		}
+		nestedBundles = append(nestedBundles, platformNestedBundles...)
+		imageRefs.AddImagesRef(platformImageRefs.ImageRefs()...)
+	}
+
+	err = ctlbundle.ValidateDependencies(nestedBundles)
+	if err != nil {
+		return nil, nil, ctlbundle.ImageRefs{}, err
+	}
+
+	deprecatedBundles, err := ctlbundle.DeprecatedBundles(nestedBundles)
+	if err != nil {
+		return nil, nil, ctlbundle.ImageRefs{}, err
+	}
+	if len(deprecatedBundles) > 0 && copyOpts.FailOnDeprecated {
+		return nil, nil, ctlbundle.ImageRefs{}, ctlbundle.DeprecationError{Bundles: deprecatedBundles}
+	}
+	for _, deprecated := range deprecatedBundles {
+		copyOpts.Logger.Warnf("%s\n", deprecated)
 	}
 	return bundle, nestedBundles, imageRefs, nil
 }
 
+// bundleTag Tag of the bundle, or of the OCI Image Index of a multi-arch bundle
+func bundleTag(bundle *ctlbundle.Bundle) string {
+	if bundle.IndexDigestRef() != "" {
+		return bundle.IndexTag()
+	}
+	return bundle.Tag()
+}
+
 func tagAllImages(reg registry.Registry, copyOpts CopyOpts, processedImages *ctlimgset.ProcessedImages) error {
 	throttle := util.NewThrottle(copyOpts.Concurrency)
 
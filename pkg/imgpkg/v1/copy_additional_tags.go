@@ -0,0 +1,67 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	ctlbundle "carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// addAdditionalTagsSourceImages Adds the other tags of the repository of the image being copied, so that they are
+// recreated in the destination. When allTags is false only the tags that point to the image digest are added,
+// otherwise every tag of the repository is added together with the image it points to
+func addAdditionalTagsSourceImages(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs, image *plainimage.PlainImage, allTags bool, reg registry.Registry, opts CopyOpts) error {
+	srcRepo, err := regname.NewRepository(image.Repo())
+	if err != nil {
+		return err
+	}
+
+	srcTags, err := reg.ListTags(srcRepo)
+	if err != nil {
+		return fmt.Errorf("Listing tags of %s: %s", srcRepo.Name(), err)
+	}
+	sort.Strings(srcTags)
+
+	var added int
+	for _, tag := range srcTags {
+		if tag == image.Tag() {
+			continue
+		}
+
+		srcDigest, err := reg.Digest(srcRepo.Tag(tag))
+		if err != nil {
+			return fmt.Errorf("Fetching digest of %s: %s", srcRepo.Tag(tag).Name(), err)
+		}
+
+		if srcDigest.String() == image.Digest() {
+			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: image.DigestRef(), Tag: tag})
+			added++
+			continue
+		}
+		if !allTags {
+			continue
+		}
+
+		plainImg := plainimage.NewPlainImage(srcRepo.Digest(srcDigest.String()).Name(), reg)
+		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return fmt.Errorf("Expected tag '%s' of %s to be an image but found a bundle (hint: Use -b to copy each bundle)", tag, srcRepo.Name())
+		}
+
+		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: tag})
+		added++
+	}
+
+	opts.Logger.Logf("Copying %d additional tag(s) of %s\n", added, srcRepo.Name())
+	return nil
+}
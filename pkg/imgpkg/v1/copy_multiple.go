@@ -0,0 +1,70 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+
+	ctlbundle "carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+)
+
+// getMultipleSourceImages Retrieves the images of all the bundles in origin.BundleRefs and the images in origin.ImageRefs.
+// Images shared between bundles are only copied once and each requested bundle is marked as a root bundle
+func getMultipleSourceImages(origin CopyOrigin, reg registry.Registry, opts CopyOpts) (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
+	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
+
+	var rootBundles, allBundles []*ctlbundle.Bundle
+	processedBundles := map[string]bool{}
+	for _, bundleRef := range origin.BundleRefs {
+		opts.Logger.Tracef("copy bundle %s\n", bundleRef)
+		bundle, nestedBundles, imagesRef, err := getBundleImageRefs(bundleRef, reg, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Retrieving images of bundle %s: %s", bundleRef, err)
+		}
+
+		for _, img := range imagesRef.ImageRefs() {
+			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation(), OrigRef: img.Image})
+		}
+
+		for _, nestedBundle := range nestedBundles {
+			if processedBundles[nestedBundle.DigestRef()] {
+				continue
+			}
+			processedBundles[nestedBundle.DigestRef()] = true
+			allBundles = append(allBundles, nestedBundle)
+		}
+		rootBundles = append(rootBundles, bundle)
+	}
+
+	// Root bundles are added last, so they keep the root bundle label when they are also nested in another bundle
+	for _, bundle := range rootBundles {
+		rootRefs, err := rootBundleImageRefs(bundle, bundleTag(bundle))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rootRef := range rootRefs {
+			unprocessedImageRefs.Add(rootRef)
+		}
+	}
+
+	for _, imageRef := range origin.ImageRefs {
+		opts.Logger.Tracef("copy image %s\n", imageRef)
+		plainImg := plainimage.NewPlainImage(imageRef, reg)
+
+		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			return nil, nil, fmt.Errorf("Expected '%s' to be an image but found a bundle (hint: List it under bundles instead of images)", imageRef)
+		}
+
+		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
+	}
+
+	return unprocessedImageRefs, allBundles, nil
+}
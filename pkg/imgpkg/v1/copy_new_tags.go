@@ -0,0 +1,109 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	ctlbundle "carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// getNewTagsSourceImages Selects the tags of the repository origin.ImageRef that match origin.TagPatterns
+// and that do not point to the same digest in the destination repository
+func getNewTagsSourceImages(origin CopyOrigin, importRepo regname.Repository, reg registry.Registry, opts CopyOpts) (*ctlimgset.UnprocessedImageRefs, error) {
+	srcRepo, err := regname.NewRepository(origin.ImageRef)
+	if err != nil {
+		return nil, fmt.Errorf("Expected '%s' to be a repository without tag or digest when copying only new tags: %s", origin.ImageRef, err)
+	}
+
+	srcTags, err := reg.ListTags(srcRepo)
+	if err != nil {
+		return nil, fmt.Errorf("Listing tags of %s: %s", srcRepo.Name(), err)
+	}
+
+	selectedTags, err := selectTags(srcTags, origin.TagPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	dstTags, err := reg.ListTags(importRepo)
+	if err != nil {
+		var tErr *transport.Error
+		if !errors.As(err, &tErr) || tErr.StatusCode != http.StatusNotFound {
+			return nil, fmt.Errorf("Listing tags of %s: %s", importRepo.Name(), err)
+		}
+		// The destination repository does not exist yet, so all tags are new
+	}
+	existingDstTags := map[string]bool{}
+	for _, tag := range dstTags {
+		existingDstTags[tag] = true
+	}
+
+	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
+	var skipped int
+	for _, tag := range selectedTags {
+		srcDigest, err := reg.Digest(srcRepo.Tag(tag))
+		if err != nil {
+			return nil, fmt.Errorf("Fetching digest of %s: %s", srcRepo.Tag(tag).Name(), err)
+		}
+
+		if existingDstTags[tag] {
+			dstDigest, err := reg.Digest(importRepo.Tag(tag))
+			if err != nil {
+				return nil, fmt.Errorf("Fetching digest of %s: %s", importRepo.Tag(tag).Name(), err)
+			}
+			if dstDigest == srcDigest {
+				opts.Logger.Logf("Skipping tag '%s': already present in the destination with digest %s\n", tag, srcDigest)
+				skipped++
+				continue
+			}
+		}
+
+		plainImg := plainimage.NewPlainImage(srcRepo.Digest(srcDigest.String()).Name(), reg)
+		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, fmt.Errorf("Expected tag '%s' of %s to be an image but found a bundle (hint: Use -b to copy each bundle)", tag, srcRepo.Name())
+		}
+
+		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: tag})
+	}
+
+	opts.Logger.Logf("Copying %d new tag(s), skipped %d tag(s) already present in the destination\n", unprocessedImageRefs.Length(), skipped)
+	return unprocessedImageRefs, nil
+}
+
+// selectTags Returns, sorted, the tags that match any of the patterns or all the tags when no pattern is provided
+func selectTags(tags []string, patterns []string) ([]string, error) {
+	var selected []string
+	for _, tag := range tags {
+		matched := len(patterns) == 0
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, tag)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid tag pattern '%s': %s", pattern, err)
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			selected = append(selected, tag)
+		}
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
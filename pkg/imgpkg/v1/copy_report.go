@@ -0,0 +1,275 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+
+	ctlbundle "carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/artifact"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Kinds of images in a CopyReport
+const (
+	BundleCopyReportKind     = "bundle"
+	ImageCopyReportKind      = "image"
+	ImageIndexCopyReportKind = "image index"
+)
+
+// CopyReport Images that a copy would transfer
+type CopyReport struct {
+	Images []CopyReportImage
+	// TotalSize compressed size of all the blobs that would be transferred, blobs shared between images are counted once
+	TotalSize int64
+}
+
+// CopyReportImage Image or image index that a copy would transfer
+type CopyReportImage struct {
+	Ref  string
+	Tag  string
+	Kind string
+	// Layers number of layers that would be transferred, including the ones of the images in an image index
+	Layers int
+	// Size compressed size of the manifests, configs and layers that would be transferred
+	Size int64
+}
+
+// CopyDryRun Enumerates the images, nested bundles and signatures that copying origin would transfer, and their sizes,
+// without writing anything to the destination
+func CopyDryRun(origin CopyOrigin, opts CopyOpts, reg registry.Registry) (CopyReport, error) {
+	opts.Logger.Tracef("CopyDryRun\n")
+
+	if origin.OnlyNewTags {
+		return CopyReport{}, fmt.Errorf("Reporting the images to copy is not possible when copying only new tags")
+	}
+
+	imgOrIndexes, err := readImagesToCopy(origin, opts, reg)
+	if err != nil {
+		return CopyReport{}, err
+	}
+
+	reporter := newCopyReporter(opts)
+	report := CopyReport{}
+	for _, item := range imgOrIndexes {
+		reportImage, err := reporter.reportImageOrIndex(item)
+		if err != nil {
+			return CopyReport{}, fmt.Errorf("Reporting '%s': %s", item.Ref(), err)
+		}
+		report.Images = append(report.Images, reportImage)
+	}
+	for _, size := range reporter.manifests {
+		report.TotalSize += size
+	}
+	for _, size := range reporter.blobs {
+		report.TotalSize += size
+	}
+
+	return report, nil
+}
+
+// readImagesToCopy Reads the images, nested bundles and signatures that copying origin would transfer
+func readImagesToCopy(origin CopyOrigin, opts CopyOpts, reg registry.Registry) ([]imagedesc.ImageOrIndex, error) {
+	var source ctlimgset.ContentSource
+	switch {
+	case origin.TarPath != "":
+		source = ctlimgset.NewTarSource(origin.TarPath)
+	case origin.OCILayoutPath != "":
+		var err error
+		source, reg, err = ociLayoutSource(origin.OCILayoutPath, opts, reg)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		unprocessedImageRefs, _, err := getAllSourceImages(origin, reg, opts)
+		if err != nil {
+			return nil, err
+		}
+		source = ctlimgset.NewRegistrySource(unprocessedImageRefs)
+	}
+
+	content, err := source.Read(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	imgOrIndexes, ok := content.ImagesOrIndexes()
+	if !ok {
+		refs, _ := content.Refs()
+		ids, err := opts.ImageSet.Export(refs, reg)
+		if err != nil {
+			return nil, err
+		}
+		imgOrIndexes = imagedesc.NewDescribedReader(ids, ids).Read()
+	}
+	return imgOrIndexes, nil
+}
+
+// copyReporter Sums the sizes of the blobs of each image and keeps track of all the manifests and blobs
+// (configs and layers) to compute the total size
+type copyReporter struct {
+	layerCheck imagetar.ImageLayerWriterFilter
+	manifests  map[regv1.Hash]int64
+	blobs      map[regv1.Hash]int64
+}
+
+func newCopyReporter(opts CopyOpts) copyReporter {
+	return copyReporter{
+		layerCheck: imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributable),
+		manifests:  map[regv1.Hash]int64{},
+		blobs:      map[regv1.Hash]int64{},
+	}
+}
+
+func (r copyReporter) reportImageOrIndex(item imagedesc.ImageOrIndex) (CopyReportImage, error) {
+	reportImage := CopyReportImage{Ref: item.Ref(), Tag: item.Tag()}
+
+	var err error
+	if item.Image != nil {
+		reportImage.Kind = ImageCopyReportKind
+		reportImage.Layers, reportImage.Size, err = r.imageSize(*item.Image)
+		if err != nil {
+			return CopyReportImage{}, err
+		}
+
+		isBundle, err := isBundleImage(*item.Image)
+		if err != nil {
+			return CopyReportImage{}, err
+		}
+		if isBundle {
+			reportImage.Kind = BundleCopyReportKind
+		}
+		return reportImage, nil
+	}
+
+	reportImage.Kind = ImageIndexCopyReportKind
+	reportImage.Layers, reportImage.Size, err = r.indexSize(*item.Index)
+	return reportImage, err
+}
+
+func (r copyReporter) imageSize(img regv1.Image) (int, int64, error) {
+	manifestSize, err := r.addManifest(img)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, 0, err
+	}
+	r.blobs[manifest.Config.Digest] = manifest.Config.Size
+	size := manifestSize + manifest.Config.Size
+
+	layers, err := img.Layers()
+	if err != nil {
+		return 0, 0, err
+	}
+	numLayers := 0
+	for _, layer := range layers {
+		include, err := r.layerCheck.ShouldLayerBeIncluded(layer)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !include {
+			continue
+		}
+
+		digest, err := layer.Digest()
+		if err != nil {
+			return 0, 0, err
+		}
+		layerSize, err := layer.Size()
+		if err != nil {
+			return 0, 0, err
+		}
+		r.blobs[digest] = layerSize
+		size += layerSize
+		numLayers++
+	}
+
+	return numLayers, size, nil
+}
+
+func (r copyReporter) indexSize(index regv1.ImageIndex) (int, int64, error) {
+	size, err := r.addManifest(index)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	numLayers := 0
+	for _, child := range indexManifest.Manifests {
+		var childLayers int
+		var childSize int64
+		switch {
+		case child.MediaType.IsIndex():
+			childIndex, err := index.ImageIndex(child.Digest)
+			if err != nil {
+				return 0, 0, err
+			}
+			childLayers, childSize, err = r.indexSize(childIndex)
+			if err != nil {
+				return 0, 0, err
+			}
+
+		case child.MediaType.IsImage():
+			childImage, err := index.Image(child.Digest)
+			if err != nil {
+				return 0, 0, err
+			}
+			childLayers, childSize, err = r.imageSize(childImage)
+			if err != nil {
+				return 0, 0, err
+			}
+
+		default:
+			// Artifacts other than images are copied as they are, only their manifest is accounted for
+			r.manifests[child.Digest] = child.Size
+			childSize = child.Size
+		}
+		numLayers += childLayers
+		size += childSize
+	}
+
+	return numLayers, size, nil
+}
+
+type sizedManifest interface {
+	RawManifest() ([]byte, error)
+	Digest() (regv1.Hash, error)
+}
+
+func (r copyReporter) addManifest(manifest sizedManifest) (int64, error) {
+	raw, err := manifest.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+	digest, err := manifest.Digest()
+	if err != nil {
+		return 0, err
+	}
+	r.manifests[digest] = int64(len(raw))
+	return int64(len(raw)), nil
+}
+
+func isBundleImage(img regv1.Image) (bool, error) {
+	hasImageConfig, err := artifact.HasImageConfig(img)
+	if err != nil || !hasImageConfig {
+		return false, err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return false, err
+	}
+	_, isBundle := cfg.Config.Labels[ctlbundle.BundleConfigLabel]
+	return isBundle, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// copySignaturesOnly Copies to importRepo the signatures, attestations and SBOMs of the images of origin that were
+// already copied to it, skipping the ones that are already in it, so that the ones added to the source after the
+// images were copied can be synced without copying the images again
+func copySignaturesOnly(origin CopyOrigin, importRepo regname.Repository, opts CopyOpts, reg registry.Registry) (*ctlimgset.ProcessedImages, error) {
+	if origin.TarPath != "" || origin.OCILayoutPath != "" {
+		return nil, fmt.Errorf("Copying only signatures is only possible when copying from a registry")
+	}
+
+	unprocessedImageRefs, _, err := getProvidedSourceImages(origin, reg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	copiedImageRefs := ctlimgset.NewUnprocessedImageRefs()
+	for _, img := range unprocessedImageRefs.All() {
+		copied, err := existsInRepository(img, importRepo, reg)
+		if err != nil {
+			return nil, err
+		}
+		if !copied {
+			opts.Logger.Warnf("Skipping the signatures of image '%s', it was not copied to '%s'\n", img.DigestRef, importRepo.Name())
+			continue
+		}
+		copiedImageRefs.Add(img)
+	}
+
+	opts.Logger.Debugf("Fetching signatures\n")
+	signatures, err := opts.SignatureRetriever.Fetch(copiedImageRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	newSignatures := ctlimgset.NewUnprocessedImageRefs()
+	for _, sig := range signatures.All() {
+		copied, err := existsInRepository(sig, importRepo, reg)
+		if err != nil {
+			return nil, err
+		}
+		if !copied {
+			newSignatures.Add(sig)
+		}
+	}
+
+	processedImages := ctlimgset.NewProcessedImages()
+	if newSignatures.Length() == 0 {
+		opts.Logger.Logf("No new signatures to copy to '%s'\n", importRepo.Name())
+		return processedImages, nil
+	}
+
+	opts.Logger.Logf("Copying %d new signature(s) to '%s'\n", newSignatures.Length(), importRepo.Name())
+	destination := ctlimgset.NewRepositoryDestination(reportingImageSet(opts, nil), importRepo)
+	written, err := ctlimgset.Copy(ctlimgset.NewRegistrySource(newSignatures), destination, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tagCopiedImages(reg, opts, written.ProcessedImages)
+	if err != nil {
+		return nil, err
+	}
+	return written.ProcessedImages, nil
+}
+
+// existsInRepository Returns true when the manifest of the image is in importRepo, the images that cannot be found
+// in it for any reason are considered as not copied
+func existsInRepository(img ctlimgset.UnprocessedImageRef, importRepo regname.Repository, reg registry.Registry) (bool, error) {
+	digestRef, err := regname.NewDigest(img.DigestRef)
+	if err != nil {
+		return false, fmt.Errorf("Parsing image reference '%s': %s", img.DigestRef, err)
+	}
+
+	_, err = reg.FirstImageExists([]string{importRepo.Digest(digestRef.DigestStr()).Name()})
+	return err == nil, nil
+}
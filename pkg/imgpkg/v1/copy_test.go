@@ -6,6 +6,10 @@ package v1_test
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,22 +17,33 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
 	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
 	"carvel.dev/imgpkg/pkg/imgpkg/imagedesc"
 	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
 	"carvel.dev/imgpkg/pkg/imgpkg/imagetar"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/journal"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"carvel.dev/imgpkg/pkg/imgpkg/signature"
 	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
 	"carvel.dev/imgpkg/test/helpers"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -234,7 +249,7 @@ func TestToTarImage(t *testing.T) {
 	t.Run("When copy to tar fails the first time but second call with resume completes successfully", func(t *testing.T) {
 		numberOfRequests := 0
 		var failedDigest regv1.Hash
-		var layersInTar []regv1.Layer
+		var journaledLayers []journal.Entry
 		fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
 			matched, err := regexp.MatchString("/v2/.+/blobs", request.URL.Path)
 			require.NoError(t, err)
@@ -243,15 +258,11 @@ func TestToTarImage(t *testing.T) {
 				parts := strings.Split(request.URL.Path, "/")
 				sha := parts[len(parts)-1]
 				hash, err := regv1.NewHash(sha)
-				// This loop ensures that if a layer is in the tar already we can return gibberish\
+				// This loop ensures that if a layer is in the journal already we can return gibberish\
 				// because imgpkg is not going to use this information
-				for _, layer := range layersInTar {
-					digest, err := layer.Digest()
-					require.NoError(t, err)
-					if hash.String() == digest.String() {
-						size, err := layer.Size()
-						require.NoError(t, err)
-						bs := make([]byte, size)
+				for _, entry := range journaledLayers {
+					if hash.String() == entry.Digest {
+						bs := make([]byte, entry.Size)
 						writer.Write(bs)
 						return true
 					}
@@ -282,21 +293,26 @@ func TestToTarImage(t *testing.T) {
 			return false
 		})
 
-		imageTarPath := filepath.Join(os.TempDir(), " imgpkg-test-img.tar")
-		if _, err := os.Stat(imageTarPath); err == nil {
-			os.Remove(imageTarPath)
-		}
-		defer os.Remove(imageTarPath)
+		tarDir := t.TempDir()
+		imageTarPath := filepath.Join(tarDir, " imgpkg-test-img.tar")
 
 		origin := origin
 		origin.ImageRef = fakeRegistry.ReferenceOnTestServer(randomImageName)
 
 		_, err := v1.CopyToTar(origin, imageTarPath, opts, reg)
 		require.ErrorContains(t, err, "error verifying sha256 checksum")
-		layersInTar, err = imagetar.NewTarReader(imageTarPath).PresentLayers()
+		require.NoFileExists(t, imageTarPath, "an incomplete tar should never be written to the destination")
+
+		copyJournal, err := journal.Open(imageTarPath + ".journal")
 		require.NoError(t, err)
-		require.Greater(t, len(layersInTar), 1)
-		require.NotContains(t, layersInTar, failedDigest, "tar should not contain the layer that fails to download")
+		journaledLayers = copyJournal.Entries()
+		require.NoError(t, copyJournal.Close())
+		var journaledDigests []string
+		for _, entry := range journaledLayers {
+			journaledDigests = append(journaledDigests, entry.Digest)
+		}
+		require.Greater(t, len(journaledDigests), 1)
+		require.NotContains(t, journaledDigests, failedDigest.String(), "journal should not contain the layer that fails to download")
 
 		opts := opts
 		opts.Resume = true
@@ -304,9 +320,95 @@ func TestToTarImage(t *testing.T) {
 		require.NoError(t, err)
 
 		assertTarballContainsEveryLayer(t, imageTarPath)
+
+		leftovers, err := os.ReadDir(tarDir)
+		require.NoError(t, err)
+		require.Len(t, leftovers, 1, "journal and partial tars should be removed after a successful copy")
+	})
+}
+
+func TestToTarResumeWithDifferentOptions(t *testing.T) {
+	randomImageName := "my/image/one"
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	fakeRegistry.WithRandomImageWithLayers(randomImageName, 3)
+	defer fakeRegistry.CleanUp()
+
+	origin, opts, reg := testSetup(fakeRegistry, randomImageName, "", "", "")
+	withOptions := func(version string, includeNonDistributable bool, resume bool) v1.CopyOpts {
+		newOpts := opts
+		newOpts.ImageSet = imageset.NewImageSet(1, opts.Logger, util.DefaultTagGenerator{}).WithCopyOptions(journal.Options{
+			ImgpkgVersion: version,
+			Settings:      map[string]string{"include-non-distributable-layers": fmt.Sprintf("%t", includeNonDistributable)},
+		})
+		newOpts.TarImageSet = imageset.NewTarImageSet(newOpts.ImageSet, 1, opts.Logger)
+		newOpts.Resume = resume
+		return newOpts
+	}
+
+	t.Run("when the tar was created with different options, it fails explaining the differences", func(t *testing.T) {
+		imageTarPath := filepath.Join(t.TempDir(), "image.tar")
+		_, err := v1.CopyToTar(origin, imageTarPath, withOptions("0.40.0", false, false), reg)
+		require.NoError(t, err)
+
+		_, err = v1.CopyToTar(origin, imageTarPath, withOptions("0.41.0", true, true), reg)
+		require.ErrorContains(t, err, "Unable to resume a copy started by imgpkg 0.40.0 with different options (current imgpkg 0.41.0)")
+		require.ErrorContains(t, err, "- include-non-distributable-layers: was 'false', is now 'true'")
+
+		_, err = v1.CopyToTar(origin, imageTarPath, withOptions("0.41.0", false, true), reg)
+		require.NoError(t, err)
+		assertTarballContainsEveryLayer(t, imageTarPath)
+	})
+
+	t.Run("when the journal was created with different options, it fails explaining the differences", func(t *testing.T) {
+		imageTarPath := filepath.Join(t.TempDir(), "image.tar")
+		copyJournal, err := journal.Open(imageTarPath + ".journal")
+		require.NoError(t, err)
+		require.NoError(t, copyJournal.CheckOptions(journal.Options{
+			ImgpkgVersion: "0.40.0",
+			Settings:      map[string]string{"include-non-distributable-layers": "true"},
+		}))
+		require.NoError(t, copyJournal.Close())
+
+		_, err = v1.CopyToTar(origin, imageTarPath, withOptions("0.40.0", false, true), reg)
+		require.ErrorContains(t, err, "- include-non-distributable-layers: was 'true', is now 'false'")
+	})
+
+	t.Run("when the tar was created by a version of imgpkg that does not record the options, it fails", func(t *testing.T) {
+		imageTarPath := filepath.Join(t.TempDir(), "image.tar")
+		_, err := v1.CopyToTar(origin, imageTarPath, opts, reg)
+		require.NoError(t, err)
+		removeTarEntry(t, imageTarPath, "imgpkg-options.json")
+
+		_, err = v1.CopyToTar(origin, imageTarPath, withOptions("0.41.0", false, true), reg)
+		require.ErrorContains(t, err, "created by a version of imgpkg that does not record the copy options")
 	})
 }
 
+func removeTarEntry(t *testing.T, path string, entryName string) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	result := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(result)
+	tarReader := tar.NewReader(file)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Name == entryName {
+			continue
+		}
+		require.NoError(t, tarWriter.WriteHeader(hdr))
+		_, err = io.Copy(tarWriter, tarReader)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, os.WriteFile(path, result.Bytes(), 0600))
+}
+
 func TestToTarImageContainingNonDistributableLayers(t *testing.T) {
 	imageName := "library/image"
 	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
@@ -380,6 +482,28 @@ func TestToRepoImageIndex(t *testing.T) {
 		require.Len(t, manifest.Manifests, int(expectedNumOfImagesForImgIndex))
 	})
 
+	t.Run("should mount the layers of every image from the source repository instead of uploading them", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistryWithRepoSeparation(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		imageIndexRefDigest := fakeRegistry.WithARandomImageIndex("library/image-to-mount", expectedNumOfImagesForImgIndex).RefDigest
+		origin := origin
+		origin.ImageRef = imageIndexRefDigest
+		reg := fakeRegistry.Build()
+		requestLog := fakeRegistry.WithRequestLogging()
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/mounted-img"), opts, reg)
+		require.NoError(t, err)
+
+		mounts := 0
+		for _, request := range requestLog.All() {
+			assert.NotEqual(t, "PATCH", request.Method, "no blob should be uploaded: %s", request.URL)
+			if request.Method == "POST" && strings.Contains(request.URL, "mount=") {
+				mounts++
+			}
+		}
+		assert.NotZero(t, mounts)
+	})
+
 	t.Run("with an ImagesLock file should copy every image to repo", func(t *testing.T) {
 		assets := &helpers.Assets{T: t}
 		defer assets.CleanCreatedFolders()
@@ -1141,6 +1265,134 @@ images:
 	})
 }
 
+func TestToRepoRequiringSignatures(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img1 := fakeRegistry.WithRandomImage("library/image-1")
+	img2 := fakeRegistry.WithRandomImage("library/image-2")
+	bundleInfo := fakeRegistry.WithRandomBundle("library/bundle").WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}, {Image: img2.RefDigest}})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	origin.ImageRef = ""
+	origin.BundleRef = bundleInfo.RefDigest
+
+	t.Run("when an image is not signed, it fails listing it and copies nothing", func(t *testing.T) {
+		opts := opts
+		opts.SignatureVerifier = &fakeSignatureVerifier{signed: map[string]bool{bundleInfo.Digest: true, img1.Digest: true}}
+		destination := fakeRegistry.ReferenceOnTestServer("library/not-copied")
+
+		_, err := v1.CopyToRepository(origin, destination, opts, reg)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "Verifying signatures: 1 image(s) failed verification, nothing was copied")
+		assert.ErrorContains(t, err, img2.RefDigest+": not signed")
+
+		copiedBundle, err := name.NewDigest(destination + "@" + bundleInfo.Digest)
+		require.NoError(t, err)
+		_, err = reg.Digest(copiedBundle)
+		require.Error(t, err)
+	})
+
+	t.Run("when every image is signed, it copies the bundle", func(t *testing.T) {
+		opts := opts
+		opts.SignatureVerifier = &fakeSignatureVerifier{signed: map[string]bool{bundleInfo.Digest: true, img1.Digest: true, img2.Digest: true}}
+
+		processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied"), opts, reg)
+		require.NoError(t, err)
+		require.Len(t, processedImages.All(), 3)
+	})
+}
+
+func TestToRepoWithRootBundleAnnotations(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img1 := fakeRegistry.WithRandomImage("library/image-1")
+	bundleInfo := fakeRegistry.WithRandomBundle("library/bundle").WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	origin.ImageRef = ""
+	origin.BundleRef = bundleInfo.RefDigest
+	opts.RootBundleAnnotations = map[string]string{"relocated-by": "jane"}
+
+	processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied"), opts, reg)
+	require.NoError(t, err)
+	require.Len(t, processedImages.All(), 2)
+
+	var copiedBundle, copiedImage imageset.ProcessedImage
+	for _, processedImage := range processedImages.All() {
+		if processedImage.UnprocessedImageRef.DigestRef == bundleInfo.RefDigest {
+			copiedBundle = processedImage
+		} else {
+			copiedImage = processedImage
+		}
+	}
+	assert.False(t, strings.HasSuffix(copiedBundle.DigestRef, bundleInfo.Digest), "the annotated bundle should have a new digest")
+	assert.True(t, strings.HasSuffix(copiedImage.DigestRef, img1.Digest), "the images of the bundle should not be modified")
+
+	description, err := v1.DescribeWithRegistryAndSignatureFetcher(copiedBundle.DigestRef, v1.DescribeOpts{Logger: opts.Logger, Concurrency: 1}, reg, &fakeSignatureRetriever{})
+	require.NoError(t, err)
+	assert.Equal(t, "jane", description.ManifestAnnotations["relocated-by"])
+	assert.Contains(t, description.Content.Images, img1.Digest)
+}
+
+func TestToRegistryPrefetchingTokens(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img1 := fakeRegistry.WithRandomImage("library/image-1")
+	img2 := fakeRegistry.WithRandomImage("library/image-2")
+	bundleInfo := fakeRegistry.WithRandomBundle("library/bundle").WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}, {Image: img2.RefDigest}})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	origin.ImageRef = ""
+	origin.BundleRef = bundleInfo.RefDigest
+	destRegistry := strings.Split(fakeRegistry.ReferenceOnTestServer("library/bundle"), "/")[0]
+
+	t.Run("when a repository cannot be accessed, it fails before copying anything", func(t *testing.T) {
+		opts := opts
+		prefetcher := &fakeTokenPrefetcher{err: fmt.Errorf("denied")}
+		opts.TokenPrefetcher = prefetcher
+
+		_, err := v1.CopyToRegistry(origin, destRegistry, opts, reg)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "denied")
+
+		assert.ElementsMatch(t, []string{
+			fakeRegistry.ReferenceOnTestServer("library/bundle"),
+			fakeRegistry.ReferenceOnTestServer("library/image-1"),
+			fakeRegistry.ReferenceOnTestServer("library/image-2"),
+		}, prefetcher.pullRepos)
+		assert.ElementsMatch(t, []string{
+			destRegistry + "/library/bundle",
+			destRegistry + "/library/image-1",
+			destRegistry + "/library/image-2",
+		}, prefetcher.pushRepos)
+	})
+
+	t.Run("when every repository can be accessed, it copies the bundle", func(t *testing.T) {
+		opts := opts
+		opts.TokenPrefetcher = &fakeTokenPrefetcher{}
+
+		processedImages, err := v1.CopyToRegistry(origin, destRegistry, opts, reg)
+		require.NoError(t, err)
+		require.Len(t, processedImages.All(), 3)
+	})
+}
+
+type fakeTokenPrefetcher struct {
+	err       error
+	pullRepos []string
+	pushRepos []string
+}
+
+func (f *fakeTokenPrefetcher) PrefetchTokens(pullRepos []name.Repository, pushRepos []name.Repository, _ int) error {
+	for _, repo := range pullRepos {
+		f.pullRepos = append(f.pullRepos, repo.Name())
+	}
+	for _, repo := range pushRepos {
+		f.pushRepos = append(f.pushRepos, repo.Name())
+	}
+	return f.err
+}
+
 type fakeSignatureRetriever struct {
 }
 
@@ -1154,6 +1406,17 @@ func (f fakeSignatureRetriever) Fetch(_ *imageset.UnprocessedImageRefs) (*images
 
 var _ v1.SignatureFetcher = new(fakeSignatureRetriever)
 
+type fakeSignatureVerifier struct {
+	signed map[string]bool
+}
+
+func (f *fakeSignatureVerifier) Verify(imageRef name.Digest) error {
+	if !f.signed[imageRef.DigestStr()] {
+		return fmt.Errorf("not signed")
+	}
+	return nil
+}
+
 func assertTarballContainsEveryLayer(t *testing.T, imageTarPath string) {
 	path := imagetar.NewTarReader(imageTarPath)
 	imageOrIndex, err := path.Read()
@@ -1280,3 +1543,2054 @@ func downloadImagesLocation(t *testing.T, imgRef, location string) {
 	err = ctlimg.NewDirImage(filepath.Join(location), img, util.NewBufferLogger(output)).AsDirectory()
 	require.NoError(t, err)
 }
+
+func TestToRepoBundleFromOCILayout(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: randomImage.RefDigest},
+	})
+	// The bundle only exists in the OCI image layout, not in the registry
+	fakeRegistry.RemoveByImageRef("library/bundle@" + bundleInfo.Digest)
+
+	layoutDir := t.TempDir()
+	helpers.WriteOCILayout(t, layoutDir, fakeRegistry.ReferenceOnTestServer("library/bundle"), bundleInfo.Image)
+
+	bundleRef, layoutPath, err := registry.OCILayoutReference("oci:" + layoutDir)
+	require.NoError(t, err)
+	assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/bundle")+"@"+bundleInfo.Digest, bundleRef)
+
+	_, opts, _ := testSetup(nil, "", "", "", "")
+	reg, err := registry.NewOCILayoutRegistry(layoutPath, fakeRegistry.BuildWithRegistryOpts(registry.Opts{
+		EnvironFunc: os.Environ,
+		RetryCount:  3,
+	}))
+	require.NoError(t, err)
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+	processedImages, err := v1.CopyToRepository(v1.CopyOrigin{BundleRef: bundleRef}, destRepo, opts, reg)
+	require.NoError(t, err)
+
+	require.Len(t, processedImages.All(), 2)
+	assertion := &helpers.Assertion{T: t}
+	assert.NoError(t, assertion.ValidateImagesPresenceInRegistry([]string{
+		destRepo + "@" + bundleInfo.Digest,
+		destRepo + "@" + randomImage.Digest,
+	}))
+}
+
+func TestToOCILayout(t *testing.T) {
+	t.Run("when copying a bundle, it writes an OCI image layout that can be copied to a repository", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		randomImage := fakeRegistry.WithRandomImage("library/image")
+		bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+		origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+		layoutDir := filepath.Join(t.TempDir(), "layout")
+
+		_, err := v1.CopyToOCILayout(origin, layoutDir, opts, reg)
+		require.NoError(t, err)
+
+		assert.FileExists(t, filepath.Join(layoutDir, "oci-layout"))
+		indexContent, err := os.ReadFile(filepath.Join(layoutDir, "index.json"))
+		require.NoError(t, err)
+		index, err := regv1.ParseIndexManifest(bytes.NewReader(indexContent))
+		require.NoError(t, err)
+		require.Len(t, index.Manifests, 2)
+		for _, desc := range index.Manifests {
+			_, isRoot := desc.Annotations["dev.carvel.imgpkg.copy.root-bundle"]
+			assert.Equal(t, desc.Digest.String() == bundleInfo.Digest, isRoot)
+		}
+
+		layers, err := randomImage.Image.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(layoutDir, "blobs", digest.Algorithm, digest.Hex))
+		}
+
+		// The images only exist in the OCI image layout, not in the registry
+		fakeRegistry.RemoveByImageRef("library/bundle@" + bundleInfo.Digest)
+		fakeRegistry.RemoveByImageRef("library/image@" + randomImage.Digest)
+
+		bundleRef, layoutPath, err := registry.OCILayoutReference("oci:" + layoutDir)
+		require.NoError(t, err)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/bundle")+"@"+bundleInfo.Digest, bundleRef)
+		layoutReg, err := registry.NewOCILayoutRegistry(layoutPath, fakeRegistry.BuildWithRegistryOpts(registry.Opts{
+			EnvironFunc: os.Environ,
+			RetryCount:  3,
+		}))
+		require.NoError(t, err)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+		_, err = v1.CopyToRepository(v1.CopyOrigin{BundleRef: bundleRef}, destRepo, opts, layoutReg)
+		require.NoError(t, err)
+
+		assertion := &helpers.Assertion{T: t}
+		assert.NoError(t, assertion.ValidateImagesPresenceInRegistry([]string{
+			destRepo + "@" + bundleInfo.Digest,
+			destRepo + "@" + randomImage.Digest,
+		}))
+	})
+
+	t.Run("when copying an image index, it writes the manifests of every image in the index", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		imageIndex := fakeRegistry.WithARandomImageIndex("library/imageindex", 3)
+
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		layoutDir := t.TempDir()
+
+		_, err := v1.CopyToOCILayout(origin, layoutDir, opts, reg)
+		require.NoError(t, err)
+
+		indexManifest, err := imageIndex.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, indexManifest.Manifests, 3)
+		for _, desc := range indexManifest.Manifests {
+			assert.FileExists(t, filepath.Join(layoutDir, "blobs", desc.Digest.Algorithm, desc.Digest.Hex))
+		}
+	})
+}
+
+func TestToRepoFromOCILayout(t *testing.T) {
+	t.Run("when the layout was written by imgpkg, it copies the bundle and records the location of its images", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		randomImage := fakeRegistry.WithRandomImage("library/image")
+		bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+		origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+		layoutDir := t.TempDir()
+		_, err := v1.CopyToOCILayout(origin, layoutDir, opts, reg)
+		require.NoError(t, err)
+
+		// The images only exist in the OCI image layout, not in the registry
+		fakeRegistry.RemoveByImageRef("library/bundle@" + bundleInfo.Digest)
+		fakeRegistry.RemoveByImageRef("library/image@" + randomImage.Digest)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+		processedImages, err := v1.CopyToRepository(v1.CopyOrigin{OCILayoutPath: layoutDir}, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 2)
+		var rootBundles []string
+		for _, processedImage := range processedImages.All() {
+			if v1.IsRootBundle(processedImage) {
+				rootBundles = append(rootBundles, processedImage.DigestRef)
+			}
+		}
+		assert.Equal(t, []string{destRepo + "@" + bundleInfo.Digest}, rootBundles)
+
+		assertion := &helpers.Assertion{T: t}
+		assert.NoError(t, assertion.ValidateImagesPresenceInRegistry([]string{
+			destRepo + "@" + bundleInfo.Digest,
+			destRepo + "@" + randomImage.Digest,
+			fmt.Sprintf("%s:%s.image-locations.imgpkg", destRepo, strings.ReplaceAll(bundleInfo.Digest, ":", "-")),
+		}))
+	})
+
+	t.Run("when the layout was written by other tools, it copies all the images and records the location of the bundle images", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		randomImage := fakeRegistry.WithRandomImage("library/image")
+		bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+		fakeRegistry.RemoveByImageRef("library/bundle@" + bundleInfo.Digest)
+		fakeRegistry.RemoveByImageRef("library/image@" + randomImage.Digest)
+
+		// The manifests in the layout index do not have the references of the images
+		layoutPath, err := layout.Write(t.TempDir(), empty.Index)
+		require.NoError(t, err)
+		require.NoError(t, layoutPath.AppendImage(bundleInfo.Image))
+		require.NoError(t, layoutPath.AppendImage(randomImage.Image))
+
+		_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+		processedImages, err := v1.CopyToRepository(v1.CopyOrigin{OCILayoutPath: string(layoutPath)}, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 2)
+		assertion := &helpers.Assertion{T: t}
+		assert.NoError(t, assertion.ValidateImagesPresenceInRegistry([]string{
+			destRepo + "@" + bundleInfo.Digest,
+			destRepo + "@" + randomImage.Digest,
+			fmt.Sprintf("%s:%s.image-locations.imgpkg", destRepo, strings.ReplaceAll(bundleInfo.Digest, ":", "-")),
+		}))
+	})
+
+	t.Run("when the destination is a tar, it returns an error", func(t *testing.T) {
+		_, opts, reg := testSetup(nil, "", "", "", "")
+		_, err := v1.CopyToTar(v1.CopyOrigin{OCILayoutPath: t.TempDir()}, filepath.Join(t.TempDir(), "bundle.tar"), opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Copying from an OCI image layout is only possible when copying to a repository")
+	})
+}
+
+func TestToRegistry(t *testing.T) {
+	sourceRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer sourceRegistry.CleanUp()
+	destinationRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destinationRegistry.CleanUp()
+
+	libraryImage := sourceRegistry.WithRandomImage("library/image")
+	otherImage := sourceRegistry.WithRandomImage("other/namespace/image")
+	bundleInfo := sourceRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: libraryImage.RefDigest},
+		{Image: otherImage.RefDigest},
+	})
+	destinationRegistry.Build()
+
+	assertCopiedToRegistry := func(t *testing.T, processedImages *imageset.ProcessedImages) {
+		bundleRepo := destinationRegistry.ReferenceOnTestServer("library/bundle")
+		libraryImageRepo := destinationRegistry.ReferenceOnTestServer("library/image")
+		otherImageRepo := destinationRegistry.ReferenceOnTestServer("other/namespace/image")
+
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			bundleRepo + "@" + bundleInfo.Digest,
+			libraryImageRepo + "@" + libraryImage.Digest,
+			otherImageRepo + "@" + otherImage.Digest,
+		}, processedImageDigests)
+
+		locationImg := fmt.Sprintf("%s:%s.image-locations.imgpkg", bundleRepo, strings.ReplaceAll(bundleInfo.Digest, ":", "-"))
+		require.NoError(t, validateImagesPresenceInRegistry(t, append(processedImageDigests, locationImg)))
+
+		locationImgFolder := t.TempDir()
+		downloadImagesLocation(t, locationImg, locationImgFolder)
+		cfg, err := bundle.NewLocationConfigFromPath(filepath.Join(locationImgFolder, "image-locations.yml"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []bundle.ImageLocation{
+			{Image: libraryImage.RefDigest, IsBundle: false, Repository: libraryImageRepo},
+			{Image: otherImage.RefDigest, IsBundle: false, Repository: otherImageRepo},
+		}, cfg.Images)
+	}
+
+	t.Run("when copying a bundle, it copies every image to the repository with the same path in the registry", func(t *testing.T) {
+		origin, opts, reg := testSetup(sourceRegistry, "", "library/bundle", "", "")
+
+		processedImages, err := v1.CopyToRegistry(origin, destinationRegistry.Host(), opts, reg)
+		require.NoError(t, err)
+
+		assertCopiedToRegistry(t, processedImages)
+
+		outputFolder := t.TempDir()
+		pullOpts := v1.PullOpts{Logger: util.NewNoopLevelLogger(), IsBundle: true}
+		_, err = v1.PullWithRegistry(destinationRegistry.ReferenceOnTestServer("library/bundle")+"@"+bundleInfo.Digest, outputFolder, pullOpts, reg)
+		require.NoError(t, err)
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputFolder, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			destinationRegistry.ReferenceOnTestServer("library/image") + "@" + libraryImage.Digest,
+			destinationRegistry.ReferenceOnTestServer("other/namespace/image") + "@" + otherImage.Digest,
+		}, []string{imagesLock.Images[0].Image, imagesLock.Images[1].Image})
+	})
+
+	t.Run("when copying a bundle from a tar, it copies every image to the repository with the same path it had before being exported", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		origin, opts, reg := testSetup(sourceRegistry, "", "library/bundle", "", "")
+		_, err := v1.CopyToTar(origin, tarPath, opts, reg)
+		require.NoError(t, err)
+
+		processedImages, err := v1.CopyToRegistry(v1.CopyOrigin{TarPath: tarPath}, destinationRegistry.Host(), opts, reg)
+		require.NoError(t, err)
+
+		assertCopiedToRegistry(t, processedImages)
+	})
+
+	t.Run("when copying from an OCI image layout, it returns an error", func(t *testing.T) {
+		_, opts, reg := testSetup(nil, "", "", "", "")
+		_, err := v1.CopyToRegistry(v1.CopyOrigin{OCILayoutPath: t.TempDir()}, destinationRegistry.Host(), opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Copying from an OCI image layout is only possible when copying to a repository")
+	})
+}
+
+func TestToRepoStreamsBlobsBetweenRegistries(t *testing.T) {
+	sourceRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer sourceRegistry.CleanUp()
+	destinationRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destinationRegistry.CleanUp()
+
+	randomImage := sourceRegistry.WithRandomImage("library/image")
+	layers, err := randomImage.Image.Layers()
+	require.NoError(t, err)
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	origin, opts, reg := testSetup(sourceRegistry, "", "", "", "")
+	origin.ImageRef = randomImage.RefDigest
+	destinationRegistry.Build()
+
+	t.Run("when a blob does not match its digest, it fails before the blob is committed in the destination", func(t *testing.T) {
+		sourceRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+			if request.Method != http.MethodGet || !strings.HasSuffix(request.URL.Path, "/blobs/"+layerDigest.String()) {
+				return false
+			}
+			size, err := layers[0].Size()
+			require.NoError(t, err)
+			writer.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+			writer.WriteHeader(http.StatusOK)
+			writer.Write(bytes.Repeat([]byte("a"), int(size)))
+			return true
+		})
+
+		committed := false
+		destinationRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+			if request.Method == http.MethodPut && request.URL.Query().Get("digest") == layerDigest.String() {
+				committed = true
+			}
+			return false
+		})
+
+		_, err := v1.CopyToRepository(origin, destinationRegistry.ReferenceOnTestServer("library/copied-image"), opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error verifying")
+		assert.False(t, committed, "expected corrupted blob to not be committed in the destination")
+	})
+}
+
+func TestToRepoWithMediaTypePolicy(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	origin.ImageRef = randomImage.RefDigest
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+	withPolicy := func(policy imageset.MediaTypePolicy) v1.CopyOpts {
+		newOpts := opts
+		newOpts.ImageSet = imageset.NewImageSet(1, opts.Logger, util.DefaultTagGenerator{}).WithMediaTypePolicy(policy)
+		newOpts.TarImageSet = imageset.NewTarImageSet(newOpts.ImageSet, 1, opts.Logger)
+		return newOpts
+	}
+
+	t.Run("when layers use a media type that is not allowed, it fails", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, withPolicy(imageset.MediaTypePolicy{
+			AllowedMediaTypes: []string{"application/vnd.oci.image.layer.v1.tar+gzip", "application/vnd.docker.container.image.v1+json"},
+		}), reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Found media types that are not allowed")
+		assert.Contains(t, err.Error(), "application/vnd.docker.image.rootfs.diff.tar.gzip")
+		assert.NotContains(t, err.Error(), "config")
+	})
+
+	t.Run("when all media types match the allowed patterns, it copies the image", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, withPolicy(imageset.MediaTypePolicy{
+			AllowedMediaTypes: []string{"application/vnd.docker.*"},
+		}), reg)
+		require.NoError(t, err)
+	})
+
+	t.Run("when only warning is requested, it copies the image and warns", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, withPolicy(imageset.MediaTypePolicy{
+			AllowedMediaTypes: []string{"application/vnd.oci.*"},
+			WarnOnly:          true,
+		}), reg)
+		require.NoError(t, err)
+		assert.Contains(t, stdOut.String(), "Warning: Found media types that are not allowed")
+	})
+
+	t.Run("when copying to tar, it fails before creating the tar", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		_, err := v1.CopyToTar(origin, tarPath, withPolicy(imageset.MediaTypePolicy{
+			AllowedMediaTypes: []string{"application/vnd.oci.*"},
+		}), reg)
+		require.Error(t, err)
+		assert.NoFileExists(t, tarPath)
+	})
+}
+
+func TestToRepoResumesFromJournal(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+	journalDir := t.TempDir()
+
+	withJournal := func(resume bool) v1.CopyOpts {
+		newOpts := opts
+		newOpts.ImageSet = imageset.NewImageSet(1, opts.Logger, util.DefaultTagGenerator{}).WithJournal(journalDir, resume)
+		newOpts.TarImageSet = imageset.NewTarImageSet(newOpts.ImageSet, 1, opts.Logger)
+		return newOpts
+	}
+
+	bundleHex := strings.TrimPrefix(bundleInfo.Digest, "sha256:")
+	image1Hex := strings.TrimPrefix(image1.Digest, "sha256:")
+	image2Hex := strings.TrimPrefix(image2.Digest, "sha256:")
+
+	failBundleManifest := true
+	imageManifestWrites := 0
+	lock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		if request.Method != http.MethodPut || !strings.Contains(request.URL.Path, "/manifests/") {
+			return false
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if strings.Contains(request.URL.Path, image1Hex) || strings.Contains(request.URL.Path, image2Hex) {
+			imageManifestWrites++
+		}
+		if failBundleManifest && strings.Contains(request.URL.Path, bundleHex) {
+			writer.WriteHeader(http.StatusBadRequest)
+			return true
+		}
+		return false
+	})
+
+	t.Run("when the copy is interrupted, it records the images that were copied", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, withJournal(false), reg)
+		require.Error(t, err)
+		require.Equal(t, 2, imageManifestWrites)
+
+		journals, err := os.ReadDir(journalDir)
+		require.NoError(t, err)
+		require.Len(t, journals, 1)
+
+		copyJournal, err := journal.Open(filepath.Join(journalDir, journals[0].Name()))
+		require.NoError(t, err)
+		defer copyJournal.Close()
+
+		_, found := copyJournal.Find(journal.ImageKind, image1.Digest)
+		assert.True(t, found)
+		_, found = copyJournal.Find(journal.ImageKind, image2.Digest)
+		assert.True(t, found)
+		_, found = copyJournal.Find(journal.ImageKind, bundleInfo.Digest)
+		assert.False(t, found)
+	})
+
+	t.Run("when resuming, it only writes the images that are not in the journal", func(t *testing.T) {
+		lock.Lock()
+		failBundleManifest = false
+		imageManifestWrites = 0
+		lock.Unlock()
+
+		_, err := v1.CopyToRepository(origin, destRepo, withJournal(true), reg)
+		require.NoError(t, err)
+		assert.Equal(t, 0, imageManifestWrites)
+
+		journals, err := os.ReadDir(journalDir)
+		require.NoError(t, err)
+		assert.Empty(t, journals, "journal should be removed after a successful copy")
+	})
+
+	t.Run("when the journal folder cannot be written, it copies without a journal", func(t *testing.T) {
+		notAFolder := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(notAFolder, nil, 0600))
+
+		newOpts := opts
+		newOpts.ImageSet = imageset.NewImageSet(1, opts.Logger, util.DefaultTagGenerator{}).WithJournal(filepath.Join(notAFolder, "journals"), true)
+		newOpts.TarImageSet = imageset.NewTarImageSet(newOpts.ImageSet, 1, opts.Logger)
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/other-copied-bundle"), newOpts, reg)
+		require.NoError(t, err)
+	})
+
+	t.Run("when the journal is used by another copy", func(t *testing.T) {
+		lock.Lock()
+		failBundleManifest = true
+		lock.Unlock()
+
+		concurrentDestRepo := fakeRegistry.ReferenceOnTestServer("library/concurrently-copied-bundle")
+		_, err := v1.CopyToRepository(origin, concurrentDestRepo, withJournal(false), reg)
+		require.Error(t, err)
+		journals, err := os.ReadDir(journalDir)
+		require.NoError(t, err)
+		require.Len(t, journals, 1)
+
+		lock.Lock()
+		failBundleManifest = false
+		lock.Unlock()
+
+		// Another copy holds the journal until it finishes
+		otherCopyJournal, err := journal.Open(filepath.Join(journalDir, journals[0].Name()))
+		require.NoError(t, err)
+		defer otherCopyJournal.Close()
+
+		t.Run("when resuming, it errors", func(t *testing.T) {
+			_, err := v1.CopyToRepository(origin, concurrentDestRepo, withJournal(true), reg)
+			require.Error(t, err)
+			assert.IsType(t, journal.LockedError{}, err)
+		})
+
+		t.Run("when not resuming, it copies without a journal", func(t *testing.T) {
+			_, err := v1.CopyToRepository(origin, concurrentDestRepo, withJournal(false), reg)
+			require.NoError(t, err)
+			assert.Contains(t, stdOut.String(), "it is being used by another copy")
+
+			_, found := otherCopyJournal.Find(journal.ImageKind, image1.Digest)
+			assert.True(t, found, "the journal of the other copy should be kept")
+		})
+	})
+
+	t.Run("when no journal folder is provided, it copies without a journal and without warnings", func(t *testing.T) {
+		_, noJournalOpts, _ := testSetup(fakeRegistry, "", "", "", "")
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-bundle-without-journal"), noJournalOpts, reg)
+		require.NoError(t, err)
+		assert.NotContains(t, stdOut.String(), "journal")
+	})
+}
+
+func TestToRepoWithJournalLimitsUploads(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	var imageRefs []lockconfig.ImageRef
+	for idx := 0; idx < 4; idx++ {
+		img := fakeRegistry.WithRandomImageWithLayers(fmt.Sprintf("library/image%d", idx), 4)
+		imageRefs = append(imageRefs, lockconfig.ImageRef{Image: img.RefDigest})
+	}
+	fakeRegistry.WithRandomBundleAndImages("library/bundle", imageRefs)
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	opts.Concurrency = 2
+	opts.ImageSet = imageset.NewImageSet(opts.Concurrency, opts.Logger, util.DefaultTagGenerator{}).WithJournal(t.TempDir(), false)
+	opts.TarImageSet = imageset.NewTarImageSet(opts.ImageSet, opts.Concurrency, opts.Logger)
+
+	inFlight, peak := 0, 0
+	lock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(_ http.ResponseWriter, request *http.Request) bool {
+		// The blobs are already present in the registry, so each upload stops after checking that its blob exists
+		if request.Method != http.MethodHead || !strings.Contains(request.URL.Path, "/library/copied-bundle/blobs/") {
+			return false
+		}
+		lock.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return false
+	})
+
+	_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-bundle"), opts, reg)
+	require.NoError(t, err)
+
+	assert.Greater(t, peak, 0)
+	assert.LessOrEqual(t, peak, opts.Concurrency, "the blobs uploaded at the same time should be limited by the concurrency")
+}
+
+func TestToRepoResumesFromStateFile(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+	stateFile := filepath.Join(t.TempDir(), "copy.state")
+
+	opts.ImageSet = imageset.NewImageSet(1, opts.Logger, util.DefaultTagGenerator{}).WithStateFile(stateFile)
+	opts.TarImageSet = imageset.NewTarImageSet(opts.ImageSet, 1, opts.Logger)
+
+	bundleHex := strings.TrimPrefix(bundleInfo.Digest, "sha256:")
+	image1Hex := strings.TrimPrefix(image1.Digest, "sha256:")
+	image2Hex := strings.TrimPrefix(image2.Digest, "sha256:")
+
+	failBundleManifest := true
+	imageManifestWrites := 0
+	lock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		if request.Method != http.MethodPut || !strings.Contains(request.URL.Path, "/manifests/") {
+			return false
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if strings.Contains(request.URL.Path, image1Hex) || strings.Contains(request.URL.Path, image2Hex) {
+			imageManifestWrites++
+		}
+		if failBundleManifest && strings.Contains(request.URL.Path, bundleHex) {
+			writer.WriteHeader(http.StatusBadRequest)
+			return true
+		}
+		return false
+	})
+
+	t.Run("when the copy is interrupted, it records the images that were copied in the state file", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.Error(t, err)
+		require.Equal(t, 2, imageManifestWrites)
+
+		copyJournal, err := journal.Open(stateFile)
+		require.NoError(t, err)
+		defer copyJournal.Close()
+
+		_, found := copyJournal.Find(journal.ImageKind, image1.Digest)
+		assert.True(t, found)
+		_, found = copyJournal.Find(journal.ImageKind, image2.Digest)
+		assert.True(t, found)
+		_, found = copyJournal.Find(journal.ImageKind, bundleInfo.Digest)
+		assert.False(t, found)
+	})
+
+	t.Run("when running the copy again, it resumes from the state file and only writes the missing images", func(t *testing.T) {
+		lock.Lock()
+		failBundleManifest = false
+		imageManifestWrites = 0
+		lock.Unlock()
+
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, 0, imageManifestWrites)
+
+		copyJournal, err := journal.Open(stateFile)
+		require.NoError(t, err)
+		defer copyJournal.Close()
+
+		_, found := copyJournal.Find(journal.ImageKind, bundleInfo.Digest)
+		assert.True(t, found, "state file is kept until the caller removes it")
+	})
+}
+
+func TestToRepoSignatureAnnotations(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image := fakeRegistry.WithRandomImage("library/image")
+	sigImage := fakeRegistry.WithRandomImage("library/image")
+	sigImage.Tag = fmt.Sprintf("sha256-%s.sig", strings.TrimPrefix(image.Digest, "sha256:"))
+
+	origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+	origin.ImageRef = image.RefDigest
+	opts.SignatureRetriever = signature.NewSignatures(signature.NewCosign(reg), 1)
+	opts.SignatureAnnotations = map[string]string{"ticket": "SEC-1234", "approver": "jane"}
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+	result, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+	require.NoError(t, err)
+
+	t.Run("it records the digest of the annotated signature as copied", func(t *testing.T) {
+		sigTag, err := name.NewTag(destRepo + ":" + sigImage.Tag)
+		require.NoError(t, err)
+		digest, err := reg.Digest(sigTag)
+		require.NoError(t, err)
+		require.NotEqual(t, sigImage.Digest, digest.String())
+
+		var copiedSignatures []string
+		for _, processedImage := range result.All() {
+			if processedImage.Tag == sigImage.Tag {
+				copiedSignatures = append(copiedSignatures, processedImage.DigestRef)
+			}
+		}
+		assert.Equal(t, []string{destRepo + "@" + digest.String()}, copiedSignatures)
+	})
+
+	t.Run("it adds the annotations to the signature tag in the repository", func(t *testing.T) {
+		sigTag, err := name.NewTag(destRepo + ":" + sigImage.Tag)
+		require.NoError(t, err)
+		desc, err := reg.Get(sigTag)
+		require.NoError(t, err)
+		img, err := desc.Image()
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, "SEC-1234", manifest.Annotations["ticket"])
+		assert.Equal(t, "jane", manifest.Annotations["approver"])
+
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		sigLayers, err := sigImage.Image.Layers()
+		require.NoError(t, err)
+		assert.Equal(t, len(sigLayers), len(layers), "the signature layers are kept")
+	})
+
+	t.Run("it does not add the annotations to the images that are not signatures", func(t *testing.T) {
+		imgRef, err := name.NewDigest(destRepo + "@" + image.Digest)
+		require.NoError(t, err)
+		desc, err := reg.Get(imgRef)
+		require.NoError(t, err)
+		img, err := desc.Image()
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		assert.NotContains(t, manifest.Annotations, "ticket")
+	})
+}
+
+func TestToRepoSignaturesOnly(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image := fakeRegistry.WithRandomImage("library/image")
+	sigImage := fakeRegistry.WithRandomImage("library/image")
+	sigImage.Tag = fmt.Sprintf("sha256-%s.sig", strings.TrimPrefix(image.Digest, "sha256:"))
+	notCopiedImage := fakeRegistry.WithRandomImage("library/other-image")
+	notCopiedSigImage := fakeRegistry.WithRandomImage("library/other-image")
+	notCopiedSigImage.Tag = fmt.Sprintf("sha256-%s.sig", strings.TrimPrefix(notCopiedImage.Digest, "sha256:"))
+
+	origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+	origin.ImageRef = image.RefDigest
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+	// The image is copied before it is signed
+	_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+	require.NoError(t, err)
+
+	opts.SignatureRetriever = signature.NewSignatures(signature.NewCosign(reg), 1)
+	origin.SignaturesOnly = true
+	sigTag, err := name.NewTag(destRepo + ":" + sigImage.Tag)
+	require.NoError(t, err)
+
+	t.Run("it copies the signatures of the image that are not in the repository", func(t *testing.T) {
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		require.Len(t, processedImages.All(), 1)
+		assert.Equal(t, sigImage.RefDigest, processedImages.All()[0].UnprocessedImageRef.DigestRef)
+
+		copiedDigest, err := reg.Digest(sigTag)
+		require.NoError(t, err)
+		assert.Equal(t, sigImage.Digest, copiedDigest.String())
+	})
+
+	t.Run("when the signatures are already in the repository, it does not copy anything", func(t *testing.T) {
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Empty(t, processedImages.All())
+		assert.Contains(t, stdOut.String(), "No new signatures to copy")
+	})
+
+	t.Run("when the image was not copied to the repository, it does not copy its signatures", func(t *testing.T) {
+		notCopiedOrigin := origin
+		notCopiedOrigin.ImageRef = notCopiedImage.RefDigest
+		processedImages, err := v1.CopyToRepository(notCopiedOrigin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Empty(t, processedImages.All())
+		assert.Contains(t, stdOut.String(), fmt.Sprintf("Skipping the signatures of image '%s', it was not copied to '%s'", notCopiedImage.RefDigest, destRepo))
+
+		notCopiedSigTag, err := name.NewTag(destRepo + ":" + notCopiedSigImage.Tag)
+		require.NoError(t, err)
+		_, err = reg.Digest(notCopiedSigTag)
+		require.Error(t, err)
+	})
+
+	t.Run("when copying to a registry, it fails", func(t *testing.T) {
+		_, err := v1.CopyToRegistry(origin, fakeRegistry.ReferenceOnTestServer(""), opts, reg)
+		require.EqualError(t, err, "Copying only signatures is only possible when copying to a repository")
+	})
+}
+
+func TestToRepoNotationSignatures(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image := fakeRegistry.WithRandomImage("library/image")
+	origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+	origin.ImageRef = image.RefDigest
+	opts.SignatureRetriever = signature.NewNotation(reg.(signature.ReferrersReader), 1)
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+	imageDigest, err := name.NewDigest(image.RefDigest)
+	require.NoError(t, err)
+	rawManifest, err := image.Image.RawManifest()
+	require.NoError(t, err)
+	mediaType, err := image.Image.MediaType()
+	require.NoError(t, err)
+	imageHash, err := regv1.NewHash(image.Digest)
+	require.NoError(t, err)
+	sigImage := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), signature.NotationSignatureArtifactType)
+	sigImage = mutate.Subject(sigImage, regv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    imageHash,
+		Size:      int64(len(rawManifest)),
+	}).(regv1.Image)
+	sigDigest, err := sigImage.Digest()
+	require.NoError(t, err)
+	require.NoError(t, reg.WriteImage(imageDigest.Context().Digest(sigDigest.String()), sigImage, nil))
+
+	_, err = v1.CopyToRepository(origin, destRepo, opts, reg)
+	require.NoError(t, err)
+
+	t.Run("it copies the signature so that it refers to the copied image", func(t *testing.T) {
+		copiedImage, err := name.NewDigest(destRepo + "@" + image.Digest)
+		require.NoError(t, err)
+
+		referrers, err := registry.Referrers(reg, copiedImage, signature.NotationSignatureArtifactType)
+		require.NoError(t, err)
+		require.Len(t, referrers, 1)
+		assert.Equal(t, sigDigest, referrers[0].Digest)
+	})
+}
+
+func TestToRepoIncremental(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	opts.ImageSet = opts.ImageSet.WithIncremental(true)
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+
+	copiedHexes := []string{
+		strings.TrimPrefix(image1.Digest, "sha256:"),
+		strings.TrimPrefix(image2.Digest, "sha256:"),
+		strings.TrimPrefix(bundleInfo.Digest, "sha256:"),
+	}
+	manifestWrites := 0
+	lock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		if request.Method != http.MethodPut || !strings.Contains(request.URL.Path, "/manifests/") ||
+			strings.HasSuffix(request.URL.Path, ".image-locations.imgpkg") {
+			return false
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, hex := range copiedHexes {
+			if strings.Contains(request.URL.Path, hex) {
+				manifestWrites++
+			}
+		}
+		return false
+	})
+
+	t.Run("when the destination is empty, it copies all the images", func(t *testing.T) {
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, 3, manifestWrites)
+		assert.Contains(t, stdOut.String(), "skipped 0 images already present in "+destRepo+", copied 3 images")
+	})
+
+	t.Run("when the images are already present in the destination, it skips them", func(t *testing.T) {
+		lock.Lock()
+		manifestWrites = 0
+		lock.Unlock()
+		stdOut.Reset()
+
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, 0, manifestWrites)
+		assert.Contains(t, stdOut.String(), "skipped 3 images already present in "+destRepo+", copied 0 images")
+
+		var digestRefs []string
+		for _, img := range processedImages.All() {
+			digestRefs = append(digestRefs, img.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + image1.Digest,
+			destRepo + "@" + image2.Digest,
+			destRepo + "@" + bundleInfo.Digest,
+		}, digestRefs)
+	})
+}
+
+func TestToRepoMultipleBundlesAndImages(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	sharedImage := fakeRegistry.WithRandomImage("library/shared-image")
+	bundle1Image := fakeRegistry.WithRandomImage("library/bundle1-image")
+	plainImage := fakeRegistry.WithRandomImage("library/plain-image")
+	bundle1 := fakeRegistry.WithBundleFromPath("library/bundle1", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+			{Image: bundle1Image.RefDigest},
+		})
+	bundle2 := fakeRegistry.WithBundleFromPath("library/bundle2", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+		})
+
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	origin := v1.CopyOrigin{
+		BundleRefs: []string{bundle1.RefDigest, bundle2.RefDigest},
+		ImageRefs:  []string{plainImage.RefDigest},
+	}
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/product-suite")
+
+	sharedImageHex := strings.TrimPrefix(sharedImage.Digest, "sha256:")
+	sharedImageWrites := 0
+	lock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		if request.Method == http.MethodPut && strings.Contains(request.URL.Path, "/manifests/") &&
+			strings.Contains(request.URL.Path, sharedImageHex) {
+			lock.Lock()
+			sharedImageWrites++
+			lock.Unlock()
+		}
+		return false
+	})
+
+	t.Run("when copying multiple bundles and images, it copies the shared images only once", func(t *testing.T) {
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sharedImageWrites)
+
+		var digestRefs, rootBundles []string
+		for _, img := range processedImages.All() {
+			digestRefs = append(digestRefs, img.DigestRef)
+			if v1.IsRootBundle(img) {
+				rootBundles = append(rootBundles, img.DigestRef)
+			}
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + sharedImage.Digest,
+			destRepo + "@" + bundle1Image.Digest,
+			destRepo + "@" + plainImage.Digest,
+			destRepo + "@" + bundle1.Digest,
+			destRepo + "@" + bundle2.Digest,
+		}, digestRefs)
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + bundle1.Digest,
+			destRepo + "@" + bundle2.Digest,
+		}, rootBundles)
+	})
+
+	t.Run("when a bundle is listed as an image, it errors", func(t *testing.T) {
+		_, err := v1.CopyToRepository(v1.CopyOrigin{ImageRefs: []string{bundle1.RefDigest}}, destRepo, opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to be an image but found a bundle")
+	})
+
+	t.Run("when copying multiple bundles through a tar, it notes the copy of every bundle", func(t *testing.T) {
+		tarFile := filepath.Join(t.TempDir(), "suite.tar")
+		_, err := v1.CopyToTar(origin, tarFile, opts, reg)
+		require.NoError(t, err)
+
+		tarDestRepo := fakeRegistry.ReferenceOnTestServer("library/product-suite-from-tar")
+		processedImages, err := v1.CopyToRepository(v1.CopyOrigin{TarPath: tarFile}, tarDestRepo, opts, reg)
+		require.NoError(t, err)
+
+		rootBundles := 0
+		for _, img := range processedImages.All() {
+			if v1.IsRootBundle(img) {
+				rootBundles++
+			}
+		}
+		assert.Equal(t, 2, rootBundles)
+	})
+}
+
+func TestCopyDryRun(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	sharedImage := fakeRegistry.WithRandomImage("library/shared-image")
+	plainImage := fakeRegistry.WithRandomImage("library/plain-image")
+	bundle1 := fakeRegistry.WithBundleFromPath("library/bundle1", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+		})
+	bundle2 := fakeRegistry.WithBundleFromPath("library/bundle2", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+			{Image: plainImage.RefDigest},
+		})
+
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	origin := v1.CopyOrigin{
+		BundleRefs: []string{bundle1.RefDigest, bundle2.RefDigest},
+		ImageRefs:  []string{plainImage.RefDigest},
+	}
+
+	writes := 0
+	fakeRegistry.WithCustomHandler(func(_ http.ResponseWriter, request *http.Request) bool {
+		if request.Method == http.MethodPut || request.Method == http.MethodPost {
+			writes++
+		}
+		return false
+	})
+
+	imageSize := func(t *testing.T, img regv1.Image) int64 {
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		rawManifest, err := img.RawManifest()
+		require.NoError(t, err)
+		size := int64(len(rawManifest)) + manifest.Config.Size
+		for _, layer := range manifest.Layers {
+			size += layer.Size
+		}
+		return size
+	}
+
+	t.Run("when copying bundles and images, it reports every image once and its size without writing anything", func(t *testing.T) {
+		report, err := v1.CopyDryRun(origin, opts, reg)
+		require.NoError(t, err)
+
+		reportedImages := map[string]v1.CopyReportImage{}
+		var sumOfSizes int64
+		for _, img := range report.Images {
+			digest, err := name.NewDigest(img.Ref)
+			require.NoError(t, err)
+			reportedImages[digest.DigestStr()] = img
+			sumOfSizes += img.Size
+		}
+		require.Len(t, reportedImages, 4)
+
+		assert.Equal(t, v1.BundleCopyReportKind, reportedImages[bundle1.Digest].Kind)
+		assert.Equal(t, v1.BundleCopyReportKind, reportedImages[bundle2.Digest].Kind)
+		assert.Equal(t, v1.ImageCopyReportKind, reportedImages[sharedImage.Digest].Kind)
+		assert.Equal(t, v1.ImageCopyReportKind, reportedImages[plainImage.Digest].Kind)
+		assert.Equal(t, imageSize(t, sharedImage.Image), reportedImages[sharedImage.Digest].Size)
+		assert.Equal(t, imageSize(t, plainImage.Image), reportedImages[plainImage.Digest].Size)
+
+		// The images shared between the bundles are reported once
+		assert.Equal(t, sumOfSizes, report.TotalSize)
+
+		assert.Equal(t, 0, writes)
+	})
+
+	t.Run("when copying only new tags, it returns an error", func(t *testing.T) {
+		_, err := v1.CopyDryRun(v1.CopyOrigin{ImageRef: plainImage.RefDigest, OnlyNewTags: true}, opts, reg)
+		require.ErrorContains(t, err, "Reporting the images to copy is not possible when copying only new tags")
+	})
+}
+
+func TestToRepoImageIndexChildPlatform(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/amd64", "linux/arm64")
+	origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	origin.ImageRef = imageIndex.RefDigest
+	origin.IndexChildPlatform = "linux/amd64"
+
+	idxManifest, err := imageIndex.ImageIndex.IndexManifest()
+	require.NoError(t, err)
+	var childDigest string
+	for _, child := range idxManifest.Manifests {
+		if child.Platform.String() == "linux/amd64" {
+			childDigest = child.Digest.String()
+		}
+	}
+
+	processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-index"), opts, reg)
+	require.NoError(t, err)
+
+	require.Len(t, processedImages.All(), 1)
+	processedImage := processedImages.All()[0]
+	assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/copied-index")+"@"+childDigest, processedImage.DigestRef)
+	assert.Nil(t, processedImage.ImageIndex)
+}
+
+func TestToRepoImageIndexPlatforms(t *testing.T) {
+	platformsImageSet := func(opts v1.CopyOpts, platforms ...string) v1.CopyOpts {
+		var parsedPlatforms []regv1.Platform
+		for _, platform := range platforms {
+			parsedPlatform, err := regv1.ParsePlatform(platform)
+			require.NoError(t, err)
+			parsedPlatforms = append(parsedPlatforms, *parsedPlatform)
+		}
+		opts.ImageSet = opts.ImageSet.WithPlatforms(parsedPlatforms)
+		opts.TarImageSet = imageset.NewTarImageSet(opts.ImageSet, 1, opts.Logger)
+		return opts
+	}
+
+	t.Run("when platforms are selected, it copies an index that only references their children", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/amd64", "linux/arm64", "linux/ppc64le")
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		opts = platformsImageSet(opts, "linux/amd64", "linux/arm64")
+
+		idxManifest, err := imageIndex.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		childDigests := map[string]string{}
+		for _, child := range idxManifest.Manifests {
+			childDigests[child.Platform.String()] = child.Digest.String()
+		}
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-index")
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		processedIndex := processedImages.All()[0]
+		require.NotNil(t, processedIndex.ImageIndex)
+		assert.NotEqual(t, destRepo+"@"+imageIndex.Digest, processedIndex.DigestRef, "the index should be rewritten")
+
+		copiedIndex, err := reg.Index(mustParseRef(t, processedIndex.DigestRef))
+		require.NoError(t, err)
+		copiedManifest, err := copiedIndex.IndexManifest()
+		require.NoError(t, err)
+		var copiedChildren []string
+		for _, child := range copiedManifest.Manifests {
+			copiedChildren = append(copiedChildren, child.Digest.String())
+		}
+		assert.ElementsMatch(t, []string{childDigests["linux/amd64"], childDigests["linux/arm64"]}, copiedChildren)
+
+		assertion := &helpers.Assertion{T: t}
+		assert.NoError(t, assertion.ValidateImagesPresenceInRegistry([]string{
+			destRepo + "@" + childDigests["linux/amd64"],
+			destRepo + "@" + childDigests["linux/arm64"],
+		}))
+		_, err = reg.Image(mustParseRef(t, destRepo+"@"+childDigests["linux/ppc64le"]))
+		assert.Error(t, err, "the image of a platform that was not selected should not be copied")
+	})
+
+	t.Run("when the index already only contains the selected platforms, it copies the index unchanged", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/amd64")
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		opts = platformsImageSet(opts, "linux/amd64")
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-index")
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		assert.Equal(t, destRepo+"@"+imageIndex.Digest, processedImages.All()[0].DigestRef)
+	})
+
+	t.Run("when none of the children match the platforms, it fails listing the available platforms", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/arm64", "linux/ppc64le")
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		opts = platformsImageSet(opts, "linux/amd64")
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-index"), opts, reg)
+		require.ErrorContains(t, err, "Unable to find platforms linux/amd64 in image index")
+		require.ErrorContains(t, err, "(available platforms: linux/arm64, linux/ppc64le)")
+	})
+
+	t.Run("when copying to a tar, it only writes the layers of the selected platforms", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/amd64", "linux/arm64")
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		opts = platformsImageSet(opts, "linux/arm64")
+
+		imageTarPath := filepath.Join(t.TempDir(), "index.tar")
+		_, err := v1.CopyToTar(origin, imageTarPath, opts, reg)
+		require.NoError(t, err)
+
+		idxManifest, err := imageIndex.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		for _, child := range idxManifest.Manifests {
+			img, err := imageIndex.ImageIndex.Image(child.Digest)
+			require.NoError(t, err)
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			for _, layer := range layers {
+				digest, err := layer.Digest()
+				require.NoError(t, err)
+				assert.Equal(t, child.Platform.String() == "linux/arm64", tarContainsEntry(t, imageTarPath, digest.Algorithm+"-"+digest.Hex+".tar.gz"),
+					"layer %s of platform %s", digest, child.Platform)
+			}
+		}
+	})
+}
+
+func mustParseRef(t *testing.T, ref string) name.Reference {
+	parsedRef, err := name.ParseReference(ref)
+	require.NoError(t, err)
+	return parsedRef
+}
+
+func tarContainsEntry(t *testing.T, path string, entryName string) bool {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	tarReader := tar.NewReader(file)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			return false
+		}
+		require.NoError(t, err)
+		if hdr.Name == entryName {
+			return true
+		}
+	}
+}
+
+func TestToRepoOnlyNewTags(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	v10 := fakeRegistry.WithRandomTaggedImage("library/app:v1.0", "v1.0")
+	v11 := fakeRegistry.WithRandomTaggedImage("library/app:v1.1", "v1.1")
+	fakeRegistry.WithRandomTaggedImage("library/app:v2.0", "v2.0")
+	fakeRegistry.WithImage("library/mirror:v1.0", v10.Image)
+	outdated := fakeRegistry.WithRandomTaggedImage("library/mirror:v1.1", "v1.1")
+
+	t.Run("when tags match the patterns, it only copies the ones missing or outdated in the destination", func(t *testing.T) {
+		origin, opts, reg := testSetup(fakeRegistry, "library/app", "", "", "")
+		origin.OnlyNewTags = true
+		origin.TagPatterns = []string{"v1.*"}
+
+		processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/mirror"), opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		assert.Equal(t, "v1.1", processedImages.All()[0].Tag)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/mirror")+"@"+v11.Digest, processedImages.All()[0].DigestRef)
+
+		v11Ref, err := name.NewTag(fakeRegistry.ReferenceOnTestServer("library/mirror:v1.1"))
+		require.NoError(t, err)
+		digest, err := reg.Digest(v11Ref)
+		require.NoError(t, err)
+		assert.Equal(t, v11.Digest, digest.String())
+		assert.NotEqual(t, outdated.Digest, digest.String())
+
+		assert.Contains(t, stdOut.String(), fmt.Sprintf("Skipping tag 'v1.0': already present in the destination with digest %s", v10.Digest))
+		assert.Contains(t, stdOut.String(), "Copying 1 new tag(s), skipped 1 tag(s) already present in the destination")
+	})
+
+	t.Run("when the destination repository does not exist, it copies all the tags", func(t *testing.T) {
+		origin, opts, reg := testSetup(fakeRegistry, "library/app", "", "", "")
+		origin.OnlyNewTags = true
+
+		processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/new-mirror"), opts, reg)
+		require.NoError(t, err)
+
+		var tags []string
+		for _, processedImage := range processedImages.All() {
+			tags = append(tags, processedImage.Tag)
+		}
+		assert.ElementsMatch(t, []string{"latest", "v1.0", "v1.1", "v2.0"}, tags)
+	})
+
+	t.Run("when the image reference contains a tag, it fails", func(t *testing.T) {
+		origin, opts, reg := testSetup(fakeRegistry, "library/app:v1.0", "", "", "")
+		origin.OnlyNewTags = true
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/mirror"), opts, reg)
+		require.ErrorContains(t, err, "to be a repository without tag or digest when copying only new tags")
+	})
+}
+
+func TestToRepositories(t *testing.T) {
+	sourceRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer sourceRegistry.CleanUp()
+	destinationRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destinationRegistry.CleanUp()
+
+	image := sourceRegistry.WithRandomImage("library/image")
+	bundleInfo := sourceRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{{Image: image.RefDigest}})
+	layers, err := image.Image.Layers()
+	require.NoError(t, err)
+	layerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	origin, opts, reg := testSetup(sourceRegistry, "", "library/bundle", "", "")
+	destinationRegistry.Build()
+	destRepos := []string{
+		destinationRegistry.ReferenceOnTestServer("library/us-bundle"),
+		destinationRegistry.ReferenceOnTestServer("library/eu-bundle"),
+	}
+
+	layerReads := 0
+	lock := &sync.Mutex{}
+	sourceRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		if request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/blobs/"+layerDigest.String()) {
+			lock.Lock()
+			layerReads++
+			lock.Unlock()
+		}
+		return false
+	})
+
+	allProcessedImages, err := v1.CopyToRepositories(origin, destRepos, opts, reg)
+	require.NoError(t, err)
+	require.Len(t, allProcessedImages, 2)
+
+	t.Run("it copies the bundle and its images to every repository", func(t *testing.T) {
+		for i, destRepo := range destRepos {
+			var digestRefs []string
+			for _, img := range allProcessedImages[i].All() {
+				digestRefs = append(digestRefs, img.DigestRef)
+			}
+			assert.ElementsMatch(t, []string{
+				destRepo + "@" + bundleInfo.Digest,
+				destRepo + "@" + image.Digest,
+			}, digestRefs)
+
+			for _, digestRef := range digestRefs {
+				ref, err := name.NewDigest(digestRef)
+				require.NoError(t, err)
+				_, err = reg.Get(ref)
+				require.NoError(t, err)
+			}
+
+			locationsRef, err := name.NewTag(fmt.Sprintf("%s:%s.image-locations.imgpkg", destRepo, strings.ReplaceAll(bundleInfo.Digest, ":", "-")))
+			require.NoError(t, err)
+			_, err = reg.Get(locationsRef)
+			require.NoError(t, err, "expected the locations image in %s", destRepo)
+		}
+	})
+
+	t.Run("it reads the layers from the source only once", func(t *testing.T) {
+		assert.Equal(t, 1, layerReads)
+	})
+
+	t.Run("when copying only new tags, it errors", func(t *testing.T) {
+		onlyNewTagsOrigin := v1.CopyOrigin{ImageRef: image.RefDigest, OnlyNewTags: true}
+
+		_, err := v1.CopyToRepositories(onlyNewTagsOrigin, destRepos, opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only possible when copying to a single repository")
+	})
+}
+
+func TestToRepoPreserveLockTags(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-images")
+
+	writeLock := func(t *testing.T, image1ID, image2ID string) string {
+		lockYAML := fmt.Sprintf(`apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+  annotations:
+    kbld.carvel.dev/id: %s
+- image: %s
+  annotations:
+    kbld.carvel.dev/id: %s
+`, image1.RefDigest, image1ID, image2.RefDigest, image2ID)
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		require.NoError(t, os.WriteFile(lockPath, []byte(lockYAML), 0600))
+		return lockPath
+	}
+
+	t.Run("it tags each image in the destination with the tag it was resolved from", func(t *testing.T) {
+		origin := v1.CopyOrigin{LockfilePath: writeLock(t, "image1:1.21", "image2"), PreserveLockTags: true}
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		tagRef, err := name.NewTag(destRepo + ":1.21")
+		require.NoError(t, err)
+		desc, err := reg.Get(tagRef)
+		require.NoError(t, err)
+		assert.Equal(t, image1.Digest, desc.Digest.String())
+
+		tags, err := reg.ListTags(tagRef.Context())
+		require.NoError(t, err)
+		assert.NotContains(t, tags, "latest", "images resolved from a reference without a tag are not tagged")
+	})
+
+	t.Run("when two images were resolved from the same tag, it errors", func(t *testing.T) {
+		origin := v1.CopyOrigin{LockfilePath: writeLock(t, "image1:stable", "image2:stable"), PreserveLockTags: true}
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "both would be tagged 'stable' in the destination")
+	})
+}
+
+func TestToRepoAdditionalTags(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	v125 := fakeRegistry.WithRandomTaggedImage("library/nginx:1.25", "1.25")
+	v124 := fakeRegistry.WithRandomTaggedImage("library/nginx:1.24", "1.24")
+	_, _, reg := testSetup(fakeRegistry, "", "", "", "")
+
+	srcRepo, err := name.NewRepository(fakeRegistry.ReferenceOnTestServer("library/nginx"))
+	require.NoError(t, err)
+	for _, tag := range []string{"1.25.3", "stable"} {
+		require.NoError(t, reg.WriteTag(srcRepo.Tag(tag), v125.Image))
+	}
+
+	repoTags := func(t *testing.T, destRepo string) map[string]string {
+		repo, err := name.NewRepository(destRepo)
+		require.NoError(t, err)
+		tags, err := reg.ListTags(repo)
+		require.NoError(t, err)
+
+		digests := map[string]string{}
+		for _, tag := range tags {
+			if strings.HasSuffix(tag, ".imgpkg") {
+				continue
+			}
+			digest, err := reg.Digest(repo.Tag(tag))
+			require.NoError(t, err)
+			digests[tag] = digest.String()
+		}
+		return digests
+	}
+
+	// The fake registry also tags one of the images with latest
+	srcTags := repoTags(t, srcRepo.Name())
+	require.Equal(t, v124.Digest, srcTags["1.24"])
+	v125Tags := map[string]string{}
+	for tag, digest := range srcTags {
+		if digest == v125.Digest {
+			v125Tags[tag] = digest
+		}
+	}
+	for _, tag := range []string{"1.25", "1.25.3", "stable"} {
+		require.Contains(t, v125Tags, tag)
+	}
+
+	t.Run("when copying additional tags, it creates the tags that point to the same digest", func(t *testing.T) {
+		_, opts, _ := testSetup(nil, "", "", "", "")
+		origin := v1.CopyOrigin{ImageRef: srcRepo.Tag("1.25").Name(), AdditionalTags: true}
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/nginx-additional")
+
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, v125Tags, repoTags(t, destRepo))
+	})
+
+	t.Run("when copying all tags, it copies every tag and the images they point to", func(t *testing.T) {
+		_, opts, _ := testSetup(nil, "", "", "", "")
+		origin := v1.CopyOrigin{ImageRef: srcRepo.Tag("1.25").Name(), AllTags: true}
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/nginx-all")
+
+		_, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, srcTags, repoTags(t, destRepo))
+	})
+}
+
+func TestToRepoExcludeImages(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	appImage := fakeRegistry.WithRandomImage("library/app")
+	docsImage := fakeRegistry.WithRandomImage("library/app-docs")
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: appImage.RefDigest},
+		{Image: docsImage.RefDigest},
+	})
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	origin.ExcludeImages = []string{"*/app-docs"}
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle")
+
+	processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+	require.NoError(t, err)
+
+	t.Run("it does not copy the images that match the patterns", func(t *testing.T) {
+		var digestRefs []string
+		for _, img := range processedImages.All() {
+			digestRefs = append(digestRefs, img.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + bundleInfo.Digest,
+			destRepo + "@" + appImage.Digest,
+		}, digestRefs)
+	})
+
+	t.Run("it records the excluded images in the locations image", func(t *testing.T) {
+		locationImg := fmt.Sprintf("%s:%s.image-locations.imgpkg", destRepo, strings.ReplaceAll(bundleInfo.Digest, ":", "-"))
+		locationImgFolder := t.TempDir()
+		downloadImagesLocation(t, locationImg, locationImgFolder)
+
+		cfg, err := bundle.NewLocationConfigFromPath(filepath.Join(locationImgFolder, "image-locations.yml"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []bundle.ImageLocation{
+			{Image: appImage.RefDigest},
+			{Image: docsImage.RefDigest, Excluded: true},
+		}, cfg.Images)
+	})
+
+	t.Run("when the copied bundle is pulled, the excluded images keep their original location and are marked", func(t *testing.T) {
+		outputFolder := t.TempDir()
+		_, err := v1.Pull(destRepo+"@"+bundleInfo.Digest, outputFolder, v1.PullOpts{Logger: util.NewNoopLevelLogger(), IsBundle: true}, registry.Opts{})
+		require.NoError(t, err)
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputFolder, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 2)
+		for _, img := range imagesLock.Images {
+			if strings.HasSuffix(img.Image, docsImage.Digest) {
+				assert.Equal(t, docsImage.RefDigest, img.Image)
+				assert.Equal(t, "true", img.Annotations[lockconfig.ImageRefExcludedAnnotationKey])
+				continue
+			}
+			assert.Equal(t, destRepo+"@"+appImage.Digest, img.Image)
+			assert.NotContains(t, img.Annotations, lockconfig.ImageRefExcludedAnnotationKey)
+		}
+	})
+
+	t.Run("when the pattern is an invalid regular expression, it errors", func(t *testing.T) {
+		invalidOrigin := origin
+		invalidOrigin.ExcludeImages = []string{"regex:("}
+		_, err := v1.CopyToRepository(invalidOrigin, destRepo, opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid exclude images pattern 'regex:('")
+	})
+}
+
+func TestToRepoImageTimeout(t *testing.T) {
+	t.Run("when an image is not written within the timeout, it fails the image instead of waiting for it", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		fakeRegistry.WithRandomImage("library/image")
+		origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+		opts.ImageSet = opts.ImageSet.WithImageTimeout(100 * time.Millisecond)
+		hangingReg := &hangingWriteRegistry{Registry: reg}
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+		started := time.Now()
+		_, err := v1.CopyToRepository(origin, destRepo, opts, hangingReg)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "not written within 100ms")
+		assert.Less(t, time.Since(started), 5*time.Second)
+		assert.Equal(t, int32(0), hangingReg.writing.Load(), "the write that timed out is still running")
+	})
+
+	t.Run("when the images are written within the timeout, it copies them", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		image := fakeRegistry.WithRandomImage("library/image")
+		origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+		opts.ImageSet = opts.ImageSet.WithImageTimeout(time.Minute)
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+		require.Len(t, processedImages.All(), 1)
+		assert.Equal(t, destRepo+"@"+image.Digest, processedImages.All()[0].DigestRef)
+	})
+}
+
+// hangingWriteRegistry Registry whose writes hang until their context is done
+type hangingWriteRegistry struct {
+	registry.Registry
+	writing atomic.Int32
+}
+
+func (r *hangingWriteRegistry) MultiWriteWithContext(ctx context.Context, _ map[name.Reference]remote.Taggable, _ int, _ chan regv1.Update) error {
+	r.writing.Add(1)
+	defer r.writing.Add(-1)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestToRepoRecompress(t *testing.T) {
+	recompressImageSet := func(opts v1.CopyOpts) v1.CopyOpts {
+		opts.ImageSet = opts.ImageSet.WithRecompression(imageset.RecompressionZstd)
+		return opts
+	}
+
+	t.Run("it converts the gzip layers to zstd and records the original digests", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		image := fakeRegistry.WithRandomImage("library/image")
+		origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+		opts = recompressImageSet(opts)
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-image")
+
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		processedImage := processedImages.All()[0]
+		assert.Equal(t, image.RefDigest, processedImage.UnprocessedImageRef.DigestRef)
+		assert.NotEqual(t, destRepo+"@"+image.Digest, processedImage.DigestRef, "the image should be rewritten")
+
+		copiedImage, err := reg.Image(mustParseRef(t, processedImage.DigestRef))
+		require.NoError(t, err)
+		copiedManifest, err := copiedImage.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, types.OCIManifestSchema1, copiedManifest.MediaType)
+		assert.Equal(t, image.Digest, copiedManifest.Annotations[imageset.RecompressedFromAnnotation])
+
+		originalManifest, err := image.Image.Manifest()
+		require.NoError(t, err)
+		originalConfig, err := image.Image.ConfigFile()
+		require.NoError(t, err)
+		copiedConfig, err := copiedImage.ConfigFile()
+		require.NoError(t, err)
+		assert.Equal(t, originalConfig.RootFS.DiffIDs, copiedConfig.RootFS.DiffIDs)
+
+		require.Len(t, copiedManifest.Layers, len(originalManifest.Layers))
+		for idx, layer := range copiedManifest.Layers {
+			assert.Equal(t, types.OCILayerZStd, layer.MediaType)
+			assert.Equal(t, originalManifest.Layers[idx].Digest.String(), layer.Annotations[imageset.RecompressedFromAnnotation])
+
+			copiedLayer, err := copiedImage.LayerByDigest(layer.Digest)
+			require.NoError(t, err)
+			uncompressed, err := copiedLayer.Uncompressed()
+			require.NoError(t, err)
+			diffID, _, err := regv1.SHA256(uncompressed)
+			require.NoError(t, err)
+			require.NoError(t, uncompressed.Close())
+			assert.Equal(t, originalConfig.RootFS.DiffIDs[idx], diffID)
+		}
+	})
+
+	t.Run("it recompresses the images of an index", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		imageIndex := fakeRegistry.WithImageIndexForPlatforms("library/index", "linux/amd64", "linux/arm64")
+		origin, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+		origin.ImageRef = imageIndex.RefDigest
+		opts = recompressImageSet(opts)
+
+		processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-index"), opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		copiedIndex, err := reg.Index(mustParseRef(t, processedImages.All()[0].DigestRef))
+		require.NoError(t, err)
+		copiedManifest, err := copiedIndex.IndexManifest()
+		require.NoError(t, err)
+		assert.Equal(t, imageIndex.Digest, copiedManifest.Annotations[imageset.RecompressedFromAnnotation])
+
+		var platforms []string
+		for _, child := range copiedManifest.Manifests {
+			platforms = append(platforms, child.Platform.String())
+			childImage, err := copiedIndex.Image(child.Digest)
+			require.NoError(t, err)
+			childManifest, err := childImage.Manifest()
+			require.NoError(t, err)
+			for _, layer := range childManifest.Layers {
+				assert.Equal(t, types.OCILayerZStd, layer.MediaType)
+			}
+		}
+		assert.ElementsMatch(t, []string{"linux/amd64", "linux/arm64"}, platforms)
+	})
+
+	t.Run("when copying a bundle, it errors", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		image := fakeRegistry.WithRandomImage("library/image")
+		fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{{Image: image.RefDigest}})
+		origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+		opts = recompressImageSet(opts)
+
+		_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-bundle"), opts, reg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Bundles cannot be recompressed")
+	})
+}
+
+func TestToRepoCopyObserver(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	appImage := fakeRegistry.WithRandomImage("library/app")
+	dbImage := fakeRegistry.WithRandomImage("library/db")
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: appImage.RefDigest},
+		{Image: dbImage.RefDigest},
+	})
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	observer := &fakeCopyObserver{progress: map[string]int64{}}
+	opts.ImageSet = opts.ImageSet.WithObserver(observer)
+
+	_, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-bundle"), opts, reg)
+	require.NoError(t, err)
+
+	allImages := []string{bundleInfo.RefDigest, appImage.RefDigest, dbImage.RefDigest}
+	assert.Equal(t, 3, observer.total)
+	assert.ElementsMatch(t, allImages, observer.started)
+	assert.ElementsMatch(t, allImages, observer.finished)
+	for _, ref := range allImages {
+		assert.Greater(t, observer.progress[ref], int64(0), "Expected progress to be reported for %s", ref)
+	}
+}
+
+func TestToRepoOnImageProcessed(t *testing.T) {
+	sourceRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer sourceRegistry.CleanUp()
+	destinationRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destinationRegistry.CleanUp()
+
+	appImage := sourceRegistry.WithRandomImage("library/app")
+	dbImage := sourceRegistry.WithRandomImage("library/db")
+	bundleInfo := sourceRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: appImage.RefDigest},
+		{Image: dbImage.RefDigest},
+	})
+	origin, opts, reg := testSetup(sourceRegistry, "", "library/bundle", "", "")
+	destinationRegistry.Build()
+	allImages := []string{bundleInfo.RefDigest, appImage.RefDigest, dbImage.RefDigest}
+
+	t.Run("when copying to a repository, it calls the handler with each image copied", func(t *testing.T) {
+		destRepo := destinationRegistry.ReferenceOnTestServer("library/copied-bundle")
+		var sources, destinations []string
+		opts.OnImageProcessed = func(img imageset.ProcessedImage) {
+			sources = append(sources, img.UnprocessedImageRef.DigestRef)
+			destinations = append(destinations, img.DigestRef)
+		}
+
+		processedImages, err := v1.CopyToRepository(origin, destRepo, opts, reg)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, allImages, sources)
+		var expectedDestinations []string
+		for _, img := range processedImages.All() {
+			expectedDestinations = append(expectedDestinations, img.DigestRef)
+		}
+		assert.ElementsMatch(t, expectedDestinations, destinations)
+	})
+
+	t.Run("when copying to several repositories, it calls the handler with the source images for every repository", func(t *testing.T) {
+		destRepos := []string{
+			destinationRegistry.ReferenceOnTestServer("library/us-bundle"),
+			destinationRegistry.ReferenceOnTestServer("library/eu-bundle"),
+		}
+		sourcesByRepo := map[string][]string{}
+		opts.OnImageProcessed = func(img imageset.ProcessedImage) {
+			repo, _, _ := strings.Cut(img.DigestRef, "@")
+			sourcesByRepo[repo] = append(sourcesByRepo[repo], img.UnprocessedImageRef.DigestRef)
+		}
+
+		_, err := v1.CopyToRepositories(origin, destRepos, opts, reg)
+		require.NoError(t, err)
+
+		require.Len(t, sourcesByRepo, 2)
+		for _, destRepo := range destRepos {
+			assert.ElementsMatch(t, allImages, sourcesByRepo[destRepo], "Expected every source image for %s", destRepo)
+		}
+	})
+
+	t.Run("when the images are written on their own, it calls the handler with each image before the copy of the other images finishes", func(t *testing.T) {
+		destRepo := destinationRegistry.ReferenceOnTestServer("library/observed-bundle")
+		observer := &fakeCopyObserver{progress: map[string]int64{}}
+		var finishedBeforeFirstHandled []string
+		handled := 0
+		opts.OnImageProcessed = func(img imageset.ProcessedImage) {
+			if handled == 0 {
+				observer.lock.Lock()
+				finishedBeforeFirstHandled = append([]string{}, observer.finished...)
+				observer.lock.Unlock()
+			}
+			handled++
+		}
+		observedOpts := opts
+		observedOpts.ImageSet = opts.ImageSet.WithObserver(observer)
+
+		_, err := v1.CopyToRepository(origin, destRepo, observedOpts, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, len(allImages), handled)
+		assert.Less(t, len(finishedBeforeFirstHandled), len(allImages))
+	})
+}
+
+type fakeCopyObserver struct {
+	lock     sync.Mutex
+	total    int
+	started  []string
+	finished []string
+	progress map[string]int64
+}
+
+func (f *fakeCopyObserver) ImagesFound(total int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.total += total
+}
+
+func (f *fakeCopyObserver) ImageStarted(ref string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.started = append(f.started, ref)
+}
+
+func (f *fakeCopyObserver) ImageProgress(ref string, complete, _ int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.progress[ref] = complete
+}
+
+func (f *fakeCopyObserver) ImageFinished(ref string, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err == nil {
+		f.finished = append(f.finished, ref)
+	}
+}
+
+func TestToTarSplit(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: randomImage.RefDigest},
+	})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	opts.TarImageSet = opts.TarImageSet.WithSplitSize(4096)
+
+	tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+	_, err := v1.CopyToTar(origin, tarPath, opts, reg)
+	require.NoError(t, err)
+
+	t.Run("it writes the tar as numbered parts of at most the split size", func(t *testing.T) {
+		assert.NoFileExists(t, tarPath)
+		parts := imagetar.SplitParts(tarPath)
+		require.Greater(t, len(parts), 1)
+		for _, part := range parts {
+			info, err := os.Stat(part)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, info.Size(), int64(4096))
+		}
+	})
+
+	for _, sourcePath := range []string{tarPath, imagetar.SplitPartPath(tarPath, 0)} {
+		t.Run(fmt.Sprintf("when the source is '%s', it reassembles the parts", filepath.Base(sourcePath)), func(t *testing.T) {
+			destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-from-" + filepath.Base(sourcePath))
+			processedImages, err := v1.CopyToRepository(v1.CopyOrigin{TarPath: sourcePath}, destRepo, opts, reg)
+			require.NoError(t, err)
+			assert.Equal(t, 2, processedImages.Len())
+		})
+	}
+
+	t.Run("when a tar that was not split is in the same path, it is replaced by the parts", func(t *testing.T) {
+		replacedTarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		imageOrigin, imageOpts, _ := testSetup(fakeRegistry, "", "", "", "")
+		imageOrigin.ImageRef = randomImage.RefDigest
+		_, err := v1.CopyToTar(imageOrigin, replacedTarPath, imageOpts, reg)
+		require.NoError(t, err)
+		require.FileExists(t, replacedTarPath)
+
+		splitOpts := opts
+		splitOpts.TarImageSet = opts.TarImageSet.WithChecksums(true)
+		_, err = v1.CopyToTar(origin, replacedTarPath, splitOpts, reg)
+		require.NoError(t, err)
+
+		assert.NoFileExists(t, replacedTarPath)
+		parts := imagetar.SplitParts(replacedTarPath)
+		require.Greater(t, len(parts), 1)
+		checksums, err := os.ReadFile(replacedTarPath + imagetar.ChecksumFileSuffix)
+		require.NoError(t, err)
+		assert.Contains(t, string(checksums), "  "+filepath.Base(parts[0])+"\n")
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-from-replaced-tar")
+		processedImages, err := v1.CopyToRepository(v1.CopyOrigin{TarPath: replacedTarPath}, destRepo, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, 2, processedImages.Len(), "the bundle and its image should be read from the parts")
+	})
+}
+
+func TestToTarChecksums(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+
+	origin, opts, reg := testSetup(fakeRegistry, "library/image", "", "", "")
+	origin.ImageRef = randomImage.RefDigest
+
+	sha256Of := func(t *testing.T, path string) string {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])
+	}
+
+	t.Run("it writes the checksum of the tar and of each blob", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		tarOpts := opts
+		tarOpts.TarImageSet = opts.TarImageSet.WithChecksums(true)
+		_, err := v1.CopyToTar(origin, tarPath, tarOpts, reg)
+		require.NoError(t, err)
+
+		checksums, err := os.ReadFile(tarPath + imagetar.ChecksumFileSuffix)
+		require.NoError(t, err)
+		assert.Equal(t, sha256Of(t, tarPath)+"  image.tar\n", string(checksums))
+
+		blobsChecksums, err := os.ReadFile(tarPath + imagetar.BlobsChecksumFileSuffix)
+		require.NoError(t, err)
+		assert.Contains(t, string(blobsChecksums), "  manifest.json\n")
+		layers, err := randomImage.Image.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			assert.Contains(t, string(blobsChecksums), fmt.Sprintf("%s  sha256-%s.tar.gz\n", digest.Hex, digest.Hex))
+		}
+	})
+
+	t.Run("when the tar is split, it writes the checksum of each part", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		tarOpts := opts
+		tarOpts.TarImageSet = opts.TarImageSet.WithSplitSize(2048).WithChecksums(true)
+		_, err := v1.CopyToTar(origin, tarPath, tarOpts, reg)
+		require.NoError(t, err)
+
+		parts := imagetar.SplitParts(tarPath)
+		require.Greater(t, len(parts), 1)
+		var expected string
+		for _, part := range parts {
+			expected += sha256Of(t, part) + "  " + filepath.Base(part) + "\n"
+		}
+		checksums, err := os.ReadFile(tarPath + imagetar.ChecksumFileSuffix)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(checksums))
+	})
+}
+
+func TestToRepoArtifactsWithNonImageConfig(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	// a CNAB bundle, whose config has fields conflicting with the ones of an image config,
+	// and an ORAS artifact whose config is not even valid UTF-8
+	cnabRef := fakeRegistry.ReferenceOnTestServer("library/cnab")
+	orasRef := fakeRegistry.ReferenceOnTestServer("library/oras")
+	cnabDigest := pushArtifact(t, cnabRef, "application/vnd.cnab.config.v1+json", []byte(`{"schemaVersion":"v1.0.0","config":"invocation image"}`))
+	orasDigest := pushArtifact(t, orasRef, "application/vnd.example.config.v1+binary", []byte{0xff, 0xfe, 0x00, 'o', 'r', 'a', 's'})
+
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle-with-artifacts", []lockconfig.ImageRef{{Image: cnabRef + "@" + cnabDigest.String()}})
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+
+	assertArtifactCopied := func(t *testing.T, destRepo string, digest regv1.Hash, configMediaType types.MediaType) {
+		ref, err := name.NewDigest(destRepo + "@" + digest.String())
+		require.NoError(t, err)
+		img, err := remote.Image(ref)
+		require.NoError(t, err)
+		manifest, err := img.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, configMediaType, manifest.Config.MediaType)
+		rawConfig, err := img.RawConfigFile()
+		require.NoError(t, err)
+		configDigest, _, err := regv1.SHA256(bytes.NewReader(rawConfig))
+		require.NoError(t, err)
+		assert.Equal(t, manifest.Config.Digest, configDigest, "the config should be copied byte-for-byte")
+	}
+
+	t.Run("copies a bundle referencing an artifact to a repository", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-bundle-with-artifacts")
+		_, err := v1.CopyToRepository(v1.CopyOrigin{BundleRef: bundleInfo.RefDigest}, destRepo, opts, reg)
+		require.NoError(t, err)
+		assertArtifactCopied(t, destRepo, cnabDigest, "application/vnd.cnab.config.v1+json")
+	})
+
+	t.Run("copies artifacts to a tar and from the tar to a repository", func(t *testing.T) {
+		for _, artifact := range []struct {
+			ref             string
+			digest          regv1.Hash
+			configMediaType types.MediaType
+		}{
+			{cnabRef, cnabDigest, "application/vnd.cnab.config.v1+json"},
+			{orasRef, orasDigest, "application/vnd.example.config.v1+binary"},
+		} {
+			tarPath := filepath.Join(t.TempDir(), "artifact.tar")
+			_, err := v1.CopyToTar(v1.CopyOrigin{ImageRef: artifact.ref + "@" + artifact.digest.String()}, tarPath, opts, reg)
+			require.NoError(t, err)
+
+			// the blobs of the artifact are not in the destination registry, so the config is read from the tar
+			fakeDestRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+			defer fakeDestRegistry.CleanUp()
+			destRepo := fakeDestRegistry.ReferenceOnTestServer("library/copied-artifact")
+			_, err = v1.CopyToRepository(v1.CopyOrigin{TarPath: tarPath}, destRepo, opts, fakeDestRegistry.Build())
+			require.NoError(t, err)
+			assertArtifactCopied(t, destRepo, artifact.digest, artifact.configMediaType)
+		}
+	})
+}
+
+// pushArtifact Pushes to repo an artifact with a layer and the config provided, returning the digest of its manifest
+func pushArtifact(t *testing.T, repo string, configMediaType types.MediaType, config []byte) regv1.Hash {
+	layer := static.NewLayer([]byte("artifact contents"), "application/vnd.example.layer.v1.tar")
+	img, err := partial.CompressedToImage(artifactImage{config: config, configMediaType: configMediaType, layer: layer})
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(repo + ":latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	return digest
+}
+
+// artifactImage OCI artifact whose config is not an image config
+type artifactImage struct {
+	config          []byte
+	configMediaType types.MediaType
+	layer           regv1.Layer
+}
+
+func (a artifactImage) RawConfigFile() ([]byte, error) { return a.config, nil }
+
+func (a artifactImage) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (a artifactImage) RawManifest() ([]byte, error) {
+	configDigest, configSize, err := regv1.SHA256(bytes.NewReader(a.config))
+	if err != nil {
+		return nil, err
+	}
+	layerDigest, err := a.layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	layerSize, err := a.layer.Size()
+	if err != nil {
+		return nil, err
+	}
+	layerMediaType, err := a.layer.MediaType()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(regv1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        regv1.Descriptor{MediaType: a.configMediaType, Digest: configDigest, Size: configSize},
+		Layers:        []regv1.Descriptor{{MediaType: layerMediaType, Digest: layerDigest, Size: layerSize}},
+	})
+}
+
+func (a artifactImage) LayerByDigest(digest regv1.Hash) (partial.CompressedLayer, error) {
+	layerDigest, err := a.layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if digest != layerDigest {
+		return nil, fmt.Errorf("Expected layer %s, got %s", layerDigest, digest)
+	}
+	return a.layer, nil
+}
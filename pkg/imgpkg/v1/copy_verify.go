@@ -0,0 +1,230 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+
+	ctlimgset "carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// VerifyCopyOpts Options of the verification of the images written by a copy
+type VerifyCopyOpts struct {
+	Logger      Logger
+	Concurrency int
+	// BlobSample number of blobs, chosen at random among the blobs of the copied images, that are downloaded
+	// again from the destination to verify their digest
+	BlobSample int
+	// IncludeNonDistributable when false the non-distributable layers are not sampled since they were not copied
+	IncludeNonDistributable bool
+}
+
+// VerifyCopyResult Number of manifests and blobs verified in the destination
+type VerifyCopyResult struct {
+	Manifests int
+	Blobs     int
+}
+
+// VerifyCopy Fetches again from the destination the manifest of every image and image index in processedImages,
+// including the children of the image indexes, and checks that their digest is the one the copy recorded.
+// Then downloads opts.BlobSample blobs of those images and checks their digest
+func VerifyCopy(processedImages *ctlimgset.ProcessedImages, opts VerifyCopyOpts, reg registry.Registry) (VerifyCopyResult, error) {
+	verifier := &copyVerifier{
+		reg:      reg,
+		opts:     opts,
+		throttle: util.NewThrottle(max(opts.Concurrency, 1)),
+		blobs:    map[string]regname.Digest{},
+		verified: map[string]bool{},
+	}
+
+	var refs []regname.Digest
+	for _, img := range processedImages.All() {
+		ref, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			panic(fmt.Sprintf("Internal consistency: %s should be a digest", img.DigestRef))
+		}
+		refs = append(refs, ref)
+	}
+	verifier.verifyManifests(refs)
+
+	result := VerifyCopyResult{Manifests: len(verifier.verified)}
+	if len(verifier.problems) == 0 && opts.BlobSample > 0 {
+		result.Blobs = verifier.verifyBlobs(opts.BlobSample)
+	}
+
+	if len(verifier.problems) > 0 {
+		sort.Strings(verifier.problems)
+		return result, fmt.Errorf("Verifying copied images: found %d problem(s):\n- %s", len(verifier.problems), strings.Join(verifier.problems, "\n- "))
+	}
+	return result, nil
+}
+
+type copyVerifier struct {
+	reg      registry.Registry
+	opts     VerifyCopyOpts
+	throttle util.Throttle
+
+	lock     sync.Mutex
+	problems []string
+	// blobs image that references each blob, used to download it
+	blobs    map[string]regname.Digest
+	verified map[string]bool
+}
+
+// verifyManifests Verifies the manifests of refs, and of their children, concurrently
+func (v *copyVerifier) verifyManifests(refs []regname.Digest) {
+	var wg sync.WaitGroup
+	for _, ref := range refs {
+		if !v.markVerified(ref) {
+			continue
+		}
+
+		ref := ref // copy
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			v.throttle.Take()
+			children, err := v.verifyManifest(ref)
+			v.throttle.Done()
+			if err != nil {
+				v.addProblem(fmt.Sprintf("%s: %s", ref.Name(), err))
+				return
+			}
+			v.verifyManifests(children)
+		}()
+	}
+	wg.Wait()
+}
+
+// verifyManifest Checks the digest of the manifest of ref and returns the children of ref when it is an image index
+func (v *copyVerifier) verifyManifest(ref regname.Digest) ([]regname.Digest, error) {
+	v.opts.Logger.Debugf("verifying manifest %s\n", ref.Name())
+	desc, err := v.reg.Get(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching manifest: %s", err)
+	}
+
+	digest, _, err := regv1.SHA256(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("Hashing manifest: %s", err)
+	}
+	if digest.String() != ref.DigestStr() {
+		return nil, fmt.Errorf("Expected manifest digest to be %s but found %s", ref.DigestStr(), digest)
+	}
+
+	if desc.MediaType.IsIndex() {
+		indexManifest, err := regv1.ParseIndexManifest(bytes.NewReader(desc.Manifest))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing image index: %s", err)
+		}
+		var children []regname.Digest
+		for _, child := range indexManifest.Manifests {
+			children = append(children, ref.Context().Digest(child.Digest.String()))
+		}
+		return children, nil
+	}
+
+	manifest, err := regv1.ParseManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("Parsing image manifest: %s", err)
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.blobs[manifest.Config.Digest.String()] = ref
+	for _, layer := range manifest.Layers {
+		if !layer.MediaType.IsDistributable() && !v.opts.IncludeNonDistributable {
+			continue
+		}
+		v.blobs[layer.Digest.String()] = ref
+	}
+	return nil, nil
+}
+
+// verifyBlobs Downloads sample blobs, chosen at random, and checks their digest. Returns the number of blobs verified
+func (v *copyVerifier) verifyBlobs(sample int) int {
+	var digests []string
+	for digest := range v.blobs {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	rand.Shuffle(len(digests), func(i, j int) { digests[i], digests[j] = digests[j], digests[i] })
+	digests = digests[:min(sample, len(digests))]
+
+	var wg sync.WaitGroup
+	for _, digest := range digests {
+		digest := digest // copy
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v.throttle.Take()
+			defer v.throttle.Done()
+
+			imageRef := v.blobs[digest]
+			err := v.verifyBlob(imageRef, digest)
+			if err != nil {
+				v.addProblem(fmt.Sprintf("%s: %s", imageRef.Context().Digest(digest).Name(), err))
+			}
+		}()
+	}
+	wg.Wait()
+	return len(digests)
+}
+
+func (v *copyVerifier) verifyBlob(imageRef regname.Digest, digest string) error {
+	v.opts.Logger.Debugf("verifying blob %s\n", imageRef.Context().Digest(digest).Name())
+	hash, err := regv1.NewHash(digest)
+	if err != nil {
+		return fmt.Errorf("Parsing blob digest: %s", err)
+	}
+
+	img, err := v.reg.Image(imageRef)
+	if err != nil {
+		return fmt.Errorf("Fetching image %s: %s", imageRef.Name(), err)
+	}
+	layer, err := img.LayerByDigest(hash)
+	if err != nil {
+		return fmt.Errorf("Fetching blob: %s", err)
+	}
+	reader, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("Fetching blob: %s", err)
+	}
+	defer reader.Close()
+
+	found, _, err := regv1.SHA256(reader)
+	if err != nil {
+		return fmt.Errorf("Reading blob: %s", err)
+	}
+	if found != hash {
+		return fmt.Errorf("Expected blob digest to be %s but found %s", hash, found)
+	}
+	return nil
+}
+
+// markVerified Returns false when the manifest of ref was already verified, or is being verified
+func (v *copyVerifier) markVerified(ref regname.Digest) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.verified[ref.Name()] {
+		return false
+	}
+	v.verified[ref.Name()] = true
+	return true
+}
+
+func (v *copyVerifier) addProblem(problem string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.problems = append(v.problems, problem)
+}
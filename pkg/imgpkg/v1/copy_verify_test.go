@@ -0,0 +1,140 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyCopy(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	randomImageIndex := fakeRegistry.WithARandomImageIndex("library/imageindex", 2)
+	defer fakeRegistry.CleanUp()
+
+	origin, opts, _ := testSetup(nil, "", "", "", "")
+	origin.ImageRef = randomImageIndex.RefDigest
+	reg := fakeRegistry.Build()
+
+	processedImages, err := v1.CopyToRepository(origin, fakeRegistry.ReferenceOnTestServer("library/copied-index"), opts, reg)
+	require.NoError(t, err)
+
+	verifyOpts := v1.VerifyCopyOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 2}
+
+	t.Run("verifies the manifests of the image index and of its images", func(t *testing.T) {
+		result, err := v1.VerifyCopy(processedImages, verifyOpts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, v1.VerifyCopyResult{Manifests: 3, Blobs: 0}, result)
+	})
+
+	t.Run("verifies at most the sampled number of blobs", func(t *testing.T) {
+		verifyOpts := verifyOpts
+		verifyOpts.BlobSample = 3
+		result, err := v1.VerifyCopy(processedImages, verifyOpts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, v1.VerifyCopyResult{Manifests: 3, Blobs: 3}, result)
+
+		verifyOpts.BlobSample = 1000
+		result, err = v1.VerifyCopy(processedImages, verifyOpts, reg)
+		require.NoError(t, err)
+		assert.Greater(t, result.Blobs, 3)
+		assert.Less(t, result.Blobs, 1000)
+	})
+
+	// intercept answers the requests of the subtest instead of the registry when it returns true
+	var intercept func(http.ResponseWriter, *http.Request) bool
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		return intercept != nil && intercept(writer, request)
+	})
+	indexDigest := strings.Split(processedImages.All()[0].DigestRef, "@")[1]
+
+	t.Run("when the manifest of an image of the image index is missing in the destination, it fails", func(t *testing.T) {
+		defer func() { intercept = nil }()
+		intercept = func(writer http.ResponseWriter, request *http.Request) bool {
+			if strings.Contains(request.URL.Path, "/library/copied-index/manifests/") && !strings.HasSuffix(request.URL.Path, indexDigest) {
+				writer.WriteHeader(http.StatusNotFound)
+				return true
+			}
+			return false
+		}
+
+		_, err := v1.VerifyCopy(processedImages, verifyOpts, reg)
+		require.ErrorContains(t, err, "Verifying copied images: found 2 problem(s)")
+		assert.ErrorContains(t, err, "Fetching manifest")
+	})
+
+	t.Run("when a blob was corrupted in the destination, it fails", func(t *testing.T) {
+		defer func() { intercept = nil }()
+		intercept = func(writer http.ResponseWriter, request *http.Request) bool {
+			if request.Method == http.MethodGet && strings.Contains(request.URL.Path, "/library/copied-index/blobs/") {
+				_, _ = writer.Write([]byte("corrupted"))
+				return true
+			}
+			return false
+		}
+
+		verifyOpts := verifyOpts
+		verifyOpts.BlobSample = 1
+		_, err := v1.VerifyCopy(processedImages, verifyOpts, reg)
+		require.ErrorContains(t, err, "Verifying copied images: found 1 problem(s)")
+	})
+}
+
+func TestVerifyCopyFromOCILayout(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/bundle", []lockconfig.ImageRef{
+		{Image: randomImage.RefDigest},
+	})
+
+	origin, opts, reg := testSetup(fakeRegistry, "", "library/bundle", "", "")
+	layoutDir := t.TempDir()
+	_, err := v1.CopyToOCILayout(origin, layoutDir, opts, reg)
+	require.NoError(t, err)
+
+	bundleRef, layoutPath, err := registry.OCILayoutReference("oci:" + layoutDir)
+	require.NoError(t, err)
+	layoutReg, err := registry.NewOCILayoutRegistry(layoutPath, fakeRegistry.BuildWithRegistryOpts(registry.Opts{
+		EnvironFunc: os.Environ,
+		RetryCount:  3,
+	}))
+	require.NoError(t, err)
+
+	processedImages, err := v1.CopyToRepository(v1.CopyOrigin{BundleRef: bundleRef}, fakeRegistry.ReferenceOnTestServer("library/copied-bundle"), opts, layoutReg)
+	require.NoError(t, err)
+
+	verifyOpts := v1.VerifyCopyOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 2, BlobSample: 1000}
+
+	t.Run("verifies the images in the destination", func(t *testing.T) {
+		result, err := v1.VerifyCopy(processedImages, verifyOpts, layoutReg)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Manifests)
+	})
+
+	t.Run("when the manifest of the bundle is missing in the destination, it fails instead of reading it from the layout", func(t *testing.T) {
+		fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+			if strings.HasSuffix(request.URL.Path, "/library/copied-bundle/manifests/"+bundleInfo.Digest) {
+				writer.WriteHeader(http.StatusNotFound)
+				return true
+			}
+			return false
+		})
+
+		_, err := v1.VerifyCopy(processedImages, verifyOpts, layoutReg)
+		require.ErrorContains(t, err, "Verifying copied images: found 1 problem(s)")
+		assert.ErrorContains(t, err, "Fetching manifest")
+	})
+}
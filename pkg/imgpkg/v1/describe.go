@@ -10,13 +10,12 @@ import (
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
 	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/imageutils/artifact"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
 	"carvel.dev/imgpkg/pkg/imgpkg/signature"
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	regname "github.com/google/go-containerregistry/pkg/name"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 // Author information from a Bundle
@@ -37,9 +36,17 @@ type Metadata struct {
 	Websites []Website         `json:"websites,omitempty"`
 }
 
+// Deprecation Reason why a Bundle should not be used anymore and the Bundle that replaces it
+type Deprecation struct {
+	Message   string `json:"message,omitempty"`
+	Successor string `json:"successor,omitempty"`
+}
+
 // Layers image layers info
 type Layers struct {
 	Digest string `json:"digest,omitempty"`
+	// Size compressed size of the layer, only used by the markdown output so it is not part of the yaml output
+	Size int64 `json:"-"`
 }
 
 // ImageInfo URLs where the image can be found as well as annotations provided in the Images Lock
@@ -48,8 +55,10 @@ type ImageInfo struct {
 	Origin      string            `json:"origin,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 	ImageType   bundle.ImageType  `json:"imageType"`
-	Error       string            `json:"error,omitempty"`
-	Layers      []Layers          `json:"layers,omitempty"`
+	// ArtifactType present when the image is an artifact, like a CNAB bundle or an ORAS artifact, instead of a container image
+	ArtifactType string   `json:"artifactType,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	Layers       []Layers `json:"layers,omitempty"`
 }
 
 // Content Contents present in a Bundle
@@ -66,6 +75,10 @@ type Description struct {
 	Metadata    Metadata          `json:"metadata,omitempty"`
 	Content     Content           `json:"content"`
 	Layers      []Layers          `json:"layers,omitempty"`
+	// Deprecated present when the Bundle is marked as deprecated
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	// ManifestAnnotations annotations of the manifest of the described Bundle, like the ones added by copy --dest-annotation
+	ManifestAnnotations map[string]string `json:"manifestAnnotations,omitempty"`
 }
 
 // DescribeOpts Options used when calling the Describe function
@@ -116,18 +129,65 @@ func DescribeWithRegistryAndSignatureFetcher(bundleImage string, opts DescribeOp
 		return Description{}, fmt.Errorf("Retrieving Images from bundle: %s", err)
 	}
 
+	err = bundle.ValidateDependencies(allBundles)
+	if err != nil {
+		return Description{}, err
+	}
+
+	deprecatedBundles, err := bundle.DeprecatedBundles(allBundles)
+	if err != nil {
+		return Description{}, err
+	}
+	deprecations := map[string]Deprecation{}
+	for _, deprecated := range deprecatedBundles {
+		opts.Logger.Warnf("%s\n", deprecated)
+		deprecations[deprecated.Bundle] = Deprecation{Message: deprecated.Deprecated.Message, Successor: deprecated.Deprecated.Successor}
+	}
+
 	topBundle := refWithDescription{
-		imgRef: bundle.NewBundleImageRef(lockconfig.ImageRef{Image: newBundle.DigestRef()}),
+		imgRef:       bundle.NewBundleImageRef(lockconfig.ImageRef{Image: newBundle.DigestRef()}),
+		deprecations: deprecations,
+	}
+	description, err := topBundle.DescribeBundle(allBundles, reg, opts.Layers)
+	if err != nil {
+		return Description{}, err
 	}
-	return topBundle.DescribeBundle(allBundles, opts.Layers)
+
+	manifestAnnotations, err := bundleManifestAnnotations(newBundle.DigestRef(), reg)
+	if err != nil {
+		return Description{}, err
+	}
+	description.ManifestAnnotations = manifestAnnotations
+	return description, nil
+}
+
+// bundleManifestAnnotations Returns the annotations of the manifest of the bundle
+func bundleManifestAnnotations(bundleRef string, reg bundle.ImagesMetadata) (map[string]string, error) {
+	ref, err := regname.NewDigest(bundleRef)
+	if err != nil {
+		return nil, err
+	}
+	img, err := reg.Image(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching bundle %s: %s", bundleRef, err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("Reading manifest of bundle %s: %s", bundleRef, err)
+	}
+	return manifest.Annotations, nil
 }
 
 type refWithDescription struct {
 	imgRef bundle.ImageRef
 	bundle Description
+	reg    bundle.ImagesMetadata
+	// deprecations of the deprecated bundles indexed by their digest reference
+	deprecations map[string]Deprecation
 }
 
-func (r *refWithDescription) DescribeBundle(bundles []*bundle.Bundle, layers bool) (Description, error) {
+func (r *refWithDescription) DescribeBundle(bundles []*bundle.Bundle, reg bundle.ImagesMetadata, layers bool) (Description, error) {
+	r.reg = reg
 	var visitedImgs map[string]refWithDescription
 	return r.describeBundleRec(visitedImgs, r.imgRef, bundles, layers)
 }
@@ -143,7 +203,7 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 	}
 
 	if showLayers {
-		layers, err = getImageLayersInfo(currentBundle.PrimaryLocation())
+		layers, err = getImageLayersInfo(r.reg, currentBundle.PrimaryLocation())
 		if err != nil {
 			return desc.bundle, err
 		}
@@ -163,6 +223,9 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 			Layers: layers,
 		},
 	}
+	if deprecation, found := r.deprecations[currentBundle.PrimaryLocation()]; found {
+		desc.bundle.Deprecated = &deprecation
+	}
 	var newBundle *bundle.Bundle
 	for _, b := range bundles {
 		if b.DigestRef() == currentBundle.PrimaryLocation() {
@@ -202,17 +265,22 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 					return desc.bundle, fmt.Errorf("Internal inconsistency: image %s should be fully resolved", ref.Image)
 				}
 				if showLayers {
-					layers, err = getImageLayersInfo(ref.PrimaryLocation())
+					layers, err = getImageLayersInfo(r.reg, ref.PrimaryLocation())
 					if err != nil {
 						return desc.bundle, err
 					}
 				}
+				artifactType, err := getArtifactType(r.reg, ref.PrimaryLocation())
+				if err != nil {
+					return desc.bundle, err
+				}
 				desc.bundle.Content.Images[digest.DigestStr()] = ImageInfo{
-					Image:       ref.PrimaryLocation(),
-					Origin:      ref.Image,
-					Annotations: ref.Annotations,
-					ImageType:   ref.ImageType,
-					Layers:      layers,
+					Image:        ref.PrimaryLocation(),
+					Origin:       ref.Image,
+					Annotations:  ref.Annotations,
+					ImageType:    ref.ImageType,
+					ArtifactType: artifactType,
+					Layers:       layers,
 				}
 			} else {
 				desc.bundle.Content.Images[ref.Image] = ImageInfo{
@@ -226,14 +294,32 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 	return desc.bundle, nil
 }
 
-func getImageLayersInfo(image string) ([]Layers, error) {
+func getArtifactType(reg bundle.ImagesMetadata, image string) (string, error) {
+	parsedImgRef, err := regname.ParseReference(image, regname.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("Error: %s in parsing image %s", err.Error(), image)
+	}
+
+	desc, err := reg.Get(parsedImgRef)
+	if err != nil {
+		return "", fmt.Errorf("Error: %s in getting manifest of image %s", err.Error(), image)
+	}
+
+	artifactType, err := artifact.TypeFromManifest(desc.Manifest)
+	if err != nil {
+		return "", fmt.Errorf("Error: %s in getting artifact type of image %s", err.Error(), image)
+	}
+	return artifactType, nil
+}
+
+func getImageLayersInfo(reg bundle.ImagesMetadata, image string) ([]Layers, error) {
 	layers := []Layers{}
 	parsedImgRef, err := regname.ParseReference(image, regname.WeakValidation)
 	if err != nil {
 		return nil, fmt.Errorf("Error: %s in parsing image %s", err.Error(), image)
 	}
 
-	v1Img, err := remote.Image(parsedImgRef, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	v1Img, err := reg.Image(parsedImgRef)
 	if err != nil {
 		return nil, fmt.Errorf("Error: %s in getting remote access of image %s", err.Error(), image)
 	}
@@ -248,7 +334,11 @@ func getImageLayersInfo(image string) ([]Layers, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Error: %s in getting digest of layer's of image %s", err.Error(), image)
 		}
-		layers = append(layers, Layers{Digest: digHash.String()})
+		size, err := imgLayer.Size()
+		if err != nil {
+			return nil, fmt.Errorf("Error: %s in getting size of layer's of image %s", err.Error(), image)
+		}
+		layers = append(layers, Layers{Digest: digHash.String(), Size: size})
 	}
 	return layers, nil
 }
@@ -0,0 +1,120 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// ImageConfigOpts Options of the inspection of the config of an image
+type ImageConfigOpts struct {
+	// IndexChildPlatform when the image is an image index, inspect the child manifest of this platform (example: linux/arm64)
+	IndexChildPlatform string
+}
+
+// ImageConfig Config of an image: environment, entrypoint, labels and history
+type ImageConfig struct {
+	// Image digest reference to the image whose config was read
+	Image        string           `json:"image"`
+	ConfigDigest string           `json:"configDigest"`
+	Config       regv1.ConfigFile `json:"config"`
+}
+
+// GetImageConfig Reads the config of the image referenced by imageRef, verifying that the manifest matches the digest
+// of imageRef, or the one reported by the registry, and that the config matches the digest recorded in the manifest
+func GetImageConfig(imageRef string, opts ImageConfigOpts, reg registry.ImagesReader) (ImageConfig, error) {
+	if opts.IndexChildPlatform != "" {
+		childRef, err := plainimage.ResolveIndexChild(imageRef, opts.IndexChildPlatform, reg)
+		if err != nil {
+			return ImageConfig{}, err
+		}
+		imageRef = childRef
+	}
+
+	ref, err := regname.ParseReference(imageRef, regname.WeakValidation)
+	if err != nil {
+		return ImageConfig{}, err
+	}
+
+	desc, err := reg.Get(ref)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Fetching image %s: %s", imageRef, err)
+	}
+	if desc.MediaType.IsIndex() {
+		return ImageConfig{}, fmt.Errorf("Expected %s to be an image, but found an image index (hint: select one of its images with --index-child-platform%s)",
+			imageRef, indexPlatformsHint(desc.Manifest))
+	}
+
+	manifestDigest, _, err := regv1.SHA256(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Hashing manifest of %s: %s", imageRef, err)
+	}
+	expectedDigest := desc.Digest
+	if digestRef, isDigest := ref.(regname.Digest); isDigest {
+		expectedDigest, err = regv1.NewHash(digestRef.DigestStr())
+		if err != nil {
+			return ImageConfig{}, fmt.Errorf("Parsing digest of %s: %s", imageRef, err)
+		}
+	}
+	if manifestDigest != expectedDigest {
+		return ImageConfig{}, fmt.Errorf("Expected manifest of %s to have digest %s but found %s", imageRef, expectedDigest, manifestDigest)
+	}
+
+	manifest, err := regv1.ParseManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Parsing manifest of %s: %s", imageRef, err)
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Reading image %s: %s", imageRef, err)
+	}
+	rawConfig, err := img.RawConfigFile()
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Fetching config of %s: %s", imageRef, err)
+	}
+	configDigest, _, err := regv1.SHA256(bytes.NewReader(rawConfig))
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Hashing config of %s: %s", imageRef, err)
+	}
+	if configDigest != manifest.Config.Digest {
+		return ImageConfig{}, fmt.Errorf("Expected config of %s to have digest %s but found %s", imageRef, manifest.Config.Digest, configDigest)
+	}
+
+	config, err := regv1.ParseConfigFile(bytes.NewReader(rawConfig))
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("Parsing config of %s: %s", imageRef, err)
+	}
+
+	return ImageConfig{
+		Image:        ref.Context().Digest(manifestDigest.String()).Name(),
+		ConfigDigest: configDigest.String(),
+		Config:       *config,
+	}, nil
+}
+
+// indexPlatformsHint Lists the platforms of the image index manifest, empty when they cannot be read
+func indexPlatformsHint(indexManifest []byte) string {
+	idx, err := regv1.ParseIndexManifest(bytes.NewReader(indexManifest))
+	if err != nil {
+		return ""
+	}
+	var platforms []string
+	for _, child := range idx.Manifests {
+		if child.Platform != nil {
+			platforms = append(platforms, child.Platform.String())
+		}
+	}
+	if len(platforms) == 0 {
+		return ""
+	}
+	return ", available platforms: " + strings.Join(platforms, ", ")
+}
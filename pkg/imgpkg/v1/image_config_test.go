@@ -0,0 +1,83 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetImageConfig(t *testing.T) {
+	randomImg, err := random.Image(100, 1)
+	require.NoError(t, err)
+	img, err := mutate.Config(randomImg, regv1.Config{
+		Env:        []string{"PATH=/usr/bin", "APP_MODE=production"},
+		Entrypoint: []string{"/app"},
+		Labels:     map[string]string{"org.opencontainers.image.source": "https://github.com/carvel-dev/imgpkg"},
+	})
+	require.NoError(t, err)
+	configDigest, err := img.ConfigName()
+	require.NoError(t, err)
+
+	t.Run("it returns the config of the image", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		imgInfo := fakeRegistry.WithImage("library/app", img)
+		reg := fakeRegistry.Build()
+
+		imageConfig, err := v1.GetImageConfig(imgInfo.RefDigest, v1.ImageConfigOpts{}, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, imgInfo.RefDigest, imageConfig.Image)
+		assert.Equal(t, configDigest.String(), imageConfig.ConfigDigest)
+		assert.Equal(t, []string{"PATH=/usr/bin", "APP_MODE=production"}, imageConfig.Config.Config.Env)
+		assert.Equal(t, []string{"/app"}, imageConfig.Config.Config.Entrypoint)
+		assert.Equal(t, "https://github.com/carvel-dev/imgpkg", imageConfig.Config.Config.Labels["org.opencontainers.image.source"])
+	})
+
+	t.Run("when the image is an image index, it returns the config of the image of the platform requested", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		indexInfo := fakeRegistry.WithImageIndexForPlatforms("library/multi-arch", "linux/amd64", "linux/arm64")
+		reg := fakeRegistry.Build()
+
+		_, err := v1.GetImageConfig(indexInfo.RefDigest, v1.ImageConfigOpts{}, reg)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "found an image index (hint: select one of its images with --index-child-platform, available platforms: linux/amd64, linux/arm64)")
+
+		indexManifest, err := indexInfo.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		imageConfig, err := v1.GetImageConfig(indexInfo.RefDigest, v1.ImageConfigOpts{IndexChildPlatform: "linux/arm64"}, reg)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(imageConfig.Image, "@"+indexManifest.Manifests[1].Digest.String()))
+	})
+
+	t.Run("when the config returned by the registry does not match its digest, it errors", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		imgInfo := fakeRegistry.WithImage("library/app", img)
+		reg := fakeRegistry.Build()
+		fakeRegistry.WithHandlerFunc(func(writer http.ResponseWriter, request *http.Request) bool {
+			if !strings.HasSuffix(request.URL.Path, "/blobs/"+configDigest.String()) {
+				return false
+			}
+			writer.WriteHeader(http.StatusOK)
+			_, _ = writer.Write([]byte(`{"config":{"Env":["TAMPERED=true"]}}`))
+			return true
+		})
+
+		_, err := v1.GetImageConfig(imgInfo.RefDigest, v1.ImageConfigOpts{}, reg)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "config of "+imgInfo.RefDigest)
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// regexExclusionPrefix Prefix of the exclusion patterns that are regular expressions instead of globs
+const regexExclusionPrefix = "regex:"
+
+// imageExclusions Patterns that select the images of a bundle that are not copied. The patterns are matched against
+// the repository of the images in the ImagesLock (example: registry.io/org/app-docs), globs match the whole repository
+// while regular expressions only need to match part of it
+type imageExclusions struct {
+	patterns []*regexp.Regexp
+}
+
+func newImageExclusions(patterns []string) (imageExclusions, error) {
+	var exclusions imageExclusions
+	for _, pattern := range patterns {
+		expression := globToRegexp(pattern)
+		if strings.HasPrefix(pattern, regexExclusionPrefix) {
+			expression = strings.TrimPrefix(pattern, regexExclusionPrefix)
+		}
+
+		compiled, err := regexp.Compile(expression)
+		if err != nil {
+			return imageExclusions{}, fmt.Errorf("Invalid exclude images pattern '%s': %s", pattern, err)
+		}
+		exclusions.patterns = append(exclusions.patterns, compiled)
+	}
+	return exclusions, nil
+}
+
+// Empty Returns true when no image is excluded
+func (e imageExclusions) Empty() bool {
+	return len(e.patterns) == 0
+}
+
+// Excludes Returns true when the image matches any of the patterns
+func (e imageExclusions) Excludes(imageRef string) bool {
+	ref, err := regname.ParseReference(imageRef)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range e.patterns {
+		if pattern.MatchString(ref.Context().Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp Converts a glob, where * matches any sequence of characters and ? a single character, to an anchored regular expression
+func globToRegexp(glob string) string {
+	expression := regexp.QuoteMeta(glob)
+	expression = strings.ReplaceAll(expression, `\*`, ".*")
+	expression = strings.ReplaceAll(expression, `\?`, ".")
+	return "^" + expression + "$"
+}
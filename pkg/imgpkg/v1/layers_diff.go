@@ -0,0 +1,110 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+)
+
+// Status of a layer when comparing two bundles
+const (
+	LayerSharedStatus  = "shared"
+	LayerNewStatus     = "new"
+	LayerRemovedStatus = "removed"
+)
+
+// LayersDiff Layers that two versions of a bundle share and the ones that only one of them has.
+// The layers of the bundle, of its images and of its nested bundles are compared, image configs included
+type LayersDiff struct {
+	OldBundle string `json:"oldBundle"`
+	NewBundle string `json:"newBundle"`
+
+	Layers []LayerDiff `json:"layers"`
+
+	SharedLayers  int   `json:"sharedLayers"`
+	SharedBytes   int64 `json:"sharedBytes"`
+	NewLayers     int   `json:"newLayers"`
+	NewBytes      int64 `json:"newBytes"`
+	RemovedLayers int   `json:"removedLayers"`
+	RemovedBytes  int64 `json:"removedBytes"`
+	// TransferSize compressed size of the manifests and layers that copying the new bundle to a repository
+	// that contains the old one would transfer
+	TransferSize int64 `json:"transferSize"`
+}
+
+// LayerDiff Layer of one, or both, of the bundles compared
+type LayerDiff struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"`
+}
+
+// DiffLayers Compares the layers of the images that copying each one of the bundles would transfer
+func DiffLayers(oldBundleRef, newBundleRef string, opts CopyOpts, reg registry.Registry) (LayersDiff, error) {
+	opts.Logger.Tracef("DiffLayers\n")
+
+	oldReporter, err := reportBundleBlobs(oldBundleRef, opts, reg)
+	if err != nil {
+		return LayersDiff{}, err
+	}
+	newReporter, err := reportBundleBlobs(newBundleRef, opts, reg)
+	if err != nil {
+		return LayersDiff{}, err
+	}
+
+	diff := LayersDiff{OldBundle: oldBundleRef, NewBundle: newBundleRef}
+	for digest, size := range newReporter.blobs {
+		if _, found := oldReporter.blobs[digest]; found {
+			diff.Layers = append(diff.Layers, LayerDiff{Digest: digest.String(), Size: size, Status: LayerSharedStatus})
+			diff.SharedLayers++
+			diff.SharedBytes += size
+			continue
+		}
+		diff.Layers = append(diff.Layers, LayerDiff{Digest: digest.String(), Size: size, Status: LayerNewStatus})
+		diff.NewLayers++
+		diff.NewBytes += size
+	}
+	for digest, size := range oldReporter.blobs {
+		if _, found := newReporter.blobs[digest]; !found {
+			diff.Layers = append(diff.Layers, LayerDiff{Digest: digest.String(), Size: size, Status: LayerRemovedStatus})
+			diff.RemovedLayers++
+			diff.RemovedBytes += size
+		}
+	}
+	sort.Slice(diff.Layers, func(i, j int) bool {
+		if diff.Layers[i].Status != diff.Layers[j].Status {
+			return diff.Layers[i].Status < diff.Layers[j].Status
+		}
+		return diff.Layers[i].Digest < diff.Layers[j].Digest
+	})
+
+	diff.TransferSize = diff.NewBytes
+	for digest, size := range newReporter.manifests {
+		if _, found := oldReporter.manifests[digest]; !found {
+			diff.TransferSize += size
+		}
+	}
+
+	return diff, nil
+}
+
+// reportBundleBlobs Collects the manifests and blobs that copying the bundle would transfer
+func reportBundleBlobs(bundleRef string, opts CopyOpts, reg registry.Registry) (copyReporter, error) {
+	imgOrIndexes, err := readImagesToCopy(CopyOrigin{BundleRef: bundleRef}, opts, reg)
+	if err != nil {
+		return copyReporter{}, err
+	}
+
+	reporter := newCopyReporter(opts)
+	for _, item := range imgOrIndexes {
+		_, err := reporter.reportImageOrIndex(item)
+		if err != nil {
+			return copyReporter{}, fmt.Errorf("Reporting '%s': %s", item.Ref(), err)
+		}
+	}
+	return reporter, nil
+}
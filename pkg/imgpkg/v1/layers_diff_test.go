@@ -0,0 +1,94 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLayers(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	sharedImage := fakeRegistry.WithRandomImage("library/shared-image")
+	removedImage := fakeRegistry.WithRandomImage("library/removed-image")
+	newImage := fakeRegistry.WithRandomImage("library/new-image")
+	oldBundle := fakeRegistry.WithBundleFromPath("library/bundle-old", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+			{Image: removedImage.RefDigest},
+		})
+	newBundle := fakeRegistry.WithBundleFromPath("library/bundle-new", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: sharedImage.RefDigest},
+			{Image: newImage.RefDigest},
+		})
+
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+
+	diff, err := v1.DiffLayers(oldBundle.RefDigest, newBundle.RefDigest, opts, reg)
+	require.NoError(t, err)
+
+	statuses := map[string]string{}
+	for _, layer := range diff.Layers {
+		statuses[layer.Digest] = layer.Status
+	}
+
+	t.Run("it reports the layers of the images in both bundles as shared", func(t *testing.T) {
+		for _, digest := range imageBlobDigests(t, sharedImage.Image) {
+			assert.Equal(t, v1.LayerSharedStatus, statuses[digest], digest)
+		}
+	})
+
+	t.Run("it reports the layers of the images only in the new bundle as new", func(t *testing.T) {
+		for _, digest := range imageBlobDigests(t, newImage.Image) {
+			assert.Equal(t, v1.LayerNewStatus, statuses[digest], digest)
+		}
+	})
+
+	t.Run("it reports the layers of the images only in the old bundle as removed", func(t *testing.T) {
+		for _, digest := range imageBlobDigests(t, removedImage.Image) {
+			assert.Equal(t, v1.LayerRemovedStatus, statuses[digest], digest)
+		}
+	})
+
+	t.Run("it sums the sizes and counts the layers of each status", func(t *testing.T) {
+		counts := map[string]int{}
+		sizes := map[string]int64{}
+		for _, layer := range diff.Layers {
+			counts[layer.Status]++
+			sizes[layer.Status] += layer.Size
+		}
+		assert.Equal(t, counts[v1.LayerSharedStatus], diff.SharedLayers)
+		assert.Equal(t, sizes[v1.LayerSharedStatus], diff.SharedBytes)
+		assert.Equal(t, counts[v1.LayerNewStatus], diff.NewLayers)
+		assert.Equal(t, sizes[v1.LayerNewStatus], diff.NewBytes)
+		assert.Equal(t, counts[v1.LayerRemovedStatus], diff.RemovedLayers)
+		assert.Equal(t, sizes[v1.LayerRemovedStatus], diff.RemovedBytes)
+	})
+
+	t.Run("it includes the manifests missing from the old bundle in the transfer size", func(t *testing.T) {
+		newImageManifest, err := newImage.Image.RawManifest()
+		require.NoError(t, err)
+		assert.Greater(t, diff.TransferSize, diff.NewBytes+int64(len(newImageManifest)))
+	})
+}
+
+func imageBlobDigests(t *testing.T, img regv1.Image) []string {
+	manifest, err := img.Manifest()
+	require.NoError(t, err)
+
+	digests := []string{manifest.Config.Digest.String()}
+	for _, layer := range manifest.Layers {
+		digests = append(digests, layer.Digest.String())
+	}
+	return digests
+}
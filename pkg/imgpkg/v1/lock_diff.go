@@ -0,0 +1,177 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// LockDiffOpts Options of the comparison between a lock file and a repository
+type LockDiffOpts struct {
+	Logger      Logger
+	Concurrency int
+}
+
+// LockDiffImage Image of the lock file and whether it is present in the repository
+type LockDiffImage struct {
+	// Image reference to the image in the lock file, or in the ImagesLock of the bundle
+	Image string `json:"image"`
+	// Destination reference to the image in the repository, where copy --to-repo writes it
+	Destination string `json:"destination"`
+	IsBundle    bool   `json:"isBundle"`
+	Present     bool   `json:"present"`
+}
+
+// LockDiff Images of a lock file present and missing in a repository
+type LockDiff struct {
+	Repository string          `json:"repository"`
+	Images     []LockDiffImage `json:"images"`
+}
+
+// Missing Returns the images that are not present in the repository
+func (d LockDiff) Missing() []LockDiffImage {
+	var missing []LockDiffImage
+	for _, img := range d.Images {
+		if !img.Present {
+			missing = append(missing, img)
+		}
+	}
+	return missing
+}
+
+// MissingImagesLock Returns an ImagesLock with the missing images that are not bundles, that copy --lock can transfer
+func (d LockDiff) MissingImagesLock() lockconfig.ImagesLock {
+	imagesLock := lockconfig.ImagesLock{
+		LockVersion: lockconfig.LockVersion{
+			APIVersion: lockconfig.ImagesLockAPIVersion,
+			Kind:       lockconfig.ImagesLockKind,
+		},
+	}
+	for _, img := range d.Missing() {
+		if !img.IsBundle {
+			imagesLock.Images = append(imagesLock.Images, lockconfig.ImageRef{Image: img.Image})
+		}
+	}
+	return imagesLock
+}
+
+// DiffLock Checks which of the images of the lock file, an ImagesLock or a BundleLock, are present in repository, without copying
+// anything. For a BundleLock the images of the bundle and of its nested bundles are checked, they are read from the
+// bundle in the repository when it was already copied, so that mirrors can be audited without access to the source registry
+func DiffLock(lockPath, repository string, opts LockDiffOpts, reg registry.Registry) (LockDiff, error) {
+	repo, err := regname.NewRepository(repository)
+	if err != nil {
+		return LockDiff{}, fmt.Errorf("Building repository ref: %s", err)
+	}
+
+	bundleLock, imagesLock, err := lockconfig.NewLockFromPath(lockPath)
+	if err != nil {
+		return LockDiff{}, err
+	}
+
+	var images []LockDiffImage
+	if imagesLock != nil {
+		for _, img := range imagesLock.Images {
+			images = append(images, LockDiffImage{Image: img.Image})
+		}
+	} else {
+		images, err = lockDiffBundleImages(bundleLock.Bundle.Image, repo, opts, reg)
+		if err != nil {
+			return LockDiff{}, err
+		}
+	}
+
+	digests := map[regv1.Hash]int64{}
+	hashes := make([]regv1.Hash, len(images))
+	for i, img := range images {
+		ref, err := regname.NewDigest(img.Image)
+		if err != nil {
+			return LockDiff{}, fmt.Errorf("Expected image '%s' to be referenced by digest: %s", img.Image, err)
+		}
+		digest, err := regv1.NewHash(ref.DigestStr())
+		if err != nil {
+			return LockDiff{}, fmt.Errorf("Parsing digest of '%s': %s", img.Image, err)
+		}
+		digests[digest] = 0
+		hashes[i] = digest
+		images[i].Destination = repo.Digest(ref.DigestStr()).Name()
+	}
+
+	present, err := presentInRepository(repo, digests, max(opts.Concurrency, 1), func(ref regname.Digest) (bool, error) {
+		return manifestExists(reg, ref)
+	})
+	if err != nil {
+		return LockDiff{}, err
+	}
+
+	found := map[string]bool{}
+	diff := LockDiff{Repository: repo.Name()}
+	for i, img := range images {
+		if found[img.Destination] {
+			continue
+		}
+		found[img.Destination] = true
+
+		img.Present = present[hashes[i]]
+		diff.Images = append(diff.Images, img)
+	}
+	sort.SliceStable(diff.Images, func(i, j int) bool { return diff.Images[i].Image < diff.Images[j].Image })
+	return diff, nil
+}
+
+// lockDiffBundleImages Returns the bundle and the images of the bundle and of its nested bundles
+func lockDiffBundleImages(bundleRef string, repo regname.Repository, opts LockDiffOpts, reg registry.Registry) ([]LockDiffImage, error) {
+	ref, err := regname.ParseReference(bundleRef)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing bundle reference '%s': %s", bundleRef, err)
+	}
+	digest := ref.Identifier()
+	if _, isDigest := ref.(regname.Digest); !isDigest {
+		hash, err := reg.Digest(ref)
+		if err != nil {
+			return nil, fmt.Errorf("Resolving bundle '%s': %s", bundleRef, err)
+		}
+		digest = hash.String()
+	}
+	bundleDigestRef := ref.Context().Digest(digest).Name()
+
+	readFrom := bundleDigestRef
+	copiedRef := repo.Digest(digest)
+	copied, err := manifestExists(reg, copiedRef)
+	if err != nil {
+		return nil, fmt.Errorf("Checking existence of '%s': %s", copiedRef.Name(), err)
+	}
+	if copied {
+		opts.Logger.Debugf("reading the images of the bundle from %s\n", copiedRef.Name())
+		readFrom = copiedRef.Name()
+	}
+
+	lockReader := bundle.NewImagesLockReader()
+	rootBundle := bundle.NewBundleFromRef(readFrom, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+	isBundle, err := rootBundle.IsBundle()
+	if err != nil {
+		return nil, err
+	}
+	if !isBundle {
+		return nil, fmt.Errorf("Expected bundle image but found plain image")
+	}
+
+	_, imageRefs, err := rootBundle.AllImagesLockRefs(max(opts.Concurrency, 1), opts.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("Reading Images from Bundle: %s", err)
+	}
+
+	images := []LockDiffImage{{Image: bundleDigestRef, IsBundle: true}}
+	for _, img := range imageRefs.ImageRefs() {
+		images = append(images, LockDiffImage{Image: img.Image, IsBundle: img.IsBundle != nil && *img.IsBundle})
+	}
+	return images, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLock(t *testing.T) {
+	opts := v1.LockDiffOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 2}
+
+	t.Run("when an ImagesLock is provided, it reports the images present in and missing from the repository", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img1 := fakeRegistry.WithRandomImage("library/image-1")
+		img2 := fakeRegistry.WithRandomImage("library/image-2")
+		fakeRegistry.CopyImage(*img1, "mirror/app")
+		reg := fakeRegistry.Build()
+
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		imagesLock := lockconfig.ImagesLock{
+			LockVersion: lockconfig.LockVersion{APIVersion: lockconfig.ImagesLockAPIVersion, Kind: lockconfig.ImagesLockKind},
+			Images:      []lockconfig.ImageRef{{Image: img1.RefDigest}, {Image: img2.RefDigest}},
+		}
+		require.NoError(t, imagesLock.WriteToPath(lockPath))
+
+		mirror := fakeRegistry.ReferenceOnTestServer("mirror/app")
+		diff, err := v1.DiffLock(lockPath, mirror, opts, reg)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []v1.LockDiffImage{
+			{Image: img1.RefDigest, Destination: mirror + "@" + img1.Digest, Present: true},
+			{Image: img2.RefDigest, Destination: mirror + "@" + img2.Digest, Present: false},
+		}, diff.Images)
+		assert.Equal(t, []lockconfig.ImageRef{{Image: img2.RefDigest}}, diff.MissingImagesLock().Images)
+	})
+
+	t.Run("when a BundleLock is provided, it reads the bundle from the repository and reports its images", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img1 := fakeRegistry.WithRandomImage("library/image-1")
+		bundleInfo := fakeRegistry.WithRandomBundle("library/bundle").WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}})
+		fakeRegistry.CopyBundleImage(bundleInfo, "mirror/app")
+		reg := fakeRegistry.Build()
+
+		lockPath := filepath.Join(t.TempDir(), "bundle.lock.yml")
+		bundleLock := lockconfig.BundleLock{
+			LockVersion: lockconfig.LockVersion{APIVersion: lockconfig.BundleLockAPIVersion, Kind: lockconfig.BundleLockKind},
+			Bundle:      lockconfig.BundleRef{Image: bundleInfo.RefDigest},
+		}
+		require.NoError(t, bundleLock.WriteToPath(lockPath))
+
+		mirror := fakeRegistry.ReferenceOnTestServer("mirror/app")
+		diff, err := v1.DiffLock(lockPath, mirror, opts, reg)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []v1.LockDiffImage{
+			{Image: bundleInfo.RefDigest, Destination: mirror + "@" + bundleInfo.Digest, IsBundle: true, Present: true},
+			{Image: img1.RefDigest, Destination: mirror + "@" + img1.Digest, Present: false},
+		}, diff.Images)
+		require.Len(t, diff.Missing(), 1)
+	})
+}
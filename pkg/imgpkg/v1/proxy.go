@@ -0,0 +1,246 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"golang.org/x/sync/singleflight"
+)
+
+// ProxyMapping Upstream repository, or prefix of repositories, served by the proxy under the name Local
+// (example: registry.corp/org=org serves registry.corp/org/app as <proxy>/org/app)
+type ProxyMapping struct {
+	Upstream string
+	Local    string
+}
+
+// ProxyOpts Options of the read-through proxy
+type ProxyOpts struct {
+	Mappings []ProxyMapping
+	// Repository where the images are relocated the first time they are requested, and then served from
+	Repository string
+	// CopyOpts options of the copies of the images requested to Repository
+	CopyOpts CopyOpts
+}
+
+// Proxy Read-only registry that relocates the images to a repository when clients request them for the first time.
+// Requesting a bundle relocates it together with all its images and nested bundles
+type Proxy struct {
+	opts       ProxyOpts
+	repository regname.Repository
+	mappings   []ProxyMapping
+	reg        registry.Registry
+
+	// copies relocations in progress by digest
+	copies singleflight.Group
+}
+
+// NewProxy Creates the proxy of the mappings in opts
+func NewProxy(opts ProxyOpts, reg registry.Registry) (*Proxy, error) {
+	repository, err := regname.NewRepository(opts.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing repository '%s': %s", opts.Repository, err)
+	}
+	if len(opts.Mappings) == 0 {
+		return nil, fmt.Errorf("Expected at least one mapping of an upstream repository")
+	}
+
+	mappings := append([]ProxyMapping{}, opts.Mappings...)
+	for i, mapping := range mappings {
+		_, err := regname.NewRepository(mapping.Upstream)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing upstream repository '%s': %s", mapping.Upstream, err)
+		}
+		mappings[i].Local = strings.Trim(mapping.Local, "/")
+		if mappings[i].Local == "" {
+			return nil, fmt.Errorf("Expected a name for the upstream repository '%s'", mapping.Upstream)
+		}
+	}
+	// The longest names first, so that the most specific mapping is used
+	sort.SliceStable(mappings, func(i, j int) bool { return len(mappings[i].Local) > len(mappings[j].Local) })
+
+	return &Proxy{opts: opts, repository: repository, mappings: mappings, reg: reg}, nil
+}
+
+// ServeHTTP Serves the pull endpoints of the OCI distribution API
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeProxyError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "The proxy is read-only")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case path == "" || path == "/v2":
+		w.WriteHeader(http.StatusOK)
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		p.serveManifest(w, r, path[:i], path[i+len("/manifests/"):])
+	case strings.Contains(path, "/blobs/"):
+		i := strings.LastIndex(path, "/blobs/")
+		p.serveBlob(w, r, path[:i], path[i+len("/blobs/"):])
+	default:
+		writeProxyError(w, http.StatusNotFound, "UNSUPPORTED", fmt.Sprintf("Endpoint '%s' is not supported by the proxy", r.URL.Path))
+	}
+}
+
+func (p *Proxy) serveManifest(w http.ResponseWriter, r *http.Request, name, reference string) {
+	upstreamRepo, ok := p.upstream(name)
+	if !ok {
+		writeProxyError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("Repository '%s' is not mapped to an upstream repository", name))
+		return
+	}
+
+	var upstreamRef regname.Reference = upstreamRepo.Tag(reference)
+	if strings.Contains(reference, ":") {
+		upstreamRef = upstreamRepo.Digest(reference)
+	}
+	digest, err := p.reg.Digest(upstreamRef)
+	if err != nil {
+		p.opts.CopyOpts.Logger.Errorf("Resolving %s: %s\n", upstreamRef.Name(), err)
+		writeProxyError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("Manifest '%s' was not found upstream", upstreamRef.Name()))
+		return
+	}
+
+	localRef := p.repository.Digest(digest.String())
+	err = p.relocate(upstreamRepo.Digest(digest.String()), localRef)
+	if err != nil {
+		p.opts.CopyOpts.Logger.Errorf("Relocating %s: %s\n", upstreamRef.Name(), err)
+		writeProxyError(w, http.StatusBadGateway, "UNKNOWN", fmt.Sprintf("Relocating '%s': %s", upstreamRef.Name(), err))
+		return
+	}
+
+	desc, err := p.reg.Get(localRef)
+	if err != nil {
+		writeProxyError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("Manifest '%s' was not found: %s", localRef.Name(), err))
+		return
+	}
+	w.Header().Set("Content-Type", string(desc.MediaType))
+	w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+	w.Header().Set("Content-Length", strconv.Itoa(len(desc.Manifest)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(desc.Manifest)
+	}
+}
+
+// serveBlob Serves the blobs from the repository, they are always requested after the manifest that relocated them
+func (p *Proxy) serveBlob(w http.ResponseWriter, r *http.Request, name, reference string) {
+	if _, ok := p.upstream(name); !ok {
+		writeProxyError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("Repository '%s' is not mapped to an upstream repository", name))
+		return
+	}
+	digest, err := regv1.NewHash(reference)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, "DIGEST_INVALID", fmt.Sprintf("Parsing digest '%s': %s", reference, err))
+		return
+	}
+
+	layer, err := registry.Layer(p.reg, p.repository.Digest(digest.String()))
+	if err != nil {
+		writeProxyError(w, http.StatusNotFound, "BLOB_UNKNOWN", fmt.Sprintf("Blob '%s' was not found: %s", digest, err))
+		return
+	}
+	size, err := layer.Size()
+	if err != nil {
+		writeProxyError(w, http.StatusNotFound, "BLOB_UNKNOWN", fmt.Sprintf("Blob '%s' was not found: %s", digest, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest.String())
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	blob, err := layer.Compressed()
+	if err != nil {
+		writeProxyError(w, http.StatusBadGateway, "UNKNOWN", fmt.Sprintf("Reading blob '%s': %s", digest, err))
+		return
+	}
+	defer blob.Close()
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		p.opts.CopyOpts.Logger.Errorf("Serving blob %s: %s\n", digest, err)
+	}
+}
+
+// relocate Copies the upstream image, or the bundle and all its images, to the repository unless it is already there.
+// Concurrent requests of the same image wait for a single copy, while different images are copied in parallel
+func (p *Proxy) relocate(upstreamRef, localRef regname.Digest) error {
+	if _, err := p.reg.Digest(localRef); err == nil {
+		return nil
+	}
+
+	_, err, _ := p.copies.Do(localRef.DigestStr(), func() (interface{}, error) {
+		if _, err := p.reg.Digest(localRef); err == nil {
+			return nil, nil
+		}
+		return nil, p.copy(upstreamRef)
+	})
+	return err
+}
+
+// copy Copies the upstream image, or the bundle and all its images, to the repository
+func (p *Proxy) copy(upstreamRef regname.Digest) error {
+	isBundle, err := bundle.NewBundleFromPlainImage(plainimage.NewPlainImage(upstreamRef.Name(), p.reg), p.reg).IsBundle()
+	if err != nil {
+		return err
+	}
+	origin := CopyOrigin{ImageRef: upstreamRef.Name()}
+	if isBundle {
+		origin = CopyOrigin{BundleRef: upstreamRef.Name()}
+	}
+
+	p.opts.CopyOpts.Logger.Logf("Relocating %s to %s\n", upstreamRef.Name(), p.repository.Name())
+	_, err = CopyToRepository(origin, p.repository.Name(), p.opts.CopyOpts, p.reg)
+	return err
+}
+
+// upstream Returns the upstream repository of the name requested to the proxy
+func (p *Proxy) upstream(name string) (regname.Repository, bool) {
+	for _, mapping := range p.mappings {
+		var upstream string
+		switch {
+		case name == mapping.Local:
+			upstream = mapping.Upstream
+		case strings.HasPrefix(name, mapping.Local+"/"):
+			upstream = mapping.Upstream + strings.TrimPrefix(name, mapping.Local)
+		default:
+			continue
+		}
+		repo, err := regname.NewRepository(upstream)
+		if err != nil {
+			return regname.Repository{}, false
+		}
+		return repo, true
+	}
+	return regname.Repository{}, false
+}
+
+func writeProxyError(w http.ResponseWriter, status int, code, message string) {
+	type registryError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string][]registryError{"errors": {{Code: code, Message: message}}})
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/imageset"
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	fakeDestRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeDestRegistry.CleanUp()
+
+	taggedImg := fakeRegistry.WithRandomTaggedImage("org/app:1.0.0", "1.0.0")
+	concurrentImg := fakeRegistry.WithRandomTaggedImage("org/concurrent-app:1.0.0", "1.0.0")
+	bundleImg := fakeRegistry.WithRandomImage("org/app-image")
+	bundle := fakeRegistry.WithRandomBundle("org/app-bundle").WithImageRefs([]lockconfig.ImageRef{{Image: bundleImg.RefDigest}})
+	reg := fakeRegistry.Build()
+	fakeDestRegistry.Build()
+
+	uiLogger := util.NewNoopLevelLogger()
+	imageSet := imageset.NewImageSet(1, uiLogger, util.DefaultTagGenerator{})
+	destRepo := fakeDestRegistry.ReferenceOnTestServer("edge/mirror")
+	proxy, err := v1.NewProxy(v1.ProxyOpts{
+		Mappings:   []v1.ProxyMapping{{Upstream: fakeRegistry.ReferenceOnTestServer("org"), Local: "upstream"}},
+		Repository: destRepo,
+		CopyOpts: v1.CopyOpts{
+			Logger:             uiLogger,
+			ImageSet:           imageSet,
+			TarImageSet:        imageset.NewTarImageSet(imageSet, 1, uiLogger),
+			Concurrency:        1,
+			SignatureRetriever: &fakeSignatureRetriever{},
+		},
+	}, reg)
+	require.NoError(t, err)
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+	proxyURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	t.Run("when an image is requested by tag, it relocates the image and serves it from the repository", func(t *testing.T) {
+		_, err := reg.Digest(parseProxyTestReference(t, destRepo+"@"+taggedImg.Digest))
+		require.Error(t, err, "image should not be in the repository before it is requested")
+
+		img, err := regremote.Image(parseProxyTestReference(t, proxyURL.Host+"/upstream/app:1.0.0"))
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, taggedImg.Digest, digest.String())
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			// the digest of the contents served is verified while reading them
+			contents, err := layer.Compressed()
+			require.NoError(t, err)
+			_, err = io.ReadAll(contents)
+			require.NoError(t, err)
+			require.NoError(t, contents.Close())
+		}
+
+		_, err = reg.Digest(parseProxyTestReference(t, destRepo+"@"+taggedImg.Digest))
+		require.NoError(t, err)
+	})
+
+	t.Run("when a bundle is requested, it relocates the bundle with its images", func(t *testing.T) {
+		bundleDigest := parseProxyTestReference(t, bundle.RefDigest).Identifier()
+		_, err := regremote.Image(parseProxyTestReference(t, proxyURL.Host+"/upstream/app-bundle@"+bundleDigest))
+		require.NoError(t, err)
+
+		_, err = reg.Digest(parseProxyTestReference(t, destRepo+"@"+bundleDigest))
+		require.NoError(t, err)
+		_, err = reg.Digest(parseProxyTestReference(t, destRepo+"@"+bundleImg.Digest))
+		require.NoError(t, err, "image of the bundle should be relocated with the bundle")
+	})
+
+	t.Run("when an image is requested concurrently, it is relocated and served to every request", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				img, err := regremote.Image(parseProxyTestReference(t, proxyURL.Host+"/upstream/concurrent-app:1.0.0"))
+				if !assert.NoError(t, err) {
+					return
+				}
+				digest, err := img.Digest()
+				assert.NoError(t, err)
+				assert.Equal(t, concurrentImg.Digest, digest.String())
+			}()
+		}
+		wg.Wait()
+
+		_, err := reg.Digest(parseProxyTestReference(t, destRepo+"@"+concurrentImg.Digest))
+		require.NoError(t, err)
+	})
+
+	t.Run("when the repository is not mapped, it fails", func(t *testing.T) {
+		_, err := regremote.Image(parseProxyTestReference(t, proxyURL.Host+"/other/app:1.0.0"))
+		require.ErrorContains(t, err, "NAME_UNKNOWN")
+	})
+
+	t.Run("when an image is pushed, it fails", func(t *testing.T) {
+		resp, err := http.Post(server.URL+"/v2/upstream/app/blobs/uploads/", "application/octet-stream", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func parseProxyTestReference(t *testing.T, ref string) regname.Reference {
+	parsedRef, err := regname.ParseReference(ref)
+	require.NoError(t, err)
+	return parsedRef
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "carvel.dev/imgpkg/pkg/imgpkg/image"
 	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -29,12 +30,22 @@ type PullOpts struct {
 	AsImage bool
 	// IsBundle the image being pulled is a Bundle
 	IsBundle bool
+	// IndexChildPlatform when the image is an image index, pull only the child manifest of this platform (example: linux/arm64)
+	IndexChildPlatform string
+	// LockRewrite how the ImagesLock is written when the bundle is pulled from the repository it was relocated to,
+	// defaults to bundle.LockRewriteRelocated
+	LockRewrite bundle.LockRewrite
+	// Paths when provided, only these files and directories of the image are pulled, relative to its root (example: config/charts).
+	// The layers compressed with zstd:chunked are read partially, using their table of contents, when the registry supports it
+	Paths []string
 }
 
 // ImagesLockInfo Information about the ImagesLock file
 type ImagesLockInfo struct {
 	Path    string `json:"path"`
 	Updated bool   `json:"updated"`
+	// OriginalPath location of the ImagesLock, as it was pushed, when it was kept next to the updated one
+	OriginalPath string `json:"originalPath,omitempty"`
 }
 
 // BundleInfo Information related to the specific bundle
@@ -45,33 +56,42 @@ type BundleInfo struct {
 }
 
 // buildBundleInfoFromBundle Given a Bundle struct it can generate the BundleInfo associated to the nested bundles
-// the updated key is used to indicate if the ImagesLock for the bundle was updated or not
-func buildBundleInfoFromBundle(b *bundle.Bundle, updated bool) []BundleInfo {
+// the relocated key is used to indicate if the bundle was pulled from the repository it was relocated to
+func buildBundleInfoFromBundle(b *bundle.Bundle, relocated bool, rewrite bundle.LockRewrite) []BundleInfo {
 	var nestedBundles []BundleInfo
 
 	for _, nBundle := range b.NestedBundles() {
-		nestedBundles = append(nestedBundles, newBundleInfo(nBundle, updated))
+		nestedBundles = append(nestedBundles, newBundleInfo(nBundle, relocated, rewrite))
 	}
 	return nestedBundles
 }
 
 // newBundleInfo create a BundleInfo struct from bundle information
-// the updated key is used to indicate if the ImagesLock for the bundle was updated or not
-func newBundleInfo(node bundle.GraphNode, updated bool) BundleInfo {
+// the relocated key is used to indicate if the bundle was pulled from the repository it was relocated to
+func newBundleInfo(node bundle.GraphNode, relocated bool, rewrite bundle.LockRewrite) BundleInfo {
 	bInfo := BundleInfo{
-		ImageRef: node.ImageRef,
-		ImagesLock: &ImagesLockInfo{
-			Path:    filepath.Join(node.Path, bundle.ImgpkgDir, bundle.ImagesLockFile),
-			Updated: updated,
-		},
+		ImageRef:      node.ImageRef,
+		ImagesLock:    newImagesLockInfo(node.Path, relocated, rewrite),
 		NestedBundles: nil,
 	}
 	for _, nestedBundle := range node.NestedBundles {
-		bInfo.NestedBundles = append(bInfo.NestedBundles, newBundleInfo(nestedBundle, updated))
+		bInfo.NestedBundles = append(bInfo.NestedBundles, newBundleInfo(nestedBundle, relocated, rewrite))
 	}
 	return bInfo
 }
 
+// newImagesLockInfo describes the ImagesLock files written to the bundle folder, given how they were rewritten
+func newImagesLockInfo(bundlePath string, relocated bool, rewrite bundle.LockRewrite) *ImagesLockInfo {
+	info := &ImagesLockInfo{
+		Path:    filepath.Join(bundlePath, bundle.ImgpkgDir, bundle.ImagesLockFile),
+		Updated: relocated && rewrite != bundle.LockRewriteOriginal,
+	}
+	if relocated && rewrite == bundle.LockRewriteBoth {
+		info.OriginalPath = filepath.Join(bundlePath, bundle.ImgpkgDir, bundle.OriginalImagesLockFile)
+	}
+	return info
+}
+
 // Status Report from the Pull command
 // Deprecated: in favor of PullStatus, the name makes more sense for API.
 type Status PullStatus
@@ -81,6 +101,9 @@ type PullStatus struct {
 	BundleInfo
 	IsBundle  bool `json:"-"`
 	Cacheable bool `json:"cacheable"`
+	// Relocations original images of the ImagesLocks and where they were found, when the bundle was pulled
+	// from the repository it was relocated to
+	Relocations []bundle.ImageRelocation `json:"relocations,omitempty"`
 }
 
 // Pull Download the contents of the image referenced by imageRef to the folder outputPath
@@ -94,6 +117,34 @@ func Pull(imageRef string, outputPath string, pullOptions PullOpts, registryOpts
 
 // PullWithRegistry Download the contents of the image referenced by imageRef to the folder outputPath
 func PullWithRegistry(imageRef string, outputPath string, pullOptions PullOpts, reg registry.Registry) (PullStatus, error) {
+	if pullOptions.IndexChildPlatform != "" {
+		if pullOptions.IsBundle {
+			return PullStatus{}, fmt.Errorf("Selecting the platform of an image index is only possible when pulling images")
+		}
+
+		childRef, err := plainimage.ResolveIndexChild(imageRef, pullOptions.IndexChildPlatform, reg)
+		if err != nil {
+			return PullStatus{}, err
+		}
+		imageRef = childRef
+	}
+
+	if len(pullOptions.Paths) > 0 {
+		if pullOptions.IsBundle {
+			return PullStatus{}, fmt.Errorf("Selecting the paths to pull is only possible when pulling images")
+		}
+
+		var paths []string
+		for _, value := range pullOptions.Paths {
+			imagePath, err := ctlimg.ParseImagePath(value)
+			if err != nil {
+				return PullStatus{}, err
+			}
+			paths = append(paths, imagePath)
+		}
+		pullOptions.Paths = paths
+	}
+
 	imagesLockReader := bundle.NewImagesLockReader()
 	bundleToPull := bundle.NewBundleFromRef(imageRef, reg, imagesLockReader, bundle.NewRegistryFetcher(reg, imagesLockReader))
 	isBundle, err := bundleToPull.IsBundle()
@@ -156,7 +207,12 @@ func PullRecursiveWithRegistry(imageRef string, outputPath string, pullOptions P
 // pullBundle Downloads the contents of the Bundle Image referenced by imageRef to the folder outputPath.
 // This functions should error out when imageRef does not point to a Bundle
 func pullBundle(imgRef string, bundleToPull *bundle.Bundle, outputPath string, pullOptions PullOpts, pullNestedBundles bool) (PullStatus, error) {
-	isRootBundleRelocated, err := bundleToPull.Pull(outputPath, pullOptions.Logger, pullNestedBundles)
+	rewrite := pullOptions.LockRewrite
+	if rewrite == "" {
+		rewrite = bundle.LockRewriteRelocated
+	}
+
+	isRootBundleRelocated, err := bundleToPull.PullWithLockRewrite(outputPath, pullOptions.Logger, pullNestedBundles, rewrite)
 	if err != nil {
 		return PullStatus{}, err
 	}
@@ -166,21 +222,33 @@ func pullBundle(imgRef string, bundleToPull *bundle.Bundle, outputPath string, p
 		return PullStatus{}, err
 	}
 
-	bInfo := buildBundleInfoFromBundle(bundleToPull, isRootBundleRelocated)
+	bInfo := buildBundleInfoFromBundle(bundleToPull, isRootBundleRelocated, rewrite)
 	return PullStatus{
 		BundleInfo: BundleInfo{
-			ImageRef: bundleToPull.DigestRef(),
-			ImagesLock: &ImagesLockInfo{
-				Path:    filepath.Join(outputPath, bundle.ImgpkgDir, bundle.ImagesLockFile),
-				Updated: isRootBundleRelocated,
-			},
+			ImageRef:      bundleToPull.DigestRef(),
+			ImagesLock:    newImagesLockInfo(outputPath, isRootBundleRelocated, rewrite),
 			NestedBundles: bInfo,
 		},
-		Cacheable: isCacheable,
-		IsBundle:  true,
+		Cacheable:   isCacheable,
+		IsBundle:    true,
+		Relocations: uniqueRelocations(bundleToPull.PulledRelocations()),
 	}, nil
 }
 
+// uniqueRelocations removes the images that are present in the ImagesLocks of more than one bundle
+func uniqueRelocations(relocations []bundle.ImageRelocation) []bundle.ImageRelocation {
+	var result []bundle.ImageRelocation
+	seen := map[bundle.ImageRelocation]bool{}
+	for _, relocation := range relocations {
+		if seen[relocation] {
+			continue
+		}
+		seen[relocation] = true
+		result = append(result, relocation)
+	}
+	return result
+}
+
 func pullImage(imageRef string, outputPath string, pullOptions PullOpts, reg registry.Registry) (PullStatus, error) {
 	plainImg := plainimage.NewPlainImage(imageRef, reg)
 	isImage, err := plainImg.IsImage()
@@ -191,7 +259,9 @@ func pullImage(imageRef string, outputPath string, pullOptions PullOpts, reg reg
 		return PullStatus{}, fmt.Errorf("Unable to pull non-images, such as image indexes. (hint: provide a specific digest to the image instead)")
 	}
 
-	err = plainImg.Pull(outputPath, pullOptions.Logger)
+	// Layers compressed with zstd:chunked are only read partially when the registry can read part of a blob
+	blobs, _ := reg.(registry.BlobRangeReader)
+	err = plainImg.PullWithPaths(outputPath, pullOptions.Logger, pullOptions.Paths, blobs)
 	if err != nil {
 		return PullStatus{}, err
 	}
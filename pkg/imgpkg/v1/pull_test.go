@@ -4,11 +4,23 @@
 package v1_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/image"
 	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
 	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
 	"carvel.dev/imgpkg/pkg/imgpkg/registry"
@@ -16,6 +28,12 @@ import (
 	"carvel.dev/imgpkg/test/helpers"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -192,12 +210,59 @@ func TestPullBundle(t *testing.T) {
 			},
 			Cacheable: true,
 			IsBundle:  true,
+			Relocations: []bundle.ImageRelocation{
+				{Original: img1.RefDigest, Relocated: colImg1.RefDigest},
+				{Original: img2.RefDigest, Relocated: colImg2.RefDigest},
+			},
 		}, status)
 
 		// Ensures that pulled ImagesLock file is updated correctly
 		assertImagesLock(t, outputFolder, []string{colImg1.RefDigest, colImg2.RefDigest})
 	})
 
+	t.Run("when keeping the original ImagesLock of a bundle that was copied, it does not update ImagesLock file", func(t *testing.T) {
+		outputFolder := t.TempDir()
+
+		opts := v1.PullOpts{
+			Logger:      uiLogger,
+			IsBundle:    true,
+			LockRewrite: bundle.LockRewriteOriginal,
+		}
+		status, err := v1.Pull(collocatedBundleRef, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+		assert.Equal(t, &v1.ImagesLockInfo{Path: filepath.Join(outputFolder, ".imgpkg", "images.yml")}, status.ImagesLock)
+		assert.Equal(t, []bundle.ImageRelocation{
+			{Original: img1.RefDigest, Relocated: colImg1.RefDigest},
+			{Original: img2.RefDigest, Relocated: colImg2.RefDigest},
+		}, status.Relocations)
+
+		assertImagesLock(t, outputFolder, []string{img1.RefDigest, img2.RefDigest})
+	})
+
+	t.Run("when writing both ImagesLocks of a bundle that was copied, it updates ImagesLock file and keeps the original one", func(t *testing.T) {
+		outputFolder := t.TempDir()
+
+		opts := v1.PullOpts{
+			Logger:      uiLogger,
+			IsBundle:    true,
+			LockRewrite: bundle.LockRewriteBoth,
+		}
+		status, err := v1.Pull(collocatedBundleRef, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+		assert.Equal(t, &v1.ImagesLockInfo{
+			Path:         filepath.Join(outputFolder, ".imgpkg", "images.yml"),
+			Updated:      true,
+			OriginalPath: filepath.Join(outputFolder, ".imgpkg", "images-original.yml"),
+		}, status.ImagesLock)
+
+		assertImagesLock(t, outputFolder, []string{colImg1.RefDigest, colImg2.RefDigest})
+		originalImagesLock, err := lockconfig.NewImagesLockFromPath(status.ImagesLock.OriginalPath)
+		require.NoError(t, err)
+		require.Len(t, originalImagesLock.Images, 2)
+		assert.Equal(t, img1.RefDigest, originalImagesLock.Images[0].Image)
+		assert.Equal(t, img2.RefDigest, originalImagesLock.Images[1].Image)
+	})
+
 	t.Run("succeeds when pulling the bundle OCI image of collocated bundle does not update ImagesLock", func(t *testing.T) {
 		outputFolder := t.TempDir()
 
@@ -390,6 +455,11 @@ func TestPullBundleRecursively(t *testing.T) {
 			},
 			Cacheable: true,
 			IsBundle:  true,
+			Relocations: []bundle.ImageRelocation{
+				{Original: img1.RefDigest, Relocated: colImg1.RefDigest},
+				{Original: simpleBundle.RefDigest, Relocated: colSimpleBundle.RefDigest},
+				{Original: img2.RefDigest, Relocated: colImg2.RefDigest},
+			},
 		}, status)
 
 		// Ensures that pulled ImagesLock file was changed
@@ -451,3 +521,247 @@ func createBundle(fakeRegistry *helpers.FakeTestRegistryBuilder, bundleName stri
 func createBundleWithImages(fakeRegistry *helpers.FakeTestRegistryBuilder, bundleName string, refs []string) string {
 	return createBundle(fakeRegistry, bundleName, refs).RefDigest
 }
+
+func TestPullImageFromIndexChildPlatform(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	imageIndex := fakeRegistry.WithImageIndexForPlatforms("some/index", "linux/amd64", "linux/arm64", "linux/arm/v6", "linux/arm/v7")
+	randomImg := fakeRegistry.WithRandomImage("some/image")
+	fakeRegistry.Build()
+
+	opts := v1.PullOpts{
+		Logger:             util.NewNoopLevelLogger(),
+		IndexChildPlatform: "linux/arm64",
+	}
+
+	t.Run("when the platform exists in the index, it pulls the child image", func(t *testing.T) {
+		idxManifest, err := imageIndex.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		var childDigest string
+		for _, child := range idxManifest.Manifests {
+			if child.Platform.String() == "linux/arm64" {
+				childDigest = child.Digest.String()
+			}
+		}
+
+		status, err := v1.Pull(imageIndex.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.NoError(t, err)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("some/index")+"@"+childDigest, status.ImageRef)
+	})
+
+	t.Run("when the platform does not exist in the index, it fails listing the available platforms", func(t *testing.T) {
+		opts := opts
+		opts.IndexChildPlatform = "windows/amd64"
+
+		_, err := v1.Pull(imageIndex.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "Unable to find platform windows/amd64")
+		require.ErrorContains(t, err, "available platforms: linux/amd64, linux/arm64, linux/arm/v6, linux/arm/v7")
+	})
+
+	t.Run("when the platform matches multiple children, it fails", func(t *testing.T) {
+		opts := opts
+		opts.IndexChildPlatform = "linux/arm"
+
+		_, err := v1.Pull(imageIndex.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "Expected platform linux/arm to match a single manifest")
+	})
+
+	t.Run("when the image is not an index, it fails", func(t *testing.T) {
+		_, err := v1.Pull(randomImg.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "to be an image index to select the platform linux/arm64")
+	})
+
+	t.Run("when pulling a bundle, it fails", func(t *testing.T) {
+		opts := opts
+		opts.IsBundle = true
+
+		_, err := v1.Pull(imageIndex.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "only possible when pulling images")
+	})
+}
+
+func TestPullImageWithPaths(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	layer, annotations := zstdChunkedLayer(t, map[string]string{
+		"README.md":               "readme",
+		"config/charts/chart.yml": strings.Repeat("chart: some-chart\n", 100),
+		"config/values.yml":       "values: true\n",
+	})
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: layer, Annotations: annotations})
+	require.NoError(t, err)
+	img = mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), types.OCIConfigJSON)
+	chunkedImg := fakeRegistry.WithImage("some/chunked-image", img)
+	randomBundle := createBundleWithImages(fakeRegistry, "some/bundle", nil)
+
+	layerDigest, err := layer.Digest()
+	require.NoError(t, err)
+	lock := &sync.Mutex{}
+	ignoreRanges := false
+	var layerRanges []string
+	fakeRegistry.WithHandlerFunc(func(_ http.ResponseWriter, request *http.Request) bool {
+		if request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/blobs/"+layerDigest.String()) {
+			lock.Lock()
+			defer lock.Unlock()
+			if ignoreRanges {
+				request.Header.Del("Range")
+			}
+			layerRanges = append(layerRanges, request.Header.Get("Range"))
+		}
+		return false
+	})
+	fakeRegistry.Build()
+
+	opts := v1.PullOpts{
+		Logger: util.NewNoopLevelLogger(),
+		Paths:  []string{"./config/charts"},
+	}
+
+	t.Run("when the layer is zstd:chunked, it fetches only the table of contents and the contents of the selected files", func(t *testing.T) {
+		layerRanges = nil
+		outputFolder := t.TempDir()
+
+		_, err := v1.Pull(chunkedImg.RefDigest, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(filepath.Join(outputFolder, "config", "charts", "chart.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("chart: some-chart\n", 100), string(contents))
+		assert.NoFileExists(t, filepath.Join(outputFolder, "config", "values.yml"))
+		assert.NoFileExists(t, filepath.Join(outputFolder, "README.md"))
+
+		require.Len(t, layerRanges, 2)
+		for _, layerRange := range layerRanges {
+			assert.NotEmpty(t, layerRange)
+		}
+	})
+
+	t.Run("when the registry does not support range requests, it downloads the whole layer and extracts the selected files", func(t *testing.T) {
+		layerRanges = nil
+		ignoreRanges = true
+		defer func() { ignoreRanges = false }()
+		outputFolder := t.TempDir()
+
+		_, err := v1.Pull(chunkedImg.RefDigest, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+
+		assert.FileExists(t, filepath.Join(outputFolder, "config", "charts", "chart.yml"))
+		assert.NoFileExists(t, filepath.Join(outputFolder, "config", "values.yml"))
+		assert.NoFileExists(t, filepath.Join(outputFolder, "README.md"))
+		assert.Equal(t, []string{"", ""}, layerRanges)
+	})
+
+	t.Run("when the path is not in the image, it fails", func(t *testing.T) {
+		opts := opts
+		opts.Paths = []string{"config/missing.yml"}
+
+		_, err := v1.Pull(chunkedImg.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "Expected path 'config/missing.yml' to be in the image")
+	})
+
+	t.Run("when the path is outside of the image, it fails", func(t *testing.T) {
+		opts := opts
+		opts.Paths = []string{"../config"}
+
+		_, err := v1.Pull(chunkedImg.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.EqualError(t, err, "Expected path '../config' to be relative to the root of the image, and inside it")
+	})
+
+	t.Run("when pulling a bundle, it fails", func(t *testing.T) {
+		opts := opts
+		opts.IsBundle = true
+
+		_, err := v1.Pull(randomBundle, t.TempDir(), opts, registry.Opts{})
+		require.ErrorContains(t, err, "only possible when pulling images")
+	})
+}
+
+// zstdChunkedLayer Creates a layer compressed with zstd:chunked, where the contents of each file are in their own
+// zstd frame, and the table of contents, in a skippable frame at the end, has their position in the blob
+func zstdChunkedLayer(t *testing.T, files map[string]string) (regv1.Layer, map[string]string) {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type tocEntry struct {
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Mode      int64  `json:"mode"`
+		Size      int64  `json:"size,omitempty"`
+		Digest    string `json:"digest,omitempty"`
+		Offset    int64  `json:"offset,omitempty"`
+		EndOffset int64  `json:"endOffset,omitempty"`
+	}
+	var entries []tocEntry
+	// offsets in the tar stream where the contents of each file start and end, so that they are compressed in their own frame
+	var boundaries []int
+
+	tarBuf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(tarBuf)
+	createdDirs := map[string]bool{}
+	for _, name := range names {
+		var dirs []string
+		for dir := path.Dir(name); dir != "." && !createdDirs[dir]; dir = path.Dir(dir) {
+			dirs = append([]string{dir}, dirs...)
+			createdDirs[dir] = true
+		}
+		for _, dir := range dirs {
+			require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755}))
+			entries = append(entries, tocEntry{Type: "dir", Name: dir, Mode: 0755})
+		}
+
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[name]))}))
+		boundaries = append(boundaries, tarBuf.Len())
+		_, err := tarWriter.Write([]byte(files[name]))
+		require.NoError(t, err)
+		boundaries = append(boundaries, tarBuf.Len())
+		entries = append(entries, tocEntry{Type: "reg", Name: name, Mode: 0644, Size: int64(len(files[name])), Digest: digest.FromString(files[name]).String()})
+	}
+	require.NoError(t, tarWriter.Close())
+
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer encoder.Close()
+
+	blob := &bytes.Buffer{}
+	start := 0
+	fileIdx := 0
+	for idx, end := range append(boundaries, tarBuf.Len()) {
+		frameStart := int64(blob.Len())
+		blob.Write(encoder.EncodeAll(tarBuf.Bytes()[start:end], nil))
+		if idx%2 == 1 {
+			for entries[fileIdx].Type != "reg" {
+				fileIdx++
+			}
+			entries[fileIdx].Offset = frameStart
+			entries[fileIdx].EndOffset = int64(blob.Len())
+			fileIdx++
+		}
+		start = end
+	}
+
+	tocJSON, err := json.Marshal(map[string]interface{}{"version": 1, "entries": entries})
+	require.NoError(t, err)
+	compressedTOC := encoder.EncodeAll(tocJSON, nil)
+	skippableFrameHeader := make([]byte, 8)
+	binary.LittleEndian.PutUint32(skippableFrameHeader, 0x184D2A50)
+	binary.LittleEndian.PutUint32(skippableFrameHeader[4:], uint32(len(compressedTOC)))
+	blob.Write(skippableFrameHeader)
+	tocOffset := blob.Len()
+	blob.Write(compressedTOC)
+
+	blobBytes := blob.Bytes()
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(blobBytes)), nil
+	}, tarball.WithMediaType(types.OCILayerZStd))
+	require.NoError(t, err)
+
+	return layer, map[string]string{
+		image.ZstdChunkedManifestPositionAnnotation: fmt.Sprintf("%d:%d:%d:1", tocOffset, len(compressedTOC), len(tocJSON)),
+		image.ZstdChunkedManifestChecksumAnnotation: digest.FromBytes(compressedTOC).String(),
+	}
+}
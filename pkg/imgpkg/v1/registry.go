@@ -27,11 +27,24 @@ func OptsFromEnv(base registry.Opts, readEnv func(string) (string, bool)) regist
 	if anon, _ := readEnv("IMGPKG_ANON"); anon == "true" {
 		opts.Anon = true
 	}
+	if fips, _ := readEnv("IMGPKG_FIPS"); fips == "true" {
+		opts.FIPS = true
+	}
+	if offline, _ := readEnv("IMGPKG_OFFLINE"); offline == "true" {
+		opts.Offline = true
+	}
 	iaasAuth, found := readEnv("IMGPKG_ENABLE_IAAS_AUTH")
 	if found && strings.ToLower(iaasAuth) == "true" {
 		opts.EnableIaasAuthProviders = true
 	}
 
+	if proxyHelper, _ := readEnv("IMGPKG_PROXY_HELPER"); opts.ProxySelector == nil && proxyHelper != "" {
+		opts.ProxySelector = registry.NewCommandProxySelector(proxyHelper)
+	}
+	if proxyAuthHelper, _ := readEnv("IMGPKG_PROXY_AUTH_HELPER"); opts.ProxyAuthenticator == nil && proxyAuthHelper != "" {
+		opts.ProxyAuthenticator = registry.NewCommandProxyAuthenticator(proxyAuthHelper)
+	}
+
 	keychains, found := readEnv("IMGPKG_ACTIVE_KEYCHAINS")
 	if found {
 		if len(keychains) > 0 {
@@ -0,0 +1,146 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// TransferEstimate What copying to a repository would transfer, given what is already present in it.
+// Blobs that the registry could mount from other repositories are counted as transferred
+type TransferEstimate struct {
+	Repository string `json:"repository"`
+
+	// Manifests number of manifests the copy would push
+	Manifests int `json:"manifests"`
+	// ManifestsPresent number of manifests already present in the repository
+	ManifestsPresent int `json:"manifestsPresent"`
+	// Blobs number of configs and layers the copy would upload
+	Blobs int `json:"blobs"`
+	// BlobsPresent number of configs and layers already present in the repository
+	BlobsPresent int `json:"blobsPresent"`
+
+	// Bytes compressed size of the manifests and blobs the copy would transfer
+	Bytes int64 `json:"bytes"`
+	// BytesPresent compressed size of the manifests and blobs already present in the repository
+	BytesPresent int64 `json:"bytesPresent"`
+}
+
+// EstimateTransfer Checks which of the manifests and blobs that copying origin to the repository needs
+// are already present in it, without writing anything to the repository
+func EstimateTransfer(origin CopyOrigin, repository string, opts CopyOpts, reg registry.Registry) (TransferEstimate, error) {
+	opts.Logger.Tracef("EstimateTransfer\n")
+
+	importRepo, err := regname.NewRepository(repository)
+	if err != nil {
+		return TransferEstimate{}, fmt.Errorf("Building import repository ref: %s", err)
+	}
+
+	imgOrIndexes, err := readImagesToCopy(origin, opts, reg)
+	if err != nil {
+		return TransferEstimate{}, err
+	}
+
+	reporter := newCopyReporter(opts)
+	for _, item := range imgOrIndexes {
+		_, err := reporter.reportImageOrIndex(item)
+		if err != nil {
+			return TransferEstimate{}, fmt.Errorf("Reporting '%s': %s", item.Ref(), err)
+		}
+	}
+
+	manifestsPresent, err := presentInRepository(importRepo, reporter.manifests, opts.Concurrency, func(ref regname.Digest) (bool, error) {
+		return manifestExists(reg, ref)
+	})
+	if err != nil {
+		return TransferEstimate{}, err
+	}
+	blobsPresent, err := presentInRepository(importRepo, reporter.blobs, opts.Concurrency, func(ref regname.Digest) (bool, error) {
+		return registry.BlobExists(reg, ref)
+	})
+	if err != nil {
+		return TransferEstimate{}, err
+	}
+
+	estimate := TransferEstimate{Repository: importRepo.Name()}
+	for digest, size := range reporter.manifests {
+		if manifestsPresent[digest] {
+			estimate.ManifestsPresent++
+			estimate.BytesPresent += size
+			continue
+		}
+		estimate.Manifests++
+		estimate.Bytes += size
+	}
+	for digest, size := range reporter.blobs {
+		if blobsPresent[digest] {
+			estimate.BlobsPresent++
+			estimate.BytesPresent += size
+			continue
+		}
+		estimate.Blobs++
+		estimate.Bytes += size
+	}
+
+	return estimate, nil
+}
+
+// presentInRepository Checks in parallel which of the digests exist in the repository
+func presentInRepository(repo regname.Repository, digests map[regv1.Hash]int64, concurrency int, exists func(regname.Digest) (bool, error)) (map[regv1.Hash]bool, error) {
+	type result struct {
+		digest regv1.Hash
+		exists bool
+		err    error
+	}
+
+	throttle := util.NewThrottle(concurrency)
+	resultsCh := make(chan result, len(digests))
+	for digest := range digests {
+		digest := digest // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			ref := repo.Digest(digest.String())
+			found, err := exists(ref)
+			if err != nil {
+				err = fmt.Errorf("Checking existence of '%s': %s", ref.Name(), err)
+			}
+			resultsCh <- result{digest: digest, exists: found, err: err}
+		}()
+	}
+
+	present := map[regv1.Hash]bool{}
+	for range digests {
+		res := <-resultsCh
+		if res.err != nil {
+			return nil, res.err
+		}
+		present[res.digest] = res.exists
+	}
+	return present, nil
+}
+
+// manifestExists Checks if the manifest is present, a repository that does not exist yet has no manifests
+func manifestExists(reg registry.Registry, ref regname.Digest) (bool, error) {
+	_, err := reg.Digest(ref)
+	if err == nil {
+		return true, nil
+	}
+
+	var tErr *transport.Error
+	if errors.As(err, &tErr) && tErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"net/http"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTransfer(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistryWithRepoSeparation(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	image := fakeRegistry.WithRandomImage("library/image")
+	bundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image.RefDigest},
+		})
+
+	_, opts, reg := testSetup(fakeRegistry, "", "", "", "")
+	origin := v1.CopyOrigin{BundleRef: bundle.RefDigest}
+
+	writes := 0
+	fakeRegistry.WithCustomHandler(func(_ http.ResponseWriter, request *http.Request) bool {
+		if request.Method == http.MethodPut || request.Method == http.MethodPost {
+			writes++
+		}
+		return false
+	})
+
+	report, err := v1.CopyDryRun(origin, opts, reg)
+	require.NoError(t, err)
+
+	t.Run("when the repository is empty, it reports that everything would be transferred", func(t *testing.T) {
+		writes = 0
+		estimate, err := v1.EstimateTransfer(origin, fakeRegistry.ReferenceOnTestServer("library/empty-repo"), opts, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, estimate.ManifestsPresent)
+		assert.Equal(t, 0, estimate.BlobsPresent)
+		assert.Equal(t, int64(0), estimate.BytesPresent)
+		assert.Equal(t, 2, estimate.Manifests)
+		assert.Equal(t, report.TotalSize, estimate.Bytes)
+		assert.Equal(t, 0, writes)
+	})
+
+	t.Run("when an image is already present in the repository, it reports only the missing manifests and blobs", func(t *testing.T) {
+		destination := fakeRegistry.ReferenceOnTestServer("library/copied-repo")
+		_, err := v1.CopyToRepository(v1.CopyOrigin{ImageRef: image.RefDigest}, destination, opts, reg)
+		require.NoError(t, err)
+
+		manifest, err := image.Image.Manifest()
+		require.NoError(t, err)
+		rawManifest, err := image.Image.RawManifest()
+		require.NoError(t, err)
+		imageSize := int64(len(rawManifest)) + manifest.Config.Size
+		for _, layer := range manifest.Layers {
+			imageSize += layer.Size
+		}
+
+		writes = 0
+		estimate, err := v1.EstimateTransfer(origin, destination, opts, reg)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, estimate.Manifests)
+		assert.Equal(t, 1, estimate.ManifestsPresent)
+		assert.Equal(t, 1+len(manifest.Layers), estimate.BlobsPresent)
+		assert.Equal(t, imageSize, estimate.BytesPresent)
+		assert.Equal(t, report.TotalSize-imageSize, estimate.Bytes)
+		assert.Equal(t, 0, writes)
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/bundle"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	"carvel.dev/imgpkg/pkg/imgpkg/plainimage"
+	"carvel.dev/imgpkg/pkg/imgpkg/registry"
+	"sigs.k8s.io/yaml"
+)
+
+// WarmOrigin Bundle, or lock file, whose images are pre-pulled onto the nodes of a cluster
+type WarmOrigin struct {
+	BundleRef    string
+	LockfilePath string
+}
+
+// WarmOpts Options of the pre-pull of the images
+type WarmOpts struct {
+	Logger      Logger
+	Concurrency int
+}
+
+// WarmImages Returns the images that run on a cluster when origin is deployed, sorted: the images of the bundle and of
+// its nested bundles, from the location where the bundle was relocated, or the images of the ImagesLock.
+// Bundles themselves are not included since they are never run
+func WarmImages(origin WarmOrigin, opts WarmOpts, reg registry.Registry) ([]string, error) {
+	bundleRef := origin.BundleRef
+	if origin.LockfilePath != "" {
+		bundleLock, imagesLock, err := lockconfig.NewLockFromPath(origin.LockfilePath)
+		if err != nil {
+			return nil, err
+		}
+		if imagesLock != nil {
+			return warmImagesFromImagesLock(*imagesLock, reg)
+		}
+		bundleRef = bundleLock.Bundle.Image
+	}
+
+	lockReader := bundle.NewImagesLockReader()
+	rootBundle := bundle.NewBundleFromRef(bundleRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+	isBundle, err := rootBundle.IsBundle()
+	if err != nil {
+		return nil, err
+	}
+	if !isBundle {
+		return nil, fmt.Errorf("Expected bundle image but found plain image (hint: use an ImagesLock with --lock for images)")
+	}
+
+	_, imageRefs, err := rootBundle.AllImagesLockRefs(opts.Concurrency, opts.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("Reading Images from Bundle: %s", err)
+	}
+
+	found := map[string]bool{}
+	var images []string
+	for _, img := range imageRefs.ImageRefs() {
+		if img.IsBundle != nil && *img.IsBundle {
+			continue
+		}
+		location := img.PrimaryLocation()
+		if !found[location] {
+			found[location] = true
+			images = append(images, location)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func warmImagesFromImagesLock(imagesLock lockconfig.ImagesLock, reg registry.Registry) ([]string, error) {
+	found := map[string]bool{}
+	var images []string
+	for _, img := range imagesLock.Images {
+		plainImg := plainimage.NewPlainImage(img.Image, reg)
+		isBundle, err := bundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return nil, err
+		}
+		if isBundle {
+			return nil, fmt.Errorf("Expected ImagesLock to only contain images, but '%s' is a bundle (hint: use -b to pre-pull the images of a bundle)", img.Image)
+		}
+		if !found[img.Image] {
+			found[img.Image] = true
+			images = append(images, img.Image)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// WarmDaemonSetOpts Configuration of the DaemonSet that pre-pulls images
+type WarmDaemonSetOpts struct {
+	Name      string
+	Namespace string
+	// HelperImage image with a statically linked busybox, whose binary is used as the command of the pre-pulled images
+	// so that they exit immediately, and that keeps the pods running once all the images are pulled
+	HelperImage      string
+	ImagePullSecrets []string
+}
+
+// WarmDaemonSet Returns the manifest of a DaemonSet that pulls images onto every node of a cluster, tainted ones included.
+// Each image is pulled by an init container that runs a copy of busybox true instead of the image entrypoint, so the
+// images are pulled without being started. The pods of the DaemonSet become ready once all the images are pulled on their node
+func WarmDaemonSet(images []string, opts WarmDaemonSetOpts) ([]byte, error) {
+	if opts.Name == "" || opts.Namespace == "" || opts.HelperImage == "" {
+		return nil, fmt.Errorf("Expected DaemonSet name, namespace and helper image to be provided")
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("Expected at least one image to pre-pull")
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "imgpkg-warm",
+		"app.kubernetes.io/instance":   opts.Name,
+		"app.kubernetes.io/managed-by": "imgpkg",
+	}
+	resources := warmResources{
+		Requests: map[string]string{"cpu": "10m", "memory": "16Mi"},
+		Limits:   map[string]string{"cpu": "100m", "memory": "32Mi"},
+	}
+	toolsMount := []warmVolumeMount{{Name: "imgpkg-warm", MountPath: "/imgpkg-warm"}}
+
+	podSpec := warmPodSpec{
+		Tolerations: []map[string]string{{"operator": "Exists"}},
+		Volumes:     []warmVolume{{Name: "imgpkg-warm", EmptyDir: map[string]string{}}},
+		InitContainers: []warmContainer{{
+			Name:            "imgpkg-warm-tools",
+			Image:           opts.HelperImage,
+			ImagePullPolicy: "IfNotPresent",
+			Command:         []string{"cp", "/bin/busybox", "/imgpkg-warm/true"},
+			Resources:       resources,
+			VolumeMounts:    toolsMount,
+		}},
+		Containers: []warmContainer{{
+			Name:            "imgpkg-warm-done",
+			Image:           opts.HelperImage,
+			ImagePullPolicy: "IfNotPresent",
+			Command:         []string{"sleep", "2147483647"},
+			Resources:       resources,
+		}},
+	}
+	for _, secret := range opts.ImagePullSecrets {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, map[string]string{"name": secret})
+	}
+	for idx, image := range images {
+		podSpec.InitContainers = append(podSpec.InitContainers, warmContainer{
+			Name:            fmt.Sprintf("image-%d", idx),
+			Image:           image,
+			ImagePullPolicy: "IfNotPresent",
+			Command:         []string{"/imgpkg-warm/true"},
+			Resources:       resources,
+			VolumeMounts:    toolsMount,
+		})
+	}
+
+	daemonSet := warmDaemonSet{
+		APIVersion: "apps/v1",
+		Kind:       "DaemonSet",
+		Metadata:   warmObjectMeta{Name: opts.Name, Namespace: opts.Namespace, Labels: labels},
+		Spec: warmDaemonSetSpec{
+			Selector: warmSelector{MatchLabels: labels},
+			Template: warmPodTemplate{Metadata: warmObjectMeta{Labels: labels}, Spec: podSpec},
+		},
+	}
+	return yaml.Marshal(daemonSet)
+}
+
+type warmDaemonSet struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   warmObjectMeta    `json:"metadata"`
+	Spec       warmDaemonSetSpec `json:"spec"`
+}
+
+type warmObjectMeta struct {
+	Name      string            `json:"name,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type warmDaemonSetSpec struct {
+	Selector warmSelector    `json:"selector"`
+	Template warmPodTemplate `json:"template"`
+}
+
+type warmSelector struct {
+	MatchLabels map[string]string `json:"matchLabels"`
+}
+
+type warmPodTemplate struct {
+	Metadata warmObjectMeta `json:"metadata"`
+	Spec     warmPodSpec    `json:"spec"`
+}
+
+type warmPodSpec struct {
+	ImagePullSecrets []map[string]string `json:"imagePullSecrets,omitempty"`
+	Tolerations      []map[string]string `json:"tolerations,omitempty"`
+	Volumes          []warmVolume        `json:"volumes,omitempty"`
+	InitContainers   []warmContainer     `json:"initContainers,omitempty"`
+	Containers       []warmContainer     `json:"containers"`
+}
+
+type warmVolume struct {
+	Name     string            `json:"name"`
+	EmptyDir map[string]string `json:"emptyDir"`
+}
+
+type warmContainer struct {
+	Name            string            `json:"name"`
+	Image           string            `json:"image"`
+	ImagePullPolicy string            `json:"imagePullPolicy"`
+	Command         []string          `json:"command"`
+	Resources       warmResources     `json:"resources"`
+	VolumeMounts    []warmVolumeMount `json:"volumeMounts,omitempty"`
+}
+
+type warmResources struct {
+	Requests map[string]string `json:"requests"`
+	Limits   map[string]string `json:"limits"`
+}
+
+type warmVolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+}
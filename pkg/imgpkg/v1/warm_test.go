@@ -0,0 +1,135 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"carvel.dev/imgpkg/pkg/imgpkg/internal/util"
+	"carvel.dev/imgpkg/pkg/imgpkg/lockconfig"
+	v1 "carvel.dev/imgpkg/pkg/imgpkg/v1"
+	"carvel.dev/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWarmImages(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	img1 := fakeRegistry.WithRandomImage("some/image-1")
+	img2 := fakeRegistry.WithRandomImage("some/image-2")
+	nestedBundle := fakeRegistry.WithRandomBundle("some/nested-bundle").WithImageRefs([]lockconfig.ImageRef{
+		{Image: img2.RefDigest},
+	})
+	rootBundle := fakeRegistry.WithRandomBundle("some/bundle").WithImageRefs([]lockconfig.ImageRef{
+		{Image: img1.RefDigest},
+		{Image: nestedBundle.RefDigest},
+	})
+	reg := fakeRegistry.Build()
+
+	opts := v1.WarmOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 2}
+	expectedImages := []string{img1.RefDigest, img2.RefDigest}
+	sort.Strings(expectedImages)
+
+	t.Run("when a bundle is provided, it returns the images of the bundle and its nested bundles, without the bundles", func(t *testing.T) {
+		images, err := v1.WarmImages(v1.WarmOrigin{BundleRef: rootBundle.RefDigest}, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, expectedImages, images)
+	})
+
+	t.Run("when a BundleLock is provided, it returns the images of the bundle", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "bundle.lock.yml")
+		bundleLock := lockconfig.BundleLock{
+			LockVersion: lockconfig.LockVersion{APIVersion: lockconfig.BundleLockAPIVersion, Kind: lockconfig.BundleLockKind},
+			Bundle:      lockconfig.BundleRef{Image: rootBundle.RefDigest},
+		}
+		require.NoError(t, bundleLock.WriteToPath(lockPath))
+
+		images, err := v1.WarmImages(v1.WarmOrigin{LockfilePath: lockPath}, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, expectedImages, images)
+	})
+
+	t.Run("when an ImagesLock is provided, it returns its images", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		imagesLock := lockconfig.ImagesLock{
+			LockVersion: lockconfig.LockVersion{APIVersion: lockconfig.ImagesLockAPIVersion, Kind: lockconfig.ImagesLockKind},
+			Images:      []lockconfig.ImageRef{{Image: img2.RefDigest}, {Image: img1.RefDigest}, {Image: img2.RefDigest}},
+		}
+		require.NoError(t, imagesLock.WriteToPath(lockPath))
+
+		images, err := v1.WarmImages(v1.WarmOrigin{LockfilePath: lockPath}, opts, reg)
+		require.NoError(t, err)
+		assert.Equal(t, expectedImages, images)
+	})
+
+	t.Run("when the ImagesLock contains a bundle, it fails", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		imagesLock := lockconfig.ImagesLock{
+			LockVersion: lockconfig.LockVersion{APIVersion: lockconfig.ImagesLockAPIVersion, Kind: lockconfig.ImagesLockKind},
+			Images:      []lockconfig.ImageRef{{Image: rootBundle.RefDigest}},
+		}
+		require.NoError(t, imagesLock.WriteToPath(lockPath))
+
+		_, err := v1.WarmImages(v1.WarmOrigin{LockfilePath: lockPath}, opts, reg)
+		require.ErrorContains(t, err, "is a bundle (hint: use -b to pre-pull the images of a bundle)")
+	})
+
+	t.Run("when the bundle is a plain image, it fails", func(t *testing.T) {
+		_, err := v1.WarmImages(v1.WarmOrigin{BundleRef: img1.RefDigest}, opts, reg)
+		require.ErrorContains(t, err, "Expected bundle image but found plain image")
+	})
+}
+
+func TestWarmDaemonSet(t *testing.T) {
+	t.Run("it pulls each image in an init container that runs the copy of busybox true", func(t *testing.T) {
+		manifest, err := v1.WarmDaemonSet([]string{"registry.io/app@sha256:aaa", "registry.io/db@sha256:bbb"}, v1.WarmDaemonSetOpts{
+			Name:             "warm-app",
+			Namespace:        "apps",
+			HelperImage:      "registry.io/busybox:1.36",
+			ImagePullSecrets: []string{"registry-creds"},
+		})
+		require.NoError(t, err)
+
+		var daemonSet struct {
+			Kind     string
+			Metadata struct{ Name, Namespace string }
+			Spec     struct {
+				Template struct {
+					Spec struct {
+						ImagePullSecrets []map[string]string `json:"imagePullSecrets"`
+						InitContainers   []struct {
+							Image   string
+							Command []string
+						} `json:"initContainers"`
+						Containers []struct{ Image string }
+					}
+				}
+			}
+		}
+		require.NoError(t, yaml.Unmarshal(manifest, &daemonSet))
+
+		assert.Equal(t, "DaemonSet", daemonSet.Kind)
+		assert.Equal(t, "warm-app", daemonSet.Metadata.Name)
+		assert.Equal(t, "apps", daemonSet.Metadata.Namespace)
+		podSpec := daemonSet.Spec.Template.Spec
+		assert.Equal(t, []map[string]string{{"name": "registry-creds"}}, podSpec.ImagePullSecrets)
+		require.Len(t, podSpec.InitContainers, 3)
+		assert.Equal(t, "registry.io/busybox:1.36", podSpec.InitContainers[0].Image)
+		assert.Equal(t, "registry.io/app@sha256:aaa", podSpec.InitContainers[1].Image)
+		assert.Equal(t, []string{"/imgpkg-warm/true"}, podSpec.InitContainers[1].Command)
+		assert.Equal(t, "registry.io/db@sha256:bbb", podSpec.InitContainers[2].Image)
+		require.Len(t, podSpec.Containers, 1)
+		assert.Equal(t, "registry.io/busybox:1.36", podSpec.Containers[0].Image)
+	})
+
+	t.Run("when there are no images, it fails", func(t *testing.T) {
+		_, err := v1.WarmDaemonSet(nil, v1.WarmDaemonSetOpts{Name: "warm", Namespace: "default", HelperImage: "busybox"})
+		require.ErrorContains(t, err, "Expected at least one image to pre-pull")
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
@@ -336,6 +337,14 @@ func (h *HTTPRequestLogs) Last() HTTPRequestLog {
 	return h.requests[len(h.requests)-1]
 }
 
+// All Returns every HTTP Request logged, in the order they were received
+func (h *HTTPRequestLogs) All() []HTTPRequestLog {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([]HTTPRequestLog{}, h.requests...)
+}
+
 // Len Length of request logs
 func (h *HTTPRequestLogs) Len() int {
 	h.lock.Lock()
@@ -714,6 +723,25 @@ func (r *FakeTestRegistryBuilder) WithImageIndex(imageIndexName string, images .
 	return r.updateState(imageIndexName, nil, index, "", "")
 }
 
+// WithImageIndexForPlatforms creates an image index with a random image for each platform (example: linux/arm64)
+func (r *FakeTestRegistryBuilder) WithImageIndexForPlatforms(imageIndexName string, platforms ...string) *ImageOrImageIndexWithTarPath {
+	var index v1.ImageIndex = empty.Index
+
+	for _, platform := range platforms {
+		image, err := random.Image(500, 1)
+		assert.NoError(r.t, err)
+		parsedPlatform, err := v1.ParsePlatform(platform)
+		assert.NoError(r.t, err)
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: v1.Descriptor{Platform: parsedPlatform},
+		})
+	}
+
+	return r.updateState(imageIndexName, nil, index, "", "")
+}
+
 func (r *FakeTestRegistryBuilder) RemoveImage(imageRef string) {
 	u, err := url.Parse(r.server.URL)
 	assert.NoError(r.t, err)
@@ -0,0 +1,29 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package helpers
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/stretchr/testify/require"
+)
+
+// WriteOCILayout Writes the provided images to an OCI image layout in path, named after repository
+// the same way containerd and docker name them
+func WriteOCILayout(t *testing.T, path string, repository string, images ...v1.Image) {
+	t.Helper()
+
+	layoutPath, err := layout.Write(path, empty.Index)
+	require.NoError(t, err)
+	for _, img := range images {
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		require.NoError(t, layoutPath.AppendImage(img, layout.WithAnnotations(map[string]string{
+			"io.containerd.image.name": repository + "@" + digest.String(),
+		})))
+	}
+}
@@ -180,6 +180,7 @@ func (b *blobs) handle(resp http.ResponseWriter, req *http.Request) *regError {
 	service := elem[len(elem)-2]
 	digest := req.URL.Query().Get("digest")
 	contentRange := req.Header.Get("Content-Range")
+	rangeHeader := req.Header.Get("Range")
 
 	repo := req.URL.Host + path.Join(elem[1:len(elem)-2]...)
 
@@ -290,6 +291,35 @@ func (b *blobs) handle(resp http.ResponseWriter, req *http.Request) *regError {
 			r = &buf
 		}
 
+		if rangeHeader != "" {
+			start, end := int64(0), int64(0)
+			if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+				return &regError{
+					Status:  http.StatusRequestedRangeNotSatisfiable,
+					Code:    "BLOB_UNKNOWN",
+					Message: "We don't understand your Range",
+				}
+			}
+			if start > end || end+1 > size {
+				return &regError{
+					Status:  http.StatusRequestedRangeNotSatisfiable,
+					Code:    "BLOB_UNKNOWN",
+					Message: fmt.Sprintf("range %d-%d outside of the %d bytes of the blob", start, end, size),
+				}
+			}
+
+			n := (end + 1) - start
+			if _, err := io.CopyN(io.Discard, r, start); err != nil {
+				return regErrInternal(err)
+			}
+			resp.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			resp.Header().Set("Content-Length", fmt.Sprint(n))
+			resp.Header().Set("Docker-Content-Digest", h.String())
+			resp.WriteHeader(http.StatusPartialContent)
+			io.CopyN(resp, r, n)
+			return nil
+		}
+
 		resp.Header().Set("Content-Length", fmt.Sprint(size))
 		resp.Header().Set("Docker-Content-Digest", h.String())
 		resp.WriteHeader(http.StatusOK)